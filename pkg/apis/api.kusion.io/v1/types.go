@@ -95,8 +95,42 @@ type Workspace struct {
 	// SecretStore represents a secure external location for storing secrets.
 	SecretStore *SecretStore `yaml:"secretStore,omitempty" json:"secretStore,omitempty"`
 
+	// SecretStores are the named secret stores besides the default SecretStore, which can be
+	// designated to specific secrets by SecretStoreSelectors.
+	SecretStores map[string]*SecretStore `yaml:"secretStores,omitempty" json:"secretStores,omitempty"`
+
+	// SecretStoreSelectors maps secret name patterns to the names of SecretStores. Secrets not
+	// matching any pattern are resolved against the default SecretStore.
+	SecretStoreSelectors map[string]string `yaml:"secretStoreSelectors,omitempty" json:"secretStoreSelectors,omitempty"`
+
+	// SecretStoreFallbacks are the names of SecretStores tried in order when the secret store resolved
+	// for an external secret is unavailable at generation time.
+	SecretStoreFallbacks []string `yaml:"secretStoreFallbacks,omitempty" json:"secretStoreFallbacks,omitempty"`
+
+	// SecretStoreNamespaces maps the namespaces to the names of SecretStores, which replace the default
+	// SecretStore for the apps in the namespaces, e.g. the tenants of a multi-tenant cluster.
+	SecretStoreNamespaces map[string]string `yaml:"secretStoreNamespaces,omitempty" json:"secretStoreNamespaces,omitempty"`
+
 	// Context contains workspace-level configurations, such as runtimes, topologies, and metadata, etc.
 	Context GenericConfig `yaml:"context,omitempty" json:"context,omitempty"`
+
+	// RetryPolicy is the default policy to retry applying the resources without their own retry policy.
+	RetryPolicy *RetryPolicy `yaml:"retryPolicy,omitempty" json:"retryPolicy,omitempty"`
+
+	// OwnerReference enables generating a parent object of the given kind for each app, which is
+	// referenced by the owner references of the other Kubernetes resources of the app, so that
+	// deleting the parent object cascades to them.
+	OwnerReference *OwnerReferenceConfig `yaml:"ownerReference,omitempty" json:"ownerReference,omitempty"`
+
+	// PreviewIgnoreFields are the dot-separated paths of the fields ignored when computing the preview
+	// changes, e.g. metadata.annotations, which are merged with the ones from the command line. The fields
+	// managed by the Kubernetes API server, e.g. metadata.managedFields, are always ignored for the Kubernetes
+	// resources.
+	PreviewIgnoreFields []string `yaml:"previewIgnoreFields,omitempty" json:"previewIgnoreFields,omitempty"`
+
+	// Extensions are the org-wide extensions of the projects and stacks using the workspace, e.g. a default
+	// namespace scheme, which are overridden by the extensions of the same kind of the projects and stacks.
+	Extensions []*Extension `yaml:"extensions,omitempty" json:"extensions,omitempty"`
 }
 
 type Accessory map[string]interface{}
@@ -167,12 +201,19 @@ type AppConfiguration struct {
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 	// Workload defines how to run your application code.
 	Workload Accessory `json:"workload" yaml:"workload"`
+	// Workloads defines the additional workloads of the App keyed by their names, e.g. a worker along with
+	// the web server defined by Workload. Each of them is generated by its module the same way as Workload.
+	Workloads map[string]Accessory `json:"workloads,omitempty" yaml:"workloads,omitempty"`
 	// Accessories defines a collection of accessories that will be attached to the workload.
 	// The key in this map represents the module name
 	Accessories map[string]Accessory `json:"accessories,omitempty" yaml:"accessories,omitempty"`
 	// Labels and Annotations can be used to attach arbitrary metadata as key-value pairs to resources.
 	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// ClusterScoped indicates the App operates at the cluster level, e.g. operators and controllers.
+	// If true, Kusion will not create a namespace for the App and the placement of the resources is
+	// controlled by the module outputs. Defaults to false.
+	ClusterScoped bool `json:"clusterScoped,omitempty" yaml:"clusterScoped,omitempty"`
 }
 
 type Secret struct {
@@ -195,8 +236,59 @@ type Patcher struct {
 	// PodAnnotations represent the annotations patched to the pods.
 	PodAnnotations map[string]string `json:"podAnnotations,omitempty" yaml:"podAnnotations,omitempty"`
 	// JSONPatchers represents patchers that can be patched to an arbitrary resource.
-	// The key of this map represents the ResourceId of the resource to be patched.
+	// The key of this map represents the ResourceId of the resource to be patched. The JSON patchers are
+	// applied in the order of the ResourceIds, and the patchers of the modules in the order of the module keys.
 	JSONPatchers map[string]JSONPatcher `json:"jsonPatcher,omitempty" yaml:"jsonPatcher,omitempty"`
+	// JSONPatchSelectors represents patchers patched to the resources selected by the apiVersion, kind and
+	// labels, which are applied in order after the JSONPatchers.
+	JSONPatchSelectors []JSONPatchSelector `json:"jsonPatchSelectors,omitempty" yaml:"jsonPatchSelectors,omitempty"`
+	// Resources represent the resource requests and limits patched to all containers in the workload.
+	Resources *ContainerResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// NodeSelector represents the node selector patched to the pods.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+	// Tolerations represent the tolerations patched to the pods.
+	Tolerations []v1.Toleration `json:"tolerations,omitempty" yaml:"tolerations,omitempty"`
+	// Affinity represents the affinity deep-merged into the affinity of the pods.
+	Affinity *v1.Affinity `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	// InitContainers represent the init containers patched to the pods, which are prepended to the
+	// existing init containers, or replace the existing ones with the same name.
+	InitContainers []v1.Container `json:"initContainers,omitempty" yaml:"initContainers,omitempty"`
+	// Volumes represent the volumes patched to the pods, which replace the existing ones with the same name.
+	Volumes []v1.Volume `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	// RemovedVolumes are the names of the volumes removed from the pods along with their volume mounts in all
+	// containers, which are removed before the Volumes and the VolumeMounts are patched.
+	RemovedVolumes []string `json:"removedVolumes,omitempty" yaml:"removedVolumes,omitempty"`
+	// VolumeMounts represent the volume mounts patched to all containers in the workload, which replace the
+	// existing ones with the same name, mount path and sub path.
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty" yaml:"volumeMounts,omitempty"`
+	// ImagePullSecrets represent the image pull secrets patched to the pods, which are appended to the
+	// existing ones if not existing.
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty" yaml:"imagePullSecrets,omitempty"`
+	// PodSecurityContext represents the security context merged into the security context of the pods.
+	PodSecurityContext *v1.PodSecurityContext `json:"podSecurityContext,omitempty" yaml:"podSecurityContext,omitempty"`
+	// ContainerSecurityContext represents the security context merged into the security context of all
+	// containers in the workload.
+	ContainerSecurityContext *v1.SecurityContext `json:"containerSecurityContext,omitempty" yaml:"containerSecurityContext,omitempty"`
+	// Replicas represents the floor of the replicas of the Deployment or StatefulSet workload.
+	Replicas *int32 `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// ContainerName selects the container patched with the command and args, all containers in the workload
+	// are patched if empty.
+	ContainerName string `json:"containerName,omitempty" yaml:"containerName,omitempty"`
+	// Command represents the command replacing the command of the selected containers.
+	Command []string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Args represent the args replacing the args of the selected containers.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+	// Extensions represent the extensions patched to the workload, e.g. the apply-pause extension.
+	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+// ContainerResources represents the compute resource requests and limits of the containers,
+// e.g. {"cpu": "500m", "memory": "512Mi"}.
+type ContainerResources struct {
+	// Requests describes the minimum amount of compute resources required.
+	Requests map[string]string `json:"requests,omitempty" yaml:"requests,omitempty"`
+	// Limits describes the maximum amount of compute resources allowed.
+	Limits map[string]string `json:"limits,omitempty" yaml:"limits,omitempty"`
 }
 
 type PatchType string
@@ -204,10 +296,14 @@ type PatchType string
 const (
 	MergePatch PatchType = "MergePatch"
 	JSONPatch  PatchType = "JSONPatch"
+	// StrategicMergePatch is the Kubernetes strategic merge patch, which merges the list items by their
+	// merge keys, e.g. the containers by name. It falls back to MergePatch for the unknown types.
+	StrategicMergePatch PatchType = "StrategicMergePatch"
 )
 
 // JSONPatcher represents the patcher that can be patched to an arbitrary resource.
-// The patch algorithm follows the RFC6902 JSON patch and RFC7396 JSON merge patches.
+// The patch algorithm follows the RFC6902 JSON patch and RFC7396 JSON merge patches, or the
+// Kubernetes strategic merge patch for the Kubernetes resources.
 type JSONPatcher struct {
 	// PatchType
 	Type PatchType `json:"type" yaml:"type"`
@@ -226,6 +322,25 @@ type JSONPatcher struct {
 	// payload := []byte(`{"height":null,"name":"Jane"}`)
 	// result: {"age":28,"name":"Jane"}
 	Payload []byte `json:"payload" yaml:"payload"`
+	// AllowMissingPathOnRemove indicates whether to ignore the `remove` operation on a missing path
+	// instead of returning an error. Only works for JSONPatch and defaults to true.
+	AllowMissingPathOnRemove *bool `json:"allowMissingPathOnRemove,omitempty" yaml:"allowMissingPathOnRemove,omitempty"`
+	// EnsurePathExistsOnAdd indicates whether to create the missing parents of the path for the `add`
+	// operation instead of returning an error. Only works for JSONPatch and defaults to true.
+	EnsurePathExistsOnAdd *bool `json:"ensurePathExistsOnAdd,omitempty" yaml:"ensurePathExistsOnAdd,omitempty"`
+}
+
+// JSONPatchSelector represents the JSON patcher patched to all the resources matching the selector, rather
+// than a resource selected by its ID. At least one of the APIVersion, Kind and Labels must be set.
+type JSONPatchSelector struct {
+	// APIVersion is the apiVersion of the selected resources.
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// Kind is the kind of the selected resources.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Labels are the labels the selected resources must have.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Patcher is the JSON patcher patched to the selected resources.
+	Patcher JSONPatcher `json:"patcher" yaml:"patcher"`
 }
 
 const ConfigBackends = "backends"
@@ -272,14 +387,71 @@ const (
 	EnvGoogleCloudCredentials     = "GOOGLE_CLOUD_CREDENTIALS"
 	EnvGoogleCloudCredentialsPath = "GOOGLE_CLOUD_CREDENTIALS_PATH"
 
+	// FieldImportedResources maps the kusion IDs to the imported resource IDs, where a kusion ID ending with
+	// the * imports all the resources with the prefix, replacing the * in the imported ID by the rest of the ID
 	FieldImportedResources = "importedResources"
-	FieldHealthPolicy      = "healthPolicy"
-	FieldKCLHealthCheckKCL = "health.kcl"
+	// FieldImportedResourcesFile is the path of the CSV or YAML file mapping the kusion IDs to the imported
+	// resource IDs relative to the stack directory, which is merged with the inline importedResources
+	FieldImportedResourcesFile = "importedResourcesFile"
+	// FieldImportedResourcesObject is the key of the CSV or YAML object mapping the kusion IDs to the imported
+	// resource IDs under the objects directory of the backend, which is merged with the inline importedResources
+	FieldImportedResourcesObject = "importedResourcesObject"
+	// FieldImportedResourcesKeepAttributes are the keys of the attributes kept for the imported resources,
+	// e.g. the region the provider needs to locate the resource, while the other attributes are cleared
+	FieldImportedResourcesKeepAttributes = "importedResourcesKeepAttributes"
+	FieldHealthPolicy                    = "healthPolicy"
+	// FieldBaseModuleConfig is the name of another module config in the workspace, whose project config is
+	// layered under the module config, i.e. the fields not set in the module config are inherited from it
+	FieldBaseModuleConfig = "baseModuleConfig"
+	// FieldModuleTimeout is the timeout of invoking the module in the platform config, e.g. "2m"
+	FieldModuleTimeout = "moduleTimeout"
+	// FieldModuleMaxAttempts is the max attempts of invoking the module on the transient failures in the
+	// platform config, e.g. 3
+	FieldModuleMaxAttempts = "moduleMaxAttempts"
+	// FieldModuleRetryBackoff is the initial backoff between the attempts of invoking the module in the
+	// platform config, which is doubled after each attempt, e.g. "500ms"
+	FieldModuleRetryBackoff = "moduleRetryBackoff"
+	FieldKCLHealthCheckKCL  = "health.kcl"
 	// kind field in kubernetes resource Attributes
 	FieldKind       = "kind"
 	FieldIsWorkload = "kusion.io/is-workload"
+	// FieldApplyPause marks the resource to pause the apply after it is created or updated
+	FieldApplyPause = "kusion.io/apply-pause"
+	// FieldRetryPolicy is the RetryPolicy to retry applying the resource on the transient errors
+	FieldRetryPolicy = "kusion.io/retry-policy"
+	// FieldDependsOn declares the IDs of the resources that the resource explicitly depends on, besides
+	// the dependencies inferred by the resource ordering
+	FieldDependsOn = "kusion.io/depends-on"
+	// FieldNoImplicitDeps suppresses the dependencies inferred by the resource ordering, which is true to
+	// suppress all of them, or the IDs of the resources to suppress
+	FieldNoImplicitDeps = "kusion.io/no-implicit-deps"
+	// FieldModuleProvenance marks the resource with the key of the module generating it
+	FieldModuleProvenance = "kusion.io/module"
+	// FieldWorkloadResource is set to true by the workload module on exactly one of its resources to mark it
+	// as the workload explicitly, which takes precedence over the detection by the number of the resources
+	// and FieldIsWorkload. It is replaced with FieldIsWorkload in the generated Spec.
+	FieldWorkloadResource = "kusion.io/workload"
 )
 
+// RetryPolicy represents the policy to retry applying a resource when the apply fails with a
+// transient error, e.g. the admission webhook is not ready or the API server throttles the requests.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries, no retry is performed if it is zero.
+	MaxRetries int `yaml:"maxRetries" json:"maxRetries"`
+	// BackoffSeconds is the backoff before the first retry, which is doubled for each subsequent
+	// retry. Default to 1 second.
+	BackoffSeconds int `yaml:"backoffSeconds,omitempty" json:"backoffSeconds,omitempty"`
+}
+
+// OwnerReferenceConfig represents the kind of the parent object representing the app, e.g. a kusion
+// Application CR. The name of the parent object is derived from the project, stack and app names.
+type OwnerReferenceConfig struct {
+	// APIVersion is the API version of the parent object, e.g. app.kusion.io/v1alpha1.
+	APIVersion string `yaml:"apiVersion" json:"apiVersion"`
+	// Kind is the kind of the parent object, e.g. Application.
+	Kind string `yaml:"kind" json:"kind"`
+}
+
 // BackendConfigs contains the configuration of multiple backends and the current backend.
 type BackendConfigs struct {
 	// Current is the name of the current used backend.
@@ -482,6 +654,9 @@ type ModuleConfig struct {
 	Path string `yaml:"path" json:"path"`
 	// Version is the version of the module.
 	Version string `yaml:"version" json:"version"`
+	// VersionOverride overrides the module version resolved from the dependencies of the stack when
+	// generating the Spec in this workspace, e.g. testing a new module version in staging.
+	VersionOverride string `yaml:"versionOverride,omitempty" json:"versionOverride,omitempty"`
 	// Configs contains all levels of module configs
 	Configs Configs `yaml:"configs" json:"configs"`
 }
@@ -527,6 +702,9 @@ type Extension struct {
 type KubeNamespaceExtension struct {
 	// The custom namespace name
 	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	// DefaultResources sets the namespace to the namespaced Kubernetes resources generated by the modules
+	// without a namespace, which changes the IDs of these resources and is therefore disabled by default.
+	DefaultResources bool `yaml:"defaultResources,omitempty" json:"defaultResources,omitempty"`
 }
 
 // KubeMetadataExtension allows you to append labels&annotations to kubernetes resources.
@@ -686,6 +864,9 @@ const (
 	// ResourceExtensionKubeConfig is the key for resource extension, which is used
 	// to indicate the path of kubeConfig for Kubernetes type resource.
 	ResourceExtensionKubeConfig = "kubeConfig"
+	// ResourceExtensionSecretStore is the key for resource extension, which is used
+	// to indicate the name of the designated secret store of a Kubernetes Secret resource.
+	ResourceExtensionSecretStore = "secretStore"
 )
 
 type Resources []Resource
@@ -718,10 +899,34 @@ type Spec struct {
 	Resources Resources `yaml:"resources" json:"resources"`
 	// SecretSore represents a external secret store location for storing secrets.
 	SecretStore *SecretStore `yaml:"secretStore" json:"secretStore"`
+	// SecretStores are the named secret stores designated to the Secret resources, which are recorded by
+	// name in the resource extensions.
+	SecretStores map[string]*SecretStore `yaml:"secretStores,omitempty" json:"secretStores,omitempty"`
 	// Context contains workspace-level configurations, such as runtimes, topologies, and metadata, etc.
 	Context GenericConfig `yaml:"context" json:"context"`
 }
 
+// ResourceSummary summarizes the number of resources generated for each App, which serves as a
+// lightweight bill of materials of a generation.
+type ResourceSummary struct {
+	// Apps is the resource summary of each App.
+	Apps []*AppResourceSummary `yaml:"apps" json:"apps"`
+	// Total is the total number of resources in the generated Spec.
+	Total int `yaml:"total" json:"total"`
+}
+
+// AppResourceSummary summarizes the number of resources generated for an App.
+type AppResourceSummary struct {
+	// Name of the App.
+	Name string `yaml:"name" json:"name"`
+	// Modules maps the module key to the number of resources generated by the module. Resources
+	// generated by the built-in generators, e.g. the namespace and secrets, are recorded under
+	// the key "builtin".
+	Modules map[string]int `yaml:"modules" json:"modules"`
+	// Total is the total number of resources generated for the App.
+	Total int `yaml:"total" json:"total"`
+}
+
 // State is a record of an operation's result. It is a mapping between resources in KCL and the actual
 // infra resource and often used as a datasource for 3-way merge/diff in operations like Apply or Preview.
 type State struct {
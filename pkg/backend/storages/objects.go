@@ -0,0 +1,107 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// objectsPrefix is the directory of the objects uploaded by the users, e.g. the mapping files of the imported
+// resources, which are the only objects readable by ReadObject.
+const objectsPrefix = "objects"
+
+var ErrInvalidObjectKey = errors.New("object key should be a relative path without '..'")
+
+// validateObjectKey checks the key refers to an object under the objects directory.
+func validateObjectKey(key string) error {
+	if key == "" || !filepath.IsLocal(key) || strings.Contains(key, `\`) {
+		return ErrInvalidObjectKey
+	}
+	return nil
+}
+
+// genGenericOssObjectKey generates the key of the object under the objects directory, which is used for
+// OssStorage, S3Storage and GoogleStorage.
+func genGenericOssObjectKey(prefix, key string) string {
+	prefix = strings.TrimPrefix(prefix, "/")
+	return path.Join(prefix, objectsPrefix, key)
+}
+
+// ReadObject reads the object with the key under the objects directory of the local storage.
+func (s *LocalStorage) ReadObject(key string) ([]byte, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(s.path, objectsPrefix, key))
+	if err != nil {
+		return nil, fmt.Errorf("read object %s failed: %w", key, err)
+	}
+	return content, nil
+}
+
+// ReadObject reads the object with the key under the objects directory of the oss storage.
+func (s *OssStorage) ReadObject(key string) ([]byte, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	body, err := s.bucket.GetObject(genGenericOssObjectKey(s.prefix, key))
+	if err != nil {
+		return nil, fmt.Errorf("get object %s from oss failed: %w", key, err)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s failed: %w", key, err)
+	}
+	return content, nil
+}
+
+// ReadObject reads the object with the key under the objects directory of the s3 storage.
+func (s *S3Storage) ReadObject(key string) ([]byte, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	objectKey := genGenericOssObjectKey(s.prefix, key)
+	output, err := s.s3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    &objectKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object %s from s3 failed: %w", key, err)
+	}
+	defer func() {
+		_ = output.Body.Close()
+	}()
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s failed: %w", key, err)
+	}
+	return content, nil
+}
+
+// ReadObject reads the object with the key under the objects directory of the google storage.
+func (s *GoogleStorage) ReadObject(key string) ([]byte, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	reader, err := s.bucket.Object(genGenericOssObjectKey(s.prefix, key)).NewReader(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get object %s from google storage failed: %w", key, err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read object %s failed: %w", key, err)
+	}
+	return content, nil
+}
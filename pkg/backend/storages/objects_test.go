@@ -0,0 +1,58 @@
+package storages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage_ReadObject(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, objectsPrefix, "imports"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, objectsPrefix, "imports", "buckets.csv"), []byte("foo, bar\n"), 0o600))
+	storage := &LocalStorage{path: dir}
+
+	testcases := []struct {
+		name     string
+		key      string
+		success  bool
+		expected []byte
+	}{
+		{
+			name:     "read object successfully",
+			key:      "imports/buckets.csv",
+			success:  true,
+			expected: []byte("foo, bar\n"),
+		},
+		{
+			name:    "failed to read missing object",
+			key:     "imports/missing.csv",
+			success: false,
+		},
+		{
+			name:    "failed to read object outside the objects directory",
+			key:     "../workspaces/.metadata.yml",
+			success: false,
+		},
+		{
+			name:    "failed to read object with absolute key",
+			key:     "/etc/passwd",
+			success: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := storage.ReadObject(tc.key)
+			assert.Equal(t, tc.success, err == nil)
+			assert.Equal(t, tc.expected, content)
+		})
+	}
+}
+
+func TestGenGenericOssObjectKey(t *testing.T) {
+	assert.Equal(t, "kusion/objects/imports/buckets.csv", genGenericOssObjectKey("/kusion", "imports/buckets.csv"))
+	assert.Equal(t, "objects/imports/buckets.csv", genGenericOssObjectKey("", "imports/buckets.csv"))
+}
@@ -55,6 +55,7 @@ import (
 	"kusionstack.io/kusion/pkg/util/pretty"
 	"kusionstack.io/kusion/pkg/util/signal"
 	"kusionstack.io/kusion/pkg/util/terminal"
+	"kusionstack.io/kusion/pkg/workspace"
 )
 
 var (
@@ -348,7 +349,7 @@ func (o *ApplyOptions) run(rel *apiv1.Release, releaseStorage release.Storage) (
 	if o.SpecFile != "" {
 		spec, err = generate.SpecFromFile(o.SpecFile)
 	} else {
-		spec, err = generate.GenerateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle)
+		spec, err = generate.GenerateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle, o.Backend)
 	}
 	if err != nil {
 		return
@@ -367,6 +368,9 @@ func (o *ApplyOptions) run(rel *apiv1.Release, releaseStorage release.Storage) (
 		return
 	}
 
+	// ignore the fields from the command line along with the ones configured in the workspace
+	o.IgnoreFields = workspace.GetPreviewIgnoreFields(o.RefWorkspace, o.IgnoreFields...)
+
 	// compute changes for preview
 	changes, err := preview.Preview(o.PreviewOptions, releaseStorage, rel.Spec, rel.State, o.RefProject, o.RefStack)
 	if err != nil {
@@ -28,10 +28,13 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/backend"
 	"kusionstack.io/kusion/pkg/cmd/meta"
 	cmdutil "kusionstack.io/kusion/pkg/cmd/util"
 	"kusionstack.io/kusion/pkg/engine/api/generate/generator"
 	"kusionstack.io/kusion/pkg/engine/api/generate/run"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
+	"kusionstack.io/kusion/pkg/generators/orderedresources"
 	"kusionstack.io/kusion/pkg/util/i18n"
 	"kusionstack.io/kusion/pkg/util/terminal"
 )
@@ -50,7 +53,17 @@ var (
 		kusion generate -o /tmp/spec.yaml --workspace dev
 		
 		# Generate spec with specified arguments
-		kusion generate -D name=test -D age=18`)
+		kusion generate -D name=test -D age=18
+
+		# Generate the resource dependency graph in the Graphviz DOT format and render it
+		kusion generate --format dot | dot -Tsvg -o /tmp/spec.svg`)
+)
+
+const (
+	// formatYAML is the output format of the Spec in YAML.
+	formatYAML = "yaml"
+	// formatDOT is the output format of the resource dependency graph of the Spec in Graphviz DOT.
+	formatDOT = "dot"
 )
 
 // GenerateFlags directly reflect the information that CLI is gathering via flags. They will be converted to
@@ -60,9 +73,12 @@ var (
 type GenerateFlags struct {
 	MetaFlags *meta.MetaFlags
 
-	Output  string
-	Values  []string
-	NoStyle bool
+	Output            string
+	Format            string
+	Values            []string
+	NoStyle           bool
+	StrictSecretStore bool
+	NonStrict         bool
 
 	UI *terminal.UI
 
@@ -74,18 +90,34 @@ type GenerateOptions struct {
 	*meta.MetaOptions
 
 	Output  string
+	Format  string
 	Values  []string
 	NoStyle bool
+	// SpecOptions are the optional settings of generating the Spec.
+	SpecOptions
 
 	UI *terminal.UI
 
 	genericiooptions.IOStreams
 }
 
+// SpecOptions are the optional settings of generating the Spec.
+type SpecOptions struct {
+	// StrictSecretStore fails the generation if the workloads reference the external secrets while no
+	// secret store is configured for them, which is only warned by default.
+	StrictSecretStore bool
+	// NonStrict continues the generation past the failures of the built-in generators, e.g. an invalid
+	// namespace, so that the partial Spec can be used for debugging.
+	NonStrict bool
+	// Errors collects the failures of the built-in generators skipped in the NonStrict mode if not nil.
+	Errors *[]error
+}
+
 // NewGenerateFlags returns a default GenerateFlags
 func NewGenerateFlags(ui *terminal.UI, streams genericiooptions.IOStreams) *GenerateFlags {
 	return &GenerateFlags{
 		MetaFlags: meta.NewMetaFlags(),
+		Format:    formatYAML,
 		UI:        ui,
 		IOStreams: streams,
 	}
@@ -121,8 +153,11 @@ func (flags *GenerateFlags) AddFlags(cmd *cobra.Command) {
 	flags.MetaFlags.AddFlags(cmd)
 
 	cmd.Flags().StringVarP(&flags.Output, "output", "o", flags.Output, i18n.T("File to write generated Spec resources to"))
+	cmd.Flags().StringVar(&flags.Format, "format", flags.Format, i18n.T("Format of the output, one of yaml and dot, where dot is the resource dependency graph in Graphviz DOT"))
 	cmd.Flags().StringArrayVarP(&flags.Values, "argument", "D", []string{}, i18n.T("Specify arguments on the command line"))
 	cmd.Flags().BoolVarP(&flags.NoStyle, "no-style", "", false, i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+	cmd.Flags().BoolVar(&flags.StrictSecretStore, "strict-secret-store", false, i18n.T("Fail if the external secrets reference no secret store, which is only warned by default"))
+	cmd.Flags().BoolVar(&flags.NonStrict, "non-strict", false, i18n.T("Continue past the failures of the built-in generators, e.g. an invalid namespace, to generate the partial Spec for debugging"))
 }
 
 // ToOptions converts from CLI inputs to runtime inputs.
@@ -136,8 +171,13 @@ func (flags *GenerateFlags) ToOptions() (*GenerateOptions, error) {
 	o := &GenerateOptions{
 		MetaOptions: metaOptions,
 		Output:      flags.Output,
+		Format:      flags.Format,
 		Values:      flags.Values,
 		NoStyle:     flags.NoStyle,
+		SpecOptions: SpecOptions{
+			StrictSecretStore: flags.StrictSecretStore,
+			NonStrict:         flags.NonStrict,
+		},
 
 		UI:        flags.UI,
 		IOStreams: flags.IOStreams,
@@ -152,6 +192,10 @@ func (o *GenerateOptions) Validate(cmd *cobra.Command, args []string) error {
 		return cmdutil.UsageErrorf(cmd, "Unexpected args: %v", args)
 	}
 
+	if o.Format != formatYAML && o.Format != formatDOT {
+		return cmdutil.UsageErrorf(cmd, "format %s is not supported, expected %s or %s", o.Format, formatYAML, formatDOT)
+	}
+
 	for _, value := range o.Values {
 		if parts := strings.SplitN(value, "=", 2); len(parts) != 2 {
 			return cmdutil.UsageErrorf(cmd, "value %s is invalid format", value)
@@ -172,13 +216,20 @@ func (o *GenerateOptions) Run() error {
 	parameters := o.buildParameters()
 
 	// call default generator to generate Spec
-	spec, err := GenerateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle)
+	var errs []error
+	opts := o.SpecOptions
+	opts.Errors = &errs
+	spec, err := generateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle, o.Backend, opts)
 	if err != nil {
 		return err
 	}
+	// the failures skipped in the non-strict mode are reported along with the partial Spec
+	for _, e := range errs {
+		fmt.Fprintf(o.ErrOut, "Warning: %v\n", e)
+	}
 
 	// write Spec to output file or a writer
-	err = write(spec, o.Output, o.Out)
+	err = write(spec, o.Output, o.Format, o.Out)
 	if err != nil {
 		// o.UI.Output("Error writing generated Spec: %s", err.Error(), terminal.WithErrorStyle())
 		return err
@@ -198,8 +249,8 @@ func (o *GenerateOptions) buildParameters() map[string]string {
 	return parameters
 }
 
-// GenerateSpecWithSpinner calls generator to generate versioned Spec.
-// Add a method wrapper for testing purposes.
+// GenerateSpecWithSpinner calls generator to generate versioned Spec, reading the objects referenced by the
+// module configs from the backend if it supports. Add a method wrapper for testing purposes.
 func GenerateSpecWithSpinner(
 	project *v1.Project,
 	stack *v1.Stack,
@@ -207,6 +258,21 @@ func GenerateSpecWithSpinner(
 	parameters map[string]string,
 	ui *terminal.UI,
 	noStyle bool,
+	bk backend.Backend,
+) (*v1.Spec, error) {
+	return generateSpecWithSpinner(project, stack, workspace, parameters, ui, noStyle, bk, SpecOptions{})
+}
+
+// generateSpecWithSpinner is the same as GenerateSpecWithSpinner, but generates the Spec with the options.
+func generateSpecWithSpinner(
+	project *v1.Project,
+	stack *v1.Stack,
+	workspace *v1.Workspace,
+	parameters map[string]string,
+	ui *terminal.UI,
+	noStyle bool,
+	bk backend.Backend,
+	opts SpecOptions,
 ) (*v1.Spec, error) {
 	// Construct generator instance
 	defaultGenerator := &generator.DefaultGenerator{
@@ -218,6 +284,12 @@ func GenerateSpecWithSpinner(
 			Username: os.Getenv("KUSION_MODULE_REGISTRY_USERNAME"),
 			Password: os.Getenv("KUSION_MODULE_REGISTRY_PASSWORD"),
 		},
+		StrictSecretStore: opts.StrictSecretStore,
+		NonStrict:         opts.NonStrict,
+		Errors:            opts.Errors,
+	}
+	if objects, ok := bk.(appconfiguration.ObjectReader); ok {
+		defaultGenerator.Objects = objects
 	}
 
 	if noStyle {
@@ -268,9 +340,17 @@ func SpecFromFile(filePath string) (*v1.Spec, error) {
 	return i, nil
 }
 
-// write writes Spec resources to a file or a writer.
-func write(spec *v1.Spec, output string, out io.Writer) error {
-	specStr, err := yamlv3.Marshal(spec)
+// write writes Spec resources, or their dependency graph in the dot format, to a file or a writer.
+func write(spec *v1.Spec, output, format string, out io.Writer) error {
+	var specStr []byte
+	var err error
+	if format == formatDOT {
+		var buf bytes.Buffer
+		err = orderedresources.WriteDOT(&buf, spec.Resources)
+		specStr = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	} else {
+		specStr, err = yamlv3.Marshal(spec)
+	}
 	if err != nil {
 		return err
 	}
@@ -40,6 +40,7 @@ import (
 	"kusionstack.io/kusion/pkg/util/i18n"
 	"kusionstack.io/kusion/pkg/util/pretty"
 	"kusionstack.io/kusion/pkg/util/terminal"
+	"kusionstack.io/kusion/pkg/workspace"
 )
 
 var (
@@ -234,7 +235,7 @@ func (o *PreviewOptions) Run() error {
 	if o.SpecFile != "" {
 		spec, err = generate.SpecFromFile(o.SpecFile)
 	} else {
-		spec, err = generate.GenerateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle)
+		spec, err = generate.GenerateSpecWithSpinner(o.RefProject, o.RefStack, o.RefWorkspace, parameters, o.UI, o.NoStyle, o.Backend)
 	}
 	if err != nil {
 		return err
@@ -266,6 +267,9 @@ func (o *PreviewOptions) Run() error {
 		state = &apiv1.State{}
 	}
 
+	// ignore the fields from the command line along with the ones configured in the workspace
+	o.IgnoreFields = workspace.GetPreviewIgnoreFields(o.RefWorkspace, o.IgnoreFields...)
+
 	// compute changes for preview
 	changes, err := Preview(o, storage, spec, state, o.RefProject, o.RefStack)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 
 	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	v1 "kusionstack.io/kusion/pkg/apis/status/v1"
+	"kusionstack.io/kusion/pkg/backend"
 	"kusionstack.io/kusion/pkg/backend/storages"
 	"kusionstack.io/kusion/pkg/cmd/generate"
 	"kusionstack.io/kusion/pkg/cmd/meta"
@@ -125,6 +126,7 @@ func mockGenerateSpecWithSpinner() {
 		parameters map[string]string,
 		ui *terminal.UI,
 		noStyle bool,
+		bk backend.Backend,
 	) (*apiv1.Spec, error) {
 		return &apiv1.Spec{Resources: []apiv1.Resource{sa1, sa2, sa3}}, nil
 	}).Build()
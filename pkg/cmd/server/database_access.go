@@ -2,12 +2,14 @@ package server
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"kusionstack.io/kusion/pkg/cmd/server/util"
+	"kusionstack.io/kusion/pkg/infra/persistence"
 
 	gomysql "github.com/go-sql-driver/mysql"
 	"github.com/spf13/pflag"
@@ -27,6 +29,9 @@ type DatabaseAccessOptions struct {
 	DBPassword string `json:"dbPassword,omitempty" yaml:"dbPassword,omitempty"`
 	DBHost     string `json:"dbHost,omitempty" yaml:"dbHost,omitempty"`
 	DBPort     int    `json:"dbPort,omitempty" yaml:"dbPort,omitempty"`
+	// SlowQueryThreshold is the duration beyond which a query is logged as a slow query,
+	// slow query logging is disabled if it is zero.
+	SlowQueryThreshold time.Duration `json:"slowQueryThreshold,omitempty" yaml:"slowQueryThreshold,omitempty"`
 }
 
 // InstallDB uses the run options to generate and open a db session.
@@ -41,10 +46,13 @@ func (o *DatabaseAccessOptions) InstallDB() (*gorm.DB, error) {
 	config.ParseTime = true
 	config.InterpolateParams = true
 	dsn := config.FormatDSN()
-	// silence log output
+	// silence log output except for the slow queries if the threshold is specified
 	cfg := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	}
+	if o.SlowQueryThreshold > 0 {
+		cfg.Logger = persistence.NewSlowQueryLogger(o.SlowQueryThreshold)
+	}
 	return gorm.Open(mysql.Open(dsn), cfg) // todo: add db connection check to healthz check
 }
 
@@ -87,4 +95,6 @@ func (o *DatabaseAccessOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.DBPassword, "db-pass", o.DBPassword, "the user password used to access database")
 	fs.StringVar(&o.DBHost, "db-host", o.DBHost, "database host")
 	fs.IntVar(&o.DBPort, "db-port", o.DBPort, "database port")
+	fs.DurationVar(&o.SlowQueryThreshold, "db-slow-query-threshold", o.SlowQueryThreshold,
+		"the duration beyond which a query is logged as a slow query, e.g. 200ms, disabled if zero")
 }
@@ -19,6 +19,7 @@ func NewServerOptions() *ServerOptions {
 		MaxConcurrent:      constant.MaxConcurrent,
 		MaxAsyncConcurrent: constant.MaxAsyncConcurrent,
 		MaxAsyncBuffer:     constant.MaxAsyncBuffer,
+		MaxRunTimeout:      constant.MaxRunTimeOut,
 		LogFilePath:        constant.DefaultLogFilePath,
 		DevPortalEnabled:   true,
 	}
@@ -42,8 +43,10 @@ func (o *ServerOptions) Config() (*server.Config, error) {
 	cfg.MaxConcurrent = o.MaxConcurrent
 	cfg.MaxAsyncConcurrent = o.MaxAsyncConcurrent
 	cfg.MaxAsyncBuffer = o.MaxAsyncBuffer
+	cfg.MaxRunTimeout = o.MaxRunTimeout
 	cfg.LogFilePath = o.LogFilePath
 	cfg.DevPortalEnabled = o.DevPortalEnabled
+	cfg.BackendOrgScoped = o.BackendOrgScoped
 	return cfg, nil
 }
 
@@ -55,10 +55,14 @@ func (o *ServerOptions) AddServerFlags(cmd *cobra.Command) {
 		i18n.T("Maximum number of buffer zones during concurrent async executions including generate, preview, apply and destroy. Default to 100."))
 	cmd.Flags().IntVarP(&o.MaxAsyncConcurrent, "max-async-concurrent", "", 10,
 		i18n.T("Maximum number of concurrent async executions including generate, preview, apply and destroy. Default to 10."))
+	cmd.Flags().DurationVarP(&o.MaxRunTimeout, "max-run-timeout", "", constant.MaxRunTimeOut,
+		i18n.T("Maximum timeout of the async executions, which bounds the timeout specified for each run. Default to 6h."))
 	cmd.Flags().StringVarP(&o.LogFilePath, "log-file-path", "", constant.DefaultLogFilePath,
 		i18n.T("File path to write logs to. Default to /home/admin/logs/kusion.log"))
 	cmd.Flags().BoolVarP(&o.DevPortalEnabled, "dev-portal-enabled", "d", true,
 		i18n.T("Enable dev portal. Default to true."))
+	cmd.Flags().BoolVarP(&o.BackendOrgScoped, "backend-org-scoped", "", false,
+		i18n.T("Scope backends to organizations so that each organization only sees its own backends and the global ones. Default to false."))
 	o.Database.AddFlags(cmd.Flags())
 	o.DefaultBackend.AddFlags(cmd.Flags())
 	o.DefaultSource.AddFlags(cmd.Flags())
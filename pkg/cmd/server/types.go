@@ -1,6 +1,8 @@
 package server
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 )
 
@@ -16,8 +18,10 @@ type ServerOptions struct {
 	MaxConcurrent      int
 	MaxAsyncConcurrent int
 	MaxAsyncBuffer     int
+	MaxRunTimeout      time.Duration
 	LogFilePath        string
 	DevPortalEnabled   bool
+	BackendOrgScoped   bool
 }
 
 type Options interface {
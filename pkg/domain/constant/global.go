@@ -21,6 +21,7 @@ const (
 	DefaultLogFilePath      = "/home/admin/logs/kusion.log"
 	RepoCacheTTL            = 60 * time.Minute
 	RunTimeOut              = 60 * time.Minute
+	MaxRunTimeOut           = 6 * time.Hour
 	DefaultWorkloadSig      = "kusion.io/is-workload"
 	ResourcePageDefault     = 1
 	ResourcePageSizeDefault = 100
@@ -16,4 +16,12 @@ const (
 	TmpDirPrefix            = "/tmp"
 )
 
-var ErrResourceHasNilStack = errors.New("resource has nil stack")
+var (
+	ErrResourceHasNilStack     = errors.New("resource has nil stack")
+	ErrUnsupportedScanProvider = errors.New("unsupported provider to scan resources, only aws is supported")
+	ErrEmptyScanRegion         = errors.New("region is required to scan resources")
+	ErrEmptyScanTagFilters     = errors.New("at least one tag filter is required to scan resources")
+	ErrEmptyScanTagKey         = errors.New("tag key of the resource filter must not be empty")
+	ErrEmptyScanWorkspace      = errors.New("workspace is required to scan resources with its credentials")
+	ErrScanCredentialsNotFound = errors.New("aws credentials are not configured in the context of the workspace")
+)
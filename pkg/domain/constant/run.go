@@ -8,20 +8,25 @@ type (
 )
 
 const (
-	RunTypeGenerate     RunType   = "Generate"
-	RunTypePreview      RunType   = "Preview"
-	RunTypeApply        RunType   = "Apply"
-	RunTypeDestroy      RunType   = "Destroy"
-	RunStatusScheduling RunStatus = "Scheduling"
-	RunStatusInProgress RunStatus = "InProgress"
-	RunStatusFailed     RunStatus = "Failed"
-	RunStatusSucceeded  RunStatus = "Succeeded"
-	RunStatusCancelled  RunStatus = "Cancelled"
-	RunStatusQueued     RunStatus = "Queued"
-	RunResultFailed     string    = "{\"result\":\"Operation Failed\"}"
-	RunResultCancelled  string    = "{\"result\":\"Operation Cancelled\"}"
+	RunTypeGenerate          RunType   = "Generate"
+	RunTypePreview           RunType   = "Preview"
+	RunTypeApply             RunType   = "Apply"
+	RunTypeDestroy           RunType   = "Destroy"
+	RunStatusScheduling      RunStatus = "Scheduling"
+	RunStatusInProgress      RunStatus = "InProgress"
+	RunStatusFailed          RunStatus = "Failed"
+	RunStatusSucceeded       RunStatus = "Succeeded"
+	RunStatusCancelled       RunStatus = "Cancelled"
+	RunStatusQueued          RunStatus = "Queued"
+	RunStatusWaitingApproval RunStatus = "WaitingApproval"
+	RunResultFailed          string    = "{\"result\":\"Operation Failed\"}"
+	RunResultCancelled       string    = "{\"result\":\"Operation Cancelled\"}"
+	RunResultConflict        string    = "{\"result\":\"Operation Aborted: Changes Conflict\"}"
 )
 
+// RunResultMessageCancelled is the message in the result of the cancelled run.
+const RunResultMessageCancelled = "Operation Cancelled"
+
 // ParseRunType parses a string into a RunType.
 // If the string is not a valid RunType, it returns an error.
 func ParseRunType(s string) (RunType, error) {
@@ -55,6 +60,8 @@ func ParseRunStatus(s string) (RunStatus, error) {
 		return RunStatusCancelled, nil
 	case strings.ToLower(string(RunStatusQueued)):
 		return RunStatusQueued, nil
+	case strings.ToLower(string(RunStatusWaitingApproval)):
+		return RunStatusWaitingApproval, nil
 	default:
 		return RunStatus(""), nil
 	}
@@ -19,6 +19,9 @@ type Backend struct {
 	BackendConfig v1.BackendConfig `yaml:"backendConfig" json:"backendConfig"`
 	// Description is a human-readable description of the backend.
 	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// OrganizationID is the id of the organization the backend is scoped to, zero
+	// means the backend is global and visible to all organizations.
+	OrganizationID uint `yaml:"organizationID,omitempty" json:"organizationID,omitempty"`
 	// CreationTimestamp is the timestamp of the created for the backend.
 	CreationTimestamp time.Time `yaml:"creationTimestamp,omitempty" json:"creationTimestamp,omitempty"`
 	// UpdateTimestamp is the timestamp of the updated for the backend.
@@ -26,6 +29,10 @@ type Backend struct {
 }
 
 type BackendFilter struct {
+	// OrgScoped indicates whether to only list the backends scoped to OrgID and the global ones.
+	OrgScoped  bool
+	OrgID      uint
+	Type       string
 	Pagination *Pagination
 }
 
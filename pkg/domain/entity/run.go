@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"time"
 
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
 )
 
 // Run represents the specific run, including type
@@ -26,12 +28,36 @@ type Run struct {
 	Trace string `yaml:"trace" json:"trace"`
 	// Logs is the logs of the run.
 	Logs string `yaml:"logs" json:"logs"`
+	// ResourceSummary is the summary of the resources generated by the run.
+	ResourceSummary *v1.ResourceSummary `yaml:"resourceSummary,omitempty" json:"resourceSummary,omitempty"`
+	// ChangeSummary is the breakdown of the previewed changes by the resource kind.
+	ChangeSummary []*models.KindChangeSummary `yaml:"changeSummary,omitempty" json:"changeSummary,omitempty"`
+	// Approvals records who approved the paused apply run to continue.
+	Approvals []*RunApproval `yaml:"approvals,omitempty" json:"approvals,omitempty"`
+	// PlanConsumed indicates the plan of the preview run has been applied, which cannot be applied again.
+	PlanConsumed bool `yaml:"planConsumed,omitempty" json:"planConsumed,omitempty"`
+	// QueuePosition is the position of the async run in the queue waiting for an available worker. It is only
+	// returned when the run is created and queued, and is not persisted.
+	QueuePosition int `yaml:"queuePosition,omitempty" json:"queuePosition,omitempty"`
+	// EstimatedWaitSeconds is the rough estimate of how long the queued run waits for an available worker.
+	// It is not persisted either.
+	EstimatedWaitSeconds int `yaml:"estimatedWaitSeconds,omitempty" json:"estimatedWaitSeconds,omitempty"`
 	// CreationTimestamp is the timestamp of the created for the run.
 	CreationTimestamp time.Time `yaml:"creationTimestamp,omitempty" json:"creationTimestamp,omitempty"`
 	// UpdateTimestamp is the timestamp of the updated for the run.
 	UpdateTimestamp time.Time `yaml:"updateTimestamp,omitempty" json:"updateTimestamp,omitempty"`
 }
 
+// RunApproval represents the approval of the apply run paused for the manual verification.
+type RunApproval struct {
+	// Approver is the user who approved the run to continue.
+	Approver string `yaml:"approver" json:"approver"`
+	// ResourceIDs are the IDs of the resources after which the run was paused.
+	ResourceIDs []string `yaml:"resourceIDs" json:"resourceIDs"`
+	// ApprovalTimestamp is the timestamp of the approval.
+	ApprovalTimestamp time.Time `yaml:"approvalTimestamp" json:"approvalTimestamp"`
+}
+
 // RunResult represents the result of the run.
 type RunResult struct {
 	// ExitCode is the exit code of the run.
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 
+	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
 )
 
@@ -147,6 +148,12 @@ type RunRepository interface {
 	Delete(ctx context.Context, id uint) error
 	// Update updates an existing run.
 	Update(ctx context.Context, run *entity.Run) error
+	// CompareAndUpdate updates the non-zero fields of the run only if its current status is the expected
+	// status, and reports whether the run is updated.
+	CompareAndUpdate(ctx context.Context, run *entity.Run, expectedStatus constant.RunStatus) (bool, error)
+	// ConsumePlan marks the plan of the preview run as consumed, and reports whether it
+	// has not been consumed by others before.
+	ConsumePlan(ctx context.Context, id uint) (bool, error)
 	// Get retrieves a run by its ID.
 	Get(ctx context.Context, id uint) (*entity.Run, error)
 	// List retrieves all existing run.
@@ -16,6 +16,9 @@ type CreateBackendRequest struct {
 	Description string `json:"description"`
 	// BackendConfig is the configuration of the backend.
 	BackendConfig v1.BackendConfig `json:"backendConfig" binding:"required"`
+	// OrganizationID is the id of the organization the backend is scoped to, leave it
+	// empty to create a global backend.
+	OrganizationID uint `json:"organizationID,omitempty"`
 }
 
 // UpdateBackendRequest represents the update request structure for
@@ -29,6 +32,8 @@ type UpdateBackendRequest struct {
 	Description string `json:"description"`
 	// BackendConfig is the configuration of the backend.
 	BackendConfig v1.BackendConfig `json:"backendConfig"`
+	// OrganizationID is the id of the organization the backend is scoped to.
+	OrganizationID uint `json:"organizationID,omitempty"`
 }
 
 func (payload *CreateBackendRequest) Validate() error {
@@ -2,6 +2,9 @@ package request
 
 import (
 	"net/http"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
 )
 
 type StackImportRequest struct {
@@ -17,6 +20,11 @@ type CreateRunRequest struct {
 	StackID           uint               `json:"stackID"`
 	Workspace         string             `json:"workspace"`
 	ImportedResources StackImportRequest `json:"importedResources"`
+	// Timeout is the timeout of the async run in seconds, which is bounded by the max run timeout of
+	// the server. The default run timeout is used if it is zero.
+	Timeout int `json:"timeout,omitempty"`
+	// Targets are the IDs of the resources to apply along with their dependencies, instead of the whole stack.
+	Targets []string `json:"targets,omitempty"`
 }
 
 type UpdateRunRequest struct {
@@ -24,11 +32,28 @@ type UpdateRunRequest struct {
 }
 
 type UpdateRunResultRequest struct {
-	Result string `json:"result"`
-	Status string `json:"status"`
-	Logs   string `json:"logs"`
+	Result          string                      `json:"result"`
+	Status          string                      `json:"status"`
+	Logs            string                      `json:"logs"`
+	ResourceSummary *v1.ResourceSummary         `json:"resourceSummary,omitempty"`
+	ChangeSummary   []*models.KindChangeSummary `json:"changeSummary,omitempty"`
 }
 
 func (payload *CreateRunRequest) Decode(r *http.Request) error {
 	return decode(r, payload)
 }
+
+// PatcherDryRunRequest is the request to dry run a patcher against a spec, which is either the result of a
+// succeeded generate run or given inline.
+type PatcherDryRunRequest struct {
+	// RunID is the ID of the succeeded generate run whose result is the spec to patch.
+	RunID uint `json:"runID,omitempty"`
+	// Spec is the inline spec to patch.
+	Spec *v1.Spec `json:"spec,omitempty"`
+	// Patcher is the patcher to dry run.
+	Patcher v1.Patcher `json:"patcher"`
+}
+
+func (payload *PatcherDryRunRequest) Decode(r *http.Request) error {
+	return decode(r, payload)
+}
@@ -0,0 +1,51 @@
+package request
+
+import (
+	"net/http"
+
+	"kusionstack.io/kusion/pkg/domain/constant"
+)
+
+// ScanImportedResourcesRequest represents the request structure for scanning the
+// existing resources of a provider to generate the importedResources mapping.
+type ScanImportedResourcesRequest struct {
+	// Provider is the type of the provider to scan, only aws is supported for now.
+	Provider string `json:"provider" binding:"required"`
+	// WorkspaceID is the ID of the workspace whose provider credentials in the context are
+	// used to scan the resources.
+	WorkspaceID uint `json:"workspaceID" binding:"required"`
+	// Region is the region of the provider to scan.
+	Region string `json:"region" binding:"required"`
+	// Tags are the tag filters of the resources to scan, resources matching all of
+	// the tags are selected. An empty tag value matches any value of the tag key.
+	Tags map[string]string `json:"tags" binding:"required"`
+}
+
+func (payload *ScanImportedResourcesRequest) Decode(r *http.Request) error {
+	return decode(r, payload)
+}
+
+func (payload *ScanImportedResourcesRequest) Validate() error {
+	if payload.Provider != constant.AWSProviderType {
+		return constant.ErrUnsupportedScanProvider
+	}
+
+	if payload.WorkspaceID == 0 {
+		return constant.ErrEmptyScanWorkspace
+	}
+
+	if payload.Region == "" {
+		return constant.ErrEmptyScanRegion
+	}
+
+	if len(payload.Tags) == 0 {
+		return constant.ErrEmptyScanTagFilters
+	}
+	for key := range payload.Tags {
+		if key == "" {
+			return constant.ErrEmptyScanTagKey
+		}
+	}
+
+	return nil
+}
@@ -10,3 +10,13 @@ type PaginatedResourceResponse struct {
 	CurrentPage int                `json:"currentPage"`
 	PageSize    int                `json:"pageSize"`
 }
+
+type ScanImportedResourcesResponse struct {
+	// ImportedResources maps the kusion resource IDs to the provider resource IDs, which
+	// can be used as the importedResources of the module configs in workspace.
+	ImportedResources map[string]string `json:"importedResources"`
+	Total             int               `json:"total"`
+	// Collisions maps the kusion resource IDs shared by multiple scanned resources to their
+	// provider resource IDs, which are left out of the ImportedResources.
+	Collisions map[string][]string `json:"collisions,omitempty"`
+}
@@ -1,6 +1,9 @@
 package response
 
-import "kusionstack.io/kusion/pkg/domain/entity"
+import (
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/entity"
+)
 
 type PaginatedStackResponse struct {
 	Stacks      []*entity.Stack `json:"stacks"`
@@ -8,3 +11,17 @@ type PaginatedStackResponse struct {
 	CurrentPage int             `json:"currentPage"`
 	PageSize    int             `json:"pageSize"`
 }
+
+// GenerateStackResponse is the generated spec along with the summary of the generated resources.
+type GenerateStackResponse struct {
+	Spec            string              `json:"spec"`
+	ResourceSummary *v1.ResourceSummary `json:"resourceSummary"`
+}
+
+// PatcherDryRunResponse is the resources of the spec after the patcher is applied, along with the unified
+// diffs of the changed resources keyed by the resource ID, and the parts of the patcher skipped.
+type PatcherDryRunResponse struct {
+	Resources v1.Resources      `json:"resources"`
+	Diffs     map[string]string `json:"diffs"`
+	Warnings  []string          `json:"warnings"`
+}
@@ -69,6 +69,9 @@ func Apply(
 			MsgCh:          make(chan models.Message),
 			IgnoreFields:   o.IgnoreFields,
 			Sem:            semaphore.New(int64(o.MaxConcurrent)),
+			PauseHook:      o.PauseHook,
+			ProgressHook:   o.ProgressHook,
+			Ctx:            ctx,
 		},
 	}
 
@@ -143,6 +146,9 @@ func Apply(
 			upRel = rsp.Release
 		}
 		if v1.IsErr(st) {
+			// Wait for the messages to be processed, so that the progress of the failed or cancelled apply is
+			// reported completely
+			wg.Wait()
 			return nil, fmt.Errorf("apply failed, status:\n%v", st)
 		}
 	}
@@ -362,6 +368,8 @@ func ProcessApplyDetails(
 				if changeStep.Action == models.UnChanged {
 					title = fmt.Sprintf("Skipped %s", changeStep.ID)
 					logutil.LogToAll(sysLogger, runLogger, "Info", title)
+				} else if msg.Retries > 0 {
+					logutil.LogToAll(sysLogger, runLogger, "Info", fmt.Sprintf("Succeeded %s after %d retries", msg.ResourceID, msg.Retries))
 				} else {
 					logutil.LogToAll(sysLogger, runLogger, "Info", fmt.Sprintf("Succeeded %s", msg.ResourceID))
 				}
@@ -382,6 +390,9 @@ func ProcessApplyDetails(
 				ls.Count(changeStep.Action)
 			case models.Failed:
 				errStr := pretty.ErrorT.Sprintf("apply %s failed as: %s\n", msg.ResourceID, msg.OpErr.Error())
+				if msg.Retries > 0 {
+					errStr = pretty.ErrorT.Sprintf("apply %s failed after %d retries as: %s\n", msg.ResourceID, msg.Retries, msg.OpErr.Error())
+				}
 				logutil.LogToAll(sysLogger, runLogger, "Error", errStr)
 				if !dryRun {
 					// Update resource status, in case anything like update fail happened
@@ -397,6 +408,9 @@ func ProcessApplyDetails(
 				)
 				logutil.LogToAll(sysLogger, runLogger, "Info", title)
 			}
+			if ac.ProgressHook != nil {
+				ac.ProgressHook(msg)
+			}
 		}
 	}
 }
@@ -65,6 +65,29 @@ func TestApply(t *testing.T) {
 		_, err := Apply(context.TODO(), o, &releasestorages.LocalStorage{}, rel, &apiv1.Graph{}, changes, os.Stdout)
 		assert.Nil(t, err)
 	})
+	mockey.PatchConvey("progress hook", t, func() {
+		rel := mockApplyRelease([]apiv1.Resource{sa1})
+		order := &models.ChangeOrder{
+			StepKeys: []string{sa1.ID},
+			ChangeSteps: map[string]*models.ChangeStep{
+				sa1.ID: {
+					ID:     sa1.ID,
+					Action: models.Create,
+					From:   sa1,
+				},
+			},
+		}
+		changes := models.NewChanges(proj, stack, order)
+		var progress []models.Message
+		o := &APIOptions{}
+		o.DryRun = true
+		o.ProgressHook = func(msg models.Message) {
+			progress = append(progress, msg)
+		}
+		_, err := Apply(context.TODO(), o, &releasestorages.LocalStorage{}, rel, &apiv1.Graph{}, changes, os.Stdout)
+		assert.Nil(t, err)
+		assert.Equal(t, []models.Message{{ResourceID: sa1.ID, OpResult: models.Success}}, progress)
+	})
 	mockey.PatchConvey("apply success", t, func() {
 		mockOperationApply(models.Success)
 		o := &APIOptions{}
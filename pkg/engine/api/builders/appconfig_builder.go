@@ -15,40 +15,126 @@
 package builders
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"kcl-lang.io/kpm/pkg/api"
 
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/generators"
 	"kusionstack.io/kusion/pkg/generators/appconfiguration"
+	"kusionstack.io/kusion/pkg/generators/orderedresources"
+	"kusionstack.io/kusion/pkg/generators/rename"
+	"kusionstack.io/kusion/pkg/log"
 )
 
 type AppsConfigBuilder struct {
 	Apps      map[string]v1.AppConfiguration
 	Workspace *v1.Workspace
+
+	// WorkloadOnly indicates whether to generate only the namespace and workload of the apps.
+	WorkloadOnly bool
+	// Summary records the number of resources generated for each app if not nil.
+	Summary *v1.ResourceSummary
+	// NonStrict continues generating the apps past the failures of the built-in generators, which are
+	// collected into the Errors.
+	NonStrict bool
+	// Errors are the failures of the built-in generators skipped in the NonStrict mode.
+	Errors []error
+	// StrictSecretStore fails the generation if the workloads reference the external secrets while no
+	// secret store is configured for them, which is only warned by default.
+	StrictSecretStore bool
+	// Context aborts the generation of the apps once it is done if not nil, e.g. the server request of
+	// the generation is cancelled.
+	Context context.Context
+	// Objects reads the objects referenced by the module configs from the backend if not nil.
+	Objects appconfiguration.ObjectReader
+	// Renames renames the Kubernetes resources generated by the apps, along with the references to them.
+	Renames []rename.Rename
 }
 
-func (acg *AppsConfigBuilder) Build(kclPackage *api.KclPackage, project *v1.Project, stack *v1.Stack) (*v1.Spec, error) {
+func (acg *AppsConfigBuilder) Build(kclPackage *api.KclPackage, project *v1.Project, stack *v1.Stack) (_ *v1.Spec, err error) {
 	i := &v1.Spec{
 		Resources: []v1.Resource{},
 	}
 
+	// share the module plugins among the apps, and kill them once the whole build is done
+	plugins := appconfiguration.NewPluginCache()
+	defer func() {
+		if pluginErr := plugins.Close(); pluginErr != nil {
+			err = errors.Join(err, fmt.Errorf("kill modules failed: %w", pluginErr))
+		}
+	}()
+
 	var gfs []generators.NewSpecGeneratorFunc
-	err := generators.ForeachOrdered(acg.Apps, func(appName string, app v1.AppConfiguration) error {
+	var unmatchedImports []*[]string
+	err = generators.ForeachOrdered(acg.Apps, func(appName string, app v1.AppConfiguration) error {
 		if kclPackage == nil {
 			return fmt.Errorf("kcl package is nil when generating app configuration for %s", appName)
 		}
 		dependencies := kclPackage.GetDependenciesInModFile()
-		gfs = append(gfs, appconfiguration.NewAppConfigurationGeneratorFunc(project, stack, appName, &app, acg.Workspace, dependencies))
+		opts := appconfiguration.GeneratorOptions{
+			WorkloadOnly:      acg.WorkloadOnly,
+			NonStrict:         acg.NonStrict,
+			Errors:            &acg.Errors,
+			Plugins:           plugins,
+			Objects:           acg.Objects,
+			StrictSecretStore: acg.StrictSecretStore,
+			Renames:           acg.Renames,
+		}
+		unmatched := &[]string{}
+		unmatchedImports = append(unmatchedImports, unmatched)
+		opts.UnmatchedImports = unmatched
+		if acg.Summary != nil {
+			opts.Summary = &v1.AppResourceSummary{Name: appName}
+			acg.Summary.Apps = append(acg.Summary.Apps, opts.Summary)
+		}
+		gfs = append(gfs, appconfiguration.NewAppConfigurationGeneratorFuncWithOptions(project, stack, appName, &app, acg.Workspace, dependencies, opts))
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if err = generators.CallGenerators(i, gfs...); err != nil {
+	ctx := acg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err = generators.CallGeneratorsWithContext(ctx, i, gfs...); err != nil {
+		return nil, err
+	}
+	// the explicit dependencies may refer to the resources of any app, thus resolved once all the apps are generated
+	if err = orderedresources.ResolveDependsOn(i.Resources); err != nil {
 		return nil, err
 	}
+	// the imported resources are shared by the apps of the project, thus checked once all the apps are generated
+	if unmatched := unmatchedByAll(unmatchedImports); len(unmatched) > 0 {
+		log.Warnf("imported resources of project %s match no resource, please check the kusion IDs: %s",
+			project.Name, strings.Join(unmatched, ", "))
+	}
+	if acg.Summary != nil {
+		acg.Summary.Total = len(i.Resources)
+	}
 
 	return i, nil
 }
+
+// unmatchedByAll returns the sorted kusion IDs of the imported resources unmatched by all the apps.
+func unmatchedByAll(unmatchedImports []*[]string) []string {
+	counts := make(map[string]int)
+	for _, ids := range unmatchedImports {
+		for _, id := range *ids {
+			counts[id]++
+		}
+	}
+	var unmatched []string
+	for id, count := range counts {
+		if count == len(unmatchedImports) {
+			unmatched = append(unmatched, id)
+		}
+	}
+	sort.Strings(unmatched)
+	return unmatched
+}
@@ -15,6 +15,7 @@
 package builders
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -25,6 +26,7 @@ import (
 
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/generators"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
 )
 
 func TestBuild(t *testing.T) {
@@ -52,6 +54,46 @@ func TestBuild(t *testing.T) {
 	assert.NotNil(t, intent)
 }
 
+func TestBuild_SharedPluginCache(t *testing.T) {
+	p, s := buildMockProjectAndStack()
+	_, app := buildMockApp()
+	acg := &AppsConfigBuilder{
+		Apps: map[string]v1.AppConfiguration{
+			"app1": *app,
+			"app2": *app,
+		},
+		Workspace: buildMockWorkspace(),
+	}
+
+	closed := 0
+	callMock := mockey.Mock(generators.CallGenerators).Return(nil).Build()
+	closeMock := mockey.Mock((*appconfiguration.PluginCache).Close).To(func(_ *appconfiguration.PluginCache) error {
+		closed++
+		return errors.New("kill failed")
+	}).Build()
+	defer func() {
+		callMock.UnPatch()
+		closeMock.UnPatch()
+	}()
+
+	cwd, _ := os.Getwd()
+	kclPkg, err := api.GetKclPackage(filepath.Join(cwd, "testdata"))
+	assert.NoError(t, err)
+
+	_, err = acg.Build(kclPkg, p, s)
+	assert.ErrorContains(t, err, "kill modules failed: kill failed")
+	assert.Equal(t, 1, closed)
+}
+
+func TestUnmatchedByAll(t *testing.T) {
+	// the resource imported by app2 is unmatched in the generation of app1 only
+	app1 := []string{"v1:ConfigMap:default:app2", "v1:ConfigMap:default:typo"}
+	app2 := []string{"v1:ConfigMap:default:typo"}
+	assert.Equal(t, []string{"v1:ConfigMap:default:typo"}, unmatchedByAll([]*[]string{&app1, &app2}))
+	assert.Empty(t, unmatchedByAll([]*[]string{&app1, {}}))
+	assert.Empty(t, unmatchedByAll(nil))
+}
+
 func buildMockApp() (string, *v1.AppConfiguration) {
 	return "app1", &v1.AppConfiguration{
 		Workload: map[string]interface{}{
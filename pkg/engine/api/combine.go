@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// StackSpec is the generated Spec of a stack.
+type StackSpec struct {
+	// Stack is the name of the stack.
+	Stack string
+	// Spec is the Spec generated for the stack.
+	Spec *v1.Spec
+}
+
+// CombinedSpec is the Spec combined across multiple stacks of a project.
+type CombinedSpec struct {
+	// Spec contains the deduplicated resources of all the stacks.
+	Spec *v1.Spec `yaml:"spec" json:"spec"`
+	// Provenance maps the resource ID to the names of the stacks generating the resource,
+	// a resource shared by multiple stacks (e.g. the namespace) has more than one stack.
+	Provenance map[string][]string `yaml:"provenance" json:"provenance"`
+}
+
+// GenerateCombinedSpec generates the Specs of the given stacks of the project in the workspace,
+// and combines them into one Spec by CombineSpecs.
+func GenerateCombinedSpec(project *v1.Project, stacks []*v1.Stack, workspace *v1.Workspace) (*CombinedSpec, error) {
+	specs := make([]StackSpec, 0, len(stacks))
+	for _, stack := range stacks {
+		spec, _, err := GenerateSpecWithSpinner(project, stack, workspace, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("generate spec of stack %s failed: %w", stack.Name, err)
+		}
+		specs = append(specs, StackSpec{Stack: stack.Name, Spec: spec})
+	}
+	return CombineSpecs(specs)
+}
+
+// CombineSpecs merges the Specs of multiple stacks into one Spec. The resources are kept in
+// the order of the stacks and then the order within each Spec. The identical resources shared
+// by multiple stacks are deduplicated, while the different resources with the same ID in
+// different stacks are reported as collisions.
+func CombineSpecs(specs []StackSpec) (*CombinedSpec, error) {
+	combined := &CombinedSpec{
+		Spec:       &v1.Spec{Resources: v1.Resources{}},
+		Provenance: make(map[string][]string),
+	}
+
+	// index records the position of the resource in the combined Spec
+	index := make(map[string]int)
+	var collisions []string
+	for _, s := range specs {
+		if s.Spec == nil {
+			continue
+		}
+		for _, res := range s.Spec.Resources {
+			idx, ok := index[res.ID]
+			if !ok {
+				index[res.ID] = len(combined.Spec.Resources)
+				combined.Spec.Resources = append(combined.Spec.Resources, res)
+				combined.Provenance[res.ID] = []string{s.Stack}
+				continue
+			}
+			if !reflect.DeepEqual(combined.Spec.Resources[idx], res) {
+				collisions = append(collisions, fmt.Sprintf("resource %s is generated differently by stacks %s and %s",
+					res.ID, strings.Join(combined.Provenance[res.ID], ","), s.Stack))
+				continue
+			}
+			combined.Provenance[res.ID] = append(combined.Provenance[res.ID], s.Stack)
+		}
+
+		// the stacks in the same workspace should share the same secret store and context
+		if s.Spec.SecretStore != nil {
+			if combined.Spec.SecretStore != nil && !reflect.DeepEqual(combined.Spec.SecretStore, s.Spec.SecretStore) {
+				collisions = append(collisions, fmt.Sprintf("secret store of stack %s conflicts with the other stacks", s.Stack))
+			} else {
+				combined.Spec.SecretStore = s.Spec.SecretStore
+			}
+		}
+		names := make([]string, 0, len(s.Spec.SecretStores))
+		for name := range s.Spec.SecretStores {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			store := s.Spec.SecretStores[name]
+			if existing, ok := combined.Spec.SecretStores[name]; ok && !reflect.DeepEqual(existing, store) {
+				collisions = append(collisions, fmt.Sprintf("secret store %s of stack %s conflicts with the other stacks", name, s.Stack))
+				continue
+			}
+			if combined.Spec.SecretStores == nil {
+				combined.Spec.SecretStores = make(map[string]*v1.SecretStore)
+			}
+			combined.Spec.SecretStores[name] = store
+		}
+		if s.Spec.Context != nil {
+			if combined.Spec.Context != nil && !reflect.DeepEqual(combined.Spec.Context, s.Spec.Context) {
+				collisions = append(collisions, fmt.Sprintf("context of stack %s conflicts with the other stacks", s.Stack))
+			} else {
+				combined.Spec.Context = s.Spec.Context
+			}
+		}
+	}
+
+	if len(collisions) != 0 {
+		return nil, errors.New("failed to combine specs: " + strings.Join(collisions, "; "))
+	}
+	return combined, nil
+}
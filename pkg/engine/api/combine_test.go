@@ -0,0 +1,63 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func mockCombineResource(id, value string) apiv1.Resource {
+	return apiv1.Resource{
+		ID:         id,
+		Type:       apiv1.Kubernetes,
+		Attributes: map[string]interface{}{"value": value},
+	}
+}
+
+func TestCombineSpecs(t *testing.T) {
+	ns := mockCombineResource("v1:Namespace:foo", "foo")
+
+	t.Run("combine with shared resources", func(t *testing.T) {
+		combined, err := CombineSpecs([]StackSpec{
+			{
+				Stack: "dev",
+				Spec: &apiv1.Spec{Resources: apiv1.Resources{
+					ns,
+					mockCombineResource("apps/v1:Deployment:foo:dev", "dev"),
+				}},
+			},
+			{
+				Stack: "pre",
+				Spec: &apiv1.Spec{Resources: apiv1.Resources{
+					ns,
+					mockCombineResource("apps/v1:Deployment:foo:pre", "pre"),
+				}},
+			},
+		})
+		assert.NoError(t, err)
+
+		var ids []string
+		for _, res := range combined.Spec.Resources {
+			ids = append(ids, res.ID)
+		}
+		assert.Equal(t, []string{"v1:Namespace:foo", "apps/v1:Deployment:foo:dev", "apps/v1:Deployment:foo:pre"}, ids)
+		assert.Equal(t, []string{"dev", "pre"}, combined.Provenance["v1:Namespace:foo"])
+		assert.Equal(t, []string{"pre"}, combined.Provenance["apps/v1:Deployment:foo:pre"])
+	})
+
+	t.Run("report cross-stack id collisions", func(t *testing.T) {
+		_, err := CombineSpecs([]StackSpec{
+			{
+				Stack: "dev",
+				Spec:  &apiv1.Spec{Resources: apiv1.Resources{mockCombineResource("v1:ConfigMap:foo:bar", "dev")}},
+			},
+			{
+				Stack: "pre",
+				Spec:  &apiv1.Spec{Resources: apiv1.Resources{mockCombineResource("v1:ConfigMap:foo:bar", "pre")}},
+			},
+		})
+		assert.ErrorContains(t, err, "resource v1:ConfigMap:foo:bar is generated differently by stacks dev and pre")
+	})
+}
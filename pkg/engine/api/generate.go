@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -14,13 +15,17 @@ import (
 	"kusionstack.io/kusion/pkg/engine"
 	"kusionstack.io/kusion/pkg/engine/api/generate/generator"
 	"kusionstack.io/kusion/pkg/engine/api/generate/run"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
 
 	// "kusionstack.io/kusion/pkg/engine/api/builders/kcl"
 
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
 
-const JSONOutput = "json"
+const (
+	JSONOutput    = "json"
+	SummaryOutput = "summary"
+)
 
 // TODO: This switch logic may still be needed for KCL Builder
 // func Intent(o *builders.Options, p *v1.Project, s *v1.Stack, ws *v1.Workspace) (*v1.Intent, error) {
@@ -60,14 +65,62 @@ const JSONOutput = "json"
 // 	return i, nil
 // }
 
-// GenerateSpecWithSpinner calls generator to generate versioned Spec. Add a method wrapper for testing purposes.
-func GenerateSpecWithSpinner(project *v1.Project, stack *v1.Stack, workspace *v1.Workspace, noStyle bool) (*v1.Spec, error) {
+// GenerateSpecWithSpinner calls generator to generate versioned Spec along with the summary of the
+// generated resources. Add a method wrapper for testing purposes.
+// If workloadOnly is true, only the namespace and workload of the apps will be generated.
+func GenerateSpecWithSpinner(project *v1.Project, stack *v1.Stack, workspace *v1.Workspace, noStyle, workloadOnly bool) (*v1.Spec, *v1.ResourceSummary, error) {
+	return GenerateSpecWithContext(context.Background(), project, stack, workspace, noStyle, workloadOnly)
+}
+
+// GenerateSpecWithContext is the same as GenerateSpecWithSpinner, but aborts the generation of the apps
+// once the ctx is done, e.g. the server request of the generation is cancelled.
+func GenerateSpecWithContext(
+	ctx context.Context,
+	project *v1.Project,
+	stack *v1.Stack,
+	workspace *v1.Workspace,
+	noStyle, workloadOnly bool,
+) (*v1.Spec, *v1.ResourceSummary, error) {
+	return GenerateSpecWithOptions(ctx, project, stack, workspace, noStyle, GenerateOptions{WorkloadOnly: workloadOnly})
+}
+
+// GenerateOptions are the optional settings of generating the Spec.
+type GenerateOptions struct {
+	// WorkloadOnly skips the generation of accessories, which is useful for debugging the workload module.
+	WorkloadOnly bool
+	// Objects reads the objects referenced by the module configs from the backend if not nil.
+	Objects appconfiguration.ObjectReader
+	// StrictSecretStore fails the generation on the external secrets without a secret store.
+	StrictSecretStore bool
+	// NonStrict continues the generation past the failures of the built-in generators.
+	NonStrict bool
+	// Errors collects the failures of the built-in generators skipped in the NonStrict mode if not nil.
+	Errors *[]error
+}
+
+// GenerateSpecWithOptions is the same as GenerateSpecWithContext, but generates the Spec with the options.
+func GenerateSpecWithOptions(
+	ctx context.Context,
+	project *v1.Project,
+	stack *v1.Stack,
+	workspace *v1.Workspace,
+	noStyle bool,
+	opts GenerateOptions,
+) (*v1.Spec, *v1.ResourceSummary, error) {
 	// Construct generator instance
+	summary := &v1.ResourceSummary{}
 	defaultGenerator := &generator.DefaultGenerator{
-		Project:   project,
-		Stack:     stack,
-		Workspace: workspace,
-		Runner:    &run.KPMRunner{},
+		Project:           project,
+		Stack:             stack,
+		Workspace:         workspace,
+		Runner:            &run.KPMRunner{},
+		WorkloadOnly:      opts.WorkloadOnly,
+		Summary:           summary,
+		Context:           ctx,
+		Objects:           opts.Objects,
+		StrictSecretStore: opts.StrictSecretStore,
+		NonStrict:         opts.NonStrict,
+		Errors:            opts.Errors,
 	}
 
 	var sp *pterm.SpinnerPrinter
@@ -85,15 +138,15 @@ func GenerateSpecWithSpinner(project *v1.Project, stack *v1.Stack, workspace *v1
 	if err != nil {
 		if style {
 			sp.Fail()
-			return nil, err
+			return nil, nil, err
 		} else {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	err = ValidateSpec(versionedSpec)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// success
@@ -103,7 +156,7 @@ func GenerateSpecWithSpinner(project *v1.Project, stack *v1.Stack, workspace *v1
 		fmt.Println()
 	}
 
-	return versionedSpec, nil
+	return versionedSpec, summary, nil
 }
 
 func SpecFromFile(filePath string) (*v1.Spec, error) {
@@ -15,6 +15,7 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -29,6 +30,7 @@ import (
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/engine/api/builders"
 	"kusionstack.io/kusion/pkg/engine/api/generate/run"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
 	"kusionstack.io/kusion/pkg/util/io"
 	"kusionstack.io/kusion/pkg/util/kfile"
 )
@@ -46,6 +48,22 @@ type DefaultGenerator struct {
 	Stack     *v1.Stack
 	Workspace *v1.Workspace
 	Runner    run.CodeRunner
+
+	// WorkloadOnly skips the generation of accessories, which is useful for debugging the workload module.
+	WorkloadOnly bool
+	// Summary records the number of resources generated for each app if not nil.
+	Summary *v1.ResourceSummary
+	// Context aborts the generation of the apps once it is done if not nil.
+	Context context.Context
+	// Objects reads the objects referenced by the module configs from the backend if not nil.
+	Objects appconfiguration.ObjectReader
+	// StrictSecretStore fails the generation on the external secrets without a secret store.
+	StrictSecretStore bool
+	// NonStrict continues the generation past the failures of the built-in generators, e.g. an invalid
+	// namespace, so that the partial Spec can be used for debugging.
+	NonStrict bool
+	// Errors collects the failures of the built-in generators skipped in the NonStrict mode if not nil.
+	Errors *[]error
 }
 
 // Generate versioned Spec with target code runner.
@@ -79,10 +97,20 @@ func (g *DefaultGenerator) Generate(workDir string, params map[string]string) (*
 	}
 
 	builder := &builders.AppsConfigBuilder{
-		Workspace: g.Workspace,
-		Apps:      apps,
+		Workspace:         g.Workspace,
+		Apps:              apps,
+		WorkloadOnly:      g.WorkloadOnly,
+		Summary:           g.Summary,
+		Context:           g.Context,
+		Objects:           g.Objects,
+		StrictSecretStore: g.StrictSecretStore,
+		NonStrict:         g.NonStrict,
+	}
+	spec, err := builder.Build(kclPkg, g.Project, g.Stack)
+	if g.Errors != nil {
+		*g.Errors = builder.Errors
 	}
-	return builder.Build(kclPkg, g.Project, g.Stack)
+	return spec, err
 }
 
 // CopyDependentModules copies dependent Kusion modules' generators to destination.
@@ -22,6 +22,10 @@ type APIOptions struct {
 	MaxConcurrent int
 	Watch         bool
 	WatchTimeout  int
+	// PauseHook blocks the apply after the resource marked with the apply-pause extension is applied
+	PauseHook func(resourceID string) error
+	// ProgressHook reports the apply result of every resource
+	ProgressHook func(msg opsmodels.Message)
 }
 
 func NewAPIOptions() APIOptions {
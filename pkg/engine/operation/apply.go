@@ -108,6 +108,7 @@ func (ao *ApplyOperation) Apply(req *ApplyRequest) (rsp *ApplyResponse, s v1.Sta
 			OperationType:           models.Apply,
 			ReleaseStorage:          o.ReleaseStorage,
 			SecretStore:             req.Release.Spec.SecretStore,
+			SecretStores:            req.Release.Spec.SecretStores,
 			CtxResourceIndex:        map[string]*apiv1.Resource{},
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      stateResourceIndex,
@@ -119,6 +120,8 @@ func (ao *ApplyOperation) Apply(req *ApplyRequest) (rsp *ApplyResponse, s v1.Sta
 			Lock:                    &sync.Mutex{},
 			Release:                 rel,
 			Sem:                     o.Sem,
+			PauseHook:               o.PauseHook,
+			Ctx:                     o.Ctx,
 		},
 	}
 
@@ -190,6 +193,11 @@ func applyWalkFun(o *models.Operation, v dag.Vertex) (diags tfdiags.Diagnostics)
 		defer o.Sem.Release()
 	}
 
+	// Do not start applying any more resources once the operation is cancelled
+	if err := o.Context().Err(); err != nil {
+		return diags.Append(fmt.Errorf("apply cancelled: %w", err))
+	}
+
 	if node, ok := v.(graph.ExecutableNode); ok {
 		if rn, ok2 := v.(*graph.ResourceNode); ok2 {
 			o.MsgCh <- models.Message{ResourceID: rn.Hashcode().(string)}
@@ -198,10 +206,10 @@ func applyWalkFun(o *models.Operation, v dag.Vertex) (diags tfdiags.Diagnostics)
 			if v1.IsErr(s) {
 				o.MsgCh <- models.Message{
 					ResourceID: rn.Hashcode().(string), OpResult: models.Failed,
-					OpErr: fmt.Errorf("node execte failed, status:\n%v", s),
+					OpErr: fmt.Errorf("node execte failed, status:\n%v", s), Retries: rn.Retries,
 				}
 			} else {
-				o.MsgCh <- models.Message{ResourceID: rn.Hashcode().(string), OpResult: models.Success}
+				o.MsgCh <- models.Message{ResourceID: rn.Hashcode().(string), OpResult: models.Success, Retries: rn.Retries}
 			}
 		} else {
 			s = node.Execute(o)
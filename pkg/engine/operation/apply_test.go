@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"context"
 	"reflect"
 	"sync"
 	"testing"
@@ -275,3 +276,21 @@ func Test_populateResourceGraph(t *testing.T) {
 		})
 	}
 }
+
+func Test_applyWalkFun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o := &models.Operation{
+		OperationType: models.Apply,
+		MsgCh:         make(chan models.Message, 2),
+		Ctx:           ctx,
+	}
+	rn, s := graph.NewResourceNode("mock-id", &apiv1.Resource{ID: "mock-id", Type: runtime.Kubernetes}, models.Create)
+	assert.Nil(t, s)
+
+	// The resource node is not executed once the operation is cancelled
+	diags := applyWalkFun(o, rn)
+	assert.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Err().Error(), "apply cancelled")
+	assert.Empty(t, o.MsgCh)
+}
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+var (
+	immutableFieldsLock sync.RWMutex
+	// immutableFields maps the GroupKind of the Kubernetes resources to the paths of their immutable
+	// fields, changing which fails the apply and requires the resource to be replaced.
+	immutableFields = map[string][]string{
+		"Service":               {"spec.clusterIP"},
+		"PersistentVolumeClaim": {"spec.storageClassName", "spec.accessModes", "spec.volumeMode", "spec.volumeName"},
+		"Deployment.apps":       {"spec.selector"},
+		"DaemonSet.apps":        {"spec.selector"},
+		"ReplicaSet.apps":       {"spec.selector"},
+		"StatefulSet.apps":      {"spec.selector", "spec.serviceName", "spec.podManagementPolicy", "spec.volumeClaimTemplates"},
+		"Job.batch":             {"spec.selector", "spec.template", "spec.completionMode"},
+	}
+)
+
+// RegisterImmutableFields registers the paths of the immutable fields of the Kubernetes resources with
+// the GroupKind, e.g. RegisterImmutableFields("Foo.example.com", "spec.bar"). The paths are dot-separated.
+func RegisterImmutableFields(groupKind string, paths ...string) {
+	immutableFieldsLock.Lock()
+	defer immutableFieldsLock.Unlock()
+	for _, path := range paths {
+		if !containsString(immutableFields[groupKind], path) {
+			immutableFields[groupKind] = append(immutableFields[groupKind], path)
+		}
+	}
+}
+
+// immutableFieldChanges returns the warnings of the immutable fields of the Kubernetes resource changed by
+// the planed resource. Only the fields set in the planed resource are compared, so the fields defaulted
+// by the server in the live resource are not reported.
+func immutableFieldChanges(planed, live *apiv1.Resource) []string {
+	if planed == nil || live == nil || planed.Type != apiv1.Kubernetes {
+		return nil
+	}
+	un := &unstructured.Unstructured{Object: planed.Attributes}
+	gk := schema.FromAPIVersionAndKind(un.GetAPIVersion(), un.GetKind()).GroupKind()
+
+	immutableFieldsLock.RLock()
+	paths := immutableFields[gk.String()]
+	immutableFieldsLock.RUnlock()
+
+	var warnings []string
+	for _, path := range paths {
+		fields := strings.Split(path, ".")
+		plannedValue, found, err := unstructured.NestedFieldNoCopy(planed.Attributes, fields...)
+		if err != nil || !found {
+			continue
+		}
+		liveValue, found, err := unstructured.NestedFieldNoCopy(live.Attributes, fields...)
+		if err != nil || !found {
+			continue
+		}
+		if !isSubset(normalize(plannedValue), normalize(liveValue)) {
+			warnings = append(warnings, fmt.Sprintf("field %s of %s is immutable, this change requires resource replacement", path, planed.ID))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// normalize converts the value to the generic JSON types, e.g. the integers are converted to float64.
+func normalize(value interface{}) interface{} {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err = json.Unmarshal(bytes, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// isSubset reports whether the planned value is contained in the live value, where the maps in the live
+// value may have more keys than the planned ones.
+func isSubset(planned, live interface{}) bool {
+	switch p := planned.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range p {
+			if !isSubset(v, l[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(p) != len(l) {
+			return false
+		}
+		for i := range p {
+			if !isSubset(p[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(planned, live)
+	}
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestImmutableFieldChanges(t *testing.T) {
+	service := func(spec map[string]interface{}) *apiv1.Resource {
+		return &apiv1.Resource{
+			ID:   "v1:Service:foo:bar",
+			Type: apiv1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"spec":       spec,
+			},
+		}
+	}
+	statefulSet := func(spec map[string]interface{}) *apiv1.Resource {
+		return &apiv1.Resource{
+			ID:   "apps/v1:StatefulSet:foo:bar",
+			Type: apiv1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "StatefulSet",
+				"spec":       spec,
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		planed *apiv1.Resource
+		live   *apiv1.Resource
+		want   []string
+	}{
+		{
+			name:   "cluster ip changed",
+			planed: service(map[string]interface{}{"clusterIP": "10.0.0.1"}),
+			live:   service(map[string]interface{}{"clusterIP": "10.0.0.2"}),
+			want:   []string{"field spec.clusterIP of v1:Service:foo:bar is immutable, this change requires resource replacement"},
+		},
+		{
+			name:   "cluster ip not set in plan",
+			planed: service(map[string]interface{}{"type": "ClusterIP"}),
+			live:   service(map[string]interface{}{"type": "ClusterIP", "clusterIP": "10.0.0.2"}),
+		},
+		{
+			name: "defaulted fields in live",
+			planed: statefulSet(map[string]interface{}{
+				"serviceName":          "bar",
+				"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+			}),
+			live: statefulSet(map[string]interface{}{
+				"serviceName":         "bar",
+				"podManagementPolicy": "OrderedReady",
+				"volumeClaimTemplates": []interface{}{map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "data"},
+					"spec":     map[string]interface{}{"volumeMode": "Filesystem"},
+				}},
+			}),
+		},
+		{
+			name: "volume claim templates changed",
+			planed: statefulSet(map[string]interface{}{
+				"replicas":             int64(2),
+				"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "logs"}}},
+			}),
+			live: statefulSet(map[string]interface{}{
+				"replicas":             int64(1),
+				"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+			}),
+			want: []string{"field spec.volumeClaimTemplates of apps/v1:StatefulSet:foo:bar is immutable, this change requires resource replacement"},
+		},
+		{
+			name:   "non kubernetes resource",
+			planed: &apiv1.Resource{ID: "hashicorp:aws:aws_s3_bucket:foo", Type: apiv1.Terraform},
+			live:   &apiv1.Resource{ID: "hashicorp:aws:aws_s3_bucket:foo", Type: apiv1.Terraform},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, immutableFieldChanges(tt.planed, tt.live))
+		})
+	}
+}
+
+func TestRegisterImmutableFields(t *testing.T) {
+	RegisterImmutableFields("Foo.example.com", "spec.bar", "spec.bar")
+	defer func() {
+		immutableFieldsLock.Lock()
+		delete(immutableFields, "Foo.example.com")
+		immutableFieldsLock.Unlock()
+	}()
+
+	foo := func(bar string) *apiv1.Resource {
+		return &apiv1.Resource{
+			ID:   "example.com/v1:Foo:foo:bar",
+			Type: apiv1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Foo",
+				"spec":       map[string]interface{}{"bar": bar},
+			},
+		}
+	}
+	assert.Equal(t, []string{"spec.bar"}, immutableFields["Foo.example.com"])
+	assert.Len(t, immutableFieldChanges(foo("a"), foo("b")), 1)
+	assert.Empty(t, immutableFieldChanges(foo("a"), foo("a")))
+}
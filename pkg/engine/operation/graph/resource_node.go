@@ -25,10 +25,23 @@ import (
 	"kusionstack.io/kusion/pkg/util/json"
 )
 
+// kubernetesManagedFields are the fields of the Kubernetes resources managed by the API server, which are always
+// ignored when computing the changes of the Kubernetes resources.
+var kubernetesManagedFields = []string{
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"metadata.creationTimestamp",
+	"metadata.uid",
+	"status",
+}
+
 type ResourceNode struct {
 	*baseNode
 	Action   models.ActionType
 	resource *apiv1.Resource
+	// Retries is the number of retries of applying the resource
+	Retries int
 }
 
 var _ ExecutableNode = (*ResourceNode)(nil)
@@ -98,6 +111,11 @@ func (rn *ResourceNode) replaceK8sSecretRefs(o *models.Operation) v1.Status {
 		return v1.NewErrorStatus(err)
 	}
 
+	secretStoreSpec, err := rn.designatedSecretStore(o)
+	if err != nil {
+		return v1.NewErrorStatus(err)
+	}
+
 	// replace refs in secret data
 	for k, data := range secret.Data {
 		ref := string(data)
@@ -111,15 +129,15 @@ func (rn *ResourceNode) replaceK8sSecretRefs(o *models.Operation) v1.Status {
 		if err != nil {
 			return v1.NewErrorStatus(err)
 		}
-		provider, exist := secrets.GetProvider(o.SecretStore.Provider)
+		provider, exist := secrets.GetProvider(secretStoreSpec.Provider)
 		if !exist {
 			return v1.NewErrorStatus(errors.New("no matched secret store found, please check workspace yaml"))
 		}
-		secretStore, err := provider.NewSecretStore(o.SecretStore)
+		secretStore, err := provider.NewSecretStore(secretStoreSpec)
 		if err != nil {
 			return v1.NewErrorStatus(err)
 		}
-		secretData, err := secretStore.GetSecret(context.Background(), *externalSecretRef)
+		secretData, err := secretStore.GetSecret(o.Context(), *externalSecretRef)
 		if err != nil {
 			return v1.NewErrorStatus(err)
 		}
@@ -135,6 +153,24 @@ func (rn *ResourceNode) replaceK8sSecretRefs(o *models.Operation) v1.Status {
 	return nil
 }
 
+// designatedSecretStore returns the named secret store recorded in the resource extensions, and falls back
+// to the default one if the resource has no designated secret store.
+func (rn *ResourceNode) designatedSecretStore(o *models.Operation) (*apiv1.SecretStore, error) {
+	ss, ok := rn.resource.Extensions[apiv1.ResourceExtensionSecretStore]
+	if !ok || ss == nil {
+		return o.SecretStore, nil
+	}
+	name, ok := ss.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid secret store name in extensions of resource %s", rn.ID)
+	}
+	secretStore, ok := o.SecretStores[name]
+	if !ok || secretStore == nil {
+		return nil, fmt.Errorf("secret store %s of resource %s is not found in the spec", name, rn.ID)
+	}
+	return secretStore, nil
+}
+
 func (rn *ResourceNode) Execute(operation *models.Operation) (s v1.Status) {
 	log.Debugf("executing resource node:%s", rn.ID)
 
@@ -181,11 +217,24 @@ func (rn *ResourceNode) Execute(operation *models.Operation) (s v1.Status) {
 		if e := operation.RefreshResourceIndex(key, dryRunResource, rn.Action); e != nil {
 			return v1.NewErrorStatus(e)
 		}
-		updateChangeOrder(operation, rn, liveResource, dryRunResource)
+		// Redact the secret data before recording the change, as the secret refs have been resolved
+		// against the secret store, so the resolved secrets never leak into the preview output.
+		maskedLive, maskedDryRun := diff.MaskSensitiveData(liveResource, dryRunResource)
+		var warnings []string
+		if rn.Action == models.Update {
+			warnings = immutableFieldChanges(planedResource, liveResource)
+			for _, warning := range warnings {
+				log.Warn(warning)
+			}
+		}
+		updateChangeOrder(operation, rn, maskedLive, maskedDryRun, warnings)
 	case models.Apply, models.Destroy:
 		if s = rn.applyResource(operation, priorResource, planedResource, liveResource); v1.IsErr(s) {
 			return s
 		}
+		if s = rn.pauseIfRequired(operation, planedResource); v1.IsErr(s) {
+			return s
+		}
 	default:
 		return v1.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
 	}
@@ -193,6 +242,29 @@ func (rn *ResourceNode) Execute(operation *models.Operation) (s v1.Status) {
 	return nil
 }
 
+// pauseIfRequired pauses the apply after the resource marked with the apply-pause extension is created
+// or updated, until the PauseHook of the operation returns. The resources depending on it are not applied
+// during the pause.
+func (rn *ResourceNode) pauseIfRequired(operation *models.Operation, planedResource *apiv1.Resource) v1.Status {
+	if operation.OperationType != models.Apply || operation.PauseHook == nil || planedResource == nil {
+		return nil
+	}
+	if rn.Action != models.Create && rn.Action != models.Update {
+		return nil
+	}
+	pause := planedResource.Extensions[apiv1.FieldApplyPause]
+	if pause != true && pause != "true" {
+		return nil
+	}
+
+	log.Infof("apply paused after resource:%s", rn.ID)
+	if err := operation.PauseHook(rn.ID); err != nil {
+		return v1.NewErrorStatus(fmt.Errorf("paused apply after resource %s is not continued: %w", rn.ID, err))
+	}
+	log.Infof("apply continued after resource:%s", rn.ID)
+	return nil
+}
+
 // computeActionType compute ActionType of current resource node according to  planResource, priorResource and liveResource.
 // dryRunResource is a middle result during the process of computing ActionType. We will use it to perform live diff latter
 func (rn *ResourceNode) computeActionType(
@@ -218,7 +290,7 @@ func (rn *ResourceNode) computeActionType(
 			rn.Action = models.Create
 		} else {
 			// Prepare the watch channel for runtime apply.
-			ctx := context.WithValue(context.Background(), engine.WatchChannel, operation.WatchCh)
+			ctx := context.WithValue(operation.Context(), engine.WatchChannel, operation.WatchCh)
 			// Dry run to fetch predictable resource
 			dryRunResp := operation.RuntimeMap[rn.resource.Type].Apply(ctx, &runtime.ApplyRequest{
 				PriorResource: priorResource,
@@ -231,7 +303,7 @@ func (rn *ResourceNode) computeActionType(
 			}
 			dryRunResource = dryRunResp.Resource
 			// Ignore differences of target fields
-			for _, field := range operation.IgnoreFields {
+			for _, field := range rn.ignoreFields(operation.IgnoreFields) {
 				splits := strings.Split(field, ".")
 				removeNestedField(liveResource.Attributes, splits...)
 				removeNestedField(dryRunResource.Attributes, splits...)
@@ -274,7 +346,7 @@ func (rn *ResourceNode) initThreeWayDiffData(operation *models.Operation) (*apiv
 		Stack:         operation.Stack,
 	}
 	resourceType := rn.resource.Type
-	response := operation.RuntimeMap[resourceType].Read(context.Background(), readRequest)
+	response := operation.RuntimeMap[resourceType].Read(operation.Context(), readRequest)
 	liveResource := response.Resource
 	s := response.Status
 	if v1.IsErr(s) {
@@ -293,6 +365,15 @@ func (rn *ResourceNode) initThreeWayDiffData(operation *models.Operation) (*apiv
 	return planedResource, priorResource, liveResource, nil
 }
 
+// ignoreFields returns the fields ignored when computing the changes of the resource, which also include the
+// kubernetesManagedFields for the Kubernetes resources.
+func (rn *ResourceNode) ignoreFields(fields []string) []string {
+	if rn.resource.Type != apiv1.Kubernetes {
+		return fields
+	}
+	return append(kubernetesManagedFields[:len(kubernetesManagedFields):len(kubernetesManagedFields)], fields...)
+}
+
 func removeNestedField(obj interface{}, fields ...string) {
 	m := obj
 	switch next := m.(type) {
@@ -324,13 +405,30 @@ func (rn *ResourceNode) applyResource(operation *models.Operation, prior, planed
 	switch rn.Action {
 	case models.Create, models.Update:
 		// Prepare the watch channel for runtime apply.
-		ctx := context.WithValue(context.Background(), engine.WatchChannel, operation.WatchCh)
-		response := rt.Apply(ctx, &runtime.ApplyRequest{PriorResource: prior, PlanResource: planed, Stack: operation.Stack})
+		ctx := context.WithValue(operation.Context(), engine.WatchChannel, operation.WatchCh)
+		policy, e := retryPolicyOf(planed)
+		if e != nil {
+			return v1.NewErrorStatus(e)
+		}
+		var response *runtime.ApplyResponse
+		for {
+			response = rt.Apply(ctx, &runtime.ApplyRequest{PriorResource: prior, PlanResource: planed, Stack: operation.Stack})
+			if !rn.shouldRetry(operation, policy, response.Status) {
+				break
+			}
+			backoff := retryBackoff(policy, rn.Retries)
+			log.Infof("apply resource:%s failed with a retryable error, retry after %v. %s", planed.ID, backoff, response.Status.Message())
+			if !waitRetryBackoff(ctx, backoff) {
+				log.Infof("stop retrying resource:%s as the operation is done", planed.ID)
+				break
+			}
+			rn.Retries++
+		}
 		res = response.Resource
 		s = response.Status
 		log.Debugf("apply resource:%s, response: %v", planed.ID, json.Marshal2String(response))
 	case models.Delete:
-		response := rt.Delete(context.Background(), &runtime.DeleteRequest{Resource: prior, Stack: operation.Stack})
+		response := rt.Delete(operation.Context(), &runtime.DeleteRequest{Resource: prior, Stack: operation.Stack})
 		s = response.Status
 		if s != nil {
 			log.Debugf("delete resource:%s, resource: %v", prior.ID, s.String())
@@ -339,7 +437,7 @@ func (rn *ResourceNode) applyResource(operation *models.Operation, prior, planed
 		log.Infof("planed resource and live resource are equal")
 		// auto import resources exist in intent and live cluster but not recorded in release file
 		if prior == nil {
-			response := rt.Import(context.Background(), &runtime.ImportRequest{
+			response := rt.Import(operation.Context(), &runtime.ImportRequest{
 				PlanResource: planed,
 				Stack:        operation.Stack,
 			})
@@ -387,7 +485,7 @@ func NewResourceNode(key string, state *apiv1.Resource, action models.ActionType
 }
 
 // save change steps in DAG walking order so that we can preview a full applying list
-func updateChangeOrder(ops *models.Operation, rn *ResourceNode, plan, live interface{}) {
+func updateChangeOrder(ops *models.Operation, rn *ResourceNode, plan, live interface{}, warnings []string) {
 	defer ops.Lock.Unlock()
 	ops.Lock.Lock()
 
@@ -402,7 +500,9 @@ func updateChangeOrder(ops *models.Operation, rn *ResourceNode, plan, live inter
 		order.ChangeSteps = make(map[string]*models.ChangeStep)
 	}
 	order.StepKeys = append(order.StepKeys, rn.ID)
-	order.ChangeSteps[rn.ID] = models.NewChangeStep(rn.ID, rn.Action, plan, live)
+	step := models.NewChangeStep(rn.ID, rn.Action, plan, live)
+	step.Warnings = warnings
+	order.ChangeSteps[rn.ID] = step
 }
 
 var MustImplicitReplaceFun = func(resourceIndex map[string]*apiv1.Resource, refPath string) (reflect.Value, v1.Status) {
@@ -250,6 +250,16 @@ func Test_removeNestedField(t *testing.T) {
 	})
 }
 
+func TestResourceNode_ignoreFields(t *testing.T) {
+	k8sNode := &ResourceNode{resource: &apiv1.Resource{Type: apiv1.Kubernetes}}
+	assert.Equal(t, append(kubernetesManagedFields, "spec.replicas"), k8sNode.ignoreFields([]string{"spec.replicas"}))
+
+	// the status of the Terraform resources is not managed by the server, which is not ignored
+	tfNode := &ResourceNode{resource: &apiv1.Resource{Type: apiv1.Terraform}}
+	assert.Equal(t, []string{"spec.replicas"}, tfNode.ignoreFields([]string{"spec.replicas"}))
+	assert.Empty(t, tfNode.ignoreFields(nil))
+}
+
 func TestParseExternalSecretDataRef(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -314,3 +324,126 @@ func TestParseExternalSecretDataRef(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceNode_pauseIfRequired(t *testing.T) {
+	paused := &apiv1.Resource{
+		ID:         "apps/v1:Deployment:default:canary",
+		Type:       runtime.Kubernetes,
+		Extensions: map[string]interface{}{apiv1.FieldApplyPause: true},
+	}
+	unpaused := &apiv1.Resource{
+		ID:   "apps/v1:Deployment:default:stable",
+		Type: runtime.Kubernetes,
+	}
+
+	tests := []struct {
+		name        string
+		action      models.ActionType
+		resource    *apiv1.Resource
+		hookErr     error
+		wantPaused  bool
+		wantErr     bool
+		withoutHook bool
+	}{
+		{
+			name:       "pause after creating the marked resource",
+			action:     models.Create,
+			resource:   paused,
+			wantPaused: true,
+		},
+		{
+			name:     "skip the unmarked resource",
+			action:   models.Update,
+			resource: unpaused,
+		},
+		{
+			name:     "skip the unchanged resource",
+			action:   models.UnChanged,
+			resource: paused,
+		},
+		{
+			name:        "ignore the extension without hook",
+			action:      models.Update,
+			resource:    paused,
+			withoutHook: true,
+		},
+		{
+			name:       "fail if the pause is not continued",
+			action:     models.Update,
+			resource:   paused,
+			hookErr:    context.DeadlineExceeded,
+			wantPaused: true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rn, s := NewResourceNode(tt.resource.ID, tt.resource, tt.action)
+			assert.Nil(t, s)
+
+			var pausedID string
+			operation := &models.Operation{OperationType: models.Apply}
+			if !tt.withoutHook {
+				operation.PauseHook = func(resourceID string) error {
+					pausedID = resourceID
+					return tt.hookErr
+				}
+			}
+
+			s = rn.pauseIfRequired(operation, tt.resource)
+			assert.Equal(t, tt.wantErr, v1.IsErr(s))
+			assert.Equal(t, tt.wantPaused, pausedID == tt.resource.ID)
+		})
+	}
+}
+
+func TestResourceNode_designatedSecretStore(t *testing.T) {
+	defaultStore := &apiv1.SecretStore{Provider: &apiv1.ProviderSpec{Fake: &apiv1.FakeProvider{}}}
+	vaultStore := &apiv1.SecretStore{Provider: &apiv1.ProviderSpec{Vault: &apiv1.VaultProvider{Server: "https://vault.example.com:8200"}}}
+	operation := &models.Operation{
+		SecretStore:  defaultStore,
+		SecretStores: map[string]*apiv1.SecretStore{"vault": vaultStore},
+	}
+
+	tests := []struct {
+		name       string
+		extensions map[string]any
+		want       *apiv1.SecretStore
+		wantErr    string
+	}{
+		{
+			name: "fall back to the default store",
+			want: defaultStore,
+		},
+		{
+			name:       "resolve the named store",
+			extensions: map[string]any{apiv1.ResourceExtensionSecretStore: "vault"},
+			want:       vaultStore,
+		},
+		{
+			name:       "fail if the named store is not in the spec",
+			extensions: map[string]any{apiv1.ResourceExtensionSecretStore: "aws"},
+			wantErr:    "secret store aws of resource v1:Secret:default:api-auth is not found in the spec",
+		},
+		{
+			name:       "fail if the extension is not a name",
+			extensions: map[string]any{apiv1.ResourceExtensionSecretStore: map[string]any{"provider": nil}},
+			wantErr:    "invalid secret store name in extensions of resource v1:Secret:default:api-auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rn := &ResourceNode{
+				baseNode: &baseNode{ID: "v1:Secret:default:api-auth"},
+				resource: &apiv1.Resource{ID: "v1:Secret:default:api-auth", Extensions: tt.extensions},
+			}
+			got, err := rn.designatedSecretStore(operation)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Same(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	v1 "kusionstack.io/kusion/pkg/apis/status/v1"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+const maxRetryBackoff = time.Minute
+
+// retryBackoffUnit is the unit of the backoff seconds in the retry policy.
+var retryBackoffUnit = time.Second
+
+// transientErrorPatterns are the messages of the transient errors which are not classified by the runtime.
+var transientErrorPatterns = []string{
+	"too many requests",
+	"rate limit",
+	"throttl",
+	"failed calling webhook",
+	"connection refused",
+	"connection reset",
+	"i/o timeout",
+	"tls handshake timeout",
+	"the server is currently unable to handle the request",
+	"etcdserver: request timed out",
+}
+
+// retryPolicyOf parses the retry policy from the extensions of the resource, and returns nil if the
+// resource has no retry policy.
+func retryPolicyOf(res *apiv1.Resource) (*apiv1.RetryPolicy, error) {
+	if res == nil || res.Extensions[apiv1.FieldRetryPolicy] == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(res.Extensions[apiv1.FieldRetryPolicy])
+	if err != nil {
+		return nil, err
+	}
+	policy := &apiv1.RetryPolicy{}
+	if err = json.Unmarshal(bytes, policy); err != nil {
+		return nil, fmt.Errorf("invalid retry policy of resource %s: %w", res.ID, err)
+	}
+	if policy.MaxRetries < 0 || policy.BackoffSeconds < 0 {
+		return nil, fmt.Errorf("invalid retry policy of resource %s: maxRetries and backoffSeconds must not be negative", res.ID)
+	}
+	return policy, nil
+}
+
+// shouldRetry reports whether to retry applying the resource after the failed status.
+func (rn *ResourceNode) shouldRetry(operation *models.Operation, policy *apiv1.RetryPolicy, s v1.Status) bool {
+	if !v1.IsErr(s) || policy == nil || rn.Retries >= policy.MaxRetries {
+		return false
+	}
+	// The operation is cancelled or timed out.
+	if operation.Context().Err() != nil {
+		return false
+	}
+	// The watching of the terraform resource stops once its apply fails, so it can not be retried.
+	if operation.WatchCh != nil && rn.resource.Type == apiv1.Terraform {
+		return false
+	}
+	return isRetryable(s)
+}
+
+// isRetryable reports whether the failed status is caused by a transient error, while the permanent
+// errors such as the invalid manifest fail the resource immediately.
+func isRetryable(s v1.Status) bool {
+	switch s.Code() {
+	case v1.Unavailable:
+		return true
+	case v1.InvalidArgument, v1.IllegalManifest, v1.PermissionDenied, v1.Unauthenticated,
+		v1.AlreadyExists, v1.NotFound, v1.Unimplemented:
+		return false
+	}
+	msg := strings.ToLower(s.Message())
+	for _, pattern := range transientErrorPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns the exponential backoff before the next retry.
+func retryBackoff(policy *apiv1.RetryPolicy, retries int) time.Duration {
+	backoff := time.Duration(policy.BackoffSeconds) * retryBackoffUnit
+	if backoff == 0 {
+		backoff = retryBackoffUnit
+	}
+	for i := 0; i < retries && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// waitRetryBackoff waits for the backoff before the next retry, and returns false without waiting it out
+// once the ctx is done, e.g. the operation is cancelled.
+func waitRetryBackoff(ctx context.Context, backoff time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(backoff):
+		return true
+	}
+}
@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bytedance/mockey"
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	v1 "kusionstack.io/kusion/pkg/apis/status/v1"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/kubernetes"
+)
+
+func TestRetryPolicyOf(t *testing.T) {
+	policy, err := retryPolicyOf(&apiv1.Resource{ID: "foo"})
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	policy, err = retryPolicyOf(&apiv1.Resource{
+		ID: "foo",
+		Extensions: map[string]interface{}{
+			apiv1.FieldRetryPolicy: map[string]interface{}{"maxRetries": 3, "backoffSeconds": 2},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &apiv1.RetryPolicy{MaxRetries: 3, BackoffSeconds: 2}, policy)
+
+	_, err = retryPolicyOf(&apiv1.Resource{
+		ID: "foo",
+		Extensions: map[string]interface{}{
+			apiv1.FieldRetryPolicy: map[string]interface{}{"maxRetries": -1},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status v1.Status
+		want   bool
+	}{
+		{
+			name:   "unavailable",
+			status: v1.NewErrorStatusWithCode(v1.Unavailable, errors.New("the server has received too many requests")),
+			want:   true,
+		},
+		{
+			name:   "webhook not ready",
+			status: v1.NewErrorStatus(errors.New("Internal error occurred: failed calling webhook \"validate.foo.io\"")),
+			want:   true,
+		},
+		{
+			name:   "illegal manifest",
+			status: v1.NewErrorStatusWithCode(v1.IllegalManifest, errors.New("connection refused")),
+			want:   false,
+		},
+		{
+			name:   "permanent error",
+			status: v1.NewErrorStatus(errors.New("Deployment.apps \"foo\" is invalid: spec.replicas: Invalid value: -1")),
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetryable(tt.status))
+		})
+	}
+}
+
+func TestResourceNode_shouldRetry(t *testing.T) {
+	retryable := v1.NewErrorStatusWithCode(v1.Unavailable, errors.New("service unavailable"))
+	policy := &apiv1.RetryPolicy{MaxRetries: 2}
+	operation := &models.Operation{}
+
+	rn, s := NewResourceNode("foo", &apiv1.Resource{ID: "foo", Type: apiv1.Kubernetes}, models.Create)
+	assert.Nil(t, s)
+	assert.False(t, rn.shouldRetry(operation, nil, retryable))
+	assert.False(t, rn.shouldRetry(operation, policy, nil))
+	assert.True(t, rn.shouldRetry(operation, policy, retryable))
+
+	// the retries are exhausted
+	rn.Retries = 2
+	assert.False(t, rn.shouldRetry(operation, policy, retryable))
+
+	// the cancelled operation is not retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rn.Retries = 0
+	assert.False(t, rn.shouldRetry(&models.Operation{Ctx: ctx}, policy, retryable))
+
+	// the watched terraform resource is not retried
+	tf, s := NewResourceNode("bar", &apiv1.Resource{ID: "bar", Type: apiv1.Terraform}, models.Create)
+	assert.Nil(t, s)
+	assert.False(t, tf.shouldRetry(&models.Operation{WatchCh: make(chan string)}, policy, retryable))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &apiv1.RetryPolicy{MaxRetries: 10, BackoffSeconds: 2}
+	assert.Equal(t, 2*time.Second, retryBackoff(policy, 0))
+	assert.Equal(t, 8*time.Second, retryBackoff(policy, 2))
+	assert.Equal(t, maxRetryBackoff, retryBackoff(policy, 9))
+	assert.Equal(t, time.Second, retryBackoff(&apiv1.RetryPolicy{MaxRetries: 1}, 0))
+}
+
+func TestResourceNode_applyResourceCancelledInBackoff(t *testing.T) {
+	mockey.PatchConvey("cancel the operation during the retry backoff", t, func() {
+		k8sRuntime := &kubernetes.KubernetesRuntime{}
+		applied := 0
+		mockey.Mock(mockey.GetMethod(k8sRuntime, "Apply")).To(
+			func(_ *kubernetes.KubernetesRuntime, _ context.Context, _ *runtime.ApplyRequest) *runtime.ApplyResponse {
+				applied++
+				return &runtime.ApplyResponse{Status: v1.NewErrorStatusWithCode(v1.Unavailable, errors.New("service unavailable"))}
+			}).Build()
+
+		planed := &apiv1.Resource{
+			ID:   "foo",
+			Type: apiv1.Kubernetes,
+			Extensions: map[string]interface{}{
+				apiv1.FieldRetryPolicy: map[string]interface{}{"maxRetries": 3, "backoffSeconds": 60},
+			},
+		}
+		rn, s := NewResourceNode("foo", planed, models.Create)
+		assert.Nil(t, s)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		operation := &models.Operation{
+			Ctx:        ctx,
+			RuntimeMap: map[apiv1.Type]runtime.Runtime{runtime.Kubernetes: k8sRuntime},
+		}
+
+		start := time.Now()
+		s = rn.applyResource(operation, nil, planed, nil)
+		assert.True(t, v1.IsErr(s))
+		// the backoff is cut short by the cancellation, and no more retry is made
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Equal(t, 1, applied)
+		assert.Equal(t, 0, rn.Retries)
+	})
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
@@ -32,6 +33,20 @@ func (t ActionType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(t.String())
 }
 
+func (t *ActionType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for i, name := range []string{"Undefined", "UnChanged", "Create", "Update", "Delete"} {
+		if name == s {
+			*t = ActionType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action type %q", s)
+}
+
 func (t ActionType) Ing() string {
 	switch t {
 	case Create:
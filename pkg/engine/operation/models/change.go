@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/liu-hm19/pterm"
@@ -25,6 +26,8 @@ type ChangeStep struct {
 	From interface{} `json:"from,omitempty" yaml:"from,omitempty"`
 	// new data
 	To interface{} `json:"to,omitempty" yaml:"to,omitempty"`
+	// the warnings of this step, e.g. the change of immutable fields requiring resource replacement
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
 }
 
 // Diff compares objects(from and to) which stores in ChangeStep,
@@ -61,6 +64,9 @@ func (cs *ChangeStep) Diff(noStyle bool) (string, error) {
 			// TODO: reportString is formatted with color, need to remove color eventually
 			buf.WriteString("\n" + strings.TrimSpace(reportString))
 		}
+		for _, warning := range cs.Warnings {
+			buf.WriteString("\nWarning: " + warning)
+		}
 	} else {
 		if len(cs.ID) != 0 {
 			buf.WriteString(pretty.GreenBold("ID: "))
@@ -76,6 +82,9 @@ func (cs *ChangeStep) Diff(noStyle bool) (string, error) {
 		} else {
 			buf.WriteString("\n" + strings.TrimSpace(reportString))
 		}
+		for _, warning := range cs.Warnings {
+			buf.WriteString("\n" + pretty.YellowBold("Warning: ") + pretty.Yellow("%s", warning))
+		}
 	}
 	buf.WriteString("\n")
 	return buf.String(), nil
@@ -113,6 +122,9 @@ func (cs *ChangeStep) NoStyleDiff() (string, error) {
 	} else {
 		buf.WriteString("\n" + strings.TrimSpace(reportString))
 	}
+	for _, warning := range cs.Warnings {
+		buf.WriteString("\nWarning: " + warning)
+	}
 	buf.WriteString("\n")
 	return buf.String(), nil
 }
@@ -219,6 +231,107 @@ func (p *Changes) AllUnChange() bool {
 	return true
 }
 
+// ActionSummary is the machine-readable summary of the changes, i.e. the number of the resources
+// to create, update and delete along with the IDs of the changed resources.
+type ActionSummary struct {
+	// Create is the number of the resources to create.
+	Create int `json:"create" yaml:"create"`
+	// Update is the number of the resources to update.
+	Update int `json:"update" yaml:"update"`
+	// Delete is the number of the resources to delete.
+	Delete int `json:"delete" yaml:"delete"`
+	// ChangedResources are the IDs of the changed resources in the order of the change steps.
+	ChangedResources []string `json:"changedResources" yaml:"changedResources"`
+}
+
+// ActionSummary summarizes the changes by the action, the unchanged resources are excluded.
+func (o *ChangeOrder) ActionSummary() *ActionSummary {
+	summary := &ActionSummary{ChangedResources: []string{}}
+	for _, step := range o.Values() {
+		switch step.Action {
+		case Create:
+			summary.Create++
+		case Update:
+			summary.Update++
+		case Delete:
+			summary.Delete++
+		default:
+			continue
+		}
+		summary.ChangedResources = append(summary.ChangedResources, step.ID)
+	}
+	return summary
+}
+
+// KindChangeSummary is the number of changed resources of a kind, grouped by the action.
+type KindChangeSummary struct {
+	// Kind is the kind of the resources, e.g. Deployment for Kubernetes resources and
+	// aws_s3_bucket for Terraform resources.
+	Kind string `json:"kind" yaml:"kind"`
+	// Actions maps the action to the number of the resources, e.g. {"Update": 3}.
+	Actions map[string]int `json:"actions" yaml:"actions"`
+	// Total is the total number of the changed resources of the kind.
+	Total int `json:"total" yaml:"total"`
+}
+
+// KindBreakdown breaks down the changes by the resource kind, the unchanged resources are
+// excluded. The result is sorted by the kind.
+func (o *ChangeOrder) KindBreakdown() []*KindChangeSummary {
+	summaries := make(map[string]*KindChangeSummary)
+	for _, step := range o.Values() {
+		if step.Action == UnChanged {
+			continue
+		}
+		kind := step.resourceKind()
+		summary, ok := summaries[kind]
+		if !ok {
+			summary = &KindChangeSummary{Kind: kind, Actions: make(map[string]int)}
+			summaries[kind] = summary
+		}
+		summary.Actions[step.Action.String()]++
+		summary.Total++
+	}
+
+	result := make([]*KindChangeSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
+
+// resourceKind gets the kind of the resource from the ID of the change step, which follows
+// apiVersion:kind:namespace:name for Kubernetes resources and
+// providerNamespace:providerName:resourceType:resourceName for Terraform resources.
+func (cs *ChangeStep) resourceKind() string {
+	var resourceType v1.Type
+	for _, state := range []interface{}{cs.To, cs.From} {
+		if res, ok := state.(*v1.Resource); ok && res != nil {
+			resourceType = res.Type
+			break
+		}
+	}
+
+	idParts := strings.Split(cs.ID, ":")
+	if resourceType == "" && len(idParts) == 4 {
+		// the apiVersion of Kubernetes resources is either "v1" or in the format of group/version
+		if idParts[0] != "v1" && !strings.Contains(idParts[0], "/") {
+			resourceType = v1.Terraform
+		}
+	}
+
+	switch {
+	case resourceType == v1.Terraform && len(idParts) == 4:
+		return idParts[2]
+	case resourceType != v1.Terraform && (len(idParts) == 3 || len(idParts) == 4):
+		return idParts[1]
+	default:
+		return cs.ID
+	}
+}
+
 func (p *Changes) Summary(writer io.Writer, noStyle bool) {
 	// Create a fork of the default table, fill it with data and print it.
 	// Data can also be generated and inserted later.
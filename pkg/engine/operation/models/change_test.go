@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"os"
 	"reflect"
 	"testing"
@@ -61,6 +62,19 @@ func TestOpType_Ing(t *testing.T) {
 	}
 }
 
+func TestOpType_JSON(t *testing.T) {
+	for _, op := range []ActionType{Undefined, UnChanged, Create, Update, Delete} {
+		out, err := json.Marshal(op)
+		assert.NoError(t, err)
+		var got ActionType
+		assert.NoError(t, json.Unmarshal(out, &got))
+		assert.Equal(t, op, got)
+	}
+
+	var got ActionType
+	assert.Error(t, json.Unmarshal([]byte(`"Replace"`), &got))
+}
+
 func TestOpType_PrettyString(t *testing.T) {
 	tests := []struct {
 		name string
@@ -481,3 +495,58 @@ func TestChanges_AllUnChange(t *testing.T) {
 		assert.True(t, flag)
 	})
 }
+
+func TestChangeOrder_KindBreakdown(t *testing.T) {
+	deployment := func(name string, action ActionType) *ChangeStep {
+		return NewChangeStep("apps/v1:Deployment:foo:"+name, action, nil, nil)
+	}
+	steps := []*ChangeStep{
+		deployment("a", Update),
+		deployment("b", Update),
+		deployment("c", Create),
+		NewChangeStep("v1:Service:foo:a", Create, nil, nil),
+		NewChangeStep("v1:Namespace:foo", UnChanged, nil, nil),
+		NewChangeStep("hashicorp:aws:aws_s3_bucket:foo", Delete, nil, &apiv1.Resource{Type: apiv1.Terraform}),
+	}
+	order := &ChangeOrder{ChangeSteps: map[string]*ChangeStep{}}
+	for _, step := range steps {
+		order.StepKeys = append(order.StepKeys, step.ID)
+		order.ChangeSteps[step.ID] = step
+	}
+
+	expected := []*KindChangeSummary{
+		{Kind: "Deployment", Actions: map[string]int{"Update": 2, "Create": 1}, Total: 3},
+		{Kind: "Service", Actions: map[string]int{"Create": 1}, Total: 1},
+		{Kind: "aws_s3_bucket", Actions: map[string]int{"Delete": 1}, Total: 1},
+	}
+	assert.Equal(t, expected, order.KindBreakdown())
+}
+
+func TestChangeOrder_ActionSummary(t *testing.T) {
+	steps := []*ChangeStep{
+		NewChangeStep("apps/v1:Deployment:foo:a", Update, nil, nil),
+		NewChangeStep("v1:Namespace:foo", UnChanged, nil, nil),
+		NewChangeStep("v1:Service:foo:a", Create, nil, nil),
+		NewChangeStep("apps/v1:Deployment:foo:b", Update, nil, nil),
+		NewChangeStep("hashicorp:aws:aws_s3_bucket:foo", Delete, nil, nil),
+	}
+	order := &ChangeOrder{ChangeSteps: map[string]*ChangeStep{}}
+	for _, step := range steps {
+		order.StepKeys = append(order.StepKeys, step.ID)
+		order.ChangeSteps[step.ID] = step
+	}
+
+	expected := &ActionSummary{
+		Create: 1,
+		Update: 2,
+		Delete: 1,
+		ChangedResources: []string{
+			"apps/v1:Deployment:foo:a",
+			"v1:Service:foo:a",
+			"apps/v1:Deployment:foo:b",
+			"hashicorp:aws:aws_s3_bucket:foo",
+		},
+	}
+	assert.Equal(t, expected, order.ActionSummary())
+	assert.Equal(t, &ActionSummary{ChangedResources: []string{}}, (&ChangeOrder{}).ActionSummary())
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -25,6 +26,9 @@ type Operation struct {
 	// SecretStore represents the storage where secrets were saved
 	SecretStore *apiv1.SecretStore
 
+	// SecretStores are the named secret stores designated to the secrets by name
+	SecretStores map[string]*apiv1.SecretStore
+
 	// CtxResourceIndex represents resources updated by this operation
 	CtxResourceIndex map[string]*apiv1.Resource
 
@@ -63,12 +67,34 @@ type Operation struct {
 
 	// Release is the release updated in this operation, and saved in the ReleaseStorage
 	Release *apiv1.Release
+
+	// PauseHook is invoked after applying the resource marked with the apply-pause extension, and blocks
+	// the resource node until the paused apply is continued. The resource node fails if an error is returned.
+	// The apply-pause extension is ignored when PauseHook is nil.
+	PauseHook func(resourceID string) error
+
+	// ProgressHook is invoked with every message received from the MsgCh after it is processed, and is used to
+	// report the apply progress of the resources elsewhere than the logs.
+	ProgressHook func(msg Message)
+
+	// Ctx is the context of the operation passed to the runtimes. Once it is done, the resource nodes not started
+	// yet are no longer executed. The background context is used if it is nil.
+	Ctx context.Context
+}
+
+// Context returns the context of the operation, or the background context if it is not set.
+func (o *Operation) Context() context.Context {
+	if o.Ctx == nil {
+		return context.Background()
+	}
+	return o.Ctx
 }
 
 type Message struct {
 	ResourceID string   // ResourceNode.ID()
 	OpResult   OpResult // Success/Failed/Skip
 	OpErr      error    // Operate error detail
+	Retries    int      // Retries of applying the resource
 }
 
 type Request struct {
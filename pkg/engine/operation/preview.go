@@ -99,6 +99,7 @@ func (po *PreviewOperation) Preview(req *PreviewRequest) (rsp *PreviewResponse,
 			OperationType:           o.OperationType,
 			ReleaseStorage:          o.ReleaseStorage,
 			SecretStore:             req.Spec.SecretStore,
+			SecretStores:            req.Spec.SecretStores,
 			CtxResourceIndex:        map[string]*apiv1.Resource{},
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      stateResourceIndex,
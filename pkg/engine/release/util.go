@@ -124,7 +124,12 @@ func CreateDestroyRelease(storage Storage, project, stack, workspace string) (*v
 		specContext = lastRelease.Spec.Context
 	}
 
-	spec := &v1.Spec{Resources: resources, SecretStore: secretStore, Context: specContext}
+	var secretStores map[string]*v1.SecretStore
+	if lastRelease.Spec != nil {
+		secretStores = lastRelease.Spec.SecretStores
+	}
+
+	spec := &v1.Spec{Resources: resources, SecretStore: secretStore, SecretStores: secretStores, Context: specContext}
 
 	// if no resource managed, set phase to Succeeded directly.
 	phase := v1.ReleasePhasePreviewing
@@ -67,6 +67,16 @@ func NewKubernetesRuntime(spec apiv1.Spec) (runtime.Runtime, error) {
 	}, nil
 }
 
+// applyErrorStatus returns the status of the failed apply request, where the transient errors such as
+// the throttling and the admission webhook not ready are marked as Unavailable to be retried.
+func applyErrorStatus(err error) v1.Status {
+	if k8serrors.IsTooManyRequests(err) || k8serrors.IsServerTimeout(err) || k8serrors.IsTimeout(err) ||
+		k8serrors.IsServiceUnavailable(err) || k8serrors.IsInternalError(err) {
+		return v1.NewErrorStatusWithCode(v1.Unavailable, err)
+	}
+	return v1.NewErrorStatus(err)
+}
+
 // Apply kubernetes Resource by client-go
 func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
 	planState := request.PlanResource
@@ -97,6 +107,14 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 	}
 	// Modified equals to input content
 	modified := jsonutil.MustMarshal2String(planState.Attributes)
+	// Resolve the uid of the owner references against the live owners, e.g. the parent object of the app
+	resolved, err := k.resolveOwnerReferences(ctx, planObj, request.DryRun)
+	if err != nil {
+		return &runtime.ApplyResponse{Status: v1.NewErrorStatus(err)}
+	}
+	if resolved {
+		modified = jsonutil.MustMarshal2String(planObj.Object)
+	}
 	// Current equals to live manifest
 	current := ""
 	if liveState != nil {
@@ -159,7 +177,7 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 			_, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patchBody, metav1.PatchOptions{FieldManager: "kusion"})
 		}
 		if err != nil {
-			return &runtime.ApplyResponse{Status: v1.NewErrorStatus(err)}
+			return &runtime.ApplyResponse{Status: applyErrorStatus(err)}
 		}
 		// Save modified
 		res = planObj
@@ -185,6 +203,56 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *runtime.ApplyReq
 	}}
 }
 
+// resolveOwnerReferences resolves the empty uid of the owner references of the object against the live owner
+// objects, and reports whether any owner reference is resolved. The owner references whose owner can not be
+// found are kept unresolved during the dry run, as the owner may not have been created yet.
+func (k *KubernetesRuntime) resolveOwnerReferences(ctx context.Context, obj *unstructured.Unstructured, dryRun bool) (bool, error) {
+	refs := obj.GetOwnerReferences()
+	resolved := false
+	for i, ref := range refs {
+		if ref.UID != "" {
+			continue
+		}
+		owner, err := k.getOwner(ctx, ref, obj.GetNamespace())
+		if err != nil {
+			if dryRun {
+				log.Infof("failed to resolve owner reference %s/%s of %s during the dry run: %v", ref.Kind, ref.Name, obj.GetName(), err)
+				continue
+			}
+			return false, fmt.Errorf("failed to resolve owner reference %s/%s of %s: %w", ref.Kind, ref.Name, obj.GetName(), err)
+		}
+		refs[i].UID = owner.GetUID()
+		resolved = true
+	}
+	if resolved {
+		obj.SetOwnerReferences(refs)
+	}
+	return resolved, nil
+}
+
+// getOwner gets the live owner object of the owner reference, which is in the namespace of the dependent
+// object if namespaced.
+func (k *KubernetesRuntime) getOwner(ctx context.Context, ref metav1.OwnerReference, namespace string) (*unstructured.Unstructured, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := k.mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, err
+	}
+	var resource dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if namespace == "" {
+			namespace = "default"
+		}
+		resource = k.client.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		resource = k.client.Resource(mapping.Resource)
+	}
+	return resource.Get(ctx, ref.Name, metav1.GetOptions{})
+}
+
 // Read kubernetes Resource by client-go
 func (k *KubernetesRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
 	requestResource := request.PlanResource
@@ -15,21 +15,43 @@
 package appconfiguration
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	goruntime "runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/google/uuid"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	yamlv2 "gopkg.in/yaml.v2"
 	"gopkg.in/yaml.v3"
 	k8sv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	k8sjson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
 	pkg "kcl-lang.io/kpm/pkg/package"
 
 	"kusionstack.io/kusion-module-framework/pkg/module"
@@ -37,12 +59,16 @@ import (
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/generators"
+	"kusionstack.io/kusion/pkg/generators/ownerreference"
 	"kusionstack.io/kusion/pkg/generators/secret"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/util/kfile"
 
 	// import the secrets register pkg to register supported secret providers
 	ns "kusionstack.io/kusion/pkg/generators/namespace"
 	orderedres "kusionstack.io/kusion/pkg/generators/orderedresources"
+	"kusionstack.io/kusion/pkg/generators/rename"
+	"kusionstack.io/kusion/pkg/secrets"
 	_ "kusionstack.io/kusion/pkg/secrets/providers/register"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
 	"kusionstack.io/kusion/pkg/workspace"
@@ -51,6 +77,11 @@ import (
 const (
 	isWorkload = "kusion.io/is-workload"
 	removalVal = "ops://kusionstack.io/remove"
+	// enabledWhen is the field of an accessory declaring the condition to enable the module, which maps
+	// the dot-separated paths in the workspace context to the expected values, e.g.
+	//
+	//	_enabledWhen: {"metadata.env": "prod"}
+	enabledWhen = "_enabledWhen"
 )
 
 const (
@@ -58,6 +89,9 @@ const (
 	kusionTraceID    = "kusion_trace_id"
 )
 
+// builtinModuleKey is the key in the resource summary of the resources generated by the built-in generators.
+const builtinModuleKey = "builtin"
+
 type appConfigurationGenerator struct {
 	project      *v1.Project
 	stack        *v1.Stack
@@ -65,6 +99,86 @@ type appConfigurationGenerator struct {
 	app          *v1.AppConfiguration
 	ws           *v1.Workspace
 	dependencies *pkg.Dependencies
+
+	// workloadOnly limits the generation to the namespace and the workload, skipping all the accessories.
+	workloadOnly bool
+	// summary records the number of resources generated by each module of the app if not nil.
+	summary *v1.AppResourceSummary
+	// captureModule is the module whose output is captured into the snapshot.
+	captureModule string
+	// snapshot records the output of the captured module if not nil.
+	snapshot *ModuleSnapshot
+	// nonStrict continues the generation past the failures of the built-in generators.
+	nonStrict bool
+	// errors collects the failures of the built-in generators in the non-strict mode if not nil.
+	builtinErrors *[]error
+	// pluginCache is the plugin cache shared with the generators of other apps if not nil.
+	pluginCache *PluginCache
+	// metrics receives the records of the module invocations if not nil.
+	metrics MetricsSink
+	// namingPolicy renames the resources generated by the app.
+	namingPolicy NamingPolicy
+	// renames renames the resources generated by the app by name after the naming policy.
+	renames []rename.Rename
+	// secretStore is the secret store selected for the namespace of the app on generating.
+	secretStore *v1.SecretStore
+	// strictSecretStore fails the generation on the external secrets without a secret store.
+	strictSecretStore bool
+	// objects reads the objects referenced by the module configs from the backend if not nil.
+	objects ObjectReader
+	// unmatchedImports records the imported resources matching no resource instead of warning if not nil.
+	unmatchedImports *[]string
+	// resourceModules is the names of the modules generating the resources by the resource IDs, which is
+	// recorded on calling the modules.
+	resourceModules map[string]string
+}
+
+// ObjectReader reads the objects stored in the backend by their keys, e.g. the mapping of the imported
+// resources referenced by the importedResourcesObject of the module configs.
+type ObjectReader interface {
+	ReadObject(key string) ([]byte, error)
+}
+
+// GeneratorOptions is the optional settings of the app configuration generator.
+type GeneratorOptions struct {
+	// WorkloadOnly limits the generation to the namespace and the workload, skipping all the accessories.
+	// It is mainly used for isolating the issues of the workload module.
+	WorkloadOnly bool
+	// Summary records the number of resources generated by each module of the app if not nil.
+	Summary *v1.AppResourceSummary
+	// CaptureModule is the name or the key of the module whose resources and patchers are captured into
+	// the Snapshot, e.g. kusionstack/service or kusionstack/service@v0.1.0.
+	CaptureModule string
+	// Snapshot records the canonicalized output of the CaptureModule for the snapshot testing if not nil.
+	Snapshot *ModuleSnapshot
+	// NonStrict continues the generation past the failures of the built-in generators, e.g. the namespace
+	// generator, so that the partial Spec can be used for debugging. It fails on them by default.
+	NonStrict bool
+	// Errors collects the failures of the built-in generators in the NonStrict mode if not nil.
+	Errors *[]error
+	// Plugins is the plugin cache shared by the generators of the apps in a build if not nil, so that the
+	// same module is started only once. The caller owns the cache and must Close it after the build.
+	// Otherwise, each generation starts and kills its own plugins.
+	Plugins *PluginCache
+	// Metrics receives the duration and the result of each module invocation if not nil, which must be safe
+	// for concurrent use. The module invocations are not recorded by default.
+	Metrics MetricsSink
+	// NamingPolicy renames the Kubernetes resources generated by the app after they are patched, updating the
+	// references to them. The names are kept by default, i.e. by the NoopNamingPolicy.
+	NamingPolicy NamingPolicy
+	// Renames renames the Kubernetes resources generated by the app from the old names to the new ones after the
+	// naming policy, rewriting the references to them in the env, the volumes and the selectors consistently.
+	Renames []rename.Rename
+	// StrictSecretStore fails the generation if the workloads reference the external secrets while no
+	// secret store is configured for them, which is only warned by default.
+	StrictSecretStore bool
+	// Objects reads the objects referenced by the module configs from the backend if not nil, which is
+	// usually the backend of the workspace.
+	Objects ObjectReader
+	// UnmatchedImports records the sorted kusion IDs of the imported resources matching no resource generated
+	// so far if not nil, instead of warning them. As the imported resources are shared by the apps of the
+	// project, the caller generating several apps should warn the ones matched by none of the apps.
+	UnmatchedImports *[]string
 }
 
 func NewAppConfigurationGenerator(
@@ -122,11 +236,61 @@ func NewAppConfigurationGeneratorFunc(
 	}
 }
 
+// NewAppConfigurationGeneratorFuncWithOptions returns a generator func of the app configuration
+// generator with the given options.
+func NewAppConfigurationGeneratorFuncWithOptions(
+	project *v1.Project,
+	stack *v1.Stack,
+	appName string,
+	app *v1.AppConfiguration,
+	ws *v1.Workspace,
+	kpmDependencies *pkg.Dependencies,
+	opts GeneratorOptions,
+) generators.NewSpecGeneratorFunc {
+	return func() (generators.SpecGenerator, error) {
+		g, err := NewAppConfigurationGenerator(project, stack, appName, app, ws, kpmDependencies)
+		if err != nil {
+			return nil, err
+		}
+		acg := g.(*appConfigurationGenerator)
+		acg.workloadOnly = opts.WorkloadOnly
+		acg.summary = opts.Summary
+		acg.captureModule = opts.CaptureModule
+		acg.snapshot = opts.Snapshot
+		acg.nonStrict = opts.NonStrict
+		acg.builtinErrors = opts.Errors
+		acg.pluginCache = opts.Plugins
+		acg.metrics = opts.Metrics
+		acg.namingPolicy = opts.NamingPolicy
+		acg.renames = opts.Renames
+		acg.strictSecretStore = opts.StrictSecretStore
+		acg.objects = opts.Objects
+		acg.unmatchedImports = opts.UnmatchedImports
+		return acg, nil
+	}
+}
+
 func (g *appConfigurationGenerator) Generate(spec *v1.Spec) error {
+	return g.GenerateWithContext(context.Background(), spec)
+}
+
+// GenerateWithContext generates the resources of the app into the spec, which is aborted once the ctx is
+// done, e.g. the server request of the generation is cancelled. The ctx is checked between the generation
+// steps, and passed to the module invocations.
+func (g *appConfigurationGenerator) GenerateWithContext(ctx context.Context, spec *v1.Spec) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("generation of app %s is cancelled: %w", g.appName, err)
+	}
 	if spec.Resources == nil {
 		spec.Resources = make(v1.Resources, 0)
 	}
 	g.app.Name = g.appName
+	generatedBefore := len(spec.Resources)
+
+	// validate the secret stores before the modules resolve the secrets with them
+	if err := validateSecretStores(g.ws); err != nil {
+		return err
+	}
 
 	// retrieve the module configs of the specified project
 	projectModuleConfigs, err := workspace.GetProjectModuleConfigs(g.ws.Modules, g.project.Name)
@@ -134,58 +298,134 @@ func (g *appConfigurationGenerator) Generate(spec *v1.Spec) error {
 		return err
 	}
 
-	// retrieve the imported resources of the specified project
-	projectImportedResources := make(map[string]string)
-	for _, cfg := range projectModuleConfigs {
-		importedResources, err := workspace.GetStringMapFromGenericConfig(cfg, v1.FieldImportedResources)
-		if err != nil {
-			return err
-		}
-
-		for kusionID, importedID := range importedResources {
-			if id, ok := projectImportedResources[kusionID]; ok && id != importedID {
-				return fmt.Errorf("duplicate kusion id '%s' for importing different resources: '%s' and '%s'",
-					kusionID, id, importedID)
-			}
-			projectImportedResources[kusionID] = importedID
-		}
+	// retrieve the imported resources of the specified project, both inline and from the mapping files
+	projectImportedResources, err := g.getProjectImportedResources(projectModuleConfigs, true)
+	if err != nil {
+		return err
+	}
+	if err = g.checkImportedResourcesAcrossProjects(projectImportedResources); err != nil {
+		return err
 	}
 
-	// generate built-in resources
-	namespace := g.getNamespaceName()
-	gfs := []generators.NewSpecGeneratorFunc{
-		ns.NewNamespaceGeneratorFunc(namespace),
+	// generate built-in resources, the namespace is skipped for the cluster-scoped app
+	var namespace string
+	var defaultResourceNamespace bool
+	var gfs []generators.NewSpecGeneratorFunc
+	if !g.app.ClusterScoped {
+		namespace, defaultResourceNamespace, err = g.getNamespaceName()
+		if err == nil {
+			gfs = append(gfs, ns.NewNamespaceGeneratorFunc(namespace))
+		} else if err = g.builtinFailed(err); err != nil {
+			return err
+		}
 	}
+	secretStoreName, secretStore := g.getSecretStore(namespace)
+	g.secretStore = secretStore
 
-	if g.app.Workload != nil {
+	if !g.workloadOnly {
 		// todo: refactor secret into a module
-		gfs = append(gfs, secret.NewSecretGeneratorFunc(&secret.GeneratorRequest{
-			Project:     g.project.Name,
-			Namespace:   namespace,
-			Workload:    g.app.Workload,
-			SecretStore: g.ws.SecretStore,
-		}))
+		workloads := []v1.Accessory{g.app.Workload}
+		for _, name := range g.workloadNames() {
+			workloads = append(workloads, g.app.Workloads[name])
+		}
+		var secretsWithoutStore []string
+		for _, workload := range workloads {
+			if workload == nil {
+				continue
+			}
+			request := &secret.GeneratorRequest{
+				Project:              g.project.Name,
+				Namespace:            namespace,
+				Workload:             workload,
+				SecretStore:          secretStore,
+				SecretStoreName:      secretStoreName,
+				SecretStores:         g.ws.SecretStores,
+				SecretStoreSelectors: g.ws.SecretStoreSelectors,
+				SecretStoreFallbacks: g.ws.SecretStoreFallbacks,
+			}
+			missing, secretErr := secret.ExternalSecretsWithoutStore(request)
+			if secretErr != nil {
+				return secretErr
+			}
+			secretsWithoutStore = append(secretsWithoutStore, missing...)
+			gfs = append(gfs, secret.NewSecretGeneratorFunc(request))
+		}
+		// the external secrets without a secret store are generated with their refs unresolved, which fail the
+		// generation in the strict mode only
+		if len(secretsWithoutStore) > 0 {
+			msg := fmt.Sprintf("external secrets %s of app %s reference no secret store, please configure the secret store in workspace %s",
+				strings.Join(secretsWithoutStore, ", "), g.appName, g.ws.Name)
+			if g.strictSecretStore {
+				return errors.New(msg)
+			}
+			log.Warn(msg)
+		}
 	}
 
-	if err = generators.CallGenerators(spec, gfs...); err != nil {
-		return err
+	for _, gf := range gfs {
+		if err = generators.CallGeneratorsWithContext(ctx, spec, gf); err != nil {
+			if err = g.builtinFailed(err); err != nil {
+				return err
+			}
+		}
 	}
+	g.recordResources(builtinModuleKey, len(spec.Resources)-generatedBefore)
 
 	// call modules to generate customized resources
-	wl, resources, patchers, err := g.callModules(projectModuleConfigs)
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("generation of app %s is cancelled: %w", g.appName, err)
+	}
+	wl, resources, patchers, err := g.callModules(ctx, projectModuleConfigs)
 	if err != nil {
 		return err
 	}
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("generation of app %s is cancelled: %w", g.appName, err)
+	}
+	if defaultResourceNamespace {
+		defaultNamespaces(namespace, wl, resources, patchers)
+	}
 
 	// append the generated resources to the spec
+	moduleResourcesStart := len(spec.Resources)
 	if wl != nil {
 		spec.Resources = append(spec.Resources, *wl)
 	}
 	spec.Resources = append(spec.Resources, resources...)
 
-	// patch workload with resource patchers
+	// the workloads of the app, i.e. the workload and the named workloads, are the module resources marked
+	// with the isWorkload extension, the same as the dry run of the patchers
+	var workloads []*v1.Resource
+	for i := moduleResourcesStart; i < len(spec.Resources); i++ {
+		if isWorkloadResource(&spec.Resources[i]) {
+			workloads = append(workloads, &spec.Resources[i])
+		}
+	}
+
+	// patch workloads with resource patchers, the app without a workload is patched by the JSON patchers only
+	patchWorkload := func(patcher *v1.Patcher) error {
+		for _, workload := range workloads {
+			err := PatchWorkload(workload, patcher)
+			var patchErr *PatchError
+			if errors.As(err, &patchErr) {
+				log.Errorf("failed to patch %s of workload %s, container: %q, error: %v",
+					patchErr.Kind, patchErr.WorkloadID, patchErr.Container, patchErr.Err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// the env of the patchers are merged and patched at once, so that they are in the order of the patchers
+	if envs := mergeEnvironments(patchers); len(envs) != 0 {
+		if err = patchWorkload(&v1.Patcher{Environments: envs}); err != nil {
+			return err
+		}
+	}
 	for _, patcher := range patchers {
-		if err = PatchWorkload(wl, &patcher); err != nil {
+		patcher.Environments = nil
+		if err = patchWorkload(&patcher); err != nil {
 			return err
 		}
 		if err = JSONPatch(spec.Resources, &patcher); err != nil {
@@ -194,9 +434,51 @@ func (g *appConfigurationGenerator) Generate(spec *v1.Spec) error {
 	}
 
 	// Patch the imported resource IDs to the resource `extensions` in Spec.
-	if err = patchImportedResources(spec.Resources, projectImportedResources); err != nil {
+	keepAttributes, err := getImportedResourcesKeepAttributes(projectModuleConfigs)
+	if err != nil {
+		return err
+	}
+	unmatched, err := patchImportedResources(spec.Resources, projectImportedResources, keepAttributes, g.resourceModules)
+	if err != nil {
+		return err
+	}
+	if g.unmatchedImports != nil {
+		*g.unmatchedImports = unmatched
+	} else if len(unmatched) > 0 {
+		log.Warnf("imported resources of project %s match no resource generated so far, please check the kusion IDs: %s",
+			g.project.Name, strings.Join(unmatched, ", "))
+	}
+
+	// Patch the default retry policy of the workspace to the resources without their own retry policy.
+	patchRetryPolicy(spec.Resources, g.ws.RetryPolicy)
+
+	// Rename the resources generated by this app by the naming policy, along with the references to them.
+	renamedNamespaces, err := applyNamingPolicy(g.getNamingPolicy(), spec.Resources[generatedBefore:])
+	if err != nil {
 		return err
 	}
+	if renamed, ok := renamedNamespaces[namespace]; ok {
+		namespace = renamed
+	}
+
+	// Rename the resources generated by this app by the rename map, along with the references to them.
+	if len(g.renames) > 0 {
+		appSpec := &v1.Spec{Resources: spec.Resources[generatedBefore:]}
+		if err = generators.CallGenerators(appSpec, rename.NewRenameGeneratorFunc(g.renames...)); err != nil {
+			return err
+		}
+	}
+
+	// Generate the parent object of the app and stamp the owner references to it onto the resources
+	// generated by this app, so that deleting the parent object cascades to them.
+	if g.ws.OwnerReference != nil {
+		appSpec := &v1.Spec{Resources: spec.Resources[generatedBefore:]}
+		if err = generators.CallGenerators(appSpec, ownerreference.NewOwnerReferenceGeneratorFunc(
+			g.ws.OwnerReference, g.project.Name, g.stack.Name, g.appName, namespace)); err != nil {
+			return err
+		}
+		spec.Resources = append(spec.Resources[:generatedBefore], appSpec.Resources...)
+	}
 
 	// The OrderedResourcesGenerator should be executed after all resources are generated.
 	if err = generators.CallGenerators(spec, orderedres.NewOrderedResourcesGeneratorFunc()); err != nil {
@@ -213,61 +495,462 @@ func (g *appConfigurationGenerator) Generate(spec *v1.Spec) error {
 		spec.Context = g.ws.Context
 	}
 
+	if g.summary != nil {
+		g.summary.Name = g.appName
+		g.summary.Total = len(spec.Resources) - generatedBefore
+	}
+
+	if g.capturing() {
+		if err = g.captureSnapshot(spec.Resources[generatedBefore:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patchRetryPolicy patches the retry policy to the extensions of the resources, while the retry
+// policy carried by the module output is kept.
+func patchRetryPolicy(resources v1.Resources, policy *v1.RetryPolicy) {
+	if policy == nil {
+		return
+	}
+	for i := range resources {
+		if resources[i].Extensions[v1.FieldRetryPolicy] != nil {
+			continue
+		}
+		if resources[i].Extensions == nil {
+			resources[i].Extensions = make(map[string]interface{})
+		}
+		resources[i].Extensions[v1.FieldRetryPolicy] = map[string]interface{}{
+			"maxRetries":     policy.MaxRetries,
+			"backoffSeconds": policy.BackoffSeconds,
+		}
+	}
+}
+
+// recordResources records the number of resources generated by the module in the summary.
+func (g *appConfigurationGenerator) recordResources(moduleKey string, count int) {
+	if g.summary == nil {
+		return
+	}
+	if g.summary.Modules == nil {
+		g.summary.Modules = make(map[string]int)
+	}
+	g.summary.Modules[moduleKey] += count
+}
+
+// builtinFailed returns the failure of the built-in generators, which is collected and skipped instead in
+// the non-strict mode.
+func (g *appConfigurationGenerator) builtinFailed(err error) error {
+	if !g.nonStrict {
+		return err
+	}
+	err = fmt.Errorf("built-in generator of app %s failed: %w", g.appName, err)
+	log.Warnf("%v, continue in the non-strict mode", err)
+	if g.builtinErrors != nil {
+		*g.builtinErrors = append(*g.builtinErrors, err)
+	}
 	return nil
 }
 
+// recordModuleOf records the name of the module generating the resource, which selects the attributes
+// kept for the resource if it is imported.
+func (g *appConfigurationGenerator) recordModuleOf(moduleName string, res *v1.Resource) {
+	if g.resourceModules != nil {
+		g.resourceModules[res.ID] = moduleName
+	}
+}
+
+// JSONPatch applies the JSON patchers of the patcher to the resources. The JSON patchers are applied in
+// the order of the target resource IDs, so that the generated Spec is the same across the generations,
+// followed by the JSON patch selectors in order.
 func JSONPatch(resources v1.Resources, patcher *v1.Patcher) error {
 	if resources == nil || patcher == nil {
 		return nil
 	}
 
+	targets, warnings, err := jsonPatchTargets(resources, patcher)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+	for _, target := range targets {
+		attributes, err := patchAttributes(target.resource.ID, target.resource.Attributes, target.patcher)
+		if err != nil {
+			return err
+		}
+		target.resource.Attributes = attributes
+	}
+	return nil
+}
+
+// JSONPatchDryRun returns the unified diffs of the attributes of the resources targeted by the JSON patchers,
+// keyed by the resource ID, without modifying the resources. The diff is empty if the patch makes no change.
+func JSONPatchDryRun(resources v1.Resources, patcher *v1.Patcher) (map[string]string, error) {
+	diffs := make(map[string]string)
+	if resources == nil || patcher == nil {
+		return diffs, nil
+	}
+
+	targeted, patched, _, err := dryRunJSONPatch(resources, patcher)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range targeted {
+		id := res.ID
+		before, err := yaml.Marshal(res.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		after, err := yaml.Marshal(patched[id])
+		if err != nil {
+			return nil, err
+		}
+		diffs[id] = unifiedDiff(id, string(before), string(after))
+	}
+	return diffs, nil
+}
+
+// dryRunJSONPatch applies the JSON patchers to the attributes of the targeted resources without modifying
+// the resources. It returns the targeted resources in order, their patched attributes keyed by the resource
+// ID, and the warnings of the JSON patchers skipped.
+func dryRunJSONPatch(resources v1.Resources, patcher *v1.Patcher) ([]*v1.Resource, map[string]map[string]interface{}, []string, error) {
+	targets, warnings, err := jsonPatchTargets(resources, patcher)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// patched records the patched attributes of the resources, as a resource can be targeted multiple times
+	patched := make(map[string]map[string]interface{})
+	var targeted []*v1.Resource
+	for _, target := range targets {
+		id := target.resource.ID
+		attributes, ok := patched[id]
+		if !ok {
+			attributes = target.resource.Attributes
+			targeted = append(targeted, target.resource)
+		}
+		if patched[id], err = patchAttributes(id, attributes, target.patcher); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return targeted, patched, warnings, nil
+}
+
+// jsonPatchTarget is a resource to be patched by a JSON patcher.
+type jsonPatchTarget struct {
+	resource *v1.Resource
+	patcher  v1.JSONPatcher
+}
+
+// jsonPatchTargets resolves the resources targeted by the JSON patchers in the order of their IDs, followed
+// by the resources selected by the JSON patch selectors in the order of the selectors and the resources.
+// The JSON patchers targeting no resources are skipped with the warnings returned.
+func jsonPatchTargets(resources v1.Resources, patcher *v1.Patcher) ([]jsonPatchTarget, []string, error) {
+	var targets []jsonPatchTarget
+	var warnings []string
 	resIndex := resources.Index()
+	_ = generators.ForeachOrdered(patcher.JSONPatchers, func(id string, jsonPatcher v1.JSONPatcher) error {
+		res, ok := resIndex[id]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("target patch resource %s not found, skipped", id))
+			return nil
+		}
+		targets = append(targets, jsonPatchTarget{resource: res, patcher: jsonPatcher})
+		return nil
+	})
 
-	if patcher.JSONPatchers != nil {
-		for id, jsonPatcher := range patcher.JSONPatchers {
-			res, ok := resIndex[id]
-			if !ok {
-				log.Warnf("target patch resource %s not found, skipped", id)
-				continue
+	for i, selector := range patcher.JSONPatchSelectors {
+		if selector.APIVersion == "" && selector.Kind == "" && len(selector.Labels) == 0 {
+			return nil, nil, fmt.Errorf("json patch selector %d selects no resources, apiVersion, kind or labels must be set", i)
+		}
+		matched := false
+		for j := range resources {
+			if selectorMatches(&selector, resources[j].Attributes) {
+				targets = append(targets, jsonPatchTarget{resource: &resources[j], patcher: selector.Patcher})
+				matched = true
 			}
+		}
+		if !matched {
+			warnings = append(warnings, fmt.Sprintf("no resources matching json patch selector %d, skipped", i))
+		}
+	}
+	return targets, warnings, nil
+}
 
-			target := jsonutil.Marshal2String(res.Attributes)
-			switch jsonPatcher.Type {
-			case v1.MergePatch:
-				modified, err := jsonpatch.MergePatch([]byte(target), jsonPatcher.Payload)
-				if err != nil {
-					return fmt.Errorf("merge patch to:%s failed with error %w", id, err)
-				}
-				if err = json.Unmarshal(modified, &res.Attributes); err != nil {
-					return err
-				}
-			case v1.JSONPatch:
-				patch, err := jsonpatch.DecodePatch(jsonPatcher.Payload)
-				if err != nil {
-					return fmt.Errorf("decode json patch:%s failed with error %w", jsonPatcher.Payload, err)
-				}
+// selectorMatches reports whether the attributes of the resource match the apiVersion, kind and labels of
+// the JSON patch selector.
+func selectorMatches(selector *v1.JSONPatchSelector, attributes map[string]interface{}) bool {
+	apiVersion, _ := attributes["apiVersion"].(string)
+	kind, _ := attributes["kind"].(string)
+	if selector.APIVersion != "" && selector.APIVersion != apiVersion {
+		return false
+	}
+	if selector.Kind != "" && selector.Kind != kind {
+		return false
+	}
+	if len(selector.Labels) == 0 {
+		return true
+	}
+	labels, _, err := unstructured.NestedStringMap(attributes, "metadata", "labels")
+	if err != nil {
+		return false
+	}
+	for k, v := range selector.Labels {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
 
-				// Apply JSON Patch with options to allow missing path on `remove`,
-				// and ensure path exists on `add`.
-				applyOpts := jsonpatch.NewApplyOptions()
-				applyOpts.AllowMissingPathOnRemove = true
-				applyOpts.EnsurePathExistsOnAdd = true
+// patchAttributes applies the JSON patcher to the attributes of the resource and returns the patched
+// attributes, the attributes passed in are not modified.
+func patchAttributes(id string, attributes map[string]interface{}, jsonPatcher v1.JSONPatcher) (map[string]interface{}, error) {
+	// patch an empty object rather than `null` for the resource without attributes
+	target := "{}"
+	if attributes != nil {
+		target = jsonutil.Marshal2String(attributes)
+	}
 
-				modified, err := patch.ApplyWithOptions([]byte(target), applyOpts)
-				if err != nil {
-					return fmt.Errorf("apply json patch to:%s failed with error %w", id, err)
-				}
-				if err = json.Unmarshal(modified, &res.Attributes); err != nil {
-					return err
+	var modified []byte
+	switch jsonPatcher.Type {
+	case v1.MergePatch:
+		var err error
+		modified, err = jsonpatch.MergePatch([]byte(target), jsonPatcher.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("merge patch to:%s failed with error %w", id, err)
+		}
+	case v1.StrategicMergePatch:
+		var err error
+		modified, err = strategicMergePatch(attributes, []byte(target), jsonPatcher.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("strategic merge patch to:%s failed with error %w", id, err)
+		}
+	case v1.JSONPatch:
+		patch, err := jsonpatch.DecodePatch(jsonPatcher.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("decode json patch:%s failed with error %w", jsonPatcher.Payload, err)
+		}
+
+		// Apply JSON Patch with options to allow missing path on `remove`,
+		// and ensure path exists on `add` by default, which can be disabled by the patcher.
+		applyOpts := jsonpatch.NewApplyOptions()
+		applyOpts.AllowMissingPathOnRemove = boolOrDefault(jsonPatcher.AllowMissingPathOnRemove, true)
+		applyOpts.EnsurePathExistsOnAdd = boolOrDefault(jsonPatcher.EnsurePathExistsOnAdd, true)
+
+		modified, err = patch.ApplyWithOptions([]byte(target), applyOpts)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				if testErr := findFailedTestOp(id, patch, []byte(target), applyOpts); testErr != nil {
+					return nil, testErr
 				}
-			default:
-				return fmt.Errorf("unsupported patch type:%s", jsonPatcher.Type)
 			}
+			return nil, fmt.Errorf("apply json patch to:%s failed with error %w", id, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported patch type:%s", jsonPatcher.Type)
+	}
+	return decodePatchedAttributes(id, modified)
+}
+
+// strategicMergePatch applies the strategic merge patch with the patch strategies of the Kubernetes type of
+// the attributes, which is looked up by the apiVersion and kind. The attributes of the unknown types, such as
+// the custom resources, are patched with the JSON merge patch.
+func strategicMergePatch(attributes map[string]interface{}, target, payload []byte) ([]byte, error) {
+	un := &unstructured.Unstructured{Object: attributes}
+	if gvk := un.GroupVersionKind(); !gvk.Empty() {
+		if obj, err := scheme.Scheme.New(gvk); err == nil {
+			return strategicpatch.StrategicMergePatch(target, payload, obj)
+		}
+	}
+	return jsonpatch.MergePatch(target, payload)
+}
+
+// unifiedDiff returns the unified diff of the before and after text in a single hunk with the full context,
+// or an empty string if they are the same.
+func unifiedDiff(id, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	dmp := diffmatchpatch.New()
+	beforeChars, afterChars, lines := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(beforeChars, afterChars, false), lines)
+
+	var body strings.Builder
+	beforeLines, afterLines := 0, 0
+	for _, d := range diffs {
+		prefix := " "
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			if d.Type != diffmatchpatch.DiffInsert {
+				beforeLines++
+			}
+			if d.Type != diffmatchpatch.DiffDelete {
+				afterLines++
+			}
+			body.WriteString(prefix + line)
+			if !strings.HasSuffix(line, "\n") {
+				body.WriteString("\n")
+			}
+		}
+	}
+	return fmt.Sprintf("--- %s (before)\n+++ %s (after)\n@@ -%s +%s @@\n%s",
+		id, id, hunkRange(beforeLines), hunkRange(afterLines), body.String())
+}
+
+func hunkRange(lines int) string {
+	if lines == 0 {
+		return "0,0"
+	}
+	return fmt.Sprintf("1,%d", lines)
+}
+
+// JSONPatchTestError is the error of a failed `test` operation in the JSON patch, which is used as a guard
+// against patching the resource in an unexpected state.
+type JSONPatchTestError struct {
+	// ResourceID is the ID of the patched resource.
+	ResourceID string
+	// Path is the path of the failed test operation.
+	Path string
+	// Expected is the value expected by the failed test operation.
+	Expected interface{}
+}
+
+func (e *JSONPatchTestError) Error() string {
+	return fmt.Sprintf("apply json patch to:%s failed, test operation on path %s does not match the expected value %s",
+		e.ResourceID, e.Path, jsonutil.Marshal2String(e.Expected))
+}
+
+func (e *JSONPatchTestError) Unwrap() error {
+	return jsonpatch.ErrTestFailed
+}
+
+// findFailedTestOp replays the operations of the patch one by one to locate the failed test operation,
+// returns nil if the patch fails on an operation other than test.
+func findFailedTestOp(id string, patch jsonpatch.Patch, doc []byte, applyOpts *jsonpatch.ApplyOptions) error {
+	for _, op := range patch {
+		var err error
+		doc, err = jsonpatch.Patch{op}.ApplyWithOptions(doc, applyOpts)
+		if err == nil {
+			continue
 		}
+		if op.Kind() != "test" || !errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil
+		}
+		path, _ := op.Path()
+		expected, _ := op.ValueInterface()
+		return &JSONPatchTestError{ResourceID: id, Path: path, Expected: expected}
 	}
 	return nil
 }
 
+// decodePatchedAttributes decodes the patched document as the attributes of the resource. As the attributes
+// of a resource are always a JSON object, a patched document with a non-object root is rejected.
+func decodePatchedAttributes(id string, modified []byte) (map[string]interface{}, error) {
+	var patched interface{}
+	if err := json.Unmarshal(modified, &patched); err != nil {
+		return nil, fmt.Errorf("unmarshal patched attributes of resource:%s failed with error %w", id, err)
+	}
+	attributes, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("patched attributes of resource:%s must be a JSON object, but got %s", id, jsonRootKind(patched))
+	}
+	return attributes, nil
+}
+
+func jsonRootKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func boolOrDefault(b *bool, defaultValue bool) bool {
+	if b == nil {
+		return defaultValue
+	}
+	return *b
+}
+
+// PatchKind is the kind of the patch applied to the workload.
+type PatchKind string
+
+const (
+	PatchKindLabels                   PatchKind = "labels"
+	PatchKindPodLabels                PatchKind = "podLabels"
+	PatchKindAnnotations              PatchKind = "annotations"
+	PatchKindPodAnnotations           PatchKind = "podAnnotations"
+	PatchKindEnv                      PatchKind = "env"
+	PatchKindResources                PatchKind = "resources"
+	PatchKindNodeSelector             PatchKind = "nodeSelector"
+	PatchKindTolerations              PatchKind = "tolerations"
+	PatchKindAffinity                 PatchKind = "affinity"
+	PatchKindInitContainers           PatchKind = "initContainers"
+	PatchKindVolumes                  PatchKind = "volumes"
+	PatchKindImagePullSecrets         PatchKind = "imagePullSecrets"
+	PatchKindPodSecurityContext       PatchKind = "podSecurityContext"
+	PatchKindContainerSecurityContext PatchKind = "containerSecurityContext"
+	PatchKindReplicas                 PatchKind = "replicas"
+	PatchKindCommand                  PatchKind = "command"
+	PatchKindExtensions               PatchKind = "extensions"
+)
+
+// PatchError is the error of patching the workload, which carries the context of the failed patch.
+type PatchError struct {
+	// WorkloadID is the ID of the patched workload.
+	WorkloadID string
+	// Kind is the kind of the failed patch.
+	Kind PatchKind
+	// Container is the name of the patched container, empty if the patch is not container-specific
+	// or the container has no name.
+	Container string
+	// Err is the underlying error.
+	Err error
+}
+
+func newPatchError(workloadID string, kind PatchKind, container string, err error) *PatchError {
+	return &PatchError{WorkloadID: workloadID, Kind: kind, Container: container, Err: err}
+}
+
+func (e *PatchError) Error() string {
+	if e.Container != "" {
+		return fmt.Sprintf("failed to patch %s of workload:%s, container:%s. %v", e.Kind, e.WorkloadID, e.Container, e.Err)
+	}
+	return fmt.Sprintf("failed to patch %s of workload:%s. %v", e.Kind, e.WorkloadID, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// containerName returns the name of the unstructured container.
+func containerName(container map[string]interface{}) string {
+	name, _ := container["name"].(string)
+	return name
+}
+
 func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 	if patcher == nil {
 		return nil
@@ -279,21 +962,32 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 	}
 
 	un := &unstructured.Unstructured{}
+	// the attributes are patched in place, so the workload without attributes is given an empty one
+	if workload.Attributes == nil {
+		workload.Attributes = make(map[string]interface{})
+	}
 	attributes := workload.Attributes
 
-	// normalize attributes with K8s json util. Especially numbers are converted to int64 or float64
-	out, err := k8sjson.Marshal(attributes)
-	if err != nil {
-		return err
-	}
-	if err = k8sjson.Unmarshal(out, &attributes); err != nil {
-		return err
+	// normalize attributes of the Kubernetes workload with K8s json util. Especially numbers are converted to
+	// int64 or float64. The attributes of the other types, e.g. Terraform, are patched as is, since the
+	// normalization coerces the types in ways the providers don't expect, e.g. 1.0 to 1.
+	if workload.Type == v1.Kubernetes {
+		out, err := k8sjson.Marshal(attributes)
+		if err != nil {
+			return fmt.Errorf("failed to normalize attributes of workload:%s. %w", workload.ID, err)
+		}
+		if err = k8sjson.Unmarshal(out, &attributes); err != nil {
+			return fmt.Errorf("failed to normalize attributes of workload:%s. %w", workload.ID, err)
+		}
 	}
 	un.SetUnstructuredContent(attributes)
 
 	// patch labels
 	if patcher.Labels != nil {
-		objLabels := un.GetLabels()
+		objLabels, _, err := unstructured.NestedStringMap(un.Object, "metadata", "labels")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindLabels, "", fmt.Errorf("failed to get labels: %w", err))
+		}
 		if objLabels == nil {
 			objLabels = make(map[string]string)
 		}
@@ -308,14 +1002,16 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 
 			objLabels[k] = v
 		}
-		un.SetLabels(objLabels)
+		if err = unstructured.SetNestedStringMap(un.Object, objLabels, "metadata", "labels"); err != nil {
+			return newPatchError(workload.ID, PatchKindLabels, "", err)
+		}
 	}
 
 	// patch pod labels
 	if patcher.PodLabels != nil {
 		podLabels, b, err := unstructured.NestedStringMap(un.Object, "spec", "template", "metadata", "labels")
 		if err != nil {
-			return fmt.Errorf("failed to get pod labels from workload:%s. %w", workload.ID, err)
+			return newPatchError(workload.ID, PatchKindPodLabels, "", fmt.Errorf("failed to get pod labels: %w", err))
 		}
 		if !b || podLabels == nil {
 			podLabels = make(map[string]string)
@@ -333,14 +1029,17 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 		}
 		err = unstructured.SetNestedStringMap(un.Object, podLabels, "spec", "template", "metadata", "labels")
 		if err != nil {
-			return err
+			return newPatchError(workload.ID, PatchKindPodLabels, "", err)
 		}
 	}
 
 	// patch annotations
 	if patcher.Annotations != nil {
-		objAnnotations := un.GetAnnotations()
-		if objAnnotations == nil {
+		objAnnotations, _, err := unstructured.NestedStringMap(un.Object, "metadata", "annotations")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindAnnotations, "", fmt.Errorf("failed to get annotations: %w", err))
+		}
+		if objAnnotations == nil {
 			objAnnotations = make(map[string]string)
 		}
 		// merge annotations
@@ -354,14 +1053,16 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 
 			objAnnotations[k] = v
 		}
-		un.SetAnnotations(objAnnotations)
+		if err = unstructured.SetNestedStringMap(un.Object, objAnnotations, "metadata", "annotations"); err != nil {
+			return newPatchError(workload.ID, PatchKindAnnotations, "", err)
+		}
 	}
 
 	// patch pod annotations
 	if patcher.PodAnnotations != nil {
 		podAnnotations, b, err := unstructured.NestedStringMap(un.Object, "spec", "template", "metadata", "annotations")
 		if err != nil {
-			return fmt.Errorf("failed to get pod annotations from workload:%s. %w", workload.ID, err)
+			return newPatchError(workload.ID, PatchKindPodAnnotations, "", fmt.Errorf("failed to get pod annotations: %w", err))
 		}
 		if !b || podAnnotations == nil {
 			podAnnotations = make(map[string]string)
@@ -379,7 +1080,7 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 		}
 		err = unstructured.SetNestedStringMap(un.Object, podAnnotations, "spec", "template", "metadata", "annotations")
 		if err != nil {
-			return err
+			return newPatchError(workload.ID, PatchKindPodAnnotations, "", err)
 		}
 	}
 
@@ -387,7 +1088,7 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 	if patcher.Environments != nil {
 		containers, b, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "containers")
 		if err != nil || !b {
-			return fmt.Errorf("failed to get containers from workload:%s. %w", workload.ID, err)
+			return newPatchError(workload.ID, PatchKindEnv, "", fmt.Errorf("failed to get containers: %w", err))
 		}
 
 		// Split the environment variables to be removed and the ones to be merged.
@@ -403,10 +1104,13 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 		}
 
 		for i, c := range containers {
-			container := c.(map[string]interface{})
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return newPatchError(workload.ID, PatchKindEnv, "", fmt.Errorf("container %d is not a map: %v", i, c))
+			}
 			envs, b, err := unstructured.NestedSlice(container, "env")
 			if err != nil {
-				return fmt.Errorf("failed to get env from workload:%s, container:%s. %w", workload.ID, container["name"], err)
+				return newPatchError(workload.ID, PatchKindEnv, containerName(container), fmt.Errorf("failed to get env: %w", err))
 			}
 			if !b {
 				envs = make([]interface{}, 0)
@@ -419,7 +1123,11 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 						log.Errorf("failed to assert the unstructured env type: %v", envs[i])
 						continue
 					} else {
-						name := e["name"].(string)
+						name, ok := e["name"].(string)
+						if !ok {
+							return newPatchError(workload.ID, PatchKindEnv, containerName(container),
+								fmt.Errorf("env %d has no name of string: %v", i, e["name"]))
+						}
 						if name == env.Name {
 							envs = append(envs[:i], envs[i+1:]...)
 							i--
@@ -431,267 +1139,1527 @@ func PatchWorkload(workload *v1.Resource, patcher *v1.Patcher) error {
 			}
 
 			// merge env
+			patched := make([]interface{}, 0, len(envsToMerge))
 			for _, env := range envsToMerge {
 				us, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&env)
 				if err != nil {
-					return err
+					return newPatchError(workload.ID, PatchKindEnv, containerName(container), err)
 				}
-				// prepend patch env to existing env slices so developers can reference them later on
-				// ref: https://kubernetes.io/docs/tasks/inject-data-application/define-interdependent-environment-variables/
-				envs = append([]interface{}{us}, envs...)
+				// the env declared again in the patcher replaces the former one
+				patched = append(removeByName(patched, env.Name), us)
 				log.Infof("we're gonna patch env:%s,value:%s to workload:%s, container:%s", env.Name, env.Value, workload.ID,
 					container["name"])
 			}
+			// prepend patch env to existing env slices in the declaration order so developers can reference them later on
+			// ref: https://kubernetes.io/docs/tasks/inject-data-application/define-interdependent-environment-variables/
+			envs = append(patched, envs...)
 
 			container["env"] = envs
 			containers[i] = container
 		}
 
 		if err = unstructured.SetNestedSlice(un.Object, containers, "spec", "template", "spec", "containers"); err != nil {
-			return err
+			return newPatchError(workload.ID, PatchKindEnv, "", err)
 		}
 	}
 
-	return nil
-}
+	// patch resources
+	if patcher.Resources != nil {
+		containers, b, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "containers")
+		if err != nil || !b {
+			return newPatchError(workload.ID, PatchKindResources, "", fmt.Errorf("failed to get containers: %w", err))
+		}
 
-// moduleConfig represents the configuration of a module, either devConfig or platformConfig can be nil
-type moduleConfig struct {
-	devConfig      v1.Accessory
-	platformConfig v1.GenericConfig
-	ctx            v1.GenericConfig
-}
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return newPatchError(workload.ID, PatchKindResources, "", fmt.Errorf("container %d is not a map: %v", i, c))
+			}
+			for field, patch := range map[string]map[string]string{
+				"requests": patcher.Resources.Requests,
+				"limits":   patcher.Resources.Limits,
+			} {
+				if patch == nil {
+					continue
+				}
+				resources, _, err := unstructured.NestedMap(container, "resources", field)
+				if err != nil {
+					return newPatchError(workload.ID, PatchKindResources, containerName(container), fmt.Errorf("failed to get resource %s: %w", field, err))
+				}
+				if resources == nil {
+					resources = make(map[string]interface{})
+				}
+				for k, v := range patch {
+					// NOTE: we implement value-based map removal by agreeing on a specific value
+					// `ops://kusionstack.io/remove` as the `remove` operation index for resource patcher.
+					if v == removalVal {
+						delete(resources, k)
+						continue
+					}
 
-func (g *appConfigurationGenerator) callModules(projectModuleConfigs map[string]v1.GenericConfig) (workload *v1.Resource, resources []v1.Resource, patchers []v1.Patcher, err error) {
-	pluginMap := make(map[string]*module.Plugin)
-	defer func() {
-		if e := recover(); e != nil {
-			switch x := e.(type) {
-			case string:
-				err = fmt.Errorf("call modules panic:%s", e)
-			case error:
-				err = x
-			default:
-				err = errors.New("call modules unknown panic")
+					resources[k] = v
+				}
+				if err = unstructured.SetNestedMap(container, resources, "resources", field); err != nil {
+					return newPatchError(workload.ID, PatchKindResources, containerName(container), err)
+				}
 			}
+			containers[i] = container
 		}
-		for _, plugin := range pluginMap {
-			pluginErr := plugin.KillPluginClient()
-			if pluginErr != nil {
-				err = fmt.Errorf("kill modules failed %w. %s", err, pluginErr)
+
+		if err = unstructured.SetNestedSlice(un.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+			return newPatchError(workload.ID, PatchKindResources, "", err)
+		}
+	}
+
+	// patch node selector, the pod template is located at spec.template for Deployment, StatefulSet and Job
+	if patcher.NodeSelector != nil {
+		nodeSelector, _, err := unstructured.NestedStringMap(un.Object, "spec", "template", "spec", "nodeSelector")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindNodeSelector, "", fmt.Errorf("failed to get node selector: %w", err))
+		}
+		if nodeSelector == nil {
+			nodeSelector = make(map[string]string)
+		}
+		for k, v := range patcher.NodeSelector {
+			if v == removalVal {
+				delete(nodeSelector, k)
+				continue
 			}
+			nodeSelector[k] = v
 		}
+		if err = unstructured.SetNestedStringMap(un.Object, nodeSelector, "spec", "template", "spec", "nodeSelector"); err != nil {
+			return newPatchError(workload.ID, PatchKindNodeSelector, "", err)
+		}
+	}
+
+	// patch tolerations
+	if patcher.Tolerations != nil {
+		tolerations, _, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "tolerations")
 		if err != nil {
-			log.Errorf(err.Error())
+			return newPatchError(workload.ID, PatchKindTolerations, "", fmt.Errorf("failed to get tolerations: %w", err))
 		}
-	}()
 
-	if g.dependencies == nil {
-		return nil, nil, nil, errors.New("dependencies should not be nil")
-	}
+		for _, toleration := range patcher.Tolerations {
+			us, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&toleration)
+			if err != nil {
+				return newPatchError(workload.ID, PatchKindTolerations, "", err)
+			}
+			// NOTE: the toleration with the value `ops://kusionstack.io/remove` removes the existing
+			// tolerations with the same key, and the same effect if the effect is specified.
+			if toleration.Value == removalVal {
+				tolerations = removeTolerations(tolerations, us)
+				continue
+			}
+			tolerations = mergeToleration(tolerations, us)
+		}
 
-	// build module config index
-	indexModuleConfig, err := g.buildModuleConfigIndex(projectModuleConfigs)
-	if err != nil {
-		return nil, nil, nil, err
+		if err = unstructured.SetNestedSlice(un.Object, tolerations, "spec", "template", "spec", "tolerations"); err != nil {
+			return newPatchError(workload.ID, PatchKindTolerations, "", err)
+		}
 	}
 
-	workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies)
-	if err != nil {
-		return nil, nil, nil, err
+	// patch affinity
+	if patcher.Affinity != nil {
+		affinity, _, err := unstructured.NestedMap(un.Object, "spec", "template", "spec", "affinity")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindAffinity, "", fmt.Errorf("failed to get affinity: %w", err))
+		}
+		patchAffinity, err := runtime.DefaultUnstructuredConverter.ToUnstructured(patcher.Affinity)
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindAffinity, "", err)
+		}
+		if err = unstructured.SetNestedMap(un.Object, deepMerge(affinity, patchAffinity), "spec", "template", "spec", "affinity"); err != nil {
+			return newPatchError(workload.ID, PatchKindAffinity, "", err)
+		}
 	}
 
-	// generate customized module resources
-	for t, config := range indexModuleConfig {
-		response, err := g.invokeModule(pluginMap, t, config)
+	// patch init containers
+	if patcher.InitContainers != nil {
+		initContainers, _, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "initContainers")
 		if err != nil {
-			return nil, nil, nil, err
+			return newPatchError(workload.ID, PatchKindInitContainers, "", fmt.Errorf("failed to get init containers: %w", err))
 		}
-		// Patch health policy to the resources
-		healthPolicy := config.platformConfig[v1.FieldHealthPolicy]
-		// parse module result
-		// if only one resource exists in the workload module, it is the workload
-		if workloadKey == t && len(response.Resources) == 1 {
-			workload = &v1.Resource{}
-			err = yaml.Unmarshal(response.Resources[0], workload)
-			if err != nil {
-				return nil, nil, nil, err
-			}
-			// add isWorkload extension to workload to mark workload
-			workload.Extensions[isWorkload] = true
-			// Add healthPolicy to workload extensions
-			if healthPolicy != nil && workload != nil {
-				patchHealthPolicy(workload, healthPolicy)
-			}
-		} else {
-			for _, res := range response.Resources {
-				temp := &v1.Resource{}
-				err = yaml.Unmarshal(res, temp)
-				if err != nil {
-					return nil, nil, nil, err
-				}
-				// filter out workload
-				if workloadKey == t && temp.Extensions[isWorkload] == "true" {
-					workload = temp
-				} else {
-					resources = append(resources, *temp)
+
+		// index the existing init containers by name to replace them in place
+		index := make(map[string]int, len(initContainers))
+		for i, c := range initContainers {
+			if container, ok := c.(map[string]interface{}); ok {
+				if name, ok := container["name"].(string); ok {
+					index[name] = i
 				}
 			}
 		}
-		if hp, ok := healthPolicy.(v1.GenericConfig); ok {
-			for _, res := range resources {
-				if res.Type == v1.Kubernetes {
-					resAPIVersion, resKind := getAPIVersionKindFromAttributes(res.Attributes)
-					hpAPIVersion, hpKind := getAPIVersionKindFromHealthPolicy(hp)
-					if strings.EqualFold(resAPIVersion, hpAPIVersion) && strings.EqualFold(resKind, hpKind) {
-						patchHealthPolicy(&res, hp)
-					}
-				}
+
+		var prepended []interface{}
+		for _, container := range patcher.InitContainers {
+			us, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&container)
+			if err != nil {
+				return newPatchError(workload.ID, PatchKindInitContainers, "", err)
+			}
+			if i, ok := index[container.Name]; ok {
+				initContainers[i] = us
+				log.Infof("we're gonna replace init container:%s of workload:%s", container.Name, workload.ID)
+				continue
 			}
+			prepended = append(prepended, us)
+			log.Infof("we're gonna patch init container:%s to workload:%s", container.Name, workload.ID)
 		}
-		// parse patcher
-		temp := &v1.Patcher{}
-		if response.Patcher != nil {
-			err = yaml.Unmarshal(response.Patcher, temp)
-			if err != nil {
-				return nil, nil, nil, err
+
+		// prepend the new init containers in order, so that the existing init containers can depend on them
+		initContainers = append(prepended, initContainers...)
+		if err = unstructured.SetNestedSlice(un.Object, initContainers, "spec", "template", "spec", "initContainers"); err != nil {
+			return newPatchError(workload.ID, PatchKindInitContainers, "", err)
+		}
+	}
+
+	// patch volumes and volume mounts
+	if patcher.Volumes != nil || patcher.RemovedVolumes != nil || patcher.VolumeMounts != nil {
+		if err := patchVolumes(un, workload.ID, patcher.Volumes, patcher.RemovedVolumes, patcher.VolumeMounts); err != nil {
+			return err
+		}
+	}
+
+	// patch image pull secrets
+	if patcher.ImagePullSecrets != nil {
+		pullSecrets, _, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "imagePullSecrets")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindImagePullSecrets, "", fmt.Errorf("failed to get image pull secrets: %w", err))
+		}
+		for _, pullSecret := range patcher.ImagePullSecrets {
+			if containsName(pullSecrets, pullSecret.Name) {
+				continue
 			}
-			patchers = append(patchers, *temp)
+			pullSecrets = append(pullSecrets, map[string]interface{}{"name": pullSecret.Name})
+			log.Infof("we're gonna patch image pull secret:%s to workload:%s", pullSecret.Name, workload.ID)
+		}
+		if err = unstructured.SetNestedSlice(un.Object, pullSecrets, "spec", "template", "spec", "imagePullSecrets"); err != nil {
+			return newPatchError(workload.ID, PatchKindImagePullSecrets, "", err)
 		}
 	}
 
-	return workload, resources, patchers, nil
-}
+	// patch pod security context
+	if patcher.PodSecurityContext != nil {
+		securityContext, _, err := unstructured.NestedMap(un.Object, "spec", "template", "spec", "securityContext")
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindPodSecurityContext, "", fmt.Errorf("failed to get security context: %w", err))
+		}
+		patchSecurityContext, err := runtime.DefaultUnstructuredConverter.ToUnstructured(patcher.PodSecurityContext)
+		if err != nil {
+			return newPatchError(workload.ID, PatchKindPodSecurityContext, "", err)
+		}
+		if err = unstructured.SetNestedMap(un.Object, deepMerge(securityContext, patchSecurityContext), "spec", "template", "spec", "securityContext"); err != nil {
+			return newPatchError(workload.ID, PatchKindPodSecurityContext, "", err)
+		}
+	}
 
-func (g *appConfigurationGenerator) invokeModule(
-	pluginMap map[string]*module.Plugin,
-	key string,
-	config moduleConfig,
-) (*proto.GeneratorResponse, error) {
-	// init the plugin
-	if pluginMap[key] == nil {
-		plugin, err := module.NewPlugin(key, g.stack.Path)
+	// patch container security context
+	if patcher.ContainerSecurityContext != nil {
+		containers, b, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "containers")
+		if err != nil || !b {
+			return newPatchError(workload.ID, PatchKindContainerSecurityContext, "", fmt.Errorf("failed to get containers: %w", err))
+		}
+		patchSecurityContext, err := runtime.DefaultUnstructuredConverter.ToUnstructured(patcher.ContainerSecurityContext)
 		if err != nil {
-			return nil, err
+			return newPatchError(workload.ID, PatchKindContainerSecurityContext, "", err)
 		}
-		if plugin == nil {
-			return nil, fmt.Errorf("init plugin for module %s failed", key)
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return newPatchError(workload.ID, PatchKindContainerSecurityContext, "", fmt.Errorf("container %d is not a map: %v", i, c))
+			}
+			securityContext, _, err := unstructured.NestedMap(container, "securityContext")
+			if err != nil {
+				return newPatchError(workload.ID, PatchKindContainerSecurityContext, containerName(container), fmt.Errorf("failed to get security context: %w", err))
+			}
+			// merge a copy of the patch, as the nested values are shared by the containers otherwise
+			container["securityContext"] = deepMerge(securityContext, runtime.DeepCopyJSON(patchSecurityContext))
+			containers[i] = container
+		}
+		if err = unstructured.SetNestedSlice(un.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+			return newPatchError(workload.ID, PatchKindContainerSecurityContext, "", err)
 		}
-		pluginMap[key] = plugin
 	}
-	plugin := pluginMap[key]
 
-	// prepare the request
-	protoRequest, err := g.initModuleRequest(config)
-	if err != nil {
-		return nil, err
+	// patch replicas, which raises the replicas of the workload to the floor
+	if patcher.Replicas != nil {
+		switch kind := un.GetKind(); kind {
+		case "Deployment", "StatefulSet":
+			replicas, found, err := unstructured.NestedInt64(un.Object, "spec", "replicas")
+			if err != nil {
+				return newPatchError(workload.ID, PatchKindReplicas, "", fmt.Errorf("failed to get replicas: %w", err))
+			}
+			if !found || replicas < int64(*patcher.Replicas) {
+				if err = unstructured.SetNestedField(un.Object, int64(*patcher.Replicas), "spec", "replicas"); err != nil {
+					return newPatchError(workload.ID, PatchKindReplicas, "", fmt.Errorf("failed to set replicas: %w", err))
+				}
+			}
+		default:
+			log.Warnf("workload:%s of kind %s has no replicas, skip patching replicas", workload.ID, kind)
+		}
 	}
 
-	// invoke the plugin
-	log.Infof("invoke module:%s with request:%s", key, protoRequest.String())
-	traceID, _ := uuid.NewUUID()
-	ctx := metadata.AppendToOutgoingContext(context.Background(), kusionTraceID, traceID.String(), kusionModuleName, plugin.ModuleName)
-	response, err := plugin.Module.Generate(ctx, protoRequest)
-	if err != nil {
-		return nil, fmt.Errorf("invoke kusion module: %s failed. %w", key, err)
+	// patch command and args of the selected containers
+	if patcher.Command != nil || patcher.Args != nil {
+		containers, b, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "containers")
+		if err != nil || !b {
+			return newPatchError(workload.ID, PatchKindCommand, "", fmt.Errorf("failed to get containers: %w", err))
+		}
+		patched := false
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return newPatchError(workload.ID, PatchKindCommand, "", fmt.Errorf("container %d is not a map: %v", i, c))
+			}
+			if patcher.ContainerName != "" && container["name"] != patcher.ContainerName {
+				continue
+			}
+			if patcher.Command != nil {
+				container["command"] = toInterfaceSlice(patcher.Command)
+			}
+			if patcher.Args != nil {
+				container["args"] = toInterfaceSlice(patcher.Args)
+			}
+			containers[i] = container
+			patched = true
+		}
+		if !patched {
+			return newPatchError(workload.ID, PatchKindCommand, patcher.ContainerName, errors.New("container not found"))
+		}
+		if err = unstructured.SetNestedSlice(un.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+			return newPatchError(workload.ID, PatchKindCommand, "", err)
+		}
 	}
-	if response == nil {
-		return nil, fmt.Errorf("empty response from module %s", key)
+
+	// patch extensions, e.g. the apply-pause extension of the workload
+	if patcher.Extensions != nil {
+		if workload.Extensions == nil {
+			workload.Extensions = make(map[string]interface{})
+		}
+		for k, v := range patcher.Extensions {
+			if v == removalVal {
+				delete(workload.Extensions, k)
+				continue
+			}
+			workload.Extensions[k] = v
+		}
 	}
-	return response, nil
+
+	return nil
 }
 
-func (g *appConfigurationGenerator) buildModuleConfigIndex(platformModuleConfigs map[string]v1.GenericConfig) (map[string]moduleConfig, error) {
-	indexModuleConfig := map[string]moduleConfig{}
+// patchVolumes removes the removed volumes from the pods along with their volume mounts in all containers,
+// and then merges the volumes into the pods deduplicated by name and the volume mounts into all containers of
+// the workload deduplicated by name, mount path and sub path.
+func patchVolumes(un *unstructured.Unstructured, workloadID string, volumes []k8sv1.Volume, removedVolumes []string, volumeMounts []k8sv1.VolumeMount) error {
+	existingVolumes, _, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "volumes")
+	if err != nil {
+		return newPatchError(workloadID, PatchKindVolumes, "", fmt.Errorf("failed to get volumes: %w", err))
+	}
 
-	// add workload to the accessory map
-	tempMap := make(map[string]v1.Accessory)
-	for k, v := range g.app.Accessories {
-		tempMap[k] = v
+	for _, name := range removedVolumes {
+		existingVolumes = removeByName(existingVolumes, name)
+		log.Infof("we're gonna remove volume:%s from workload:%s", name, workloadID)
 	}
-	if g.app.Workload != nil {
-		tempMap["workload"] = g.app.Workload
+	for _, volume := range volumes {
+		us, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&volume)
+		if err != nil {
+			return newPatchError(workloadID, PatchKindVolumes, "", err)
+		}
+		existingVolumes = mergeByName(existingVolumes, us)
+	}
+	if len(existingVolumes) == 0 {
+		unstructured.RemoveNestedField(un.Object, "spec", "template", "spec", "volumes")
+	} else if err = unstructured.SetNestedSlice(un.Object, existingVolumes, "spec", "template", "spec", "volumes"); err != nil {
+		return newPatchError(workloadID, PatchKindVolumes, "", err)
 	}
 
-	for accName, accessory := range tempMap {
-		// parse accessory module key
-		key, err := parseModuleKey(accessory, g.dependencies)
-		if err != nil {
-			return nil, err
+	if len(volumeMounts) == 0 && len(removedVolumes) == 0 {
+		return nil
+	}
+	containers, b, err := unstructured.NestedSlice(un.Object, "spec", "template", "spec", "containers")
+	if err != nil || !b {
+		return newPatchError(workloadID, PatchKindVolumes, "", fmt.Errorf("failed to get containers: %w", err))
+	}
+	for i, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
 		}
-		log.Info("build module index of accessory:%s module key: %s", accName, key)
-		moduleName, err := getModuleName(accessory)
+		mounts, _, err := unstructured.NestedSlice(container, "volumeMounts")
 		if err != nil {
-			return nil, err
+			return newPatchError(workloadID, PatchKindVolumes, containerName(container), fmt.Errorf("failed to get volume mounts: %w", err))
 		}
-		indexModuleConfig[key] = moduleConfig{
-			devConfig:      accessory,
-			platformConfig: platformModuleConfigs[moduleName],
-			ctx:            g.ws.Context,
+		for _, name := range removedVolumes {
+			mounts = removeByName(mounts, name)
+		}
+		for _, mount := range volumeMounts {
+			us, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&mount)
+			if err != nil {
+				return newPatchError(workloadID, PatchKindVolumes, containerName(container), err)
+			}
+			mounts = mergeVolumeMount(mounts, us)
 		}
+		if len(mounts) == 0 {
+			delete(container, "volumeMounts")
+		} else {
+			container["volumeMounts"] = mounts
+		}
+		containers[i] = container
 	}
-	return indexModuleConfig, nil
+	if err = unstructured.SetNestedSlice(un.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+		return newPatchError(workloadID, PatchKindVolumes, "", err)
+	}
+	return nil
 }
 
-// parseModuleKey returns the module key of the accessory in format of "org/module@version"
-// example: "kusionstack/mysql@v0.1.0"
-func parseModuleKey(accessory v1.Accessory, dependencies *pkg.Dependencies) (string, error) {
-	if accessory == nil {
-		log.Info("accessory is nil, return empty module key")
-		return "", nil
+// mergeEnvironments merges the env of the patchers in the order of the patchers and then the declaration
+// order, where the env patched again by a latter patcher, including the removal, replaces the former one.
+func mergeEnvironments(patchers []v1.Patcher) []k8sv1.EnvVar {
+	var merged []k8sv1.EnvVar
+	for _, patcher := range patchers {
+		for _, env := range patcher.Environments {
+			merged = slices.DeleteFunc(merged, func(e k8sv1.EnvVar) bool {
+				return e.Name == env.Name
+			})
+			merged = append(merged, env)
+		}
 	}
+	return merged
+}
 
-	moduleName, err := getModuleName(accessory)
-	if err != nil {
-		return "", err
+// mergeByName replaces the item with the same name in the items, or appends it if not existing.
+func mergeByName(items []interface{}, item map[string]interface{}) []interface{} {
+	for i, existing := range items {
+		if e, ok := existing.(map[string]interface{}); ok && e["name"] == item["name"] {
+			items[i] = item
+			return items
+		}
 	}
+	return append(items, item)
+}
 
-	// find module namespace and version
-	d, ok := dependencies.Deps.Get(moduleName)
-	if !ok {
-		return "", fmt.Errorf("can not find module %s in dependencies", moduleName)
-	}
-	// key example "kusionstack/mysql@v0.1.0"
-	var key string
-	if d.Oci != nil {
-		key = fmt.Sprintf("%s@%s", d.Oci.Repo, d.Version)
-	} else if d.Git != nil {
-		// todo: kpm will change the repo version with the filed `version` in d.Git.Version
-		url := strings.TrimSuffix(d.Git.Url, ".git")
-		splits := strings.Split(url, "/")
-		ns := splits[len(splits)-2] + "/" + splits[len(splits)-1]
-		key = fmt.Sprintf("%s@%s", ns, d.Git.Tag)
+// mergeVolumeMount replaces the volume mount with the same name, mount path and sub path in the mounts, or
+// appends it if not existing, so that the mounts of the different sub paths of a volume are kept.
+func mergeVolumeMount(mounts []interface{}, mount map[string]interface{}) []interface{} {
+	for i, existing := range mounts {
+		e, ok := existing.(map[string]interface{})
+		if ok && e["name"] == mount["name"] && e["mountPath"] == mount["mountPath"] && e["subPath"] == mount["subPath"] {
+			mounts[i] = mount
+			return mounts
+		}
 	}
-	return key, nil
+	return append(mounts, mount)
 }
 
-func getModuleName(accessory v1.Accessory) (string, error) {
-	t, ok := accessory["_type"]
-	if !ok {
-		return "", errors.New("can not find '_type' in module config")
+func toInterfaceSlice(items []string) []interface{} {
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		result = append(result, item)
 	}
-	split := strings.Split(t.(string), ".")
-	return split[0], nil
+	return result
 }
 
-func (g *appConfigurationGenerator) initModuleRequest(config moduleConfig) (*proto.GeneratorRequest, error) {
-	var workloadConfig, secretStoreConfig, devConfig, platformConfig, ctx []byte
-	var err error
-	// Attention: we MUST yaml.v2 to serialize the object,
-	// because we have introduced MapSlice in the Workload which is supported only in the yaml.v2
-	if g.app.Workload != nil {
-		if workloadConfig, err = yamlv2.Marshal(g.app.Workload); err != nil {
-			return nil, fmt.Errorf("marshal workload config failed. %w", err)
-		}
-	}
-	if g.ws.SecretStore != nil {
-		if secretStoreConfig, err = yamlv2.Marshal(g.ws.SecretStore); err != nil {
-			return nil, fmt.Errorf("marshal secret store config failed. %w", err)
+// containsName reports whether the items contain the item with the given name.
+func containsName(items []interface{}, name string) bool {
+	for _, existing := range items {
+		if e, ok := existing.(map[string]interface{}); ok && e["name"] == name {
+			return true
 		}
 	}
-	if config.devConfig != nil {
-		if devConfig, err = yaml.Marshal(config.devConfig); err != nil {
-			return nil, fmt.Errorf("marshal dev module config failed. %w", err)
+	return false
+}
+
+// removeByName removes the items with the given name.
+func removeByName(items []interface{}, name string) []interface{} {
+	kept := items[:0]
+	for _, existing := range items {
+		if e, ok := existing.(map[string]interface{}); ok && e["name"] == name {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	return kept
+}
+
+// deepMerge merges the unstructured patch into the base recursively, where the values of the patch are
+// preferred on conflict. The items of the slices are appended to the base if not existing, e.g. the
+// terms of requiredDuringSchedulingIgnoredDuringExecution, except the nodeSelectorTerms, see
+// mergeNodeSelectorTerms.
+func deepMerge(base, patch map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = make(map[string]interface{}, len(patch))
+	}
+	for k, pv := range patch {
+		if k == "nodeSelectorTerms" {
+			base[k] = mergeNodeSelectorTerms(base[k], pv)
+			continue
+		}
+		switch patchValue := pv.(type) {
+		case map[string]interface{}:
+			if baseValue, ok := base[k].(map[string]interface{}); ok {
+				base[k] = deepMerge(baseValue, patchValue)
+				continue
+			}
+		case []interface{}:
+			if baseValue, ok := base[k].([]interface{}); ok {
+				for _, item := range patchValue {
+					if !containsItem(baseValue, item) {
+						baseValue = append(baseValue, item)
+					}
+				}
+				base[k] = baseValue
+				continue
+			}
+		}
+		base[k] = pv
+	}
+	return base
+}
+
+// mergeNodeSelectorTerms merges the patch node selector terms into the base ones. The terms are ORed, thus
+// appending the patch terms would loosen the constraint. Instead, the requirements of each patch term are
+// merged into each base term, i.e. (A or B) and (C or D) is (A and C) or (A and D) or (B and C) or (B and D).
+func mergeNodeSelectorTerms(base, patch interface{}) interface{} {
+	baseTerms, _ := base.([]interface{})
+	patchTerms, _ := patch.([]interface{})
+	if len(patchTerms) == 0 {
+		return base
+	}
+	if len(baseTerms) == 0 {
+		return patch
+	}
+	merged := make([]interface{}, 0, len(baseTerms)*len(patchTerms))
+	for _, baseTerm := range baseTerms {
+		for _, patchTerm := range patchTerms {
+			b, ok := runtime.DeepCopyJSONValue(baseTerm).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			p, ok := runtime.DeepCopyJSONValue(patchTerm).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if term := deepMerge(b, p); !containsItem(merged, term) {
+				merged = append(merged, term)
+			}
+		}
+	}
+	return merged
+}
+
+func containsItem(items []interface{}, item interface{}) bool {
+	for _, i := range items {
+		if reflect.DeepEqual(i, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeToleration appends the toleration to the tolerations, or replaces the existing toleration with the
+// same key, operator, value and effect, e.g. to update the tolerationSeconds.
+func mergeToleration(tolerations []interface{}, toleration map[string]interface{}) []interface{} {
+	for i, t := range tolerations {
+		existing, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if tolerationField(existing, "key") == tolerationField(toleration, "key") &&
+			tolerationField(existing, "operator") == tolerationField(toleration, "operator") &&
+			tolerationField(existing, "value") == tolerationField(toleration, "value") &&
+			tolerationField(existing, "effect") == tolerationField(toleration, "effect") {
+			tolerations[i] = toleration
+			return tolerations
+		}
+	}
+	return append(tolerations, toleration)
+}
+
+// removeTolerations removes the tolerations with the same key as the removal, and the same effect if the
+// effect of the removal is specified.
+func removeTolerations(tolerations []interface{}, removal map[string]interface{}) []interface{} {
+	kept := make([]interface{}, 0, len(tolerations))
+	for _, t := range tolerations {
+		existing, ok := t.(map[string]interface{})
+		if ok && tolerationField(existing, "key") == tolerationField(removal, "key") &&
+			(tolerationField(removal, "effect") == "" || tolerationField(existing, "effect") == tolerationField(removal, "effect")) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// tolerationField returns the string field of the unstructured toleration, where the empty operator
+// defaults to Equal.
+func tolerationField(toleration map[string]interface{}, field string) string {
+	value, _ := toleration[field].(string)
+	if field == "operator" && value == "" {
+		return string(k8sv1.TolerationOpEqual)
+	}
+	return value
+}
+
+// moduleConfig represents the configuration of a module, either devConfig or platformConfig can be nil
+type moduleConfig struct {
+	devConfig      v1.Accessory
+	platformConfig v1.GenericConfig
+	ctx            v1.GenericConfig
+	// workloadName is the name of the named workload generated by the module, which is empty for the
+	// workload and the accessories.
+	workloadName string
+	// moduleName is the name of the module in the module configs of the workspace.
+	moduleName string
+}
+
+func (g *appConfigurationGenerator) callModules(
+	ctx context.Context,
+	projectModuleConfigs map[string]v1.GenericConfig,
+) (workload *v1.Resource, resources []v1.Resource, patchers []v1.Patcher, err error) {
+	plugins := g.pluginCache
+	if plugins == nil {
+		plugins = NewPluginCache()
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			switch x := e.(type) {
+			case string:
+				err = fmt.Errorf("call modules panic:%s", e)
+			case error:
+				err = x
+			default:
+				err = errors.New("call modules unknown panic")
+			}
+		}
+		// the plugins of a shared cache are killed by its owner at the end of the whole build
+		if g.pluginCache == nil {
+			if pluginErr := plugins.Close(); pluginErr != nil {
+				err = fmt.Errorf("kill modules failed %w. %s", err, pluginErr)
+			}
+		}
+		if err != nil {
+			log.Errorf(err.Error())
+		}
+	}()
+
+	if g.dependencies == nil {
+		return nil, nil, nil, errors.New("dependencies should not be nil")
+	}
+
+	g.resourceModules = make(map[string]string)
+
+	// build module config index
+	indexModuleConfig, err := g.buildModuleConfigIndex(projectModuleConfigs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the app without a workload, e.g. a bundle of accessories only, has no workload module, and the empty
+	// key of a dependency without a source must not be taken as the workload key
+	hasWorkload := g.app.Workload != nil
+	var workloadKey string
+	if hasWorkload {
+		workloadKey, err = parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// generate customized module resources in the order of the module keys, so that the patchers returned
+	// by the modules are applied in a deterministic order
+	moduleKeys := make([]string, 0, len(indexModuleConfig))
+	for t := range indexModuleConfig {
+		moduleKeys = append(moduleKeys, t)
+	}
+	sort.Strings(moduleKeys)
+	responses, err := g.invokeModules(ctx, plugins, moduleKeys, indexModuleConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i, t := range moduleKeys {
+		config := indexModuleConfig[t]
+		response := responses[i]
+		g.recordResources(t, len(response.Resources))
+		// Patch health policy to the resources
+		isWorkloadModule := hasWorkload && workloadKey == t
+		var healthPolicies *moduleHealthPolicies
+		healthPolicies, err = parseHealthPolicies(t, config.platformConfig[v1.FieldHealthPolicy], isWorkloadModule)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// parse module result
+		// if only one resource exists in the workload module, it is the workload
+		moduleResourcesStart := len(resources)
+		if isWorkloadModule && len(response.Resources) == 1 {
+			workload = &v1.Resource{}
+			err = unmarshalModuleOutput(t, "workload", response.Resources[0], workload)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if err = validateModuleResource(t, "workload", workload); err != nil {
+				return nil, nil, nil, err
+			}
+			g.stampProvenance(workload, t)
+			markWorkload(workload, healthPolicies.workload)
+		} else {
+			var moduleResources []*v1.Resource
+			moduleResources, err = g.unmarshalModuleResources(t, response.Resources)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			var marked int
+			marked, err = explicitWorkload(t, isWorkloadModule, moduleResources)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			for i, temp := range moduleResources {
+				switch {
+				// the workload marked explicitly takes precedence over the isWorkload extension
+				case i == marked:
+					workload = temp
+					markWorkload(workload, healthPolicies.workload)
+				// filter out workload
+				case marked < 0 && isWorkloadModule && temp.Extensions[isWorkload] == "true":
+					workload = temp
+				default:
+					resources = append(resources, *temp)
+				}
+			}
+		}
+		// the health policies are matched against the resources of the module, including the workload,
+		// e.g. a health policy targeting the Deployment
+		for i := moduleResourcesStart; i < len(resources); i++ {
+			healthPolicies.match(&resources[i])
+		}
+		if isWorkloadModule && workload != nil {
+			healthPolicies.match(workload)
+			g.recordModuleOf(config.moduleName, workload)
+		}
+		for i := moduleResourcesStart; i < len(resources); i++ {
+			g.recordModuleOf(config.moduleName, &resources[i])
+		}
+		// parse patcher
+		temp := &v1.Patcher{}
+		if response.Patcher != nil {
+			err = unmarshalModuleOutput(t, "patcher", response.Patcher, temp)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			patchers = append(patchers, *temp)
+			g.capturePatcher(t, temp)
+		}
+	}
+
+	// the named workloads are marked as the workloads among the resources
+	workloadResources, workloadPatchers, err := g.callWorkloadModules(ctx, plugins, projectModuleConfigs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	resources = append(resources, workloadResources...)
+	patchers = append(patchers, workloadPatchers...)
+
+	// sort the resources by ID, so that the order of the resources is the same across the generations
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].ID < resources[j].ID
+	})
+
+	return workload, resources, patchers, nil
+}
+
+// callWorkloadModules invokes the modules of the named workloads in order of their names, and returns the
+// resources with the workloads marked along with the patchers. The named workloads are invoked apart from
+// the index of the module keys, as they usually share the module with the workload, e.g. a worker along
+// with the web server.
+func (g *appConfigurationGenerator) callWorkloadModules(
+	ctx context.Context,
+	plugins *PluginCache,
+	platformModuleConfigs map[string]v1.GenericConfig,
+) (resources []v1.Resource, patchers []v1.Patcher, err error) {
+	names := g.workloadNames()
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, len(names))
+	configs := make([]moduleConfig, len(names))
+	versionOverrides := g.moduleVersionOverrides()
+	for i, name := range names {
+		workload := g.app.Workloads[name]
+		key, err := parseModuleKey(workload, g.dependencies, versionOverrides)
+		if err != nil {
+			return nil, nil, err
+		}
+		moduleName, err := getModuleName(workload)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = key
+		configs[i] = moduleConfig{
+			devConfig:      workload,
+			platformConfig: platformModuleConfigs[moduleName],
+			ctx:            g.ws.Context,
+			workloadName:   name,
+			moduleName:     moduleName,
+		}
+	}
+
+	responses, err := g.invokeModuleList(ctx, plugins, keys, configs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, name := range names {
+		t, response := keys[i], responses[i]
+		g.recordResources(t, len(response.Resources))
+		moduleResources, err := g.unmarshalModuleResources(t, response.Resources)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the workload is found the same way as the workload of the app
+		marked, err := explicitWorkload(t, true, moduleResources)
+		if err != nil {
+			return nil, nil, err
+		}
+		if marked < 0 && len(moduleResources) == 1 {
+			marked = 0
+		}
+		for j, res := range moduleResources {
+			if marked < 0 && res.Extensions[isWorkload] == "true" {
+				marked = j
+			}
+		}
+		healthPolicies, err := parseHealthPolicies(t, configs[i].platformConfig[v1.FieldHealthPolicy], true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if marked < 0 {
+			log.Warnf("no workload found in the resources generated by module %s for workload %s", t, name)
+		} else {
+			markWorkload(moduleResources[marked], healthPolicies.workload)
+		}
+		for _, res := range moduleResources {
+			healthPolicies.match(res)
+			g.recordModuleOf(configs[i].moduleName, res)
+			resources = append(resources, *res)
+		}
+
+		if response.Patcher != nil {
+			temp := &v1.Patcher{}
+			if err = unmarshalModuleOutput(t, "patcher", response.Patcher, temp); err != nil {
+				return nil, nil, err
+			}
+			patchers = append(patchers, *temp)
+			g.capturePatcher(t, temp)
+		}
+	}
+	return resources, patchers, nil
+}
+
+// unmarshalModuleResources unmarshals and validates the resources generated by the module, and stamps the
+// provenance of the module onto them.
+func (g *appConfigurationGenerator) unmarshalModuleResources(moduleKey string, outputs [][]byte) ([]*v1.Resource, error) {
+	resources := make([]*v1.Resource, len(outputs))
+	for i, output := range outputs {
+		res := &v1.Resource{}
+		name := fmt.Sprintf("resource %d", i)
+		if err := unmarshalModuleOutput(moduleKey, name, output, res); err != nil {
+			return nil, err
+		}
+		if err := validateModuleResource(moduleKey, name, res); err != nil {
+			return nil, err
+		}
+		g.stampProvenance(res, moduleKey)
+		resources[i] = res
+	}
+	return resources, nil
+}
+
+// workloadNames returns the names of the named workloads of the app in order.
+func (g *appConfigurationGenerator) workloadNames() []string {
+	names := make([]string, 0, len(g.app.Workloads))
+	for name := range g.app.Workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// explicitWorkload returns the index of the resource marked as the workload with the FieldWorkloadResource
+// extension, or -1 if none is marked. Only the workload module can mark the workload, and at most one of
+// its resources.
+func explicitWorkload(moduleKey string, isWorkloadModule bool, resources []*v1.Resource) (int, error) {
+	marked := -1
+	for i, res := range resources {
+		switch res.Extensions[v1.FieldWorkloadResource] {
+		case true, "true":
+		default:
+			continue
+		}
+		if !isWorkloadModule {
+			return -1, fmt.Errorf("module %s is not the workload module but marks resource %s as the workload with %s",
+				moduleKey, res.ID, v1.FieldWorkloadResource)
+		}
+		if marked >= 0 {
+			return -1, fmt.Errorf("module %s marks more than one resource as the workload with %s: %s and %s",
+				moduleKey, v1.FieldWorkloadResource, resources[marked].ID, res.ID)
+		}
+		marked = i
+	}
+	return marked, nil
+}
+
+// markWorkload adds the isWorkload extension to the workload to mark it, keeping the extensions returned
+// by the module, e.g. the apply-pause extension, and adds the health policy to the workload extensions.
+func markWorkload(workload *v1.Resource, healthPolicy v1.GenericConfig) {
+	if workload.Extensions == nil {
+		workload.Extensions = make(map[string]interface{})
+	}
+	delete(workload.Extensions, v1.FieldWorkloadResource)
+	workload.Extensions[isWorkload] = true
+	if healthPolicy != nil {
+		patchHealthPolicy(workload, healthPolicy)
+	}
+}
+
+// maxConcurrentModules is the max number of the modules invoked concurrently in a generation.
+const maxConcurrentModules = 8
+
+// defaultModuleTimeout is the timeout of invoking a module, which can be overridden by the moduleTimeout
+// in the platform config of the module.
+var defaultModuleTimeout = 60 * time.Second
+
+// moduleTimeout returns the timeout of invoking the module with the platform config.
+func moduleTimeout(platformConfig v1.GenericConfig) (time.Duration, error) {
+	value, err := workspace.GetStringFromGenericConfig(platformConfig, v1.FieldModuleTimeout)
+	if err != nil || value == "" {
+		return defaultModuleTimeout, err
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", value)
+	}
+	return timeout, nil
+}
+
+// The default retry policy of invoking a module, which can be overridden by the moduleMaxAttempts and
+// moduleRetryBackoff in the platform config of the module.
+var (
+	defaultModuleMaxAttempts  int32 = 3
+	defaultModuleRetryBackoff       = 500 * time.Millisecond
+)
+
+// moduleRetryPolicy is the policy of retrying the invocation of a module on the transient failures.
+type moduleRetryPolicy struct {
+	maxAttempts int32
+	// backoff is the wait before the second attempt, which is doubled after each attempt.
+	backoff time.Duration
+}
+
+// retryPolicy returns the retry policy of invoking the module with the platform config.
+func retryPolicy(platformConfig v1.GenericConfig) (moduleRetryPolicy, error) {
+	policy := moduleRetryPolicy{maxAttempts: defaultModuleMaxAttempts, backoff: defaultModuleRetryBackoff}
+	maxAttempts, err := workspace.GetInt32PointerFromGenericConfig(platformConfig, v1.FieldModuleMaxAttempts)
+	if err != nil {
+		return policy, err
+	}
+	if maxAttempts != nil {
+		if *maxAttempts <= 0 {
+			return policy, fmt.Errorf("%s must be positive, got %d", v1.FieldModuleMaxAttempts, *maxAttempts)
+		}
+		policy.maxAttempts = *maxAttempts
+	}
+	value, err := workspace.GetStringFromGenericConfig(platformConfig, v1.FieldModuleRetryBackoff)
+	if err != nil || value == "" {
+		return policy, err
+	}
+	if policy.backoff, err = time.ParseDuration(value); err != nil {
+		return policy, err
+	}
+	if policy.backoff < 0 {
+		return policy, fmt.Errorf("%s must not be negative, got %s", v1.FieldModuleRetryBackoff, value)
+	}
+	return policy, nil
+}
+
+// isTransientModuleError tells whether the failure of invoking a module is transient and worth retrying,
+// e.g. the plugin failed to start or is not ready to serve. The timed out invocations are not retried, which
+// would multiply the timeout of the module.
+func isTransientModuleError(err error) bool {
+	var startErr *pluginStartError
+	if errors.As(err, &startErr) {
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
+
+// PluginCache caches the plugins of the modules keyed by the module key, which are safe to be started
+// concurrently. A cache shared by the generators of several apps starts each module only once, and the
+// plugins are killed by Close at the end of the whole build.
+type PluginCache struct {
+	mu      sync.Mutex
+	plugins map[string]*module.Plugin
+	closed  bool
+
+	// starts deduplicates the concurrent starts of the same module.
+	starts singleflight.Group
+}
+
+// errPluginCacheClosed is returned when starting a plugin in the closed cache, whose plugins are never killed.
+var errPluginCacheClosed = errors.New("plugin cache has been closed")
+
+// NewPluginCache returns an empty PluginCache.
+func NewPluginCache() *PluginCache {
+	return &PluginCache{plugins: make(map[string]*module.Plugin)}
+}
+
+func (p *PluginCache) get(key string) *module.Plugin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.plugins[key]
+}
+
+// set caches the plugin, and refuses it after the cache is closed, as it would never be killed.
+func (p *PluginCache) set(key string, plugin *module.Plugin) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPluginCacheClosed
+	}
+	p.plugins[key] = plugin
+	return nil
+}
+
+// getOrStart returns the cached plugin of the module, or starts and caches it if not cached. The concurrent
+// invocations of the same module wait for the only start of it, so that no plugin is started but overwritten
+// in the cache and never killed.
+func (p *PluginCache) getOrStart(key string, start func() (*module.Plugin, error)) (*module.Plugin, error) {
+	if plugin := p.get(key); plugin != nil {
+		return plugin, nil
+	}
+	plugin, err, _ := p.starts.Do(key, func() (interface{}, error) {
+		// the plugin may have been started by the start finished right before this one
+		if plugin := p.get(key); plugin != nil {
+			return plugin, nil
+		}
+		plugin, err := start()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.set(key, plugin); err != nil {
+			if killErr := killPlugin(plugin); killErr != nil {
+				log.Warnf("kill the plugin of module %s started after the cache closed failed: %v", key, killErr)
+			}
+			return nil, err
+		}
+		return plugin, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plugin.(*module.Plugin), nil
+}
+
+// evict removes the plugin from the cache and kills it, so that the module is restarted by the next
+// invocation. The plugin already replaced in the cache is left to the one replacing it.
+func (p *PluginCache) evict(key string, plugin *module.Plugin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.plugins[key] != plugin {
+		return
+	}
+	delete(p.plugins, key)
+	if err := killPlugin(plugin); err != nil {
+		log.Warnf("kill the unavailable plugin of module %s failed: %v", key, err)
+	}
+}
+
+// Close kills the plugin clients in the cache. The clients are killed only once, the later calls are no-ops.
+func (p *PluginCache) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []error
+	_ = generators.ForeachOrdered(p.plugins, func(_ string, plugin *module.Plugin) error {
+		if err := killPlugin(plugin); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	p.plugins = make(map[string]*module.Plugin)
+	return errors.Join(errs...)
+}
+
+// invokeModules invokes the modules concurrently with at most maxConcurrentModules at a time, and returns
+// the responses in the order of the keys. The first failure or the ctx done cancels the invocations not
+// finished yet.
+func (g *appConfigurationGenerator) invokeModules(
+	ctx context.Context,
+	plugins *PluginCache,
+	keys []string,
+	configs map[string]moduleConfig,
+) ([]*proto.GeneratorResponse, error) {
+	configList := make([]moduleConfig, len(keys))
+	for i, key := range keys {
+		configList[i] = configs[key]
+	}
+	return g.invokeModuleList(ctx, plugins, keys, configList)
+}
+
+// invokeModuleList is the same as invokeModules, but with the config of each invocation given in the
+// same order as the keys, so that a module can be invoked more than once with different configs.
+func (g *appConfigurationGenerator) invokeModuleList(
+	ctx context.Context,
+	plugins *PluginCache,
+	keys []string,
+	configs []moduleConfig,
+) ([]*proto.GeneratorResponse, error) {
+	responses := make([]*proto.GeneratorResponse, len(keys))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(maxConcurrentModules)
+	for i, key := range keys {
+		eg.Go(func() (err error) {
+			// the panic in the goroutine is not recovered by callModules
+			defer func() {
+				if e := recover(); e != nil {
+					err = fmt.Errorf("invoke module %s panic: %v", key, e)
+				}
+			}()
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			responses[i], err = g.invokeModule(ctx, plugins, key, configs[i])
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+func (g *appConfigurationGenerator) invokeModule(
+	ctx context.Context,
+	plugins *PluginCache,
+	key string,
+	config moduleConfig,
+) (response *proto.GeneratorResponse, err error) {
+	// record the duration of the invocation along with the trace ID passed to the module
+	traceID, _ := uuid.NewUUID()
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		log.Infof("invoke module:%s finished in %s, trace id:%s, succeeded:%t", key, duration, traceID, err == nil)
+		g.metricsSink().RecordModuleInvocation(ModuleInvocation{
+			App:      g.appName,
+			Module:   key,
+			TraceID:  traceID.String(),
+			Duration: duration,
+			Err:      err,
+		})
+	}()
+
+	// prepare the request
+	protoRequest, err := g.initModuleRequest(config)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := moduleTimeout(config.platformConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s of module %s: %w", v1.FieldModuleTimeout, key, err)
+	}
+	policy, err := retryPolicy(config.platformConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry policy of module %s: %w", key, err)
+	}
+
+	// invoke the plugin, retrying on the transient failures with the exponential backoff
+	log.Infof("invoke module:%s with request:%s", key, protoRequest.String())
+	backoff := policy.backoff
+	for attempt := int32(1); ; attempt++ {
+		response, timedOut, err := g.generate(ctx, plugins, key, traceID.String(), protoRequest, timeout)
+		if err == nil {
+			if response == nil {
+				return nil, fmt.Errorf("empty response from module %s", key)
+			}
+			return response, nil
+		}
+		if attempt >= policy.maxAttempts || !isTransientModuleError(err) || ctx.Err() != nil {
+			var attempts string
+			if attempt > 1 {
+				attempts = fmt.Sprintf(" in %d attempts", attempt)
+			}
+			if timedOut {
+				return nil, fmt.Errorf("invoke kusion module: %s timed out after %s%s. %w", key, timeout, attempts, err)
+			}
+			return nil, fmt.Errorf("invoke kusion module: %s failed%s. %w", key, attempts, err)
+		}
+		log.Warnf("invoke module:%s failed in attempt %d, retry in %s: %v", key, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// generate invokes the plugin of the module once, which is started if not in the cache. The recent stderr of
+// the plugin is appended to the error of the invocation, and the unavailable plugin is evicted from the cache
+// to be restarted by the next invocation.
+func (g *appConfigurationGenerator) generate(
+	ctx context.Context,
+	plugins *PluginCache,
+	key, traceID string,
+	request *proto.GeneratorRequest,
+	timeout time.Duration,
+) (*proto.GeneratorResponse, bool, error) {
+	plugin, err := plugins.getOrStart(key, func() (*module.Plugin, error) {
+		plugin, err := g.newPlugin(key)
+		if err != nil {
+			return nil, err
+		}
+		if plugin == nil {
+			return nil, fmt.Errorf("init plugin for module %s failed", key)
+		}
+		return plugin, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, kusionTraceID, traceID, kusionModuleName, plugin.ModuleName)
+	response, timedOut, err := generateWithTimeout(ctx, plugin, request, timeout)
+	if err == nil {
+		return response, false, nil
+	}
+	if status.Code(err) == codes.Unavailable {
+		plugins.evict(key, plugin)
+	}
+	if tailer, ok := plugin.Module.(StderrTailer); ok {
+		err = withModuleStderr(err, key, tailer)
+	}
+	return nil, timedOut, err
+}
+
+// generateWithTimeout invokes the plugin with the timeout, and tells whether the invocation timed out.
+func generateWithTimeout(
+	ctx context.Context,
+	plugin *module.Plugin,
+	request *proto.GeneratorRequest,
+	timeout time.Duration,
+) (*proto.GeneratorResponse, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	response, err := plugin.Module.Generate(ctx, request)
+	return response, err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded), err
+}
+
+func (g *appConfigurationGenerator) buildModuleConfigIndex(platformModuleConfigs map[string]v1.GenericConfig) (map[string]moduleConfig, error) {
+	indexModuleConfig := map[string]moduleConfig{}
+
+	if err := g.validateWorkloadModule(); err != nil {
+		return nil, err
+	}
+
+	// add workload to the accessory map
+	tempMap := make(map[string]v1.Accessory)
+	if !g.workloadOnly {
+		for k, v := range g.app.Accessories {
+			enabled, err := accessoryEnabled(v, g.ws.Context)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enablement condition of accessory %s: %w", k, err)
+			}
+			if !enabled {
+				log.Infof("skip accessory %s as its enablement condition is not satisfied", k)
+				continue
+			}
+			tempMap[k] = withoutEnablementCondition(v)
+		}
+	}
+	if g.app.Workload != nil {
+		tempMap["workload"] = g.app.Workload
+	}
+
+	versionOverrides := g.moduleVersionOverrides()
+	// the accessories are indexed in order of names, so that a collision is reported deterministically
+	accNames := make([]string, 0, len(tempMap))
+	for accName := range tempMap {
+		accNames = append(accNames, accName)
+	}
+	sort.Strings(accNames)
+	keyOwners := make(map[string]string, len(accNames))
+	for _, accName := range accNames {
+		accessory := tempMap[accName]
+		// parse accessory module key
+		key, err := parseModuleKey(accessory, g.dependencies, versionOverrides)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("build module index of accessory:%s module key: %s", accName, key)
+		// the empty key of a dependency without a source identifies no module, thus never collides
+		if owner, ok := keyOwners[key]; ok && key != "" {
+			if !reflect.DeepEqual(indexModuleConfig[key].devConfig, accessory) {
+				return nil, fmt.Errorf("accessories %s and %s of app %s resolve to the same module %s with different configs",
+					owner, accName, g.appName, key)
+			}
+			continue
+		}
+		keyOwners[key] = accName
+		moduleName, err := getModuleName(accessory)
+		if err != nil {
+			return nil, err
+		}
+		indexModuleConfig[key] = moduleConfig{
+			devConfig:      accessory,
+			platformConfig: platformModuleConfigs[moduleName],
+			ctx:            g.ws.Context,
+			moduleName:     moduleName,
+		}
+	}
+	return indexModuleConfig, nil
+}
+
+// validateWorkloadModule checks in advance that the module of the workload resolves to a known
+// dependency, which gives a clear feedback on the most common misconfiguration of the app.
+func (g *appConfigurationGenerator) validateWorkloadModule() error {
+	if err := g.validateModuleOfWorkload("workload", g.app.Workload); err != nil {
+		return err
+	}
+	for _, name := range g.workloadNames() {
+		if err := g.validateModuleOfWorkload("workload "+name, g.app.Workloads[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateModuleOfWorkload checks the module of the workload described by desc, e.g. "workload" for the
+// workload of the app, resolves to a known dependency.
+func (g *appConfigurationGenerator) validateModuleOfWorkload(desc string, workload v1.Accessory) error {
+	if workload == nil {
+		return nil
+	}
+
+	t, ok := workload["_type"]
+	if !ok {
+		return fmt.Errorf("can not find '_type' in the %s of app %s", desc, g.appName)
+	}
+	workloadType, ok := t.(string)
+	if !ok || workloadType == "" {
+		return fmt.Errorf("invalid %s type %v of app %s, expected a non-empty string", desc, t, g.appName)
+	}
+
+	moduleName := strings.Split(workloadType, ".")[0]
+	if g.dependencies == nil || g.dependencies.Deps == nil {
+		return fmt.Errorf("%s type %s of app %s refers to module %s, but no dependencies are declared in kcl.mod",
+			desc, workloadType, g.appName, moduleName)
+	}
+	if _, ok = g.dependencies.Deps.Get(moduleName); !ok {
+		return fmt.Errorf("%s type %s of app %s refers to module %s, which is not declared in the dependencies of kcl.mod",
+			desc, workloadType, g.appName, moduleName)
+	}
+	return nil
+}
+
+// accessoryEnabled evaluates the enablement condition of the accessory against the workspace context.
+// The accessory is enabled if it declares no condition, or all the context values match the expected ones.
+func accessoryEnabled(accessory v1.Accessory, ctx v1.GenericConfig) (bool, error) {
+	condition, ok := accessory[enabledWhen]
+	if !ok || condition == nil {
+		return true, nil
+	}
+	expectations, ok := toStringMap(condition)
+	if !ok {
+		return false, fmt.Errorf("%s must be a map of context paths to the expected values", enabledWhen)
+	}
+
+	for path, expected := range expectations {
+		actual, found := lookupContextValue(ctx, path)
+		if !found || fmt.Sprint(actual) != fmt.Sprint(expected) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lookupContextValue gets the value of the dot-separated path in the workspace context.
+func lookupContextValue(ctx v1.GenericConfig, path string) (interface{}, bool) {
+	var current interface{} = map[string]interface{}(ctx)
+	for _, field := range strings.Split(path, ".") {
+		m, ok := toStringMap(current)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[field]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// toStringMap converts the maps decoded by yaml.v2 and yaml.v3 into map[string]interface{}.
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case v1.GenericConfig:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			result[fmt.Sprint(k)] = val
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// withoutEnablementCondition returns a copy of the accessory without the enablement condition,
+// which is consumed by Kusion and should not be passed to the module.
+func withoutEnablementCondition(accessory v1.Accessory) v1.Accessory {
+	if _, ok := accessory[enabledWhen]; !ok {
+		return accessory
+	}
+	result := make(v1.Accessory, len(accessory)-1)
+	for k, v := range accessory {
+		if k != enabledWhen {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// parseModuleKey returns the module key of the accessory in format of "org/module@version"
+// example: "kusionstack/mysql@v0.1.0". The version resolved from the dependencies can be
+// overridden by the versionOverrides, which maps the module name to the version. The module from a
+// local path has the key described in localModuleKey.
+func parseModuleKey(accessory v1.Accessory, dependencies *pkg.Dependencies, versionOverrides map[string]string) (string, error) {
+	if accessory == nil {
+		log.Info("accessory is nil, return empty module key")
+		return "", nil
+	}
+
+	moduleName, err := getModuleName(accessory)
+	if err != nil {
+		return "", err
+	}
+
+	// find module namespace and version
+	d, ok := dependencies.Deps.Get(moduleName)
+	if !ok {
+		return "", fmt.Errorf("can not find module %s in dependencies", moduleName)
+	}
+	// key example "kusionstack/mysql@v0.1.0"
+	var repo, version string
+	if d.Oci != nil {
+		repo, version = d.Oci.Repo, d.Version
+	} else if d.Git != nil {
+		// todo: kpm will change the repo version with the filed `version` in d.Git.Version
+		url := strings.TrimSuffix(d.Git.Url, ".git")
+		splits := strings.Split(url, "/")
+		repo, version = splits[len(splits)-2]+"/"+splits[len(splits)-1], d.Git.Tag
+	} else if d.Local != nil {
+		// the module from a local path is never published, thus its version is not overridden
+		return localModuleKey(moduleName, d), nil
+	} else {
+		return "", nil
+	}
+
+	if override := versionOverrides[moduleName]; override != "" && override != version {
+		if err = checkModuleVersionAvailable(repo, override); err != nil {
+			return "", fmt.Errorf("invalid version override %s of module %s: %w", override, moduleName, err)
+		}
+		log.Infof("override the version of module %s from %s to %s", moduleName, version, override)
+		version = override
+	}
+	return fmt.Sprintf("%s@%s", repo, version), nil
+}
+
+// localModuleRepo is the repo in the key of the modules from the local paths.
+const localModuleRepo = "local"
+
+// localModuleKey returns the key of the module from a local path dependency in the format of
+// "local/<module>@0.0.0-local.<hash>", where the synthetic version derived from the resolved path keeps the
+// key stable across the generations and distinct between the paths of the module in development. The plugin
+// of the module is started from the module directory, see newPlugin.
+func localModuleKey(moduleName string, d pkg.Dependency) string {
+	path := d.LocalFullPath
+	if path == "" {
+		path = d.Local.Path
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return fmt.Sprintf("%s/%s@0.0.0-local.%s", localModuleRepo, moduleName, hex.EncodeToString(sum[:4]))
+}
+
+// checkModuleVersionAvailable checks whether the module of the specified version has been
+// downloaded into the $KUSION_HOME modules directory.
+func checkModuleVersionAvailable(repo, version string) error {
+	kusionHomePath, err := kfile.KusionDataFolder()
+	if err != nil {
+		return err
+	}
+	moduleDir := filepath.Join(kusionHomePath, "modules", repo, version, goruntime.GOOS, goruntime.GOARCH)
+	if exist, _ := kfile.FileExists(moduleDir); !exist {
+		return fmt.Errorf("module %s@%s is not available in %s", repo, version, moduleDir)
+	}
+	return nil
+}
+
+// moduleVersionOverrides returns the module versions overridden by the workspace, indexed by the module name.
+func (g *appConfigurationGenerator) moduleVersionOverrides() map[string]string {
+	overrides := make(map[string]string)
+	for name, config := range g.ws.Modules {
+		if config != nil && config.VersionOverride != "" {
+			overrides[name] = config.VersionOverride
+		}
+	}
+	return overrides
+}
+
+func getModuleName(accessory v1.Accessory) (string, error) {
+	t, ok := accessory["_type"]
+	if !ok {
+		return "", errors.New("can not find '_type' in module config")
+	}
+	split := strings.Split(t.(string), ".")
+	return split[0], nil
+}
+
+func (g *appConfigurationGenerator) initModuleRequest(config moduleConfig) (*proto.GeneratorRequest, error) {
+	var workloadConfig, secretStoreConfig, devConfig, platformConfig, ctx []byte
+	var err error
+	// the named workload is generated as the workload of its own app named after it, so that the resources
+	// of the workloads sharing a module do not conflict
+	app, workload := g.appName, g.app.Workload
+	if config.workloadName != "" {
+		app, workload = g.appName+"-"+config.workloadName, config.devConfig
+	}
+	// Attention: we MUST yaml.v2 to serialize the object,
+	// because we have introduced MapSlice in the Workload which is supported only in the yaml.v2
+	if workload != nil {
+		if workloadConfig, err = yamlv2.Marshal(workload); err != nil {
+			return nil, fmt.Errorf("marshal workload config failed. %w", err)
+		}
+	}
+	// the secret store is selected for the namespace on generating, the default one is used otherwise
+	secretStore := g.secretStore
+	if secretStore == nil {
+		secretStore = g.ws.SecretStore
+	}
+	if secretStore != nil {
+		if secretStoreConfig, err = yamlv2.Marshal(secretStore); err != nil {
+			return nil, fmt.Errorf("marshal secret store config failed. %w", err)
+		}
+	}
+	if config.devConfig != nil {
+		if devConfig, err = yaml.Marshal(config.devConfig); err != nil {
+			return nil, fmt.Errorf("marshal dev module config failed. %w", err)
 		}
 	}
 	if config.platformConfig != nil {
@@ -708,7 +2676,7 @@ func (g *appConfigurationGenerator) initModuleRequest(config moduleConfig) (*pro
 	protoRequest := &proto.GeneratorRequest{
 		Project:        g.project.Name,
 		Stack:          g.stack.Name,
-		App:            g.appName,
+		App:            app,
 		Workload:       workloadConfig,
 		DevConfig:      devConfig,
 		PlatformConfig: platformConfig,
@@ -718,72 +2686,598 @@ func (g *appConfigurationGenerator) initModuleRequest(config moduleConfig) (*pro
 	return protoRequest, nil
 }
 
+// validateSecretStores validates the secret stores of the workspace have the required fields of their
+// providers, which must be registered, and normalizes them with the defaults of the providers. The
+// fallbacks and the secret stores of the namespaces must refer to the named secret stores.
+func validateSecretStores(ws *v1.Workspace) error {
+	validate := func(name string, store *v1.SecretStore) error {
+		if store == nil {
+			return fmt.Errorf("invalid %s of workspace %s: %w", name, ws.Name, workspace.ErrMissingProvider)
+		}
+		if errs := workspace.ValidateSecretStoreConfig(store); errs != nil {
+			return fmt.Errorf("invalid %s of workspace %s: %w", name, ws.Name, utilerrors.NewAggregate(errs))
+		}
+		if err := secrets.ValidateProviderSpec(store.Provider); err != nil {
+			return fmt.Errorf("invalid %s of workspace %s: %w", name, ws.Name, err)
+		}
+		normalizeSecretStore(store)
+		return nil
+	}
+
+	if ws.SecretStore != nil {
+		if err := validate("secretStore", ws.SecretStore); err != nil {
+			return err
+		}
+	}
+	for _, name := range ws.SecretStoreFallbacks {
+		if _, ok := ws.SecretStores[name]; !ok {
+			return fmt.Errorf("invalid secretStoreFallbacks of workspace %s: secret store %s is not configured", ws.Name, name)
+		}
+	}
+	err := generators.ForeachOrdered(ws.SecretStoreNamespaces, func(namespace string, name string) error {
+		if _, ok := ws.SecretStores[name]; !ok {
+			return fmt.Errorf("invalid secretStoreNamespaces of workspace %s: secret store %s of namespace %s is not configured",
+				ws.Name, name, namespace)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return generators.ForeachOrdered(ws.SecretStores, func(name string, store *v1.SecretStore) error {
+		return validate(fmt.Sprintf("secret store %s", name), store)
+	})
+}
+
+// getSecretStore returns the name and spec of the secret store selected for the namespace, which falls
+// back to the default secret store of the workspace with an empty name.
+func (g *appConfigurationGenerator) getSecretStore(namespace string) (string, *v1.SecretStore) {
+	if name, ok := g.ws.SecretStoreNamespaces[namespace]; ok && namespace != "" {
+		return name, g.ws.SecretStores[name]
+	}
+	return "", g.ws.SecretStore
+}
+
+// normalizeSecretStore sets the defaults of the provider of the secret store.
+func normalizeSecretStore(store *v1.SecretStore) {
+	if vault := store.Provider.Vault; vault != nil && vault.Version == "" {
+		vault.Version = v1.VaultKVStoreV2
+	}
+	if azure := store.Provider.Azure; azure != nil && azure.EnvironmentType == "" {
+		azure.EnvironmentType = v1.AzureEnvironmentPublicCloud
+	}
+}
+
 // getNamespaceName obtains the final namespace name using the following precedence
-// (from lower to higher):
+// (from higher to lower):
+// - KubernetesNamespace extension of the stack
+// - KubernetesNamespace extension of the project
+// - KubernetesNamespace extension of the workspace
 // - Project name
-// - KubernetesNamespace extensions (specified in corresponding workspace file)
-func (g *appConfigurationGenerator) getNamespaceName() string {
-	extensions := mergeExtensions(g.project, g.stack)
-	if len(extensions) != 0 {
-		for _, extension := range extensions {
-			switch extension.Kind {
-			case v1.KubernetesNamespace:
-				return extension.KubeNamespace.Namespace
-			default:
-				// do nothing
+// so that a stack can override its own namespace while the project provides the default for the others.
+// The placeholders in the namespace of the extension, e.g. {project}-{stack}, are rendered against the
+// project and the stack. The namespace resolved is validated against the RFC 1123 label rules, so that an
+// invalid namespace fails the generation instead of the apply. It also reports whether the namespace is set to
+// the namespace-less resources generated by the modules, as opted in by the DefaultResources of the extension.
+func (g *appConfigurationGenerator) getNamespaceName() (string, bool, error) {
+	namespace := g.project.Name
+	var defaultResources bool
+	extensions, shadowed := mergeExtensions(
+		extensionScope{name: "stack " + g.stack.Name, extensions: g.stack.Extensions},
+		extensionScope{name: "project " + g.project.Name, extensions: g.project.Extensions},
+		extensionScope{name: "workspace " + g.ws.Name, extensions: g.ws.Extensions, defaults: true},
+	)
+	for _, warning := range shadowed {
+		log.Warn(warning)
+	}
+	for _, extension := range extensions {
+		if extension.Kind == v1.KubernetesNamespace {
+			var err error
+			if namespace, err = g.renderNamespace(extension.KubeNamespace.Namespace); err != nil {
+				return "", false, err
 			}
+			defaultResources = extension.KubeNamespace.DefaultResources
+			break
+		}
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return "", false, fmt.Errorf("invalid namespace %q of app %s: %s", namespace, g.appName, strings.Join(errs, "; "))
+	}
+	return namespace, defaultResources, nil
+}
+
+// namespacePlaceholder matches the placeholders in the namespace template, e.g. {project}.
+var namespacePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// renderNamespace renders the placeholders {project} and {stack} in the namespace template with the names
+// of the project and the stack, while the literal namespace is returned as it is.
+func (g *appConfigurationGenerator) renderNamespace(template string) (string, error) {
+	variables := map[string]string{
+		"project": g.project.Name,
+		"stack":   g.stack.Name,
+	}
+	var unknown []string
+	namespace := namespacePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := namespacePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := variables[name]
+		if !ok {
+			unknown = append(unknown, placeholder)
 		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholders %s in namespace %q, only {project} and {stack} are supported",
+			strings.Join(unknown, ", "), template)
 	}
+	return namespace, nil
+}
 
-	return g.project.Name
+// extensionScope is the extensions declared at a scope, e.g. the stack or the project.
+type extensionScope struct {
+	name       string
+	extensions []*v1.Extension
+	// defaults marks the extensions of the scope as the defaults meant to be overridden by the higher
+	// scopes, e.g. the workspace, thus shadowing them is intended rather than a conflict.
+	defaults bool
 }
 
-func mergeExtensions(project *v1.Project, stack *v1.Stack) []*v1.Extension {
+// mergeExtensions merges the extensions of the scopes given in the order of precedence from higher to
+// lower, e.g. the stack and then the project. The extension of a kind in a scope shadows the ones of the
+// same kind in the lower scopes, while the empty extensions are skipped, so that they do not shadow the
+// lower scopes, e.g. a KubernetesNamespace extension of the stack without a namespace. It returns the
+// warnings of the extensions shadowed by a different one of the same kind as well, which usually are
+// accidental overrides, except for the ones of the default scopes.
+func mergeExtensions(scopes ...extensionScope) ([]*v1.Extension, []string) {
 	var extensions []*v1.Extension
-	extensionKindMap := make(map[string]struct{})
-	if len(stack.Extensions) != 0 {
-		for _, extension := range stack.Extensions {
+	var shadowed []string
+	// kindScopes maps the extension kind to the extension and the scope taking precedence
+	type kindScope struct {
+		extension *v1.Extension
+		scope     string
+	}
+	kindScopes := make(map[v1.ExtensionKind]kindScope)
+	for _, scope := range scopes {
+		// the extensions of the same kind in a scope are all kept as they are
+		scopeKinds := make(map[v1.ExtensionKind]*v1.Extension)
+		for _, extension := range scope.extensions {
+			if isEmptyExtension(extension) {
+				continue
+			}
+			if winner, exist := kindScopes[extension.Kind]; exist {
+				if !scope.defaults && !reflect.DeepEqual(winner.extension, extension) {
+					shadowed = append(shadowed, fmt.Sprintf("%s extension of %s is shadowed by the one of %s",
+						extension.Kind, scope.name, winner.scope))
+				}
+				continue
+			}
 			extensions = append(extensions, extension)
-			extensionKindMap[string(extension.Kind)] = struct{}{}
+			if _, exist := scopeKinds[extension.Kind]; !exist {
+				scopeKinds[extension.Kind] = extension
+			}
+		}
+		for kind, extension := range scopeKinds {
+			kindScopes[kind] = kindScope{extension: extension, scope: scope.name}
+		}
+	}
+	return extensions, shadowed
+}
+
+// isEmptyExtension reports whether the extension is nil or has nothing set for its kind.
+func isEmptyExtension(extension *v1.Extension) bool {
+	if extension == nil {
+		return true
+	}
+	switch extension.Kind {
+	case v1.KubernetesNamespace:
+		return extension.KubeNamespace.Namespace == ""
+	case v1.KubernetesMetadata:
+		return len(extension.KubeMetadata.Labels) == 0 && len(extension.KubeMetadata.Annotations) == 0
+	default:
+		return false
+	}
+}
+
+// getProjectImportedResources merges the imported resources of the module configs of the project, which are
+// declared inline by the importedResources or in the file referenced by the importedResourcesFile. The same
+// kusion ID importing different resources in different sources is reported as a conflict. The files are
+// only loaded if loadFiles is set, as the relative paths are resolved against the stack being generated.
+func (g *appConfigurationGenerator) getProjectImportedResources(
+	projectModuleConfigs map[string]v1.GenericConfig,
+	loadFiles bool,
+) (map[string]string, error) {
+	projectImportedResources := make(map[string]string)
+	// sources records where the kusion ID is imported for reporting the conflicts
+	sources := make(map[string]string)
+	merge := func(source string, importedResources map[string]string) error {
+		for kusionID, importedID := range importedResources {
+			if id, ok := projectImportedResources[kusionID]; ok && id != importedID {
+				return fmt.Errorf("duplicate kusion id '%s' for importing different resources: '%s' in %s and '%s' in %s",
+					kusionID, id, sources[kusionID], importedID, source)
+			}
+			projectImportedResources[kusionID] = importedID
+			sources[kusionID] = source
+		}
+		return nil
+	}
+
+	// merge in the order of the module names for the deterministic conflict reporting
+	err := generators.ForeachOrdered(projectModuleConfigs, func(moduleName string, cfg v1.GenericConfig) error {
+		importedResources, err := workspace.GetStringMapFromGenericConfig(cfg, v1.FieldImportedResources)
+		if err != nil {
+			return err
+		}
+		if err = merge(fmt.Sprintf("module config %s", moduleName), importedResources); err != nil {
+			return err
+		}
+
+		if !loadFiles {
+			return nil
+		}
+		path, err := workspace.GetStringFromGenericConfig(cfg, v1.FieldImportedResourcesFile)
+		if err != nil {
+			return err
+		}
+		if path != "" {
+			fileResources, err := g.loadImportedResourcesFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to load imported resources file of module %s: %w", moduleName, err)
+			}
+			if err = merge(fmt.Sprintf("file %s", path), fileResources); err != nil {
+				return err
+			}
+		}
+
+		key, err := workspace.GetStringFromGenericConfig(cfg, v1.FieldImportedResourcesObject)
+		if err != nil || key == "" {
+			return err
+		}
+		objectResources, err := g.loadImportedResourcesObject(key)
+		if err != nil {
+			return fmt.Errorf("failed to load imported resources object of module %s: %w", moduleName, err)
+		}
+		return merge(fmt.Sprintf("backend object %s", key), objectResources)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projectImportedResources, nil
+}
+
+// checkImportedResourcesAcrossProjects checks that a resource is imported under the same kusion ID by all the
+// projects selected in the module configs of the workspace, as importing the same resource under different
+// kusion IDs results in two resources managing the same live resource at apply. The mapping files of the other
+// projects are relative to their own stacks, so only their inline imported resources are checked.
+func (g *appConfigurationGenerator) checkImportedResourcesAcrossProjects(projectImportedResources map[string]string) error {
+	projects := map[string]struct{}{g.project.Name: {}}
+	for _, cfg := range g.ws.Modules {
+		if cfg == nil {
+			continue
+		}
+		for name, patcherCfg := range cfg.Configs.ModulePatcherConfigs {
+			if name == v1.DefaultBlock || patcherCfg == nil {
+				continue
+			}
+			for _, project := range patcherCfg.ProjectSelector {
+				projects[project] = struct{}{}
+			}
 		}
 	}
-	if len(project.Extensions) != 0 {
-		for _, extension := range project.Extensions {
-			if _, exist := extensionKindMap[string(extension.Kind)]; !exist {
-				extensions = append(extensions, extension)
+
+	type importRecord struct {
+		kusionID string
+		project  string
+	}
+	imports := make(map[string]importRecord)
+	check := func(project string, importedResources map[string]string) error {
+		return generators.ForeachOrdered(importedResources, func(kusionID, importedID string) error {
+			if record, ok := imports[importedID]; ok && record.kusionID != kusionID {
+				return fmt.Errorf("resource '%s' is imported under different kusion ids: '%s' in project %s and '%s' in project %s",
+					importedID, record.kusionID, record.project, kusionID, project)
 			}
+			imports[importedID] = importRecord{kusionID: kusionID, project: project}
+			return nil
+		})
+	}
+
+	// check the project being generated first, then the other projects in the order of their names
+	if err := check(g.project.Name, projectImportedResources); err != nil {
+		return err
+	}
+	delete(projects, g.project.Name)
+	return generators.ForeachOrdered(projects, func(project string, _ struct{}) error {
+		projectModuleConfigs, err := workspace.GetProjectModuleConfigs(g.ws.Modules, project)
+		if err != nil {
+			return err
+		}
+		importedResources, err := g.getProjectImportedResources(projectModuleConfigs, false)
+		if err != nil {
+			return fmt.Errorf("invalid imported resources of project %s: %w", project, err)
+		}
+		return check(project, importedResources)
+	})
+}
+
+// loadImportedResourcesFile loads the mapping from the kusion IDs to the imported resource IDs from the file,
+// whose path is relative to the stack directory. The paths resolved outside the stack directory are rejected,
+// so that the workspace config cannot read arbitrary files of the host, e.g. in the server mode.
+func (g *appConfigurationGenerator) loadImportedResourcesFile(path string) (map[string]string, error) {
+	if g.stack.Path == "" {
+		return nil, errors.New("the stack path is unknown to resolve the file")
+	}
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("file %s should be a relative path inside the stack directory", path)
+	}
+	stackDir, err := filepath.EvalSymlinks(g.stack.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the stack directory: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(stackDir, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve file %s: %w", path, err)
+	}
+	if rel, err := filepath.Rel(stackDir, resolved); err != nil || !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("file %s should be a relative path inside the stack directory", path)
+	}
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, errors.Unwrap(err))
+	}
+	return parseImportedResources(path, content)
+}
+
+// loadImportedResourcesObject loads the mapping from the kusion IDs to the imported resource IDs from the
+// object stored in the backend with the key.
+func (g *appConfigurationGenerator) loadImportedResourcesObject(key string) (map[string]string, error) {
+	if g.objects == nil {
+		return nil, errors.New("the backend does not support reading objects")
+	}
+	content, err := g.objects.ReadObject(key)
+	if err != nil {
+		return nil, err
+	}
+	return parseImportedResources(key, content)
+}
+
+// parseImportedResources parses the mapping from the kusion IDs to the imported resource IDs. The CSV content,
+// which is named with the .csv extension, has the kusion ID and the imported resource ID in each record, and
+// the others are parsed as a YAML or JSON map. The errors never include the content, which is possibly not
+// a mapping at all.
+func parseImportedResources(name string, content []byte) (map[string]string, error) {
+	importedResources := make(map[string]string)
+	if !strings.EqualFold(filepath.Ext(name), ".csv") {
+		if err := yaml.Unmarshal(content, &importedResources); err != nil {
+			return nil, fmt.Errorf("invalid imported resources %s: not a map from the kusion ids to the imported ids", name)
+		}
+		return importedResources, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("invalid imported resources %s: malformed record on line %d", name, parseErr.StartLine)
+		}
+		return nil, fmt.Errorf("invalid imported resources %s: malformed csv", name)
+	}
+	for i, record := range records {
+		kusionID, importedID := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if kusionID == "" || importedID == "" {
+			return nil, fmt.Errorf("invalid imported resources %s: empty id in record %d", name, i+1)
+		}
+		if id, ok := importedResources[kusionID]; ok && id != importedID {
+			return nil, fmt.Errorf("invalid imported resources %s: record %d imports a different resource under the kusion id of a previous record",
+				name, i+1)
 		}
+		importedResources[kusionID] = importedID
+	}
+	return importedResources, nil
+}
+
+// getImportedResourcesKeepAttributes returns the keys of the attributes kept for the imported resources
+// by the module names, read from the module configs of the project.
+func getImportedResourcesKeepAttributes(projectModuleConfigs map[string]v1.GenericConfig) (map[string][]string, error) {
+	keepAttributes := make(map[string][]string)
+	err := generators.ForeachOrdered(projectModuleConfigs, func(moduleName string, cfg v1.GenericConfig) error {
+		keys, err := workspace.GetStringSliceFromGenericConfig(cfg, v1.FieldImportedResourcesKeepAttributes)
+		if err != nil {
+			return fmt.Errorf("invalid module config %s: %w", moduleName, err)
+		}
+		if len(keys) > 0 {
+			keepAttributes[moduleName] = keys
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return extensions
+	return keepAttributes, nil
 }
 
 // patchImportedResources patch the imported resource IDs to the `extensions` field
-// of the resources in Spec.
-func patchImportedResources(resources v1.Resources, projectImportedResources map[string]string) error {
+// of the resources in Spec. The attributes of the imported resources are cleared except the keepAttributes
+// of the modules generating them, which are looked up by the resourceModules.
+// The wildcard entries are expanded to the resources they match, and the imported IDs are validated by the
+// provider of the resources. The sorted kusion IDs matching no resource are returned, which are usually typos
+// in the import mappings.
+func patchImportedResources(
+	resources v1.Resources,
+	projectImportedResources map[string]string,
+	keepAttributes map[string][]string,
+	resourceModules map[string]string,
+) ([]string, error) {
+	importedResources, unmatched, err := expandImportedResources(resources, projectImportedResources)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the map of Kusion ID and Kusion Resource.
 	resIndex := resources.Index()
 
 	// Set the `extensions` field of each Kusion Resource.
-	for kusionID, importedID := range projectImportedResources {
-		if res, ok := resIndex[kusionID]; ok {
-			res.Extensions[tfops.ImportIDKey] = importedID
-			// remove the resource attribute to avoid update conflict when using terraform import
-			res.Attributes = make(map[string]interface{})
+	err = generators.ForeachOrdered(importedResources, func(kusionID string, importedID string) error {
+		res, ok := resIndex[kusionID]
+		if !ok {
+			unmatched = append(unmatched, kusionID)
+			return nil
+		}
+		if err := validateImportedID(res, importedID); err != nil {
+			return fmt.Errorf("invalid imported ID %q of resource %s: %w", importedID, kusionID, err)
+		}
+		if res.Extensions == nil {
+			res.Extensions = make(map[string]interface{})
+		}
+		res.Extensions[tfops.ImportIDKey] = importedID
+		// remove the resource attribute to avoid update conflict when using terraform import, while the
+		// attributes needed by the provider to locate the resource are kept, e.g. the region
+		attributes := make(map[string]interface{})
+		for _, key := range keepAttributes[resourceModules[kusionID]] {
+			if value, ok := res.Attributes[key]; ok {
+				attributes[key] = value
+			}
 		}
+		res.Attributes = attributes
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	sort.Strings(unmatched)
 
-	return nil
+	return unmatched, nil
 }
 
 // patchHealthPolicy patch the health policy to the `extensions` field of the resource in the Spec.
-func patchHealthPolicy(resource *v1.Resource, healthPolicy any) {
+func patchHealthPolicy(resource *v1.Resource, healthPolicy v1.GenericConfig) {
 	healthPolicyMap := make(map[string]any)
-	if hp, ok := healthPolicy.(v1.GenericConfig); ok {
-		for k, v := range hp {
-			healthPolicyMap[k] = v
+	for k, v := range healthPolicy {
+		healthPolicyMap[k] = v
+	}
+	if resource.Extensions == nil {
+		resource.Extensions = make(map[string]interface{})
+	}
+	resource.Extensions[v1.FieldHealthPolicy] = healthPolicyMap
+}
+
+// moduleHealthPolicies are the health policies in the platform config of a module, which is either a
+// single health policy in the map form or a list of them.
+type moduleHealthPolicies struct {
+	// workload is the single health policy in the map form, patched to the workload regardless of its kind.
+	workload v1.GenericConfig
+	// matchers are the health policies patched to the Kubernetes resources of their apiVersion and kind.
+	matchers []v1.GenericConfig
+}
+
+// match patches the first health policy matching the apiVersion and kind of the resource to it.
+func (p *moduleHealthPolicies) match(resource *v1.Resource) {
+	for _, hp := range p.matchers {
+		if matchHealthPolicy(resource, hp) {
+			return
+		}
+	}
+}
+
+// parseHealthPolicies validates the shape of the health policies in the platform config of the module.
+// The single health policy in the map form is patched to the workload, and to the Kubernetes resources of
+// its apiVersion and kind if set, while each health policy in the list form must set its apiVersion and
+// kind to match the resources, e.g. a Deployment and a Service generated by the same module.
+func parseHealthPolicies(moduleKey string, healthPolicy any, isWorkloadModule bool) (*moduleHealthPolicies, error) {
+	policies := &moduleHealthPolicies{}
+	var items []any
+	switch p := healthPolicy.(type) {
+	case nil:
+		return policies, nil
+	case []any:
+		items = p
+	case []v1.GenericConfig:
+		for _, item := range p {
+			items = append(items, item)
+		}
+	case []map[string]any:
+		for _, item := range p {
+			items = append(items, item)
+		}
+	default:
+		hp, err := parseHealthPolicy(v1.FieldHealthPolicy, moduleKey, healthPolicy)
+		if err != nil {
+			return nil, err
+		}
+		policies.workload = hp
+		if hasHealthPolicyMatcher(hp) {
+			policies.matchers = append(policies.matchers, hp)
+		} else if !isWorkloadModule {
+			log.Warnf("%s of module %s has no apiVersion and kind of the Kubernetes resources to check, skipped",
+				v1.FieldHealthPolicy, moduleKey)
+		}
+		return policies, nil
+	}
+
+	for i, item := range items {
+		field := fmt.Sprintf("%s[%d]", v1.FieldHealthPolicy, i)
+		hp, err := parseHealthPolicy(field, moduleKey, item)
+		if err != nil {
+			return nil, err
+		}
+		if !hasHealthPolicyMatcher(hp) {
+			return nil, fmt.Errorf("%s of module %s must set the apiVersion and kind to match", field, moduleKey)
+		}
+		policies.matchers = append(policies.matchers, hp)
+	}
+	return policies, nil
+}
+
+// parseHealthPolicy validates the shape of the health policy of the field in the platform config of the
+// module, which must be a map with the apiVersion and kind set together as strings if any.
+func parseHealthPolicy(field, moduleKey string, healthPolicy any) (v1.GenericConfig, error) {
+	var hp v1.GenericConfig
+	switch p := healthPolicy.(type) {
+	case v1.GenericConfig:
+		hp = p
+	case map[string]any:
+		hp = p
+	default:
+		return nil, fmt.Errorf("%s of module %s must be a map, got %T", field, moduleKey, healthPolicy)
+	}
+
+	for _, key := range []string{"apiVersion", "kind"} {
+		if v, ok := hp[key]; ok {
+			if _, ok = v.(string); !ok {
+				return nil, fmt.Errorf("%s of %s of module %s must be a string, got %T", key, field, moduleKey, v)
+			}
 		}
-		resource.Extensions[v1.FieldHealthPolicy] = healthPolicyMap
 	}
+	apiVersion, kind := getAPIVersionKindFromHealthPolicy(hp)
+	switch {
+	case apiVersion == "" && kind != "":
+		return nil, fmt.Errorf("%s of module %s targeting kind %s must set the apiVersion", field, moduleKey, kind)
+	case apiVersion != "" && kind == "":
+		return nil, fmt.Errorf("%s of module %s targeting apiVersion %s must set the kind", field, moduleKey, apiVersion)
+	}
+	return hp, nil
+}
+
+// hasHealthPolicyMatcher reports whether the health policy sets the apiVersion and kind to match.
+func hasHealthPolicyMatcher(healthPolicy v1.GenericConfig) bool {
+	apiVersion, kind := getAPIVersionKindFromHealthPolicy(healthPolicy)
+	return apiVersion != "" && kind != ""
+}
+
+// matchHealthPolicy patches the health policy to the resource if it is a Kubernetes resource of the
+// apiVersion and kind of the health policy, and reports whether it is patched.
+func matchHealthPolicy(resource *v1.Resource, healthPolicy v1.GenericConfig) bool {
+	if resource.Type != v1.Kubernetes || resource.Attributes == nil {
+		return false
+	}
+	resAPIVersion, resKind := getAPIVersionKindFromAttributes(resource.Attributes)
+	hpAPIVersion, hpKind := getAPIVersionKindFromHealthPolicy(healthPolicy)
+	if !strings.EqualFold(resAPIVersion, hpAPIVersion) || !strings.EqualFold(resKind, hpKind) {
+		return false
+	}
+	patchHealthPolicy(resource, healthPolicy)
+	return true
 }
 
 // getAPIVersionKindFromAttributes returns the API version and kind from the resource attributes.
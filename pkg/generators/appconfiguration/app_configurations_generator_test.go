@@ -2,12 +2,24 @@ package appconfiguration
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/bytedance/mockey"
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -21,7 +33,11 @@ import (
 	"kusionstack.io/kusion-module-framework/pkg/module"
 	"kusionstack.io/kusion-module-framework/pkg/module/proto"
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+	ns "kusionstack.io/kusion/pkg/generators/namespace"
+	"kusionstack.io/kusion/pkg/generators/rename"
 	jsonutil "kusionstack.io/kusion/pkg/util/json"
+	"kusionstack.io/kusion/pkg/util/kfile"
 )
 
 type fakeModule struct{}
@@ -65,7 +81,7 @@ func (f *fakeModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*pr
 }
 
 func mockPlugin() (*mockey.Mocker, *mockey.Mocker) {
-	pluginMock := mockey.Mock(module.NewPlugin).To(func(key string) (*module.Plugin, error) {
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
 		return &module.Plugin{Module: &fakeModule{}}, nil
 	}).Build()
 	killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
@@ -141,399 +157,3698 @@ func TestAppConfigurationGenerator_Generate_CustomNamespace(t *testing.T) {
 	}
 }
 
-func TestNewAppConfigurationGeneratorFunc(t *testing.T) {
+func TestAppConfigurationGenerator_GetSecretStore(t *testing.T) {
+	defaultStore := &v1.SecretStore{Provider: &v1.ProviderSpec{Fake: &v1.FakeProvider{}}}
+	tenantStore := &v1.SecretStore{Provider: &v1.ProviderSpec{
+		Vault: &v1.VaultProvider{Server: "https://vault.example.com:8200"},
+	}}
+	g := &appConfigurationGenerator{ws: &v1.Workspace{
+		SecretStore:           defaultStore,
+		SecretStores:          map[string]*v1.SecretStore{"tenant-a": tenantStore},
+		SecretStoreNamespaces: map[string]string{"tenant-a": "tenant-a"},
+	}}
+
+	testcases := []struct {
+		name          string
+		namespace     string
+		expectedName  string
+		expectedStore *v1.SecretStore
+	}{
+		{name: "namespace specific", namespace: "tenant-a", expectedName: "tenant-a", expectedStore: tenantStore},
+		{name: "fall back to the default", namespace: "tenant-b", expectedStore: defaultStore},
+		{name: "cluster-scoped app", namespace: "", expectedStore: defaultStore},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, store := g.getSecretStore(tc.namespace)
+			assert.Equal(t, tc.expectedName, name)
+			assert.Same(t, tc.expectedStore, store)
+		})
+	}
+}
+
+func TestAppConfigurationGenerator_GetNamespaceName(t *testing.T) {
+	namespaceExtension := func(namespace string) *v1.Extension {
+		return &v1.Extension{
+			Kind:          v1.KubernetesNamespace,
+			KubeNamespace: v1.KubeNamespaceExtension{Namespace: namespace},
+		}
+	}
+	tests := []struct {
+		name              string
+		projectExtensions []*v1.Extension
+		stackExtensions   []*v1.Extension
+		wsExtensions      []*v1.Extension
+		expectedNamespace string
+		expectedErr       string
+	}{
+		{
+			name:              "project name",
+			expectedNamespace: "testproject",
+		},
+		{
+			name:              "project extension",
+			projectExtensions: []*v1.Extension{namespaceExtension("project-ns")},
+			expectedNamespace: "project-ns",
+		},
+		{
+			name:              "stack extension overriding project extension",
+			projectExtensions: []*v1.Extension{namespaceExtension("project-ns")},
+			stackExtensions:   []*v1.Extension{namespaceExtension("stack-ns")},
+			expectedNamespace: "stack-ns",
+		},
+		{
+			name:              "stack extension without namespace",
+			projectExtensions: []*v1.Extension{namespaceExtension("project-ns")},
+			stackExtensions:   []*v1.Extension{namespaceExtension("")},
+			expectedNamespace: "project-ns",
+		},
+		{
+			name:              "workspace extension",
+			wsExtensions:      []*v1.Extension{namespaceExtension("{project}-{stack}")},
+			expectedNamespace: "testproject-test",
+		},
+		{
+			name:              "project extension overriding workspace extension",
+			projectExtensions: []*v1.Extension{namespaceExtension("project-ns")},
+			wsExtensions:      []*v1.Extension{namespaceExtension("{project}-{stack}")},
+			expectedNamespace: "project-ns",
+		},
+		{
+			name:              "stack extension overriding workspace extension",
+			stackExtensions:   []*v1.Extension{namespaceExtension("stack-ns")},
+			wsExtensions:      []*v1.Extension{namespaceExtension("{project}-{stack}")},
+			expectedNamespace: "stack-ns",
+		},
+		{
+			name:              "templated namespace",
+			projectExtensions: []*v1.Extension{namespaceExtension("{project}-{stack}-prod")},
+			expectedNamespace: "testproject-test-prod",
+		},
+		{
+			name:              "unknown placeholder",
+			projectExtensions: []*v1.Extension{namespaceExtension("{project}-{env}")},
+			expectedErr:       `unknown placeholders {env} in namespace "{project}-{env}", only {project} and {stack} are supported`,
+		},
+		{
+			name:              "namespace too long",
+			projectExtensions: []*v1.Extension{namespaceExtension(strings.Repeat("a", 64))},
+			expectedErr:       fmt.Sprintf(`invalid namespace "%s" of app testapp: must be no more than 63 characters`, strings.Repeat("a", 64)),
+		},
+		{
+			name:            "namespace with underscores",
+			stackExtensions: []*v1.Extension{namespaceExtension("{project}_{stack}")},
+			expectedErr:     `invalid namespace "testproject_test" of app testapp: a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-'`,
+		},
+		{
+			name:              "stack extension of another kind",
+			projectExtensions: []*v1.Extension{namespaceExtension("project-ns")},
+			stackExtensions: []*v1.Extension{{
+				Kind:         v1.KubernetesMetadata,
+				KubeMetadata: v1.KubeMetadataExtension{Labels: map[string]string{"team": "infra"}},
+			}},
+			expectedNamespace: "project-ns",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, stack := buildMockProjectAndStack()
+			project.Extensions = tt.projectExtensions
+			stack.Extensions = tt.stackExtensions
+			ws := buildMockWorkspace()
+			ws.Extensions = tt.wsExtensions
+			g := &appConfigurationGenerator{project: project, stack: stack, ws: ws, appName: "testapp"}
+			namespace, _, err := g.getNamespaceName()
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNamespace, namespace)
+		})
+	}
+}
+
+func TestMergeExtensions(t *testing.T) {
+	stackNamespace := &v1.Extension{Kind: v1.KubernetesNamespace, KubeNamespace: v1.KubeNamespaceExtension{Namespace: "stack-ns"}}
+	projectNamespace := &v1.Extension{Kind: v1.KubernetesNamespace, KubeNamespace: v1.KubeNamespaceExtension{Namespace: "project-ns"}}
+	projectMetadata := &v1.Extension{Kind: v1.KubernetesMetadata, KubeMetadata: v1.KubeMetadataExtension{Labels: map[string]string{"team": "infra"}}}
+
+	t.Run("ShadowedKind", func(t *testing.T) {
+		extensions, shadowed := mergeExtensions(
+			extensionScope{name: "stack dev", extensions: []*v1.Extension{stackNamespace}},
+			extensionScope{name: "project foo", extensions: []*v1.Extension{projectNamespace, projectMetadata}},
+		)
+		assert.Equal(t, []*v1.Extension{stackNamespace, projectMetadata}, extensions)
+		assert.Equal(t, []string{"kubernetesNamespace extension of project foo is shadowed by the one of stack dev"}, shadowed)
+	})
+
+	t.Run("SameExtension", func(t *testing.T) {
+		extensions, shadowed := mergeExtensions(
+			extensionScope{name: "stack dev", extensions: []*v1.Extension{projectNamespace}},
+			extensionScope{name: "project foo", extensions: []*v1.Extension{projectNamespace}},
+		)
+		assert.Equal(t, []*v1.Extension{projectNamespace}, extensions)
+		assert.Empty(t, shadowed)
+	})
+
+	t.Run("OverriddenDefaults", func(t *testing.T) {
+		extensions, shadowed := mergeExtensions(
+			extensionScope{name: "project foo", extensions: []*v1.Extension{projectNamespace}},
+			extensionScope{name: "workspace dev", extensions: []*v1.Extension{stackNamespace, projectMetadata}, defaults: true},
+		)
+		assert.Equal(t, []*v1.Extension{projectNamespace, projectMetadata}, extensions)
+		assert.Empty(t, shadowed)
+	})
+}
+
+func TestAppConfigurationGenerator_Generate_ClusterScoped(t *testing.T) {
 	appName, app := buildMockApp()
-	ws := buildMockWorkspace()
+	app.ClusterScoped = true
+
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Name:    "port",
+		Version: "1.0.0",
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      appName,
+		app:          app,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	spec := &v1.Spec{
+		Resources: []v1.Resource{},
+	}
+
+	m1, m2 := mockPlugin()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+	}()
+
+	err := g.Generate(spec)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, spec.Resources)
+
+	// no namespace should be generated for the cluster-scoped app
+	for _, res := range spec.Resources {
+		if res.Type != v1.Kubernetes {
+			continue
+		}
+		assert.NotEqual(t, "Namespace", mapToUnstructured(res.Attributes).GetKind())
+	}
+}
+
+func TestAppConfigurationGenerator_Generate_NoWorkload(t *testing.T) {
+	appName, app := buildMockApp()
+	app.Workload = nil
+
+	// the port dependency has no source, so its module key is empty as the key of a nil workload
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Name:    "port",
+		Version: "1.0.0",
+	})
+
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      appName,
+		app:          app,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	spec := &v1.Spec{
+		Resources: []v1.Resource{},
+	}
+
+	m1, m2 := mockPlugin()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+	}()
+
+	err := g.Generate(spec)
+	assert.NoError(t, err)
+
+	var kinds []string
+	for _, res := range spec.Resources {
+		assert.NotContains(t, res.Extensions, isWorkload, "resource %s should not be the workload", res.ID)
+		kinds = append(kinds, mapToUnstructured(res.Attributes).GetKind())
+	}
+	assert.Contains(t, kinds, "PodTransitionRule")
+	assert.NotContains(t, kinds, "Secret")
+}
+
+// appWorkloadModule generates a deployment marked as the workload and a service, both named after the app
+// in the request.
+type appWorkloadModule struct{}
+
+func (m *appWorkloadModule) Generate(_ context.Context, request *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	deployment := v1.Resource{
+		ID:   "apps/v1:Deployment:" + request.App,
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": request.App},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"name": "main", "image": "nginx"}},
+					},
+				},
+			},
+		},
+		Extensions: map[string]interface{}{v1.FieldWorkloadResource: "true"},
+	}
+	service := v1.Resource{
+		ID:   "v1:Service:" + request.App,
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": request.App},
+		},
+	}
+	return &proto.GeneratorResponse{Resources: [][]byte{
+		[]byte(jsonutil.Marshal2String(deployment)),
+		[]byte(jsonutil.Marshal2String(service)),
+	}}, nil
+}
+
+// labelPatcherModule generates no resources but a patcher of the workload labels.
+type labelPatcherModule struct{}
+
+func (m *labelPatcherModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	patcher := v1.Patcher{Labels: map[string]string{"team": "infra"}}
+	return &proto.GeneratorResponse{Patcher: []byte(jsonutil.Marshal2String(patcher))}, nil
+}
+
+func TestAppConfigurationGenerator_Generate_MultipleWorkloads(t *testing.T) {
+	appName, app := buildMockApp()
+	app.Workloads = map[string]v1.Accessory{
+		"worker": {"_type": "service.Service", "replicas": 1},
+	}
+
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source:  downloader.Source{Oci: &downloader.Oci{Repo: "kusionstack/port"}},
+	})
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Source:  downloader.Source{Oci: &downloader.Oci{Repo: "kusionstack/service"}},
+	})
+
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      appName,
+		app:          app,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	var serviceCalls atomic.Int32
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		if key == "kusionstack/port@1.0.0" {
+			return &module.Plugin{Module: &labelPatcherModule{}}, nil
+		}
+		serviceCalls.Add(1)
+		// start slowly, so that the workloads invoking the module concurrently overlap in starting it
+		time.Sleep(100 * time.Millisecond)
+		return &module.Plugin{Module: &appWorkloadModule{}}, nil
+	}).Build()
+	killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+	defer func() {
+		pluginMock.UnPatch()
+		killMock.UnPatch()
+	}()
+
+	spec := &v1.Spec{}
+	err := g.Generate(spec)
+	assert.NoError(t, err)
+	// the workloads share the plugin of the module
+	assert.Equal(t, int32(1), serviceCalls.Load())
+
+	workloads := make(map[string]map[string]string)
+	var services []string
+	for _, res := range spec.Resources {
+		un := mapToUnstructured(res.Attributes)
+		switch un.GetKind() {
+		case "Deployment":
+			assert.Equal(t, true, res.Extensions[isWorkload])
+			assert.NotContains(t, res.Extensions, v1.FieldWorkloadResource)
+			workloads[un.GetName()] = un.GetLabels()
+		case "Service":
+			assert.NotContains(t, res.Extensions, isWorkload)
+			services = append(services, un.GetName())
+		}
+	}
+	// the patchers are applied to all the workloads
+	assert.Equal(t, map[string]map[string]string{
+		appName:             {"team": "infra"},
+		appName + "-worker": {"team": "infra"},
+	}, workloads)
+	assert.ElementsMatch(t, []string{appName, appName + "-worker"}, services)
+}
+
+// referencingModule generates the workload referencing a ConfigMap by the env valueFrom and a Secret by the
+// volume, along with the Service selecting the workload.
+type referencingModule struct{}
+
+func (m *referencingModule) Generate(_ context.Context, request *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	deployment := v1.Resource{
+		ID:   "apps/v1:Deployment:" + request.App,
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": request.App},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{
+							"name":  "main",
+							"image": "nginx",
+							"env": []interface{}{map[string]interface{}{
+								"name": "LEVEL",
+								"valueFrom": map[string]interface{}{
+									"configMapKeyRef": map[string]interface{}{"name": "config", "key": "level"},
+								},
+							}},
+						}},
+						"volumes": []interface{}{map[string]interface{}{
+							"name":   "certs",
+							"secret": map[string]interface{}{"secretName": "certs"},
+						}},
+					},
+				},
+			},
+		},
+		Extensions: map[string]interface{}{v1.FieldWorkloadResource: "true"},
+	}
+	service := v1.Resource{
+		ID:   "v1:Service:" + request.App,
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": request.App},
+			"spec":       map[string]interface{}{"selector": map[string]interface{}{"app.kubernetes.io/name": request.App}},
+		},
+	}
+	configMap := v1.Resource{
+		ID:         "v1:ConfigMap:config",
+		Type:       v1.Kubernetes,
+		Attributes: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "config"}},
+	}
+	return &proto.GeneratorResponse{Resources: [][]byte{
+		[]byte(jsonutil.Marshal2String(deployment)),
+		[]byte(jsonutil.Marshal2String(service)),
+		[]byte(jsonutil.Marshal2String(configMap)),
+	}}, nil
+}
+
+func TestAppConfigurationGenerator_Generate_Renames(t *testing.T) {
+	appName, app := buildMockApp()
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{Name: "port", Version: "1.0.0"})
+	deps.Set("service", pkg.Dependency{Name: "service", Version: "1.0.0"})
+	project, stack := buildMockProjectAndStack()
+
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		if strings.Contains(key, "port") {
+			return &module.Plugin{Module: &labelPatcherModule{}}, nil
+		}
+		return &module.Plugin{Module: &referencingModule{}}, nil
+	}).Build()
+	killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+	defer func() {
+		pluginMock.UnPatch()
+		killMock.UnPatch()
+	}()
+
+	gf := NewAppConfigurationGeneratorFuncWithOptions(project, stack, appName, app, buildMockWorkspace(),
+		&pkg.Dependencies{Deps: deps}, GeneratorOptions{Renames: []rename.Rename{
+			{Kind: "Deployment", OldName: appName, NewName: appName + "-v2"},
+			{Kind: "ConfigMap", OldName: "config", NewName: "config-v2"},
+			{Kind: "Secret", OldName: "certs", NewName: "certs-v2"},
+		}})
+	g, err := gf()
+	assert.NoError(t, err)
+	spec := &v1.Spec{}
+	assert.NoError(t, g.Generate(spec))
+
+	kinds := make(map[string]*unstructured.Unstructured)
+	for _, res := range spec.Resources {
+		un := mapToUnstructured(res.Attributes)
+		kinds[un.GetKind()] = un
+		if un.GetKind() == "Deployment" || un.GetKind() == "ConfigMap" {
+			assert.True(t, strings.HasSuffix(res.ID, ":"+un.GetName()), res.ID)
+		}
+	}
+	assert.Equal(t, appName+"-v2", kinds["Deployment"].GetName())
+	assert.Equal(t, "config-v2", kinds["ConfigMap"].GetName())
+
+	// the references in the env valueFrom, the volume sources and the service selectors are rewritten
+	containers, _, _ := unstructured.NestedSlice(kinds["Deployment"].Object, "spec", "template", "spec", "containers")
+	env := containers[0].(map[string]interface{})["env"].([]interface{})[0].(map[string]interface{})
+	configMapName, _, _ := unstructured.NestedString(env, "valueFrom", "configMapKeyRef", "name")
+	assert.Equal(t, "config-v2", configMapName)
+	volumes, _, _ := unstructured.NestedSlice(kinds["Deployment"].Object, "spec", "template", "spec", "volumes")
+	secretName, _, _ := unstructured.NestedString(volumes[0].(map[string]interface{}), "secret", "secretName")
+	assert.Equal(t, "certs-v2", secretName)
+	selector, _, _ := unstructured.NestedStringMap(kinds["Service"].Object, "spec", "selector")
+	assert.Equal(t, appName+"-v2", selector["app.kubernetes.io/name"])
+}
+
+func TestAppConfigurationGenerator_Generate_NonStrict(t *testing.T) {
+	appName, app := buildMockApp()
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Name:    "port",
+		Version: "1.0.0",
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+	project, stack := buildMockProjectAndStack()
+	newGenerator := func(nonStrict bool, errs *[]error) *appConfigurationGenerator {
+		return &appConfigurationGenerator{
+			project:       project,
+			stack:         stack,
+			appName:       appName,
+			app:           app,
+			ws:            buildMockWorkspace(),
+			dependencies:  &pkg.Dependencies{Deps: deps},
+			nonStrict:     nonStrict,
+			builtinErrors: errs,
+		}
+	}
+
+	m1, m2 := mockPlugin()
+	nsMock := mockey.Mock(ns.NewNamespaceGenerator).Return(nil, errors.New("invalid namespace")).Build()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+		nsMock.UnPatch()
+	}()
+
+	t.Run("strict by default", func(t *testing.T) {
+		err := newGenerator(false, nil).Generate(&v1.Spec{})
+		assert.ErrorContains(t, err, "invalid namespace")
+	})
+
+	t.Run("non-strict", func(t *testing.T) {
+		var errs []error
+		spec := &v1.Spec{}
+		err := newGenerator(true, &errs).Generate(spec)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, spec.Resources)
+		assert.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "built-in generator of app "+appName+" failed: invalid namespace")
+	})
+
+	t.Run("non-strict on invalid namespace", func(t *testing.T) {
+		var errs []error
+		g := newGenerator(true, &errs)
+		g.stack = &v1.Stack{
+			Name: stack.Name,
+			Extensions: []*v1.Extension{{
+				Kind:          v1.KubernetesNamespace,
+				KubeNamespace: v1.KubeNamespaceExtension{Namespace: "{unknown}"},
+			}},
+		}
+		spec := &v1.Spec{}
+		assert.NoError(t, g.Generate(spec))
+		assert.NotEmpty(t, spec.Resources)
+		assert.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "unknown placeholders {unknown}")
+	})
+}
+
+func TestAppConfigurationGenerator_Generate_StrictSecretStore(t *testing.T) {
+	appName, app := buildMockApp()
+	app.Workload["secrets"] = map[string]any{
+		"db-password": map[string]any{
+			"type": "external",
+			"data": map[string]any{"password": "ref://db/password"},
+		},
+	}
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Name:    "port",
+		Version: "1.0.0",
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+	project, stack := buildMockProjectAndStack()
+	newGenerator := func(strict bool) *appConfigurationGenerator {
+		return &appConfigurationGenerator{
+			project:           project,
+			stack:             stack,
+			appName:           appName,
+			app:               app,
+			ws:                buildMockWorkspace(),
+			dependencies:      &pkg.Dependencies{Deps: deps},
+			strictSecretStore: strict,
+		}
+	}
+
+	m1, m2 := mockPlugin()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+	}()
+
+	t.Run("strict", func(t *testing.T) {
+		err := newGenerator(true).Generate(&v1.Spec{})
+		assert.EqualError(t, err, "external secrets db-password of app app1 reference no secret store, "+
+			"please configure the secret store in workspace test")
+	})
+
+	t.Run("warn by default", func(t *testing.T) {
+		spec := &v1.Spec{}
+		assert.NoError(t, newGenerator(false).Generate(spec))
+		// the secret is generated with the refs unresolved
+		assert.Contains(t, spec.Resources.Index(), "v1:Secret:"+project.Name+":db-password")
+	})
+}
+
+func TestAppConfigurationGenerator_Generate_Summary(t *testing.T) {
+	appName, app := buildMockApp()
+
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+
+	project, stack := buildMockProjectAndStack()
+	summary := &v1.AppResourceSummary{}
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      appName,
+		app:          app,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+		summary:      summary,
+	}
+
+	spec := &v1.Spec{
+		Resources: []v1.Resource{},
+	}
+
+	m1, m2 := mockPlugin()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+	}()
+
+	err := g.Generate(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, appName, summary.Name)
+	assert.Equal(t, len(spec.Resources), summary.Total)
+	assert.Equal(t, map[string]int{
+		builtinModuleKey:            1,
+		"kusionstack/module1@1.0.0": 1,
+		"":                          1,
+	}, summary.Modules)
+}
+
+func TestValidateSecretStores(t *testing.T) {
+	t.Run("valid and normalized", func(t *testing.T) {
+		ws := &v1.Workspace{
+			Name: "dev",
+			SecretStore: &v1.SecretStore{Provider: &v1.ProviderSpec{
+				Vault: &v1.VaultProvider{Server: "https://vault.example.com:8200"},
+			}},
+			SecretStores: map[string]*v1.SecretStore{
+				"fake": {Provider: &v1.ProviderSpec{Fake: &v1.FakeProvider{}}},
+			},
+		}
+		assert.NoError(t, validateSecretStores(ws))
+		assert.Equal(t, v1.VaultKVStoreV2, ws.SecretStore.Provider.Vault.Version)
+	})
+
+	testcases := []struct {
+		name        string
+		ws          *v1.Workspace
+		expectedErr string
+	}{
+		{
+			name: "missing required field",
+			ws: &v1.Workspace{
+				Name:        "dev",
+				SecretStore: &v1.SecretStore{Provider: &v1.ProviderSpec{AWS: &v1.AWSProvider{}}},
+			},
+			expectedErr: "invalid secretStore of workspace dev: region must be provided when using AWS Secrets Manager",
+		},
+		{
+			name: "provider not registered",
+			ws: &v1.Workspace{
+				Name: "dev",
+				SecretStores: map[string]*v1.SecretStore{
+					"kv": {Provider: &v1.ProviderSpec{OnPremises: &v1.OnPremisesProvider{Name: "unknown"}}},
+				},
+			},
+			expectedErr: "invalid secret store kv of workspace dev: secret store provider unknown is not registered",
+		},
+		{
+			name: "missing provider",
+			ws: &v1.Workspace{
+				Name:         "dev",
+				SecretStores: map[string]*v1.SecretStore{"kv": nil},
+			},
+			expectedErr: "invalid secret store kv of workspace dev: invalid secret store spec, missing provider config",
+		},
+		{
+			name: "fallback not configured",
+			ws: &v1.Workspace{
+				Name:                 "dev",
+				SecretStores:         map[string]*v1.SecretStore{"fake": {Provider: &v1.ProviderSpec{Fake: &v1.FakeProvider{}}}},
+				SecretStoreFallbacks: []string{"fake", "aws"},
+			},
+			expectedErr: "invalid secretStoreFallbacks of workspace dev: secret store aws is not configured",
+		},
+		{
+			name: "namespace secret store not configured",
+			ws: &v1.Workspace{
+				Name:                  "dev",
+				SecretStoreNamespaces: map[string]string{"tenant-a": "vault"},
+			},
+			expectedErr: "invalid secretStoreNamespaces of workspace dev: secret store vault of namespace tenant-a is not configured",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorContains(t, validateSecretStores(tc.ws), tc.expectedErr)
+		})
+	}
+}
+
+func TestNewAppConfigurationGeneratorFunc(t *testing.T) {
+	appName, app := buildMockApp()
+	ws := buildMockWorkspace()
+
+	project, stack := buildMockProjectAndStack()
+	t.Run("Valid app configuration generator func", func(t *testing.T) {
+		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, app, ws, nil)()
+		assert.NoError(t, err)
+		assert.NotNil(t, g)
+	})
+
+	t.Run("Empty app name", func(t *testing.T) {
+		g, err := NewAppConfigurationGeneratorFunc(project, stack, "", app, ws, nil)()
+		assert.EqualError(t, err, "app name must not be empty")
+		assert.Nil(t, g)
+	})
+
+	t.Run("Nil app", func(t *testing.T) {
+		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, nil, ws, nil)()
+		assert.EqualError(t, err, "can not find app configuration when generating the Spec")
+		assert.Nil(t, g)
+	})
+
+	t.Run("Nil project", func(t *testing.T) {
+		g, err := NewAppConfigurationGeneratorFunc(nil, stack, appName, app, ws, nil)()
+		assert.EqualError(t, err, "project must not be nil")
+		assert.Nil(t, g)
+	})
+
+	t.Run("Empty workspace", func(t *testing.T) {
+		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, app, nil, nil)()
+		assert.EqualError(t, err, "workspace must not be empty")
+		assert.Nil(t, g)
+	})
+}
+
+func buildMockApp() (string, *v1.AppConfiguration) {
+	return "app1", &v1.AppConfiguration{
+		Workload: map[string]interface{}{
+			"type": "Deployment",
+			"ports": []map[string]any{
+				{
+					"port":     80,
+					"protocol": "TCP",
+				},
+			},
+			"_type": "service",
+		},
+		Accessories: map[string]v1.Accessory{
+			"port": map[string]interface{}{
+				"port":  "2333",
+				"_type": "port.Port",
+			},
+		},
+	}
+}
+
+func buildMockWorkspace() *v1.Workspace {
+	return &v1.Workspace{
+		Name: "test",
+		Modules: v1.ModuleConfigs{
+			"mysql": &v1.ModuleConfig{
+				Path:    "kusionstack.io/mysql",
+				Version: "v1.0.0",
+				Configs: v1.Configs{
+					Default: v1.GenericConfig{
+						"type":         "aws",
+						"version":      "5.7",
+						"instanceType": "db.t3.micro",
+					},
+					ModulePatcherConfigs: v1.ModulePatcherConfigs{
+						"smallClass": {
+							GenericConfig: v1.GenericConfig{
+								"instanceType": "db.t3.small",
+							},
+							ProjectSelector: []string{"foo", "bar"},
+						},
+					},
+				},
+			},
+			"port": &v1.ModuleConfig{
+				Path:    "kusionstack.io/port",
+				Version: "v1.0.0",
+				Configs: v1.Configs{
+					Default: v1.GenericConfig{
+						"type": "aws",
+					},
+				},
+			},
+			"service": &v1.ModuleConfig{
+				Path:    "kusionstack.io/service",
+				Version: "v1.0.0",
+				Configs: v1.Configs{
+					Default: v1.GenericConfig{
+						"replica": 2,
+					},
+				},
+			},
+		},
+		Context: map[string]any{
+			"Kubernetes": map[string]string{
+				"Config": "/etc/kubeconfig.yaml",
+			},
+		},
+	}
+}
+
+func buildMockProjectAndStack() (*v1.Project, *v1.Stack) {
+	p := &v1.Project{
+		Name: "testproject",
+	}
+
+	s := &v1.Stack{
+		Name: "test",
+	}
+
+	return p, s
+}
+
+func mapToUnstructured(data map[string]interface{}) *unstructured.Unstructured {
+	unstructuredObj := &unstructured.Unstructured{}
+	unstructuredObj.SetUnstructuredContent(data)
+	return unstructuredObj
+}
+
+func getNamespace(actual *unstructured.Unstructured) (string, error) {
+	metadata, ok := actual.Object["metadata"].(map[interface{}]interface{})
+	if !ok {
+		return "", errors.New("failed to get metadata from unstructed object")
+	}
+	ns, ok := metadata["namespace"].(string)
+	if !ok {
+		return "", errors.New("failed to get namespace from metadata")
+	}
+
+	return ns, nil
+}
+
+func Test_patchWorkload(t *testing.T) {
+	replica := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-deployment",
+			Labels: map[string]string{
+				"oldLabel": "oldValue",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replica,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"oldLabel": "oldValue",
+					},
+					Annotations: map[string]string{
+						"oldAnnotation": "oldValue",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "my-app",
+							Image: "my-app-image",
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Host: "localhost:8080",
+									},
+								},
+								InitialDelaySeconds: 2,
+							},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "MY_ENV",
+									Value: "my-env-value",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// convert deploy to map with yamlv3 to simulate what we did in the module framework
+	deploymentUnstructured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	assert.NoError(t, err)
+	out, err := yaml.Marshal(deploymentUnstructured)
+	assert.NoError(t, err)
+	err = yaml.Unmarshal(out, deploymentUnstructured)
+
+	res := &v1.Resource{
+		ID:         "apps/v1:Deployment:default:default-dev-foo",
+		Type:       "Kubernetes",
+		Attributes: deploymentUnstructured,
+	}
+
+	t.Run("Patch labels and annotations", func(t *testing.T) {
+		patcher := &v1.Patcher{
+			Labels:         map[string]string{"newLabel": "newValue"},
+			Annotations:    map[string]string{"newAnnotation": "newValue"},
+			PodLabels:      map[string]string{"newPodLabel": "newValue"},
+			PodAnnotations: map[string]string{"newPodAnnotation": "newValue"},
+		}
+
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+
+		workloadLabels := res.Attributes["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+		podLabels := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+
+		// assert deployment workloadLabels
+		assert.Equal(t, "newValue", workloadLabels["newLabel"])
+		assert.Equal(t, "oldValue", workloadLabels["oldLabel"])
+		// assert pod labels
+		assert.Equal(t, "newValue", podLabels["newPodLabel"])
+		assert.Equal(t, "oldValue", podLabels["oldLabel"])
+
+		annotations := res.Attributes["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+		// get pod annotations
+		podAnnotations := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+		// assert deployment annotations
+		assert.Equal(t, "newValue", annotations["newAnnotation"])
+		// assert pod annotations
+		assert.Equal(t, "newValue", podAnnotations["newPodAnnotation"])
+		assert.Equal(t, "oldValue", podLabels["oldLabel"])
+	})
+
+	t.Run("Patch environment variables", func(t *testing.T) {
+		patcher := &v1.Patcher{
+			Environments: []corev1.EnvVar{
+				{
+					Name:  "MY_ENV",
+					Value: "ops://kusionstack.io/remove",
+				},
+				{
+					Name:  "NEW_ENV",
+					Value: "my-new-value",
+				},
+			},
+		}
+
+		err = PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+
+		containers := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+		env := containers[0].(map[string]interface{})["env"].([]interface{})
+		assert.Contains(t, env, map[string]interface{}{"name": "NEW_ENV", "value": "my-new-value"})
+		assert.NotContains(t, env, map[string]interface{}{"name": "MY_ENV", "value": "my-env-value"})
+	})
+}
+
+func TestAppConfigurationGenerator_CallModules(t *testing.T) {
+	// Mock dependencies
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Name:    "service",
+	})
+	dependencies := &pkg.Dependencies{
+		Deps: deps,
+	}
+
+	// Mock project module configs
+	projectModuleConfigs := map[string]v1.GenericConfig{
+		"port": {
+			"config1": "value1",
+		},
+	}
+
+	// Mock app appConfig generator
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      "testapp",
+		app:          appConfig,
+		ws:           buildMockWorkspace(),
+		dependencies: dependencies,
+	}
+
+	t.Run("Successful module call", func(t *testing.T) {
+		// Mock the plugin
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		wl, resources, patchers, err := g.callModules(context.Background(), projectModuleConfigs)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, wl)
+		assert.NotEmpty(t, resources)
+		assert.Empty(t, patchers)
+	})
+
+	t.Run("Failed module call due to missing module in dependencies", func(t *testing.T) {
+		// Mock the plugin
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			return nil, fmt.Errorf("module not found")
+		}).Build()
+		defer func() {
+			pluginMock.UnPatch()
+		}()
+
+		_, _, _, err := g.callModules(context.Background(), projectModuleConfigs)
+		assert.Error(t, err)
+	})
+
+	t.Run("Failed module call due to error in plugin", func(t *testing.T) {
+		// Mock the plugin
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(fmt.Errorf("error in plugin")).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+		_, _, _, err := g.callModules(context.Background(), projectModuleConfigs)
+		assert.Error(t, err)
+	})
+
+	t.Run("Workload marked explicitly", func(t *testing.T) {
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &markedWorkloadModule{marked: []string{"rollout"}}}, nil
+			}
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		wl, resources, _, err := g.callModules(context.Background(), projectModuleConfigs)
+		assert.NoError(t, err)
+		// the explicit mark takes precedence over the isWorkload extension
+		assert.Equal(t, "rollout", wl.ID)
+		assert.Equal(t, true, wl.Extensions[isWorkload])
+		assert.NotContains(t, wl.Extensions, v1.FieldWorkloadResource)
+		var ids []string
+		for _, res := range resources {
+			ids = append(ids, res.ID)
+		}
+		assert.Contains(t, ids, "deployment")
+		assert.Contains(t, ids, "service")
+	})
+
+	t.Run("Health policy matching the workload by kind", func(t *testing.T) {
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &kindedWorkloadModule{}}, nil
+			}
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		healthPolicy := v1.GenericConfig{"apiVersion": "apps/v1", "kind": "Deployment", "health.kcl": "assert res.status.readyReplicas > 0"}
+		configs := map[string]v1.GenericConfig{
+			"port":    projectModuleConfigs["port"],
+			"service": {v1.FieldHealthPolicy: healthPolicy},
+		}
+
+		wl, resources, _, err := g.callModules(context.Background(), configs)
+		assert.NoError(t, err)
+		assert.Equal(t, "apps/v1:Deployment:default:foo", wl.ID)
+		assert.Equal(t, map[string]any(healthPolicy), wl.Extensions[v1.FieldHealthPolicy])
+		for _, res := range resources {
+			if res.ID == "v1:Service:default:foo" {
+				assert.NotContains(t, res.Extensions, v1.FieldHealthPolicy)
+			}
+		}
+	})
+
+	t.Run("Health policies matching the resources by kind", func(t *testing.T) {
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &kindedWorkloadModule{}}, nil
+			}
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		deploymentPolicy := map[string]any{"apiVersion": "apps/v1", "kind": "Deployment", "health.kcl": "assert res.status.readyReplicas > 0"}
+		servicePolicy := map[string]any{"apiVersion": "v1", "kind": "Service", "health.kcl": "assert res.spec.clusterIP"}
+		configs := map[string]v1.GenericConfig{
+			"port":    projectModuleConfigs["port"],
+			"service": {v1.FieldHealthPolicy: []any{deploymentPolicy, servicePolicy}},
+		}
+
+		wl, resources, _, err := g.callModules(context.Background(), configs)
+		assert.NoError(t, err)
+		assert.Equal(t, "apps/v1:Deployment:default:foo", wl.ID)
+		assert.Equal(t, deploymentPolicy, wl.Extensions[v1.FieldHealthPolicy])
+		var service *v1.Resource
+		for i := range resources {
+			if resources[i].ID == "v1:Service:default:foo" {
+				service = &resources[i]
+			}
+		}
+		if assert.NotNil(t, service) {
+			assert.Equal(t, servicePolicy, service.Extensions[v1.FieldHealthPolicy])
+		}
+	})
+
+	t.Run("Workload marked more than once", func(t *testing.T) {
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &markedWorkloadModule{marked: []string{"deployment", "rollout"}}}, nil
+			}
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		_, _, _, err = g.callModules(context.Background(), projectModuleConfigs)
+		assert.ErrorContains(t, err, "marks more than one resource as the workload with kusion.io/workload: deployment and rollout")
+	})
+
+	t.Run("Invalid resource from module", func(t *testing.T) {
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+		var accessoryKey string
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &fakeModule{}}, nil
+			}
+			accessoryKey = key
+			return &module.Plugin{Module: &keyedModule{key: "no-attributes"}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		_, _, _, err = g.callModules(context.Background(), projectModuleConfigs)
+		assert.EqualError(t, err, fmt.Sprintf("invalid resource 0 of module %s: nil attributes of resource no-attributes", accessoryKey))
+	})
+
+	t.Run("Shared plugin cache across generators", func(t *testing.T) {
+		var started, killed atomic.Int32
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			started.Add(1)
+			return &module.Plugin{Module: &fakeModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).To(func(_ *module.Plugin) error {
+			killed.Add(1)
+			return nil
+		}).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		cache := NewPluginCache()
+		g1, g2 := *g, *g
+		g1.pluginCache, g2.pluginCache = cache, cache
+		g2.appName = "testapp2"
+
+		_, _, _, err := g1.callModules(context.Background(), projectModuleConfigs)
+		assert.NoError(t, err)
+		n := started.Load()
+		assert.NotZero(t, n)
+		_, _, _, err = g2.callModules(context.Background(), projectModuleConfigs)
+		assert.NoError(t, err)
+
+		// the modules are started once and not killed until the cache is closed
+		assert.Equal(t, n, started.Load())
+		assert.Zero(t, killed.Load())
+		assert.NoError(t, cache.Close())
+		assert.Equal(t, n, killed.Load())
+		assert.NoError(t, cache.Close())
+		assert.Equal(t, n, killed.Load())
+	})
+}
+
+// markedWorkloadModule generates several workload-like resources, and marks the ones in marked as the
+// workload explicitly. The deployment is also marked with the isWorkload extension.
+type markedWorkloadModule struct {
+	marked []string
+}
+
+func (m *markedWorkloadModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	response := &proto.GeneratorResponse{}
+	for _, id := range []string{"deployment", "rollout", "service"} {
+		res := v1.Resource{
+			ID:         id,
+			Type:       v1.Kubernetes,
+			Attributes: map[string]interface{}{},
+			Extensions: map[string]interface{}{},
+		}
+		if id == "deployment" {
+			res.Extensions[isWorkload] = "true"
+		}
+		if slices.Contains(m.marked, id) {
+			res.Extensions[v1.FieldWorkloadResource] = true
+		}
+		response.Resources = append(response.Resources, []byte(jsonutil.Marshal2String(res)))
+	}
+	return response, nil
+}
+
+// kindedWorkloadModule generates a Deployment marked as the workload with the isWorkload extension along
+// with a Service.
+type kindedWorkloadModule struct{}
+
+func (m *kindedWorkloadModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	response := &proto.GeneratorResponse{}
+	for _, res := range []v1.Resource{
+		{
+			ID:         "apps/v1:Deployment:default:foo",
+			Type:       v1.Kubernetes,
+			Attributes: map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"},
+			Extensions: map[string]interface{}{isWorkload: "true"},
+		},
+		{
+			ID:         "v1:Service:default:foo",
+			Type:       v1.Kubernetes,
+			Attributes: map[string]interface{}{"apiVersion": "v1", "kind": "Service"},
+		},
+	} {
+		response.Resources = append(response.Resources, []byte(jsonutil.Marshal2String(res)))
+	}
+	return response, nil
+}
+
+func TestParseHealthPolicies(t *testing.T) {
+	t.Run("Map", func(t *testing.T) {
+		policies, err := parseHealthPolicies("service", map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, v1.GenericConfig{"apiVersion": "apps/v1", "kind": "Deployment"}, policies.workload)
+		assert.Equal(t, []v1.GenericConfig{{"apiVersion": "apps/v1", "kind": "Deployment"}}, policies.matchers)
+
+		// the health policy of the workload module may target the workload only
+		policies, err = parseHealthPolicies("service", v1.GenericConfig{"health.kcl": "assert res.status.readyReplicas > 0"}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, v1.GenericConfig{"health.kcl": "assert res.status.readyReplicas > 0"}, policies.workload)
+		assert.Empty(t, policies.matchers)
+
+		policies, err = parseHealthPolicies("service", nil, true)
+		assert.NoError(t, err)
+		assert.Nil(t, policies.workload)
+		assert.Empty(t, policies.matchers)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		policies, err := parseHealthPolicies("service", []interface{}{
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"},
+			v1.GenericConfig{"apiVersion": "v1", "kind": "Service"},
+		}, true)
+		assert.NoError(t, err)
+		assert.Nil(t, policies.workload)
+		assert.Equal(t, []v1.GenericConfig{
+			{"apiVersion": "apps/v1", "kind": "Deployment"},
+			{"apiVersion": "v1", "kind": "Service"},
+		}, policies.matchers)
+
+		_, err = parseHealthPolicies("service", []interface{}{
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"},
+			map[string]interface{}{"health.kcl": "assert true"},
+		}, true)
+		assert.EqualError(t, err, "healthPolicy[1] of module service must set the apiVersion and kind to match")
+
+		_, err = parseHealthPolicies("service", []interface{}{"apps/v1:Deployment"}, true)
+		assert.EqualError(t, err, "healthPolicy[0] of module service must be a map, got string")
+	})
+
+	t.Run("NotMap", func(t *testing.T) {
+		_, err := parseHealthPolicies("service", "apps/v1:Deployment", false)
+		assert.EqualError(t, err, "healthPolicy of module service must be a map, got string")
+	})
+
+	t.Run("MissingField", func(t *testing.T) {
+		_, err := parseHealthPolicies("service", v1.GenericConfig{"kind": "Deployment"}, false)
+		assert.EqualError(t, err, "healthPolicy of module service targeting kind Deployment must set the apiVersion")
+
+		_, err = parseHealthPolicies("service", v1.GenericConfig{"apiVersion": "apps/v1"}, false)
+		assert.EqualError(t, err, "healthPolicy of module service targeting apiVersion apps/v1 must set the kind")
+
+		_, err = parseHealthPolicies("service", v1.GenericConfig{"apiVersion": "apps/v1", "kind": 1}, false)
+		assert.EqualError(t, err, "kind of healthPolicy of module service must be a string, got int")
+	})
+}
+
+func TestExplicitWorkload(t *testing.T) {
+	resources := []*v1.Resource{
+		{ID: "a"},
+		{ID: "b", Extensions: map[string]interface{}{v1.FieldWorkloadResource: "true"}},
+		{ID: "c", Extensions: map[string]interface{}{v1.FieldWorkloadResource: false}},
+	}
+	marked, err := explicitWorkload("workload", true, resources)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	marked, err = explicitWorkload("workload", true, resources[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, -1, marked)
+
+	_, err = explicitWorkload("accessory", false, resources)
+	assert.EqualError(t, err, "module accessory is not the workload module but marks resource b as the workload with kusion.io/workload")
+}
+
+// keyedModule generates a resource with the ID of the module key.
+type keyedModule struct {
+	key string
+}
+
+func (m *keyedModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	res := v1.Resource{ID: m.key, Type: v1.Kubernetes}
+	return &proto.GeneratorResponse{Resources: [][]byte{[]byte(jsonutil.Marshal2String(res))}}, nil
+}
+
+type failingModule struct{}
+
+func (m *failingModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	return nil, errors.New("module failed")
+}
+
+// blockingModule blocks until the invocation is canceled.
+type blockingModule struct{}
+
+func (m *blockingModule) Generate(ctx context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Second):
+		return nil, errors.New("not canceled")
+	}
+}
+
+// flakyModule fails with the code for the first failures invocations, and succeeds afterwards.
+type flakyModule struct {
+	code     codes.Code
+	failures int
+	calls    int
+}
+
+func (m *flakyModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, status.Errorf(m.code, "attempt %d failed", m.calls)
+	}
+	res := v1.Resource{ID: "flaky", Type: v1.Kubernetes}
+	return &proto.GeneratorResponse{Resources: [][]byte{[]byte(jsonutil.Marshal2String(res))}}, nil
+}
+
+func TestAppConfigurationGenerator_InvokeModules(t *testing.T) {
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project: project,
+		stack:   stack,
+		appName: "testapp",
+		app:     appConfig,
+		ws:      buildMockWorkspace(),
+	}
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		switch key {
+		case "failing":
+			return &module.Plugin{Module: &failingModule{}}, nil
+		case "blocking":
+			return &module.Plugin{Module: &blockingModule{}}, nil
+		default:
+			return &module.Plugin{Module: &keyedModule{key: key}}, nil
+		}
+	}).Build()
+	defer pluginMock.UnPatch()
+
+	t.Run("responses in order", func(t *testing.T) {
+		keys := make([]string, 20)
+		configs := make(map[string]moduleConfig)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("module-%02d", i)
+			configs[keys[i]] = moduleConfig{}
+		}
+		plugins := NewPluginCache()
+		responses, err := g.invokeModules(context.Background(), plugins, keys, configs)
+		assert.NoError(t, err)
+		assert.Len(t, plugins.plugins, len(keys))
+		for i, response := range responses {
+			res := &v1.Resource{}
+			assert.NoError(t, yaml.Unmarshal(response.Resources[0], res))
+			assert.Equal(t, keys[i], res.ID)
+		}
+	})
+
+	t.Run("failure cancels the rest", func(t *testing.T) {
+		keys := []string{"blocking", "failing"}
+		configs := map[string]moduleConfig{"blocking": {}, "failing": {}}
+		plugins := NewPluginCache()
+		start := time.Now()
+		_, err := g.invokeModules(context.Background(), plugins, keys, configs)
+		assert.ErrorContains(t, err, "invoke kusion module: failing failed. module failed")
+		assert.Less(t, time.Since(start), 10*time.Second)
+		// the started plugins are recorded to be killed, even the failed one
+		assert.Contains(t, plugins.plugins, "failing")
+	})
+
+	t.Run("module timeout", func(t *testing.T) {
+		configs := map[string]moduleConfig{
+			"blocking": {platformConfig: v1.GenericConfig{v1.FieldModuleTimeout: "100ms"}},
+		}
+		plugins := NewPluginCache()
+		_, err := g.invokeModules(context.Background(), plugins, []string{"blocking"}, configs)
+		assert.ErrorContains(t, err, "invoke kusion module: blocking timed out after 100ms")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("cancelled by the context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := g.invokeModules(ctx, NewPluginCache(), []string{"blocking", "module-00"},
+			map[string]moduleConfig{"blocking": {}, "module-00": {}})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+func TestPluginCache_GetOrStart(t *testing.T) {
+	var killed atomic.Int32
+	killMock := mockey.Mock((*module.Plugin).KillPluginClient).To(func(_ *module.Plugin) error {
+		killed.Add(1)
+		return nil
+	}).Build()
+	defer killMock.UnPatch()
+
+	var started atomic.Int32
+	start := func() (*module.Plugin, error) {
+		started.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return &module.Plugin{Module: &keyedModule{key: "module"}}, nil
+	}
+
+	cache := NewPluginCache()
+	plugins := make([]*module.Plugin, 10)
+	var wg sync.WaitGroup
+	for i := range plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			plugins[i], err = cache.getOrStart("module", start)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	// the concurrent invocations share the only plugin started
+	assert.Equal(t, int32(1), started.Load())
+	for _, plugin := range plugins {
+		assert.Same(t, plugins[0], plugin)
+	}
+
+	// the plugin started after the cache is closed is refused and killed
+	assert.NoError(t, cache.Close())
+	assert.Equal(t, int32(1), killed.Load())
+	_, err := cache.getOrStart("other", start)
+	assert.ErrorIs(t, err, errPluginCacheClosed)
+	assert.Equal(t, int32(2), killed.Load())
+	assert.Empty(t, cache.plugins)
+}
+
+func TestAppConfigurationGenerator_GenerateWithContext(t *testing.T) {
+	appName, app := buildMockApp()
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{Name: "port", Version: "1.0.0"})
+	deps.Set("service", pkg.Dependency{Name: "service", Version: "1.0.0"})
+	project, stack := buildMockProjectAndStack()
+	newGenerator := func() *appConfigurationGenerator {
+		return &appConfigurationGenerator{
+			project:      project,
+			stack:        stack,
+			appName:      appName,
+			app:          app,
+			ws:           buildMockWorkspace(),
+			dependencies: &pkg.Dependencies{Deps: deps},
+		}
+	}
+
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		return &module.Plugin{Module: &blockingModule{}}, nil
+	}).Build()
+	killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+	defer func() {
+		pluginMock.UnPatch()
+		killMock.UnPatch()
+	}()
+
+	t.Run("cancelled before generating", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		spec := &v1.Spec{}
+		err := newGenerator().GenerateWithContext(ctx, spec)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, spec.Resources)
+	})
+
+	t.Run("cancelled while invoking the modules", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		err := newGenerator().GenerateWithContext(ctx, &v1.Spec{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+func TestAppConfigurationGenerator_InvokeModule_Retry(t *testing.T) {
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project: project,
+		stack:   stack,
+		appName: "testapp",
+		app:     appConfig,
+		ws:      buildMockWorkspace(),
+	}
+	backoff := defaultModuleRetryBackoff
+	defaultModuleRetryBackoff = time.Millisecond
+	defer func() {
+		defaultModuleRetryBackoff = backoff
+	}()
+
+	testcases := []struct {
+		name          string
+		module        *flakyModule
+		startFailures int
+		config        v1.GenericConfig
+		expectedErr   string
+		calls         int
+		starts        int
+	}{
+		{
+			name:   "succeed after transient failures",
+			module: &flakyModule{code: codes.Unavailable, failures: 2},
+			calls:  3,
+			// the unavailable plugin is restarted
+			starts: 3,
+		},
+		{
+			name:          "succeed after plugin start failures",
+			module:        &flakyModule{},
+			startFailures: 2,
+			calls:         1,
+			starts:        3,
+		},
+		{
+			name:        "attempts exhausted",
+			module:      &flakyModule{code: codes.Unavailable, failures: 3},
+			config:      v1.GenericConfig{v1.FieldModuleMaxAttempts: 2},
+			expectedErr: "invoke kusion module: flaky failed in 2 attempts. rpc error: code = Unavailable desc = attempt 2 failed",
+			calls:       2,
+			starts:      2,
+		},
+		{
+			name:        "no retry on deadline exceeded",
+			module:      &flakyModule{code: codes.DeadlineExceeded, failures: 1},
+			expectedErr: "invoke kusion module: flaky failed. rpc error: code = DeadlineExceeded desc = attempt 1 failed",
+			calls:       1,
+			starts:      1,
+		},
+		{
+			name:        "no retry on invalid argument",
+			module:      &flakyModule{code: codes.InvalidArgument, failures: 1},
+			expectedErr: "invoke kusion module: flaky failed. rpc error: code = InvalidArgument desc = attempt 1 failed",
+			calls:       1,
+			starts:      1,
+		},
+		{
+			name:        "invalid max attempts",
+			module:      &flakyModule{},
+			config:      v1.GenericConfig{v1.FieldModuleMaxAttempts: 0},
+			expectedErr: "invalid retry policy of module flaky: moduleMaxAttempts must be positive, got 0",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			starts := 0
+			pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+				starts++
+				if starts <= tc.startFailures {
+					return nil, &pluginStartError{err: errors.New("plugin exited before the handshake")}
+				}
+				return &module.Plugin{Module: tc.module}, nil
+			}).Build()
+			killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+			defer func() {
+				pluginMock.UnPatch()
+				killMock.UnPatch()
+			}()
+
+			response, err := g.invokeModule(context.Background(), NewPluginCache(), "flaky",
+				moduleConfig{platformConfig: tc.config})
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, response.Resources, 1)
+			}
+			assert.Equal(t, tc.calls, tc.module.calls)
+			assert.Equal(t, tc.starts, starts)
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy, err := retryPolicy(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, moduleRetryPolicy{maxAttempts: defaultModuleMaxAttempts, backoff: defaultModuleRetryBackoff}, policy)
+
+	policy, err = retryPolicy(v1.GenericConfig{v1.FieldModuleMaxAttempts: 5, v1.FieldModuleRetryBackoff: "2s"})
+	assert.NoError(t, err)
+	assert.Equal(t, moduleRetryPolicy{maxAttempts: 5, backoff: 2 * time.Second}, policy)
+
+	_, err = retryPolicy(v1.GenericConfig{v1.FieldModuleMaxAttempts: "5"})
+	assert.Error(t, err)
+
+	_, err = retryPolicy(v1.GenericConfig{v1.FieldModuleRetryBackoff: "-1s"})
+	assert.ErrorContains(t, err, "moduleRetryBackoff must not be negative")
+}
+
+func TestModuleTimeout(t *testing.T) {
+	timeout, err := moduleTimeout(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultModuleTimeout, timeout)
+
+	timeout, err = moduleTimeout(v1.GenericConfig{v1.FieldModuleTimeout: "2m"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, timeout)
+
+	_, err = moduleTimeout(v1.GenericConfig{v1.FieldModuleTimeout: "-1s"})
+	assert.ErrorContains(t, err, "timeout must be positive")
+
+	_, err = moduleTimeout(v1.GenericConfig{v1.FieldModuleTimeout: 60})
+	assert.Error(t, err)
+}
+
+func TestAppConfigurationGenerator_BuildModuleConfigIndex_WorkloadOnly(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Name:    "service",
+	})
+
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      "testapp",
+		app:          appConfig,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	index, err := g.buildModuleConfigIndex(nil)
+	assert.NoError(t, err)
+	assert.Len(t, index, 2)
+
+	g.workloadOnly = true
+	index, err = g.buildModuleConfigIndex(nil)
+	assert.NoError(t, err)
+	assert.Len(t, index, 1)
+	for _, config := range index {
+		assert.Equal(t, appConfig.Workload, config.devConfig)
+	}
+}
+
+func TestAppConfigurationGenerator_BuildModuleConfigIndex_DuplicateKey(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Name:    "service",
+	})
+
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      "testapp",
+		app:          appConfig,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	t.Run("identical configs", func(t *testing.T) {
+		appConfig.Accessories = map[string]v1.Accessory{
+			"port":  {"port": "2333", "_type": "port.Port"},
+			"port2": {"port": "2333", "_type": "port.Port"},
+		}
+		index, err := g.buildModuleConfigIndex(nil)
+		assert.NoError(t, err)
+		assert.Len(t, index, 2)
+	})
+
+	t.Run("different configs", func(t *testing.T) {
+		appConfig.Accessories = map[string]v1.Accessory{
+			"port":  {"port": "2333", "_type": "port.Port"},
+			"port2": {"port": "8080", "_type": "port.Port"},
+		}
+		_, err := g.buildModuleConfigIndex(nil)
+		assert.EqualError(t, err, "accessories port and port2 of app testapp resolve to the same module "+
+			"kusionstack/module1@1.0.0 with different configs")
+	})
+}
+
+func TestAppConfigurationGenerator_ValidateWorkloadModule(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Name:    "service",
+	})
+
+	testcases := []struct {
+		name         string
+		workload     v1.Accessory
+		dependencies *pkg.Dependencies
+		errContains  string
+	}{
+		{
+			name:         "no workload",
+			workload:     nil,
+			dependencies: &pkg.Dependencies{Deps: deps},
+		},
+		{
+			name:         "workload module resolved",
+			workload:     v1.Accessory{"_type": "service.Service"},
+			dependencies: &pkg.Dependencies{Deps: deps},
+		},
+		{
+			name:         "workload without type",
+			workload:     v1.Accessory{"replicas": 2},
+			dependencies: &pkg.Dependencies{Deps: deps},
+			errContains:  "can not find '_type' in the workload of app testapp",
+		},
+		{
+			name:         "workload module not in dependencies",
+			workload:     v1.Accessory{"_type": "job.Job"},
+			dependencies: &pkg.Dependencies{Deps: deps},
+			errContains:  "workload type job.Job of app testapp refers to module job",
+		},
+		{
+			name:         "nil dependencies",
+			workload:     v1.Accessory{"_type": "service.Service"},
+			dependencies: nil,
+			errContains:  "no dependencies are declared",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &appConfigurationGenerator{
+				appName:      "testapp",
+				app:          &v1.AppConfiguration{Workload: tc.workload},
+				dependencies: tc.dependencies,
+			}
+			err := g.validateWorkloadModule()
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
+		})
+	}
+
+	t.Run("named workload module not in dependencies", func(t *testing.T) {
+		g := &appConfigurationGenerator{
+			appName: "testapp",
+			app: &v1.AppConfiguration{
+				Workload:  v1.Accessory{"_type": "service.Service"},
+				Workloads: map[string]v1.Accessory{"worker": {"_type": "job.Job"}},
+			},
+			dependencies: &pkg.Dependencies{Deps: deps},
+		}
+		err := g.validateWorkloadModule()
+		assert.ErrorContains(t, err, "workload worker type job.Job of app testapp refers to module job")
+	})
+}
+
+func TestAppConfigurationGenerator_BuildModuleConfigIndex_EnabledWhen(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Version: "1.0.0",
+		Name:    "service",
+	})
+
+	testcases := []struct {
+		name      string
+		condition interface{}
+		enabled   bool
+	}{
+		{
+			name:      "condition satisfied",
+			condition: map[interface{}]interface{}{"metadata.env": "prod"},
+			enabled:   true,
+		},
+		{
+			name:      "condition not satisfied",
+			condition: map[interface{}]interface{}{"metadata.env": "dev"},
+			enabled:   false,
+		},
+		{
+			name:      "context path not found",
+			condition: map[string]interface{}{"metadata.region": "us-east-1"},
+			enabled:   false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, appConfig := buildMockApp()
+			appConfig.Accessories["port"][enabledWhen] = tc.condition
+			project, stack := buildMockProjectAndStack()
+			ws := buildMockWorkspace()
+			ws.Context = v1.GenericConfig{
+				"metadata": map[string]interface{}{"env": "prod"},
+			}
+			g := &appConfigurationGenerator{
+				project:      project,
+				stack:        stack,
+				appName:      "testapp",
+				app:          appConfig,
+				ws:           ws,
+				dependencies: &pkg.Dependencies{Deps: deps},
+			}
+
+			index, err := g.buildModuleConfigIndex(nil)
+			assert.NoError(t, err)
+			config, ok := index["kusionstack/module1@1.0.0"]
+			assert.Equal(t, tc.enabled, ok)
+			if ok {
+				assert.NotContains(t, config.devConfig, enabledWhen)
+			}
+		})
+	}
+
+	t.Run("invalid condition", func(t *testing.T) {
+		_, appConfig := buildMockApp()
+		appConfig.Accessories["port"][enabledWhen] = "prod"
+		g := &appConfigurationGenerator{
+			appName:      "testapp",
+			app:          appConfig,
+			ws:           buildMockWorkspace(),
+			dependencies: &pkg.Dependencies{Deps: deps},
+		}
+		_, err := g.buildModuleConfigIndex(nil)
+		assert.ErrorContains(t, err, "invalid enablement condition of accessory port")
+	})
+}
+
+func TestParseModuleKey_VersionOverride(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("mysql", pkg.Dependency{
+		Version: "0.1.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/mysql",
+			},
+		},
+	})
+	dependencies := &pkg.Dependencies{Deps: deps}
+	accessory := v1.Accessory{"_type": "mysql.MySQL"}
+
+	kusionHome := t.TempDir()
+	t.Setenv(kfile.EnvKusionHome, kusionHome)
+	err := os.MkdirAll(filepath.Join(kusionHome, "modules", "kusionstack/mysql", "0.2.0", goruntime.GOOS, goruntime.GOARCH), os.ModePerm)
+	assert.NoError(t, err)
+
+	key, err := parseModuleKey(accessory, dependencies, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "kusionstack/mysql@0.1.0", key)
+
+	key, err = parseModuleKey(accessory, dependencies, map[string]string{"mysql": "0.2.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "kusionstack/mysql@0.2.0", key)
+
+	_, err = parseModuleKey(accessory, dependencies, map[string]string{"mysql": "0.3.0"})
+	assert.ErrorContains(t, err, "invalid version override 0.3.0 of module mysql")
+}
+
+func TestParseModuleKey_Local(t *testing.T) {
+	newDependencies := func(dep pkg.Dependency) *pkg.Dependencies {
+		deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+		deps.Set("mysql", dep)
+		return &pkg.Dependencies{Deps: deps}
+	}
+	accessory := v1.Accessory{"_type": "mysql.MySQL"}
+	localDep := func(path, fullPath string) pkg.Dependency {
+		return pkg.Dependency{
+			Name:          "mysql",
+			LocalFullPath: fullPath,
+			Source:        downloader.Source{Local: &downloader.Local{Path: path}},
+		}
+	}
+
+	key, err := parseModuleKey(accessory, newDependencies(localDep("../mysql", "/home/dev/mysql")), nil)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^local/mysql@0\.0\.0-local\.[0-9a-f]{8}$`, key)
+
+	// the key is stable for the same resolved path, and the version is not overridden
+	sameKey, err := parseModuleKey(accessory, newDependencies(localDep("./mysql", "/home/dev/mysql/")),
+		map[string]string{"mysql": "0.2.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, key, sameKey)
+
+	// the key differs between the paths
+	otherKey, err := parseModuleKey(accessory, newDependencies(localDep("../mysql", "/home/dev/mysql-fork")), nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	// the path is taken as is if not resolved
+	unresolvedKey, err := parseModuleKey(accessory, newDependencies(localDep("/home/dev/mysql", "")), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, key, unresolvedKey)
+}
+
+func TestGetProjectImportedResources(t *testing.T) {
+	dir := t.TempDir()
+	csvContent := `# kusion id, imported id
+hashicorp:aws:aws_s3_bucket:foo, foo-bucket
+hashicorp:aws:aws_s3_bucket:bar, bar-bucket
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "imported.csv"), []byte(csvContent), 0o600))
+	yamlContent := "hashicorp:aws:aws_s3_bucket:baz: baz-bucket\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "imported.yaml"), []byte(yamlContent), 0o600))
+
+	g := &appConfigurationGenerator{stack: &v1.Stack{Path: dir}}
+
+	t.Run("merge inline and files", func(t *testing.T) {
+		importedResources, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResources: map[string]any{
+					"hashicorp:aws:aws_s3_bucket:foo": "foo-bucket",
+				},
+				v1.FieldImportedResourcesFile: "imported.csv",
+			},
+			"other": {
+				v1.FieldImportedResourcesFile: "imported.yaml",
+			},
+		}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:foo": "foo-bucket",
+			"hashicorp:aws:aws_s3_bucket:bar": "bar-bucket",
+			"hashicorp:aws:aws_s3_bucket:baz": "baz-bucket",
+		}, importedResources)
+	})
+
+	t.Run("conflict between inline and file", func(t *testing.T) {
+		_, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResources: map[string]any{
+					"hashicorp:aws:aws_s3_bucket:foo": "another-bucket",
+				},
+				v1.FieldImportedResourcesFile: "imported.csv",
+			},
+		}, true)
+		assert.ErrorContains(t, err, "duplicate kusion id 'hashicorp:aws:aws_s3_bucket:foo' for importing different resources: "+
+			"'another-bucket' in module config bucket and 'foo-bucket' in file imported.csv")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResourcesFile: "missing.csv",
+			},
+		}, true)
+		assert.ErrorContains(t, err, "failed to load imported resources file of module bucket")
+	})
+
+	t.Run("file outside the stack directory", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "secret.yaml")
+		assert.NoError(t, os.WriteFile(outside, []byte("password: foo\n"), 0o600))
+		assert.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.yaml")))
+		for _, path := range []string{outside, "../secret.yaml", "link.yaml"} {
+			_, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+				"bucket": {
+					v1.FieldImportedResourcesFile: path,
+				},
+			}, true)
+			assert.ErrorContains(t, err, "should be a relative path inside the stack directory")
+			assert.NotContains(t, err.Error(), "password")
+		}
+	})
+
+	t.Run("invalid file content not reported", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte("- password: foo\n"), 0o600))
+		_, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResourcesFile: "invalid.yaml",
+			},
+		}, true)
+		assert.ErrorContains(t, err, "invalid imported resources invalid.yaml")
+		assert.NotContains(t, err.Error(), "password")
+	})
+
+	t.Run("backend object", func(t *testing.T) {
+		g := &appConfigurationGenerator{
+			stack: &v1.Stack{Path: dir},
+			objects: fakeObjectReader{
+				"imports/buckets.csv": []byte("hashicorp:aws:aws_s3_bucket:qux, qux-bucket\n"),
+			},
+		}
+		importedResources, err := g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResourcesObject: "imports/buckets.csv",
+			},
+		}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"hashicorp:aws:aws_s3_bucket:qux": "qux-bucket"}, importedResources)
+
+		_, err = g.getProjectImportedResources(map[string]v1.GenericConfig{
+			"bucket": {
+				v1.FieldImportedResourcesObject: "imports/missing.csv",
+			},
+		}, true)
+		assert.ErrorContains(t, err, "failed to load imported resources object of module bucket")
+	})
+}
+
+// fakeObjectReader reads the objects from the map keyed by the object keys.
+type fakeObjectReader map[string][]byte
+
+func (r fakeObjectReader) ReadObject(key string) ([]byte, error) {
+	content, ok := r[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s does not exist", key)
+	}
+	return content, nil
+}
+
+func TestCheckImportedResourcesAcrossProjects(t *testing.T) {
+	modules := v1.ModuleConfigs{
+		"bucket": {
+			Configs: v1.Configs{
+				Default: v1.GenericConfig{
+					v1.FieldImportedResources: map[string]any{
+						"hashicorp:aws:aws_s3_bucket:shared": "shared-bucket",
+					},
+				},
+				ModulePatcherConfigs: v1.ModulePatcherConfigs{
+					"other": {
+						GenericConfig: v1.GenericConfig{
+							v1.FieldImportedResources: map[string]any{
+								"hashicorp:aws:aws_s3_bucket:bar": "foo-bucket",
+							},
+						},
+						ProjectSelector: []string{"other"},
+					},
+				},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name              string
+		importedResources map[string]string
+		errContains       string
+	}{
+		{
+			name: "same kusion id in different projects",
+			importedResources: map[string]string{
+				"hashicorp:aws:aws_s3_bucket:shared": "shared-bucket",
+			},
+		},
+		{
+			name: "different kusion ids in different projects",
+			importedResources: map[string]string{
+				"hashicorp:aws:aws_s3_bucket:foo": "foo-bucket",
+			},
+			errContains: "resource 'foo-bucket' is imported under different kusion ids: " +
+				"'hashicorp:aws:aws_s3_bucket:foo' in project foo and 'hashicorp:aws:aws_s3_bucket:bar' in project other",
+		},
+		{
+			name: "different kusion ids in the same project",
+			importedResources: map[string]string{
+				"hashicorp:aws:aws_s3_bucket:a": "baz-bucket",
+				"hashicorp:aws:aws_s3_bucket:b": "baz-bucket",
+			},
+			errContains: "resource 'baz-bucket' is imported under different kusion ids",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &appConfigurationGenerator{
+				project: &v1.Project{Name: "foo"},
+				ws:      &v1.Workspace{Modules: modules},
+			}
+			err := g.checkImportedResourcesAcrossProjects(tc.importedResources)
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
+		})
+	}
+}
+
+func importedResources() v1.Resources {
+	return v1.Resources{
+		{
+			ID:   "hashicorp:aws:aws_s3_bucket:foo",
+			Type: v1.Terraform,
+			Attributes: map[string]interface{}{
+				"bucket": "foo",
+				"region": "us-east-1",
+				"tags":   map[string]interface{}{"team": "infra"},
+			},
+			Extensions: map[string]interface{}{},
+		},
+		{
+			ID:         "hashicorp:aws:aws_s3_bucket:bar",
+			Type:       v1.Terraform,
+			Attributes: map[string]interface{}{"bucket": "bar", "region": "us-east-1"},
+		},
+	}
+}
+
+func TestPatchImportedResources(t *testing.T) {
+	resourceModules := map[string]string{
+		"hashicorp:aws:aws_s3_bucket:foo": "s3",
+		"hashicorp:aws:aws_s3_bucket:bar": "s3",
+	}
+	t.Run("KeepAttributes", func(t *testing.T) {
+		resources := importedResources()
+		unmatched, err := patchImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:foo": "foo",
+		}, map[string][]string{"s3": {"region", "zone"}}, resourceModules)
+		assert.NoError(t, err)
+		assert.Empty(t, unmatched)
+		assert.Equal(t, map[string]interface{}{"region": "us-east-1"}, resources[0].Attributes)
+		assert.Equal(t, "foo", resources[0].Extensions[tfops.ImportIDKey])
+		// the resources not imported are kept as they are
+		assert.Equal(t, importedResources()[1], resources[1])
+	})
+
+	t.Run("ClearAttributes", func(t *testing.T) {
+		resources := importedResources()
+		_, err := patchImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:bar": "bar",
+		}, nil, resourceModules)
+		assert.NoError(t, err)
+		assert.Empty(t, resources[1].Attributes)
+		assert.Equal(t, "bar", resources[1].Extensions[tfops.ImportIDKey])
+	})
+
+	t.Run("KeepAttributesOfOtherModules", func(t *testing.T) {
+		resources := importedResources()
+		_, err := patchImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:foo": "foo",
+		}, map[string][]string{"rds": {"region"}}, resourceModules)
+		assert.NoError(t, err)
+		// the attributes kept by the other modules are not kept for the resource of the s3 module
+		assert.Empty(t, resources[0].Attributes)
+	})
+
+	t.Run("UnmatchedIDs", func(t *testing.T) {
+		resources := importedResources()
+		unmatched, err := patchImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:foo":  "foo",
+			"hashicorp:aws:aws_s3_bucket:fooo": "fooo",
+		}, nil, resourceModules)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hashicorp:aws:aws_s3_bucket:fooo"}, unmatched)
+		assert.Equal(t, "foo", resources[0].Extensions[tfops.ImportIDKey])
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		resources := importedResources()
+		unmatched, err := patchImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:*": "acme-*",
+		}, map[string][]string{"s3": {"region"}}, resourceModules)
+		assert.NoError(t, err)
+		assert.Empty(t, unmatched)
+		assert.Equal(t, "acme-foo", resources[0].Extensions[tfops.ImportIDKey])
+		assert.Equal(t, "acme-bar", resources[1].Extensions[tfops.ImportIDKey])
+		assert.Equal(t, map[string]interface{}{"region": "us-east-1"}, resources[1].Attributes)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		_, err := patchImportedResources(importedResources(), map[string]string{
+			"hashicorp:aws:aws_s3_bucket:foo": "arn:aws:s3",
+		}, nil, resourceModules)
+		assert.ErrorContains(t, err, `invalid imported ID "arn:aws:s3" of resource hashicorp:aws:aws_s3_bucket:foo: malformed ARN`)
+	})
+}
+
+func TestGetImportedResourcesKeepAttributes(t *testing.T) {
+	keepAttributes, err := getImportedResourcesKeepAttributes(map[string]v1.GenericConfig{
+		"s3":  {v1.FieldImportedResourcesKeepAttributes: []any{"region"}},
+		"rds": {v1.FieldImportedResourcesKeepAttributes: []any{"region", "identifier"}},
+		"sqs": {},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"s3":  {"region"},
+		"rds": {"region", "identifier"},
+	}, keepAttributes)
+
+	_, err = getImportedResourcesKeepAttributes(map[string]v1.GenericConfig{
+		"s3": {v1.FieldImportedResourcesKeepAttributes: "region"},
+	})
+	assert.EqualError(t, err, "invalid module config s3: the value of importedResourcesKeepAttributes is not list")
+}
+
+func TestJsonPatch(t *testing.T) {
+	t.Run("ResourcesNil", func(t *testing.T) {
+		err := JSONPatch(nil, &v1.Patcher{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("PatcherNil", func(t *testing.T) {
+		err := JSONPatch([]v1.Resource{{ID: "test"}}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("JsonPatchersNil", func(t *testing.T) {
+		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ResourceNotFound", func(t *testing.T) {
+		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"notfound": {Type: v1.MergePatch, Payload: []byte(`{"key": "value"}`)},
+			},
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("MergePatch", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", resources[0].Attributes["key"])
+	})
+
+	t.Run("JSONPatch", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "replace", "path": "/key", "value": "new"}]`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", resources[0].Attributes["key"])
+	})
+
+	t.Run("JSONPatchLenientByDefault", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[
+					{"op": "remove", "path": "/missing"},
+					{"op": "add", "path": "/parent/child", "value": "new"}
+				]`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"child": "new"}, resources[0].Attributes["parent"])
+	})
+
+	t.Run("JSONPatchStrictRemove", func(t *testing.T) {
+		strict := false
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {
+					Type:                     v1.JSONPatch,
+					Payload:                  []byte(`[{"op": "remove", "path": "/missing"}]`),
+					AllowMissingPathOnRemove: &strict,
+				},
+			},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("JSONPatchStrictAdd", func(t *testing.T) {
+		strict := false
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {
+					Type:                  v1.JSONPatch,
+					Payload:               []byte(`[{"op": "add", "path": "/parent/child", "value": "new"}]`),
+					EnsurePathExistsOnAdd: &strict,
+				},
+			},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("ConflictingPatchers", func(t *testing.T) {
+		patchers := []v1.Patcher{
+			{
+				JSONPatchers: map[string]v1.JSONPatcher{
+					"a":    {Type: v1.MergePatch, Payload: []byte(`{"key": "a"}`)},
+					"test": {Type: v1.MergePatch, Payload: []byte(`{"key": "first"}`)},
+				},
+			},
+			{
+				JSONPatchers: map[string]v1.JSONPatcher{
+					"test": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "replace", "path": "/key", "value": "second"}]`)},
+					"z":    {Type: v1.MergePatch, Payload: []byte(`{"key": "z"}`)},
+				},
+			},
+		}
+		for i := 0; i < 10; i++ {
+			resources := []v1.Resource{
+				{ID: "a", Attributes: map[string]interface{}{}},
+				{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+				{ID: "z", Attributes: map[string]interface{}{}},
+			}
+			for _, patcher := range patchers {
+				assert.NoError(t, JSONPatch(resources, &patcher))
+			}
+			assert.Equal(t, "second", resources[1].Attributes["key"])
+		}
+	})
+
+	t.Run("StopAtFirstFailureInOrder", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			resources := []v1.Resource{
+				{ID: "a", Attributes: map[string]interface{}{"key": "old"}},
+				{ID: "b", Attributes: map[string]interface{}{"key": "old"}},
+			}
+			err := JSONPatch(resources, &v1.Patcher{
+				JSONPatchers: map[string]v1.JSONPatcher{
+					"b": {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
+					"a": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "test", "path": "/key", "value": "new"}]`)},
+				},
+			})
+			assert.ErrorContains(t, err, "apply json patch to:a failed")
+			assert.Equal(t, "old", resources[1].Attributes["key"])
+		}
+	})
+
+	t.Run("Selector", func(t *testing.T) {
+		newResource := func(id, kind string, labels map[string]interface{}) v1.Resource {
+			return v1.Resource{ID: id, Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       kind,
+				"metadata":   map[string]interface{}{"name": id, "labels": labels},
+			}}
+		}
+		resources := []v1.Resource{
+			newResource("foo", "ConfigMap", map[string]interface{}{"app": "foo", "tier": "web"}),
+			newResource("bar", "ConfigMap", map[string]interface{}{"app": "foo"}),
+			newResource("baz", "Secret", map[string]interface{}{"app": "foo"}),
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchSelectors: []v1.JSONPatchSelector{
+				{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Labels:     map[string]string{"app": "foo"},
+					Patcher:    v1.JSONPatcher{Type: v1.MergePatch, Payload: []byte(`{"data": {"key": "value"}}`)},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"key": "value"}, resources[0].Attributes["data"])
+		assert.Equal(t, map[string]interface{}{"key": "value"}, resources[1].Attributes["data"])
+		assert.NotContains(t, resources[2].Attributes, "data")
+	})
+
+	t.Run("SelectorAfterID", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "foo"}},
+				"key":      "old",
+			}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.MergePatch, Payload: []byte(`{"key": "id"}`)},
+			},
+			JSONPatchSelectors: []v1.JSONPatchSelector{
+				{
+					Labels:  map[string]string{"app": "foo"},
+					Patcher: v1.JSONPatcher{Type: v1.MergePatch, Payload: []byte(`{"key": "selector"}`)},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "selector", resources[0].Attributes["key"])
+	})
+
+	t.Run("EmptySelector", func(t *testing.T) {
+		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{
+			JSONPatchSelectors: []v1.JSONPatchSelector{
+				{Patcher: v1.JSONPatcher{Type: v1.MergePatch, Payload: []byte(`{"key": "value"}`)}},
+			},
+		})
+		assert.ErrorContains(t, err, "json patch selector 0 selects no resources")
+	})
+
+	t.Run("StrategicMergePatch", func(t *testing.T) {
+		resources := []v1.Resource{
+			{
+				ID: "apps/v1:Deployment:default:foo",
+				Attributes: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"spec": map[string]interface{}{
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{"name": "main", "image": "nginx:1.0"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"apps/v1:Deployment:default:foo": {
+					Type: v1.StrategicMergePatch,
+					Payload: []byte(`{"spec": {"template": {"spec": {"containers": [
+						{"name": "main", "image": "nginx:2.0"},
+						{"name": "sidecar", "image": "envoy:1.0"}
+					]}}}}`),
+				},
+			},
+		})
+		assert.NoError(t, err)
+		containers, _, _ := unstructured.NestedSlice(resources[0].Attributes, "spec", "template", "spec", "containers")
+		assert.ElementsMatch(t, []interface{}{
+			map[string]interface{}{"name": "main", "image": "nginx:2.0"},
+			map[string]interface{}{"name": "sidecar", "image": "envoy:1.0"},
+		}, containers)
+	})
+
+	t.Run("StrategicMergePatchUnknownType", func(t *testing.T) {
+		resources := []v1.Resource{
+			{
+				ID: "test",
+				Attributes: map[string]interface{}{
+					"apiVersion": "example.com/v1",
+					"kind":       "Foo",
+					"items":      []interface{}{"a"},
+				},
+			},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.StrategicMergePatch, Payload: []byte(`{"items": ["b"]}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{"b"}, resources[0].Attributes["items"])
+	})
+
+	t.Run("JSONPatchTestFailed", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old", "replicas": 1}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[
+					{"op": "test", "path": "/key", "value": "old"},
+					{"op": "replace", "path": "/key", "value": "new"},
+					{"op": "test", "path": "/replicas", "value": 2},
+					{"op": "replace", "path": "/replicas", "value": 3}
+				]`)},
+			},
+		})
+		var testErr *JSONPatchTestError
+		assert.ErrorAs(t, err, &testErr)
+		assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+		assert.Equal(t, "test", testErr.ResourceID)
+		assert.Equal(t, "/replicas", testErr.Path)
+		assert.Equal(t, float64(2), testErr.Expected)
+		assert.ErrorContains(t, err, "test operation on path /replicas does not match the expected value 2")
+		assert.Equal(t, "old", resources[0].Attributes["key"])
+	})
+
+	t.Run("NilAttributes", func(t *testing.T) {
+		resources := []v1.Resource{{ID: "test"}}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "add", "path": "/key", "value": "new"}]`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", resources[0].Attributes["key"])
+	})
+
+	t.Run("NonObjectRoot", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"items": []interface{}{"a", "b"}}},
+		}
+		err := JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.MergePatch, Payload: []byte(`["a", "b"]`)},
+			},
+		})
+		assert.ErrorContains(t, err, "must be a JSON object, but got array")
+		assert.Equal(t, []interface{}{"a", "b"}, resources[0].Attributes["items"])
+
+		err = JSONPatch(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.MergePatch, Payload: []byte(`"raw"`)},
+			},
+		})
+		assert.ErrorContains(t, err, "must be a JSON object, but got string")
+	})
+
+	t.Run("UnsupportedPatchType", func(t *testing.T) {
+		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: "unsupported", Payload: []byte(`{"key": "value"}`)},
+			},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestJSONPatchDryRun(t *testing.T) {
+	t.Run("MergePatch", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old", "other": "value"}},
+			{ID: "untouched", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		diffs, err := JSONPatchDryRun(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test":    {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
+				"missing": {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"test": `--- test (before)
++++ test (after)
+@@ -1,2 +1,2 @@
+-key: old
++key: new
+ other: value
+`,
+		}, diffs)
+		assert.Equal(t, "old", resources[0].Attributes["key"])
+	})
+
+	t.Run("JSONPatch", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		diffs, err := JSONPatchDryRun(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[
+					{"op": "add", "path": "/added", "value": "new"},
+					{"op": "test", "path": "/key", "value": "old"}
+				]`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `--- test (before)
++++ test (after)
+@@ -1,1 +1,2 @@
++added: new
+ key: old
+`, diffs["test"])
+		assert.Equal(t, map[string]interface{}{"key": "old"}, resources[0].Attributes)
+	})
+
+	t.Run("NoChange", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		diffs, err := JSONPatchDryRun(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.MergePatch, Payload: []byte(`{"key": "old"}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"test": ""}, diffs)
+	})
+
+	t.Run("PatchFailed", func(t *testing.T) {
+		resources := []v1.Resource{
+			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+		}
+		_, err := JSONPatchDryRun(resources, &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"test": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "test", "path": "/key", "value": "new"}]`)},
+			},
+		})
+		assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+	})
+}
+
+func Test_patchWorkloadResources(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "apps/v1:Deployment:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "my-deployment"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "my-app",
+								"image": "my-app-image",
+								"resources": map[string]interface{}{
+									"requests": map[string]interface{}{"cpu": "100m"},
+									"limits":   map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+								},
+							},
+							map[string]interface{}{
+								"name":  "my-sidecar",
+								"image": "my-sidecar-image",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	patcher := &v1.Patcher{
+		Environments: []corev1.EnvVar{
+			{
+				Name:  "NEW_ENV",
+				Value: "my-new-value",
+			},
+		},
+		Resources: &v1.ContainerResources{
+			Requests: map[string]string{"memory": "256Mi"},
+			Limits:   map[string]string{"cpu": "2", "memory": removalVal},
+		},
+	}
+
+	err := PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+
+	containers := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+	assert.Len(t, containers, 2)
+
+	app := containers[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"requests": map[string]interface{}{"cpu": "100m", "memory": "256Mi"},
+		"limits":   map[string]interface{}{"cpu": "2"},
+	}, app["resources"])
+	assert.Contains(t, app["env"], map[string]interface{}{"name": "NEW_ENV", "value": "my-new-value"})
+
+	sidecar := containers[1].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"requests": map[string]interface{}{"memory": "256Mi"},
+		"limits":   map[string]interface{}{"cpu": "2"},
+	}, sidecar["resources"])
+	assert.Contains(t, sidecar["env"], map[string]interface{}{"name": "NEW_ENV", "value": "my-new-value"})
+}
+
+func Test_patchWorkloadExtensions(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "apps/v1:Deployment:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "my-deployment"},
+		},
+		Extensions: map[string]interface{}{
+			isWorkload:        true,
+			"kusion.io/to-be": "removed",
+		},
+	}
 
-	project, stack := buildMockProjectAndStack()
-	t.Run("Valid app configuration generator func", func(t *testing.T) {
-		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, app, ws, nil)()
-		assert.NoError(t, err)
-		assert.NotNil(t, g)
-	})
+	patcher := &v1.Patcher{
+		Extensions: map[string]interface{}{
+			v1.FieldApplyPause: true,
+			"kusion.io/to-be":  removalVal,
+		},
+	}
 
-	t.Run("Empty app name", func(t *testing.T) {
-		g, err := NewAppConfigurationGeneratorFunc(project, stack, "", app, ws, nil)()
-		assert.EqualError(t, err, "app name must not be empty")
-		assert.Nil(t, g)
-	})
+	err := PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		isWorkload:         true,
+		v1.FieldApplyPause: true,
+	}, res.Extensions)
+}
 
-	t.Run("Nil app", func(t *testing.T) {
-		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, nil, ws, nil)()
-		assert.EqualError(t, err, "can not find app configuration when generating the Spec")
-		assert.Nil(t, g)
-	})
+func Test_patchWorkloadTolerations(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "apps/v1:Deployment:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "my-deployment"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "my-app",
+								"image": "my-app-image",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
 
-	t.Run("Nil project", func(t *testing.T) {
-		g, err := NewAppConfigurationGeneratorFunc(nil, stack, appName, app, ws, nil)()
-		assert.EqualError(t, err, "project must not be nil")
-		assert.Nil(t, g)
-	})
+	// the workload has no tolerations yet, and the duplicated toleration is merged
+	patcher := &v1.Patcher{
+		Tolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "canary", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "dedicated", Value: "canary", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		},
+	}
+	err := PatchWorkload(res, patcher)
+	assert.NoError(t, err)
 
-	t.Run("Empty workspace", func(t *testing.T) {
-		g, err := NewAppConfigurationGeneratorFunc(project, stack, appName, app, nil, nil)()
-		assert.EqualError(t, err, "workspace must not be empty")
-		assert.Nil(t, g)
-	})
+	tolerations, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "tolerations")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "dedicated", "value": "canary", "effect": "NoSchedule"},
+		map[string]interface{}{"key": "spot", "operator": "Exists", "effect": "NoExecute"},
+	}, tolerations)
+
+	// remove the tolerations by the removal value
+	patcher = &v1.Patcher{
+		Tolerations: []corev1.Toleration{
+			{Key: "spot", Value: removalVal},
+		},
+	}
+	err = PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+
+	tolerations, _, err = unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "tolerations")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "dedicated", "value": "canary", "effect": "NoSchedule"},
+	}, tolerations)
 }
 
-func buildMockApp() (string, *v1.AppConfiguration) {
-	return "app1", &v1.AppConfiguration{
-		Workload: map[string]interface{}{
-			"type": "Deployment",
-			"ports": []map[string]any{
-				{
-					"port":     80,
-					"protocol": "TCP",
+func Test_patchWorkloadNodeSelector(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "batch/v1:Job:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": "my-job"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "my-job",
+								"image": "my-job-image",
+							},
+						},
+					},
 				},
 			},
-			"_type": "service",
 		},
-		Accessories: map[string]v1.Accessory{
-			"port": map[string]interface{}{
-				"port":  "2333",
-				"_type": "port.Port",
-			},
+	}
+
+	// the workload has no node selector yet
+	patcher := &v1.Patcher{
+		NodeSelector: map[string]string{
+			"kubernetes.io/os": "linux",
+			"node-pool":        "batch",
+		},
+	}
+	err := PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+
+	nodeSelector, _, err := unstructured.NestedStringMap(res.Attributes, "spec", "template", "spec", "nodeSelector")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux", "node-pool": "batch"}, nodeSelector)
+
+	// merge and remove the node selector by the removal value
+	patcher = &v1.Patcher{
+		NodeSelector: map[string]string{
+			"node-pool": removalVal,
+			"zone":      "zone-a",
 		},
 	}
+	err = PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+
+	nodeSelector, _, err = unstructured.NestedStringMap(res.Attributes, "spec", "template", "spec", "nodeSelector")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux", "zone": "zone-a"}, nodeSelector)
 }
 
-func buildMockWorkspace() *v1.Workspace {
-	return &v1.Workspace{
-		Name: "test",
-		Modules: v1.ModuleConfigs{
-			"mysql": &v1.ModuleConfig{
-				Path:    "kusionstack.io/mysql",
-				Version: "v1.0.0",
-				Configs: v1.Configs{
-					Default: v1.GenericConfig{
-						"type":         "aws",
-						"version":      "5.7",
-						"instanceType": "db.t3.micro",
-					},
-					ModulePatcherConfigs: v1.ModulePatcherConfigs{
-						"smallClass": {
-							GenericConfig: v1.GenericConfig{
-								"instanceType": "db.t3.small",
+func Test_patchWorkloadAffinity(t *testing.T) {
+	zoneTerm := map[string]interface{}{
+		"labelSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"app": "foo"},
+		},
+		"topologyKey": "kubernetes.io/hostname",
+	}
+	res := &v1.Resource{
+		ID:   "apps/v1:Deployment:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "my-deployment"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"affinity": map[string]interface{}{
+							"podAntiAffinity": map[string]interface{}{
+								"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{zoneTerm},
+							},
+							"nodeAffinity": map[string]interface{}{
+								"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+									"nodeSelectorTerms": []interface{}{
+										map[string]interface{}{
+											"matchExpressions": []interface{}{
+												map[string]interface{}{"key": "kubernetes.io/os", "operator": "In", "values": []interface{}{"linux"}},
+											},
+										},
+									},
+								},
 							},
-							ProjectSelector: []string{"foo", "bar"},
 						},
 					},
 				},
 			},
-			"port": &v1.ModuleConfig{
-				Path:    "kusionstack.io/port",
-				Version: "v1.0.0",
-				Configs: v1.Configs{
-					Default: v1.GenericConfig{
-						"type": "aws",
+		},
+	}
+
+	patcher := &v1.Patcher{
+		Affinity: &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+						TopologyKey:   "topology.kubernetes.io/zone",
 					},
 				},
 			},
-			"service": &v1.ModuleConfig{
-				Path:    "kusionstack.io/service",
-				Version: "v1.0.0",
-				Configs: v1.Configs{
-					Default: v1.GenericConfig{
-						"replica": 2,
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+						}},
 					},
 				},
 			},
 		},
-		Context: map[string]any{
-			"Kubernetes": map[string]string{
-				"Config": "/etc/kubeconfig.yaml",
+	}
+	err := PatchWorkload(res, patcher)
+	assert.NoError(t, err)
+
+	affinity, _, err := unstructured.NestedMap(res.Attributes, "spec", "template", "spec", "affinity")
+	assert.NoError(t, err)
+	// the existing term is kept without duplication and the new term is appended
+	assert.Equal(t, []interface{}{
+		zoneTerm,
+		map[string]interface{}{
+			"labelSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "foo"},
 			},
+			"topologyKey": "topology.kubernetes.io/zone",
 		},
-	}
+	}, affinity["podAntiAffinity"].(map[string]interface{})["requiredDuringSchedulingIgnoredDuringExecution"])
+	// the requirements of the node selector term are merged into the existing term rather than ORed with it
+	nodeSelectorTerms, _, err := unstructured.NestedSlice(affinity, "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "kubernetes.io/os", "operator": "In", "values": []interface{}{"linux"}},
+				map[string]interface{}{"key": "zone", "operator": "In", "values": []interface{}{"a"}},
+			},
+		},
+	}, nodeSelectorTerms)
 }
 
-func buildMockProjectAndStack() (*v1.Project, *v1.Stack) {
-	p := &v1.Project{
-		Name: "testproject",
-	}
-
-	s := &v1.Stack{
-		Name: "test",
+func Test_mergeNodeSelectorTerms(t *testing.T) {
+	term := func(keys ...string) interface{} {
+		var expressions []interface{}
+		for _, key := range keys {
+			expressions = append(expressions, map[string]interface{}{"key": key, "operator": "Exists"})
+		}
+		return map[string]interface{}{"matchExpressions": expressions}
 	}
 
-	return p, s
+	// (a or b) and (c or d)
+	assert.Equal(t, []interface{}{term("a", "c"), term("a", "d"), term("b", "c"), term("b", "d")},
+		mergeNodeSelectorTerms([]interface{}{term("a"), term("b")}, []interface{}{term("c"), term("d")}))
+	// the empty patch keeps the base terms
+	assert.Equal(t, []interface{}{term("a")}, mergeNodeSelectorTerms([]interface{}{term("a")}, nil))
+	assert.Equal(t, []interface{}{term("c")}, mergeNodeSelectorTerms(nil, []interface{}{term("c")}))
 }
 
-func mapToUnstructured(data map[string]interface{}) *unstructured.Unstructured {
-	unstructuredObj := &unstructured.Unstructured{}
-	unstructuredObj.SetUnstructuredContent(data)
-	return unstructuredObj
+func TestPatchRetryPolicy(t *testing.T) {
+	resources := v1.Resources{
+		{ID: "v1:Namespace:foo"},
+		{
+			ID: "apps/v1:Deployment:foo:foo-dev-app",
+			Extensions: map[string]interface{}{
+				v1.FieldRetryPolicy: map[string]interface{}{"maxRetries": 5},
+			},
+		},
+	}
+
+	patchRetryPolicy(resources, &v1.RetryPolicy{MaxRetries: 3, BackoffSeconds: 2})
+	assert.Equal(t, map[string]interface{}{"maxRetries": 3, "backoffSeconds": 2}, resources[0].Extensions[v1.FieldRetryPolicy])
+	// the retry policy from the module output is kept
+	assert.Equal(t, map[string]interface{}{"maxRetries": 5}, resources[1].Extensions[v1.FieldRetryPolicy])
 }
 
-func getNamespace(actual *unstructured.Unstructured) (string, error) {
-	metadata, ok := actual.Object["metadata"].(map[interface{}]interface{})
-	if !ok {
-		return "", errors.New("failed to get metadata from unstructed object")
+func Test_patchWorkloadInitContainers(t *testing.T) {
+	newWorkload := func() *v1.Resource {
+		return &v1.Resource{
+			ID:   "apps/v1:Deployment:default:default-dev-foo",
+			Type: "Kubernetes",
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "my-deployment"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"initContainers": []interface{}{
+								map[string]interface{}{
+									"name":  "migrate",
+									"image": "migrate:v1",
+								},
+								map[string]interface{}{
+									"name":  "fetch-credential",
+									"image": "fetch-credential:v1",
+								},
+							},
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":  "my-app",
+									"image": "my-app-image",
+								},
+							},
+						},
+					},
+				},
+			},
+		}
 	}
-	ns, ok := metadata["namespace"].(string)
-	if !ok {
-		return "", errors.New("failed to get namespace from metadata")
+	initContainerImages := func(res *v1.Resource) map[string]string {
+		initContainers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "initContainers")
+		assert.NoError(t, err)
+		images := make(map[string]string, len(initContainers))
+		for _, c := range initContainers {
+			container := c.(map[string]interface{})
+			images[container["name"].(string)] = container["image"].(string)
+		}
+		return images
+	}
+	initContainerNames := func(res *v1.Resource) []string {
+		initContainers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "initContainers")
+		assert.NoError(t, err)
+		var names []string
+		for _, c := range initContainers {
+			names = append(names, c.(map[string]interface{})["name"].(string))
+		}
+		return names
 	}
 
-	return ns, nil
-}
+	t.Run("prepend new init containers", func(t *testing.T) {
+		res := newWorkload()
+		patcher := &v1.Patcher{
+			InitContainers: []corev1.Container{
+				{Name: "wait-for-db", Image: "busybox"},
+				{Name: "load-config", Image: "load-config:v1"},
+			},
+		}
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"wait-for-db", "load-config", "migrate", "fetch-credential"}, initContainerNames(res))
+	})
 
-func Test_patchWorkload(t *testing.T) {
-	replica := int32(2)
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "my-deployment",
-			Labels: map[string]string{
-				"oldLabel": "oldValue",
+	t.Run("replace existing init containers by name", func(t *testing.T) {
+		res := newWorkload()
+		patcher := &v1.Patcher{
+			InitContainers: []corev1.Container{
+				{Name: "fetch-credential", Image: "fetch-credential:v2"},
 			},
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &replica,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"oldLabel": "oldValue",
-					},
-					Annotations: map[string]string{
-						"oldAnnotation": "oldValue",
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "my-app",
-							Image: "my-app-image",
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									TCPSocket: &corev1.TCPSocketAction{
-										Host: "localhost:8080",
-									},
+		}
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"migrate", "fetch-credential"}, initContainerNames(res))
+		assert.Equal(t, map[string]string{
+			"migrate":          "migrate:v1",
+			"fetch-credential": "fetch-credential:v2",
+		}, initContainerImages(res))
+	})
+}
+
+func Test_patchWorkloadVolumes(t *testing.T) {
+	newWorkload := func() *v1.Resource {
+		return &v1.Resource{
+			ID:   "apps/v1:Deployment:default:default-dev-foo",
+			Type: "Kubernetes",
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "my-deployment"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"volumes": []interface{}{
+								map[string]interface{}{
+									"name":      "config",
+									"configMap": map[string]interface{}{"name": "my-config"},
 								},
-								InitialDelaySeconds: 2,
 							},
-							Env: []corev1.EnvVar{
-								{
-									Name:  "MY_ENV",
-									Value: "my-env-value",
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":  "my-app",
+									"image": "my-app-image",
+									"volumeMounts": []interface{}{
+										map[string]interface{}{"name": "config", "mountPath": "/etc/config"},
+									},
+								},
+								map[string]interface{}{
+									"name":  "my-sidecar",
+									"image": "my-sidecar-image",
 								},
 							},
 						},
 					},
 				},
 			},
-		},
+		}
 	}
-
-	// convert deploy to map with yamlv3 to simulate what we did in the module framework
-	deploymentUnstructured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
-	assert.NoError(t, err)
-	out, err := yaml.Marshal(deploymentUnstructured)
-	assert.NoError(t, err)
-	err = yaml.Unmarshal(out, deploymentUnstructured)
-
-	res := &v1.Resource{
-		ID:         "apps/v1:Deployment:default:default-dev-foo",
-		Type:       "Kubernetes",
-		Attributes: deploymentUnstructured,
+	names := func(items []interface{}) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.(map[string]interface{})["name"].(string))
+		}
+		return result
+	}
+	volumeNames := func(res *v1.Resource) []string {
+		volumes, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "volumes")
+		assert.NoError(t, err)
+		return names(volumes)
+	}
+	volumeMountNames := func(res *v1.Resource) [][]string {
+		containers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "containers")
+		assert.NoError(t, err)
+		var result [][]string
+		for _, c := range containers {
+			mounts, _, err := unstructured.NestedSlice(c.(map[string]interface{}), "volumeMounts")
+			assert.NoError(t, err)
+			result = append(result, names(mounts))
+		}
+		return result
 	}
 
-	t.Run("Patch labels and annotations", func(t *testing.T) {
+	t.Run("merge volumes and volume mounts", func(t *testing.T) {
+		res := newWorkload()
 		patcher := &v1.Patcher{
-			Labels:         map[string]string{"newLabel": "newValue"},
-			Annotations:    map[string]string{"newAnnotation": "newValue"},
-			PodLabels:      map[string]string{"newPodLabel": "newValue"},
-			PodAnnotations: map[string]string{"newPodAnnotation": "newValue"},
+			Volumes: []corev1.Volume{
+				{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				{Name: "config", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "my-secret"}}},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "logs", MountPath: "/var/log"},
+			},
 		}
-
 		err := PatchWorkload(res, patcher)
 		assert.NoError(t, err)
+		assert.Equal(t, []string{"config", "logs"}, volumeNames(res))
+		assert.Equal(t, [][]string{{"config", "logs"}, {"logs"}}, volumeMountNames(res))
 
-		workloadLabels := res.Attributes["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
-		podLabels := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
-
-		// assert deployment workloadLabels
-		assert.Equal(t, "newValue", workloadLabels["newLabel"])
-		assert.Equal(t, "oldValue", workloadLabels["oldLabel"])
-		// assert pod labels
-		assert.Equal(t, "newValue", podLabels["newPodLabel"])
-		assert.Equal(t, "oldValue", podLabels["oldLabel"])
-
-		annotations := res.Attributes["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
-		// get pod annotations
-		podAnnotations := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
-		// assert deployment annotations
-		assert.Equal(t, "newValue", annotations["newAnnotation"])
-		// assert pod annotations
-		assert.Equal(t, "newValue", podAnnotations["newPodAnnotation"])
-		assert.Equal(t, "oldValue", podLabels["oldLabel"])
+		volumes, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "volumes")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"secretName": "my-secret"}, volumes[0].(map[string]interface{})["secret"])
+		assert.NotContains(t, volumes[0].(map[string]interface{}), "configMap")
 	})
 
-	t.Run("Patch environment variables", func(t *testing.T) {
+	t.Run("remove volume and its volume mounts", func(t *testing.T) {
+		res := newWorkload()
 		patcher := &v1.Patcher{
-			Environments: []corev1.EnvVar{
-				{
-					Name:  "MY_ENV",
-					Value: "ops://kusionstack.io/remove",
-				},
-				{
-					Name:  "NEW_ENV",
-					Value: "my-new-value",
-				},
+			Volumes: []corev1.Volume{
+				{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+			RemovedVolumes: []string{"config"},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "logs", MountPath: "/var/log"},
 			},
 		}
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"logs"}, volumeNames(res))
+		assert.Equal(t, [][]string{{"logs"}, {"logs"}}, volumeMountNames(res))
+	})
 
-		err = PatchWorkload(res, patcher)
+	t.Run("keep volume mounts of different sub paths", func(t *testing.T) {
+		res := newWorkload()
+		patcher := &v1.Patcher{
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "config", MountPath: "/etc/config/app.yaml", SubPath: "app.yaml"},
+				{Name: "config", MountPath: "/etc/config/log.yaml", SubPath: "log.yaml"},
+				{Name: "config", MountPath: "/etc/config", ReadOnly: true},
+			},
+		}
+		err := PatchWorkload(res, patcher)
 		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"config", "config", "config"}, {"config", "config", "config"}}, volumeMountNames(res))
 
-		containers := res.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
-		env := containers[0].(map[string]interface{})["env"].([]interface{})
-		assert.Contains(t, env, map[string]interface{}{"name": "NEW_ENV", "value": "my-new-value"})
-		assert.NotContains(t, env, map[string]interface{}{"name": "MY_ENV", "value": "my-env-value"})
+		containers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "containers")
+		assert.NoError(t, err)
+		mounts := containers[0].(map[string]interface{})["volumeMounts"].([]interface{})
+		// the existing volume mount with the same name, mount path and sub path is replaced
+		assert.Equal(t, map[string]interface{}{"name": "config", "mountPath": "/etc/config", "readOnly": true}, mounts[0])
+		assert.Equal(t, "app.yaml", mounts[1].(map[string]interface{})["subPath"])
+		assert.Equal(t, "log.yaml", mounts[2].(map[string]interface{})["subPath"])
 	})
 }
 
-func TestAppConfigurationGenerator_CallModules(t *testing.T) {
-	// Mock dependencies
-	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
-	deps.Set("port", pkg.Dependency{
-		Version: "1.0.0",
-		Source: downloader.Source{
-			Oci: &downloader.Oci{
-				Repo: "kusionstack/module1",
+func Test_patchWorkloadImagePullSecrets(t *testing.T) {
+	newWorkload := func(spec map[string]interface{}) *v1.Resource {
+		return &v1.Resource{
+			ID:   "apps/v1:Deployment:default:default-dev-foo",
+			Type: "Kubernetes",
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "my-deployment"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{"spec": spec},
+				},
 			},
-		},
-	})
-	deps.Set("service", pkg.Dependency{
-		Version: "1.0.0",
-		Name:    "service",
-	})
-	dependencies := &pkg.Dependencies{
-		Deps: deps,
+		}
 	}
-
-	// Mock project module configs
-	projectModuleConfigs := map[string]v1.GenericConfig{
-		"port": {
-			"config1": "value1",
+	pullSecretNames := func(res *v1.Resource) []string {
+		pullSecrets, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "imagePullSecrets")
+		assert.NoError(t, err)
+		var names []string
+		for _, s := range pullSecrets {
+			names = append(names, s.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+	patcher := &v1.Patcher{
+		ImagePullSecrets: []corev1.LocalObjectReference{
+			{Name: "platform-registry"},
+			{Name: "module-registry"},
+			{Name: "mirror-registry"},
 		},
 	}
 
-	// Mock app appConfig generator
-	_, appConfig := buildMockApp()
-	project, stack := buildMockProjectAndStack()
-	g := &appConfigurationGenerator{
-		project:      project,
-		stack:        stack,
-		appName:      "testapp",
-		app:          appConfig,
-		ws:           buildMockWorkspace(),
-		dependencies: dependencies,
-	}
+	t.Run("create image pull secrets", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "my-app", "image": "my-app-image"}},
+		})
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"platform-registry", "module-registry", "mirror-registry"}, pullSecretNames(res))
+	})
 
-	t.Run("Successful module call", func(t *testing.T) {
-		// Mock the plugin
-		pluginMock := mockey.Mock(module.NewPlugin).To(func(key string) (*module.Plugin, error) {
-			return &module.Plugin{Module: &fakeModule{}}, nil
-		}).Build()
-		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
-		defer func() {
-			pluginMock.UnPatch()
-			killMock.UnPatch()
-		}()
+	t.Run("merge into existing image pull secrets", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "my-app", "image": "my-app-image"}},
+			"imagePullSecrets": []interface{}{
+				map[string]interface{}{"name": "module-registry"},
+				map[string]interface{}{"name": "legacy-registry"},
+			},
+		})
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		// the existing pull secrets keep their order, and the new ones are appended in the patched order
+		assert.Equal(t, []string{"module-registry", "legacy-registry", "platform-registry", "mirror-registry"}, pullSecretNames(res))
 
-		wl, resources, patchers, err := g.callModules(projectModuleConfigs)
+		// patching again is stable
+		err = PatchWorkload(res, patcher)
 		assert.NoError(t, err)
-		assert.NotEmpty(t, wl)
-		assert.NotEmpty(t, resources)
-		assert.Empty(t, patchers)
+		assert.Equal(t, []string{"module-registry", "legacy-registry", "platform-registry", "mirror-registry"}, pullSecretNames(res))
 	})
+}
 
-	t.Run("Failed module call due to missing module in dependencies", func(t *testing.T) {
-		// Mock the plugin
-		pluginMock := mockey.Mock(module.NewPlugin).To(func(key string) (*module.Plugin, error) {
-			return nil, fmt.Errorf("module not found")
-		}).Build()
-		defer func() {
-			pluginMock.UnPatch()
-		}()
+func Test_patchWorkloadSecurityContext(t *testing.T) {
+	newWorkload := func(container map[string]interface{}) *v1.Resource {
+		return &v1.Resource{
+			ID:   "apps/v1:Deployment:default:default-dev-foo",
+			Type: "Kubernetes",
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "my-deployment"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								container,
+								map[string]interface{}{"name": "my-sidecar", "image": "my-sidecar-image"},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	runAsNonRoot, privileged := true, false
+	patcher := &v1.Patcher{
+		PodSecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot},
+		ContainerSecurityContext: &corev1.SecurityContext{
+			Privileged:   &privileged,
+			Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+	}
+	containerSecurityContexts := func(res *v1.Resource) []interface{} {
+		containers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "containers")
+		assert.NoError(t, err)
+		var result []interface{}
+		for _, c := range containers {
+			result = append(result, c.(map[string]interface{})["securityContext"])
+		}
+		return result
+	}
 
-		_, _, _, err := g.callModules(projectModuleConfigs)
-		assert.Error(t, err)
+	t.Run("create security contexts", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{"name": "my-app", "image": "my-app-image"})
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+
+		podSecurityContext, _, err := unstructured.NestedMap(res.Attributes, "spec", "template", "spec", "securityContext")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"runAsNonRoot": true}, podSecurityContext)
+		expected := map[string]interface{}{
+			"privileged":   false,
+			"capabilities": map[string]interface{}{"drop": []interface{}{"ALL"}},
+		}
+		assert.Equal(t, []interface{}{expected, expected}, containerSecurityContexts(res))
 	})
 
-	t.Run("Failed module call due to error in plugin", func(t *testing.T) {
-		// Mock the plugin
-		pluginMock := mockey.Mock(module.NewPlugin).To(func(key string) (*module.Plugin, error) {
-			return &module.Plugin{Module: &fakeModule{}}, nil
-		}).Build()
-		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(fmt.Errorf("error in plugin")).Build()
-		defer func() {
-			pluginMock.UnPatch()
-			killMock.UnPatch()
-		}()
-		_, _, _, err := g.callModules(projectModuleConfigs)
-		assert.Error(t, err)
+	t.Run("overwrite privileged container", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{
+			"name":  "my-app",
+			"image": "my-app-image",
+			"securityContext": map[string]interface{}{
+				"privileged":   true,
+				"runAsUser":    int64(1000),
+				"capabilities": map[string]interface{}{"add": []interface{}{"NET_ADMIN"}},
+			},
+		})
+		err := PatchWorkload(res, patcher)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"privileged": false,
+			"runAsUser":  int64(1000),
+			"capabilities": map[string]interface{}{
+				"add":  []interface{}{"NET_ADMIN"},
+				"drop": []interface{}{"ALL"},
+			},
+		}, containerSecurityContexts(res)[0])
 	})
 }
 
-func TestJsonPatch(t *testing.T) {
-	t.Run("ResourcesNil", func(t *testing.T) {
-		err := JSONPatch(nil, &v1.Patcher{})
+func Test_patchWorkloadCommandAndArgs(t *testing.T) {
+	newWorkload := func(containers ...interface{}) *v1.Resource {
+		return &v1.Resource{
+			ID:   "apps/v1:Deployment:default:default-dev-foo",
+			Type: "Kubernetes",
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "my-deployment"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{"containers": containers},
+					},
+				},
+			},
+		}
+	}
+	containerField := func(res *v1.Resource, field string) []interface{} {
+		containers, _, err := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "containers")
 		assert.NoError(t, err)
-	})
+		var result []interface{}
+		for _, c := range containers {
+			result = append(result, c.(map[string]interface{})[field])
+		}
+		return result
+	}
 
-	t.Run("PatcherNil", func(t *testing.T) {
-		err := JSONPatch([]v1.Resource{{ID: "test"}}, nil)
+	t.Run("single container by default", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{
+			"name":    "my-app",
+			"image":   "my-app-image",
+			"command": []interface{}{"/app"},
+			"args":    []interface{}{"--port", "8080"},
+		})
+		err := PatchWorkload(res, &v1.Patcher{Command: []string{"sleep"}, Args: []string{"infinity"}})
 		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{[]interface{}{"sleep"}}, containerField(res, "command"))
+		assert.Equal(t, []interface{}{[]interface{}{"infinity"}}, containerField(res, "args"))
 	})
 
-	t.Run("JsonPatchersNil", func(t *testing.T) {
-		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{})
+	t.Run("named target container", func(t *testing.T) {
+		res := newWorkload(
+			map[string]interface{}{"name": "my-app", "image": "my-app-image", "args": []interface{}{"--port", "8080"}},
+			map[string]interface{}{"name": "my-sidecar", "image": "my-sidecar-image"},
+		)
+		err := PatchWorkload(res, &v1.Patcher{ContainerName: "my-sidecar", Command: []string{"/bin/sh", "-c"}})
 		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{nil, []interface{}{"/bin/sh", "-c"}}, containerField(res, "command"))
+		// the args are kept if not patched
+		assert.Equal(t, []interface{}{[]interface{}{"--port", "8080"}, nil}, containerField(res, "args"))
 	})
 
-	t.Run("ResourceNotFound", func(t *testing.T) {
-		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{
-			JSONPatchers: map[string]v1.JSONPatcher{
-				"notfound": {Type: v1.MergePatch, Payload: []byte(`{"key": "value"}`)},
-			},
-		})
-		assert.Nil(t, err)
+	t.Run("unknown target container", func(t *testing.T) {
+		res := newWorkload(map[string]interface{}{"name": "my-app", "image": "my-app-image"})
+		err := PatchWorkload(res, &v1.Patcher{ContainerName: "unknown", Args: []string{"--debug"}})
+		assert.ErrorContains(t, err, "unknown")
 	})
+}
 
-	t.Run("MergePatch", func(t *testing.T) {
-		resources := []v1.Resource{
-			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+func Test_patchWorkloadReplicas(t *testing.T) {
+	newWorkload := func(kind string, spec interface{}) *v1.Resource {
+		attributes := map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "my-workload"},
 		}
-		err := JSONPatch(resources, &v1.Patcher{
-			JSONPatchers: map[string]v1.JSONPatcher{
-				"test": {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
-			},
+		if spec != nil {
+			attributes["spec"] = spec
+		}
+		return &v1.Resource{
+			ID:         "apps/v1:" + kind + ":default:default-dev-foo",
+			Type:       "Kubernetes",
+			Attributes: attributes,
+		}
+	}
+	floor := int32(3)
+	patcher := &v1.Patcher{Replicas: &floor}
+
+	t.Run("raise replicas of deployment", func(t *testing.T) {
+		res := newWorkload("Deployment", map[string]interface{}{"replicas": 1})
+		assert.NoError(t, PatchWorkload(res, patcher))
+		replicas, _, _ := unstructured.NestedInt64(res.Attributes, "spec", "replicas")
+		assert.Equal(t, int64(3), replicas)
+	})
+
+	t.Run("keep replicas of deployment above floor", func(t *testing.T) {
+		res := newWorkload("Deployment", map[string]interface{}{"replicas": 5})
+		assert.NoError(t, PatchWorkload(res, patcher))
+		replicas, _, _ := unstructured.NestedInt64(res.Attributes, "spec", "replicas")
+		assert.Equal(t, int64(5), replicas)
+	})
+
+	t.Run("statefulset without spec", func(t *testing.T) {
+		res := newWorkload("StatefulSet", nil)
+		assert.NoError(t, PatchWorkload(res, patcher))
+		replicas, _, _ := unstructured.NestedInt64(res.Attributes, "spec", "replicas")
+		assert.Equal(t, int64(3), replicas)
+	})
+
+	t.Run("no-op for daemonset", func(t *testing.T) {
+		res := newWorkload("DaemonSet", map[string]interface{}{})
+		assert.NoError(t, PatchWorkload(res, patcher))
+		_, found, _ := unstructured.NestedInt64(res.Attributes, "spec", "replicas")
+		assert.False(t, found)
+	})
+
+	t.Run("malformed workload", func(t *testing.T) {
+		res := newWorkload("Deployment", "malformed")
+		assert.NotPanics(t, func() {
+			assert.Error(t, PatchWorkload(res, patcher))
 		})
-		assert.NoError(t, err)
-		assert.Equal(t, "new", resources[0].Attributes["key"])
 	})
+}
 
-	t.Run("JSONPatch", func(t *testing.T) {
-		resources := []v1.Resource{
-			{ID: "test", Attributes: map[string]interface{}{"key": "old"}},
+func TestPatchWorkload_PatchError(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "apps/v1:Deployment:default:default-dev-foo",
+		Type: "Kubernetes",
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "my-deployment"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{"not-a-container"},
+					},
+				},
+			},
+		},
+	}
+	patcher := &v1.Patcher{
+		Environments: []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+	}
+
+	err := PatchWorkload(res, patcher)
+	var patchErr *PatchError
+	assert.True(t, errors.As(err, &patchErr))
+	assert.Equal(t, "apps/v1:Deployment:default:default-dev-foo", patchErr.WorkloadID)
+	assert.Equal(t, PatchKindEnv, patchErr.Kind)
+	assert.Empty(t, patchErr.Container)
+	assert.ErrorContains(t, patchErr.Err, "container 0 is not a map")
+	assert.ErrorContains(t, err, "failed to patch env of workload:apps/v1:Deployment:default:default-dev-foo")
+}
+
+func TestMergeEnvironments(t *testing.T) {
+	patchers := []v1.Patcher{
+		{Environments: []corev1.EnvVar{{Name: "A", Value: "a"}, {Name: "B", Value: "b"}, {Name: "C", Value: "c"}}},
+		{Environments: []corev1.EnvVar{{Name: "D", Value: "d"}, {Name: "B", Value: "b2"}, {Name: "C", Value: removalVal}}},
+	}
+	merged := mergeEnvironments(patchers)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "A", Value: "a"},
+		{Name: "D", Value: "d"},
+		{Name: "B", Value: "b2"},
+		{Name: "C", Value: removalVal},
+	}, merged)
+
+	for i := 0; i < 10; i++ {
+		res := &v1.Resource{
+			ID: "apps/v1:Deployment:default:default-dev-foo",
+			Attributes: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name": "main",
+									"env": []interface{}{
+										map[string]interface{}{"name": "C", "value": "c0"},
+										map[string]interface{}{"name": "E", "value": "e"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 		}
-		err := JSONPatch(resources, &v1.Patcher{
-			JSONPatchers: map[string]v1.JSONPatcher{
-				"test": {Type: v1.JSONPatch, Payload: []byte(`[{"op": "replace", "path": "/key", "value": "new"}]`)},
+		assert.NoError(t, PatchWorkload(res, &v1.Patcher{Environments: mergeEnvironments(patchers)}))
+		containers, _, _ := unstructured.NestedSlice(res.Attributes, "spec", "template", "spec", "containers")
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "A", "value": "a"},
+			map[string]interface{}{"name": "D", "value": "d"},
+			map[string]interface{}{"name": "B", "value": "b2"},
+			map[string]interface{}{"name": "E", "value": "e"},
+		}, containers[0].(map[string]interface{})["env"])
+	}
+}
+
+func TestPatchWorkload_Terraform(t *testing.T) {
+	res := &v1.Resource{
+		ID:   "hashicorp:aws:aws_instance:foo",
+		Type: v1.Terraform,
+		Attributes: map[string]interface{}{
+			"instance_count": 2,
+			"cpu_ratio":      float64(1),
+			"root_block_device": []interface{}{
+				map[string]interface{}{"volume_size": float32(8.5)},
 			},
+		},
+	}
+	err := PatchWorkload(res, &v1.Patcher{
+		Labels:     map[string]string{"app": "foo"},
+		Extensions: map[string]interface{}{"foo": "bar"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Attributes["instance_count"])
+	assert.Equal(t, float64(1), res.Attributes["cpu_ratio"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"volume_size": float32(8.5)},
+	}, res.Attributes["root_block_device"])
+	assert.Equal(t, "bar", res.Extensions["foo"])
+}
+
+// fuzzPatcher patches all the fields of the workload, with the removals of the env and volumes.
+func fuzzPatcher() *v1.Patcher {
+	replicas := int32(2)
+	runAsNonRoot := true
+	return &v1.Patcher{
+		Labels:         map[string]string{"app": "foo", "removed": removalVal},
+		PodLabels:      map[string]string{"app": "foo"},
+		Annotations:    map[string]string{"owner": "foo"},
+		PodAnnotations: map[string]string{"owner": "foo"},
+		Environments: []corev1.EnvVar{
+			{Name: "FOO", Value: "bar"},
+			{Name: "REMOVED", Value: removalVal},
+		},
+		Resources:    &v1.ContainerResources{Requests: map[string]string{"cpu": "100m"}},
+		NodeSelector: map[string]string{"zone": "a"},
+		Tolerations:  []corev1.Toleration{{Key: "dedicated", Value: "foo", Effect: corev1.TaintEffectNoSchedule}},
+		Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{},
+		}},
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+		Volumes: []corev1.Volume{
+			{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		},
+		RemovedVolumes:           []string{"removed"},
+		VolumeMounts:             []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		ImagePullSecrets:         []corev1.LocalObjectReference{{Name: "registry"}},
+		PodSecurityContext:       &corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot},
+		ContainerSecurityContext: &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot},
+		Replicas:                 &replicas,
+		Command:                  []string{"/bin/sh"},
+		Args:                     []string{"-c", "true"},
+		Extensions:               map[string]interface{}{"foo": "bar"},
+	}
+}
+
+func TestPatchWorkload_MalformedWorkload(t *testing.T) {
+	testcases := []struct {
+		name        string
+		attributes  string
+		errContains string
+	}{
+		{
+			name:        "labels of wrong type",
+			attributes:  `{"metadata": {"labels": "foo"}}`,
+			errContains: "failed to patch labels of workload:test",
+		},
+		{
+			name:        "metadata of wrong type",
+			attributes:  `{"metadata": []}`,
+			errContains: "failed to patch labels of workload:test",
+		},
+		{
+			name:        "nil containers",
+			attributes:  `{"spec": {"template": {"spec": {"containers": null}}}}`,
+			errContains: "failed to patch env of workload:test",
+		},
+		{
+			name:        "container of wrong type",
+			attributes:  `{"spec": {"template": {"spec": {"containers": [1]}}}}`,
+			errContains: "container 0 is not a map",
+		},
+		{
+			name:        "env without name",
+			attributes:  `{"spec": {"template": {"spec": {"containers": [{"name": "main", "env": [{"value": "foo"}]}]}}}}`,
+			errContains: "env 0 has no name of string",
+		},
+		{
+			name:        "env of wrong type",
+			attributes:  `{"spec": {"template": {"spec": {"containers": [{"name": "main", "env": "foo"}]}}}}`,
+			errContains: "failed to get env",
+		},
+		{
+			name:        "replicas of wrong type",
+			attributes:  `{"kind": "Deployment", "spec": {"replicas": "1", "template": {"spec": {"containers": [{"name": "main"}]}}}}`,
+			errContains: "failed to get replicas",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attributes map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tc.attributes), &attributes))
+			err := PatchWorkload(&v1.Resource{ID: "test", Type: v1.Kubernetes, Attributes: attributes}, fuzzPatcher())
+			var patchErr *PatchError
+			assert.ErrorAs(t, err, &patchErr)
+			assert.ErrorContains(t, err, tc.errContains)
 		})
+	}
+
+	t.Run("nil attributes", func(t *testing.T) {
+		res := &v1.Resource{ID: "test", Type: v1.Kubernetes}
+		err := PatchWorkload(res, &v1.Patcher{Labels: map[string]string{"app": "foo"}})
 		assert.NoError(t, err)
-		assert.Equal(t, "new", resources[0].Attributes["key"])
+		assert.Equal(t, map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "foo"}},
+		}, res.Attributes)
 	})
+}
 
-	t.Run("UnsupportedPatchType", func(t *testing.T) {
-		err := JSONPatch([]v1.Resource{{ID: "test"}}, &v1.Patcher{
-			JSONPatchers: map[string]v1.JSONPatcher{
-				"test": {Type: "unsupported", Payload: []byte(`{"key": "value"}`)},
-			},
-		})
-		assert.Error(t, err)
+func FuzzPatchWorkload(f *testing.F) {
+	f.Add(`{"kind": "Deployment", "metadata": {"name": "foo"}, "spec": {"replicas": 1, "template": {"spec": {"containers": [{"name": "main", "env": [{"name": "REMOVED", "value": "foo"}]}]}}}}`)
+	f.Add(`{"metadata": {"labels": null}, "spec": {"template": {"spec": {"containers": [{"name": 1}]}}}}`)
+	f.Add(`{"spec": {"template": {"spec": {"containers": [null, "foo", {"env": [1, {"name": null}]}]}}}}`)
+	f.Add(`{"spec": {"template": {"metadata": "foo", "spec": {"volumes": "foo", "tolerations": [1]}}}}`)
+	f.Add(`{"spec": {"template": {"spec": {"containers": [{"name": "main", "resources": {"requests": []}, "volumeMounts": 1}]}}}}`)
+	f.Add(`{}`)
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var attributes map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &attributes); err != nil {
+			t.Skip()
+		}
+		err := PatchWorkload(&v1.Resource{ID: "test", Type: v1.Kubernetes, Attributes: attributes}, fuzzPatcher())
+		var patchErr *PatchError
+		if err != nil && !errors.As(err, &patchErr) {
+			t.Errorf("unexpected error type %T: %v", err, err)
+		}
 	})
 }
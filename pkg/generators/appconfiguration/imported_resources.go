@@ -0,0 +1,154 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// importedIDValidators validate the imported IDs of the Terraform resources by the provider name, which
+// is the second part of the kusion ID, e.g. aws in hashicorp:aws:aws_s3_bucket:foo. The validators only
+// reject the IDs obviously wrong, since the formats of the IDs vary among the resource types.
+var importedIDValidators = map[string]func(resourceType, importedID string) error{
+	"aws": validateAWSImportedID,
+}
+
+var awsAccountID = regexp.MustCompile(`^\d{12}$`)
+
+// importWildcard is the suffix of the kusion IDs of the wildcard entries in the imported resources.
+const importWildcard = "*"
+
+// expandImportedResources expands the wildcard entries of the imported resources, whose kusion IDs end with
+// the * to match all the resources with the prefix, to the exact entries of the resources matched. The * in
+// the imported ID of a wildcard entry is replaced by the part of the kusion ID matched by the *, e.g. the entry
+// hashicorp:aws:aws_s3_bucket:logs-* => logs-* imports the resource hashicorp:aws:aws_s3_bucket:logs-app as
+// logs-app. The exact entries take precedence over the wildcard ones, and the longest prefix wins among the
+// wildcard ones. The kusion IDs of the wildcard entries matching no resource are returned as well.
+func expandImportedResources(resources v1.Resources, importedResources map[string]string) (map[string]string, []string, error) {
+	expanded := make(map[string]string, len(importedResources))
+	wildcards := make(map[string]string)
+	for kusionID, importedID := range importedResources {
+		prefix, isWildcard := strings.CutSuffix(kusionID, importWildcard)
+		if !isWildcard {
+			expanded[kusionID] = importedID
+			continue
+		}
+		if strings.Contains(prefix, importWildcard) {
+			return nil, nil, fmt.Errorf("invalid imported resource %s: the * is only supported at the end of the kusion id", kusionID)
+		}
+		if !strings.Contains(importedID, importWildcard) {
+			return nil, nil, fmt.Errorf("invalid imported resource %s: the imported id %q must contain the * "+
+				"to import the resources matched as different ones", kusionID, importedID)
+		}
+		wildcards[prefix] = importedID
+	}
+	if len(wildcards) == 0 {
+		return expanded, nil, nil
+	}
+
+	// the longest prefixes are matched first
+	prefixes := make([]string, 0, len(wildcards))
+	for prefix := range wildcards {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+
+	matched := make(map[string]bool)
+	for _, res := range resources {
+		if _, ok := expanded[res.ID]; ok {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if suffix, ok := strings.CutPrefix(res.ID, prefix); ok {
+				expanded[res.ID] = strings.ReplaceAll(wildcards[prefix], importWildcard, suffix)
+				matched[prefix] = true
+				break
+			}
+		}
+	}
+
+	var unmatched []string
+	for _, prefix := range prefixes {
+		if !matched[prefix] {
+			unmatched = append(unmatched, prefix+importWildcard)
+		}
+	}
+	return expanded, unmatched, nil
+}
+
+// validateImportedID validates the ID in the provider of the resource to import, so that a malformed ID
+// is rejected on generating instead of failing the terraform import.
+func validateImportedID(res *v1.Resource, importedID string) error {
+	if importedID == "" {
+		return errors.New("the imported ID must not be empty")
+	}
+	if strings.TrimSpace(importedID) != importedID {
+		return errors.New("the imported ID must not have leading or trailing spaces")
+	}
+	if res.Type != v1.Terraform {
+		return nil
+	}
+
+	parts := strings.Split(res.ID, ":")
+	if len(parts) != 4 {
+		return nil
+	}
+	resourceType := parts[2]
+	// the terraform address like aws_s3_bucket.foo is a common mistake for the ID in the provider
+	if strings.HasPrefix(importedID, resourceType+".") {
+		return fmt.Errorf("the imported ID looks like a terraform address, the ID of the %s in the provider is expected", resourceType)
+	}
+	if validate, ok := importedIDValidators[parts[1]]; ok {
+		return validate(resourceType, importedID)
+	}
+	return nil
+}
+
+// validateAWSImportedID validates the ARN of the AWS resource, i.e. arn:partition:service:region:account-id:resource,
+// while the IDs other than the ARNs, e.g. the bucket names and the instance IDs, are left to the provider.
+func validateAWSImportedID(_, importedID string) error {
+	if !strings.HasPrefix(importedID, "arn:") {
+		return nil
+	}
+	sections := strings.SplitN(importedID, ":", 6)
+	if len(sections) != 6 {
+		return errors.New("malformed ARN, arn:partition:service:region:account-id:resource is expected")
+	}
+	partition, service, account, resource := sections[1], sections[2], sections[4], sections[5]
+	if !strings.HasPrefix(partition, "aws") {
+		return fmt.Errorf("invalid partition %q of the ARN", partition)
+	}
+	if service == "" {
+		return errors.New("the service of the ARN must not be empty")
+	}
+	if account != "" && !awsAccountID.MatchString(account) {
+		return fmt.Errorf("invalid account ID %q of the ARN, 12 digits are expected", account)
+	}
+	if resource == "" {
+		return errors.New("the resource of the ARN must not be empty")
+	}
+	return nil
+}
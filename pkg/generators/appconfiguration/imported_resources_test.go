@@ -0,0 +1,105 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestValidateImportedID(t *testing.T) {
+	bucket := &v1.Resource{ID: "hashicorp:aws:aws_s3_bucket:foo", Type: v1.Terraform}
+	role := &v1.Resource{ID: "hashicorp:aws:aws_iam_role:foo", Type: v1.Terraform}
+	password := &v1.Resource{ID: "hashicorp:random:random_password:foo", Type: v1.Terraform}
+
+	testcases := []struct {
+		name        string
+		res         *v1.Resource
+		importedID  string
+		errContains string
+	}{
+		{name: "AWS bucket name", res: bucket, importedID: "foo-bucket"},
+		{name: "AWS ARN", res: role, importedID: "arn:aws:iam::123456789012:role/foo"},
+		{name: "AWS China ARN", res: bucket, importedID: "arn:aws-cn:s3:::foo-bucket"},
+		{name: "other provider", res: password, importedID: "arn:whatever"},
+		{name: "empty", res: bucket, importedID: "", errContains: "must not be empty"},
+		{name: "spaces", res: bucket, importedID: " foo-bucket", errContains: "leading or trailing spaces"},
+		{name: "terraform address", res: bucket, importedID: "aws_s3_bucket.foo", errContains: "looks like a terraform address"},
+		{name: "truncated ARN", res: role, importedID: "arn:aws:iam::123456789012", errContains: "malformed ARN"},
+		{name: "ARN partition", res: role, importedID: "arn:azure:iam::123456789012:role/foo", errContains: `invalid partition "azure"`},
+		{name: "ARN account", res: role, importedID: "arn:aws:iam::12345:role/foo", errContains: `invalid account ID "12345"`},
+		{name: "ARN resource", res: role, importedID: "arn:aws:iam::123456789012:", errContains: "resource of the ARN must not be empty"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateImportedID(tc.res, tc.importedID)
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
+		})
+	}
+}
+
+func TestExpandImportedResources(t *testing.T) {
+	resources := v1.Resources{
+		{ID: "hashicorp:aws:aws_s3_bucket:logs-app", Type: v1.Terraform},
+		{ID: "hashicorp:aws:aws_s3_bucket:logs-audit", Type: v1.Terraform},
+		{ID: "hashicorp:aws:aws_s3_bucket:logs-audit-eu", Type: v1.Terraform},
+		{ID: "hashicorp:aws:aws_s3_bucket:data", Type: v1.Terraform},
+	}
+
+	t.Run("prefix matching multiple resources", func(t *testing.T) {
+		expanded, unmatched, err := expandImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:logs-*":       "acme-logs-*",
+			"hashicorp:aws:aws_s3_bucket:logs-audit-*": "arn:aws:s3:::audit-*",
+			"hashicorp:aws:aws_s3_bucket:logs-app":     "legacy-app-logs",
+			"hashicorp:aws:aws_s3_bucket:cache-*":      "cache-*",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			// the exact entry takes precedence over the wildcard one
+			"hashicorp:aws:aws_s3_bucket:logs-app":   "legacy-app-logs",
+			"hashicorp:aws:aws_s3_bucket:logs-audit": "acme-logs-audit",
+			// the longest prefix wins
+			"hashicorp:aws:aws_s3_bucket:logs-audit-eu": "arn:aws:s3:::audit-eu",
+		}, expanded)
+		assert.Equal(t, []string{"hashicorp:aws:aws_s3_bucket:cache-*"}, unmatched)
+	})
+
+	t.Run("exact entries only", func(t *testing.T) {
+		importedResources := map[string]string{"hashicorp:aws:aws_s3_bucket:data": "acme-data"}
+		expanded, unmatched, err := expandImportedResources(resources, importedResources)
+		assert.NoError(t, err)
+		assert.Equal(t, importedResources, expanded)
+		assert.Empty(t, unmatched)
+	})
+
+	t.Run("invalid wildcard entries", func(t *testing.T) {
+		_, _, err := expandImportedResources(resources, map[string]string{
+			"hashicorp:aws:aws_s3_bucket:logs-*": "acme-logs",
+		})
+		assert.ErrorContains(t, err, `the imported id "acme-logs" must contain the *`)
+
+		_, _, err = expandImportedResources(resources, map[string]string{
+			"hashicorp:aws:*:logs-*": "logs-*",
+		})
+		assert.ErrorContains(t, err, "the * is only supported at the end of the kusion id")
+	})
+}
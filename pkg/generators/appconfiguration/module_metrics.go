@@ -0,0 +1,50 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import "time"
+
+// ModuleInvocation is the record of invoking a module in the generation of an app.
+type ModuleInvocation struct {
+	// App is the name of the app generated.
+	App string
+	// Module is the key of the module invoked, e.g. kusionstack/service@v0.1.0.
+	Module string
+	// TraceID is the trace ID passed to the module in the gRPC metadata.
+	TraceID string
+	// Duration is the time taken by the invocation, including starting the plugin and the retries.
+	Duration time.Duration
+	// Err is the failure of the invocation, or nil if it succeeded.
+	Err error
+}
+
+// MetricsSink receives the records of the module invocations. The modules are invoked concurrently, so the
+// implementations must be safe for concurrent use.
+type MetricsSink interface {
+	RecordModuleInvocation(invocation ModuleInvocation)
+}
+
+// noopMetricsSink discards the records of the module invocations.
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) RecordModuleInvocation(ModuleInvocation) {}
+
+// metricsSink returns the metrics sink of the generator, which discards the records by default.
+func (g *appConfigurationGenerator) metricsSink() MetricsSink {
+	if g.metrics == nil {
+		return noopMetricsSink{}
+	}
+	return g.metrics
+}
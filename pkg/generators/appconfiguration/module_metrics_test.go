@@ -0,0 +1,87 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bytedance/mockey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion-module-framework/pkg/module"
+)
+
+type fakeMetricsSink struct {
+	mu      sync.Mutex
+	records []ModuleInvocation
+}
+
+func (s *fakeMetricsSink) RecordModuleInvocation(invocation ModuleInvocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, invocation)
+}
+
+func TestAppConfigurationGenerator_ModuleMetrics(t *testing.T) {
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	sink := &fakeMetricsSink{}
+	g := &appConfigurationGenerator{
+		project: project,
+		stack:   stack,
+		appName: "testapp",
+		app:     appConfig,
+		ws:      buildMockWorkspace(),
+		metrics: sink,
+	}
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		if key == "failing" {
+			return &module.Plugin{Module: &failingModule{}}, nil
+		}
+		return &module.Plugin{Module: &keyedModule{key: key}}, nil
+	}).Build()
+	defer pluginMock.UnPatch()
+
+	keys := []string{"module-a", "module-b"}
+	_, err := g.invokeModules(context.Background(), NewPluginCache(), keys, map[string]moduleConfig{"module-a": {}, "module-b": {}})
+	assert.NoError(t, err)
+	_, err = g.invokeModules(context.Background(), NewPluginCache(), []string{"failing"}, map[string]moduleConfig{"failing": {}})
+	assert.Error(t, err)
+
+	// one record per module invocation
+	sort.Slice(sink.records, func(i, j int) bool {
+		return sink.records[i].Module < sink.records[j].Module
+	})
+	assert.Len(t, sink.records, 3)
+	for i, key := range []string{"failing", "module-a", "module-b"} {
+		record := sink.records[i]
+		assert.Equal(t, key, record.Module)
+		assert.Equal(t, "testapp", record.App)
+		assert.NotEmpty(t, record.TraceID)
+		if key == "failing" {
+			assert.ErrorContains(t, record.Err, "module failed")
+		} else {
+			assert.NoError(t, record.Err)
+		}
+	}
+}
+
+func TestAppConfigurationGenerator_MetricsSinkDefault(t *testing.T) {
+	g := &appConfigurationGenerator{}
+	assert.Equal(t, noopMetricsSink{}, g.metricsSink())
+}
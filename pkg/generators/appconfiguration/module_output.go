@@ -0,0 +1,79 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// The formats of the module output.
+const (
+	moduleOutputJSON = "JSON"
+	moduleOutputYAML = "YAML"
+)
+
+// moduleOutputFormat returns the format of the module output, which is JSON if the output starts
+// with a JSON object or array, otherwise YAML.
+func moduleOutputFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return moduleOutputJSON
+	}
+	return moduleOutputYAML
+}
+
+// unmarshalModuleOutput unmarshals the resource or patcher output by the module into out. The output can be
+// either JSON or YAML. The JSON output is validated strictly before unmarshalled the same way as the YAML, as
+// a malformed JSON document may still be parsed as a valid YAML one. The empty output leaves out unchanged.
+// The error names the module but not the payload, which may contain the data of the secrets.
+func unmarshalModuleOutput(moduleKey, name string, data []byte, out interface{}) error {
+	format := moduleOutputFormat(data)
+	var err error
+	switch {
+	case format == moduleOutputJSON && !json.Valid(data):
+		// decode again for the detailed syntax error
+		var v interface{}
+		err = json.Unmarshal(data, &v)
+	default:
+		err = yaml.Unmarshal(data, out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the %s of module %s as %s: %w", name, moduleKey, format, err)
+	}
+	return nil
+}
+
+// validateModuleResource validates the resource output by the module has the ID, the type and the attributes,
+// so that a malformed resource fails the generation rather than the apply later on.
+func validateModuleResource(moduleKey, name string, res *v1.Resource) error {
+	var reason string
+	switch {
+	case res.ID == "":
+		reason = "empty id"
+	case res.Type == "":
+		reason = fmt.Sprintf("empty type of resource %s", res.ID)
+	case res.Attributes == nil:
+		reason = fmt.Sprintf("nil attributes of resource %s", res.ID)
+	default:
+		return nil
+	}
+	return fmt.Errorf("invalid %s of module %s: %s", name, moduleKey, reason)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestUnmarshalModuleOutput(t *testing.T) {
+	testcases := []struct {
+		name        string
+		data        string
+		expected    *v1.Resource
+		errContains string
+	}{
+		{
+			name: "yaml",
+			data: "id: v1:Namespace:foo\ntype: Kubernetes\nattributes:\n  kind: Namespace\n",
+			expected: &v1.Resource{
+				ID:         "v1:Namespace:foo",
+				Type:       v1.Kubernetes,
+				Attributes: map[string]interface{}{"kind": "Namespace"},
+			},
+		},
+		{
+			name: "json",
+			data: ` {"id": "v1:Namespace:foo", "type": "Kubernetes", "attributes": {"kind": "Namespace"}}`,
+			expected: &v1.Resource{
+				ID:         "v1:Namespace:foo",
+				Type:       v1.Kubernetes,
+				Attributes: map[string]interface{}{"kind": "Namespace"},
+			},
+		},
+		{
+			name:     "empty",
+			data:     " \n",
+			expected: &v1.Resource{},
+		},
+		{
+			name:        "malformed json",
+			data:        `{"id": "v1:Namespace:foo", "password": "s3cr3t",}`,
+			errContains: "failed to unmarshal the resource 0 of module kusionstack/foo@v0.1.0 as JSON",
+		},
+		{
+			name:        "malformed yaml",
+			data:        "password: s3cr3t\nid: [v1:Namespace:foo",
+			errContains: "failed to unmarshal the resource 0 of module kusionstack/foo@v0.1.0 as YAML",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &v1.Resource{}
+			err := unmarshalModuleOutput("kusionstack/foo@v0.1.0", "resource 0", []byte(tc.data), res)
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, res)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+				// the payload may contain the data of the secrets, which is not echoed
+				assert.NotContains(t, err.Error(), "s3cr3t")
+			}
+		})
+	}
+}
+
+func TestValidateModuleResource(t *testing.T) {
+	testcases := []struct {
+		name        string
+		resource    v1.Resource
+		expectedErr string
+	}{
+		{
+			name:     "valid",
+			resource: v1.Resource{ID: "v1:Namespace:foo", Type: v1.Kubernetes, Attributes: map[string]interface{}{}},
+		},
+		{
+			name:        "empty id",
+			resource:    v1.Resource{Type: v1.Kubernetes, Attributes: map[string]interface{}{}},
+			expectedErr: "invalid resource 0 of module kusionstack/foo@v0.1.0: empty id",
+		},
+		{
+			name:        "empty type",
+			resource:    v1.Resource{ID: "v1:Namespace:foo", Attributes: map[string]interface{}{}},
+			expectedErr: "invalid resource 0 of module kusionstack/foo@v0.1.0: empty type of resource v1:Namespace:foo",
+		},
+		{
+			name:        "nil attributes",
+			resource:    v1.Resource{ID: "v1:Namespace:foo", Type: v1.Kubernetes},
+			expectedErr: "invalid resource 0 of module kusionstack/foo@v0.1.0: nil attributes of resource v1:Namespace:foo",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateModuleResource("kusionstack/foo@v0.1.0", "resource 0", &tc.resource)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"kusionstack.io/kusion-module-framework/pkg/module"
+
+	"kusionstack.io/kusion/pkg/util/kfile"
+)
+
+// localModuleDistDir is the directory of the plugin binaries in a module, where `kusion mod push` builds the
+// binaries of the platforms to, e.g. _dist/darwin/arm64/kusion-module-mysql_0.1.0.
+const localModuleDistDir = "_dist"
+
+// pluginStartError is the failure of starting the plugin process of a module, e.g. the process exits before
+// the handshake, which is transient and retried by restarting the plugin.
+type pluginStartError struct {
+	err error
+}
+
+func (e *pluginStartError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pluginStartError) Unwrap() error {
+	return e.err
+}
+
+// pluginModule is the module client of a plugin process started by Kusion, which keeps the recent stderr
+// output of the process and is killed along with the process.
+type pluginModule struct {
+	module.Module
+	client *goplugin.Client
+	stderr *StderrBuffer
+}
+
+// StderrTail returns the recent stderr output of the plugin process.
+func (m *pluginModule) StderrTail() string {
+	return m.stderr.StderrTail()
+}
+
+// kill kills the plugin process of the module.
+func (m *pluginModule) kill() {
+	m.client.Kill()
+}
+
+// killPlugin kills the plugin process of the module. The plugins not started by Kusion, e.g. the fakes in the
+// tests, are killed by the module framework.
+func killPlugin(plugin *module.Plugin) error {
+	if m, ok := plugin.Module.(*pluginModule); ok {
+		m.kill()
+		return nil
+	}
+	return plugin.KillPluginClient()
+}
+
+// newPlugin starts the plugin of the module with the key, see startModulePlugin.
+func (g *appConfigurationGenerator) newPlugin(key string) (*module.Plugin, error) {
+	return startModulePlugin(key, g.localModulePath(key), g.stack.Path)
+}
+
+// splitModuleKey splits the module key in the format of "org/module@version".
+func splitModuleKey(key string) (org, moduleName, version string, err error) {
+	repo, version, found := strings.Cut(key, "@")
+	org, moduleName, ok := strings.Cut(repo, "/")
+	if !found || !ok || org == "" || moduleName == "" || version == "" {
+		return "", "", "", fmt.Errorf("invalid module key %s, which should be in the format of org/module@version, "+
+			"e.g. kusionstack/mysql@v0.1.0", key)
+	}
+	return org, moduleName, version, nil
+}
+
+// installedModuleBinary returns the plugin binary of the module with the key downloaded into the $KUSION_HOME
+// modules directory for the current platform, the same as the module framework, e.g.
+// $KUSION_HOME/modules/kusionstack/mysql/0.1.0/linux/amd64/kusion-module-mysql_0.1.0.
+func installedModuleBinary(key string) (string, error) {
+	org, moduleName, version, err := splitModuleKey(key)
+	if err != nil {
+		return "", err
+	}
+	kusionHomePath, err := kfile.KusionDataFolder()
+	if err != nil {
+		return "", err
+	}
+	binary := filepath.Join(kusionHomePath, "modules", org, moduleName, version, goruntime.GOOS, goruntime.GOARCH,
+		"kusion-module-"+moduleName+"_"+version)
+	if goruntime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	return binary, nil
+}
+
+// localModulePath returns the resolved path of the module if the key refers to a module from a local path
+// dependency, see localModuleKey, or empty otherwise.
+func (g *appConfigurationGenerator) localModulePath(key string) string {
+	name, found := strings.CutPrefix(key, localModuleRepo+"/")
+	if !found || g.dependencies == nil {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "@")
+	d, ok := g.dependencies.Deps.Get(name)
+	if !ok || d.Local == nil {
+		return ""
+	}
+	if d.LocalFullPath != "" {
+		return d.LocalFullPath
+	}
+	if filepath.IsAbs(d.Local.Path) {
+		return d.Local.Path
+	}
+	return filepath.Join(g.stack.Path, d.Local.Path)
+}
+
+// localModuleBinary returns the plugin binary of the module with the key from the local path for the current
+// platform, which is built by the module developer, e.g. with `go build` in the src directory of the module.
+func localModuleBinary(key, modulePath string) (string, error) {
+	_, moduleName, _, err := splitModuleKey(key)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(modulePath, localModuleDistDir, goruntime.GOOS, goruntime.GOARCH)
+	binaries, err := filepath.Glob(filepath.Join(dir, "kusion-module-"+moduleName+"*"))
+	if err != nil {
+		return "", err
+	}
+	if len(binaries) != 1 {
+		return "", fmt.Errorf("expect exactly one plugin binary of local module %s in %s, found %d, "+
+			"please build the module to %s", moduleName, dir, len(binaries),
+			filepath.Join(dir, "kusion-module-"+moduleName))
+	}
+	return binaries[0], nil
+}
+
+// pluginClientOption customizes the client config of a module plugin on top of the one of the module framework.
+type pluginClientOption func(config *goplugin.ClientConfig)
+
+// withPluginStderr copies the stderr output of the plugin process to the writer, in addition to the logger.
+func withPluginStderr(stderr io.Writer) pluginClientOption {
+	return func(config *goplugin.ClientConfig) {
+		config.Stderr = stderr
+	}
+}
+
+// newPluginClient returns the client of the plugin process started from the binary in the stack directory, with
+// the same handshake, plugins and protocol as the module framework and customized by the options.
+func newPluginClient(binary, stackDir string, opts ...pluginClientOption) *goplugin.Client {
+	cmd := exec.Command(binary)
+	cmd.Dir = stackDir
+	config := &goplugin.ClientConfig{
+		HandshakeConfig:  module.HandshakeConfig,
+		Plugins:          module.PluginMap,
+		Cmd:              cmd,
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Logger:           hclog.NewNullLogger(),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return goplugin.NewClient(config)
+}
+
+// startModulePlugin starts the plugin process of the module with the key, and returns the plugin with the
+// module client dispensed from the process. The module from a local path dependency is started from the binary
+// built in the localPath, while the others are started from the binary downloaded into the $KUSION_HOME modules
+// directory. The stderr of the process is kept for the errors of the module.
+func startModulePlugin(key, localPath, stackDir string) (*module.Plugin, error) {
+	var binary string
+	var err error
+	if localPath != "" {
+		binary, err = localModuleBinary(key, localPath)
+	} else {
+		binary, err = installedModuleBinary(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := &StderrBuffer{}
+	client := newPluginClient(binary, stackDir, withPluginStderr(stderr))
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		err = withModuleStderr(fmt.Errorf("start plugin of module %s failed: %w", key, err), key, stderr)
+		return nil, &pluginStartError{err: err}
+	}
+	raw, err := rpcClient.Dispense(module.PluginKey)
+	if err != nil {
+		client.Kill()
+		return nil, &pluginStartError{err: fmt.Errorf("dispense plugin of module %s failed: %w", key, err)}
+	}
+	moduleClient, ok := raw.(module.Module)
+	if !ok {
+		client.Kill()
+		return nil, fmt.Errorf("plugin of module %s is not a module", key)
+	}
+	return &module.Plugin{
+		Module:     &pluginModule{Module: moduleClient, client: client, stderr: stderr},
+		ModuleName: strings.ReplaceAll(strings.Split(key, "@")[0], "/", "-"),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package appconfiguration
+
+import (
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/bytedance/mockey"
+	orderedmap "github.com/elliotchance/orderedmap/v2"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/stretchr/testify/assert"
+	"kcl-lang.io/kpm/pkg/downloader"
+	pkg "kcl-lang.io/kpm/pkg/package"
+	"kusionstack.io/kusion-module-framework/pkg/module"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/util/kfile"
+)
+
+func TestAppConfigurationGenerator_Generate_LocalModule(t *testing.T) {
+	modulePath := t.TempDir()
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("service", pkg.Dependency{
+		Name:          "service",
+		LocalFullPath: modulePath,
+		Source:        downloader.Source{Local: &downloader.Local{Path: "../service"}},
+	})
+	appName, app := buildMockApp()
+	app.Accessories = nil
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project:      project,
+		stack:        stack,
+		appName:      appName,
+		app:          app,
+		ws:           buildMockWorkspace(),
+		dependencies: &pkg.Dependencies{Deps: deps},
+	}
+
+	var started []string
+	startMock := mockey.Mock(startModulePlugin).To(func(key, localPath, stackDir string) (*module.Plugin, error) {
+		started = append(started, localPath)
+		return &module.Plugin{Module: &fakeModule{}}, nil
+	}).Build()
+	killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+	defer func() {
+		startMock.UnPatch()
+		killMock.UnPatch()
+	}()
+
+	spec := &v1.Spec{Resources: []v1.Resource{}}
+	assert.NoError(t, g.Generate(spec))
+	// the local module is started from its directory, instead of the $KUSION_HOME
+	assert.Equal(t, []string{modulePath}, started)
+	assert.Contains(t, spec.Resources.Index(), "apps.kusionstack.io/v1alpha1:PodTransitionRule:fakeNs:default-dev-foo")
+}
+
+func TestLocalModuleBinary(t *testing.T) {
+	modulePath := t.TempDir()
+	key := "local/service@0.0.0-local.00000000"
+	_, err := localModuleBinary(key, modulePath)
+	assert.ErrorContains(t, err, "expect exactly one plugin binary of local module service")
+
+	binaryDir := filepath.Join(modulePath, localModuleDistDir, goruntime.GOOS, goruntime.GOARCH)
+	assert.NoError(t, os.MkdirAll(binaryDir, os.ModePerm))
+	binary := filepath.Join(binaryDir, "kusion-module-service")
+	assert.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755))
+	found, err := localModuleBinary(key, modulePath)
+	assert.NoError(t, err)
+	assert.Equal(t, binary, found)
+}
+
+func TestInstalledModuleBinary(t *testing.T) {
+	kusionHome := t.TempDir()
+	t.Setenv(kfile.EnvKusionHome, kusionHome)
+
+	binary, err := installedModuleBinary("kusionstack/mysql@0.1.0")
+	assert.NoError(t, err)
+	expected := filepath.Join(kusionHome, "modules", "kusionstack/mysql", "0.1.0", goruntime.GOOS, goruntime.GOARCH,
+		"kusion-module-mysql_0.1.0")
+	if goruntime.GOOS == "windows" {
+		expected += ".exe"
+	}
+	assert.Equal(t, expected, binary)
+
+	_, err = installedModuleBinary("mysql")
+	assert.ErrorContains(t, err, "invalid module key mysql")
+}
+
+func TestWithPluginStderr(t *testing.T) {
+	stderr := &StderrBuffer{}
+	config := &goplugin.ClientConfig{HandshakeConfig: module.HandshakeConfig}
+	withPluginStderr(stderr)(config)
+	assert.Same(t, stderr, config.Stderr)
+	assert.Equal(t, module.HandshakeConfig, config.HandshakeConfig)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// ModuleSnapshot is the canonicalized output of a module in the generated Spec, which is stable across
+// generations and suitable for the snapshot (golden file) testing of the module.
+type ModuleSnapshot struct {
+	// Module is the name or the key of the captured module.
+	Module string `json:"module" yaml:"module"`
+	// Resources are the resources generated by the module after patching, sorted by ID.
+	Resources v1.Resources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// Patchers are the patchers returned by the module.
+	Patchers []v1.Patcher `json:"patchers,omitempty" yaml:"patchers,omitempty"`
+}
+
+// nondeterministicFields are the fields which vary across generations, and are removed from the
+// snapshot of the resources.
+var nondeterministicFields = [][]string{
+	{"metadata", "creationTimestamp"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"status"},
+}
+
+// Marshal marshals the snapshot into indented JSON with sorted keys, which can be compared with a golden file.
+func (s *ModuleSnapshot) Marshal() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// capturing reports whether the output of a module is captured.
+func (g *appConfigurationGenerator) capturing() bool {
+	return g.captureModule != "" && g.snapshot != nil
+}
+
+// stampProvenance marks the resource with the key of the captured module generating it, which is used to
+// filter the resources of the module from the generated Spec.
+func (g *appConfigurationGenerator) stampProvenance(res *v1.Resource, moduleKey string) {
+	if !g.capturing() || !moduleMatches(moduleKey, g.captureModule) {
+		return
+	}
+	if res.Extensions == nil {
+		res.Extensions = make(map[string]interface{})
+	}
+	res.Extensions[v1.FieldModuleProvenance] = moduleKey
+}
+
+// capturePatcher records the patcher returned by the captured module.
+func (g *appConfigurationGenerator) capturePatcher(moduleKey string, patcher *v1.Patcher) {
+	if !g.capturing() || !moduleMatches(moduleKey, g.captureModule) {
+		return
+	}
+	g.snapshot.Patchers = append(g.snapshot.Patchers, *patcher)
+}
+
+// captureSnapshot records the canonicalized resources marked with the provenance of the captured module, and
+// removes the provenance from the resources, so the generated Spec is not changed by the capturing.
+func (g *appConfigurationGenerator) captureSnapshot(resources v1.Resources) error {
+	g.snapshot.Module = g.captureModule
+	for i := range resources {
+		if _, ok := resources[i].Extensions[v1.FieldModuleProvenance]; !ok {
+			continue
+		}
+		delete(resources[i].Extensions, v1.FieldModuleProvenance)
+		if len(resources[i].Extensions) == 0 {
+			resources[i].Extensions = nil
+		}
+		res, err := canonicalize(resources[i])
+		if err != nil {
+			return err
+		}
+		g.snapshot.Resources = append(g.snapshot.Resources, res)
+	}
+	sort.SliceStable(g.snapshot.Resources, func(i, j int) bool {
+		return g.snapshot.Resources[i].ID < g.snapshot.Resources[j].ID
+	})
+	return nil
+}
+
+// canonicalize returns a deep copy of the resource with the normalized values, the sorted dependsOn and
+// without the nondeterministic fields.
+func canonicalize(res v1.Resource) (v1.Resource, error) {
+	out, err := json.Marshal(res)
+	if err != nil {
+		return v1.Resource{}, err
+	}
+	var copied v1.Resource
+	if err = json.Unmarshal(out, &copied); err != nil {
+		return v1.Resource{}, err
+	}
+	if copied.Type == v1.Kubernetes && copied.Attributes != nil {
+		for _, field := range nondeterministicFields {
+			unstructured.RemoveNestedField(copied.Attributes, field...)
+		}
+	}
+	sort.Strings(copied.DependsOn)
+	return copied, nil
+}
+
+// moduleMatches reports whether the module key, e.g. kusionstack/service@v0.1.0, matches the module
+// name or key.
+func moduleMatches(moduleKey, module string) bool {
+	if moduleKey == module {
+		return true
+	}
+	repo, _, _ := strings.Cut(moduleKey, "@")
+	return repo == module || repo[strings.LastIndex(repo, "/")+1:] == module
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"testing"
+
+	orderedmap "github.com/elliotchance/orderedmap/v2"
+	"github.com/stretchr/testify/assert"
+	"kcl-lang.io/kpm/pkg/downloader"
+	pkg "kcl-lang.io/kpm/pkg/package"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestAppConfigurationGenerator_Generate_CaptureModule(t *testing.T) {
+	appName, app := buildMockApp()
+
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Version: "1.0.0",
+		Source: downloader.Source{
+			Oci: &downloader.Oci{
+				Repo: "kusionstack/module1",
+			},
+		},
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+
+	m1, m2 := mockPlugin()
+	defer func() {
+		m1.UnPatch()
+		m2.UnPatch()
+	}()
+
+	generate := func() (*v1.Spec, *ModuleSnapshot) {
+		project, stack := buildMockProjectAndStack()
+		snapshot := &ModuleSnapshot{}
+		g := &appConfigurationGenerator{
+			project:       project,
+			stack:         stack,
+			appName:       appName,
+			app:           app,
+			ws:            buildMockWorkspace(),
+			dependencies:  &pkg.Dependencies{Deps: deps},
+			captureModule: "kusionstack/module1",
+			snapshot:      snapshot,
+		}
+		spec := &v1.Spec{Resources: []v1.Resource{}}
+		assert.NoError(t, g.Generate(spec))
+		return spec, snapshot
+	}
+
+	spec, snapshot := generate()
+	assert.Equal(t, "kusionstack/module1", snapshot.Module)
+	assert.Len(t, snapshot.Resources, 1)
+	res := snapshot.Resources[0]
+	assert.Equal(t, "apps.kusionstack.io/v1alpha1:PodTransitionRule:fakeNs:default-dev-foo", res.ID)
+	assert.NotContains(t, res.Extensions, v1.FieldModuleProvenance)
+	// the nondeterministic fields are removed
+	assert.NotContains(t, res.Attributes["metadata"], "creationTimestamp")
+	assert.NotContains(t, res.Attributes, "status")
+
+	// the generated spec is not changed by the capturing
+	for _, r := range spec.Resources {
+		assert.NotContains(t, r.Extensions, v1.FieldModuleProvenance)
+	}
+
+	// the snapshot is stable across generations
+	_, another := generate()
+	expected, err := snapshot.Marshal()
+	assert.NoError(t, err)
+	actual, err := another.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestModuleMatches(t *testing.T) {
+	assert.True(t, moduleMatches("kusionstack/service@v0.1.0", "kusionstack/service@v0.1.0"))
+	assert.True(t, moduleMatches("kusionstack/service@v0.1.0", "kusionstack/service"))
+	assert.True(t, moduleMatches("kusionstack/service@v0.1.0", "service"))
+	assert.False(t, moduleMatches("kusionstack/service@v0.1.0", "kusionstack/service@v0.2.0"))
+	assert.False(t, moduleMatches("kusionstack/service@v0.1.0", "network"))
+}
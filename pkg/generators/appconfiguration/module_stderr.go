@@ -0,0 +1,67 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxStderrTail is the max length of the recent stderr output of a module plugin kept and reported.
+const maxStderrTail = 4096
+
+// StderrTailer is implemented by the module clients able to report the recent stderr output of their
+// plugin processes, which is included in the error of a failed invocation. The module clients of the plugins
+// started by Kusion implement it with the stderr of the process copied to a StderrBuffer.
+type StderrTailer interface {
+	// StderrTail returns the recent stderr output of the plugin process.
+	StderrTail() string
+}
+
+// StderrBuffer is an io.Writer keeping the last maxStderrTail bytes written, which is safe for the
+// concurrent use.
+type StderrBuffer struct {
+	mu   sync.Mutex
+	tail []byte
+}
+
+// Write appends p to the buffer, dropping the oldest bytes beyond the limit.
+func (b *StderrBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > maxStderrTail {
+		b.tail = append([]byte(nil), b.tail[len(b.tail)-maxStderrTail:]...)
+	}
+	return len(p), nil
+}
+
+// StderrTail returns the bytes kept in the buffer.
+func (b *StderrBuffer) StderrTail() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.tail)
+}
+
+// withModuleStderr appends the recent stderr output of the module plugin to the error of the invocation or
+// the start of the plugin, if it is reported.
+func withModuleStderr(err error, key string, tailer StderrTailer) error {
+	tail := strings.TrimSpace(tailer.StderrTail())
+	if tail == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nrecent stderr of module %s:\n%s", err, key, tail)
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/mockey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion-module-framework/pkg/module"
+	"kusionstack.io/kusion-module-framework/pkg/module/proto"
+)
+
+// stderrModule writes to the stderr of its plugin process before failing.
+type stderrModule struct {
+	stderr *StderrBuffer
+}
+
+func (m *stderrModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	fmt.Fprintln(m.stderr, "panic: runtime error: invalid memory address or nil pointer dereference")
+	fmt.Fprintln(m.stderr, "goroutine 1 [running]:")
+	return nil, errors.New("rpc error: code = Unavailable desc = error reading from server: EOF")
+}
+
+func TestAppConfigurationGenerator_ModuleStderr(t *testing.T) {
+	_, appConfig := buildMockApp()
+	project, stack := buildMockProjectAndStack()
+	g := &appConfigurationGenerator{
+		project: project,
+		stack:   stack,
+		appName: "testapp",
+		app:     appConfig,
+		ws:      buildMockWorkspace(),
+	}
+	pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+		if key == "stderr" {
+			// the stderr of the plugin process is copied to the buffer of the module client
+			stderr := &StderrBuffer{}
+			return &module.Plugin{Module: &pluginModule{Module: &stderrModule{stderr: stderr}, stderr: stderr}}, nil
+		}
+		return &module.Plugin{Module: &failingModule{}}, nil
+	}).Build()
+	defer pluginMock.UnPatch()
+
+	_, err := g.invokeModule(context.Background(), NewPluginCache(), "stderr", moduleConfig{})
+	assert.EqualError(t, err, `invoke kusion module: stderr failed. rpc error: code = Unavailable desc = error reading from server: EOF
+recent stderr of module stderr:
+panic: runtime error: invalid memory address or nil pointer dereference
+goroutine 1 [running]:`)
+
+	// the error is kept as is if the module reports no stderr
+	_, err = g.invokeModule(context.Background(), NewPluginCache(), "failing", moduleConfig{})
+	assert.EqualError(t, err, "invoke kusion module: failing failed. module failed")
+}
+
+func TestStderrBuffer(t *testing.T) {
+	b := &StderrBuffer{}
+	fmt.Fprint(b, "head")
+	fmt.Fprint(b, strings.Repeat("x", maxStderrTail-1))
+	fmt.Fprint(b, "end")
+	tail := b.StderrTail()
+	assert.Len(t, tail, maxStderrTail)
+	assert.True(t, strings.HasSuffix(tail, "xend"))
+	assert.False(t, strings.Contains(tail, "head"))
+}
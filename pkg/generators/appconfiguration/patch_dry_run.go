@@ -0,0 +1,113 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// PatchDryRunResult is the result of applying a patcher to the resources of a Spec without modifying them.
+type PatchDryRunResult struct {
+	// Resources are the resources after the patcher is applied.
+	Resources v1.Resources
+	// Diffs are the unified diffs of the attributes of the resources changed by the patcher, keyed by the
+	// resource ID.
+	Diffs map[string]string
+	// Warnings are the parts of the patcher skipped, e.g. the JSON patchers targeting no resources.
+	Warnings []string
+}
+
+// PatchDryRun applies the patcher to the copies of the resources the same way as the generation, i.e. the
+// workload patches by PatchWorkload to the workloads followed by the JSON patchers, and returns the patched
+// resources along with the diffs. The resources passed in are not modified.
+func PatchDryRun(resources v1.Resources, patcher *v1.Patcher) (*PatchDryRunResult, error) {
+	result := &PatchDryRunResult{Diffs: make(map[string]string)}
+	if patcher == nil {
+		patcher = &v1.Patcher{}
+	}
+
+	patched := make(v1.Resources, len(resources))
+	before := make(map[string]string, len(resources))
+	for i := range resources {
+		res, err := resources[i].DeepCopy()
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(res.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		patched[i] = *res
+		before[res.ID] = string(out)
+	}
+
+	// the workload patches are all the patches except the JSON patchers
+	workloadPatcher := *patcher
+	workloadPatcher.JSONPatchers = nil
+	workloadPatcher.JSONPatchSelectors = nil
+	if !reflect.DeepEqual(workloadPatcher, v1.Patcher{}) {
+		workloads := 0
+		for i := range patched {
+			if !isWorkloadResource(&patched[i]) {
+				continue
+			}
+			if err := PatchWorkload(&patched[i], &workloadPatcher); err != nil {
+				return nil, err
+			}
+			workloads++
+		}
+		if workloads == 0 {
+			result.Warnings = append(result.Warnings, "no workload found, the workload patches skipped")
+		}
+	}
+
+	if len(patcher.JSONPatchers) > 0 || len(patcher.JSONPatchSelectors) > 0 {
+		targeted, attributes, warnings, err := dryRunJSONPatch(patched, patcher)
+		if err != nil {
+			return nil, err
+		}
+		result.Warnings = append(result.Warnings, warnings...)
+		// the targeted resources are the copies, which take the patched attributes
+		for _, res := range targeted {
+			res.Attributes = attributes[res.ID]
+		}
+	}
+
+	for _, res := range patched {
+		out, err := yaml.Marshal(res.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if diff := unifiedDiff(res.ID, before[res.ID], string(out)); diff != "" {
+			result.Diffs[res.ID] = diff
+		}
+	}
+	result.Resources = patched
+	return result, nil
+}
+
+// isWorkloadResource reports whether the resource is marked as the workload with the isWorkload extension.
+func isWorkloadResource(res *v1.Resource) bool {
+	switch res.Extensions[isWorkload] {
+	case true, "true":
+		return true
+	default:
+		return false
+	}
+}
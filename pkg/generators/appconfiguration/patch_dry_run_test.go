@@ -0,0 +1,89 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func dryRunResources() v1.Resources {
+	return v1.Resources{
+		{
+			ID:   "apps/v1:Deployment:default:foo",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+			},
+			Extensions: map[string]interface{}{isWorkload: true},
+		},
+		{
+			ID:         "v1:Service:default:foo",
+			Type:       v1.Kubernetes,
+			Attributes: map[string]interface{}{"apiVersion": "v1", "kind": "Service"},
+		},
+	}
+}
+
+func TestPatchDryRun(t *testing.T) {
+	t.Run("WorkloadAndJSONPatches", func(t *testing.T) {
+		resources := dryRunResources()
+		result, err := PatchDryRun(resources, &v1.Patcher{
+			Labels: map[string]string{"team": "infra"},
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"v1:Service:default:foo": {Type: v1.MergePatch, Payload: []byte(`{"spec": {"type": "ClusterIP"}}`)},
+				"missing":                {Type: v1.MergePatch, Payload: []byte(`{"key": "new"}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, result.Resources, 2)
+		assert.Contains(t, result.Diffs["apps/v1:Deployment:default:foo"], "team: infra")
+		assert.Equal(t, `--- v1:Service:default:foo (before)
++++ v1:Service:default:foo (after)
+@@ -1,2 +1,4 @@
+ apiVersion: v1
+ kind: Service
++spec:
++    type: ClusterIP
+`, result.Diffs["v1:Service:default:foo"])
+		assert.Equal(t, []string{"target patch resource missing not found, skipped"}, result.Warnings)
+
+		// the resources passed in are not modified
+		assert.Equal(t, dryRunResources(), resources)
+	})
+
+	t.Run("NoWorkload", func(t *testing.T) {
+		result, err := PatchDryRun(dryRunResources()[1:], &v1.Patcher{
+			Labels: map[string]string{"team": "infra"},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, result.Diffs)
+		assert.Equal(t, []string{"no workload found, the workload patches skipped"}, result.Warnings)
+	})
+
+	t.Run("InvalidPatch", func(t *testing.T) {
+		_, err := PatchDryRun(dryRunResources(), &v1.Patcher{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"v1:Service:default:foo": {Type: v1.JSONPatch, Payload: []byte(`not a patch`)},
+			},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// clusterScopedKinds are the kinds of the built-in Kubernetes resources not in any namespace.
+var clusterScopedKinds = map[string]struct{}{
+	"APIService":                     {},
+	"CSIDriver":                      {},
+	"CSINode":                        {},
+	"CertificateSigningRequest":      {},
+	"ClusterRole":                    {},
+	"ClusterRoleBinding":             {},
+	"ComponentStatus":                {},
+	"CustomResourceDefinition":       {},
+	"FlowSchema":                     {},
+	"IngressClass":                   {},
+	"MutatingWebhookConfiguration":   {},
+	"Namespace":                      {},
+	"Node":                           {},
+	"PersistentVolume":               {},
+	"PriorityClass":                  {},
+	"PriorityLevelConfiguration":     {},
+	"RuntimeClass":                   {},
+	"StorageClass":                   {},
+	"ValidatingWebhookConfiguration": {},
+	"VolumeAttachment":               {},
+}
+
+// defaultNamespaces sets the app namespace to the namespaced Kubernetes resources generated by the modules
+// without a namespace, while the namespace set explicitly in the module output is respected, e.g. for a
+// monitoring resource living in the namespace of the monitoring system. Only the built-in kinds known to be
+// namespaced are defaulted, as the scope of the custom resources is unknown. The ID of a defaulted resource
+// derived from its metadata is renamed accordingly, along with the references to it in the dependencies
+// of the resources and the JSON patchers. As the renamed resources are replaced on the next apply, it is
+// only done if opted in by the DefaultResources of the KubernetesNamespace extension.
+func defaultNamespaces(namespace string, workload *v1.Resource, resources []v1.Resource, patchers []v1.Patcher) {
+	if namespace == "" {
+		return
+	}
+
+	renamed := make(map[string]string)
+	defaultNamespace := func(res *v1.Resource) {
+		if oldID, ok := defaultNamespace(res, namespace); ok {
+			renamed[oldID] = res.ID
+		}
+	}
+	if workload != nil {
+		defaultNamespace(workload)
+	}
+	for i := range resources {
+		defaultNamespace(&resources[i])
+	}
+	if len(renamed) == 0 {
+		return
+	}
+
+	renameDependsOn := func(res *v1.Resource) {
+		for i, id := range res.DependsOn {
+			if newID, ok := renamed[id]; ok {
+				res.DependsOn[i] = newID
+			}
+		}
+	}
+	if workload != nil {
+		renameDependsOn(workload)
+	}
+	for i := range resources {
+		renameDependsOn(&resources[i])
+	}
+	for _, patcher := range patchers {
+		for oldID, newID := range renamed {
+			if jsonPatcher, ok := patcher.JSONPatchers[oldID]; ok {
+				delete(patcher.JSONPatchers, oldID)
+				patcher.JSONPatchers[newID] = jsonPatcher
+			}
+		}
+	}
+}
+
+// defaultNamespace sets the namespace to the resource if it is a namespaced Kubernetes resource without a
+// namespace, and returns the old ID if the ID is renamed.
+func defaultNamespace(res *v1.Resource, namespace string) (string, bool) {
+	if res.Type != v1.Kubernetes || res.Attributes == nil {
+		return "", false
+	}
+	un := &unstructured.Unstructured{Object: res.Attributes}
+	if un.GetNamespace() != "" || !isNamespacedKind(un.GroupVersionKind()) {
+		return "", false
+	}
+	un.SetNamespace(namespace)
+
+	clusterScopedID := strings.Join([]string{un.GetAPIVersion(), un.GetKind(), un.GetName()}, ":")
+	if res.ID != clusterScopedID {
+		return "", false
+	}
+	oldID := res.ID
+	res.ID = strings.Join([]string{un.GetAPIVersion(), un.GetKind(), namespace, un.GetName()}, ":")
+	return oldID, true
+}
+
+// isNamespacedKind reports whether the kind is a built-in Kubernetes kind in a namespace.
+func isNamespacedKind(gvk schema.GroupVersionKind) bool {
+	if _, ok := clusterScopedKinds[gvk.Kind]; ok {
+		return false
+	}
+	return scheme.Scheme.Recognizes(gvk)
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytedance/mockey"
+	orderedmap "github.com/elliotchance/orderedmap/v2"
+	"github.com/stretchr/testify/assert"
+	pkg "kcl-lang.io/kpm/pkg/package"
+
+	"kusionstack.io/kusion-module-framework/pkg/module"
+	"kusionstack.io/kusion-module-framework/pkg/module/proto"
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+func configMap(id, namespace, name string) v1.Resource {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return v1.Resource{
+		ID:   id,
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata,
+		},
+	}
+}
+
+// namespacedModule generates a config map in its own namespace and a config map without namespace.
+type namespacedModule struct{}
+
+func (m *namespacedModule) Generate(_ context.Context, _ *proto.GeneratorRequest) (*proto.GeneratorResponse, error) {
+	response := &proto.GeneratorResponse{}
+	for _, res := range []v1.Resource{
+		configMap("v1:ConfigMap:monitoring:agent", "monitoring", "agent"),
+		configMap("v1:ConfigMap:settings", "", "settings"),
+	} {
+		response.Resources = append(response.Resources, []byte(jsonutil.Marshal2String(res)))
+	}
+	return response, nil
+}
+
+func TestDefaultNamespaces(t *testing.T) {
+	t.Run("default and respect namespaces", func(t *testing.T) {
+		workload := configMap("v1:ConfigMap:workload", "", "workload")
+		workload.DependsOn = []string{"v1:ConfigMap:settings"}
+		resources := []v1.Resource{
+			configMap("v1:ConfigMap:monitoring:agent", "monitoring", "agent"),
+			configMap("v1:ConfigMap:settings", "", "settings"),
+			configMap("custom-id", "", "custom"),
+			{
+				ID:   "rbac.authorization.k8s.io/v1:ClusterRole:reader",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind":       "ClusterRole",
+					"metadata":   map[string]interface{}{"name": "reader"},
+				},
+			},
+			{
+				ID:   "example.com/v1:Widget:widget",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "example.com/v1",
+					"kind":       "Widget",
+					"metadata":   map[string]interface{}{"name": "widget"},
+				},
+			},
+		}
+		patchers := []v1.Patcher{{
+			JSONPatchers: map[string]v1.JSONPatcher{
+				"v1:ConfigMap:settings": {Type: v1.MergePatch, Payload: []byte(`{"data": {"key": "value"}}`)},
+			},
+		}}
+
+		defaultNamespaces("app", &workload, resources, patchers)
+
+		assert.Equal(t, "v1:ConfigMap:app:workload", workload.ID)
+		assert.Equal(t, []string{"v1:ConfigMap:app:settings"}, workload.DependsOn)
+		// the namespace set by the module is respected
+		assert.Equal(t, "v1:ConfigMap:monitoring:agent", resources[0].ID)
+		assert.Equal(t, "monitoring", mapToUnstructured(resources[0].Attributes).GetNamespace())
+		assert.Equal(t, "v1:ConfigMap:app:settings", resources[1].ID)
+		assert.Equal(t, "app", mapToUnstructured(resources[1].Attributes).GetNamespace())
+		// the ID not derived from the metadata is kept
+		assert.Equal(t, "custom-id", resources[2].ID)
+		assert.Equal(t, "app", mapToUnstructured(resources[2].Attributes).GetNamespace())
+		// the cluster-scoped and custom resources are untouched
+		assert.Empty(t, mapToUnstructured(resources[3].Attributes).GetNamespace())
+		assert.Empty(t, mapToUnstructured(resources[4].Attributes).GetNamespace())
+		assert.Contains(t, patchers[0].JSONPatchers, "v1:ConfigMap:app:settings")
+		assert.NotContains(t, patchers[0].JSONPatchers, "v1:ConfigMap:settings")
+	})
+
+	t.Run("cluster-scoped app", func(t *testing.T) {
+		resources := []v1.Resource{configMap("v1:ConfigMap:settings", "", "settings")}
+		defaultNamespaces("", nil, resources, nil)
+		assert.Equal(t, "v1:ConfigMap:settings", resources[0].ID)
+		assert.Empty(t, mapToUnstructured(resources[0].Attributes).GetNamespace())
+	})
+}
+
+func TestAppConfigurationGenerator_Generate_ResourceNamespace(t *testing.T) {
+	deps := orderedmap.NewOrderedMap[string, pkg.Dependency]()
+	deps.Set("port", pkg.Dependency{
+		Name:    "port",
+		Version: "1.0.0",
+	})
+	deps.Set("service", pkg.Dependency{
+		Name:    "service",
+		Version: "1.0.0",
+	})
+
+	generate := func(t *testing.T, extensions []*v1.Extension) v1.Resources {
+		appName, app := buildMockApp()
+		project, stack := buildMockProjectAndStack()
+		project.Extensions = extensions
+		g := &appConfigurationGenerator{
+			project:      project,
+			stack:        stack,
+			appName:      appName,
+			app:          app,
+			ws:           buildMockWorkspace(),
+			dependencies: &pkg.Dependencies{Deps: deps},
+		}
+		workloadKey, err := parseModuleKey(g.app.Workload, g.dependencies, g.moduleVersionOverrides())
+		assert.NoError(t, err)
+
+		pluginMock := mockey.Mock(startModulePlugin).To(func(key string) (*module.Plugin, error) {
+			if key == workloadKey {
+				return &module.Plugin{Module: &fakeModule{}}, nil
+			}
+			return &module.Plugin{Module: &namespacedModule{}}, nil
+		}).Build()
+		killMock := mockey.Mock((*module.Plugin).KillPluginClient).Return(nil).Build()
+		defer func() {
+			pluginMock.UnPatch()
+			killMock.UnPatch()
+		}()
+
+		spec := &v1.Spec{Resources: []v1.Resource{}}
+		assert.NoError(t, g.Generate(spec))
+		return spec.Resources
+	}
+
+	t.Run("default namespaces opted in", func(t *testing.T) {
+		index := generate(t, []*v1.Extension{{
+			Kind:          v1.KubernetesNamespace,
+			KubeNamespace: v1.KubeNamespaceExtension{Namespace: "{project}", DefaultResources: true},
+		}}).Index()
+		assert.Contains(t, index, "v1:ConfigMap:monitoring:agent")
+		assert.Equal(t, "monitoring", mapToUnstructured(index["v1:ConfigMap:monitoring:agent"].Attributes).GetNamespace())
+		assert.Contains(t, index, "v1:ConfigMap:testproject:settings")
+		assert.Equal(t, "testproject", mapToUnstructured(index["v1:ConfigMap:testproject:settings"].Attributes).GetNamespace())
+	})
+
+	t.Run("namespaces and IDs kept by default", func(t *testing.T) {
+		index := generate(t, nil).Index()
+		assert.Contains(t, index, "v1:ConfigMap:monitoring:agent")
+		assert.Contains(t, index, "v1:ConfigMap:settings")
+		assert.Empty(t, mapToUnstructured(index["v1:ConfigMap:settings"].Attributes).GetNamespace())
+	})
+}
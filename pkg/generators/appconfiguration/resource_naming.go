@@ -0,0 +1,320 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// NamingPolicy rewrites the names of the Kubernetes resources generated by an app to conform to the naming
+// conventions of an organization, e.g. a prefix, the casing or a length limit.
+type NamingPolicy interface {
+	// Name returns the name conforming to the policy of the resource of the kind in the namespace, which is
+	// the name of the namespace itself for the Namespace.
+	Name(kind, namespace, name string) string
+}
+
+// NoopNamingPolicy keeps the names of the resources as they are.
+type NoopNamingPolicy struct{}
+
+func (NoopNamingPolicy) Name(_, _, name string) string { return name }
+
+// getNamingPolicy returns the naming policy of the generator, which keeps the names by default.
+func (g *appConfigurationGenerator) getNamingPolicy() NamingPolicy {
+	if g.namingPolicy == nil {
+		return NoopNamingPolicy{}
+	}
+	return g.namingPolicy
+}
+
+// namingReference is the path in the attributes of a resource to the name of another resource of the kind
+// in the same namespace. The path segments ending with [] are the lists whose items are all followed.
+type namingReference struct {
+	kind string
+	path []string
+}
+
+// podSpecReferences are the references in the pod spec to the resources renamed by the naming policy.
+var podSpecReferences = []namingReference{
+	{kind: "ConfigMap", path: []string{"volumes[]", "configMap", "name"}},
+	{kind: "Secret", path: []string{"volumes[]", "secret", "secretName"}},
+	{kind: "PersistentVolumeClaim", path: []string{"volumes[]", "persistentVolumeClaim", "claimName"}},
+	{kind: "ConfigMap", path: []string{"volumes[]", "projected", "sources[]", "configMap", "name"}},
+	{kind: "Secret", path: []string{"volumes[]", "projected", "sources[]", "secret", "name"}},
+	{kind: "Secret", path: []string{"imagePullSecrets[]", "name"}},
+	{kind: "ServiceAccount", path: []string{"serviceAccountName"}},
+	{kind: "ConfigMap", path: []string{"containers[]", "envFrom[]", "configMapRef", "name"}},
+	{kind: "Secret", path: []string{"containers[]", "envFrom[]", "secretRef", "name"}},
+	{kind: "ConfigMap", path: []string{"containers[]", "env[]", "valueFrom", "configMapKeyRef", "name"}},
+	{kind: "Secret", path: []string{"containers[]", "env[]", "valueFrom", "secretKeyRef", "name"}},
+	{kind: "ConfigMap", path: []string{"initContainers[]", "envFrom[]", "configMapRef", "name"}},
+	{kind: "Secret", path: []string{"initContainers[]", "envFrom[]", "secretRef", "name"}},
+	{kind: "ConfigMap", path: []string{"initContainers[]", "env[]", "valueFrom", "configMapKeyRef", "name"}},
+	{kind: "Secret", path: []string{"initContainers[]", "env[]", "valueFrom", "secretKeyRef", "name"}},
+}
+
+// podSpecPaths are the paths to the pod spec in the attributes of the workload kinds.
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// kusionLabelPrefix is the prefix of the label keys owned by Kusion. The values of these labels equal to the
+// names of the renamed workloads are rewritten, so that the selectors keep matching the pods, while the labels
+// owned by the users are never touched.
+const kusionLabelPrefix = "kusion.io/"
+
+// kindReferences are the references to the resources renamed by the naming policy in the attributes of
+// the kinds other than the workloads.
+var kindReferences = map[string][]namingReference{
+	"Ingress": {
+		{kind: "Service", path: []string{"spec", "defaultBackend", "service", "name"}},
+		{kind: "Service", path: []string{"spec", "rules[]", "http", "paths[]", "backend", "service", "name"}},
+		{kind: "Secret", path: []string{"spec", "tls[]", "secretName"}},
+	},
+	"RoleBinding": {
+		{kind: "ServiceAccount", path: []string{"subjects[]", "name"}},
+	},
+}
+
+// applyNamingPolicy renames the Kubernetes resources by the naming policy and validates the new names
+// against the DNS rules. The namespaces renamed are set to the resources in them, and the IDs derived from
+// the metadata are renamed along with the references to the renamed resources in the dependencies and the
+// attributes of the resources, including the labels owned by Kusion selecting the renamed workloads. It returns
+// the namespaces renamed.
+func applyNamingPolicy(policy NamingPolicy, resources v1.Resources) (map[string]string, error) {
+	if _, ok := policy.(NoopNamingPolicy); ok {
+		return nil, nil
+	}
+
+	// the namespaces are renamed first, so that the resources are renamed in their new namespaces
+	renamedNamespaces := make(map[string]string)
+	for i := range resources {
+		un, ok := kubernetesObject(&resources[i])
+		if !ok || un.GetKind() != "Namespace" {
+			continue
+		}
+		if newName := policy.Name("Namespace", "", un.GetName()); newName != un.GetName() {
+			renamedNamespaces[un.GetName()] = newName
+		}
+	}
+
+	// renamedNames maps the kind, the old namespace and the old name to the new name
+	renamedNames := make(map[string]string)
+	// renamedWorkloads maps the old namespace and the old name of the workloads of any kind to the new name
+	renamedWorkloads := make(map[string]string)
+	renamedIDs := make(map[string]string)
+	oldNamespaces := make([]string, len(resources))
+	for i := range resources {
+		res := &resources[i]
+		un, ok := kubernetesObject(res)
+		if !ok {
+			continue
+		}
+		oldNamespace, oldName := un.GetNamespace(), un.GetName()
+		oldNamespaces[i] = oldNamespace
+		newNamespace, newName := oldNamespace, oldName
+		if renamed, ok := renamedNamespaces[oldNamespace]; ok {
+			newNamespace = renamed
+		}
+		if un.GetKind() == "Namespace" {
+			if renamed, ok := renamedNamespaces[oldName]; ok {
+				newName = renamed
+			}
+		} else {
+			newName = policy.Name(un.GetKind(), newNamespace, oldName)
+		}
+		if newNamespace == oldNamespace && newName == oldName {
+			continue
+		}
+		if err := validateResourceName(un.GetKind(), newName); err != nil {
+			return nil, fmt.Errorf("naming policy renames resource %s to %q: %w", res.ID, newName, err)
+		}
+		un.SetNamespace(newNamespace)
+		un.SetName(newName)
+		renamedNames[namingKey(un.GetKind(), oldNamespace, oldName)] = newName
+		if _, ok := podSpecPaths[un.GetKind()]; ok {
+			workloadKey := namingKey("", oldNamespace, oldName)
+			if _, ok := renamedWorkloads[workloadKey]; !ok {
+				renamedWorkloads[workloadKey] = newName
+			}
+		}
+
+		if res.ID == kubernetesResourceID(un.GetAPIVersion(), un.GetKind(), oldNamespace, oldName) {
+			oldID := res.ID
+			res.ID = kubernetesResourceID(un.GetAPIVersion(), un.GetKind(), newNamespace, newName)
+			renamedIDs[oldID] = res.ID
+		}
+	}
+	if len(renamedNames) == 0 {
+		return renamedNamespaces, nil
+	}
+
+	for i := range resources {
+		res := &resources[i]
+		for j, id := range res.DependsOn {
+			if newID, ok := renamedIDs[id]; ok {
+				res.DependsOn[j] = newID
+			}
+		}
+		un, ok := kubernetesObject(res)
+		if !ok {
+			continue
+		}
+		// the names referenced are looked up in the old namespace of the referrer
+		namespace := oldNamespaces[i]
+		references := append([]namingReference{}, kindReferences[un.GetKind()]...)
+		if podSpecPath, ok := podSpecPaths[un.GetKind()]; ok {
+			for _, ref := range podSpecReferences {
+				references = append(references, namingReference{
+					kind: ref.kind,
+					path: append(append([]string{}, podSpecPath...), ref.path...),
+				})
+			}
+		}
+		for _, ref := range references {
+			renameReference(res.Attributes, ref.path, func(name string) string {
+				if newName, ok := renamedNames[namingKey(ref.kind, namespace, name)]; ok {
+					return newName
+				}
+				return name
+			})
+		}
+		for _, path := range labelPaths(un.GetKind()) {
+			renameWorkloadLabels(res.Attributes, path, namespace, renamedWorkloads)
+		}
+	}
+	return renamedNamespaces, nil
+}
+
+// labelPaths returns the paths to the labels selecting or labeling the pods of the workloads in the
+// attributes of the kind.
+func labelPaths(kind string) [][]string {
+	if kind == "Service" {
+		return [][]string{{"spec", "selector"}}
+	}
+	podSpecPath, ok := podSpecPaths[kind]
+	if !ok {
+		return nil
+	}
+	podLabelsPath := append(append([]string{}, podSpecPath[:len(podSpecPath)-1]...), "metadata", "labels")
+	return [][]string{{"spec", "selector", "matchLabels"}, podLabelsPath}
+}
+
+// renameWorkloadLabels rewrites the values of the labels owned by Kusion at the path of the object, which
+// equal to the old names of the renamed workloads in the namespace.
+func renameWorkloadLabels(obj map[string]interface{}, path []string, namespace string, renamedWorkloads map[string]string) {
+	value, found, err := unstructured.NestedFieldNoCopy(obj, path...)
+	if err != nil || !found {
+		return
+	}
+	labels, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, label := range labels {
+		name, ok := label.(string)
+		if !ok || !strings.HasPrefix(key, kusionLabelPrefix) {
+			continue
+		}
+		if newName, ok := renamedWorkloads[namingKey("", namespace, name)]; ok {
+			labels[key] = newName
+		}
+	}
+}
+
+// kubernetesObject returns the unstructured object of the attributes of the Kubernetes resource.
+func kubernetesObject(res *v1.Resource) (*unstructured.Unstructured, bool) {
+	if res.Type != v1.Kubernetes || res.Attributes == nil {
+		return nil, false
+	}
+	un := &unstructured.Unstructured{Object: res.Attributes}
+	if un.GetName() == "" {
+		return nil, false
+	}
+	return un, true
+}
+
+// kubernetesResourceID returns the ID of the Kubernetes resource derived from its metadata.
+func kubernetesResourceID(apiVersion, kind, namespace, name string) string {
+	if namespace == "" {
+		return strings.Join([]string{apiVersion, kind, name}, ":")
+	}
+	return strings.Join([]string{apiVersion, kind, namespace, name}, ":")
+}
+
+func namingKey(kind, namespace, name string) string {
+	return strings.Join([]string{kind, namespace, name}, "/")
+}
+
+// validateResourceName validates the name of the resource of the kind against the DNS rules, i.e. the
+// Namespace names are DNS labels, the Service names are DNS-1035 labels, and the others are DNS subdomains.
+func validateResourceName(kind, name string) error {
+	var errs []string
+	switch kind {
+	case "Namespace":
+		errs = validation.IsDNS1123Label(name)
+	case "Service":
+		errs = validation.IsDNS1035Label(name)
+	default:
+		errs = validation.IsDNS1123Subdomain(name)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid name: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// renameReference replaces the string at the path in the object by the rename function, following all the
+// items of the lists at the path segments ending with [].
+func renameReference(obj map[string]interface{}, path []string, rename func(string) string) {
+	if len(path) == 0 || obj == nil {
+		return
+	}
+	key, isList := strings.CutSuffix(path[0], "[]")
+	value, ok := obj[key]
+	if !ok {
+		return
+	}
+	if isList {
+		items, _ := value.([]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				renameReference(m, path[1:], rename)
+			}
+		}
+		return
+	}
+	if len(path) == 1 {
+		if name, ok := value.(string); ok {
+			obj[key] = rename(name)
+		}
+		return
+	}
+	if m, ok := value.(map[string]interface{}); ok {
+		renameReference(m, path[1:], rename)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 KusionStack Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appconfiguration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// prefixNamingPolicy enforces the prefix and the lowercase names.
+type prefixNamingPolicy struct {
+	prefix string
+}
+
+func (p prefixNamingPolicy) Name(_, _, name string) string {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, p.prefix) {
+		return name
+	}
+	return p.prefix + name
+}
+
+func namingResources() v1.Resources {
+	return v1.Resources{
+		{
+			ID:   "v1:Namespace:foo",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": "foo"},
+			},
+		},
+		{
+			ID:   "apps/v1:Deployment:foo:Web",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "Web", "namespace": "foo"},
+				"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"kusion.io/workload-name": "Web", "app": "Web"},
+				}, "spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name": "web",
+						"env": []interface{}{map[string]interface{}{
+							"name": "PASSWORD",
+							"valueFrom": map[string]interface{}{
+								"secretKeyRef": map[string]interface{}{"name": "Web-Secret", "key": "password"},
+							},
+						}},
+					}},
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "Web-Config"}},
+						map[string]interface{}{"name": "external", "configMap": map[string]interface{}{"name": "external"}},
+					},
+				}}},
+			},
+			DependsOn: []string{"v1:Namespace:foo", "v1:ConfigMap:foo:Web-Config"},
+		},
+		{
+			ID:   "v1:Service:foo:Web",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "Web", "namespace": "foo"},
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{"kusion.io/workload-name": "Web", "app": "Web"},
+				},
+			},
+		},
+		{
+			ID:   "v1:ConfigMap:foo:Web-Config",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "Web-Config", "namespace": "foo"},
+			},
+		},
+		{
+			ID:   "v1:Secret:foo:Web-Secret",
+			Type: v1.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": "Web-Secret", "namespace": "foo"},
+			},
+		},
+		{
+			ID:         "hashicorp:aws:aws_db_instance:Web",
+			Type:       v1.Terraform,
+			Attributes: map[string]interface{}{"identifier": "Web"},
+		},
+	}
+}
+
+func TestApplyNamingPolicy(t *testing.T) {
+	t.Run("PrefixAndLowercase", func(t *testing.T) {
+		resources := namingResources()
+		renamedNamespaces, err := applyNamingPolicy(prefixNamingPolicy{prefix: "acme-"}, resources)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "acme-foo"}, renamedNamespaces)
+
+		var ids []string
+		for _, res := range resources {
+			ids = append(ids, res.ID)
+		}
+		assert.Equal(t, []string{
+			"v1:Namespace:acme-foo",
+			"apps/v1:Deployment:acme-foo:acme-web",
+			"v1:Service:acme-foo:acme-web",
+			"v1:ConfigMap:acme-foo:acme-web-config",
+			"v1:Secret:acme-foo:acme-web-secret",
+			"hashicorp:aws:aws_db_instance:Web",
+		}, ids)
+
+		deployment := resources[1]
+		assert.Equal(t, []string{"v1:Namespace:acme-foo", "v1:ConfigMap:acme-foo:acme-web-config"}, deployment.DependsOn)
+		assert.Equal(t, map[string]interface{}{"name": "acme-web", "namespace": "acme-foo"}, deployment.Attributes["metadata"])
+		template := deployment.Attributes["spec"].(map[string]interface{})["template"].(map[string]interface{})
+		// only the labels owned by Kusion are rewritten along with the renamed workload
+		expectedLabels := map[string]interface{}{"kusion.io/workload-name": "acme-web", "app": "Web"}
+		assert.Equal(t, expectedLabels, template["metadata"].(map[string]interface{})["labels"])
+		assert.Equal(t, expectedLabels, resources[2].Attributes["spec"].(map[string]interface{})["selector"])
+		podSpec := template["spec"].(map[string]interface{})
+		volumes := podSpec["volumes"].([]interface{})
+		assert.Equal(t, "acme-web-config", volumes[0].(map[string]interface{})["configMap"].(map[string]interface{})["name"])
+		// the references to the resources not generated are kept
+		assert.Equal(t, "external", volumes[1].(map[string]interface{})["configMap"].(map[string]interface{})["name"])
+		env := podSpec["containers"].([]interface{})[0].(map[string]interface{})["env"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "acme-web-secret", env["valueFrom"].(map[string]interface{})["secretKeyRef"].(map[string]interface{})["name"])
+
+		// the non-Kubernetes resources are kept
+		assert.Equal(t, map[string]interface{}{"identifier": "Web"}, resources[5].Attributes)
+	})
+
+	t.Run("Noop", func(t *testing.T) {
+		resources := namingResources()
+		renamedNamespaces, err := applyNamingPolicy(NoopNamingPolicy{}, resources)
+		assert.NoError(t, err)
+		assert.Empty(t, renamedNamespaces)
+		assert.Equal(t, namingResources(), resources)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		_, err := applyNamingPolicy(prefixNamingPolicy{prefix: strings.Repeat("a", 250)}, namingResources())
+		assert.ErrorContains(t, err, "naming policy renames resource v1:Namespace:foo")
+	})
+}
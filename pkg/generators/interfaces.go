@@ -1,6 +1,8 @@
 package generators
 
 import (
+	"context"
+
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 )
 
@@ -11,5 +13,13 @@ type SpecGenerator interface {
 	Generate(intent *v1.Spec) error
 }
 
+// ContextSpecGenerator is a SpecGenerator which can be cancelled by the context, e.g. the generator invoking
+// the modules which may take long.
+type ContextSpecGenerator interface {
+	SpecGenerator
+	// GenerateWithContext performs the intent generate operation, which is aborted once the ctx is done.
+	GenerateWithContext(ctx context.Context, intent *v1.Spec) error
+}
+
 // NewSpecGeneratorFunc is a function that returns a SpecGenerator.
 type NewSpecGeneratorFunc func() (SpecGenerator, error)
@@ -0,0 +1,66 @@
+package orderedresources
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// WriteDOT writes the dependency graph of the resources in the Graphviz DOT format, where each resource is
+// a node labeled with its kind and name, and each dependency in the dependsOn of the resources, e.g. the
+// ones computed by the ordering, is an edge from the resource to the resource it depends on. The resources
+// not in the Spec but depended on are drawn dashed.
+func WriteDOT(w io.Writer, resources v1.Resources) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph resources {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box];")
+
+	index := resources.Index()
+	missing := make(map[string]bool)
+	for i := range resources {
+		res := &resources[i]
+		fmt.Fprintf(bw, "  %s [label=%s];\n", dotQuote(res.ID), dotQuote(resourceLabel(res)))
+		for _, dep := range res.DependsOn {
+			if _, ok := index[dep]; !ok && !missing[dep] {
+				missing[dep] = true
+				fmt.Fprintf(bw, "  %s [label=%s, style=dashed];\n", dotQuote(dep), dotQuote(dep))
+			}
+		}
+	}
+	for _, res := range resources {
+		for _, dep := range res.DependsOn {
+			fmt.Fprintf(bw, "  %s -> %s [label=\"depends on\"];\n", dotQuote(res.ID), dotQuote(dep))
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// resourceLabel returns the kind and name of the resource, which are taken from the attributes of the
+// Kubernetes resource, or the last two parts of the ID of the others, e.g. the type and name of the
+// Terraform resource.
+func resourceLabel(res *v1.Resource) string {
+	if res.Type == v1.Kubernetes && res.Attributes != nil {
+		un := &unstructured.Unstructured{Object: res.Attributes}
+		if un.GetKind() != "" && un.GetName() != "" {
+			return un.GetKind() + "/" + un.GetName()
+		}
+	}
+	parts := strings.Split(res.ID, ":")
+	if len(parts) < 2 {
+		return res.ID
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// dotQuote returns the string quoted as a DOT ID.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
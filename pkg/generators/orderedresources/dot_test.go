@@ -0,0 +1,43 @@
+package orderedresources
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestWriteDOT(t *testing.T) {
+	orderedGenerator, err := NewOrderedResourcesGenerator()
+	assert.NoError(t, err)
+	spec := genOldSpec()
+	assert.NoError(t, orderedGenerator.Generate(spec))
+	spec.Resources = append(spec.Resources, v1.Resource{
+		ID:        "hashicorp:aws:aws_db_instance:db",
+		Type:      v1.Terraform,
+		DependsOn: []string{"external"},
+	})
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDOT(&buf, spec.Resources))
+	assert.Equal(t, `digraph resources {
+  rankdir=LR;
+  node [shape=box];
+  "apps/v1:Deployment:foo:bar" [label="Deployment/bar"];
+  "v1:Service:foo:bar" [label="Service/bar"];
+  "v1:Namespace:foo" [label="Namespace/foo"];
+  "hashicorp:aws:aws_db_instance:db" [label="aws_db_instance/db"];
+  "external" [label="external", style=dashed];
+  "apps/v1:Deployment:foo:bar" -> "v1:Namespace:foo" [label="depends on"];
+  "apps/v1:Deployment:foo:bar" -> "v1:Service:foo:bar" [label="depends on"];
+  "v1:Service:foo:bar" -> "v1:Namespace:foo" [label="depends on"];
+  "hashicorp:aws:aws_db_instance:db" -> "external" [label="depends on"];
+}
+`, buf.String())
+}
+
+func TestDOTQuote(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c"`, dotQuote(`a"b\c`))
+}
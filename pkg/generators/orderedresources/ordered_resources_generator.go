@@ -2,6 +2,8 @@ package orderedresources
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	"github.com/jinzhu/copier"
 	apiv1 "kusionstack.io/kusion-api-go/api.kusion.io/v1"
@@ -33,12 +35,21 @@ func NewOrderedResourcesGeneratorFunc(multipleOrderedKinds ...[]string) generato
 	}
 }
 
-// Generate inject the dependsOn of resources in a specified order.
+// Generate inject the dependsOn of resources in a specified order. The inferred dependencies suppressed by
+// the no-implicit-deps extension are removed, so that users can correct the ordering when the inference gets
+// it wrong. The dependencies declared by the depends-on extension are added by ResolveDependsOn once all the
+// apps are generated, as they may refer to the resources of the apps generated later.
 func (g *orderedResourcesGenerator) Generate(itt *v1.Spec) error {
 	if itt.Resources == nil {
 		itt.Resources = make(v1.Resources, 0)
 	}
 
+	// Record the dependencies declared before the ordering, which are never suppressed.
+	declared := make(map[string][]string, len(itt.Resources))
+	for _, res := range itt.Resources {
+		declared[res.ID] = append([]string(nil), res.DependsOn...)
+	}
+
 	// In Kusion, the type of `Resources` being passed around is internally defined,
 	// so here we are converting the `Resources` type in `kusionstack.io/kusion-api-go` into
 	// the internally defined type.
@@ -58,5 +69,94 @@ func (g *orderedResourcesGenerator) Generate(itt *v1.Spec) error {
 
 	copier.Copy(&itt.Resources, &orderedResources)
 
+	return suppressDependencies(itt.Resources, declared)
+}
+
+// suppressDependencies removes the inferred dependencies of the resources suppressed by the no-implicit-deps
+// extension, except the declared ones.
+func suppressDependencies(resources v1.Resources, declared map[string][]string) error {
+	for i := range resources {
+		res := &resources[i]
+
+		suppressAll, suppressed, err := parseNoImplicitDeps(res.Extensions[v1.FieldNoImplicitDeps])
+		if err != nil {
+			return fmt.Errorf("invalid %s of resource %s: %w", v1.FieldNoImplicitDeps, res.ID, err)
+		}
+		if suppressAll {
+			res.DependsOn = declared[res.ID]
+		} else if len(suppressed) != 0 {
+			var dependsOn []string
+			for _, id := range res.DependsOn {
+				if slices.Contains(suppressed, id) && !slices.Contains(declared[res.ID], id) {
+					continue
+				}
+				dependsOn = append(dependsOn, id)
+			}
+			res.DependsOn = dependsOn
+		}
+	}
+	return nil
+}
+
+// ResolveDependsOn adds the dependencies declared by the depends-on extension to the resources, which should
+// be called after all the apps are generated, so that the resources of any app can be depended on.
+func ResolveDependsOn(resources v1.Resources) error {
+	index := resources.Index()
+	for i := range resources {
+		res := &resources[i]
+		explicit, err := toStringSlice(res.Extensions[v1.FieldDependsOn])
+		if err != nil {
+			return fmt.Errorf("invalid %s of resource %s: %w", v1.FieldDependsOn, res.ID, err)
+		}
+		for _, id := range explicit {
+			if index[id] == nil {
+				return fmt.Errorf("resource %s depends on the resource %s not found", res.ID, id)
+			}
+			if id == res.ID {
+				return fmt.Errorf("resource %s depends on itself", res.ID)
+			}
+			if !slices.Contains(res.DependsOn, id) {
+				res.DependsOn = append(res.DependsOn, id)
+			}
+		}
+	}
 	return nil
 }
+
+// parseNoImplicitDeps parses the no-implicit-deps extension, which is either true to suppress all the
+// inferred dependencies, or the IDs of the resources to suppress.
+func parseNoImplicitDeps(value interface{}) (bool, []string, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil, nil
+	case bool:
+		return v, nil, nil
+	case string:
+		return v == "true", nil, nil
+	default:
+		ids, err := toStringSlice(v)
+		return false, ids, err
+	}
+}
+
+// toStringSlice converts the extension value to a string slice.
+func toStringSlice(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of resource IDs, got item %v", item)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected a list of resource IDs, got %v", value)
+	}
+}
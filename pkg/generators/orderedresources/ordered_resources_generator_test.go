@@ -103,3 +103,94 @@ func TestOrderedResourcesGenerator_Generate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
+
+func TestOrderedResourcesGenerator_GenerateWithDependencyExtensions(t *testing.T) {
+	t.Run("explicit dependencies", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[1].Extensions = map[string]interface{}{
+			v1.FieldDependsOn: []interface{}{"apps/v1:Deployment:foo:bar"},
+		}
+		err = orderedGenerator.Generate(actual)
+		assert.NoError(t, err)
+		assert.NoError(t, ResolveDependsOn(actual.Resources))
+		assert.Equal(t, []string{"v1:Namespace:foo", "apps/v1:Deployment:foo:bar"}, actual.Resources[1].DependsOn)
+	})
+
+	t.Run("suppress all implicit dependencies", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[0].Extensions = map[string]interface{}{v1.FieldNoImplicitDeps: true}
+		err = orderedGenerator.Generate(actual)
+		assert.NoError(t, err)
+		assert.Nil(t, actual.Resources[0].DependsOn)
+		assert.Equal(t, []string{"v1:Namespace:foo"}, actual.Resources[1].DependsOn)
+	})
+
+	t.Run("suppress specific implicit dependencies", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[0].Extensions = map[string]interface{}{
+			v1.FieldNoImplicitDeps: []interface{}{"v1:Service:foo:bar"},
+		}
+		err = orderedGenerator.Generate(actual)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v1:Namespace:foo"}, actual.Resources[0].DependsOn)
+	})
+
+	t.Run("declared dependencies are not suppressed", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[0].DependsOn = []string{"v1:Service:foo:bar"}
+		actual.Resources[0].Extensions = map[string]interface{}{v1.FieldNoImplicitDeps: "true"}
+		err = orderedGenerator.Generate(actual)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v1:Service:foo:bar"}, actual.Resources[0].DependsOn)
+	})
+
+	t.Run("unknown explicit dependency", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[0].Extensions = map[string]interface{}{
+			v1.FieldDependsOn: []string{"v1:ConfigMap:foo:bar"},
+		}
+		err = orderedGenerator.Generate(actual)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, ResolveDependsOn(actual.Resources), "v1:ConfigMap:foo:bar")
+	})
+
+	t.Run("explicit dependency on resource generated later", func(t *testing.T) {
+		orderedGenerator, err := NewOrderedResourcesGenerator()
+		assert.NoError(t, err)
+
+		actual := genOldSpec()
+		actual.Resources[0].Extensions = map[string]interface{}{
+			v1.FieldDependsOn: []string{"v1:ConfigMap:foo:bar"},
+		}
+		assert.NoError(t, orderedGenerator.Generate(actual))
+
+		// the resource depended on is generated by another app afterwards
+		actual.Resources = append(actual.Resources, v1.Resource{
+			ID:   "v1:ConfigMap:foo:bar",
+			Type: runtime.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "bar", "namespace": "foo"},
+			},
+		})
+		assert.NoError(t, orderedGenerator.Generate(actual))
+		assert.NoError(t, ResolveDependsOn(actual.Resources))
+		assert.Contains(t, actual.Resources.Index()["apps/v1:Deployment:foo:bar"].DependsOn, "v1:ConfigMap:foo:bar")
+	})
+}
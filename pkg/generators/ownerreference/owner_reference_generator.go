@@ -0,0 +1,138 @@
+package ownerreference
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/generators"
+)
+
+// ownerReferenceGenerator is a generator that generates the parent object representing the app, and stamps
+// the owner references to it onto the other Kubernetes resources of the app.
+type ownerReferenceGenerator struct {
+	config    *v1.OwnerReferenceConfig
+	name      string
+	namespace string
+}
+
+// NewOwnerReferenceGenerator returns a new instance of ownerReferenceGenerator. The name of the parent
+// object is derived from the project, stack and app names, and the parent object is cluster-scoped if
+// the namespace is empty.
+func NewOwnerReferenceGenerator(config *v1.OwnerReferenceConfig, project, stack, app, namespace string) (generators.SpecGenerator, error) {
+	if config == nil || config.APIVersion == "" || config.Kind == "" {
+		return nil, fmt.Errorf("apiVersion and kind of the owner reference must not be empty")
+	}
+	return &ownerReferenceGenerator{
+		config:    config,
+		name:      strings.ToLower(fmt.Sprintf("%s-%s-%s", project, stack, app)),
+		namespace: namespace,
+	}, nil
+}
+
+// NewOwnerReferenceGeneratorFunc returns a function that creates a new ownerReferenceGenerator.
+func NewOwnerReferenceGeneratorFunc(config *v1.OwnerReferenceConfig, project, stack, app, namespace string) generators.NewSpecGeneratorFunc {
+	return func() (generators.SpecGenerator, error) {
+		return NewOwnerReferenceGenerator(config, project, stack, app, namespace)
+	}
+}
+
+// Generate appends the parent object to the Spec, and stamps the owner references to it onto the Kubernetes
+// resources in the same namespace, which are applied after the parent object. The uid of the owner reference
+// is left empty and resolved against the live parent object by the Kubernetes runtime.
+func (g *ownerReferenceGenerator) Generate(spec *v1.Spec) error {
+	if spec.Resources == nil {
+		spec.Resources = make(v1.Resources, 0)
+	}
+
+	parentID := g.parentID()
+	for i := range spec.Resources {
+		res := &spec.Resources[i]
+		if res.ID == parentID || !g.ownable(res) {
+			continue
+		}
+		if err := g.stampOwnerReference(res); err != nil {
+			return fmt.Errorf("failed to stamp owner reference to resource %s: %w", res.ID, err)
+		}
+		res.DependsOn = appendIfMissing(res.DependsOn, parentID)
+	}
+
+	if spec.Resources.Index()[parentID] == nil {
+		spec.Resources = append(spec.Resources, g.parent())
+	}
+	return nil
+}
+
+// parentID returns the ID of the parent object in the Spec.
+func (g *ownerReferenceGenerator) parentID() string {
+	if g.namespace == "" {
+		return strings.Join([]string{g.config.APIVersion, g.config.Kind, g.name}, ":")
+	}
+	return strings.Join([]string{g.config.APIVersion, g.config.Kind, g.namespace, g.name}, ":")
+}
+
+// parent returns the parent object representing the app.
+func (g *ownerReferenceGenerator) parent() v1.Resource {
+	metadata := map[string]interface{}{"name": g.name}
+	if g.namespace != "" {
+		metadata["namespace"] = g.namespace
+	}
+	gv, _ := schema.ParseGroupVersion(g.config.APIVersion)
+	return v1.Resource{
+		ID:   g.parentID(),
+		Type: v1.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": g.config.APIVersion,
+			"kind":       g.config.Kind,
+			"metadata":   metadata,
+		},
+		Extensions: map[string]interface{}{
+			v1.ResourceExtensionGVK: gv.WithKind(g.config.Kind).String(),
+		},
+	}
+}
+
+// ownable reports whether the resource can be owned by the parent object. The namespaced parent object
+// can only own the namespaced resources in the same namespace, and the namespaces are never owned.
+func (g *ownerReferenceGenerator) ownable(res *v1.Resource) bool {
+	if res.Type != v1.Kubernetes {
+		return false
+	}
+	un := &unstructured.Unstructured{Object: res.Attributes}
+	if un.GetKind() == "Namespace" && un.GetAPIVersion() == "v1" {
+		return false
+	}
+	return g.namespace == "" || un.GetNamespace() == g.namespace
+}
+
+// stampOwnerReference appends the owner reference to the parent object onto the resource if not existing.
+func (g *ownerReferenceGenerator) stampOwnerReference(res *v1.Resource) error {
+	ownerReferences, _, err := unstructured.NestedSlice(res.Attributes, "metadata", "ownerReferences")
+	if err != nil {
+		return err
+	}
+	for _, ref := range ownerReferences {
+		if r, ok := ref.(map[string]interface{}); ok && r["kind"] == g.config.Kind && r["name"] == g.name {
+			return nil
+		}
+	}
+	ownerReferences = append(ownerReferences, map[string]interface{}{
+		"apiVersion": g.config.APIVersion,
+		"kind":       g.config.Kind,
+		"name":       g.name,
+		"uid":        "",
+	})
+	return unstructured.SetNestedSlice(res.Attributes, ownerReferences, "metadata", "ownerReferences")
+}
+
+func appendIfMissing(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
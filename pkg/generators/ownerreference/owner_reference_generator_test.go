@@ -0,0 +1,114 @@
+package ownerreference
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func newSpec() *v1.Spec {
+	return &v1.Spec{
+		Resources: v1.Resources{
+			{
+				ID:   "v1:Namespace:foo",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Namespace",
+					"metadata":   map[string]interface{}{"name": "foo"},
+				},
+			},
+			{
+				ID:   "apps/v1:Deployment:foo:foo-dev-bar",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata":   map[string]interface{}{"namespace": "foo", "name": "foo-dev-bar"},
+				},
+			},
+			{
+				ID:   "v1:Service:other:bar",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"namespace": "other", "name": "bar"},
+				},
+			},
+			{
+				ID:   "hashicorp:aws:aws_s3_bucket:bar",
+				Type: v1.Terraform,
+			},
+		},
+	}
+}
+
+func TestNewOwnerReferenceGenerator(t *testing.T) {
+	_, err := NewOwnerReferenceGenerator(nil, "foo", "dev", "bar", "foo")
+	assert.Error(t, err)
+	_, err = NewOwnerReferenceGenerator(&v1.OwnerReferenceConfig{Kind: "Application"}, "foo", "dev", "bar", "foo")
+	assert.Error(t, err)
+}
+
+func TestOwnerReferenceGenerator_Generate(t *testing.T) {
+	config := &v1.OwnerReferenceConfig{APIVersion: "app.kusion.io/v1alpha1", Kind: "Application"}
+	g, err := NewOwnerReferenceGenerator(config, "foo", "dev", "bar", "foo")
+	assert.NoError(t, err)
+
+	spec := newSpec()
+	assert.NoError(t, g.Generate(spec))
+	// generating twice should not stamp duplicate owner references
+	assert.NoError(t, g.Generate(spec))
+
+	parentID := "app.kusion.io/v1alpha1:Application:foo:foo-dev-bar"
+	assert.Len(t, spec.Resources, 5)
+	parent := spec.Resources[4]
+	assert.Equal(t, parentID, parent.ID)
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "app.kusion.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": "foo-dev-bar", "namespace": "foo"},
+	}, parent.Attributes)
+	assert.Equal(t, "app.kusion.io/v1alpha1, Kind=Application", parent.Extensions[v1.ResourceExtensionGVK])
+
+	ownerReferences := func(res v1.Resource) []interface{} {
+		refs, _, err := unstructured.NestedSlice(res.Attributes, "metadata", "ownerReferences")
+		assert.NoError(t, err)
+		return refs
+	}
+	// the namespace, the resources in other namespaces and the non-Kubernetes resources are not owned
+	assert.Nil(t, ownerReferences(spec.Resources[0]))
+	assert.Nil(t, spec.Resources[0].DependsOn)
+	assert.Nil(t, ownerReferences(spec.Resources[2]))
+	assert.Nil(t, spec.Resources[3].DependsOn)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"apiVersion": "app.kusion.io/v1alpha1",
+			"kind":       "Application",
+			"name":       "foo-dev-bar",
+			"uid":        "",
+		},
+	}, ownerReferences(spec.Resources[1]))
+	assert.Equal(t, []string{parentID}, spec.Resources[1].DependsOn)
+}
+
+func TestOwnerReferenceGenerator_GenerateClusterScoped(t *testing.T) {
+	config := &v1.OwnerReferenceConfig{APIVersion: "app.kusion.io/v1alpha1", Kind: "Application"}
+	g, err := NewOwnerReferenceGenerator(config, "foo", "dev", "bar", "")
+	assert.NoError(t, err)
+
+	spec := newSpec()
+	assert.NoError(t, g.Generate(spec))
+
+	parentID := "app.kusion.io/v1alpha1:Application:foo-dev-bar"
+	assert.Equal(t, parentID, spec.Resources[4].ID)
+	// the cluster-scoped parent object owns the resources in all namespaces
+	assert.Equal(t, []string{parentID}, spec.Resources[1].DependsOn)
+	assert.Equal(t, []string{parentID}, spec.Resources[2].DependsOn)
+	assert.Nil(t, spec.Resources[0].DependsOn)
+}
@@ -0,0 +1,233 @@
+package rename
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/generators"
+)
+
+// Rename represents renaming the Kubernetes resources of the kind from the old name to the new name.
+type Rename struct {
+	// Kind is the kind of the renamed resources, e.g. ConfigMap.
+	Kind string
+	// Namespace limits the renaming to the resources in the namespace, all namespaces if empty.
+	Namespace string
+	// OldName is the name of the resources before renaming.
+	OldName string
+	// NewName is the name of the resources after renaming.
+	NewName string
+}
+
+// workloadKinds are the kinds whose pods are labeled with the name of the workload, so the label
+// selectors referencing the old name are rewritten on renaming.
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"ReplicaSet":  true,
+	"Job":         true,
+	"CronJob":     true,
+	"Pod":         true,
+}
+
+// renameGenerator is a generator that renames the Kubernetes resources, and rewrites the references to
+// them across the resources consistently, so that no reference dangles after renaming.
+type renameGenerator struct {
+	renames []Rename
+}
+
+// NewRenameGenerator returns a new instance of renameGenerator.
+func NewRenameGenerator(renames ...Rename) (generators.SpecGenerator, error) {
+	for _, r := range renames {
+		if r.Kind == "" || r.OldName == "" || r.NewName == "" {
+			return nil, fmt.Errorf("kind, old name and new name of the rename must not be empty: %+v", r)
+		}
+	}
+	return &renameGenerator{renames: renames}, nil
+}
+
+// NewRenameGeneratorFunc returns a function that creates a new renameGenerator.
+func NewRenameGeneratorFunc(renames ...Rename) generators.NewSpecGeneratorFunc {
+	return func() (generators.SpecGenerator, error) {
+		return NewRenameGenerator(renames...)
+	}
+}
+
+// Generate renames the resources, and rewrites the references to them in the resource IDs, the dependsOn,
+// the environment variables, the volumes, the service accounts, the image pull secrets and the label
+// selectors of the workloads.
+func (g *renameGenerator) Generate(spec *v1.Spec) error {
+	if len(g.renames) == 0 || spec.Resources == nil {
+		return nil
+	}
+
+	// rename the resources and record the renamed IDs to rewrite the dependsOn
+	renamedIDs := make(map[string]string)
+	for i := range spec.Resources {
+		res := &spec.Resources[i]
+		if res.Type != v1.Kubernetes {
+			continue
+		}
+		un := &unstructured.Unstructured{Object: res.Attributes}
+		for _, r := range g.renames {
+			if r.matches(un.GetKind(), un.GetNamespace(), un.GetName()) {
+				un.SetName(r.NewName)
+				newID := engine.BuildIDForKubernetes(un)
+				renamedIDs[res.ID] = newID
+				res.ID = newID
+				break
+			}
+		}
+	}
+
+	for i := range spec.Resources {
+		res := &spec.Resources[i]
+		for j, id := range res.DependsOn {
+			if newID, ok := renamedIDs[id]; ok {
+				res.DependsOn[j] = newID
+			}
+		}
+		if res.Type != v1.Kubernetes {
+			continue
+		}
+		if err := g.rewriteReferences(res); err != nil {
+			return fmt.Errorf("failed to rewrite references of resource %s: %w", res.ID, err)
+		}
+	}
+	return nil
+}
+
+// rewriteReferences rewrites the references to the renamed resources in the Kubernetes resource.
+func (g *renameGenerator) rewriteReferences(res *v1.Resource) error {
+	un := &unstructured.Unstructured{Object: res.Attributes}
+	namespace, kind := un.GetNamespace(), un.GetKind()
+
+	if kind == "Service" {
+		if err := g.rewriteLabels(res.Attributes, namespace, "spec", "selector"); err != nil {
+			return err
+		}
+	}
+	if workloadKinds[kind] {
+		if err := g.rewriteLabels(res.Attributes, namespace, "spec", "selector", "matchLabels"); err != nil {
+			return err
+		}
+	}
+
+	podPath := podSpecPath(kind)
+	if podPath == nil {
+		return nil
+	}
+	// the pod labels are selected by the workload and the services
+	labelsPath := append(append([]string{}, podPath[:len(podPath)-1]...), "metadata", "labels")
+	if err := g.rewriteLabels(res.Attributes, namespace, labelsPath...); err != nil {
+		return err
+	}
+	// the pod spec is rewritten in place, as the attributes may not be deep-copyable JSON values
+	podSpec, found, err := unstructured.NestedFieldNoCopy(res.Attributes, podPath...)
+	if err != nil || !found {
+		return err
+	}
+	if m, ok := podSpec.(map[string]interface{}); ok {
+		g.rewritePodSpec(m, namespace)
+	}
+	return nil
+}
+
+// rewritePodSpec rewrites the references to the renamed resources in the pod spec.
+func (g *renameGenerator) rewritePodSpec(podSpec map[string]interface{}, namespace string) {
+	g.rewriteName(podSpec, "ServiceAccount", namespace, "serviceAccountName")
+	for _, s := range items(podSpec["imagePullSecrets"]) {
+		g.rewriteName(s, "Secret", namespace, "name")
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		for _, container := range items(podSpec[field]) {
+			for _, env := range items(container["env"]) {
+				g.rewriteName(env, "ConfigMap", namespace, "valueFrom", "configMapKeyRef", "name")
+				g.rewriteName(env, "Secret", namespace, "valueFrom", "secretKeyRef", "name")
+			}
+			for _, envFrom := range items(container["envFrom"]) {
+				g.rewriteName(envFrom, "ConfigMap", namespace, "configMapRef", "name")
+				g.rewriteName(envFrom, "Secret", namespace, "secretRef", "name")
+			}
+		}
+	}
+
+	for _, volume := range items(podSpec["volumes"]) {
+		g.rewriteName(volume, "ConfigMap", namespace, "configMap", "name")
+		g.rewriteName(volume, "Secret", namespace, "secret", "secretName")
+		g.rewriteName(volume, "PersistentVolumeClaim", namespace, "persistentVolumeClaim", "claimName")
+		projected, _ := volume["projected"].(map[string]interface{})
+		for _, source := range items(projected["sources"]) {
+			g.rewriteName(source, "ConfigMap", namespace, "configMap", "name")
+			g.rewriteName(source, "Secret", namespace, "secret", "name")
+		}
+	}
+}
+
+// rewriteName rewrites the name of the resource of the kind at the path of the object if renamed.
+func (g *renameGenerator) rewriteName(obj map[string]interface{}, kind, namespace string, path ...string) {
+	name, found, err := unstructured.NestedString(obj, path...)
+	if err != nil || !found {
+		return
+	}
+	for _, r := range g.renames {
+		if r.matches(kind, namespace, name) {
+			_ = unstructured.SetNestedField(obj, r.NewName, path...)
+			return
+		}
+	}
+}
+
+// rewriteLabels rewrites the label values equal to the old names of the renamed workloads.
+func (g *renameGenerator) rewriteLabels(obj map[string]interface{}, namespace string, path ...string) error {
+	labels, found, err := unstructured.NestedStringMap(obj, path...)
+	if err != nil || !found {
+		return err
+	}
+	for k, value := range labels {
+		for _, r := range g.renames {
+			if workloadKinds[r.Kind] && r.matches(r.Kind, namespace, value) {
+				labels[k] = r.NewName
+				break
+			}
+		}
+	}
+	return unstructured.SetNestedStringMap(obj, labels, path...)
+}
+
+// matches reports whether the resource of the kind in the namespace with the name is renamed.
+func (r Rename) matches(kind, namespace, name string) bool {
+	return r.Kind == kind && r.OldName == name && (r.Namespace == "" || r.Namespace == namespace)
+}
+
+// podSpecPath returns the path of the pod spec of the workload kind, or nil if not a workload.
+func podSpecPath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		if workloadKinds[kind] {
+			return []string{"spec", "template", "spec"}
+		}
+		return nil
+	}
+}
+
+// items returns the map items of the unstructured slice.
+func items(value interface{}) []map[string]interface{} {
+	slice, _ := value.([]interface{})
+	result := make([]map[string]interface{}, 0, len(slice))
+	for _, item := range slice {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
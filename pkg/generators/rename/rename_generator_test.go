@@ -0,0 +1,168 @@
+package rename
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func newSpec() *v1.Spec {
+	return &v1.Spec{
+		Resources: v1.Resources{
+			{
+				ID:   "v1:ConfigMap:foo:config",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   map[string]interface{}{"namespace": "foo", "name": "config"},
+				},
+			},
+			{
+				ID:   "v1:Secret:foo:credential",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Secret",
+					"metadata":   map[string]interface{}{"namespace": "foo", "name": "credential"},
+				},
+			},
+			{
+				ID:   "apps/v1:Deployment:foo:web",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata":   map[string]interface{}{"namespace": "foo", "name": "web"},
+					"spec": map[string]interface{}{
+						"selector": map[string]interface{}{
+							"matchLabels": map[string]interface{}{"app": "web"},
+						},
+						"template": map[string]interface{}{
+							"metadata": map[string]interface{}{
+								"labels": map[string]interface{}{"app": "web", "tier": "frontend"},
+							},
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{
+										"name": "web",
+										"env": []interface{}{
+											map[string]interface{}{
+												"name": "PASSWORD",
+												"valueFrom": map[string]interface{}{
+													"secretKeyRef": map[string]interface{}{"name": "credential", "key": "password"},
+												},
+											},
+											map[string]interface{}{
+												"name": "LEVEL",
+												"valueFrom": map[string]interface{}{
+													"configMapKeyRef": map[string]interface{}{"name": "config", "key": "level"},
+												},
+											},
+										},
+									},
+								},
+								"volumes": []interface{}{
+									map[string]interface{}{
+										"name":      "config",
+										"configMap": map[string]interface{}{"name": "config"},
+									},
+									map[string]interface{}{
+										"name":   "credential",
+										"secret": map[string]interface{}{"secretName": "credential"},
+									},
+								},
+							},
+						},
+					},
+				},
+				DependsOn: []string{"v1:ConfigMap:foo:config", "v1:Secret:foo:credential"},
+			},
+			{
+				ID:   "v1:Service:foo:web",
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"namespace": "foo", "name": "web"},
+					"spec": map[string]interface{}{
+						"selector": map[string]interface{}{"app": "web"},
+					},
+				},
+				DependsOn: []string{"apps/v1:Deployment:foo:web"},
+			},
+		},
+	}
+}
+
+func TestNewRenameGenerator(t *testing.T) {
+	_, err := NewRenameGenerator(Rename{Kind: "ConfigMap", OldName: "config"})
+	assert.Error(t, err)
+}
+
+func TestRenameGenerator_Generate(t *testing.T) {
+	g, err := NewRenameGenerator(
+		Rename{Kind: "ConfigMap", OldName: "config", NewName: "foo-config"},
+		Rename{Kind: "Secret", Namespace: "foo", OldName: "credential", NewName: "foo-credential"},
+		Rename{Kind: "Deployment", OldName: "web", NewName: "foo-web"},
+	)
+	assert.NoError(t, err)
+
+	spec := newSpec()
+	assert.NoError(t, g.Generate(spec))
+
+	ids := make([]string, 0, len(spec.Resources))
+	for _, res := range spec.Resources {
+		ids = append(ids, res.ID)
+	}
+	assert.Equal(t, []string{
+		"v1:ConfigMap:foo:foo-config",
+		"v1:Secret:foo:foo-credential",
+		"apps/v1:Deployment:foo:foo-web",
+		"v1:Service:foo:web",
+	}, ids)
+	assert.Equal(t, []string{"v1:ConfigMap:foo:foo-config", "v1:Secret:foo:foo-credential"}, spec.Resources[2].DependsOn)
+	assert.Equal(t, []string{"apps/v1:Deployment:foo:foo-web"}, spec.Resources[3].DependsOn)
+
+	deployment := spec.Resources[2].Attributes
+	nestedString := func(obj map[string]interface{}, path ...string) string {
+		value, _, err := unstructured.NestedString(obj, path...)
+		assert.NoError(t, err)
+		return value
+	}
+	assert.Equal(t, "foo-web", nestedString(deployment, "metadata", "name"))
+
+	t.Run("env valueFrom", func(t *testing.T) {
+		containers, _, _ := unstructured.NestedSlice(deployment, "spec", "template", "spec", "containers")
+		envs := containers[0].(map[string]interface{})["env"].([]interface{})
+		assert.Equal(t, "foo-credential", nestedString(envs[0].(map[string]interface{}), "valueFrom", "secretKeyRef", "name"))
+		assert.Equal(t, "foo-config", nestedString(envs[1].(map[string]interface{}), "valueFrom", "configMapKeyRef", "name"))
+	})
+
+	t.Run("volume sources", func(t *testing.T) {
+		volumes, _, _ := unstructured.NestedSlice(deployment, "spec", "template", "spec", "volumes")
+		assert.Equal(t, "foo-config", nestedString(volumes[0].(map[string]interface{}), "configMap", "name"))
+		assert.Equal(t, "foo-credential", nestedString(volumes[1].(map[string]interface{}), "secret", "secretName"))
+		// the volume names are not references
+		assert.Equal(t, "config", nestedString(volumes[0].(map[string]interface{}), "name"))
+	})
+
+	t.Run("selectors", func(t *testing.T) {
+		assert.Equal(t, "foo-web", nestedString(spec.Resources[3].Attributes, "spec", "selector", "app"))
+		assert.Equal(t, "foo-web", nestedString(deployment, "spec", "selector", "matchLabels", "app"))
+		assert.Equal(t, "foo-web", nestedString(deployment, "spec", "template", "metadata", "labels", "app"))
+		assert.Equal(t, "frontend", nestedString(deployment, "spec", "template", "metadata", "labels", "tier"))
+	})
+}
+
+func TestRenameGenerator_GenerateOtherNamespace(t *testing.T) {
+	g, err := NewRenameGenerator(Rename{Kind: "Secret", Namespace: "bar", OldName: "credential", NewName: "bar-credential"})
+	assert.NoError(t, err)
+
+	spec := newSpec()
+	assert.NoError(t, g.Generate(spec))
+	assert.Equal(t, newSpec(), spec)
+}
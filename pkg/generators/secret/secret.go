@@ -1,25 +1,44 @@
 package secret
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/maps"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"kusionstack.io/kusion-module-framework/pkg/module"
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
 	"kusionstack.io/kusion/pkg/generators"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/secrets"
 )
 
+// maxConcurrentProbes is the max number of the external secrets probed against the secret stores concurrently.
+const maxConcurrentProbes = 8
+
+// probeTimeout is the timeout of probing a secret store for the refs of an external secret.
+const probeTimeout = 10 * time.Second
+
 type secretGenerator struct {
-	project     string
-	namespace   string
-	secrets     map[string]v1.Secret
-	secretStore *v1.SecretStore
+	project              string
+	namespace            string
+	secrets              map[string]v1.Secret
+	secretStore          *v1.SecretStore
+	secretStoreName      string
+	secretStores         map[string]*v1.SecretStore
+	secretStoreSelectors map[string]string
+	secretStoreFallbacks []string
 }
 
 type GeneratorRequest struct {
@@ -31,6 +50,17 @@ type GeneratorRequest struct {
 	Workload v1.Accessory
 	// SecretStore contains configuration to describe target secret store.
 	SecretStore *v1.SecretStore
+	// SecretStoreName is the name of SecretStore if it is one of the SecretStores, e.g. the one selected
+	// for the namespace, which is recorded to the secrets resolved against it.
+	SecretStoreName string
+	// SecretStores contains the named secret stores which can be designated to specific secrets.
+	SecretStores map[string]*v1.SecretStore
+	// SecretStoreSelectors maps secret name patterns to the names of SecretStores, secrets not
+	// matching any pattern fall back to SecretStore.
+	SecretStoreSelectors map[string]string
+	// SecretStoreFallbacks are the names of SecretStores tried in order when the secret store resolved
+	// for an external secret fails to serve it, which are probed only if set.
+	SecretStoreFallbacks []string
 }
 
 func NewSecretGenerator(request *GeneratorRequest) (generators.SpecGenerator, error) {
@@ -57,13 +87,48 @@ func NewSecretGenerator(request *GeneratorRequest) (generators.SpecGenerator, er
 	}
 
 	return &secretGenerator{
-		project:     request.Project,
-		secrets:     secretMap,
-		namespace:   request.Namespace,
-		secretStore: request.SecretStore,
+		project:              request.Project,
+		secrets:              secretMap,
+		namespace:            request.Namespace,
+		secretStore:          request.SecretStore,
+		secretStoreName:      request.SecretStoreName,
+		secretStores:         request.SecretStores,
+		secretStoreSelectors: request.SecretStoreSelectors,
+		secretStoreFallbacks: request.SecretStoreFallbacks,
 	}, nil
 }
 
+// ExternalSecretsWithoutStore returns the names of the external secrets of the workload in order, which
+// are resolved against no secret store, e.g. the workspace configures no secret store at all.
+func ExternalSecretsWithoutStore(request *GeneratorRequest) ([]string, error) {
+	generator, err := NewSecretGenerator(request)
+	if err != nil {
+		return nil, err
+	}
+	g := generator.(*secretGenerator)
+	// the fallbacks are validated to be configured, which are probed on generating
+	if len(g.secretStoreFallbacks) > 0 {
+		return nil, nil
+	}
+
+	secretNames := maps.Keys(g.secrets)
+	sort.Strings(secretNames)
+	var missing []string
+	for _, secretName := range secretNames {
+		if g.secrets[secretName].Type != "external" {
+			continue
+		}
+		_, secretStore, err := g.resolveSecretStore(secretName)
+		if err != nil {
+			return nil, err
+		}
+		if secretStore == nil {
+			missing = append(missing, secretName)
+		}
+	}
+	return missing, nil
+}
+
 func NewSecretGeneratorFunc(request *GeneratorRequest) generators.NewSpecGeneratorFunc {
 	return func() (generators.SpecGenerator, error) {
 		return NewSecretGenerator(request)
@@ -75,27 +140,93 @@ func (g *secretGenerator) Generate(spec *v1.Spec) error {
 		spec.Resources = make(v1.Resources, 0)
 	}
 
-	for secretName, secretRef := range g.secrets {
-		secret, err := g.generateSecret(secretName, secretRef)
-		if err != nil {
-			return err
+	// secrets are namespaced resources, which can not be placed without a namespace
+	if len(g.secrets) != 0 && g.namespace == "" {
+		return fmt.Errorf("secrets are not supported in the cluster-scoped app of project %s", g.project)
+	}
+
+	// the secrets are appended to the spec in order of their names, so that the spec is the same across the
+	// generations. Only probing the secret stores with the fallbacks calls the remote secret stores, thus the
+	// secrets are generated concurrently in that case only.
+	secretNames := maps.Keys(g.secrets)
+	sort.Strings(secretNames)
+	results := make([]*generatedSecret, len(secretNames))
+	errs := make([]error, len(secretNames))
+	if len(g.secretStoreFallbacks) == 0 {
+		for i, secretName := range secretNames {
+			results[i], errs[i] = g.resolveSecret(secretName, g.secrets[secretName])
 		}
+	} else {
+		workers := make(chan struct{}, maxConcurrentProbes)
+		var wg sync.WaitGroup
+		for i, secretName := range secretNames {
+			wg.Add(1)
+			workers <- struct{}{}
+			go func() {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+				results[i], errs[i] = g.resolveSecret(secretName, g.secrets[secretName])
+			}()
+		}
+		wg.Wait()
+	}
+	// all the secrets failed are reported at once
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
 
-		resourceID := module.KubernetesResourceID(secret.TypeMeta, secret.ObjectMeta)
-		err = generators.AppendToSpec(
+	for i, result := range results {
+		resourceID := module.KubernetesResourceID(result.secret.TypeMeta, result.secret.ObjectMeta)
+		err := generators.AppendToSpec(
 			v1.Kubernetes,
 			resourceID,
 			spec,
-			secret,
+			result.secret,
 		)
 		if err != nil {
 			return err
 		}
+
+		// record the name of the designated secret store of the external secret, which will be used to
+		// resolve the secret refs instead of the default one during apply. The store is carried once in
+		// the spec, however many secrets it serves.
+		if result.storeName != "" {
+			log.Infof("secret %s is served by secret store %s", secretNames[i], result.storeName)
+			spec.Resources[len(spec.Resources)-1].Extensions[v1.ResourceExtensionSecretStore] = result.storeName
+			if spec.SecretStores == nil {
+				spec.SecretStores = make(map[string]*v1.SecretStore)
+			}
+			spec.SecretStores[result.storeName] = result.secretStore
+		}
 	}
 
 	return nil
 }
 
+// generatedSecret is the secret generated along with its designated secret store if any.
+type generatedSecret struct {
+	secret      *corev1.Secret
+	storeName   string
+	secretStore *v1.SecretStore
+}
+
+// resolveSecret generates the secret, and selects the secret store serving the external secret.
+func (g *secretGenerator) resolveSecret(secretName string, secretRef v1.Secret) (*generatedSecret, error) {
+	result := &generatedSecret{}
+	var err error
+	if secretRef.Type == "external" {
+		if result.storeName, result.secretStore, err = g.selectSecretStore(secretName, secretRef); err != nil {
+			return nil, err
+		}
+	}
+	if result.secret, err = g.generateSecret(secretName, secretRef); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // generateSecret generates target secret based on secret type. Most of these secret types are just semantic wrapper
 // of native Kubernetes secret types:https://kubernetes.io/docs/concepts/configuration/secret/#secret-types, and more
 // detailed usage info can be found in public documentation.
@@ -165,10 +296,6 @@ func (g *secretGenerator) generateCertificate(secretName string, secretRef v1.Se
 // generateSecretWithExternalProvider retrieves target sensitive information from external secret provider and
 // generates corresponding Kubernetes Secret object.
 func (g *secretGenerator) generateSecretWithExternalProvider(secretName string, secretRef v1.Secret) (*corev1.Secret, error) {
-	if g.secretStore == nil {
-		return nil, errors.New("secret store is missing, please add valid secret store spec in workspace")
-	}
-
 	secret := initBasicSecret(g.namespace, secretName, corev1.SecretTypeOpaque, secretRef.Immutable)
 	secret.Data = make(map[string][]byte)
 
@@ -179,6 +306,116 @@ func (g *secretGenerator) generateSecretWithExternalProvider(secretName string,
 	return secret, nil
 }
 
+// resolveSecretStore returns the name and spec of the secret store designated to the secret. The
+// selector patterns are matched in lexical order and the first matched one wins, the default secret
+// store is returned if no pattern matches, whose name is empty unless it is one of the named ones.
+func (g *secretGenerator) resolveSecretStore(secretName string) (string, *v1.SecretStore, error) {
+	patterns := make([]string, 0, len(g.secretStoreSelectors))
+	for pattern := range g.secretStoreSelectors {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, secretName)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid secret store selector pattern %s: %w", pattern, err)
+		}
+		if !matched {
+			continue
+		}
+		storeName := g.secretStoreSelectors[pattern]
+		secretStore, ok := g.secretStores[storeName]
+		if !ok || secretStore == nil {
+			return "", nil, fmt.Errorf("secret %s matches selector pattern %s, but secret store %s is not configured in workspace",
+				secretName, pattern, storeName)
+		}
+		return storeName, secretStore, nil
+	}
+
+	return g.secretStoreName, g.secretStore, nil
+}
+
+// selectSecretStore returns the name and spec of the secret store serving the external secret. The secret
+// store resolved for the secret serves it directly if no fallback is configured, otherwise the resolved one
+// and the fallbacks are probed in order, and the first one serving all the refs of the secret wins.
+func (g *secretGenerator) selectSecretStore(secretName string, secretRef v1.Secret) (string, *v1.SecretStore, error) {
+	storeName, secretStore, err := g.resolveSecretStore(secretName)
+	if err != nil {
+		return "", nil, err
+	}
+	// the secret without a secret store is generated with its refs unresolved, which is reported by the caller,
+	// see ExternalSecretsWithoutStore
+	if len(g.secretStoreFallbacks) == 0 {
+		return storeName, secretStore, nil
+	}
+
+	type candidate struct {
+		name  string
+		store *v1.SecretStore
+	}
+	candidates := []candidate{{name: storeName, store: secretStore}}
+	for _, name := range g.secretStoreFallbacks {
+		if name == storeName {
+			continue
+		}
+		candidates = append(candidates, candidate{name: name, store: g.secretStores[name]})
+	}
+
+	var errs []string
+	for _, c := range candidates {
+		displayName := c.name
+		if displayName == "" {
+			displayName = "default"
+		}
+		if c.store == nil {
+			errs = append(errs, fmt.Sprintf("secret store %s is not configured in workspace", displayName))
+			continue
+		}
+		if err = probeSecretStore(c.store, secretRef); err != nil {
+			log.Warnf("secret store %s is unavailable for secret %s: %v", displayName, secretName, err)
+			errs = append(errs, fmt.Sprintf("secret store %s: %v", displayName, err))
+			continue
+		}
+		if c.name != storeName {
+			log.Warnf("secret %s falls back to secret store %s", secretName, displayName)
+		}
+		return c.name, c.store, nil
+	}
+	return "", nil, fmt.Errorf("no secret store serves secret %s: %s", secretName, strings.Join(errs, "; "))
+}
+
+// probeSecretStore checks the secret store serves all the refs of the external secret.
+func probeSecretStore(secretStore *v1.SecretStore, secretRef v1.Secret) error {
+	provider, ok := secrets.GetProvider(secretStore.Provider)
+	if !ok {
+		return errors.New("secret store provider is not registered")
+	}
+	store, err := provider.NewSecretStore(secretStore)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	keys := maps.Keys(secretRef.Data)
+	sort.Strings(keys)
+	for _, key := range keys {
+		data := secretRef.Data[key]
+		if !strings.HasPrefix(data, graph.SecretRefPrefix) {
+			continue
+		}
+		ref, err := graph.ParseExternalSecretDataRef(data)
+		if err != nil {
+			return err
+		}
+		if _, err = store.GetSecret(ctx, *ref); err != nil {
+			return fmt.Errorf("failed to get %s: %w", data, err)
+		}
+	}
+	return nil
+}
+
 // grabData extracts keys mapping data from original string map.
 func grabData(from map[string]string, keys ...string) map[string][]byte {
 	to := map[string][]byte{}
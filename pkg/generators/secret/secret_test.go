@@ -1,6 +1,7 @@
 package secret
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -125,10 +126,19 @@ func TestGenerateSecretWithExternalRef(t *testing.T) {
 		secretType string
 		secretData map[string]string
 
-		providerData []v1.FakeProviderData
+		providerData  []v1.FakeProviderData
+		noSecretStore bool
 
 		expectErr string
 	}{
+		"create_external_secret_without_secret_store": {
+			secretName: "api-auth",
+			secretType: "external",
+			secretData: map[string]string{
+				"accessKey": "ref://api-auth-info/accessKey?version=1",
+			},
+			noSecretStore: true,
+		},
 		"create_external_secret": {
 			secretName: "api-auth",
 			secretType: "external",
@@ -156,6 +166,9 @@ func TestGenerateSecretWithExternalRef(t *testing.T) {
 				},
 			}
 			secretStoreSpec := initSecretStoreSpec(test.providerData)
+			if test.noSecretStore {
+				secretStoreSpec = nil
+			}
 			context := initGeneratorRequest(testProject, secrets, secretStoreSpec)
 			generator, _ := NewSecretGenerator(context)
 			err := generator.Generate(&v1.Spec{})
@@ -168,3 +181,225 @@ func TestGenerateSecretWithExternalRef(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSecretWithDesignatedSecretStore(t *testing.T) {
+	defaultStore := initSecretStoreSpec(nil)
+	vaultStore := &v1.SecretStore{
+		Provider: &v1.ProviderSpec{
+			Vault: &v1.VaultProvider{
+				Server: "https://vault.example.com:8200",
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		secretName       string
+		selectors        map[string]string
+		defaultStoreName string
+
+		expectStoreName string
+		expectStore     *v1.SecretStore
+		expectErr       string
+	}{
+		"record_named_default_store": {
+			secretName:       "api-auth",
+			defaultStoreName: "tenant",
+			expectStoreName:  "tenant",
+			expectStore:      defaultStore,
+		},
+		"fallback_to_default_store": {
+			secretName:  "api-auth",
+			selectors:   map[string]string{"vault-*": "vault"},
+			expectStore: nil,
+		},
+		"resolve_designated_store": {
+			secretName:      "vault-api-auth",
+			selectors:       map[string]string{"vault-*": "vault"},
+			expectStoreName: "vault",
+			expectStore:     vaultStore,
+		},
+		"pattern_matches_no_configured_store": {
+			secretName: "aws-api-auth",
+			selectors:  map[string]string{"aws-*": "aws"},
+			expectErr:  "secret aws-api-auth matches selector pattern aws-*, but secret store aws is not configured in workspace",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secrets := map[string]v1.Secret{
+				test.secretName: {
+					Type: "external",
+					Data: map[string]string{
+						"accessKey": "ref://api-auth-info/accessKey",
+					},
+				},
+			}
+			request := initGeneratorRequest(testProject, secrets, defaultStore)
+			request.SecretStores = map[string]*v1.SecretStore{"vault": vaultStore}
+			request.SecretStoreSelectors = test.selectors
+			request.SecretStoreName = test.defaultStoreName
+			generator, _ := NewSecretGenerator(request)
+			spec := &v1.Spec{}
+			err := generator.Generate(spec)
+			if test.expectErr != "" {
+				require.EqualError(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, spec.Resources, 1)
+			if test.expectStoreName == "" {
+				require.NotContains(t, spec.Resources[0].Extensions, v1.ResourceExtensionSecretStore)
+				require.Empty(t, spec.SecretStores)
+			} else {
+				require.Equal(t, test.expectStoreName, spec.Resources[0].Extensions[v1.ResourceExtensionSecretStore])
+				require.Equal(t, map[string]*v1.SecretStore{test.expectStoreName: test.expectStore}, spec.SecretStores)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretWithFallbackSecretStores(t *testing.T) {
+	authInfo := []v1.FakeProviderData{{Key: "api-auth-info", Value: `{"accessKey":"some sensitive info"}`}}
+	// the primary store missing the secret simulates the one unavailable
+	primaryStore := initSecretStoreSpec(nil)
+	fallbackStore := initSecretStoreSpec(authInfo)
+
+	tests := map[string]struct {
+		primaryStore *v1.SecretStore
+		fallbacks    []string
+
+		expectStoreName string
+		expectStore     *v1.SecretStore
+		expectErr       string
+	}{
+		"primary_serves": {
+			primaryStore: initSecretStoreSpec(authInfo),
+			fallbacks:    []string{"backup"},
+			expectStore:  nil,
+		},
+		"fallback_serves": {
+			primaryStore:    primaryStore,
+			fallbacks:       []string{"missing", "backup"},
+			expectStoreName: "backup",
+			expectStore:     fallbackStore,
+		},
+		"fallback_without_primary": {
+			fallbacks:       []string{"backup"},
+			expectStoreName: "backup",
+			expectStore:     fallbackStore,
+		},
+		"no_store_serves": {
+			primaryStore: primaryStore,
+			fallbacks:    []string{"missing"},
+			expectErr: "no secret store serves secret api-auth: secret store default: failed to get " +
+				"ref://api-auth-info/accessKey: Secret does not exist; secret store missing is not configured in workspace",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secrets := map[string]v1.Secret{
+				"api-auth": {
+					Type: "external",
+					Data: map[string]string{"accessKey": "ref://api-auth-info/accessKey"},
+				},
+			}
+			request := initGeneratorRequest(testProject, secrets, test.primaryStore)
+			request.SecretStores = map[string]*v1.SecretStore{"backup": fallbackStore}
+			request.SecretStoreFallbacks = test.fallbacks
+			generator, _ := NewSecretGenerator(request)
+			spec := &v1.Spec{}
+			err := generator.Generate(spec)
+			if test.expectErr != "" {
+				require.EqualError(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, spec.Resources, 1)
+			if test.expectStoreName == "" {
+				require.NotContains(t, spec.Resources[0].Extensions, v1.ResourceExtensionSecretStore)
+				require.Empty(t, spec.SecretStores)
+			} else {
+				require.Equal(t, test.expectStoreName, spec.Resources[0].Extensions[v1.ResourceExtensionSecretStore])
+				require.Equal(t, map[string]*v1.SecretStore{test.expectStoreName: test.expectStore}, spec.SecretStores)
+			}
+		})
+	}
+}
+
+func TestExternalSecretsWithoutStore(t *testing.T) {
+	secrets := map[string]v1.Secret{
+		"api-auth":   {Type: "external", Data: map[string]string{"accessKey": "ref://api-auth-info/accessKey"}},
+		"vault-auth": {Type: "external", Data: map[string]string{"token": "ref://vault-auth-info/token"}},
+		"token":      {Type: "token"},
+	}
+	vaultStore := initSecretStoreSpec(nil)
+
+	tests := map[string]struct {
+		secretStore *v1.SecretStore
+		fallbacks   []string
+
+		expectMissing []string
+	}{
+		"no_secret_store": {
+			expectMissing: []string{"api-auth"},
+		},
+		"default_secret_store": {
+			secretStore: initSecretStoreSpec(nil),
+		},
+		"fallback_secret_stores": {
+			fallbacks: []string{"vault"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := initGeneratorRequest(testProject, secrets, test.secretStore)
+			request.SecretStores = map[string]*v1.SecretStore{"vault": vaultStore}
+			request.SecretStoreSelectors = map[string]string{"vault-*": "vault"}
+			request.SecretStoreFallbacks = test.fallbacks
+			missing, err := ExternalSecretsWithoutStore(request)
+			require.NoError(t, err)
+			require.Equal(t, test.expectMissing, missing)
+		})
+	}
+}
+
+func TestGenerateSecrets(t *testing.T) {
+	t.Run("deterministic_order", func(t *testing.T) {
+		secrets := make(map[string]v1.Secret)
+		var expectedIDs []string
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("secret-%02d", i)
+			secrets[name] = v1.Secret{Type: "token"}
+			expectedIDs = append(expectedIDs, "v1:Secret:"+testProject+":"+name)
+		}
+		request := initGeneratorRequest(testProject, secrets, nil)
+		generator, err := NewSecretGenerator(request)
+		require.NoError(t, err)
+
+		spec := &v1.Spec{}
+		require.NoError(t, generator.Generate(spec))
+		var ids []string
+		for _, res := range spec.Resources {
+			ids = append(ids, res.ID)
+		}
+		require.Equal(t, expectedIDs, ids)
+	})
+
+	t.Run("aggregated_errors", func(t *testing.T) {
+		secrets := map[string]v1.Secret{
+			"secret-a": {Type: "cred"},
+			"secret-b": {Type: "token"},
+			"secret-c": {Type: "key"},
+		}
+		generator, err := NewSecretGenerator(initGeneratorRequest(testProject, secrets, nil))
+		require.NoError(t, err)
+
+		spec := &v1.Spec{}
+		err = generator.Generate(spec)
+		require.EqualError(t, err, "[unrecognized secret type cred, unrecognized secret type key]")
+		require.Empty(t, spec.Resources)
+	})
+}
@@ -1,6 +1,7 @@
 package generators
 
 import (
+	"context"
 	"errors"
 	"sort"
 
@@ -26,12 +27,26 @@ func CallGeneratorFuncs(newGenerators ...NewSpecGeneratorFunc) ([]SpecGenerator,
 // CallGenerators calls the Generate method of each SpecGenerator instance
 // returned by the given NewSpecGeneratorFuncs.
 func CallGenerators(i *v1.Spec, newGenerators ...NewSpecGeneratorFunc) error {
+	return CallGeneratorsWithContext(context.Background(), i, newGenerators...)
+}
+
+// CallGeneratorsWithContext is the same as CallGenerators, but stops calling the generators once the ctx is
+// done, which is passed to the generators implementing the ContextSpecGenerator.
+func CallGeneratorsWithContext(ctx context.Context, i *v1.Spec, newGenerators ...NewSpecGeneratorFunc) error {
 	gs, err := CallGeneratorFuncs(newGenerators...)
 	if err != nil {
 		return err
 	}
 	for _, g := range gs {
-		if err := g.Generate(i); err != nil {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if cg, ok := g.(ContextSpecGenerator); ok {
+			err = cg.GenerateWithContext(ctx, i)
+		} else {
+			err = g.Generate(i)
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -1,6 +1,7 @@
 package generators
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,6 +42,50 @@ func TestCallGenerators(t *testing.T) {
 	assert.EqualError(t, err, assert.AnError.Error())
 }
 
+// mockContextGenerator is a mockGenerator cancelling the context on generating.
+type mockContextGenerator struct {
+	mockGenerator
+	cancel context.CancelFunc
+}
+
+func (m *mockContextGenerator) GenerateWithContext(ctx context.Context, i *v1.Spec) error {
+	m.cancel()
+	return ctx.Err()
+}
+
+func TestCallGeneratorsWithContext(t *testing.T) {
+	var calls []string
+	newGeneratorFunc := func(name string) NewSpecGeneratorFunc {
+		return func() (SpecGenerator, error) {
+			return &mockGenerator{GenerateFunc: func(*v1.Spec) error {
+				calls = append(calls, name)
+				return nil
+			}}, nil
+		}
+	}
+
+	t.Run("cancelled before generating", func(t *testing.T) {
+		calls = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := CallGeneratorsWithContext(ctx, &v1.Spec{}, newGeneratorFunc("a"))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, calls)
+	})
+
+	t.Run("cancelled by a generator", func(t *testing.T) {
+		calls = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		contextGenerator := func() (SpecGenerator, error) {
+			return &mockContextGenerator{cancel: cancel}, nil
+		}
+		err := CallGeneratorsWithContext(ctx, &v1.Spec{}, newGeneratorFunc("a"), contextGenerator, newGeneratorFunc("b"))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []string{"a"}, calls)
+	})
+}
+
 func TestCallGeneratorFuncs(t *testing.T) {
 	generatorFunc1 := func() (SpecGenerator, error) {
 		return &mockGenerator{}, nil
@@ -28,6 +28,9 @@ func NewBackendRepository(db *gorm.DB) repository.BackendRepository {
 
 // Create saves a backend to the repository.
 func (r *backendRepository) Create(ctx context.Context, dataEntity *entity.Backend) error {
+	ctx, done := instrumentQuery(ctx, "backend", "Create")
+	defer done()
+
 	// r.db.AutoMigrate(&BackendModel{})
 	err := dataEntity.Validate()
 	if err != nil {
@@ -56,6 +59,9 @@ func (r *backendRepository) Create(ctx context.Context, dataEntity *entity.Backe
 
 // Delete removes a backend from the repository.
 func (r *backendRepository) Delete(ctx context.Context, id uint) error {
+	ctx, done := instrumentQuery(ctx, "backend", "Delete")
+	defer done()
+
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		var dataModel BackendModel
 		err := tx.WithContext(ctx).First(&dataModel, id).Error
@@ -69,6 +75,9 @@ func (r *backendRepository) Delete(ctx context.Context, id uint) error {
 
 // Update updates an existing backend in the repository.
 func (r *backendRepository) Update(ctx context.Context, dataEntity *entity.Backend) error {
+	ctx, done := instrumentQuery(ctx, "backend", "Update")
+	defer done()
+
 	// Map the data from Entity to DO
 	var dataModel BackendModel
 	err := dataModel.FromEntity(dataEntity)
@@ -86,6 +95,9 @@ func (r *backendRepository) Update(ctx context.Context, dataEntity *entity.Backe
 
 // Get retrieves a backend by its ID.
 func (r *backendRepository) Get(ctx context.Context, id uint) (*entity.Backend, error) {
+	ctx, done := instrumentQuery(ctx, "backend", "Get")
+	defer done()
+
 	var dataModel BackendModel
 	err := r.db.WithContext(ctx).First(&dataModel, id).Error
 	if err != nil {
@@ -97,21 +109,29 @@ func (r *backendRepository) Get(ctx context.Context, id uint) (*entity.Backend,
 
 // List retrieves all backends.
 func (r *backendRepository) List(ctx context.Context, filter *entity.BackendFilter, sortOptions *entity.SortOptions) (*entity.BackendListResult, error) {
+	ctx, done := instrumentQuery(ctx, "backend", "List")
+	defer done()
+
 	var dataModel []BackendModel
 	backendEntityList := make([]*entity.Backend, 0)
+	pattern, args := GetBackendQuery(filter)
 
 	sortArgs := sortOptions.Field
 	if !sortOptions.Ascending {
 		sortArgs += " DESC"
 	}
 
+	searchResult := r.db.WithContext(ctx).
+		Order(sortArgs).
+		Where(pattern, args...)
+
 	// Get total rows.
 	var totalRows int64
-	r.db.WithContext(ctx).Model(dataModel).Count(&totalRows)
+	searchResult.Model(dataModel).Count(&totalRows)
 
 	// Fetch paginated data with offset and limit.
 	offset := (filter.Pagination.Page - 1) * filter.Pagination.PageSize
-	result := r.db.WithContext(ctx).Order(sortArgs).Offset(offset).Limit(filter.Pagination.PageSize).Find(&dataModel)
+	result := searchResult.Offset(offset).Limit(filter.Pagination.PageSize).Find(&dataModel)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -3,8 +3,6 @@ package persistence
 import (
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/domain/entity"
-
-	"gorm.io/gorm"
 )
 
 // type KusionBackend v1.BackendConfig
@@ -27,13 +25,14 @@ import (
 
 // BackendModel is a DO used to map the entity to the database.
 type BackendModel struct {
-	gorm.Model
-	Name          string `gorm:"index:unique_backend,unique"`
-	Type          string `gorm:"index:unique_backend,unique"`
-	Description   string
-	Labels        MultiString
-	Owners        MultiString
-	BackendConfig v1.BackendConfig `gorm:"serializer:json" json:"backendConfig"`
+	IndexedModel
+	Name           string `gorm:"index:unique_backend,unique"`
+	Type           string `gorm:"index:unique_backend,unique"`
+	Description    string
+	OrganizationID uint `gorm:"index"`
+	Labels         MultiString
+	Owners         MultiString
+	BackendConfig  v1.BackendConfig `gorm:"serializer:json" json:"backendConfig"`
 }
 
 // The TableName method returns the name of the database table that the struct is mapped to.
@@ -51,6 +50,7 @@ func (m *BackendModel) ToEntity() (*entity.Backend, error) {
 		ID:                m.ID,
 		Name:              m.Name,
 		Description:       m.Description,
+		OrganizationID:    m.OrganizationID,
 		CreationTimestamp: m.CreatedAt,
 		UpdateTimestamp:   m.UpdatedAt,
 		BackendConfig:     m.BackendConfig,
@@ -65,7 +65,9 @@ func (m *BackendModel) FromEntity(e *entity.Backend) error {
 
 	m.ID = e.ID
 	m.Name = e.Name
+	m.Type = e.BackendConfig.Type
 	m.Description = e.Description
+	m.OrganizationID = e.OrganizationID
 	m.CreatedAt = e.CreationTimestamp
 	m.UpdatedAt = e.UpdateTimestamp
 	m.BackendConfig = e.BackendConfig
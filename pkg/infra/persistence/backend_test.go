@@ -165,3 +165,21 @@ func TestBackendRepository(t *testing.T) {
 		require.Len(t, actual.Backends, 2)
 	})
 }
+
+func TestBackfillBackendTypes(t *testing.T) {
+	fakeGDB, sqlMock, err := GetMockDB()
+	require.NoError(t, err)
+	defer CloseDB(t, fakeGDB)
+	defer sqlMock.ExpectClose()
+
+	sqlMock.ExpectQuery("SELECT .* FROM `backend` WHERE type = ").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "backend_config"}).
+			AddRow(1, `{"type":"s3"}`).
+			AddRow(2, `{}`))
+	// only the backend with a typed config is backfilled
+	sqlMock.ExpectExec("UPDATE `backend` SET `type`=").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, backfillBackendTypes(fakeGDB))
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IndexedModel is a replacement of gorm.Model with the timestamps indexed, which are
+// used as the sort columns of the list queries.
+type IndexedModel struct {
+	ID        uint           `gorm:"primarykey"`
+	CreatedAt time.Time      `gorm:"index"`
+	UpdatedAt time.Time      `gorm:"index"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// requiredIndex describes the fields of a model which must be indexed, for they are
+// used by the filter or sort clauses of the list queries.
+type requiredIndex struct {
+	model  any
+	fields []string
+}
+
+// requiredIndexes are the indexes the list queries of projects, organizations and
+// backends rely on, the fields are the struct field names of the models.
+var requiredIndexes = []requiredIndex{
+	{
+		model:  &ProjectModel{},
+		fields: []string{"Name", "OrganizationID", "CreatedAt", "UpdatedAt"},
+	},
+	{
+		model:  &OrganizationModel{},
+		fields: []string{"Name", "CreatedAt", "UpdatedAt"},
+	},
+	{
+		model:  &BackendModel{},
+		fields: []string{"Name", "CreatedAt", "UpdatedAt"},
+	},
+}
+
+// VerifyIndexes checks that the indexes required by the list queries exist in the
+// database, which is intended to be called after the migration.
+func VerifyIndexes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, idx := range requiredIndexes {
+		for _, field := range idx.fields {
+			if !migrator.HasIndex(idx.model, field) {
+				return fmt.Errorf("missing index of field %s on %T", field, idx.model)
+			}
+		}
+	}
+	return nil
+}
+
+// legacyIndex describes an index of a model which has been superseded, and must be
+// dropped before the migration as AutoMigrate never drops or replaces existing indexes.
+type legacyIndex struct {
+	model any
+	name  string
+}
+
+// legacyIndexes are the unique indexes on the names of projects and organizations, which
+// are replaced by the soft-delete-aware unique indexes on the generated live names.
+var legacyIndexes = []legacyIndex{
+	{model: &ProjectModel{}, name: "unique_project"},
+	{model: &OrganizationModel{}, name: "unique_org"},
+}
+
+// dropLegacyIndexes drops the superseded indexes if they still exist in the database.
+func dropLegacyIndexes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, idx := range legacyIndexes {
+		if !migrator.HasTable(idx.model) || !migrator.HasIndex(idx.model, idx.name) {
+			continue
+		}
+		if err := migrator.DropIndex(idx.model, idx.name); err != nil {
+			return fmt.Errorf("failed to drop legacy index %s on %T: %w", idx.name, idx.model, err)
+		}
+	}
+	return nil
+}
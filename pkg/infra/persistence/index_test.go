@@ -0,0 +1,61 @@
+package persistence
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestRequiredIndexes(t *testing.T) {
+	for _, idx := range requiredIndexes {
+		s, err := schema.Parse(idx.model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		for _, field := range idx.fields {
+			require.NotNil(t, s.LookIndex(field), "missing index of field %s on %T", field, idx.model)
+		}
+	}
+}
+
+func TestSoftDeleteAwareUniqueIndexes(t *testing.T) {
+	testcases := []struct {
+		model     any
+		indexName string
+	}{
+		{model: &ProjectModel{}, indexName: "unique_live_project"},
+		{model: &OrganizationModel{}, indexName: "unique_live_org"},
+	}
+	for _, tc := range testcases {
+		s, err := schema.Parse(tc.model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		idx := s.LookIndex(tc.indexName)
+		require.NotNil(t, idx)
+		require.Equal(t, "UNIQUE", idx.Class)
+		require.Len(t, idx.Fields, 1)
+		require.Equal(t, "LiveName", idx.Fields[0].Name)
+
+		// the generated column must not be written by gorm
+		field := s.LookUpField("LiveName")
+		require.NotNil(t, field)
+		require.False(t, field.Creatable)
+		require.False(t, field.Updatable)
+	}
+}
+
+func TestAutoMigrateUnsupportedDialect(t *testing.T) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{})
+	require.NoError(t, err)
+	require.ErrorIs(t, AutoMigrate(db), ErrUnsupportedDialect)
+}
+
+func TestLegacyIndexes(t *testing.T) {
+	for _, idx := range legacyIndexes {
+		s, err := schema.Parse(idx.model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		// the legacy index must not be declared by the model, or the migration recreates it.
+		require.Nil(t, s.LookIndex(idx.name), "legacy index %s still declared on %T", idx.name, idx.model)
+	}
+}
@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sanitizedParam is the placeholder of the bound args that may contain sensitive data.
+const sanitizedParam = "******"
+
+var (
+	_ logger.Interface  = &slowQueryLogger{}
+	_ gorm.ParamsFilter = &slowQueryLogger{}
+)
+
+// slowQueryLogger is a gorm logger which logs the queries exceeding the threshold with
+// the SQL, sanitized bound args, duration and the originating repository method. Other
+// messages are delegated to the wrapped gorm logger.
+type slowQueryLogger struct {
+	logger.Interface
+	threshold time.Duration
+}
+
+// NewSlowQueryLogger creates a gorm logger logging the queries slower than the threshold,
+// slow query logging is disabled if the threshold is not positive.
+func NewSlowQueryLogger(threshold time.Duration) logger.Interface {
+	return &slowQueryLogger{
+		Interface: logger.Default.LogMode(logger.Silent),
+		threshold: threshold,
+	}
+}
+
+// LogMode sets the log level of the wrapped gorm logger.
+func (l *slowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &slowQueryLogger{
+		Interface: l.Interface.LogMode(level),
+		threshold: l.threshold,
+	}
+}
+
+// Trace logs the query if its duration exceeds the threshold.
+func (l *slowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	elapsed := time.Since(begin)
+	if l.threshold <= 0 || elapsed < l.threshold {
+		return
+	}
+
+	sql, rows := fc()
+	entity, method := repositoryMethodFromContext(ctx)
+	fields := logrus.Fields{
+		"entity":   entity,
+		"method":   method,
+		"duration": elapsed.String(),
+		"rows":     rows,
+		"sql":      sql,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	logrus.WithFields(fields).Warnf("slow query exceeding %s", l.threshold)
+}
+
+// ParamsFilter masks the string-like bound args before they are interpolated into the
+// logged SQL, in case that they contain sensitive data.
+func (l *slowQueryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	sanitized := make([]interface{}, len(params))
+	for i, param := range params {
+		switch param.(type) {
+		case string, *string, []byte:
+			sanitized[i] = sanitizedParam
+		default:
+			sanitized[i] = param
+		}
+	}
+	return sql, sanitized
+}
@@ -0,0 +1,28 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryLoggerParamsFilter(t *testing.T) {
+	l := &slowQueryLogger{}
+	name := "secret-name"
+	sql, params := l.ParamsFilter(context.TODO(), "name = ? AND id = ? AND token = ?", "foo", uint(1), &name)
+	assert.Equal(t, "name = ? AND id = ? AND token = ?", sql)
+	assert.Equal(t, []interface{}{sanitizedParam, uint(1), sanitizedParam}, params)
+}
+
+func TestRepositoryMethodFromContext(t *testing.T) {
+	entity, method := repositoryMethodFromContext(context.TODO())
+	assert.Empty(t, entity)
+	assert.Empty(t, method)
+
+	ctx, done := instrumentQuery(context.TODO(), "project", "List")
+	done()
+	entity, method = repositoryMethodFromContext(ctx)
+	assert.Equal(t, "project", entity)
+	assert.Equal(t, "List", method)
+}
@@ -0,0 +1,60 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// repositoryQueryDuration records the latency of the repository methods, labeled by
+// the entity and the method name, e.g. entity="project", method="List".
+var repositoryQueryDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "kusion",
+		Subsystem: "persistence",
+		Name:      "repository_query_duration_seconds",
+		Help:      "Latency of the persistence repository methods in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	},
+	[]string{"entity", "method"},
+)
+
+func init() {
+	prometheus.MustRegister(repositoryQueryDuration)
+}
+
+// repositoryMethodKey is the context key of the originating repository method.
+type repositoryMethodKey struct{}
+
+// repositoryMethod identifies the repository method a query originates from.
+type repositoryMethod struct {
+	entity string
+	method string
+}
+
+// instrumentQuery tags the context with the originating repository method and starts
+// timing it. It returns the tagged context and the function to stop the timing, which
+// is intended to be deferred at the beginning of the method:
+//
+//	ctx, done := instrumentQuery(ctx, "project", "List")
+//	defer done()
+func instrumentQuery(ctx context.Context, entity, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx = context.WithValue(ctx, repositoryMethodKey{}, repositoryMethod{entity: entity, method: method})
+	return ctx, func() {
+		repositoryQueryDuration.WithLabelValues(entity, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// repositoryMethodFromContext returns the originating repository method tagged by
+// instrumentQuery, or empty strings if the context is not tagged.
+func repositoryMethodFromContext(ctx context.Context) (entity, method string) {
+	if ctx == nil {
+		return "", ""
+	}
+	if m, ok := ctx.Value(repositoryMethodKey{}).(repositoryMethod); ok {
+		return m.entity, m.method
+	}
+	return "", ""
+}
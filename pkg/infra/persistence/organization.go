@@ -28,6 +28,9 @@ func NewOrganizationRepository(db *gorm.DB) repository.OrganizationRepository {
 
 // Create saves a organization to the repository.
 func (r *organizationRepository) Create(ctx context.Context, dataEntity *entity.Organization) error {
+	ctx, done := instrumentQuery(ctx, "organization", "Create")
+	defer done()
+
 	// r.db.AutoMigrate(&OrganizationModel{})
 	err := dataEntity.Validate()
 	if err != nil {
@@ -56,6 +59,9 @@ func (r *organizationRepository) Create(ctx context.Context, dataEntity *entity.
 
 // Delete removes a organization from the repository.
 func (r *organizationRepository) Delete(ctx context.Context, id uint) error {
+	ctx, done := instrumentQuery(ctx, "organization", "Delete")
+	defer done()
+
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		var dataModel OrganizationModel
 		err := tx.WithContext(ctx).First(&dataModel, id).Error
@@ -69,6 +75,9 @@ func (r *organizationRepository) Delete(ctx context.Context, id uint) error {
 
 // Update updates an existing organization in the repository.
 func (r *organizationRepository) Update(ctx context.Context, dataEntity *entity.Organization) error {
+	ctx, done := instrumentQuery(ctx, "organization", "Update")
+	defer done()
+
 	// Map the data from Entity to DO
 	var dataModel OrganizationModel
 	err := dataModel.FromEntity(dataEntity)
@@ -86,6 +95,9 @@ func (r *organizationRepository) Update(ctx context.Context, dataEntity *entity.
 
 // Get retrieves a organization by its ID.
 func (r *organizationRepository) Get(ctx context.Context, id uint) (*entity.Organization, error) {
+	ctx, done := instrumentQuery(ctx, "organization", "Get")
+	defer done()
+
 	var dataModel OrganizationModel
 	err := r.db.WithContext(ctx).First(&dataModel, id).Error
 	if err != nil {
@@ -97,6 +109,9 @@ func (r *organizationRepository) Get(ctx context.Context, id uint) (*entity.Orga
 
 // GetByName retrieves a organization by its name.
 func (r *organizationRepository) GetByName(ctx context.Context, name string) (*entity.Organization, error) {
+	ctx, done := instrumentQuery(ctx, "organization", "GetByName")
+	defer done()
+
 	var dataModel OrganizationModel
 	err := r.db.WithContext(ctx).Where("name = ?", name).First(&dataModel).Error
 	if err != nil {
@@ -107,6 +122,9 @@ func (r *organizationRepository) GetByName(ctx context.Context, name string) (*e
 
 // List retrieves all organizations.
 func (r *organizationRepository) List(ctx context.Context, filter *entity.OrganizationFilter, sortOptions *entity.SortOptions) (*entity.OrganizationListResult, error) {
+	ctx, done := instrumentQuery(ctx, "organization", "List")
+	defer done()
+
 	var dataModel []OrganizationModel
 	organizationEntityList := make([]*entity.Organization, 0)
 
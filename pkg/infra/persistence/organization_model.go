@@ -2,17 +2,21 @@ package persistence
 
 import (
 	"kusionstack.io/kusion/pkg/domain/entity"
-
-	"gorm.io/gorm"
 )
 
 // OrganizationModel is a DO used to map the entity to the database.
 type OrganizationModel struct {
-	gorm.Model
-	Name        string `gorm:"index:unique_org,unique"`
+	IndexedModel
+	Name        string `gorm:"index"`
 	Description string
 	Labels      MultiString
 	Owners      MultiString
+
+	// LiveName is the name of an organization not soft deleted, which is generated from the Name and
+	// DeletedAt, and NULL for the soft deleted ones. The unique index on it emulates a partial
+	// unique index of the name among live records, so that the names are reusable after soft deletion.
+	// The generated column is MySQL specific, which is the only database AutoMigrate supports.
+	LiveName *string `gorm:"->;type:varchar(191) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) STORED;index:unique_live_org,unique"`
 }
 
 // The TableName method returns the name of the database table that the struct is mapped to.
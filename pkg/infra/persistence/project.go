@@ -27,6 +27,9 @@ func NewProjectRepository(db *gorm.DB) repository.ProjectRepository {
 
 // Create saves a project to the repository.
 func (r *projectRepository) Create(ctx context.Context, dataEntity *entity.Project) error {
+	ctx, done := instrumentQuery(ctx, "project", "Create")
+	defer done()
+
 	// r.db.AutoMigrate(&ProjectModel{})
 	err := dataEntity.Validate()
 	if err != nil {
@@ -55,6 +58,9 @@ func (r *projectRepository) Create(ctx context.Context, dataEntity *entity.Proje
 
 // Delete removes a project from the repository.
 func (r *projectRepository) Delete(ctx context.Context, id uint) error {
+	ctx, done := instrumentQuery(ctx, "project", "Delete")
+	defer done()
+
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		var dataModel ProjectModel
 		err := tx.WithContext(ctx).First(&dataModel, id).Error
@@ -68,6 +74,9 @@ func (r *projectRepository) Delete(ctx context.Context, id uint) error {
 
 // Update updates an existing project in the repository.
 func (r *projectRepository) Update(ctx context.Context, dataEntity *entity.Project) error {
+	ctx, done := instrumentQuery(ctx, "project", "Update")
+	defer done()
+
 	// Map the data from Entity to DO
 	var dataModel ProjectModel
 	err := dataModel.FromEntity(dataEntity)
@@ -85,6 +94,9 @@ func (r *projectRepository) Update(ctx context.Context, dataEntity *entity.Proje
 
 // Get retrieves a project by its ID.
 func (r *projectRepository) Get(ctx context.Context, id uint) (*entity.Project, error) {
+	ctx, done := instrumentQuery(ctx, "project", "Get")
+	defer done()
+
 	var dataModel ProjectModel
 	err := r.db.WithContext(ctx).
 		Preload("Source").
@@ -99,6 +111,9 @@ func (r *projectRepository) Get(ctx context.Context, id uint) (*entity.Project,
 
 // GetByName retrieves a project by its name.
 func (r *projectRepository) GetByName(ctx context.Context, name string) (*entity.Project, error) {
+	ctx, done := instrumentQuery(ctx, "project", "GetByName")
+	defer done()
+
 	var dataModel ProjectModel
 	err := r.db.WithContext(ctx).
 		Where("name = ?", name).
@@ -111,6 +126,9 @@ func (r *projectRepository) GetByName(ctx context.Context, name string) (*entity
 
 // List retrieves all projects.
 func (r *projectRepository) List(ctx context.Context, filter *entity.ProjectFilter, sortOptions *entity.SortOptions) (*entity.ProjectListResult, error) {
+	ctx, done := instrumentQuery(ctx, "project", "List")
+	defer done()
+
 	var dataModel []ProjectModel
 	projectEntityList := make([]*entity.Project, 0)
 	pattern, args := GetProjectQuery(filter)
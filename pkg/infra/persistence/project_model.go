@@ -2,22 +2,26 @@ package persistence
 
 import (
 	"kusionstack.io/kusion/pkg/domain/entity"
-
-	"gorm.io/gorm"
 )
 
 // ProjectModel is a DO used to map the entity to the database.
 type ProjectModel struct {
-	gorm.Model
-	Name           string `gorm:"index:unique_project,unique"`
+	IndexedModel
+	Name           string `gorm:"index"`
 	SourceID       uint
 	Source         *SourceModel
-	OrganizationID uint
+	OrganizationID uint `gorm:"index"`
 	Organization   *OrganizationModel
 	Path           string
 	Description    string
 	Labels         MultiString
 	Owners         MultiString
+
+	// LiveName is the name of a project not soft deleted, which is generated from the Name and
+	// DeletedAt, and NULL for the soft deleted ones. The unique index on it emulates a partial
+	// unique index of the name among live records, so that the names are reusable after soft deletion.
+	// The generated column is MySQL specific, which is the only database AutoMigrate supports.
+	LiveName *string `gorm:"->;type:varchar(191) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) STORED;index:unique_live_project,unique"`
 }
 
 // The TableName method returns the name of the database table that the struct is mapped to.
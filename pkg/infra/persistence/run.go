@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"gorm.io/gorm"
+	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/repository"
 )
@@ -81,6 +82,37 @@ func (r *runRepository) Update(ctx context.Context, dataEntity *entity.Run) erro
 	return nil
 }
 
+// CompareAndUpdate updates the non-zero fields of the run only if its current status is the expected status, so
+// that the status changed concurrently by others is never overwritten.
+func (r *runRepository) CompareAndUpdate(ctx context.Context, dataEntity *entity.Run, expectedStatus constant.RunStatus) (bool, error) {
+	// Map the data from Entity to DO
+	var dataModel RunModel
+	err := dataModel.FromEntity(dataEntity)
+	if err != nil {
+		return false, err
+	}
+
+	result := r.db.WithContext(ctx).Model(&dataModel).
+		Where("status = ?", string(expectedStatus)).
+		Updates(&dataModel)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ConsumePlan marks the plan of the preview run as consumed only if it has not been consumed yet,
+// so that the plan is applied at most once even if it is applied concurrently.
+func (r *runRepository) ConsumePlan(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&RunModel{}).
+		Where("id = ? AND plan_consumed = ?", id, false).
+		Update("plan_consumed", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // Get retrieves a run by its ID.
 func (r *runRepository) Get(ctx context.Context, id uint) (*entity.Run, error) {
 	var dataModel RunModel
@@ -1,8 +1,10 @@
 package persistence
 
 import (
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
 
 	"gorm.io/gorm"
 )
@@ -26,6 +28,14 @@ type RunModel struct {
 	Logs string
 	// Trace is the trace of the run.
 	Trace string
+	// ResourceSummary is the summary of the resources generated by the run.
+	ResourceSummary *v1.ResourceSummary `gorm:"serializer:json"`
+	// ChangeSummary is the breakdown of the previewed changes by the resource kind.
+	ChangeSummary []*models.KindChangeSummary `gorm:"serializer:json"`
+	// Approvals records who approved the paused apply run to continue.
+	Approvals []*entity.RunApproval `gorm:"serializer:json"`
+	// PlanConsumed indicates the plan of the preview run has been applied.
+	PlanConsumed bool
 }
 
 // The TableName method returns the name of the database table that the struct is mapped to.
@@ -66,6 +76,10 @@ func (m *RunModel) ToEntity() (*entity.Run, error) {
 		Result:            m.Result,
 		Trace:             m.Trace,
 		Logs:              m.Logs,
+		ResourceSummary:   m.ResourceSummary,
+		ChangeSummary:     m.ChangeSummary,
+		Approvals:         m.Approvals,
+		PlanConsumed:      m.PlanConsumed,
 		CreationTimestamp: m.CreatedAt,
 		UpdateTimestamp:   m.UpdatedAt,
 	}, nil
@@ -89,6 +103,10 @@ func (m *RunModel) FromEntity(e *entity.Run) error {
 	m.Result = e.Result
 	m.Logs = e.Logs
 	m.Trace = e.Trace
+	m.ResourceSummary = e.ResourceSummary
+	m.ChangeSummary = e.ChangeSummary
+	m.Approvals = e.Approvals
+	m.PlanConsumed = e.PlanConsumed
 	m.CreatedAt = e.CreationTimestamp
 	m.UpdatedAt = e.UpdateTimestamp
 
@@ -105,6 +105,49 @@ func TestRunRepository(t *testing.T) {
 		require.ErrorIs(t, err, gorm.ErrMissingWhereClause)
 	})
 
+	t.Run("CompareAndUpdate", func(t *testing.T) {
+		fakeGDB, sqlMock, err := GetMockDB()
+		require.NoError(t, err)
+		repo := NewRunRepository(fakeGDB)
+		defer CloseDB(t, fakeGDB)
+		defer sqlMock.ExpectClose()
+
+		run := &entity.Run{ID: 1, Status: constant.RunStatusWaitingApproval}
+		sqlMock.ExpectExec("UPDATE `run` SET .* WHERE status = .*").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		updated, err := repo.CompareAndUpdate(context.Background(), run, constant.RunStatusInProgress)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		// The run whose status has been changed by others is not updated
+		sqlMock.ExpectExec("UPDATE `run` SET .* WHERE status = .*").
+			WillReturnResult(sqlmock.NewResult(1, 0))
+		updated, err = repo.CompareAndUpdate(context.Background(), run, constant.RunStatusInProgress)
+		require.NoError(t, err)
+		require.False(t, updated)
+	})
+
+	t.Run("ConsumePlan", func(t *testing.T) {
+		fakeGDB, sqlMock, err := GetMockDB()
+		require.NoError(t, err)
+		repo := NewRunRepository(fakeGDB)
+		defer CloseDB(t, fakeGDB)
+		defer sqlMock.ExpectClose()
+
+		sqlMock.ExpectExec("UPDATE `run` SET `plan_consumed`").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		consumed, err := repo.ConsumePlan(context.Background(), 1)
+		require.NoError(t, err)
+		require.True(t, consumed)
+
+		// The plan consumed by others is not updated again
+		sqlMock.ExpectExec("UPDATE `run` SET `plan_consumed`").
+			WillReturnResult(sqlmock.NewResult(1, 0))
+		consumed, err = repo.ConsumePlan(context.Background(), 1)
+		require.NoError(t, err)
+		require.False(t, consumed)
+	})
+
 	t.Run("Get", func(t *testing.T) {
 		fakeGDB, sqlMock, err := GetMockDB()
 		require.NoError(t, err)
@@ -28,4 +28,5 @@ var (
 	ErrRunModelNil                    = errors.New("run model can't be nil")
 	ErrFailedToGetRunType             = errors.New("failed to parse run type")
 	ErrFailedToGetRunStatus           = errors.New("failed to parse run status")
+	ErrUnsupportedDialect             = errors.New("auto migration only supports mysql")
 )
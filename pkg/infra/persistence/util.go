@@ -126,6 +126,21 @@ func GetStackQuery(filter *entity.StackFilter) (string, []interface{}) {
 	return CombineQueryParts(pattern), args
 }
 
+func GetBackendQuery(filter *entity.BackendFilter) (string, []interface{}) {
+	pattern := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.OrgScoped {
+		// global backends are visible to all organizations
+		pattern = append(pattern, "(organization_id = ? OR organization_id = 0)")
+		args = append(args, fmt.Sprint(filter.OrgID))
+	}
+	if filter.Type != "" {
+		pattern = append(pattern, "type = ?")
+		args = append(args, filter.Type)
+	}
+	return CombineQueryParts(pattern), args
+}
+
 func GetWorkspaceQuery(filter *entity.WorkspaceFilter) (string, []interface{}) {
 	pattern := make([]string, 0)
 	args := make([]interface{}, 0)
@@ -242,10 +257,21 @@ func CombineQueryParts(queryParts []string) string {
 	return queryString
 }
 
+// AutoMigrate migrates the tables of all the models. Only MySQL is supported, since the
+// soft-delete-aware unique indexes of projects and organizations rely on MySQL generated columns.
 func AutoMigrate(db *gorm.DB) error {
+	if db.Dialector.Name() != "mysql" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedDialect, db.Dialector.Name())
+	}
+	if err := dropLegacyIndexes(db); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&BackendModel{}); err != nil {
 		return err
 	}
+	if err := backfillBackendTypes(db); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&SourceModel{}); err != nil {
 		return err
 	}
@@ -270,5 +296,23 @@ func AutoMigrate(db *gorm.DB) error {
 	if err := db.AutoMigrate(&RunModel{}); err != nil {
 		return err
 	}
-	return nil
+	return VerifyIndexes(db)
+}
+
+// backfillBackendTypes fills the type column of the backends persisted before the column was
+// written, whose types are only kept in the backend configs.
+func backfillBackendTypes(db *gorm.DB) error {
+	var models []BackendModel
+	return db.Unscoped().Where("type = ?", "").FindInBatches(&models, 100, func(_ *gorm.DB, _ int) error {
+		for _, m := range models {
+			if m.BackendConfig.Type == "" {
+				continue
+			}
+			if err := db.Unscoped().Model(&BackendModel{}).Where("id = ?", m.ID).
+				UpdateColumn("type", m.BackendConfig.Type).Error; err != nil {
+				return fmt.Errorf("failed to backfill the type of backend %d: %w", m.ID, err)
+			}
+		}
+		return nil
+	}).Error
 }
@@ -99,6 +99,47 @@ func TestGetWorkspaceQuery(t *testing.T) {
 	}
 }
 
+func TestGetBackendQuery(t *testing.T) {
+	testcases := []struct {
+		name          string
+		filter        *entity.BackendFilter
+		expectedQuery string
+		expectedArgs  []interface{}
+	}{
+		{
+			name:          "not scoped to organizations",
+			filter:        &entity.BackendFilter{OrgID: 42},
+			expectedQuery: "",
+			expectedArgs:  []interface{}{},
+		},
+		{
+			name: "scoped to organization",
+			filter: &entity.BackendFilter{
+				OrgScoped: true,
+				OrgID:     42,
+			},
+			expectedQuery: "(organization_id = ? OR organization_id = 0)",
+			expectedArgs:  []interface{}{"42"},
+		},
+		{
+			name: "filter by type",
+			filter: &entity.BackendFilter{
+				Type: "s3",
+			},
+			expectedQuery: "type = ?",
+			expectedArgs:  []interface{}{"s3"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args := GetBackendQuery(tc.filter)
+			assert.Equal(t, tc.expectedQuery, query)
+			assert.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
 func TestGetStackQuery(t *testing.T) {
 	testcases := []struct {
 		name          string
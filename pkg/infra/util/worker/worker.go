@@ -2,18 +2,23 @@ package worker
 
 import (
 	"sync"
+	"time"
 )
 
 type WorkerPool struct {
 	tasks               chan func() // use channel to store tasks
 	wg                  sync.WaitGroup
-	numAvailableWorkers int        // number of available workers
-	mu                  sync.Mutex // lock read/write of numAvailableWorkers
+	numWorkers          int           // number of workers
+	numAvailableWorkers int           // number of available workers
+	numCompletedTasks   int           // number of tasks completed
+	totalTaskDuration   time.Duration // total duration of the completed tasks
+	mu                  sync.Mutex    // lock read/write of numAvailableWorkers and the task statistics
 }
 
 func NewWorkerPool(maxConcurrentGoroutines, maxBufferGoroutines int) *WorkerPool {
 	pool := &WorkerPool{
 		tasks:               make(chan func(), maxBufferGoroutines),
+		numWorkers:          maxConcurrentGoroutines,
 		numAvailableWorkers: maxConcurrentGoroutines, // initialize worker count
 	}
 
@@ -24,10 +29,13 @@ func NewWorkerPool(maxConcurrentGoroutines, maxBufferGoroutines int) *WorkerPool
 				pool.numAvailableWorkers-- // lower worker count
 				pool.mu.Unlock()
 
+				start := time.Now()
 				task() // execute the task
 
 				pool.mu.Lock()
 				pool.numAvailableWorkers++ // increase worker count
+				pool.numCompletedTasks++
+				pool.totalTaskDuration += time.Since(start)
 				pool.mu.Unlock()
 			}
 		}()
@@ -57,6 +65,26 @@ func (p *WorkerPool) Do(task func()) bool {
 	return inBufferZone
 }
 
+// Pending returns the number of tasks in the buffer zone waiting for an available worker, which is the
+// position in the queue of the task just added to the buffer zone
+func (p *WorkerPool) Pending() int {
+	return len(p.tasks)
+}
+
+// EstimatedWait roughly estimates how long the task at the position in the queue waits for an available worker,
+// based on the average duration of the completed tasks. Zero is returned if no task has completed yet.
+func (p *WorkerPool) EstimatedWait(position int) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.numCompletedTasks == 0 || p.numWorkers <= 0 || position <= 0 {
+		return 0
+	}
+	averageDuration := p.totalTaskDuration / time.Duration(p.numCompletedTasks)
+	// The tasks ahead are executed by all the workers in rounds
+	rounds := (position + p.numWorkers - 1) / p.numWorkers
+	return averageDuration * time.Duration(rounds)
+}
+
 // Wait for all tasks before closing the channel
 func (p *WorkerPool) Wait() {
 	p.wg.Wait()
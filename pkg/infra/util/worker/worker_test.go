@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_Pending(t *testing.T) {
+	pool := NewWorkerPool(1, 2)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	assert.False(t, pool.Do(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	assert.Equal(t, 0, pool.Pending())
+
+	// The worker is busy, the tasks are queued in the buffer zone
+	assert.True(t, pool.Do(func() {}))
+	assert.Equal(t, 1, pool.Pending())
+	assert.True(t, pool.Do(func() {}))
+	assert.Equal(t, 2, pool.Pending())
+
+	close(release)
+	pool.Wait()
+	assert.Equal(t, 0, pool.Pending())
+}
+
+func TestWorkerPool_EstimatedWait(t *testing.T) {
+	pool := &WorkerPool{numWorkers: 2}
+	assert.Equal(t, time.Duration(0), pool.EstimatedWait(1))
+
+	pool.numCompletedTasks = 2
+	pool.totalTaskDuration = 4 * time.Minute
+	assert.Equal(t, 2*time.Minute, pool.EstimatedWait(1))
+	assert.Equal(t, 2*time.Minute, pool.EstimatedWait(2))
+	assert.Equal(t, 4*time.Minute, pool.EstimatedWait(3))
+	assert.Equal(t, time.Duration(0), pool.EstimatedWait(0))
+}
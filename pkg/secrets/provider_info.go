@@ -0,0 +1,81 @@
+package secrets
+
+// ProviderInfo describes a registered secret store provider and how to configure it in the SecretStore.
+type ProviderInfo struct {
+	// Name is the name of the provider.
+	Name string `json:"name"`
+	// Spec is the key of the provider config in the provider spec of the SecretStore, which is onpremises
+	// for the providers registered on premises.
+	Spec string `json:"spec"`
+	// Fields are the fields of the provider config.
+	Fields []ProviderField `json:"fields"`
+}
+
+// ProviderField describes a field of the provider config.
+type ProviderField struct {
+	// Name is the name of the field in the provider config.
+	Name string `json:"name"`
+	// Type is the type of the field, e.g. string, []object.
+	Type string `json:"type"`
+	// Required reports whether the field must be set.
+	Required bool `json:"required"`
+	// Value is the value the field must be set to if any, e.g. the name of an on-premises provider.
+	Value string `json:"value,omitempty"`
+}
+
+// onPremisesSpec is the key of the config of the providers registered on premises.
+const onPremisesSpec = "onpremises"
+
+// providerFields are the fields of the configs of the built-in providers keyed by their keys in the
+// provider spec, which are declared along with the provider configs in the api package.
+var providerFields = map[string][]ProviderField{
+	"alicloud": {
+		{Name: "region", Type: "string", Required: true},
+	},
+	// the region and the profile fall back to the shared config of AWS, e.g. AWS_REGION
+	"aws": {
+		{Name: "region", Type: "string"},
+		{Name: "profile", Type: "string"},
+	},
+	"azure": {
+		{Name: "vaultUrl", Type: "string", Required: true},
+		{Name: "tenantId", Type: "string", Required: true},
+		{Name: "environmentType", Type: "string"},
+	},
+	"fake": {
+		{Name: "data", Type: "[]object"},
+	},
+	// the version defaults to v2
+	"vault": {
+		{Name: "server", Type: "string", Required: true},
+		{Name: "path", Type: "string"},
+		{Name: "version", Type: "string"},
+	},
+	"viettelcloud": {
+		{Name: "cmpURL", Type: "string", Required: true},
+		{Name: "projectID", Type: "string", Required: true},
+	},
+}
+
+// ListProviders returns the registered secret store providers in order of name, along with the fields of
+// their configs. The providers registered on premises are configured by their names and attributes.
+func ListProviders() []ProviderInfo {
+	names := secretStoreProviders.names()
+	infos := make([]ProviderInfo, 0, len(names))
+	for _, name := range names {
+		info := ProviderInfo{
+			Name:   name,
+			Spec:   name,
+			Fields: providerFields[name],
+		}
+		if info.Fields == nil {
+			info.Spec = onPremisesSpec
+			info.Fields = []ProviderField{
+				{Name: "name", Type: "string", Required: true, Value: name},
+				{Name: "attributes", Type: "map[string]string"},
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"golang.org/x/exp/maps"
@@ -49,6 +51,22 @@ func GetProvider(spec *v1.ProviderSpec) (SecretStoreProvider, bool) {
 	return secretStoreProviders.getProviderByName(providerName)
 }
 
+// ValidateProviderSpec validates the provider spec specifies exactly one provider, which is registered.
+func ValidateProviderSpec(spec *v1.ProviderSpec) error {
+	if spec == nil {
+		return errors.New("secret store provider spec is missing")
+	}
+	providerName, err := getProviderName(spec)
+	if err != nil {
+		return err
+	}
+	if _, found := secretStoreProviders.getProviderByName(providerName); !found {
+		return fmt.Errorf("secret store provider %s is not registered, registered providers: %s",
+			providerName, strings.Join(secretStoreProviders.names(), ", "))
+	}
+	return nil
+}
+
 type Providers struct {
 	lock     sync.RWMutex
 	registry map[string]SecretStoreProvider
@@ -85,6 +103,15 @@ func (ps *Providers) getProviderByName(providerName string) (SecretStoreProvider
 	return provider, found
 }
 
+// names returns the names of the registered providers in order.
+func (ps *Providers) names() []string {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+	names := maps.Keys(ps.registry)
+	sort.Strings(names)
+	return names
+}
+
 func getProviderName(spec *v1.ProviderSpec) (string, error) {
 	if spec.OnPremises != nil {
 		if spec.OnPremises.Name == "" {
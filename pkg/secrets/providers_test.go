@@ -77,3 +77,56 @@ func TestRegister(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateProviderSpec(t *testing.T) {
+	Register(&FakeSecretStoreProvider{}, &v1.ProviderSpec{Alicloud: &v1.AlicloudProvider{}})
+
+	assert.NoError(t, ValidateProviderSpec(&v1.ProviderSpec{Alicloud: &v1.AlicloudProvider{Region: "cn-beijing"}}))
+	assert.ErrorContains(t, ValidateProviderSpec(&v1.ProviderSpec{Azure: &v1.AzureKVProvider{}}),
+		"secret store provider azure is not registered")
+	assert.ErrorContains(t, ValidateProviderSpec(&v1.ProviderSpec{}),
+		"secret stores must only have exactly one provider specified, found 0")
+	assert.Error(t, ValidateProviderSpec(nil))
+}
+
+func TestListProviders(t *testing.T) {
+	Register(&FakeSecretStoreProvider{}, &v1.ProviderSpec{Vault: &v1.VaultProvider{}})
+	Register(&FakeSecretStoreProvider{}, &v1.ProviderSpec{OnPremises: &v1.OnPremisesProvider{Name: "myplatform"}})
+
+	infos := make(map[string]ProviderInfo)
+	var names []string
+	for _, info := range ListProviders() {
+		infos[info.Name] = info
+		names = append(names, info.Name)
+	}
+	assert.IsNonDecreasing(t, names)
+
+	assert.Equal(t, ProviderInfo{
+		Name: "vault",
+		Spec: "vault",
+		Fields: []ProviderField{
+			{Name: "server", Type: "string", Required: true},
+			{Name: "path", Type: "string"},
+			{Name: "version", Type: "string"},
+		},
+	}, infos["vault"])
+	assert.Equal(t, ProviderInfo{
+		Name: "myplatform",
+		Spec: "onpremises",
+		Fields: []ProviderField{
+			{Name: "name", Type: "string", Required: true, Value: "myplatform"},
+			{Name: "attributes", Type: "map[string]string"},
+		},
+	}, infos["myplatform"])
+
+	Register(&FakeSecretStoreProvider{}, &v1.ProviderSpec{AWS: &v1.AWSProvider{}})
+	for _, info := range ListProviders() {
+		if info.Name == "aws" {
+			// the region of AWS falls back to the shared config
+			assert.Equal(t, []ProviderField{
+				{Name: "region", Type: "string"},
+				{Name: "profile", Type: "string"},
+			}, info.Fields)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 	"kusionstack.io/kusion/pkg/domain/entity"
 )
@@ -16,9 +18,11 @@ type Config struct {
 	MaxConcurrent      int
 	MaxAsyncConcurrent int
 	MaxAsyncBuffer     int
+	MaxRunTimeout      time.Duration
 	LogFilePath        string
 	AutoMigrate        bool
 	DevPortalEnabled   bool
+	BackendOrgScoped   bool
 }
 
 func NewConfig() *Config {
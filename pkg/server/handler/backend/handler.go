@@ -158,6 +158,7 @@ func (h *Handler) GetBackend() http.HandlerFunc {
 // @Description	List all backends
 // @Tags			backend
 // @Produce		json
+// @Param			type		query		string														false	"Backend type to filter backend list by, one of local, oss, s3 and google. Default to all backends."
 // @Param			page		query		uint														false	"The current page to fetch. Default to 1"
 // @Param			pageSize	query		uint														false	"The size of the page. Default to 10"
 // @Param			sortBy		query		string														false	"Which field to sort the list by. Default to id"
@@ -19,6 +19,7 @@ import (
 	"kusionstack.io/kusion/pkg/infra/persistence"
 	"kusionstack.io/kusion/pkg/server/handler"
 	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
+	appmiddleware "kusionstack.io/kusion/pkg/server/middleware"
 )
 
 func TestBackendHandler(t *testing.T) {
@@ -92,6 +93,41 @@ func TestBackendHandler(t *testing.T) {
 		assert.Equal(t, backendName, resp.Data.(map[string]any)["name"])
 	})
 
+	t.Run("Get Backend Scoped To Other Organization", func(t *testing.T) {
+		sqlMock, fakeGDB, recorder, backendHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		defer sqlMock.ExpectClose()
+		backendHandler.backendManager = backendmanager.NewBackendManager(
+			persistence.NewBackendRepository(fakeGDB), persistence.NewOrganizationRepository(fakeGDB), true)
+
+		sqlMock.ExpectQuery("SELECT .* FROM `backend`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "organization_id"}).
+				AddRow(1, backendName, 2))
+		sqlMock.ExpectQuery("SELECT .* FROM `organization`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+				AddRow(1, "org-a"))
+
+		// The organization of the requester is taken from the authenticated context rather than the query
+		req, err := http.NewRequest("GET", "/backends/{backendID}?orgID=2", nil)
+		assert.NoError(t, err)
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("backendID", "1")
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		req = req.WithContext(context.WithValue(ctx, appmiddleware.OrgNameKey, "org-a"))
+
+		backendHandler.GetBackend()(recorder, req)
+
+		var resp handler.Response
+		err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+
+		assert.Equal(t, false, resp.Success)
+		assert.Equal(t, backendmanager.ErrGettingNonExistingBackend.Error(), resp.Message)
+	})
+
 	t.Run("CreateBackend", func(t *testing.T) {
 		sqlMock, fakeGDB, recorder, backendHandler := setupTest(t)
 		defer persistence.CloseDB(t, fakeGDB)
@@ -303,7 +339,7 @@ func setupTest(t *testing.T) (sqlmock.Sqlmock, *gorm.DB, *httptest.ResponseRecor
 	require.NoError(t, err)
 	backendRepo := persistence.NewBackendRepository(fakeGDB)
 	backendHandler := &Handler{
-		backendManager: backendmanager.NewBackendManager(backendRepo),
+		backendManager: backendmanager.NewBackendManager(backendRepo, persistence.NewOrganizationRepository(fakeGDB), false),
 	}
 	recorder := httptest.NewRecorder()
 	return sqlMock, fakeGDB, recorder, backendHandler
@@ -9,6 +9,7 @@ import (
 	"github.com/go-chi/httplog/v2"
 	"github.com/go-chi/render"
 	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
 	"kusionstack.io/kusion/pkg/domain/response"
 	"kusionstack.io/kusion/pkg/server/handler"
 	resourcemanager "kusionstack.io/kusion/pkg/server/manager/resource"
@@ -95,6 +96,54 @@ func (h *Handler) GetResource() http.HandlerFunc {
 	}
 }
 
+// @Id				scanImportedResources
+// @Summary		Scan resources to import
+// @Description	Scan the existing resources of a provider by tags with the credentials of the workspace and generate the importedResources mapping
+// @Tags			resource
+// @Accept			json
+// @Produce		json
+// @Param			request	body		request.ScanImportedResourcesRequest								true	"Provider and tag filters to scan"
+// @Success		200		{object}	handler.Response{data=response.ScanImportedResourcesResponse}	"Success"
+// @Failure		400		{object}	error															"Bad Request"
+// @Failure		401		{object}	error															"Unauthorized"
+// @Failure		429		{object}	error															"Too Many Requests"
+// @Failure		404		{object}	error															"Not Found"
+// @Failure		500		{object}	error															"Internal Server Error"
+// @Router			/api/v1/resources/scan [post]
+func (h *Handler) ScanImportedResources() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx := r.Context()
+		logger := logutil.GetLogger(ctx)
+		logger.Info("Scanning resources to import...")
+
+		// Decode the request body into the payload.
+		var requestPayload request.ScanImportedResourcesRequest
+		if err := requestPayload.Decode(r); err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		// Validate request payload
+		if err := requestPayload.Validate(); err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		importedResources, collisions, err := h.resourceManager.ScanImportedResources(ctx, requestPayload)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		handler.HandleResult(w, r, ctx, err, response.ScanImportedResourcesResponse{
+			ImportedResources: importedResources,
+			Total:             len(importedResources),
+			Collisions:        collisions,
+		})
+	}
+}
+
 // @Id				getResourceGraph
 // @Summary		Get resource graph
 // @Description	Get resource graph by stack ID
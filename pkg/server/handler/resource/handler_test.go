@@ -147,7 +147,7 @@ func setupTest(t *testing.T) (sqlmock.Sqlmock, *gorm.DB, *httptest.ResponseRecor
 	require.NoError(t, err)
 	resourceRepo := persistence.NewResourceRepository(fakeGDB)
 	resourceHandler := &Handler{
-		resourceManager: resourcemanager.NewResourceManager(resourceRepo),
+		resourceManager: resourcemanager.NewResourceManager(resourceRepo, nil),
 	}
 	recorder := httptest.NewRecorder()
 	return sqlMock, fakeGDB, recorder, resourceHandler
@@ -11,6 +11,7 @@ import (
 	_ "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 
 	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/domain/response"
 	"kusionstack.io/kusion/pkg/server/handler"
 	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
 )
@@ -24,7 +25,7 @@ import (
 // @Param			importedResources	body		request.StackImportRequest				false	"The resources to import during the stack preview"
 // @Param			workspace			query		string									true	"The target workspace to preview the spec in."
 // @Param			importResources		query		bool									false	"Import existing resources during the stack preview"
-// @Param			output				query		string									false	"Output format. Choices are: json, default. Default to default output format in Kusion."
+// @Param			output				query		string									false	"Output format. Choices are: json, summary, default. Default to default output format in Kusion."
 // @Param			detail				query		bool									false	"Show detailed output"
 // @Param			specID				query		string									false	"The Spec ID to use for the preview. Default to the last one generated."
 // @Param			force				query		bool									false	"Force the preview even when the stack is locked"
@@ -83,6 +84,10 @@ func (h *Handler) PreviewStack() http.HandlerFunc {
 // @Param			workspace	query		string							true	"The target workspace to preview the spec in."
 // @Param			format		query		string							false	"The format to generate the spec in. Choices are: spec. Default to spec."
 // @Param			force		query		bool							false	"Force the generate even when the stack is locked"
+// @Param			workloadOnly	query		bool							false	"Generate only the namespace and workload, skipping all accessories"
+// @Param			withSummary	query		bool							false	"Return the spec along with the number of resources generated for each app and module"
+// @Param			strictSecretStore	query		bool							false	"Fail the generation if the external secrets reference no secret store, which is only warned by default"
+// @Param			nonStrict	query		bool							false	"Continue the generation past the failures of the built-in generators, e.g. an invalid namespace"
 // @Success		200			{object}	handler.Response{data=v1.Spec}	"Success"
 // @Failure		400			{object}	error							"Bad Request"
 // @Failure		401			{object}	error							"Unauthorized"
@@ -101,13 +106,20 @@ func (h *Handler) GenerateStack() http.HandlerFunc {
 		logger.Info("Generating stack...", "stackID", params.StackID)
 
 		// Call generate stack
-		_, sp, err := h.stackManager.GenerateSpec(ctx, params)
+		sp, summary, err := h.stackManager.GenerateSpec(ctx, params)
 		if err != nil {
 			render.Render(w, r, handler.FailureResponse(ctx, err))
 			return
 		}
 
 		yaml, err := yamlv2.Marshal(sp)
+		if params.ExecuteParams.WithSummary {
+			handler.HandleResult(w, r, ctx, err, response.GenerateStackResponse{
+				Spec:            string(yaml),
+				ResourceSummary: summary,
+			})
+			return
+		}
 		handler.HandleResult(w, r, ctx, err, string(yaml))
 	}
 }
@@ -122,6 +134,7 @@ func (h *Handler) GenerateStack() http.HandlerFunc {
 // @Param			workspace			query		string							true	"The target workspace to preview the spec in."
 // @Param			importResources		query		bool							false	"Import existing resources during the stack preview"
 // @Param			specID				query		string							false	"The Spec ID to use for the apply. Will generate a new spec if omitted."
+// @Param			planID				query		int								false	"The ID of the preview run whose plan is applied exactly once, skipping the generation and the preview, as long as the state has not changed since the preview"
 // @Param			force				query		bool							false	"Force the apply even when the stack is locked. May cause concurrency issues!!!"
 // @Param			dryrun				query		bool							false	"Apply in dry-run mode"
 // @Success		200					{object}	handler.Response{data=string}	"Success"
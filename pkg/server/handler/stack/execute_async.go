@@ -1,12 +1,13 @@
 package stack
 
 import (
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/render"
-	yamlv2 "gopkg.in/yaml.v2"
 
 	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/domain/constant"
@@ -27,10 +28,12 @@ import (
 // @Param			importedResources	body		request.StackImportRequest			false	"The resources to import during the stack preview"
 // @Param			workspace			query		string								true	"The target workspace to preview the spec in."
 // @Param			importResources		query		bool								false	"Import existing resources during the stack preview"
-// @Param			output				query		string								false	"Output format. Choices are: json, default. Default to default output format in Kusion."
+// @Param			output				query		string								false	"Output format. Choices are: json, summary, default. Default to default output format in Kusion."
 // @Param			detail				query		bool								false	"Show detailed output"
 // @Param			specID				query		string								false	"The Spec ID to use for the preview. Default to the last one generated."
 // @Param			force				query		bool								false	"Force the preview even when the stack is locked"
+// @Param			timeout				query		int								false	"The timeout of the preview run in seconds, bounded by the max run timeout of the server. Default to 3600"
+// @Param			plan				query		bool								false	"Store the plan of the preview as the run result to be applied later by the run ID"
 // @Success		200					{object}	handler.Response{data=entity.Run}	"Success"
 // @Failure		400					{object}	error								"Bad Request"
 // @Failure		401					{object}	error								"Unauthorized"
@@ -71,20 +74,27 @@ func (h *Handler) PreviewStackAsync() http.HandlerFunc {
 		runLogger := logutil.GetRunLogger(ctx)
 		runLogger.Info("Starting previewing stack in StackManager ... This is a preview run.", "runID", runEntity.ID)
 
+		// Make the run cancellable while it is queued, and mark it as queued before a worker may pick it up and
+		// set it to InProgress
+		h.stackManager.QueueRun(runEntity.ID)
+		h.setRunToQueued(ctx, runEntity.ID)
 		// Starts a safe goroutine using given recover handler
 		inBufferZone := h.workerPool.Do(func() {
 			// defer safe.HandleCrash(aciLoggingRecoverHandler(h.aciClient, &req, log))
 			logger.Info("Async preview in progress")
 			var previewChanges any
-			newCtx, cancel := CopyToNewContextWithTimeout(ctx, constant.RunTimeOut)
+			var changes *models.Changes
+			newCtx, cancel := CopyToNewContextWithTimeout(ctx, h.runTimeout(requestPayload.Timeout))
 			defer cancel()                                            // make sure the context is canceled to free resources
 			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+			defer h.stackManager.TrackRun(runEntity.ID, cancel)()     // make the run cancellable by the run ID
+			h.setRunToInProgress(newCtx, runEntity.ID)
 
 			// update status of the run when exiting the async run
 			defer func() {
 				select {
 				case <-newCtx.Done():
-					logutil.LogToAll(logger, runLogger, "info", "preview execution timed out", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
+					logutil.LogToAll(logger, runLogger, "info", "preview execution timed out or cancelled", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
 					h.setRunToCancelled(newCtx, runEntity.ID)
 				default:
 					if err != nil {
@@ -93,7 +103,17 @@ func (h *Handler) PreviewStackAsync() http.HandlerFunc {
 					} else {
 						logutil.LogToAll(logger, runLogger, "info", "preview completed for stack", "stackID", params.StackID, "time", time.Now())
 						if pc, ok := previewChanges.(*models.Changes); ok {
-							h.setRunToSuccess(newCtx, runEntity.ID, pc)
+							h.setRunToSuccessWithPayload(newCtx, runEntity.ID, pc, request.UpdateRunResultRequest{
+								ChangeSummary: pc.KindBreakdown(),
+							})
+						} else if plan, ok := previewChanges.(*stackmanager.Plan); ok {
+							h.setRunToSuccessWithPayload(newCtx, runEntity.ID, plan, request.UpdateRunResultRequest{
+								ChangeSummary: plan.Changes.KindBreakdown(),
+							})
+						} else if summary, ok := previewChanges.(*models.ActionSummary); ok {
+							h.setRunToSuccessWithPayload(newCtx, runEntity.ID, summary, request.UpdateRunResultRequest{
+								ChangeSummary: changes.KindBreakdown(),
+							})
 						} else {
 							logutil.LogToAll(logger, runLogger, "error", "Error casting preview changes to models.Changes", "error", "casting error")
 							h.setRunToFailed(newCtx, runEntity.ID)
@@ -105,7 +125,17 @@ func (h *Handler) PreviewStackAsync() http.HandlerFunc {
 			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
 
 			// Call preview stack
-			var changes *models.Changes
+			if params.ExecuteParams.Plan {
+				// Store the plan instead of the processed changes to be applied later by the run ID
+				var plan *stackmanager.Plan
+				plan, err = h.stackManager.PlanStack(newCtx, params, requestPayload.ImportedResources)
+				if err != nil {
+					logutil.LogToAll(logger, runLogger, "error", "Error planning stack", "error", err)
+					return
+				}
+				previewChanges = plan
+				return
+			}
 			changes, err = h.stackManager.PreviewStack(newCtx, params, requestPayload.ImportedResources)
 			if err != nil {
 				logutil.LogToAll(logger, runLogger, "error", "Error previewing stack", "error", err)
@@ -118,12 +148,11 @@ func (h *Handler) PreviewStackAsync() http.HandlerFunc {
 				return
 			}
 		})
-		defer func() {
-			if inBufferZone {
-				logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker")
-				h.setRunToQueued(ctx, runEntity.ID)
-			}
-		}()
+		// Tell the client the run is queued along with its position in the queue
+		if inBufferZone {
+			h.setRunQueuePosition(runEntity)
+			logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker", "queuePosition", runEntity.QueuePosition)
+		}
 		render.Render(w, r, handler.SuccessResponse(ctx, runEntity))
 	}
 }
@@ -138,7 +167,9 @@ func (h *Handler) PreviewStackAsync() http.HandlerFunc {
 // @Param			workspace			query		string								true	"The target workspace to preview the spec in."
 // @Param			importResources		query		bool								false	"Import existing resources during the stack preview"
 // @Param			specID				query		string								false	"The Spec ID to use for the apply. Will generate a new spec if omitted."
+// @Param			planID				query		int									false	"The ID of the preview run whose plan is applied exactly once, skipping the generation and the preview, as long as the state has not changed since the preview"
 // @Param			force				query		bool								false	"Force the apply even when the stack is locked. May cause concurrency issues!!!"
+// @Param			timeout				query		int								false	"The timeout of the apply run in seconds, bounded by the max run timeout of the server. Default to 3600"
 // @Param			dryrun				query		bool								false	"Apply in dry-run mode"
 // @Success		200					{object}	handler.Response{data=entity.Run}	"Success"
 // @Failure		400					{object}	error								"Bad Request"
@@ -179,21 +210,36 @@ func (h *Handler) ApplyStackAsync() http.HandlerFunc {
 
 		runLogger := logutil.GetRunLogger(ctx)
 		runLogger.Info("Starting applying stack in StackManager ... This is an apply run.", "runID", runEntity.ID)
-
+		// The run ID is used to pause the apply run for approval
+		params.RunID = runEntity.ID
+		params.ExecuteParams.Targets = requestPayload.Targets
+
+		// Make the run cancellable while it is queued, and mark it as queued before a worker may pick it up and
+		// set it to InProgress
+		h.stackManager.QueueRun(runEntity.ID)
+		h.setRunToQueued(ctx, runEntity.ID)
 		// Starts a safe goroutine using given recover handler
 		inBufferZone := h.workerPool.Do(func() {
 			// defer safe.HandleCrash(aciLoggingRecoverHandler(h.aciClient, &req, log))
 			logger.Info("Async apply in progress")
-			newCtx, cancel := CopyToNewContextWithTimeout(ctx, constant.RunTimeOut)
+			newCtx, cancel := CopyToNewContextWithTimeout(ctx, h.runTimeout(requestPayload.Timeout))
 			defer cancel()                                            // make sure the context is canceled to free resources
 			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+			defer h.stackManager.TrackRun(runEntity.ID, cancel)()     // make the run cancellable by the run ID
+			h.setRunToInProgress(newCtx, runEntity.ID)
 
 			// update status of the run when exiting the async run
 			defer func() {
+				progress := h.stackManager.TakeApplyProgress(runEntity.ID)
 				select {
 				case <-newCtx.Done():
-					logutil.LogToAll(logger, runLogger, "info", "apply execution timed out", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
-					h.setRunToCancelled(newCtx, runEntity.ID)
+					logutil.LogToAll(logger, runLogger, "info", "apply execution timed out or cancelled", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
+					// Keep the resources already applied in the result
+					if progress != nil {
+						h.setRunToCancelledWithProgress(newCtx, runEntity.ID, progress)
+					} else {
+						h.setRunToCancelled(newCtx, runEntity.ID)
+					}
 				default:
 					if err != nil {
 						logutil.LogToAll(logger, runLogger, "error", "apply failed for stack", "stackID", params.StackID, "time", time.Now())
@@ -220,12 +266,128 @@ func (h *Handler) ApplyStackAsync() http.HandlerFunc {
 			}
 		})
 
-		defer func() {
-			if inBufferZone {
-				logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker")
-				h.setRunToQueued(ctx, runEntity.ID)
+		// Tell the client the run is queued along with its position in the queue
+		if inBufferZone {
+			h.setRunQueuePosition(runEntity)
+			logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker", "queuePosition", runEntity.QueuePosition)
+		}
+		render.Render(w, r, handler.SuccessResponse(ctx, runEntity))
+	}
+}
+
+// @Id				previewApplyStackAsync
+// @Summary		Asynchronously preview and apply stack
+// @Description	Start a run to asynchronously preview stack changes by stack ID, and apply them in the same run only if they match the expected checksum
+// @Tags			stack
+// @Produce		json
+// @Param			stackID				path		int									true	"Stack ID"
+// @Param			importedResources	body		request.StackImportRequest			false	"The resources to import during the stack preview"
+// @Param			workspace			query		string								true	"The target workspace to preview the spec in."
+// @Param			expectedChecksum	query		string								true	"The checksum of the plan expected to be previewed, as returned by a preview run with the plan"
+// @Param			importResources		query		bool								false	"Import existing resources during the stack preview"
+// @Param			specID				query		string								false	"The Spec ID to use for the preview. Default to the last one generated."
+// @Param			force				query		bool								false	"Force the preview and apply even when the stack is locked. May cause concurrency issues!!!"
+// @Param			timeout				query		int								false	"The timeout of the run in seconds, bounded by the max run timeout of the server. Default to 3600"
+// @Success		200					{object}	handler.Response{data=entity.Run}	"Success"
+// @Failure		400					{object}	error								"Bad Request"
+// @Failure		401					{object}	error								"Unauthorized"
+// @Failure		429					{object}	error								"Too Many Requests"
+// @Failure		404					{object}	error								"Not Found"
+// @Failure		500					{object}	error								"Internal Server Error"
+// @Router			/api/v1/stacks/{stackID}/preview-apply/async [post]
+func (h *Handler) PreviewApplyStackAsync() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx, logger, params, err := requestHelper(r)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+		if params.ExecuteParams.ExpectedChecksum == "" {
+			render.Render(w, r, handler.FailureResponse(ctx, stackmanager.ErrExpectedChecksumEmpty))
+			return
+		}
+		logger.Info("Previewing and applying stack asynchronously...", "stackID", params.StackID)
+
+		var requestPayload request.CreateRunRequest
+		if err := requestPayload.Decode(r); err != nil {
+			if err == io.EOF {
+				render.Render(w, r, handler.FailureResponse(ctx, stackmanager.ErrRunRequestBodyEmpty))
+				return
+			} else {
+				render.Render(w, r, handler.FailureResponse(ctx, err))
+				return
 			}
-		}()
+		}
+		updateRunRequestPayload(&requestPayload, params, constant.RunTypeApply)
+
+		requestPayload.Type = string(constant.RunTypeApply)
+		// Create a Run object in database and start background task
+		runEntity, err := h.stackManager.CreateRun(ctx, requestPayload)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		runLogger := logutil.GetRunLogger(ctx)
+		runLogger.Info("Starting previewing and applying stack in StackManager ... This is an apply run.", "runID", runEntity.ID)
+		// The run ID is used to pause the apply run for approval
+		params.RunID = runEntity.ID
+
+		// Make the run cancellable while it is queued, and mark it as queued before a worker may pick it up and
+		// set it to InProgress
+		h.stackManager.QueueRun(runEntity.ID)
+		h.setRunToQueued(ctx, runEntity.ID)
+		// Starts a safe goroutine using given recover handler
+		inBufferZone := h.workerPool.Do(func() {
+			logger.Info("Async preview and apply in progress")
+			newCtx, cancel := CopyToNewContextWithTimeout(ctx, h.runTimeout(requestPayload.Timeout))
+			defer cancel()                                            // make sure the context is canceled to free resources
+			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+			defer h.stackManager.TrackRun(runEntity.ID, cancel)()     // make the run cancellable by the run ID
+			h.setRunToInProgress(newCtx, runEntity.ID)
+
+			// update status of the run when exiting the async run
+			defer func() {
+				progress := h.stackManager.TakeApplyProgress(runEntity.ID)
+				select {
+				case <-newCtx.Done():
+					logutil.LogToAll(logger, runLogger, "info", "preview and apply execution timed out or cancelled", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
+					// Keep the resources already applied in the result
+					if progress != nil {
+						h.setRunToCancelledWithProgress(newCtx, runEntity.ID, progress)
+					} else {
+						h.setRunToCancelled(newCtx, runEntity.ID)
+					}
+				default:
+					if errors.Is(err, stackmanager.ErrPlanChecksumConflict) {
+						logutil.LogToAll(logger, runLogger, "error", "preview and apply aborted for stack", "stackID", params.StackID, "time", time.Now())
+						h.setRunToFailedWithResult(newCtx, runEntity.ID, constant.RunResultConflict)
+					} else if err != nil {
+						logutil.LogToAll(logger, runLogger, "error", "preview and apply failed for stack", "stackID", params.StackID, "time", time.Now())
+						h.setRunToFailed(newCtx, runEntity.ID)
+					} else {
+						logutil.LogToAll(logger, runLogger, "info", "preview and apply completed for stack", "stackID", params.StackID, "time", time.Now())
+						h.setRunToSuccess(newCtx, runEntity.ID, "apply completed")
+					}
+				}
+			}()
+
+			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+
+			// call preview and apply stack
+			err = h.stackManager.PreviewAndApplyStack(newCtx, params, requestPayload.ImportedResources)
+			if err != nil {
+				logutil.LogToAll(logger, runLogger, "error", "Error previewing and applying stack", "error", err)
+				return
+			}
+		})
+
+		// Tell the client the run is queued along with its position in the queue
+		if inBufferZone {
+			h.setRunQueuePosition(runEntity)
+			logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker", "queuePosition", runEntity.QueuePosition)
+		}
 		render.Render(w, r, handler.SuccessResponse(ctx, runEntity))
 	}
 }
@@ -239,6 +401,10 @@ func (h *Handler) ApplyStackAsync() http.HandlerFunc {
 // @Param			workspace	query		string								true	"The target workspace to preview the spec in."
 // @Param			format		query		string								false	"The format to generate the spec in. Choices are: spec. Default to spec."
 // @Param			force		query		bool								false	"Force the generate even when the stack is locked"
+// @Param			timeout		query		int								false	"The timeout of the generate run in seconds, bounded by the max run timeout of the server. Default to 3600"
+// @Param			workloadOnly	query		bool								false	"Generate only the namespace and workload, skipping all accessories"
+// @Param			strictSecretStore	query		bool								false	"Fail the generation if the external secrets reference no secret store, which is only warned by default"
+// @Param			nonStrict	query		bool								false	"Continue the generation past the failures of the built-in generators, e.g. an invalid namespace"
 // @Success		200			{object}	handler.Response{data=entity.Run}	"Success"
 // @Failure		400			{object}	error								"Bad Request"
 // @Failure		401			{object}	error								"Unauthorized"
@@ -279,20 +445,27 @@ func (h *Handler) GenerateStackAsync() http.HandlerFunc {
 		runLogger := logutil.GetRunLogger(ctx)
 		runLogger.Info("Starting generating stack in StackManager ... This is a generate run.", "runID", runEntity.ID)
 
+		// Make the run cancellable while it is queued, and mark it as queued before a worker may pick it up and
+		// set it to InProgress
+		h.stackManager.QueueRun(runEntity.ID)
+		h.setRunToQueued(ctx, runEntity.ID)
 		// Starts a safe goroutine using given recover handler
 		inBufferZone := h.workerPool.Do(func() {
 			// defer safe.HandleCrash(aciLoggingRecoverHandler(h.aciClient, &req, log))
 			logger.Info("Async generate in progress")
-			newCtx, cancel := CopyToNewContextWithTimeout(ctx, constant.RunTimeOut)
+			newCtx, cancel := CopyToNewContextWithTimeout(ctx, h.runTimeout(requestPayload.Timeout))
 			defer cancel()                                            // make sure the context is canceled to free resources
 			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+			defer h.stackManager.TrackRun(runEntity.ID, cancel)()     // make the run cancellable by the run ID
+			h.setRunToInProgress(newCtx, runEntity.ID)
 
 			var sp *apiv1.Spec
+			var summary *apiv1.ResourceSummary
 			// update status of the run when exiting the async run
 			defer func() {
 				select {
 				case <-newCtx.Done():
-					logutil.LogToAll(logger, runLogger, "info", "generate execution timed out", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
+					logutil.LogToAll(logger, runLogger, "info", "generate execution timed out or cancelled", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
 					h.setRunToCancelled(newCtx, runEntity.ID)
 				default:
 					if err != nil {
@@ -300,8 +473,13 @@ func (h *Handler) GenerateStackAsync() http.HandlerFunc {
 						h.setRunToFailed(newCtx, runEntity.ID)
 					} else {
 						logutil.LogToAll(logger, runLogger, "info", "generate completed for stack", "stackID", params.StackID, "time", time.Now())
-						if yaml, err := yamlv2.Marshal(sp); err == nil {
-							h.setRunToSuccess(newCtx, runEntity.ID, string(yaml))
+						// stream the spec into the run result rather than marshalling the whole spec at once, the
+						// result is not copied out of the builder
+						var result strings.Builder
+						if err := writeSpecResult(&result, sp); err == nil {
+							h.setRunToSuccessWithMarshalledResult(newCtx, runEntity.ID, result.String(), request.UpdateRunResultRequest{
+								ResourceSummary: summary,
+							})
 						} else {
 							logutil.LogToAll(logger, runLogger, "error", "Error marshalling generated spec", "error", err)
 							h.setRunToFailed(newCtx, runEntity.ID)
@@ -311,19 +489,18 @@ func (h *Handler) GenerateStackAsync() http.HandlerFunc {
 			}()
 
 			// Call generate stack
-			_, sp, err = h.stackManager.GenerateSpec(newCtx, params)
+			sp, summary, err = h.stackManager.GenerateSpec(newCtx, params)
 			if err != nil {
 				logutil.LogToAll(logger, runLogger, "error", "Error generating stack", "error", err)
 				return
 			}
 		})
 
-		defer func() {
-			if inBufferZone {
-				logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker")
-				h.setRunToQueued(ctx, runEntity.ID)
-			}
-		}()
+		// Tell the client the run is queued along with its position in the queue
+		if inBufferZone {
+			h.setRunQueuePosition(runEntity)
+			logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker", "queuePosition", runEntity.QueuePosition)
+		}
 		render.Render(w, r, handler.SuccessResponse(ctx, runEntity))
 	}
 }
@@ -336,6 +513,7 @@ func (h *Handler) GenerateStackAsync() http.HandlerFunc {
 // @Param			stackID		path		int									true	"Stack ID"
 // @Param			workspace	query		string								true	"The target workspace to preview the spec in."
 // @Param			force		query		bool								false	"Force the destroy even when the stack is locked. May cause concurrency issues!!!"
+// @Param			timeout		query		int								false	"The timeout of the destroy run in seconds, bounded by the max run timeout of the server. Default to 3600"
 // @Param			dryrun		query		bool								false	"Destroy in dry-run mode"
 // @Success		200			{object}	handler.Response{data=entity.Run}	"Success"
 // @Failure		400			{object}	error								"Bad Request"
@@ -377,18 +555,24 @@ func (h *Handler) DestroyStackAsync() http.HandlerFunc {
 		runLogger := logutil.GetRunLogger(ctx)
 		runLogger.Info("Starting destroying stack in StackManager ... This is a destroy run.", "runID", runEntity.ID)
 
+		// Make the run cancellable while it is queued, and mark it as queued before a worker may pick it up and
+		// set it to InProgress
+		h.stackManager.QueueRun(runEntity.ID)
+		h.setRunToQueued(ctx, runEntity.ID)
 		// Starts a safe goroutine using given recover handler
 		inBufferZone := h.workerPool.Do(func() {
 			logger.Info("Async destroy in progress")
-			newCtx, cancel := CopyToNewContextWithTimeout(ctx, constant.RunTimeOut)
+			newCtx, cancel := CopyToNewContextWithTimeout(ctx, h.runTimeout(requestPayload.Timeout))
 			defer cancel()                                            // make sure the context is canceled to free resources
 			defer handleCrash(newCtx, h.setRunToFailed, runEntity.ID) // recover from possible panic
+			defer h.stackManager.TrackRun(runEntity.ID, cancel)()     // make the run cancellable by the run ID
+			h.setRunToInProgress(newCtx, runEntity.ID)
 
 			// update status of the run when exiting the async run
 			defer func() {
 				select {
 				case <-newCtx.Done():
-					logutil.LogToAll(logger, runLogger, "info", "destroy execution timed out", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
+					logutil.LogToAll(logger, runLogger, "info", "destroy execution timed out or cancelled", "stackID", params.StackID, "time", time.Now(), "timeout", newCtx.Err())
 					h.setRunToCancelled(newCtx, runEntity.ID)
 				default:
 					if err != nil {
@@ -414,12 +598,11 @@ func (h *Handler) DestroyStackAsync() http.HandlerFunc {
 			}
 		})
 
-		defer func() {
-			if inBufferZone {
-				logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker")
-				h.setRunToQueued(ctx, runEntity.ID)
-			}
-		}()
+		// Tell the client the run is queued along with its position in the queue
+		if inBufferZone {
+			h.setRunQueuePosition(runEntity)
+			logutil.LogToAll(logger, runLogger, "info", "The task is in the buffer zone, waiting for an available worker", "queuePosition", runEntity.QueuePosition)
+		}
 		render.Render(w, r, handler.SuccessResponse(ctx, runEntity))
 	}
 }
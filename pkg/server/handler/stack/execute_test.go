@@ -1 +1,362 @@
 package stack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/mockey"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/infra/persistence"
+	"kusionstack.io/kusion/pkg/infra/util/worker"
+	"kusionstack.io/kusion/pkg/server/handler"
+	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
+)
+
+func TestRunTimeout(t *testing.T) {
+	testcases := []struct {
+		name           string
+		maxRunTimeout  time.Duration
+		timeoutSeconds int
+		expected       time.Duration
+	}{
+		{
+			name:          "default timeout",
+			maxRunTimeout: constant.MaxRunTimeOut,
+			expected:      constant.RunTimeOut,
+		},
+		{
+			name:           "custom timeout",
+			maxRunTimeout:  constant.MaxRunTimeOut,
+			timeoutSeconds: 7200,
+			expected:       2 * time.Hour,
+		},
+		{
+			name:           "custom timeout clamped to the max",
+			maxRunTimeout:  constant.MaxRunTimeOut,
+			timeoutSeconds: 86400,
+			expected:       constant.MaxRunTimeOut,
+		},
+		{
+			name:          "default timeout clamped to the max",
+			maxRunTimeout: 30 * time.Minute,
+			expected:      30 * time.Minute,
+		},
+		{
+			name:           "no max timeout",
+			timeoutSeconds: 86400,
+			expected:       24 * time.Hour,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &Handler{maxRunTimeout: tc.maxRunTimeout}
+			assert.Equal(t, tc.expected, h.runTimeout(tc.timeoutSeconds))
+		})
+	}
+}
+
+func TestApplyStackAsync_Timeout(t *testing.T) {
+	// applyAsync applies the stack asynchronously with the fake apply, and returns the response of the request
+	applyAsync := func(stackHandler *Handler, query string) handler.Response {
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/apply/async", stackHandler.ApplyStackAsync())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/apply/async?"+query, strings.NewReader("{}")))
+		var resp handler.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		// Wait for the async run to exit
+		stackHandler.workerPool.Wait()
+		return resp
+	}
+	mockRun := func() *[]constant.RunStatus {
+		var statuses []constant.RunStatus
+		mockey.Mock((*stackmanager.StackManager).CreateRun).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint, payload request.UpdateRunResultRequest) (*entity.Run, error) {
+				statuses = append(statuses, constant.RunStatus(payload.Status))
+				return &entity.Run{ID: id}, nil
+			}).Build()
+		return &statuses
+	}
+
+	mockey.PatchConvey("custom timeout", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+		mockRun()
+		var deadline time.Time
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				deadline, _ = ctx.Deadline()
+				return nil
+			}).Build()
+
+		start := time.Now()
+		resp := applyAsync(stackHandler, "workspace=dev&timeout=7200")
+		assert.True(t, resp.Success)
+		assert.WithinRange(t, deadline, start.Add(2*time.Hour), time.Now().Add(2*time.Hour))
+	})
+
+	mockey.PatchConvey("timeout clamped to the max", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = time.Hour
+		mockRun()
+		var deadline time.Time
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				deadline, _ = ctx.Deadline()
+				return nil
+			}).Build()
+
+		start := time.Now()
+		resp := applyAsync(stackHandler, "workspace=dev&timeout=86400")
+		assert.True(t, resp.Success)
+		assert.WithinRange(t, deadline, start.Add(time.Hour), time.Now().Add(time.Hour))
+	})
+
+	mockey.PatchConvey("run cancelled after the timeout", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+		statuses := mockRun()
+		var applyErr error
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				<-ctx.Done()
+				applyErr = ctx.Err()
+				return applyErr
+			}).Build()
+
+		resp := applyAsync(stackHandler, "workspace=dev&timeout=1")
+		assert.True(t, resp.Success)
+		assert.ErrorIs(t, applyErr, context.DeadlineExceeded)
+		// The run is queued before the worker picks it up
+		assert.Equal(t, []constant.RunStatus{constant.RunStatusQueued, constant.RunStatusInProgress, constant.RunStatusCancelled}, *statuses)
+	})
+
+	mockey.PatchConvey("invalid timeout", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+
+		resp := applyAsync(stackHandler, "workspace=dev&timeout=-1")
+		assert.False(t, resp.Success)
+		assert.Equal(t, stackmanager.ErrInvalidRunTimeout.Error(), resp.Message)
+	})
+}
+
+func TestApplyStackAsync_PartialProgress(t *testing.T) {
+	mockey.PatchConvey("apply timed out after some resources applied", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+
+		var updates []request.UpdateRunResultRequest
+		mockey.Mock((*stackmanager.StackManager).CreateRun).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint, payload request.UpdateRunResultRequest) (*entity.Run, error) {
+				updates = append(updates, payload)
+				return &entity.Run{ID: id}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).TakeApplyProgress).Return(&stackmanager.ApplyProgress{
+			Succeeded: []string{"v1:Namespace:default"},
+			Failed:    []string{},
+			Applying:  []string{"apps/v1:Deployment:default:app"},
+		}).Build()
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/apply/async", stackHandler.ApplyStackAsync())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/apply/async?workspace=dev&timeout=1", strings.NewReader("{}")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		stackHandler.workerPool.Wait()
+
+		require.NotEmpty(t, updates)
+		cancelled := updates[len(updates)-1]
+		assert.Equal(t, string(constant.RunStatusCancelled), cancelled.Status)
+		assert.JSONEq(t, `{
+			"result": "Operation Cancelled",
+			"succeeded": ["v1:Namespace:default"],
+			"failed": [],
+			"applying": ["apps/v1:Deployment:default:app"]
+		}`, cancelled.Result)
+	})
+
+	mockey.PatchConvey("apply timed out before applying any resource", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+
+		var updates []request.UpdateRunResultRequest
+		mockey.Mock((*stackmanager.StackManager).CreateRun).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint, payload request.UpdateRunResultRequest) (*entity.Run, error) {
+				updates = append(updates, payload)
+				return &entity.Run{ID: id}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}).Build()
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/apply/async", stackHandler.ApplyStackAsync())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/apply/async?workspace=dev&timeout=1", strings.NewReader("{}")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		stackHandler.workerPool.Wait()
+
+		require.NotEmpty(t, updates)
+		cancelled := updates[len(updates)-1]
+		assert.Equal(t, string(constant.RunStatusCancelled), cancelled.Status)
+		assert.Equal(t, constant.RunResultCancelled, cancelled.Result)
+	})
+}
+
+func TestApplyStackAsync_QueuePosition(t *testing.T) {
+	mockey.PatchConvey("queue position when the worker pool is saturated", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 2)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+
+		var runID uint
+		mockey.Mock((*stackmanager.StackManager).CreateRun).To(
+			func(_ *stackmanager.StackManager, _ context.Context, _ request.CreateRunRequest) (*entity.Run, error) {
+				runID++
+				return &entity.Run{ID: runID, Status: constant.RunStatusInProgress}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).Return(&entity.Run{}, nil).Build()
+		// The first apply occupies the only worker until it is released
+		started := make(chan struct{}, 3)
+		release := make(chan struct{})
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, _ context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}).Build()
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/apply/async", stackHandler.ApplyStackAsync())
+		applyAsync := func() map[string]any {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/apply/async?workspace=dev", strings.NewReader("{}")))
+			var resp handler.Response
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+			require.True(t, resp.Success)
+			return resp.Data.(map[string]any)
+		}
+
+		first := applyAsync()
+		<-started
+		assert.Equal(t, string(constant.RunStatusInProgress), first["status"])
+		assert.NotContains(t, first, "queuePosition")
+
+		second := applyAsync()
+		assert.Equal(t, string(constant.RunStatusQueued), second["status"])
+		assert.Equal(t, float64(1), second["queuePosition"])
+
+		third := applyAsync()
+		assert.Equal(t, string(constant.RunStatusQueued), third["status"])
+		assert.Equal(t, float64(2), third["queuePosition"])
+
+		close(release)
+		stackHandler.workerPool.Wait()
+	})
+}
+
+func TestPreviewApplyStackAsync(t *testing.T) {
+	// previewApplyAsync previews and applies the stack asynchronously with the fake preview and apply, and returns
+	// whether the stack is applied along with the last update of the run
+	previewApplyAsync := func(t *testing.T, expectedChecksum string, applyErr error) (bool, request.UpdateRunResultRequest) {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+		stackHandler.maxRunTimeout = constant.MaxRunTimeOut
+
+		var updates []request.UpdateRunResultRequest
+		mockey.Mock((*stackmanager.StackManager).CreateRun).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint, payload request.UpdateRunResultRequest) (*entity.Run, error) {
+				updates = append(updates, payload)
+				return &entity.Run{ID: id}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).PlanStack).Return(&stackmanager.Plan{Checksum: "abc"}, nil).Build()
+		applied := false
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, _ context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				applied = applyErr == nil
+				return applyErr
+			}).Build()
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/preview-apply/async", stackHandler.PreviewApplyStackAsync())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/preview-apply/async?workspace=dev&expectedChecksum="+expectedChecksum, strings.NewReader("{}")))
+		var resp handler.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		require.True(t, resp.Success)
+		stackHandler.workerPool.Wait()
+
+		require.NotEmpty(t, updates)
+		return applied, updates[len(updates)-1]
+	}
+
+	mockey.PatchConvey("previewed changes match the expected checksum", t, func() {
+		applied, update := previewApplyAsync(t, "abc", nil)
+		assert.True(t, applied)
+		assert.Equal(t, string(constant.RunStatusSucceeded), update.Status)
+	})
+
+	mockey.PatchConvey("resources changed after the preview", t, func() {
+		applied, update := previewApplyAsync(t, "abc", fmt.Errorf("%w: the resources have changed since the plan was previewed", stackmanager.ErrPlanChecksumConflict))
+		assert.False(t, applied)
+		assert.Equal(t, string(constant.RunStatusFailed), update.Status)
+		assert.Equal(t, constant.RunResultConflict, update.Result)
+	})
+
+	mockey.PatchConvey("previewed changes conflict with the expected checksum", t, func() {
+		applied, update := previewApplyAsync(t, "def", nil)
+		assert.False(t, applied)
+		assert.Equal(t, string(constant.RunStatusFailed), update.Status)
+		assert.Equal(t, constant.RunResultConflict, update.Result)
+	})
+
+	mockey.PatchConvey("expected checksum missing", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/preview-apply/async", stackHandler.PreviewApplyStackAsync())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/preview-apply/async?workspace=dev", strings.NewReader("{}")))
+		var resp handler.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.False(t, resp.Success)
+		assert.Equal(t, stackmanager.ErrExpectedChecksumEmpty.Error(), resp.Message)
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"kusionstack.io/kusion/pkg/domain/request"
 	"kusionstack.io/kusion/pkg/infra/persistence"
 	"kusionstack.io/kusion/pkg/server/handler"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
 )
 
@@ -329,7 +330,9 @@ func setupTest(t *testing.T) (sqlmock.Sqlmock, *gorm.DB, *httptest.ResponseRecor
 	resourceRepo := persistence.NewResourceRepository(fakeGDB)
 	runRepo := persistence.NewRunRepository(fakeGDB)
 	stackHandler := &Handler{
-		stackManager: stackmanager.NewStackManager(stackRepo, projectRepo, workspaceRepo, resourceRepo, runRepo, entity.Backend{}, constant.MaxConcurrent),
+		stackManager: stackmanager.NewStackManager(stackRepo, projectRepo, workspaceRepo, resourceRepo, runRepo,
+			backendmanager.NewScope(persistence.NewBackendRepository(fakeGDB), persistence.NewOrganizationRepository(fakeGDB), false),
+			entity.Backend{}, constant.MaxConcurrent),
 	}
 	recorder := httptest.NewRecorder()
 	return sqlMock, fakeGDB, recorder, stackHandler
@@ -0,0 +1,44 @@
+package stack
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/server/handler"
+	logutil "kusionstack.io/kusion/pkg/server/util/logging"
+)
+
+// @Id				dryRunPatcher
+// @Summary		Dry run patcher
+// @Description	Apply the patcher to the spec of a succeeded generate run or an inline spec without applying it, and return the patched resources, diffs and warnings
+// @Tags			stack
+// @Accept			json
+// @Produce		json
+// @Param			patcher	body		request.PatcherDryRunRequest								true	"The spec and the patcher to dry run"
+// @Success		200		{object}	handler.Response{data=response.PatcherDryRunResponse}	"Success"
+// @Failure		400		{object}	error													"Bad Request"
+// @Failure		401		{object}	error													"Unauthorized"
+// @Failure		429		{object}	error													"Too Many Requests"
+// @Failure		404		{object}	error													"Not Found"
+// @Failure		500		{object}	error													"Internal Server Error"
+// @Router			/api/v1/patchers/dry-run [post]
+func (h *Handler) DryRunPatcher() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx := r.Context()
+		logger := logutil.GetLogger(ctx)
+		logger.Info("Dry running patcher...")
+
+		// Decode the request body into the payload.
+		var requestPayload request.PatcherDryRunRequest
+		if err := requestPayload.Decode(r); err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		result, err := h.stackManager.DryRunPatcher(ctx, requestPayload)
+		handler.HandleResult(w, r, ctx, err, result)
+	}
+}
@@ -3,11 +3,15 @@ package stack
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 	response "kusionstack.io/kusion/pkg/domain/response"
 	"kusionstack.io/kusion/pkg/server/handler"
+	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
 	logutil "kusionstack.io/kusion/pkg/server/util/logging"
 )
 
@@ -73,6 +77,63 @@ func (h *Handler) GetRunResult() http.HandlerFunc {
 	}
 }
 
+// @Id				continueRun
+// @Summary		Continue run
+// @Description	Approve the apply run waiting for approval to continue applying the remaining resources
+// @Tags			run
+// @Produce		json
+// @Param			runID	path		int									true	"Run ID"
+// @Success		200		{object}	handler.Response{data=entity.Run}	"Success"
+// @Failure		400		{object}	error								"Bad Request"
+// @Failure		401		{object}	error								"Unauthorized"
+// @Failure		429		{object}	error								"Too Many Requests"
+// @Failure		404		{object}	error								"Not Found"
+// @Failure		500		{object}	error								"Internal Server Error"
+// @Router			/api/v1/runs/{runID}/continue [post]
+func (h *Handler) ContinueRun() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx, logger, params, err := runRequestHelper(r)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+		approver := getOperator(ctx, r)
+		logger.Info("Continuing run...", "runID", params.RunID, "approver", approver)
+
+		runEntity, err := h.stackManager.ContinueRun(ctx, params.RunID, approver)
+		handler.HandleResult(w, r, ctx, err, runEntity)
+	}
+}
+
+// @Id				cancelRun
+// @Summary		Cancel run
+// @Description	Cancel the in-flight async run, the queued run is cancelled before it starts
+// @Tags			run
+// @Produce		json
+// @Param			runID	path		int									true	"Run ID"
+// @Success		200		{object}	handler.Response{data=entity.Run}	"Success"
+// @Failure		400		{object}	error								"Bad Request"
+// @Failure		401		{object}	error								"Unauthorized"
+// @Failure		429		{object}	error								"Too Many Requests"
+// @Failure		404		{object}	error								"Not Found"
+// @Failure		500		{object}	error								"Internal Server Error"
+// @Router			/api/v1/runs/{runID}/cancel [post]
+func (h *Handler) CancelRun() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx, logger, params, err := runRequestHelper(r)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+		logger.Info("Cancelling run...", "runID", params.RunID)
+
+		runEntity, err := h.stackManager.CancelRun(ctx, params.RunID)
+		handler.HandleResult(w, r, ctx, err, runEntity)
+	}
+}
+
 // @Id				listRun
 // @Summary		List runs
 // @Description	List all runs
@@ -126,24 +187,85 @@ func (h *Handler) ListRuns() http.HandlerFunc {
 	}
 }
 
-// TODO: StreamRunLogs to stream logs from a run using SSE
-// func StreamRunLogs(c echo.Context) error {
-// 	// 设置 SSE headers
-// 	c.Response().Header().Set("Content-Type", "text/event-stream")
-// 	c.Response().Header().Set("Cache-Control", "no-cache")
-// 	c.Response().Header().Set("Connection", "keep-alive")
-
-// 	// id := c.Param("id")
-// 	logs := []string{"log1", "log2", "log3"}
-
-// 	for {
-// 		if len(logs) > 0 {
-// 			for _, logMessage := range logs {
-// 				fmt.Fprintf(c.Response().Writer, "data: %s\n\n", logMessage)
-// 			}
-// 			logs = nil
-// 			c.Response().Flush()
-// 		}
-// 		time.Sleep(1 * time.Second)
-// 	}
-// }
+// @Id				streamRunEvents
+// @Summary		Stream run events
+// @Description	Stream the progress events of the async run by run ID as server-sent events until the run completes
+// @Tags			run
+// @Produce		text/event-stream
+// @Param			runID	path		int						true	"Run ID"
+// @Success		200		{object}	stackmanager.RunEvent	"Success"
+// @Failure		400		{object}	error					"Bad Request"
+// @Failure		401		{object}	error					"Unauthorized"
+// @Failure		429		{object}	error					"Too Many Requests"
+// @Failure		404		{object}	error					"Not Found"
+// @Failure		500		{object}	error					"Internal Server Error"
+// @Router			/api/v1/runs/{runID}/events [get]
+func (h *Handler) StreamRunEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Getting stuff from context
+		ctx, logger, params, err := runRequestHelper(r)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			render.Render(w, r, handler.FailureResponse(ctx, stackmanager.ErrRunEventsStreamingUnsupported))
+			return
+		}
+		logger.Info("Streaming run events...", "runID", params.RunID)
+
+		// Subscribe before getting the run to not miss the events published in between
+		events, unsubscribe := h.stackManager.SubscribeRunEvents(params.RunID)
+		defer unsubscribe()
+		runEntity, err := h.stackManager.GetRunByID(ctx, params.RunID)
+		if err != nil {
+			render.Render(w, r, handler.FailureResponse(ctx, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		// Start the stream with the current status of the run
+		current := stackmanager.RunEvent{
+			Type:   stackmanager.RunEventStatus,
+			RunID:  runEntity.ID,
+			Status: runEntity.Status,
+			Time:   time.Now(),
+		}
+		if err = writeRunEvent(w, flusher, current); err != nil || current.IsTerminal() {
+			return
+		}
+		for {
+			select {
+			case event, ok := <-events:
+				// The events are closed after the run completes
+				if !ok {
+					return
+				}
+				if err = writeRunEvent(w, flusher, event); err != nil {
+					logger.Error("Error writing run event", "runID", params.RunID, "error", err)
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// writeRunEvent writes the run event as a server-sent event named by the event type.
+func writeRunEvent(w io.Writer, flusher http.Flusher, event stackmanager.RunEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
@@ -0,0 +1,194 @@
+package stack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bytedance/mockey"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/infra/persistence"
+	"kusionstack.io/kusion/pkg/infra/util/worker"
+	"kusionstack.io/kusion/pkg/server/handler"
+	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
+)
+
+func TestStreamRunEvents(t *testing.T) {
+	mockey.PatchConvey("stream the events until the run completes", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		mockey.Mock((*stackmanager.StackManager).GetRunByID).Return(&entity.Run{ID: 1, Status: constant.RunStatusQueued}, nil).Build()
+		server := newRunEventsServer(stackHandler)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/runs/1/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		// The stream starts with the current status of the run
+		events := readRunEvents(resp.Body)
+		first := <-events
+		assert.Equal(t, stackmanager.RunEventStatus, first.Type)
+		assert.Equal(t, constant.RunStatusQueued, first.Status)
+
+		// Drive the run through its states after it is picked up by a worker
+		stackHandler.stackManager.PublishRunStatus(1, constant.RunStatusInProgress)
+		stackHandler.stackManager.PublishRunStatus(1, constant.RunStatusWaitingApproval)
+		stackHandler.stackManager.PublishRunStatus(1, constant.RunStatusInProgress)
+		stackHandler.stackManager.PublishRunStatus(1, constant.RunStatusSucceeded)
+		// No more events are streamed after the run completes
+		stackHandler.stackManager.PublishRunStatus(1, constant.RunStatusFailed)
+
+		var statuses []constant.RunStatus
+		for event := range events {
+			assert.Equal(t, uint(1), event.RunID)
+			statuses = append(statuses, event.Status)
+		}
+		assert.Equal(t, []constant.RunStatus{
+			constant.RunStatusInProgress,
+			constant.RunStatusWaitingApproval,
+			constant.RunStatusInProgress,
+			constant.RunStatusSucceeded,
+		}, statuses)
+	})
+
+	mockey.PatchConvey("completed run", t, func() {
+		_, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		mockey.Mock((*stackmanager.StackManager).GetRunByID).Return(&entity.Run{ID: 1, Status: constant.RunStatusFailed}, nil).Build()
+		server := newRunEventsServer(stackHandler)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/runs/1/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var statuses []constant.RunStatus
+		for event := range readRunEvents(resp.Body) {
+			statuses = append(statuses, event.Status)
+		}
+		assert.Equal(t, []constant.RunStatus{constant.RunStatusFailed}, statuses)
+	})
+}
+
+func TestCancelRun(t *testing.T) {
+	mockey.PatchConvey("cancel a long-running apply", t, func() {
+		sqlMock, fakeGDB, _, stackHandler := setupTest(t)
+		defer persistence.CloseDB(t, fakeGDB)
+		stackHandler.workerPool = worker.NewWorkerPool(1, 1)
+
+		// Record the status updates of the run in place of the database
+		var mu sync.Mutex
+		var statuses []constant.RunStatus
+		runStatus := func() constant.RunStatus {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(statuses) == 0 {
+				return constant.RunStatusInProgress
+			}
+			return statuses[len(statuses)-1]
+		}
+		mockey.Mock((*stackmanager.StackManager).CreateRun).To(
+			func(_ *stackmanager.StackManager, _ context.Context, _ request.CreateRunRequest) (*entity.Run, error) {
+				return &entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).GetRunByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint) (*entity.Run, error) {
+				return &entity.Run{ID: id, Status: runStatus()}, nil
+			}).Build()
+		mockey.Mock((*stackmanager.StackManager).UpdateRunResultAndStatusByID).To(
+			func(_ *stackmanager.StackManager, _ context.Context, id uint, payload request.UpdateRunResultRequest) (*entity.Run, error) {
+				if payload.Status != "" {
+					mu.Lock()
+					statuses = append(statuses, constant.RunStatus(payload.Status))
+					mu.Unlock()
+				}
+				return &entity.Run{ID: id, Status: runStatus()}, nil
+			}).Build()
+		// The fake apply keeps running until its context is cancelled
+		applying := make(chan struct{})
+		applyErr := make(chan error, 1)
+		mockey.Mock((*stackmanager.StackManager).ApplyStack).To(
+			func(_ *stackmanager.StackManager, ctx context.Context, _ *stackmanager.StackRequestParams, _ request.StackImportRequest) error {
+				close(applying)
+				<-ctx.Done()
+				applyErr <- ctx.Err()
+				return ctx.Err()
+			}).Build()
+
+		router := chi.NewRouter()
+		router.Post("/stacks/{stackID}/apply/async", stackHandler.ApplyStackAsync())
+		router.Post("/runs/{runID}/cancel", stackHandler.CancelRun())
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/stacks/1/apply/async?workspace=dev", strings.NewReader("{}")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		<-applying
+
+		// The run is set to cancelled on condition that it is still in progress
+		sqlMock.ExpectExec("UPDATE").
+			WillReturnResult(sqlmock.NewResult(int64(1), int64(1)))
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/runs/1/cancel", nil))
+		var resp handler.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.True(t, resp.Success)
+		assert.ErrorIs(t, <-applyErr, context.Canceled)
+
+		// The worker unwinds and sets the run to cancelled with its logs
+		stackHandler.workerPool.Wait()
+		require.NoError(t, sqlMock.ExpectationsWereMet())
+		assert.Equal(t, []constant.RunStatus{
+			constant.RunStatusQueued,
+			constant.RunStatusInProgress,
+			constant.RunStatusCancelled,
+		}, statuses)
+
+		// The cancelled run cannot be cancelled again
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/runs/1/cancel", nil))
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.False(t, resp.Success)
+		assert.Equal(t, stackmanager.ErrRunAlreadyCompleted.Error(), resp.Message)
+	})
+}
+
+func newRunEventsServer(stackHandler *Handler) *httptest.Server {
+	router := chi.NewRouter()
+	router.Get("/runs/{runID}/events", stackHandler.StreamRunEvents())
+	return httptest.NewServer(router)
+}
+
+// readRunEvents decodes the data of the server-sent events, the returned channel is closed at the end of the stream.
+func readRunEvents(body io.Reader) <-chan stackmanager.RunEvent {
+	events := make(chan stackmanager.RunEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event stackmanager.RunEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return
+			}
+			events <- event
+		}
+	}()
+	return events
+}
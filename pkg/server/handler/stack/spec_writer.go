@@ -0,0 +1,100 @@
+package stack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	yamlv2 "gopkg.in/yaml.v2"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// specTail contains the fields of the spec following the resources, in the order of the spec fields.
+type specTail struct {
+	SecretStore  *apiv1.SecretStore            `yaml:"secretStore"`
+	SecretStores map[string]*apiv1.SecretStore `yaml:"secretStores,omitempty"`
+	Context      apiv1.GenericConfig           `yaml:"context"`
+}
+
+// writeSpecYAML writes the spec to w in the YAML form of yamlv2.Marshal, but marshals the spec resource by
+// resource, so that the YAML of the whole spec is never built up in memory for the stacks generating
+// thousands of resources. Each resource is written to w in a single Write call.
+func writeSpecYAML(w io.Writer, sp *apiv1.Spec) error {
+	if sp == nil {
+		_, err := io.WriteString(w, "null\n")
+		return err
+	}
+
+	if len(sp.Resources) == 0 {
+		if _, err := io.WriteString(w, "resources: []\n"); err != nil {
+			return err
+		}
+	} else {
+		if _, err := io.WriteString(w, "resources:\n"); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for i := range sp.Resources {
+			out, err := yamlv2.Marshal(&sp.Resources[i])
+			if err != nil {
+				return err
+			}
+
+			// write the resource as a sequence item, which is not indented in the mapping by yamlv2
+			buf.Reset()
+			for j, line := range bytes.SplitAfter(out, []byte("\n")) {
+				if len(line) == 0 {
+					continue
+				}
+				if j == 0 {
+					buf.WriteString("- ")
+				} else {
+					buf.WriteString("  ")
+				}
+				buf.Write(line)
+			}
+			if _, err = w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	out, err := yamlv2.Marshal(&specTail{SecretStore: sp.SecretStore, SecretStores: sp.SecretStores, Context: sp.Context})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// jsonStringWriter writes the content as the body of a JSON string, i.e. the JSON-encoded string without
+// the surrounding quotes. The content of each Write call must be complete UTF-8 characters.
+type jsonStringWriter struct {
+	w io.Writer
+}
+
+func (j *jsonStringWriter) Write(p []byte) (int, error) {
+	out, err := json.Marshal(string(p))
+	if err != nil {
+		return 0, err
+	}
+	if _, err = j.w.Write(out[1 : len(out)-1]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeSpecResult writes the run result of the spec to w, which is the JSON-encoded YAML of the spec as
+// the result set by setRunToSuccessWithPayload. The spec is encoded resource by resource straight to w, so
+// neither the YAML of the spec nor its JSON encoding is held in memory apart from w.
+func writeSpecResult(w io.Writer, sp *apiv1.Spec) error {
+	if _, err := io.WriteString(w, `"`); err != nil {
+		return err
+	}
+	if err := writeSpecYAML(&jsonStringWriter{w: w}, sp); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, `"`)
+	return err
+}
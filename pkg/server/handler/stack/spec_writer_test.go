@@ -0,0 +1,72 @@
+package stack
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yamlv2 "gopkg.in/yaml.v2"
+
+	apiv1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestWriteSpecYAML(t *testing.T) {
+	testcases := []struct {
+		name string
+		spec *apiv1.Spec
+	}{
+		{
+			name: "nil spec",
+		},
+		{
+			name: "empty spec",
+			spec: &apiv1.Spec{},
+		},
+		{
+			name: "spec with resources",
+			spec: &apiv1.Spec{
+				Resources: apiv1.Resources{
+					{
+						ID:   "v1:Namespace:foo",
+						Type: apiv1.Kubernetes,
+						Attributes: map[string]interface{}{
+							"apiVersion": "v1",
+							"kind":       "Namespace",
+							"metadata": map[string]interface{}{
+								"name":   "foo",
+								"labels": map[string]interface{}{"app": "foo"},
+							},
+						},
+					},
+					{
+						ID:        "v1:ConfigMap:foo:bar",
+						Type:      apiv1.Kubernetes,
+						DependsOn: []string{"v1:Namespace:foo"},
+						Attributes: map[string]interface{}{
+							"data": map[string]interface{}{"script": "line1\nline2\n"},
+						},
+					},
+				},
+				Context: apiv1.GenericConfig{"cluster": "dev"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := yamlv2.Marshal(tc.spec)
+			assert.NoError(t, err)
+
+			var sb strings.Builder
+			assert.NoError(t, writeSpecYAML(&sb, tc.spec))
+			assert.Equal(t, string(expected), sb.String())
+
+			var result strings.Builder
+			assert.NoError(t, writeSpecResult(&result, tc.spec))
+			expectedResult, err := json.Marshal(string(expected))
+			assert.NoError(t, err)
+			assert.Equal(t, string(expectedResult), result.String())
+		})
+	}
+}
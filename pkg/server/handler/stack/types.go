@@ -1,6 +1,8 @@
 package stack
 
 import (
+	"time"
+
 	worker "kusionstack.io/kusion/pkg/infra/util/worker"
 	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
 )
@@ -9,16 +11,20 @@ func NewHandler(
 	stackManager *stackmanager.StackManager,
 	maxAsyncConcurrent int,
 	maxAsyncBuffer int,
+	maxRunTimeout time.Duration,
 ) (*Handler, error) {
 	return &Handler{
-		stackManager: stackManager,
-		workerPool:   worker.NewWorkerPool(maxAsyncConcurrent, maxAsyncBuffer),
+		stackManager:  stackManager,
+		workerPool:    worker.NewWorkerPool(maxAsyncConcurrent, maxAsyncBuffer),
+		maxRunTimeout: maxRunTimeout,
 	}, nil
 }
 
 type Handler struct {
 	stackManager *stackmanager.StackManager
 	workerPool   *worker.WorkerPool
+	// maxRunTimeout bounds the timeout specified for each async run, no bound is applied if it is zero
+	maxRunTimeout time.Duration
 }
 
 // TODO: graceful shutdown of worker pool when exiting
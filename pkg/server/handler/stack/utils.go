@@ -13,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v2"
 	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/request"
 	stackmanager "kusionstack.io/kusion/pkg/server/manager/stack"
 	appmiddleware "kusionstack.io/kusion/pkg/server/middleware"
@@ -22,20 +23,30 @@ import (
 )
 
 func (h *Handler) setRunToSuccess(ctx context.Context, runID uint, result any) {
+	h.setRunToSuccessWithPayload(ctx, runID, result, request.UpdateRunResultRequest{})
+}
+
+// setRunToSuccessWithPayload sets the run to success along with the extra fields in the payload,
+// e.g. the summaries of the run.
+func (h *Handler) setRunToSuccessWithPayload(ctx context.Context, runID uint, result any, updateRunResultPayload request.UpdateRunResultRequest) {
 	logger := logutil.GetLogger(ctx)
-	runLogs := logutil.GetRunLoggerBuffer(ctx)
 	resultBytes, err := json.Marshal(result)
 	if err != nil {
 		logger.Error("Error marshalling preview changes", "error", err)
 		return
 	}
+	h.setRunToSuccessWithMarshalledResult(ctx, runID, string(resultBytes), updateRunResultPayload)
+}
+
+// setRunToSuccessWithMarshalledResult sets the run to success with the result already marshalled to JSON.
+func (h *Handler) setRunToSuccessWithMarshalledResult(ctx context.Context, runID uint, result string, updateRunResultPayload request.UpdateRunResultRequest) {
+	logger := logutil.GetLogger(ctx)
+	runLogs := logutil.GetRunLoggerBuffer(ctx)
 	// Update the Run object in database to include the preview result
-	updateRunResultPayload := request.UpdateRunResultRequest{
-		Result: string(resultBytes),
-		Status: string(constant.RunStatusSucceeded),
-		Logs:   runLogs.String(),
-	}
-	_, err = h.stackManager.UpdateRunResultAndStatusByID(ctx, runID, updateRunResultPayload)
+	updateRunResultPayload.Result = result
+	updateRunResultPayload.Status = string(constant.RunStatusSucceeded)
+	updateRunResultPayload.Logs = runLogs.String()
+	_, err := h.stackManager.UpdateRunResultAndStatusByID(ctx, runID, updateRunResultPayload)
 	if err != nil {
 		logger.Error("Error updating run result after success", "error", err)
 		return
@@ -43,11 +54,17 @@ func (h *Handler) setRunToSuccess(ctx context.Context, runID uint, result any) {
 }
 
 func (h *Handler) setRunToFailed(ctx context.Context, runID uint) {
+	h.setRunToFailedWithResult(ctx, runID, constant.RunResultFailed)
+}
+
+// setRunToFailedWithResult sets the run to failed with the result telling why it failed, e.g. the conflict of
+// the previewed changes.
+func (h *Handler) setRunToFailedWithResult(ctx context.Context, runID uint, result string) {
 	logger := logutil.GetLogger(ctx)
 	runLogs := logutil.GetRunLoggerBuffer(ctx)
 	logsNew := strings.ReplaceAll(runLogs.String(), "\n", "\n\n")
 	updateRunResultPayload := request.UpdateRunResultRequest{
-		Result: constant.RunResultFailed,
+		Result: result,
 		Status: string(constant.RunStatusFailed),
 		Logs:   logsNew,
 	}
@@ -58,10 +75,29 @@ func (h *Handler) setRunToFailed(ctx context.Context, runID uint) {
 }
 
 func (h *Handler) setRunToCancelled(ctx context.Context, runID uint) {
+	h.setRunToCancelledWithMarshalledResult(ctx, runID, constant.RunResultCancelled)
+}
+
+// setRunToCancelledWithProgress sets the apply run to cancelled with its partial progress as the result, so that
+// the resources already applied are known.
+func (h *Handler) setRunToCancelledWithProgress(ctx context.Context, runID uint, progress *stackmanager.ApplyProgress) {
+	logger := logutil.GetLogger(ctx)
+	progress.Result = constant.RunResultMessageCancelled
+	resultBytes, err := json.Marshal(progress)
+	if err != nil {
+		logger.Error("Error marshalling apply progress", "error", err)
+		h.setRunToCancelled(ctx, runID)
+		return
+	}
+	h.setRunToCancelledWithMarshalledResult(ctx, runID, string(resultBytes))
+}
+
+// setRunToCancelledWithMarshalledResult sets the run to cancelled with the result already marshalled to JSON.
+func (h *Handler) setRunToCancelledWithMarshalledResult(ctx context.Context, runID uint, result string) {
 	logger := logutil.GetLogger(ctx)
 	runLogs := logutil.GetRunLoggerBuffer(ctx)
 	updateRunResultPayload := request.UpdateRunResultRequest{
-		Result: constant.RunResultCancelled,
+		Result: result,
 		Status: string(constant.RunStatusCancelled),
 		Logs:   runLogs.String(),
 	}
@@ -72,6 +108,8 @@ func (h *Handler) setRunToCancelled(ctx context.Context, runID uint) {
 	}
 }
 
+// setRunToQueued sets the run to Queued before its async task is queued, which must not be called after the
+// task is queued, otherwise it may overwrite the status set by the worker picking up the task.
 func (h *Handler) setRunToQueued(ctx context.Context, runID uint) {
 	logger := logutil.GetLogger(ctx)
 	runLogs := logutil.GetRunLoggerBuffer(ctx)
@@ -87,6 +125,31 @@ func (h *Handler) setRunToQueued(ctx context.Context, runID uint) {
 	}
 }
 
+// setRunToInProgress sets the run to InProgress when the async task of the run is picked up by a worker,
+// unless the run has been cancelled while it is queued.
+func (h *Handler) setRunToInProgress(ctx context.Context, runID uint) {
+	if ctx.Err() != nil {
+		return
+	}
+	logger := logutil.GetLogger(ctx)
+	updateRunResultPayload := request.UpdateRunResultRequest{
+		Status: string(constant.RunStatusInProgress),
+	}
+	_, err := h.stackManager.UpdateRunResultAndStatusByID(ctx, runID, updateRunResultPayload)
+	if err != nil {
+		logger.Error("Error updating run status after starting", "error", err)
+	}
+}
+
+// setRunQueuePosition sets the run queued in the buffer zone to Queued in the response, along with its position
+// in the queue and the estimated wait computed from the pending tasks of the worker pool.
+func (h *Handler) setRunQueuePosition(runEntity *entity.Run) {
+	runEntity.Status = constant.RunStatusQueued
+	// The run may have been picked up by a worker right after it is queued
+	runEntity.QueuePosition = max(h.workerPool.Pending(), 1)
+	runEntity.EstimatedWaitSeconds = int(h.workerPool.EstimatedWait(runEntity.QueuePosition).Seconds())
+}
+
 func requestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackmanager.StackRequestParams, error) {
 	ctx := r.Context()
 	stackID := chi.URLParam(r, "stackID")
@@ -104,6 +167,10 @@ func requestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackman
 	noCacheParam, _ := strconv.ParseBool(r.URL.Query().Get("noCache"))
 	unlockParam, _ := strconv.ParseBool(r.URL.Query().Get("unlock"))
 	watchParam, _ := strconv.ParseBool(r.URL.Query().Get("watch"))
+	workloadOnlyParam, _ := strconv.ParseBool(r.URL.Query().Get("workloadOnly"))
+	withSummaryParam, _ := strconv.ParseBool(r.URL.Query().Get("withSummary"))
+	strictSecretStoreParam, _ := strconv.ParseBool(r.URL.Query().Get("strictSecretStore"))
+	nonStrictParam, _ := strconv.ParseBool(r.URL.Query().Get("nonStrict"))
 	watchTimeoutStr := r.URL.Query().Get("watchTimeout")
 	if watchTimeoutStr == "" {
 		watchTimeoutStr = "120"
@@ -113,17 +180,28 @@ func requestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackman
 		return ctx, nil, nil, stackmanager.ErrInvalidWatchTimeout
 	}
 	importResourcesParam, _ := strconv.ParseBool(r.URL.Query().Get("importResources"))
+	planParam, _ := strconv.ParseBool(r.URL.Query().Get("plan"))
+	var planIDParam uint
+	if planIDStr := r.URL.Query().Get("planID"); planIDStr != "" {
+		planID, err := strconv.Atoi(planIDStr)
+		if err != nil || planID <= 0 {
+			return ctx, nil, nil, stackmanager.ErrInvalidPlanID
+		}
+		planIDParam = uint(planID)
+	}
+	var timeoutParam int
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		timeout, err := strconv.Atoi(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return ctx, nil, nil, stackmanager.ErrInvalidRunTimeout
+		}
+		timeoutParam = timeout
+	}
 	specIDParam := r.URL.Query().Get("specID")
+	expectedChecksumParam := r.URL.Query().Get("expectedChecksum")
 	// TODO: Should match automatically eventually???
 	workspaceParam := r.URL.Query().Get("workspace")
-	operatorParam, err := authutil.GetSubjectFromUnverifiedJWTToken(ctx, r)
-	// fall back to x-kusion-user if operator is not parsed from cookie
-	if operatorParam == "" || err != nil {
-		operatorParam = appmiddleware.GetUserID(ctx)
-		if operatorParam == "" {
-			operatorParam = constant.DefaultUser
-		}
-	}
+	operatorParam := getOperator(ctx, r)
 	executeParams := stackmanager.StackExecuteParams{
 		Detail:              detailParam,
 		Dryrun:              dryrunParam,
@@ -134,6 +212,14 @@ func requestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackman
 		Unlock:              unlockParam,
 		Watch:               watchParam,
 		WatchTimeoutSeconds: watchTimeoutParam,
+		WorkloadOnly:        workloadOnlyParam,
+		WithSummary:         withSummaryParam,
+		StrictSecretStore:   strictSecretStoreParam,
+		NonStrict:           nonStrictParam,
+		Plan:                planParam,
+		PlanID:              planIDParam,
+		TimeoutSeconds:      timeoutParam,
+		ExpectedChecksum:    expectedChecksumParam,
 	}
 	params := stackmanager.StackRequestParams{
 		StackID:       uint(id),
@@ -145,6 +231,19 @@ func requestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackman
 	return ctx, logger, &params, nil
 }
 
+// getOperator returns the user operating the request.
+func getOperator(ctx context.Context, r *http.Request) string {
+	operator, err := authutil.GetSubjectFromUnverifiedJWTToken(ctx, r)
+	// fall back to x-kusion-user if operator is not parsed from cookie
+	if operator == "" || err != nil {
+		operator = appmiddleware.GetUserID(ctx)
+		if operator == "" {
+			operator = constant.DefaultUser
+		}
+	}
+	return operator
+}
+
 func runRequestHelper(r *http.Request) (context.Context, *httplog.Logger, *stackmanager.RunRequestParams, error) {
 	ctx := r.Context()
 	runID := chi.URLParam(r, "runID")
@@ -164,6 +263,7 @@ func CopyToNewContext(ctx context.Context) context.Context {
 	newCtx := context.Background()
 	newCtx = context.WithValue(newCtx, appmiddleware.TraceIDKey, appmiddleware.GetTraceID(ctx))
 	newCtx = context.WithValue(newCtx, appmiddleware.UserIDKey, appmiddleware.GetUserID(ctx))
+	newCtx = context.WithValue(newCtx, appmiddleware.OrgNameKey, appmiddleware.GetOrgName(ctx))
 	if logger, ok := ctx.Value(appmiddleware.APILoggerKey).(*httplog.Logger); ok {
 		newCtx = context.WithValue(newCtx, appmiddleware.APILoggerKey, logger)
 	}
@@ -207,4 +307,21 @@ func updateRunRequestPayload(requestPayload *request.CreateRunRequest, params *s
 	requestPayload.StackID = params.StackID
 	requestPayload.Type = string(runType)
 	requestPayload.Workspace = params.Workspace
+	// The timeout in the query overrides the one in the request body
+	if params.ExecuteParams.TimeoutSeconds > 0 {
+		requestPayload.Timeout = params.ExecuteParams.TimeoutSeconds
+	}
+}
+
+// runTimeout returns the timeout of the async run, which defaults to constant.RunTimeOut if the timeout in seconds
+// is not positive, and is bounded by the max run timeout of the server.
+func (h *Handler) runTimeout(timeoutSeconds int) time.Duration {
+	timeout := constant.RunTimeOut
+	if timeoutSeconds > 0 {
+		timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+	if h.maxRunTimeout > 0 && timeout > h.maxRunTimeout {
+		timeout = h.maxRunTimeout
+	}
+	return timeout
 }
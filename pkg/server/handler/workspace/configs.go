@@ -66,6 +66,25 @@ func (h *Handler) ValidateWorkspaceConfigs() http.HandlerFunc {
 	}
 }
 
+// @Id				listSecretProviders
+// @Summary		List secret store providers
+// @Description	List the registered secret store providers and the fields to configure them in the secret store
+// @Tags			workspace
+// @Produce		json
+// @Success		200	{object}	[]secrets.ProviderInfo	"Success"
+// @Failure		400	{object}	error					"Bad Request"
+// @Failure		401	{object}	error					"Unauthorized"
+// @Failure		429	{object}	error					"Too Many Requests"
+// @Failure		404	{object}	error					"Not Found"
+// @Failure		500	{object}	error					"Internal Server Error"
+// @Router			/api/v1/workspaces/secret-providers [get]
+func (h *Handler) ListSecretProviders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		providers := h.workspaceManager.ListSecretProviders(r.Context())
+		handler.HandleResult(w, r, r.Context(), nil, providers)
+	}
+}
+
 // @Id				updateWorkspaceConfigs
 // @Summary		Update workspace configurations
 // @Description	Update the configurations in the specified workspace
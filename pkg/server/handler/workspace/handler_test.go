@@ -23,6 +23,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/resource/graph"
 	"kusionstack.io/kusion/pkg/infra/persistence"
 	"kusionstack.io/kusion/pkg/server/handler"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 	workspacemanager "kusionstack.io/kusion/pkg/server/manager/workspace"
 	"kusionstack.io/kusion/pkg/workspace"
 )
@@ -320,7 +321,8 @@ func setupTest(t *testing.T) (sqlmock.Sqlmock, *gorm.DB, *httptest.ResponseRecor
 	backendRepo := persistence.NewBackendRepository(fakeGDB)
 	moduleRepo := persistence.NewModuleRepository(fakeGDB)
 	workspaceHandler := &Handler{
-		workspaceManager: workspacemanager.NewWorkspaceManager(workspaceRepo, backendRepo, moduleRepo, entity.Backend{}),
+		workspaceManager: workspacemanager.NewWorkspaceManager(workspaceRepo,
+			backendmanager.NewScope(backendRepo, persistence.NewOrganizationRepository(fakeGDB), false), moduleRepo, entity.Backend{}),
 	}
 	recorder := httptest.NewRecorder()
 	return sqlMock, fakeGDB, recorder, workspaceHandler
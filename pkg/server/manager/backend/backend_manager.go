@@ -8,6 +8,7 @@ import (
 
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/request"
@@ -33,12 +34,11 @@ func (m *BackendManager) ListBackends(ctx context.Context, filter *entity.Backen
 	return backendEntities, nil
 }
 
+// GetBackendByID gets the backend by id. If the backends are scoped to organizations, the
+// backend scoped to other organizations than the requesting one is regarded as non-existing.
 func (m *BackendManager) GetBackendByID(ctx context.Context, id uint) (*entity.Backend, error) {
-	existingEntity, err := m.backendRepo.Get(ctx, id)
+	existingEntity, err := m.scope.GetVisibleBackend(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrGettingNonExistingBackend
-		}
 		return nil, err
 	}
 
@@ -51,6 +51,11 @@ func (m *BackendManager) GetBackendByID(ctx context.Context, id uint) (*entity.B
 }
 
 func (m *BackendManager) DeleteBackendByID(ctx context.Context, id uint) error {
+	if m.scope.OrgScoped() {
+		if _, err := m.scope.GetVisibleBackend(ctx, id); err != nil {
+			return err
+		}
+	}
 	err := m.backendRepo.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -67,11 +72,14 @@ func (m *BackendManager) UpdateBackendByID(ctx context.Context, id uint, request
 	if err := copier.Copy(&requestEntity, &requestPayload); err != nil {
 		return nil, err
 	}
+	if err := m.scope.CheckScopedOrganization(ctx, requestEntity.OrganizationID); err != nil {
+		return nil, err
+	}
 
 	// Get the existing backend by id
-	updatedEntity, err := m.backendRepo.Get(ctx, id)
+	updatedEntity, err := m.scope.GetVisibleBackend(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, ErrGettingNonExistingBackend) {
 			return nil, ErrUpdatingNonExistingBackend
 		}
 		return nil, err
@@ -100,6 +108,9 @@ func (m *BackendManager) CreateBackend(ctx context.Context, requestPayload reque
 	if err := copier.Copy(&createdEntity, &requestPayload); err != nil {
 		return nil, err
 	}
+	if err := m.scope.CheckScopedOrganization(ctx, createdEntity.OrganizationID); err != nil {
+		return nil, err
+	}
 
 	// Create backend with repository
 	err := m.backendRepo.Create(ctx, &createdEntity)
@@ -119,7 +130,25 @@ func (m *BackendManager) BuildBackendFilterAndSortOptions(ctx context.Context, q
 	logger := logutil.GetLogger(ctx)
 	logger.Info("Building backend filter...")
 
-	filter := entity.BackendFilter{}
+	orgID, err := m.scope.RequesterOrgID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := entity.BackendFilter{
+		OrgScoped: m.scope.OrgScoped(),
+		OrgID:     orgID,
+	}
+
+	backendType := query.Get("type")
+	if backendType != "" {
+		if backendType != v1.BackendTypeLocal &&
+			backendType != v1.BackendTypeOss &&
+			backendType != v1.BackendTypeS3 &&
+			backendType != v1.BackendTypeGoogle {
+			return nil, nil, constant.ErrInvalidBackendType
+		}
+		filter.Type = backendType
+	}
 
 	// Set pagination parameters.
 	page, _ := strconv.Atoi(query.Get("page"))
@@ -137,7 +166,7 @@ func (m *BackendManager) BuildBackendFilterAndSortOptions(ctx context.Context, q
 
 	// Build sort options
 	sortBy := query.Get("sortBy")
-	sortBy, err := validateBackendSortOptions(sortBy)
+	sortBy, err = validateBackendSortOptions(sortBy)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/repository"
+	appmiddleware "kusionstack.io/kusion/pkg/server/middleware"
+)
+
+// Scope resolves the backends visible to the requester, which is shared by all the managers looking up
+// the backends, so that a backend scoped to an organization is neither seen nor used by the others.
+type Scope struct {
+	backendRepo      repository.BackendRepository
+	organizationRepo repository.OrganizationRepository
+	// orgScoped indicates whether the backends are only visible to the organizations they are
+	// scoped to, the global backends are always visible. The organization of the requester is
+	// taken from the authenticated request context.
+	orgScoped bool
+}
+
+func NewScope(backendRepo repository.BackendRepository, organizationRepo repository.OrganizationRepository, orgScoped bool) *Scope {
+	return &Scope{
+		backendRepo:      backendRepo,
+		organizationRepo: organizationRepo,
+		orgScoped:        orgScoped,
+	}
+}
+
+// OrgScoped reports whether the backends are scoped to organizations.
+func (s *Scope) OrgScoped() bool {
+	return s.orgScoped
+}
+
+// RequesterOrgID returns the ID of the organization of the authenticated requester if the backends are
+// scoped to organizations. Zero is returned for the requester without an organization, who is only
+// allowed to access the global backends.
+func (s *Scope) RequesterOrgID(ctx context.Context) (uint, error) {
+	if !s.orgScoped {
+		return 0, nil
+	}
+	orgName := appmiddleware.GetOrgName(ctx)
+	if orgName == "" {
+		return 0, nil
+	}
+	org, err := s.organizationRepo.GetByName(ctx, orgName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return org.ID, nil
+}
+
+// GetVisibleBackend gets the backend by id, which is regarded as non-existing if it is scoped to other
+// organizations than the one of the requester.
+func (s *Scope) GetVisibleBackend(ctx context.Context, id uint) (*entity.Backend, error) {
+	existingEntity, err := s.backendRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGettingNonExistingBackend
+		}
+		return nil, err
+	}
+	if err = s.CheckVisible(ctx, existingEntity); err != nil {
+		return nil, err
+	}
+	return existingEntity, nil
+}
+
+// CheckVisible checks the backend already loaded, e.g. along with a workspace, is visible to the requester,
+// and regards the backend scoped to other organizations as non-existing.
+func (s *Scope) CheckVisible(ctx context.Context, backendEntity *entity.Backend) error {
+	if !s.orgScoped || backendEntity.OrganizationID == 0 {
+		return nil
+	}
+	orgID, err := s.RequesterOrgID(ctx)
+	if err != nil {
+		return err
+	}
+	if backendEntity.OrganizationID != orgID {
+		return ErrGettingNonExistingBackend
+	}
+	return nil
+}
+
+// CheckScopedOrganization checks the backend is scoped to the organization of the requester, or to none
+// for the global backend, if the backends are scoped to organizations.
+func (s *Scope) CheckScopedOrganization(ctx context.Context, scopedOrgID uint) error {
+	if !s.orgScoped || scopedOrgID == 0 {
+		return nil
+	}
+	orgID, err := s.RequesterOrgID(ctx)
+	if err != nil {
+		return err
+	}
+	if scopedOrgID != orgID {
+		return ErrScopingToOtherOrganization
+	}
+	return nil
+}
@@ -11,14 +11,17 @@ var (
 	ErrUpdatingNonExistingBackend = errors.New("the backend to update does not exist")
 	ErrInvalidBackendID           = errors.New("the backend ID should be a uuid")
 	ErrInternalServerError        = errors.New("internal server error")
+	ErrScopingToOtherOrganization = errors.New("the backend can only be scoped to the organization of the requester")
 )
 
 type BackendManager struct {
 	backendRepo repository.BackendRepository
+	scope       *Scope
 }
 
-func NewBackendManager(backendRepo repository.BackendRepository) *BackendManager {
+func NewBackendManager(backendRepo repository.BackendRepository, organizationRepo repository.OrganizationRepository, orgScoped bool) *BackendManager {
 	return &BackendManager{
 		backendRepo: backendRepo,
+		scope:       NewScope(backendRepo, organizationRepo, orgScoped),
 	}
 }
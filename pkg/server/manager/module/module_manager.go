@@ -13,6 +13,7 @@ import (
 	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/request"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 	"kusionstack.io/kusion/pkg/server/manager/workspace"
 	logutil "kusionstack.io/kusion/pkg/server/util/logging"
 )
@@ -149,9 +150,9 @@ func (m *ModuleManager) ListModulesByWorkspaceID(ctx context.Context, workspaceI
 		return nil, err
 	}
 
-	// Get backend by backend ID.
-	backendEntity, err := m.backendRepo.Get(ctx, existingEntity.Backend.ID)
-	if err != nil && err == gorm.ErrRecordNotFound {
+	// Get backend by backend ID, which must be visible to the requester.
+	backendEntity, err := m.backendScope.GetVisibleBackend(ctx, existingEntity.Backend.ID)
+	if errors.Is(err, backendmanager.ErrGettingNonExistingBackend) {
 		return nil, workspace.ErrBackendNotFound
 	} else if err != nil {
 		return nil, err
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"kusionstack.io/kusion/pkg/domain/repository"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 )
 
 var (
@@ -16,16 +17,16 @@ var (
 type ModuleManager struct {
 	moduleRepo    repository.ModuleRepository
 	workspaceRepo repository.WorkspaceRepository
-	backendRepo   repository.BackendRepository
+	backendScope  *backendmanager.Scope
 }
 
 func NewModuleManager(moduleRepo repository.ModuleRepository,
 	workspaceRepo repository.WorkspaceRepository,
-	backendRepo repository.BackendRepository,
+	backendScope *backendmanager.Scope,
 ) *ModuleManager {
 	return &ModuleManager{
 		moduleRepo:    moduleRepo,
 		workspaceRepo: workspaceRepo,
-		backendRepo:   backendRepo,
+		backendScope:  backendScope,
 	}
 }
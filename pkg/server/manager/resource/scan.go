@@ -0,0 +1,237 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/request"
+	logutil "kusionstack.io/kusion/pkg/server/util/logging"
+	"kusionstack.io/kusion/pkg/workspace"
+)
+
+// awsNameTagKey is the tag key of the AWS resource name, which is preferred as the
+// resource name in the kusion resource ID.
+const awsNameTagKey = "Name"
+
+// awsResourceTypes maps the AWS service and resource type in the ARN to the terraform
+// resource type, resources of other types are skipped when scanning. The load balancers
+// are keyed by their types additionally, i.e. app, net and gwy for the ones of ELBv2, and
+// none for the classic ones.
+var awsResourceTypes = map[string]string{
+	"s3":                                    "aws_s3_bucket",
+	"ec2/instance":                          "aws_instance",
+	"ec2/vpc":                               "aws_vpc",
+	"ec2/subnet":                            "aws_subnet",
+	"ec2/security-group":                    "aws_security_group",
+	"rds/db":                                "aws_db_instance",
+	"dynamodb/table":                        "aws_dynamodb_table",
+	"elasticache/cluster":                   "aws_elasticache_cluster",
+	"secretsmanager/secret":                 "aws_secretsmanager_secret",
+	"lambda/function":                       "aws_lambda_function",
+	"elasticloadbalancing/loadbalancer":     "aws_elb",
+	"elasticloadbalancing/loadbalancer/app": "aws_lb",
+	"elasticloadbalancing/loadbalancer/net": "aws_lb",
+	"elasticloadbalancing/loadbalancer/gwy": "aws_lb",
+}
+
+// awsImportedByARN are the terraform resource types imported by the full ARN, instead of
+// the ID in the resource part of the ARN.
+var awsImportedByARN = map[string]bool{
+	"aws_lb":                    true,
+	"aws_secretsmanager_secret": true,
+}
+
+// taggedResource is a resource selected by the tag filters.
+type taggedResource struct {
+	arn  string
+	tags map[string]string
+}
+
+// ScanImportedResources scans the existing resources of the provider matching the tag
+// filters with the credentials of the workspace, and generates the importedResources
+// mapping from the kusion resource IDs to the provider resource IDs. The kusion resource
+// IDs shared by multiple resources are returned as collisions along with their provider
+// resource IDs, which are left out of the mapping.
+func (m *ResourceManager) ScanImportedResources(ctx context.Context, requestPayload request.ScanImportedResourcesRequest) (map[string]string, map[string][]string, error) {
+	logger := logutil.GetLogger(ctx)
+	logger.Info("Scanning resources to import...", "provider", requestPayload.Provider,
+		"workspaceID", requestPayload.WorkspaceID, "region", requestPayload.Region)
+
+	if requestPayload.Provider != constant.AWSProviderType {
+		return nil, nil, constant.ErrUnsupportedScanProvider
+	}
+
+	// The workspace must be visible to the requester, which is checked on getting its configs
+	configs, err := m.workspaceManager.GetWorkspaceConfigs(ctx, requestPayload.WorkspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds, err := awsCredentials(configs.Workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resources, err := scanAWSTaggedResources(ctx, creds, requestPayload.Region, requestPayload.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	importedResources, collisions, skipped, err := awsImportedResources(resources)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, resourceARN := range skipped {
+		logger.Warn("Skipped scanned resource of unsupported type", "arn", resourceARN)
+	}
+	for kusionID, importedIDs := range collisions {
+		logger.Warn("Skipped scanned resources sharing the same kusion id", "kusionID", kusionID, "ids", importedIDs)
+	}
+	return importedResources, collisions, nil
+}
+
+// awsCredentials returns the static AWS credentials in the context of the workspace, which
+// are the same ones used by the terraform runtime to apply the AWS resources. The default
+// AWS credential chain of the server is never used.
+func awsCredentials(ws *v1.Workspace) (*credentials.Credentials, error) {
+	if ws == nil {
+		return nil, constant.ErrScanCredentialsNotFound
+	}
+	accessKeyID, err := workspace.GetStringFromGenericConfig(ws.Context, v1.EnvAwsAccessKeyID)
+	if err != nil {
+		return nil, err
+	}
+	secretAccessKey, err := workspace.GetStringFromGenericConfig(ws.Context, v1.EnvAwsSecretAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, constant.ErrScanCredentialsNotFound
+	}
+	return credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""), nil
+}
+
+// scanAWSTaggedResources lists the AWS resources matching all the tag filters with the
+// resource groups tagging API.
+func scanAWSTaggedResources(ctx context.Context, creds *credentials.Credentials, region string, tags map[string]string) ([]taggedResource, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region), Credentials: creds})
+	if err != nil {
+		return nil, err
+	}
+	client := resourcegroupstaggingapi.New(sess)
+
+	input := &resourcegroupstaggingapi.GetResourcesInput{}
+	for key, value := range tags {
+		filter := &resourcegroupstaggingapi.TagFilter{Key: aws.String(key)}
+		if value != "" {
+			filter.Values = []*string{aws.String(value)}
+		}
+		input.TagFilters = append(input.TagFilters, filter)
+	}
+
+	var resources []taggedResource
+	err = client.GetResourcesPagesWithContext(ctx, input, func(page *resourcegroupstaggingapi.GetResourcesOutput, _ bool) bool {
+		for _, mapping := range page.ResourceTagMappingList {
+			res := taggedResource{
+				arn:  aws.StringValue(mapping.ResourceARN),
+				tags: make(map[string]string, len(mapping.Tags)),
+			}
+			for _, tag := range mapping.Tags {
+				res.tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			resources = append(resources, res)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan aws resources: %w", err)
+	}
+	return resources, nil
+}
+
+// awsImportedResources converts the scanned AWS resources to the importedResources mapping,
+// and returns the resources sharing the same kusion ID, e.g. the ones of the same type and
+// Name tag, as collisions, and the ARNs of the resources skipped for unsupported types.
+func awsImportedResources(resources []taggedResource) (map[string]string, map[string][]string, []string, error) {
+	importedResources := make(map[string]string)
+	collisions := make(map[string][]string)
+	var skipped []string
+	for _, res := range resources {
+		parsed, err := arn.Parse(res.arn)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid aws resource arn %s: %w", res.arn, err)
+		}
+
+		resourceType, importedID, name, ok := awsResource(parsed)
+		if !ok {
+			skipped = append(skipped, res.arn)
+			continue
+		}
+		if tagName := res.tags[awsNameTagKey]; tagName != "" {
+			name = tagName
+		}
+		// kusion ID of terraform resource is in format of "providerNamespace:providerName:resourceType:resourceName"
+		kusionID := strings.Join([]string{constant.HashicorpProviderType, constant.AWSProviderType, resourceType, name}, ":")
+		// none of the resources sharing the kusion id is imported, which is ambiguous
+		if ids, ok := collisions[kusionID]; ok {
+			collisions[kusionID] = append(ids, importedID)
+			continue
+		}
+		if id, ok := importedResources[kusionID]; ok {
+			collisions[kusionID] = []string{id, importedID}
+			delete(importedResources, kusionID)
+			continue
+		}
+		importedResources[kusionID] = importedID
+	}
+	if len(collisions) == 0 {
+		collisions = nil
+	}
+	return importedResources, collisions, skipped, nil
+}
+
+// awsResource returns the terraform resource type, the import ID and the default name of
+// the AWS resource of the ARN, or false if the type is not supported.
+func awsResource(parsed arn.ARN) (string, string, string, bool) {
+	// the resource part of the ARN is in format of "id", "type/id" or "type:id"
+	key, id := parsed.Service, parsed.Resource
+	if i := strings.IndexAny(parsed.Resource, "/:"); i >= 0 {
+		key = parsed.Service + "/" + parsed.Resource[:i]
+		id = parsed.Resource[i+1:]
+	}
+	name := id
+	switch key {
+	case "elasticloadbalancing/loadbalancer":
+		// the ID of ELBv2 is in format of "type/name/hash", while the one of classic ELB is the name
+		if parts := strings.Split(id, "/"); len(parts) == 3 {
+			key += "/" + parts[0]
+			name = parts[1]
+		}
+	case "lambda/function":
+		// the function ARN may be qualified with the version or alias
+		name, _, _ = strings.Cut(id, ":")
+		id = name
+	case "secretsmanager/secret":
+		// the secret name is suffixed with a hyphen and 6 random characters
+		if i := strings.LastIndex(id, "-"); i > 0 && len(id)-i == 7 {
+			name = id[:i]
+		}
+	}
+
+	resourceType, ok := awsResourceTypes[key]
+	if !ok {
+		return "", "", "", false
+	}
+	if awsImportedByARN[resourceType] {
+		id = parsed.String()
+	}
+	return resourceType, id, name, true
+}
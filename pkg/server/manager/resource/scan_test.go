@@ -0,0 +1,143 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+)
+
+func TestAWSImportedResources(t *testing.T) {
+	testcases := []struct {
+		name        string
+		arn         string
+		tags        map[string]string
+		kusionID    string
+		importedID  string
+		skipped     bool
+		errContains string
+	}{
+		{
+			name:       "s3 bucket",
+			arn:        "arn:aws:s3:::my-bucket",
+			kusionID:   "hashicorp:aws:aws_s3_bucket:my-bucket",
+			importedID: "my-bucket",
+		},
+		{
+			name:       "ec2 instance named by tag",
+			arn:        "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0",
+			tags:       map[string]string{"Name": "web"},
+			kusionID:   "hashicorp:aws:aws_instance:web",
+			importedID: "i-0123456789abcdef0",
+		},
+		{
+			name:       "rds instance",
+			arn:        "arn:aws:rds:us-east-1:123456789012:db:my-db",
+			kusionID:   "hashicorp:aws:aws_db_instance:my-db",
+			importedID: "my-db",
+		},
+		{
+			name:       "dynamodb table",
+			arn:        "arn:aws:dynamodb:us-east-1:123456789012:table/my-table",
+			kusionID:   "hashicorp:aws:aws_dynamodb_table:my-table",
+			importedID: "my-table",
+		},
+		{
+			name:       "elasticache cluster",
+			arn:        "arn:aws:elasticache:us-east-1:123456789012:cluster:my-cluster",
+			kusionID:   "hashicorp:aws:aws_elasticache_cluster:my-cluster",
+			importedID: "my-cluster",
+		},
+		{
+			name:       "secretsmanager secret imported by arn",
+			arn:        "arn:aws:secretsmanager:us-east-1:123456789012:secret:my-secret-a1B2c3",
+			kusionID:   "hashicorp:aws:aws_secretsmanager_secret:my-secret",
+			importedID: "arn:aws:secretsmanager:us-east-1:123456789012:secret:my-secret-a1B2c3",
+		},
+		{
+			name:       "qualified lambda function",
+			arn:        "arn:aws:lambda:us-east-1:123456789012:function:my-func:1",
+			kusionID:   "hashicorp:aws:aws_lambda_function:my-func",
+			importedID: "my-func",
+		},
+		{
+			name:       "application load balancer imported by arn",
+			arn:        "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/50dc6c495c0c9188",
+			kusionID:   "hashicorp:aws:aws_lb:my-alb",
+			importedID: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/50dc6c495c0c9188",
+		},
+		{
+			name:       "network load balancer imported by arn",
+			arn:        "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/my-nlb/50dc6c495c0c9188",
+			kusionID:   "hashicorp:aws:aws_lb:my-nlb",
+			importedID: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/my-nlb/50dc6c495c0c9188",
+		},
+		{
+			name:       "classic load balancer",
+			arn:        "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/my-elb",
+			kusionID:   "hashicorp:aws:aws_elb:my-elb",
+			importedID: "my-elb",
+		},
+		{
+			name:    "unsupported target group",
+			arn:     "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/my-tg/50dc6c495c0c9188",
+			skipped: true,
+		},
+		{
+			name:        "invalid arn",
+			arn:         "my-bucket",
+			errContains: "invalid aws resource arn my-bucket",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			importedResources, collisions, skipped, err := awsImportedResources([]taggedResource{{arn: tc.arn, tags: tc.tags}})
+			if tc.errContains != "" {
+				assert.ErrorContains(t, err, tc.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, collisions)
+			if tc.skipped {
+				assert.Equal(t, []string{tc.arn}, skipped)
+				assert.Empty(t, importedResources)
+				return
+			}
+			assert.Empty(t, skipped)
+			assert.Equal(t, map[string]string{tc.kusionID: tc.importedID}, importedResources)
+		})
+	}
+
+	t.Run("duplicate kusion id", func(t *testing.T) {
+		importedResources, collisions, _, err := awsImportedResources([]taggedResource{
+			{arn: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", tags: map[string]string{"Name": "web"}},
+			{arn: "arn:aws:ec2:us-east-1:123456789012:instance/i-2", tags: map[string]string{"Name": "web"}},
+			{arn: "arn:aws:ec2:us-east-1:123456789012:instance/i-3", tags: map[string]string{"Name": "db"}},
+			{arn: "arn:aws:ec2:us-east-1:123456789012:instance/i-4", tags: map[string]string{"Name": "web"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"hashicorp:aws:aws_instance:db": "i-3"}, importedResources)
+		assert.Equal(t, map[string][]string{"hashicorp:aws:aws_instance:web": {"i-1", "i-2", "i-4"}}, collisions)
+	})
+}
+
+func TestAWSCredentials(t *testing.T) {
+	_, err := awsCredentials(&v1.Workspace{Name: "dev"})
+	assert.ErrorIs(t, err, constant.ErrScanCredentialsNotFound)
+
+	_, err = awsCredentials(&v1.Workspace{Name: "dev", Context: v1.GenericConfig{v1.EnvAwsAccessKeyID: "ak"}})
+	assert.ErrorIs(t, err, constant.ErrScanCredentialsNotFound)
+
+	creds, err := awsCredentials(&v1.Workspace{Name: "dev", Context: v1.GenericConfig{
+		v1.EnvAwsAccessKeyID:     "ak",
+		v1.EnvAwsSecretAccessKey: "sk",
+	}})
+	assert.NoError(t, err)
+	value, err := creds.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "ak", value.AccessKeyID)
+	assert.Equal(t, "sk", value.SecretAccessKey)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"kusionstack.io/kusion/pkg/domain/repository"
+	workspacemanager "kusionstack.io/kusion/pkg/server/manager/workspace"
 )
 
 var (
@@ -13,10 +14,14 @@ var (
 
 type ResourceManager struct {
 	resourceRepo repository.ResourceRepository
+	// workspaceManager gets the configs of the workspaces visible to the requester, whose
+	// credentials are used to scan the resources.
+	workspaceManager *workspacemanager.WorkspaceManager
 }
 
-func NewResourceManager(resourceRepo repository.ResourceRepository) *ResourceManager {
+func NewResourceManager(resourceRepo repository.ResourceRepository, workspaceManager *workspacemanager.WorkspaceManager) *ResourceManager {
 	return &ResourceManager{
-		resourceRepo: resourceRepo,
+		resourceRepo:     resourceRepo,
+		workspaceManager: workspaceManager,
 	}
 }
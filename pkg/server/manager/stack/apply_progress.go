@@ -0,0 +1,68 @@
+package stack
+
+import (
+	"sync"
+
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+// ApplyProgress is the progress of the apply run when it exits, which is stored as the result of the cancelled
+// apply run to tell the resources already applied. The cancelled apply stops applying the resources not started
+// yet and interrupts those being applied before the run exits, so the progress no longer changes once taken.
+type ApplyProgress struct {
+	// Result is the message of the run result
+	Result string `json:"result"`
+	// Succeeded is the IDs of the resources applied or skipped
+	Succeeded []string `json:"succeeded"`
+	// Failed is the IDs of the resources failed to apply
+	Failed []string `json:"failed"`
+	// Applying is the IDs of the resources started applying without any result reported when the run exits
+	Applying []string `json:"applying"`
+}
+
+// applyProgress records the apply result of every resource in the order the resources are applied.
+type applyProgress struct {
+	mu          sync.Mutex
+	resourceIDs []string
+	results     map[string]models.OpResult
+}
+
+func (p *applyProgress) record(msg models.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.results[msg.ResourceID]; !ok {
+		p.resourceIDs = append(p.resourceIDs, msg.ResourceID)
+	}
+	p.results[msg.ResourceID] = msg.OpResult
+}
+
+func (p *applyProgress) snapshot() *ApplyProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	progress := &ApplyProgress{
+		Succeeded: []string{},
+		Failed:    []string{},
+		Applying:  []string{},
+	}
+	for _, id := range p.resourceIDs {
+		switch p.results[id] {
+		case models.Success, models.Skip:
+			progress.Succeeded = append(progress.Succeeded, id)
+		case models.Failed:
+			progress.Failed = append(progress.Failed, id)
+		default:
+			progress.Applying = append(progress.Applying, id)
+		}
+	}
+	return progress
+}
+
+// TakeApplyProgress returns the progress of the apply run and stops recording it, nil is returned if the run has
+// not started applying the resources. It must be called after ApplyStack returns, when the progress is complete.
+func (m *StackManager) TakeApplyProgress(runID uint) *ApplyProgress {
+	value, ok := m.applyProgresses.LoadAndDelete(runID)
+	if !ok {
+		return nil
+	}
+	return value.(*applyProgress).snapshot()
+}
@@ -0,0 +1,35 @@
+package stack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+func TestStackManager_TakeApplyProgress(t *testing.T) {
+	t.Run("apply timed out after some resources applied", func(t *testing.T) {
+		m := &StackManager{}
+		progress := m.newProgressHook(1)
+		progress(models.Message{ResourceID: "a"})
+		progress(models.Message{ResourceID: "a", OpResult: models.Success})
+		progress(models.Message{ResourceID: "b"})
+		progress(models.Message{ResourceID: "b", OpResult: models.Failed, OpErr: errors.New("boom")})
+		progress(models.Message{ResourceID: "c", OpResult: models.Skip})
+		progress(models.Message{ResourceID: "d"})
+
+		assert.Equal(t, &ApplyProgress{
+			Succeeded: []string{"a", "c"},
+			Failed:    []string{"b"},
+			Applying:  []string{"d"},
+		}, m.TakeApplyProgress(1))
+		// The progress is no longer recorded after it is taken
+		assert.Nil(t, m.TakeApplyProgress(1))
+	})
+
+	t.Run("apply not started", func(t *testing.T) {
+		m := &StackManager{}
+		assert.Nil(t, m.TakeApplyProgress(1))
+	})
+}
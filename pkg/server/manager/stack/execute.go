@@ -22,25 +22,27 @@ import (
 
 	appmiddleware "kusionstack.io/kusion/pkg/server/middleware"
 	logutil "kusionstack.io/kusion/pkg/server/util/logging"
+	"kusionstack.io/kusion/pkg/workspace"
 )
 
-func (m *StackManager) GenerateSpec(ctx context.Context, params *StackRequestParams) (string, *apiv1.Spec, error) {
+// GenerateSpec generates the spec of the stack along with the summary of the generated resources.
+func (m *StackManager) GenerateSpec(ctx context.Context, params *StackRequestParams) (*apiv1.Spec, *apiv1.ResourceSummary, error) {
 	logger := logutil.GetLogger(ctx)
 	runLogger := logutil.GetRunLogger(ctx)
 	logutil.LogToAll(logger, runLogger, "Info", "Starting generating spec in StackManager...")
 
 	err := validateExecuteRequestParams(params)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Get the stack entity and return error if stack ID is not found
 	stackEntity, err := m.stackRepo.Get(ctx, params.StackID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, ErrGettingNonExistingStack
+			return nil, nil, ErrGettingNonExistingStack
 		}
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Ensure the state is updated properly
@@ -58,33 +60,33 @@ func (m *StackManager) GenerateSpec(ctx context.Context, params *StackRequestPar
 	// To override this, pass in force == true
 	if stackEntity.StackInOperation() && !params.ExecuteParams.Force {
 		err = ErrStackInOperation
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Set stack sync state to generating
 	stackEntity.SyncState = constant.StackStateGenerating
 	err = m.stackRepo.Update(ctx, stackEntity)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Otherwise, generate spec from stack entity using the default generator
 	project, stack, wsBackend, err := m.getStackProjectAndBackend(ctx, stackEntity, params.Workspace)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	wsStorage, err := wsBackend.WorkspaceStorage()
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	ws, err := wsStorage.Get(params.Workspace)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	directory, workDir, err := m.GetWorkdirAndDirectory(ctx, params, stackEntity)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	stack.Path = workDir
 
@@ -98,31 +100,46 @@ func (m *StackManager) GenerateSpec(ctx context.Context, params *StackRequestPar
 	stackEntity.SyncState = constant.StackStateGenerated
 	err = m.stackRepo.Update(ctx, stackEntity)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	// Generate spec
-	sp, err := engineapi.GenerateSpecWithSpinner(project, stack, ws, true)
-	return "", sp, err
+	sp, summary, err := engineapi.GenerateSpecWithOptions(ctx, project, stack, ws, true, generateOptions(params.ExecuteParams.WorkloadOnly, params.ExecuteParams, wsBackend))
+	return sp, summary, err
 }
 
 func (m *StackManager) PreviewStack(ctx context.Context, params *StackRequestParams, requestPayload request.StackImportRequest) (*models.Changes, error) {
+	_, changes, _, err := m.previewStack(ctx, params, requestPayload)
+	return changes, err
+}
+
+// PlanStack previews the stack the same way as PreviewStack, and returns the plan of the resolved spec
+// and the computed changes, which can be applied exactly by ApplyStack later with the plan ID.
+func (m *StackManager) PlanStack(ctx context.Context, params *StackRequestParams, requestPayload request.StackImportRequest) (*Plan, error) {
+	sp, changes, state, err := m.previewStack(ctx, params, requestPayload)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlan(sp, changes, state)
+}
+
+func (m *StackManager) previewStack(ctx context.Context, params *StackRequestParams, requestPayload request.StackImportRequest) (*apiv1.Spec, *models.Changes, *apiv1.State, error) {
 	logger := logutil.GetLogger(ctx)
 	runLogger := logutil.GetRunLogger(ctx)
 	logutil.LogToAll(logger, runLogger, "Info", "Starting previewing stack in StackManager...")
 
 	err := validateExecuteRequestParams(params)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	// Get the stack entity by id
 	stackEntity, err := m.stackRepo.Get(ctx, params.StackID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrGettingNonExistingStack
+			return nil, nil, nil, ErrGettingNonExistingStack
 		}
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	defer func() {
@@ -146,42 +163,42 @@ func (m *StackManager) PreviewStack(ctx context.Context, params *StackRequestPar
 	// To override this, pass in force == true
 	if stackEntity.StackInOperation() && !params.ExecuteParams.Force {
 		err = ErrStackInOperation
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	// Set stack sync state to previewing
 	stackEntity.SyncState = constant.StackStatePreviewing
 	err = m.stackRepo.Update(ctx, stackEntity)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	var sp *apiv1.Spec
 	executeOptions := BuildOptions(false, m.maxConcurrent)
 	project, stack, stateBackend, err := m.getStackProjectAndBackend(ctx, stackEntity, params.Workspace)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	// Get workspace configurations from backend
 	wsStorage, err := stateBackend.WorkspaceStorage()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	ws, err := wsStorage.Get(params.Workspace)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	releasePath := getReleasePath(constant.DefaultReleaseNamespace, stackEntity.Project.Source.Name, stackEntity.Project.Path, ws.Name)
 	releaseStorage, err := stateBackend.StateStorageWithPath(releasePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	logutil.LogToAll(logger, runLogger, "Info", "State storage found with path", "releasePath", releasePath)
 
 	directory, workDir, err := m.GetWorkdirAndDirectory(ctx, params, stackEntity)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	stack.Path = workDir
 
@@ -193,9 +210,9 @@ func (m *StackManager) PreviewStack(ctx context.Context, params *StackRequestPar
 	}()
 
 	// Generate spec using default generator
-	sp, err = engineapi.GenerateSpecWithSpinner(project, stack, ws, true)
+	sp, _, err = engineapi.GenerateSpecWithOptions(ctx, project, stack, ws, true, generateOptions(false, params.ExecuteParams, stateBackend))
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	// Treat nil spec as empty and continue with diff
@@ -210,7 +227,7 @@ func (m *StackManager) PreviewStack(ctx context.Context, params *StackRequestPar
 	// Preview
 	state, err := release.GetLatestState(releaseStorage)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	if state == nil {
 		state = &apiv1.State{}
@@ -228,8 +245,11 @@ func (m *StackManager) PreviewStack(ctx context.Context, params *StackRequestPar
 	}
 	logutil.LogToAll(logger, runLogger, "Info", "Final Spec is: ", "spec", sp)
 
+	// Ignore the fields configured in the workspace, so that they are not previewed as changes
+	executeOptions.IgnoreFields = workspace.GetPreviewIgnoreFields(ws)
+
 	changes, err := engineapi.Preview(executeOptions, releaseStorage, sp, state, project, stack)
-	return changes, err
+	return sp, changes, state, err
 }
 
 func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParams, requestPayload request.StackImportRequest) error {
@@ -361,25 +381,48 @@ func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParam
 	}
 	executeOptions := BuildOptions(params.ExecuteParams.Dryrun, m.maxConcurrent)
 
-	logutil.LogToAll(logger, runLogger, "Info", "Previewing using the default generator ...")
-
-	directory, workDir, err := m.GetWorkdirAndDirectory(ctx, params, stackEntity)
-	if err != nil {
+	// The spec and the changes of a plan are applied exactly, thus the targets are not applicable
+	if (params.ExecuteParams.plan != nil || params.ExecuteParams.PlanID != 0) && len(params.ExecuteParams.Targets) > 0 {
+		err = ErrApplyTargetsWithPlan
 		return err
 	}
-	stack.Path = workDir
 
-	// Cleanup
-	defer func() {
-		if params.ExecuteParams.NoCache {
-			sourceapi.Cleanup(ctx, directory)
+	var plan *Plan
+	if params.ExecuteParams.plan != nil {
+		// Apply exactly the plan just previewed in the same run
+		logutil.LogToAll(logger, runLogger, "Info", "Applying the plan previewed in this run", "checksum", params.ExecuteParams.plan.Checksum)
+		plan = params.ExecuteParams.plan
+		sp = plan.Spec
+	} else if params.ExecuteParams.PlanID != 0 {
+		// Apply exactly the spec and the changes of the plan without generating and previewing again
+		logutil.LogToAll(logger, runLogger, "Info", "Applying the plan of the preview run", "planID", params.ExecuteParams.PlanID)
+		plan, err = m.getPlan(ctx, params.ExecuteParams.PlanID, stackEntity.ID, params.Workspace)
+		if err != nil {
+			return err
 		}
-	}()
+		sp = plan.Spec
+	} else {
+		logutil.LogToAll(logger, runLogger, "Info", "Previewing using the default generator ...")
 
-	// Generate spec using default generator
-	sp, err = engineapi.GenerateSpecWithSpinner(project, stack, ws, true)
-	if err != nil {
-		return err
+		var directory, workDir string
+		directory, workDir, err = m.GetWorkdirAndDirectory(ctx, params, stackEntity)
+		if err != nil {
+			return err
+		}
+		stack.Path = workDir
+
+		// Cleanup
+		defer func() {
+			if params.ExecuteParams.NoCache {
+				sourceapi.Cleanup(ctx, directory)
+			}
+		}()
+
+		// Generate spec using default generator
+		sp, _, err = engineapi.GenerateSpecWithOptions(ctx, project, stack, ws, true, generateOptions(false, params.ExecuteParams, stateBackend))
+		if err != nil {
+			return err
+		}
 	}
 
 	// return immediately if no resource found in stack
@@ -389,6 +432,15 @@ func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParam
 		return nil
 	}
 
+	// Apply only the target resources and their dependencies, leaving the others unchanged
+	if len(params.ExecuteParams.Targets) > 0 {
+		logutil.LogToAll(logger, runLogger, "Info", "Applying the target resources only", "targets", params.ExecuteParams.Targets)
+		sp, err = targetSpec(sp, priorState, params.ExecuteParams.Targets)
+		if err != nil {
+			return err
+		}
+	}
+
 	// update release phase to previewing
 	rel.Spec = sp
 	release.UpdateReleasePhase(rel, apiv1.ReleasePhasePreviewing, relLock)
@@ -406,23 +458,45 @@ func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParam
 	logutil.LogToAll(logger, runLogger, "Info", "State backend found", "stateBackend", stateBackend)
 	stack.Path = tempPath(stackEntity.Path)
 
-	// Set context from workspace to spec
-	if ws != nil && len(ws.Context) > 0 {
-		sp.Context = ws.Context
-		// Set x-kusion-trace in spec context
-		sp.Context["x-kusion-trace"] = appmiddleware.GetTraceID(ctx)
-		sp.Context["x-kusion-spec-id"] = specID
-	}
+	executeOptions.IgnoreFields = workspace.GetPreviewIgnoreFields(ws)
+	if plan != nil {
+		// The changes of the plan are only valid against the state they are previewed with. Preview the plan
+		// again while the stack is being applied, in case the resources are changed after the plan is previewed
+		if err = plan.VerifyPriorState(priorState); err != nil {
+			return err
+		}
+		changes, err = engineapi.Preview(executeOptions, storage, sp, priorState, project, stack)
+		if err != nil {
+			return err
+		}
+		if err = plan.VerifyChanges(changes); err != nil {
+			return err
+		}
+		if params.ExecuteParams.PlanID != 0 {
+			if err = m.consumePlan(ctx, params.ExecuteParams.PlanID); err != nil {
+				return err
+			}
+		}
+		changes = models.NewChanges(project, stack, plan.Changes)
+	} else {
+		// Set context from workspace to spec
+		if ws != nil && len(ws.Context) > 0 {
+			sp.Context = ws.Context
+			// Set x-kusion-trace in spec context
+			sp.Context["x-kusion-trace"] = appmiddleware.GetTraceID(ctx)
+			sp.Context["x-kusion-spec-id"] = specID
+		}
 
-	// Set import details if importResources is set to true
-	if params.ExecuteParams.ImportResources && len(requestPayload.ImportedResources) > 0 {
-		m.ImportTerraformResourceID(ctx, sp, requestPayload.ImportedResources)
-	}
+		// Set import details if importResources is set to true
+		if params.ExecuteParams.ImportResources && len(requestPayload.ImportedResources) > 0 {
+			m.ImportTerraformResourceID(ctx, sp, requestPayload.ImportedResources)
+		}
 
-	// Calculate change steps
-	changes, err = engineapi.Preview(executeOptions, storage, sp, priorState, project, stack)
-	if err != nil {
-		return err
+		// Calculate change steps
+		changes, err = engineapi.Preview(executeOptions, storage, sp, priorState, project, stack)
+		if err != nil {
+			return err
+		}
 	}
 
 	logutil.LogToAll(logger, runLogger, "Info", "Start applying diffs ...")
@@ -434,6 +508,12 @@ func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParam
 	executeOptions = BuildOptions(params.ExecuteParams.Dryrun, m.maxConcurrent)
 	executeOptions.Watch = params.ExecuteParams.Watch
 	executeOptions.WatchTimeout = params.ExecuteParams.WatchTimeoutSeconds
+	// Pause the apply run after the resources with the apply-pause extension until it is approved,
+	// and publish the apply progress of the resources to the subscribers of the run
+	if params.RunID != 0 {
+		executeOptions.PauseHook = m.newPauseHook(ctx, params.RunID)
+		executeOptions.ProgressHook = m.newProgressHook(params.RunID)
+	}
 
 	// Get graph storage directory, create if not exist
 	graphStorage, err := stackBackend.GraphStorage(project.Name, ws.Name)
@@ -466,6 +546,10 @@ func (m *StackManager) ApplyStack(ctx context.Context, params *StackRequestParam
 		return err
 	}
 
+	// Do not start applying the resources once the run is cancelled
+	if err = ctx.Err(); err != nil {
+		return err
+	}
 	var upRel *apiv1.Release
 	if upRel, err = engineapi.Apply(ctx, executeOptions, storage, rel, gph, changes, os.Stdout); err != nil {
 		return err
@@ -600,6 +684,10 @@ func (m *StackManager) DestroyStack(ctx context.Context, params *StackRequestPar
 	if err = release.UpdateDestroyRelease(storage, rel); err != nil {
 		return err
 	}
+	// Do not start destroying the resources once the run is cancelled
+	if err = ctx.Err(); err != nil {
+		return err
+	}
 	// Destroy
 	logutil.LogToAll(logger, runLogger, "Info", "Start destroying resources......")
 	var upRel *apiv1.Release
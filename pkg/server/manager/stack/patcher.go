@@ -0,0 +1,67 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/domain/response"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
+	logutil "kusionstack.io/kusion/pkg/server/util/logging"
+)
+
+// DryRunPatcher applies the patcher to the spec the same way as the generation without applying the spec,
+// and returns the patched resources along with the diffs and the warnings. The spec is either the result
+// of a succeeded generate run or given inline.
+func (m *StackManager) DryRunPatcher(ctx context.Context, requestPayload request.PatcherDryRunRequest) (*response.PatcherDryRunResponse, error) {
+	logger := logutil.GetLogger(ctx)
+
+	if (requestPayload.RunID == 0) == (requestPayload.Spec == nil) {
+		return nil, ErrPatcherDryRunSpecSource
+	}
+	sp := requestPayload.Spec
+	if sp == nil {
+		var err error
+		if sp, err = m.getGeneratedSpec(ctx, requestPayload.RunID); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("Dry running patcher...", "resources", len(sp.Resources))
+	result, err := appconfiguration.PatchDryRun(sp.Resources, &requestPayload.Patcher)
+	if err != nil {
+		return nil, err
+	}
+	return &response.PatcherDryRunResponse{
+		Resources: result.Resources,
+		Diffs:     result.Diffs,
+		Warnings:  result.Warnings,
+	}, nil
+}
+
+// getGeneratedSpec returns the spec generated by the succeeded generate run, which is stored as the run
+// result in the form of the JSON-encoded YAML.
+func (m *StackManager) getGeneratedSpec(ctx context.Context, runID uint) (*v1.Spec, error) {
+	runEntity, err := m.GetRunByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if runEntity.Type != constant.RunTypeGenerate || runEntity.Status != constant.RunStatusSucceeded {
+		return nil, ErrRunHasNoSpec
+	}
+
+	var specYAML string
+	if err = json.Unmarshal([]byte(runEntity.Result), &specYAML); err != nil {
+		return nil, fmt.Errorf("failed to decode the result of run %d: %w", runID, err)
+	}
+	sp := &v1.Spec{}
+	if err = yaml.Unmarshal([]byte(specYAML), sp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the spec of run %d: %w", runID, err)
+	}
+	return sp, nil
+}
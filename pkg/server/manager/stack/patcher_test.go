@@ -0,0 +1,87 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
+)
+
+func TestStackManager_DryRunPatcher(t *testing.T) {
+	ctx := context.TODO()
+	patcher := v1.Patcher{
+		JSONPatchers: map[string]v1.JSONPatcher{
+			"v1:ConfigMap:default:foo": {Type: v1.MergePatch, Payload: []byte(`{"data": {"key": "new"}}`)},
+		},
+	}
+	specYAML := `resources:
+- id: v1:ConfigMap:default:foo
+  type: Kubernetes
+  attributes:
+    apiVersion: v1
+    kind: ConfigMap
+    data:
+      key: old
+`
+
+	t.Run("inline spec", func(t *testing.T) {
+		m := &StackManager{}
+		result, err := m.DryRunPatcher(ctx, request.PatcherDryRunRequest{
+			Spec: &v1.Spec{Resources: v1.Resources{{
+				ID:         "v1:ConfigMap:default:foo",
+				Type:       v1.Kubernetes,
+				Attributes: map[string]interface{}{"data": map[string]interface{}{"key": "old"}},
+			}}},
+			Patcher: patcher,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"key": "new"}, result.Resources[0].Attributes["data"])
+		assert.Contains(t, result.Diffs["v1:ConfigMap:default:foo"], "+    key: new")
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("spec of generate run", func(t *testing.T) {
+		runResult, _ := json.Marshal(specYAML)
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{
+			ID:     1,
+			Type:   constant.RunTypeGenerate,
+			Status: constant.RunStatusSucceeded,
+			Result: string(runResult),
+		}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		result, err := m.DryRunPatcher(ctx, request.PatcherDryRunRequest{RunID: 1, Patcher: patcher})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"key": "new"}, result.Resources[0].Attributes["data"])
+		assert.Contains(t, result.Diffs["v1:ConfigMap:default:foo"], "-    key: old")
+	})
+
+	t.Run("run without spec", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{
+			ID:     1,
+			Type:   constant.RunTypeApply,
+			Status: constant.RunStatusSucceeded,
+		}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		_, err := m.DryRunPatcher(ctx, request.PatcherDryRunRequest{RunID: 1, Patcher: patcher})
+		assert.ErrorIs(t, err, ErrRunHasNoSpec)
+	})
+
+	t.Run("ambiguous spec source", func(t *testing.T) {
+		m := &StackManager{}
+		_, err := m.DryRunPatcher(ctx, request.PatcherDryRunRequest{Patcher: patcher})
+		assert.ErrorIs(t, err, ErrPatcherDryRunSpecSource)
+
+		_, err = m.DryRunPatcher(ctx, request.PatcherDryRunRequest{RunID: 1, Spec: &v1.Spec{}, Patcher: patcher})
+		assert.ErrorIs(t, err, ErrPatcherDryRunSpecSource)
+	})
+}
@@ -0,0 +1,210 @@
+package stack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+// Plan is the serialized artifact of a preview, which is applied exactly as it is by an apply run
+// referring to the preview run by the plan ID, instead of generating and previewing the stack again.
+type Plan struct {
+	// Spec is the resolved spec the changes are computed from.
+	Spec *v1.Spec `json:"spec"`
+	// Changes are the computed change steps in their order.
+	Changes *models.ChangeOrder `json:"changes"`
+	// PriorStateHash is the SHA-256 checksum of the resources in the prior state the changes are computed
+	// against, which must still match the latest state when the plan is applied.
+	PriorStateHash string `json:"priorStateHash"`
+	// Checksum is the SHA-256 checksum of the spec, the changes and the prior state hash.
+	Checksum string `json:"checksum"`
+}
+
+// NewPlan returns the plan of the spec and the changes computed from it against the prior state with the
+// checksum set.
+func NewPlan(sp *v1.Spec, changes *models.Changes, priorState *v1.State) (*Plan, error) {
+	plan := &Plan{Spec: sp}
+	if changes != nil {
+		plan.Changes = changes.ChangeOrder
+	}
+	priorStateHash, err := hashState(priorState)
+	if err != nil {
+		return nil, err
+	}
+	plan.PriorStateHash = priorStateHash
+	checksum, err := plan.computeChecksum()
+	if err != nil {
+		return nil, err
+	}
+	plan.Checksum = checksum
+	return plan, nil
+}
+
+// Verify checks the checksum of the plan still matches its spec and changes.
+func (p *Plan) Verify() error {
+	checksum, err := p.computeChecksum()
+	if err != nil {
+		return err
+	}
+	if checksum != p.Checksum {
+		return ErrPlanChecksumMismatch
+	}
+	return nil
+}
+
+// VerifyPriorState checks the latest state is still the prior state the changes of the plan are computed
+// against, i.e. no other apply has changed the resources since the plan was previewed.
+func (p *Plan) VerifyPriorState(latestState *v1.State) error {
+	latestStateHash, err := hashState(latestState)
+	if err != nil {
+		return err
+	}
+	if latestStateHash != p.PriorStateHash {
+		return ErrPlanStateChanged
+	}
+	return nil
+}
+
+// VerifyChanges checks the changes previewed again right before the plan is applied are still the changes
+// of the plan, regardless of the order in which the resources are previewed. Otherwise, the resources have
+// been changed since the plan was previewed, and the plan is aborted with ErrPlanChecksumConflict.
+func (p *Plan) VerifyChanges(changes *models.Changes) error {
+	expected, err := canonicalChecksum(changeSteps(p.Changes))
+	if err != nil {
+		return err
+	}
+	var order *models.ChangeOrder
+	if changes != nil {
+		order = changes.ChangeOrder
+	}
+	actual, err := canonicalChecksum(changeSteps(order))
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: the resources have changed since the plan was previewed", ErrPlanChecksumConflict)
+	}
+	return nil
+}
+
+// changeSteps returns the change steps of the change order indexed by the resource ID, and the nil change
+// order is treated as empty.
+func changeSteps(order *models.ChangeOrder) map[string]*models.ChangeStep {
+	if order == nil || order.ChangeSteps == nil {
+		return map[string]*models.ChangeStep{}
+	}
+	return order.ChangeSteps
+}
+
+// computeChecksum computes the checksum of the spec, the changes and the prior state hash.
+func (p *Plan) computeChecksum() (string, error) {
+	return canonicalChecksum(struct {
+		Spec           *v1.Spec            `json:"spec"`
+		Changes        *models.ChangeOrder `json:"changes"`
+		PriorStateHash string              `json:"priorStateHash"`
+	}{p.Spec, p.Changes, p.PriorStateHash})
+}
+
+// hashState computes the checksum of the resources in the state, and the nil state is treated as empty.
+func hashState(state *v1.State) (string, error) {
+	var resources v1.Resources
+	if state != nil {
+		resources = state.Resources
+	}
+	if len(resources) == 0 {
+		resources = v1.Resources{}
+	}
+	return canonicalChecksum(resources)
+}
+
+// canonicalChecksum computes the checksum of the canonical JSON of the value, i.e. the JSON re-encoded
+// from its generic form with the keys sorted, so that the value decoded from the run result has the same
+// checksum as it is computed.
+func canonicalChecksum(v interface{}) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the plan: %w", err)
+	}
+	var generic interface{}
+	if err = json.Unmarshal(out, &generic); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the plan: %w", err)
+	}
+	if out, err = json.Marshal(generic); err != nil {
+		return "", fmt.Errorf("failed to marshal the plan: %w", err)
+	}
+	sum := sha256.Sum256(out)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getPlan returns the plan stored as the result of the succeeded preview run with the plan ID, which
+// must be a plan of the same stack and workspace not consumed yet. The checksum of the plan is verified
+// before returned.
+func (m *StackManager) getPlan(ctx context.Context, planID uint, stackID uint, workspace string) (*Plan, error) {
+	runEntity, err := m.GetRunByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if runEntity.Type != constant.RunTypePreview || runEntity.Status != constant.RunStatusSucceeded {
+		return nil, ErrRunHasNoPlan
+	}
+	if runEntity.Stack == nil || runEntity.Stack.ID != stackID || runEntity.Workspace != workspace {
+		return nil, ErrPlanStackMismatch
+	}
+	if runEntity.PlanConsumed {
+		return nil, ErrPlanConsumed
+	}
+
+	plan := &Plan{}
+	if err = json.Unmarshal([]byte(runEntity.Result), plan); err != nil {
+		return nil, fmt.Errorf("failed to decode the plan of run %d: %w", planID, err)
+	}
+	if plan.Checksum == "" || plan.Spec == nil || plan.PriorStateHash == "" {
+		return nil, ErrRunHasNoPlan
+	}
+	if err = plan.Verify(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// consumePlan marks the plan of the preview run with the plan ID as consumed right before it is applied,
+// which fails with ErrPlanConsumed if the plan has been applied by another run.
+func (m *StackManager) consumePlan(ctx context.Context, planID uint) error {
+	consumed, err := m.runRepo.ConsumePlan(ctx, planID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrPlanConsumed
+	}
+	return nil
+}
+
+// PreviewAndApplyStack previews the stack and applies the previewed plan exactly in the same run, only if the
+// checksum of the plan matches the expected checksum, i.e. the changes are the same as those previewed before.
+// Otherwise, it aborts with ErrPlanChecksumConflict without applying anything. As the stack is released between
+// the preview and the apply, ApplyStack previews the plan again and aborts the same way if the changes differ.
+func (m *StackManager) PreviewAndApplyStack(ctx context.Context, params *StackRequestParams, requestPayload request.StackImportRequest) error {
+	if params.ExecuteParams.ExpectedChecksum == "" {
+		return ErrExpectedChecksumEmpty
+	}
+	if len(params.ExecuteParams.Targets) > 0 {
+		return ErrApplyTargetsWithPlan
+	}
+	plan, err := m.PlanStack(ctx, params, requestPayload)
+	if err != nil {
+		return err
+	}
+	if plan.Checksum != params.ExecuteParams.ExpectedChecksum {
+		return fmt.Errorf("%w: expected %s, got %s", ErrPlanChecksumConflict, params.ExecuteParams.ExpectedChecksum, plan.Checksum)
+	}
+	params.ExecuteParams.plan = plan
+	return m.ApplyStack(ctx, params, requestPayload)
+}
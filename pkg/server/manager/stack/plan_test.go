@@ -0,0 +1,188 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+func mockPlan(t *testing.T) *Plan {
+	sp := &v1.Spec{Resources: v1.Resources{{
+		ID:         "v1:ConfigMap:default:foo",
+		Type:       v1.Kubernetes,
+		Attributes: map[string]interface{}{"data": map[string]interface{}{"key": "new"}},
+	}}}
+	changes := models.NewChanges(nil, nil, &models.ChangeOrder{
+		StepKeys: []string{"v1:ConfigMap:default:foo"},
+		ChangeSteps: map[string]*models.ChangeStep{
+			"v1:ConfigMap:default:foo": {ID: "v1:ConfigMap:default:foo", Action: models.Create, To: &sp.Resources[0]},
+		},
+	})
+	plan, err := NewPlan(sp, changes, &v1.State{Resources: v1.Resources{{
+		ID:         "v1:ConfigMap:default:bar",
+		Type:       v1.Kubernetes,
+		Attributes: map[string]interface{}{"data": map[string]interface{}{"key": "old"}},
+	}}})
+	assert.NoError(t, err)
+	return plan
+}
+
+func TestPlan_Verify(t *testing.T) {
+	plan := mockPlan(t)
+	assert.NotEmpty(t, plan.Checksum)
+	assert.NoError(t, plan.Verify())
+
+	plan.Spec.Resources[0].Attributes["data"] = map[string]interface{}{"key": "tampered"}
+	assert.ErrorIs(t, plan.Verify(), ErrPlanChecksumMismatch)
+
+	plan = mockPlan(t)
+	plan.PriorStateHash = "tampered"
+	assert.ErrorIs(t, plan.Verify(), ErrPlanChecksumMismatch)
+}
+
+func TestPlan_VerifyPriorState(t *testing.T) {
+	plan := mockPlan(t)
+	assert.NoError(t, plan.VerifyPriorState(&v1.State{ID: 2, Resources: v1.Resources{{
+		ID:         "v1:ConfigMap:default:bar",
+		Type:       v1.Kubernetes,
+		Attributes: map[string]interface{}{"data": map[string]interface{}{"key": "old"}},
+	}}}))
+	assert.ErrorIs(t, plan.VerifyPriorState(&v1.State{Resources: v1.Resources{{
+		ID:         "v1:ConfigMap:default:bar",
+		Type:       v1.Kubernetes,
+		Attributes: map[string]interface{}{"data": map[string]interface{}{"key": "drifted"}},
+	}}}), ErrPlanStateChanged)
+	assert.ErrorIs(t, plan.VerifyPriorState(nil), ErrPlanStateChanged)
+
+	// The nil state is the same as the empty state
+	emptyPlan, err := NewPlan(&v1.Spec{}, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, emptyPlan.VerifyPriorState(&v1.State{}))
+}
+
+func TestPlan_VerifyChanges(t *testing.T) {
+	plan := mockPlan(t)
+	resource := plan.Spec.Resources[0]
+	assert.NoError(t, plan.VerifyChanges(models.NewChanges(nil, nil, &models.ChangeOrder{
+		StepKeys: []string{"v1:ConfigMap:default:foo"},
+		ChangeSteps: map[string]*models.ChangeStep{
+			"v1:ConfigMap:default:foo": {ID: "v1:ConfigMap:default:foo", Action: models.Create, To: &resource},
+		},
+	})))
+
+	// The resource created by another apply after the plan was previewed
+	err := plan.VerifyChanges(models.NewChanges(nil, nil, &models.ChangeOrder{
+		StepKeys: []string{"v1:ConfigMap:default:foo"},
+		ChangeSteps: map[string]*models.ChangeStep{
+			"v1:ConfigMap:default:foo": {ID: "v1:ConfigMap:default:foo", Action: models.Update, From: &resource, To: &resource},
+		},
+	}))
+	assert.ErrorIs(t, err, ErrPlanChecksumConflict)
+	assert.ErrorIs(t, plan.VerifyChanges(nil), ErrPlanChecksumConflict)
+}
+
+func TestStackManager_getPlan(t *testing.T) {
+	ctx := context.TODO()
+	mockRunEntity := func(runType constant.RunType, result string) *entity.Run {
+		return &entity.Run{
+			ID:        1,
+			Type:      runType,
+			Status:    constant.RunStatusSucceeded,
+			Stack:     &entity.Stack{ID: 2},
+			Workspace: "dev",
+			Result:    result,
+		}
+	}
+	mockRun := func(runType constant.RunType, result string) *mockRunRepository {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(mockRunEntity(runType, result), nil)
+		return mockRepo
+	}
+	planResult, _ := json.Marshal(mockPlan(t))
+
+	t.Run("plan of preview run", func(t *testing.T) {
+		m := &StackManager{runRepo: mockRun(constant.RunTypePreview, string(planResult))}
+		plan, err := m.getPlan(ctx, 1, 2, "dev")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1:ConfigMap:default:foo", plan.Spec.Resources[0].ID)
+		assert.Equal(t, models.Create, plan.Changes.Get("v1:ConfigMap:default:foo").Action)
+	})
+
+	t.Run("run without plan", func(t *testing.T) {
+		m := &StackManager{runRepo: mockRun(constant.RunTypeApply, "")}
+		_, err := m.getPlan(ctx, 1, 2, "dev")
+		assert.ErrorIs(t, err, ErrRunHasNoPlan)
+
+		m = &StackManager{runRepo: mockRun(constant.RunTypePreview, `{"stepKeys": []}`)}
+		_, err = m.getPlan(ctx, 1, 2, "dev")
+		assert.ErrorIs(t, err, ErrRunHasNoPlan)
+	})
+
+	t.Run("plan of another stack", func(t *testing.T) {
+		m := &StackManager{runRepo: mockRun(constant.RunTypePreview, string(planResult))}
+		_, err := m.getPlan(ctx, 1, 3, "dev")
+		assert.ErrorIs(t, err, ErrPlanStackMismatch)
+
+		_, err = m.getPlan(ctx, 1, 2, "prod")
+		assert.ErrorIs(t, err, ErrPlanStackMismatch)
+
+		runEntity := mockRunEntity(constant.RunTypePreview, string(planResult))
+		runEntity.Stack = nil
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(runEntity, nil)
+		m = &StackManager{runRepo: mockRepo}
+		_, err = m.getPlan(ctx, 1, 2, "dev")
+		assert.ErrorIs(t, err, ErrPlanStackMismatch)
+	})
+
+	t.Run("consumed plan", func(t *testing.T) {
+		runEntity := mockRunEntity(constant.RunTypePreview, string(planResult))
+		runEntity.PlanConsumed = true
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(runEntity, nil)
+		m := &StackManager{runRepo: mockRepo}
+		_, err := m.getPlan(ctx, 1, 2, "dev")
+		assert.ErrorIs(t, err, ErrPlanConsumed)
+	})
+
+	t.Run("tampered plan", func(t *testing.T) {
+		plan := mockPlan(t)
+		plan.Checksum = "tampered"
+		result, _ := json.Marshal(plan)
+		m := &StackManager{runRepo: mockRun(constant.RunTypePreview, string(result))}
+		_, err := m.getPlan(ctx, 1, 2, "dev")
+		assert.ErrorIs(t, err, ErrPlanChecksumMismatch)
+	})
+}
+
+func TestStackManager_consumePlan(t *testing.T) {
+	ctx := context.TODO()
+	mockRepo := &mockRunRepository{}
+	mockRepo.On("ConsumePlan", ctx, uint(1)).Return(true, nil).Once()
+	mockRepo.On("ConsumePlan", ctx, uint(1)).Return(false, nil).Once()
+	m := &StackManager{runRepo: mockRepo}
+
+	assert.NoError(t, m.consumePlan(ctx, 1))
+	// The plan cannot be applied twice
+	assert.ErrorIs(t, m.consumePlan(ctx, 1), ErrPlanConsumed)
+}
+
+func TestStackManager_PreviewAndApplyStack_Targets(t *testing.T) {
+	m := &StackManager{}
+	params := &StackRequestParams{ExecuteParams: StackExecuteParams{
+		ExpectedChecksum: "checksum",
+		Targets:          []string{"v1:ConfigMap:default:foo"},
+	}}
+
+	// The targets are rejected before previewing, as the plan is applied exactly
+	err := m.PreviewAndApplyStack(context.TODO(), params, request.StackImportRequest{})
+	assert.ErrorIs(t, err, ErrApplyTargetsWithPlan)
+}
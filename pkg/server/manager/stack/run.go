@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
@@ -137,5 +139,225 @@ func (m *StackManager) UpdateRunResultAndStatusByID(ctx context.Context, id uint
 	if err != nil {
 		return nil, err
 	}
+	if requestPayload.Status != "" {
+		m.PublishRunStatus(id, updatedEntity.Status)
+	}
 	return updatedEntity, nil
 }
+
+// pausedRun is the apply run paused after applying the resources with the apply-pause extension. The pauses of
+// the run are guarded by StackManager.pauseMu.
+type pausedRun struct {
+	// resourceIDs are the resources pending approval, which are all continued by one approval
+	resourceIDs []string
+	// continueCh is closed when the run is approved to continue
+	continueCh chan struct{}
+}
+
+// removeResource removes the resource no longer pending approval, and returns the number of the pending ones.
+func (p *pausedRun) removeResource(resourceID string) int {
+	for i, id := range p.resourceIDs {
+		if id == resourceID {
+			p.resourceIDs = append(p.resourceIDs[:i], p.resourceIDs[i+1:]...)
+			break
+		}
+	}
+	return len(p.resourceIDs)
+}
+
+// newPauseHook returns the pause hook of the apply run, which blocks until the run is continued by ContinueRun.
+// The resources paused concurrently in the same run share one approval, and only the first of them sets the
+// run from InProgress to WaitingApproval. An error is returned if the run times out while waiting.
+func (m *StackManager) newPauseHook(ctx context.Context, runID uint) func(resourceID string) error {
+	return func(resourceID string) error {
+		logger := logutil.GetLogger(ctx)
+		runLogger := logutil.GetRunLogger(ctx)
+		logutil.LogToAll(logger, runLogger, "Info", fmt.Sprintf("Apply paused after resource %s, waiting for approval ...", resourceID))
+
+		m.pauseMu.Lock()
+		value, loaded := m.pausedRuns.LoadOrStore(runID, &pausedRun{continueCh: make(chan struct{})})
+		paused := value.(*pausedRun)
+		paused.resourceIDs = append(paused.resourceIDs, resourceID)
+		if !loaded {
+			if err := m.transitRunStatus(ctx, runID, constant.RunStatusInProgress, constant.RunStatusWaitingApproval); err != nil {
+				m.pausedRuns.Delete(runID)
+				m.pauseMu.Unlock()
+				return err
+			}
+		}
+		m.pauseMu.Unlock()
+
+		select {
+		case <-paused.continueCh:
+			logutil.LogToAll(logger, runLogger, "Info", fmt.Sprintf("Apply continued after resource %s", resourceID))
+			return nil
+		case <-ctx.Done():
+			m.pauseMu.Lock()
+			if paused.removeResource(resourceID) == 0 {
+				m.pausedRuns.CompareAndDelete(runID, paused)
+			}
+			m.pauseMu.Unlock()
+			return fmt.Errorf("run %d timed out while waiting for approval: %w", runID, ctx.Err())
+		}
+	}
+}
+
+// transitRunStatus sets the run from the expected status to the new status and publishes it, which fails if the
+// status of the run has been changed by others.
+func (m *StackManager) transitRunStatus(ctx context.Context, runID uint, from, to constant.RunStatus) error {
+	updated, err := m.runRepo.CompareAndUpdate(ctx, &entity.Run{ID: runID, Status: to}, from)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("%w: run %d is no longer %s", ErrRunStatusChanged, runID, from)
+	}
+	m.PublishRunStatus(runID, to)
+	return nil
+}
+
+// ContinueRun continues the apply run waiting for approval, and records the approver for audit.
+func (m *StackManager) ContinueRun(ctx context.Context, runID uint, approver string) (*entity.Run, error) {
+	logger := logutil.GetLogger(ctx)
+
+	runEntity, err := m.GetRunByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	switch runEntity.Status {
+	case constant.RunStatusWaitingApproval:
+	case constant.RunStatusCancelled, constant.RunStatusFailed:
+		// The run waiting for approval is cancelled or fails when it times out
+		return nil, ErrRunTimedOutWaitingApproval
+	default:
+		return nil, ErrRunNotWaitingApproval
+	}
+
+	m.pauseMu.Lock()
+	defer m.pauseMu.Unlock()
+	value, ok := m.pausedRuns.Load(runID)
+	if !ok {
+		// The run is still marked as waiting, but it has stopped waiting or the server has restarted
+		return nil, ErrRunTimedOutWaitingApproval
+	}
+	paused := value.(*pausedRun)
+
+	// Record the approval along with leaving WaitingApproval, which fails if the run has stopped waiting
+	approval := &entity.RunApproval{
+		Approver:          approver,
+		ResourceIDs:       append([]string(nil), paused.resourceIDs...),
+		ApprovalTimestamp: time.Now(),
+	}
+	runEntity.Approvals = append(runEntity.Approvals, approval)
+	runEntity.Status = constant.RunStatusInProgress
+	updated, err := m.runRepo.CompareAndUpdate(ctx, &entity.Run{
+		ID:        runID,
+		Status:    runEntity.Status,
+		Approvals: runEntity.Approvals,
+	}, constant.RunStatusWaitingApproval)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrRunTimedOutWaitingApproval
+	}
+	m.PublishRunStatus(runID, runEntity.Status)
+
+	logger.Info("Continuing the paused run", "runID", runID, "approver", approver, "resources", approval.ResourceIDs)
+	m.pausedRuns.Delete(runID)
+	close(paused.continueCh)
+	return runEntity, nil
+}
+
+// inFlightRun is the async run picked up or to be picked up by a worker, which can be cancelled by CancelRun.
+type inFlightRun struct {
+	mu sync.Mutex
+	// cancel cancels the context of the run, it is nil until the run is picked up by a worker
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+func (r *inFlightRun) setCancel(cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancel = cancel
+	// The run is cancelled while it is queued
+	if r.cancelled {
+		cancel()
+	}
+}
+
+func (r *inFlightRun) cancelRun() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelled = true
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// QueueRun makes the async run cancellable by CancelRun before it is picked up by a worker, which must be called
+// before the run is queued. The run is tracked until it exits after picked up, see TrackRun.
+func (m *StackManager) QueueRun(runID uint) {
+	m.inFlightRuns.LoadOrStore(runID, &inFlightRun{})
+}
+
+// TrackRun makes the async run cancellable by CancelRun with the cancel function of its context, and returns the
+// function to stop tracking the run when the run exits. The context is cancelled right away if the run has been
+// cancelled while it is queued.
+func (m *StackManager) TrackRun(runID uint, cancel context.CancelFunc) func() {
+	value, _ := m.inFlightRuns.LoadOrStore(runID, &inFlightRun{})
+	run := value.(*inFlightRun)
+	run.setCancel(cancel)
+	return func() {
+		m.inFlightRuns.CompareAndDelete(runID, run)
+	}
+}
+
+// CancelRun sets the run to Cancelled on condition that its status is unchanged since it is read, and cancels the
+// in-flight async run by cancelling its context. The queued run is cancelled as soon as it is picked up by a worker.
+// The terminal status of the in-flight run is published by its worker on exiting, along with its logs.
+func (m *StackManager) CancelRun(ctx context.Context, runID uint) (*entity.Run, error) {
+	logger := logutil.GetLogger(ctx)
+
+	runEntity, err := m.GetRunByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	switch runEntity.Status {
+	case constant.RunStatusSucceeded, constant.RunStatusFailed, constant.RunStatusCancelled:
+		return nil, ErrRunAlreadyCompleted
+	}
+
+	value, inFlight := m.inFlightRuns.Load(runID)
+	if !inFlight && runEntity.Status != constant.RunStatusQueued {
+		// The run is neither an async run nor started by this server
+		return nil, ErrRunNotInFlight
+	}
+
+	// Set the run to Cancelled before cancelling it, which loses to the worker completing the run in the meantime
+	updated, err := m.runRepo.CompareAndUpdate(ctx, &entity.Run{
+		ID:     runID,
+		Status: constant.RunStatusCancelled,
+		Result: constant.RunResultCancelled,
+	}, runEntity.Status)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrRunAlreadyCompleted
+	}
+	runEntity.Status = constant.RunStatusCancelled
+	runEntity.Result = constant.RunResultCancelled
+
+	if inFlight {
+		logger.Info("Cancelling the run", "runID", runID)
+		value.(*inFlightRun).cancelRun()
+	} else {
+		// The run was queued by another server or before the server restarted, and will never be picked up, so
+		// there is no worker to publish its terminal status
+		logger.Info("Cancelling the run which will never be picked up", "runID", runID)
+		m.PublishRunStatus(runID, runEntity.Status)
+	}
+	return runEntity, nil
+}
@@ -0,0 +1,147 @@
+package stack
+
+import (
+	"sync"
+	"time"
+
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+// runEventBufferSize is the number of events buffered for every subscriber of a run
+const runEventBufferSize = 100
+
+type RunEventType string
+
+const (
+	// RunEventStatus is published when the status of the run changes
+	RunEventStatus RunEventType = "status"
+	// RunEventResource is published when a resource of the apply run starts or finishes applying
+	RunEventResource RunEventType = "resource"
+)
+
+// RunEvent is the progress event of an async run streamed to the subscribers of the run.
+type RunEvent struct {
+	Type   RunEventType       `json:"type"`
+	RunID  uint               `json:"runID"`
+	Status constant.RunStatus `json:"status,omitempty"`
+	// ResourceID and Result are only set for the resource events, and the Result is empty while applying
+	ResourceID string          `json:"resourceID,omitempty"`
+	Result     models.OpResult `json:"result,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+// IsTerminal returns true if the event reports the run has completed, after which no more events are published.
+func (e RunEvent) IsTerminal() bool {
+	if e.Type != RunEventStatus {
+		return false
+	}
+	switch e.Status {
+	case constant.RunStatusSucceeded, constant.RunStatusFailed, constant.RunStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// runEventHub fans out the events of the async runs to their subscribers. The zero value is ready to use.
+type runEventHub struct {
+	mu sync.Mutex
+	// subscribers maps the run ID to the channels of its subscribers
+	subscribers map[uint]map[chan RunEvent]struct{}
+}
+
+// subscribe returns the channel receiving the events of the run published from now on, and the function to
+// cancel the subscription. The channel is closed after the terminal event of the run is published.
+func (h *runEventHub) subscribe(runID uint) (<-chan RunEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[uint]map[chan RunEvent]struct{})
+	}
+	if h.subscribers[runID] == nil {
+		h.subscribers[runID] = make(map[chan RunEvent]struct{})
+	}
+	ch := make(chan RunEvent, runEventBufferSize)
+	h.subscribers[runID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[runID][ch]; ok {
+			delete(h.subscribers[runID], ch)
+			close(ch)
+			if len(h.subscribers[runID]) == 0 {
+				delete(h.subscribers, runID)
+			}
+		}
+	}
+}
+
+// publish sends the event to the subscribers of the run without blocking the run, the event is dropped for
+// the subscribers not keeping up, except the terminal event which takes the place of the oldest buffered event.
+// The subscriptions of the run are closed on the terminal event.
+func (h *runEventHub) publish(event RunEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[event.RunID] {
+		select {
+		case ch <- event:
+		default:
+			if event.IsTerminal() {
+				// The subscriber must know the run has completed. The buffer always has room after the oldest
+				// event is dropped, as the events are only sent with the lock held
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- event
+			}
+		}
+		if event.IsTerminal() {
+			close(ch)
+		}
+	}
+	if event.IsTerminal() {
+		delete(h.subscribers, event.RunID)
+	}
+}
+
+// SubscribeRunEvents subscribes to the progress events of the async run, see runEventHub.subscribe.
+func (m *StackManager) SubscribeRunEvents(runID uint) (<-chan RunEvent, func()) {
+	return m.runEvents.subscribe(runID)
+}
+
+// PublishRunStatus publishes the status of the run to its subscribers without persisting it.
+func (m *StackManager) PublishRunStatus(runID uint, status constant.RunStatus) {
+	m.runEvents.publish(RunEvent{
+		Type:   RunEventStatus,
+		RunID:  runID,
+		Status: status,
+	})
+}
+
+// newProgressHook returns the progress hook of the apply run publishing the result of every resource, and
+// recording it to be taken by TakeApplyProgress.
+func (m *StackManager) newProgressHook(runID uint) func(msg models.Message) {
+	progress := &applyProgress{results: make(map[string]models.OpResult)}
+	m.applyProgresses.Store(runID, progress)
+	return func(msg models.Message) {
+		progress.record(msg)
+		event := RunEvent{
+			Type:       RunEventResource,
+			RunID:      runID,
+			ResourceID: msg.ResourceID,
+			Result:     msg.OpResult,
+		}
+		if msg.OpErr != nil {
+			event.Message = msg.OpErr.Error()
+		}
+		m.runEvents.publish(event)
+	}
+}
@@ -0,0 +1,120 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+func collectRunEvents(events <-chan RunEvent) []RunEvent {
+	var collected []RunEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestStackManager_RunEvents(t *testing.T) {
+	t.Run("event sequence of an apply run", func(t *testing.T) {
+		m := &StackManager{}
+		events, unsubscribe := m.SubscribeRunEvents(1)
+		defer unsubscribe()
+		otherEvents, unsubscribeOther := m.SubscribeRunEvents(2)
+		defer unsubscribeOther()
+
+		progress := m.newProgressHook(1)
+		m.PublishRunStatus(1, constant.RunStatusQueued)
+		m.PublishRunStatus(1, constant.RunStatusInProgress)
+		progress(models.Message{ResourceID: "a"})
+		progress(models.Message{ResourceID: "a", OpResult: models.Success})
+		progress(models.Message{ResourceID: "b", OpResult: models.Failed, OpErr: errors.New("boom")})
+		m.PublishRunStatus(1, constant.RunStatusFailed)
+
+		var sequence []string
+		for _, event := range collectRunEvents(events) {
+			assert.Equal(t, uint(1), event.RunID)
+			assert.False(t, event.Time.IsZero())
+			switch event.Type {
+			case RunEventStatus:
+				sequence = append(sequence, string(event.Status))
+			case RunEventResource:
+				sequence = append(sequence, event.ResourceID+":"+string(event.Result)+":"+event.Message)
+			}
+		}
+		assert.Equal(t, []string{
+			string(constant.RunStatusQueued),
+			string(constant.RunStatusInProgress),
+			"a::",
+			"a:" + string(models.Success) + ":",
+			"b:" + string(models.Failed) + ":boom",
+			string(constant.RunStatusFailed),
+		}, sequence)
+
+		// The events of the other runs are not affected
+		select {
+		case event := <-otherEvents:
+			t.Fatalf("unexpected event %v", event)
+		default:
+		}
+	})
+
+	t.Run("terminal event delivered to the subscriber not keeping up", func(t *testing.T) {
+		m := &StackManager{}
+		events, unsubscribe := m.SubscribeRunEvents(1)
+		defer unsubscribe()
+
+		progress := m.newProgressHook(1)
+		for i := 0; i < runEventBufferSize+1; i++ {
+			progress(models.Message{ResourceID: "a"})
+		}
+		m.PublishRunStatus(1, constant.RunStatusSucceeded)
+
+		collected := collectRunEvents(events)
+		assert.Len(t, collected, runEventBufferSize)
+		assert.True(t, collected[len(collected)-1].IsTerminal())
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		m := &StackManager{}
+		events, unsubscribe := m.SubscribeRunEvents(1)
+		unsubscribe()
+		m.PublishRunStatus(1, constant.RunStatusSucceeded)
+		_, ok := <-events
+		assert.False(t, ok)
+		assert.Empty(t, m.runEvents.subscribers)
+		// Unsubscribing twice is a no-op
+		unsubscribe()
+	})
+
+	t.Run("publish the updated run status", func(t *testing.T) {
+		ctx := context.TODO()
+		run := &entity.Run{ID: 1, Status: constant.RunStatusInProgress}
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(run, nil)
+		mockRepo.On("Update", ctx, mock.Anything).Return(nil)
+		m := &StackManager{runRepo: mockRepo}
+		events, unsubscribe := m.SubscribeRunEvents(1)
+		defer unsubscribe()
+
+		// Updating the result only does not publish any status
+		_, err := m.UpdateRunResultAndStatusByID(ctx, 1, request.UpdateRunResultRequest{Logs: "logs"})
+		assert.NoError(t, err)
+		_, err = m.UpdateRunResultAndStatusByID(ctx, 1, request.UpdateRunResultRequest{
+			Status: string(constant.RunStatusSucceeded),
+		})
+		assert.NoError(t, err)
+
+		collected := collectRunEvents(events)
+		if assert.Len(t, collected, 1) {
+			assert.Equal(t, RunEventStatus, collected[0].Type)
+			assert.Equal(t, constant.RunStatusSucceeded, collected[0].Status)
+		}
+	})
+}
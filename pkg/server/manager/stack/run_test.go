@@ -0,0 +1,277 @@
+package stack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"kusionstack.io/kusion/pkg/domain/constant"
+	"kusionstack.io/kusion/pkg/domain/entity"
+)
+
+type mockRunRepository struct {
+	mock.Mock
+}
+
+func (m *mockRunRepository) Create(ctx context.Context, run *entity.Run) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *mockRunRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockRunRepository) Update(ctx context.Context, run *entity.Run) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *mockRunRepository) CompareAndUpdate(ctx context.Context, run *entity.Run, expectedStatus constant.RunStatus) (bool, error) {
+	args := m.Called(ctx, run, expectedStatus)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRunRepository) ConsumePlan(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRunRepository) Get(ctx context.Context, id uint) (*entity.Run, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Run), args.Error(1)
+}
+
+func (m *mockRunRepository) List(ctx context.Context, filter *entity.RunFilter, sortOptions *entity.SortOptions) (*entity.RunListResult, error) {
+	args := m.Called(ctx, filter, sortOptions)
+	return args.Get(0).(*entity.RunListResult), args.Error(1)
+}
+
+func TestStackManager_ContinueRun(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("run not waiting for approval", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		_, err := m.ContinueRun(ctx, 1, "alice")
+		assert.ErrorIs(t, err, ErrRunNotWaitingApproval)
+	})
+
+	t.Run("run timed out while waiting for approval", func(t *testing.T) {
+		for _, status := range []constant.RunStatus{constant.RunStatusCancelled, constant.RunStatusFailed} {
+			mockRepo := &mockRunRepository{}
+			mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: status}, nil)
+			m := &StackManager{runRepo: mockRepo}
+
+			_, err := m.ContinueRun(ctx, 1, "alice")
+			assert.ErrorIs(t, err, ErrRunTimedOutWaitingApproval)
+		}
+	})
+
+	t.Run("paused run not tracked", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusWaitingApproval}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		_, err := m.ContinueRun(ctx, 1, "alice")
+		assert.ErrorIs(t, err, ErrRunTimedOutWaitingApproval)
+	})
+
+	t.Run("continue the concurrently paused run", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", mock.Anything, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusWaitingApproval}, nil)
+		waiting := make(chan struct{}, 1)
+		mockRepo.On("CompareAndUpdate", mock.Anything, &entity.Run{ID: 1, Status: constant.RunStatusWaitingApproval}, constant.RunStatusInProgress).
+			Return(true, nil).Once().
+			Run(func(args mock.Arguments) {
+				waiting <- struct{}{}
+			})
+		mockRepo.On("CompareAndUpdate", mock.Anything, mock.Anything, constant.RunStatusWaitingApproval).Return(true, nil).Once()
+		m := &StackManager{runRepo: mockRepo}
+
+		hookErrs := make(chan error, 2)
+		go func() {
+			hookErrs <- m.newPauseHook(ctx, 1)("apps/v1:Deployment:default:canary")
+		}()
+		<-waiting
+		go func() {
+			hookErrs <- m.newPauseHook(ctx, 1)("apps/v1:Deployment:default:canary-2")
+		}()
+		// Wait for both resources to be pending approval
+		assert.Eventually(t, func() bool {
+			m.pauseMu.Lock()
+			defer m.pauseMu.Unlock()
+			value, ok := m.pausedRuns.Load(uint(1))
+			return ok && len(value.(*pausedRun).resourceIDs) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		continued, err := m.ContinueRun(ctx, 1, "alice")
+		assert.NoError(t, err)
+		assert.NoError(t, <-hookErrs)
+		assert.NoError(t, <-hookErrs)
+		assert.Len(t, continued.Approvals, 1)
+		assert.Equal(t, "alice", continued.Approvals[0].Approver)
+		assert.Equal(t, []string{"apps/v1:Deployment:default:canary", "apps/v1:Deployment:default:canary-2"}, continued.Approvals[0].ResourceIDs)
+		assert.Equal(t, constant.RunStatusInProgress, continued.Status)
+		// Only the first pause sets the run to WaitingApproval, and the run leaves it once for both
+		mockRepo.AssertNumberOfCalls(t, "CompareAndUpdate", 2)
+		_, ok := m.pausedRuns.Load(uint(1))
+		assert.False(t, ok)
+	})
+
+	t.Run("run stopped waiting while being continued", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", mock.Anything, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusWaitingApproval}, nil)
+		mockRepo.On("CompareAndUpdate", mock.Anything, mock.Anything, constant.RunStatusWaitingApproval).Return(false, nil)
+		m := &StackManager{runRepo: mockRepo}
+		m.pausedRuns.Store(uint(1), &pausedRun{
+			resourceIDs: []string{"apps/v1:Deployment:default:canary"},
+			continueCh:  make(chan struct{}),
+		})
+
+		_, err := m.ContinueRun(ctx, 1, "alice")
+		assert.ErrorIs(t, err, ErrRunTimedOutWaitingApproval)
+	})
+
+	t.Run("pause the run not in progress", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("CompareAndUpdate", mock.Anything, mock.Anything, constant.RunStatusInProgress).Return(false, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		err := m.newPauseHook(ctx, 1)("apps/v1:Deployment:default:canary")
+		assert.ErrorIs(t, err, ErrRunStatusChanged)
+		_, ok := m.pausedRuns.Load(uint(1))
+		assert.False(t, ok)
+	})
+
+	t.Run("pause hook times out", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("CompareAndUpdate", mock.Anything, mock.Anything, constant.RunStatusInProgress).Return(true, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := m.newPauseHook(timeoutCtx, 1)("apps/v1:Deployment:default:canary")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		_, ok := m.pausedRuns.Load(uint(1))
+		assert.False(t, ok)
+	})
+}
+
+func TestStackManager_CancelRun(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("cancel a long-running apply", func(t *testing.T) {
+		run := &entity.Run{ID: 1, Status: constant.RunStatusInProgress}
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(run, nil)
+		mockRepo.On("CompareAndUpdate", ctx, &entity.Run{
+			ID:     1,
+			Status: constant.RunStatusCancelled,
+			Result: constant.RunResultCancelled,
+		}, constant.RunStatusInProgress).Return(true, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		runCtx, cancel := context.WithTimeout(context.Background(), constant.RunTimeOut)
+		defer cancel()
+		applyErr := make(chan error)
+		go func() {
+			defer m.TrackRun(1, cancel)()
+			select {
+			case <-runCtx.Done():
+				applyErr <- runCtx.Err()
+			case <-time.After(time.Minute):
+				applyErr <- nil
+			}
+		}()
+
+		// Wait for the apply to be tracked
+		assert.Eventually(t, func() bool {
+			_, ok := m.inFlightRuns.Load(uint(1))
+			return ok
+		}, time.Second, 10*time.Millisecond)
+		cancelled, err := m.CancelRun(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, constant.RunStatusCancelled, cancelled.Status)
+		assert.Equal(t, constant.RunResultCancelled, cancelled.Result)
+		assert.ErrorIs(t, <-applyErr, context.Canceled)
+		assert.Eventually(t, func() bool {
+			_, ok := m.inFlightRuns.Load(uint(1))
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("cancel a queued run", func(t *testing.T) {
+		run := &entity.Run{ID: 1, Status: constant.RunStatusQueued}
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(run, nil)
+		mockRepo.On("CompareAndUpdate", ctx, mock.Anything, constant.RunStatusQueued).Return(true, nil)
+		m := &StackManager{runRepo: mockRepo}
+		m.QueueRun(1)
+
+		_, err := m.CancelRun(ctx, 1)
+		assert.NoError(t, err)
+
+		// The run is cancelled as soon as it is picked up by a worker
+		runCtx, cancel := context.WithCancel(context.Background())
+		untrack := m.TrackRun(1, cancel)
+		assert.ErrorIs(t, runCtx.Err(), context.Canceled)
+		untrack()
+		_, ok := m.inFlightRuns.Load(uint(1))
+		assert.False(t, ok)
+	})
+
+	t.Run("cancel a queued run never picked up", func(t *testing.T) {
+		run := &entity.Run{ID: 1, Status: constant.RunStatusQueued}
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(run, nil)
+		mockRepo.On("CompareAndUpdate", ctx, mock.Anything, constant.RunStatusQueued).Return(true, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		cancelled, err := m.CancelRun(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, constant.RunStatusCancelled, cancelled.Status)
+		// The run is not tracked as nothing will pick it up
+		_, ok := m.inFlightRuns.Load(uint(1))
+		assert.False(t, ok)
+	})
+
+	t.Run("run completed while cancelling", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil)
+		// The worker completes the run after it is read
+		mockRepo.On("CompareAndUpdate", ctx, mock.Anything, constant.RunStatusInProgress).Return(false, nil)
+		m := &StackManager{runRepo: mockRepo}
+		runCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		defer m.TrackRun(1, cancel)()
+
+		_, err := m.CancelRun(ctx, 1)
+		assert.ErrorIs(t, err, ErrRunAlreadyCompleted)
+		// The completed run is not cancelled
+		assert.NoError(t, runCtx.Err())
+	})
+
+	t.Run("run already completed", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusSucceeded}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		_, err := m.CancelRun(ctx, 1)
+		assert.ErrorIs(t, err, ErrRunAlreadyCompleted)
+	})
+
+	t.Run("run not in flight", func(t *testing.T) {
+		mockRepo := &mockRunRepository{}
+		mockRepo.On("Get", ctx, uint(1)).Return(&entity.Run{ID: 1, Status: constant.RunStatusInProgress}, nil)
+		m := &StackManager{runRepo: mockRepo}
+
+		_, err := m.CancelRun(ctx, 1)
+		assert.ErrorIs(t, err, ErrRunNotInFlight)
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
 	"kusionstack.io/kusion/pkg/infra/persistence"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 )
 
 func TestBuildOptions(t *testing.T) {
@@ -51,9 +52,34 @@ func TestProcessChanges(t *testing.T) {
 	assert.Equal(t, changes, result)
 }
 
+func TestProcessChanges_Summary(t *testing.T) {
+	changes := &models.Changes{
+		ChangeOrder: &models.ChangeOrder{
+			StepKeys: []string{"v1:Namespace:foo", "v1:ConfigMap:foo:bar", "v1:ConfigMap:foo:baz", "v1:Secret:foo:qux"},
+			ChangeSteps: map[string]*models.ChangeStep{
+				"v1:Namespace:foo":     {ID: "v1:Namespace:foo", Action: models.UnChanged},
+				"v1:ConfigMap:foo:bar": {ID: "v1:ConfigMap:foo:bar", Action: models.Create},
+				"v1:ConfigMap:foo:baz": {ID: "v1:ConfigMap:foo:baz", Action: models.Update},
+				"v1:Secret:foo:qux":    {ID: "v1:Secret:foo:qux", Action: models.Delete},
+			},
+		},
+	}
+
+	result, err := ProcessChanges(context.Background(), nil, changes, "summary", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ActionSummary{
+		Create:           1,
+		Update:           1,
+		Delete:           1,
+		ChangedResources: []string{"v1:ConfigMap:foo:bar", "v1:ConfigMap:foo:baz", "v1:Secret:foo:qux"},
+	}, result)
+}
+
 func TestGetBackendFromWorkspaceName(t *testing.T) {
 	m := &StackManager{
 		workspaceRepo: &mockWorkspaceRepository{},
+		backendScope:  backendmanager.NewScope(nil, nil, false),
 		defaultBackend: entity.Backend{
 			ID:   1,
 			Name: "default",
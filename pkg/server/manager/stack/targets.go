@@ -0,0 +1,59 @@
+package stack
+
+import (
+	"fmt"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// targetSpec returns the spec to apply only the target resources along with their dependencies. The other
+// resources are kept as they are in the prior state so that they are skipped as unchanged, instead of being
+// created, updated or deleted.
+func targetSpec(sp *v1.Spec, priorState *v1.State, targets []string) (*v1.Spec, error) {
+	index := sp.Resources.Index()
+	// Collect the targets and their dependencies transitively
+	targeted := make(map[string]bool)
+	queue := make([]string, 0, len(targets))
+	for _, id := range targets {
+		if _, ok := index[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrApplyTargetNotFound, id)
+		}
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if targeted[id] {
+			continue
+		}
+		targeted[id] = true
+		if res, ok := index[id]; ok {
+			queue = append(queue, res.DependsOn...)
+		}
+	}
+
+	var priorIndex map[string]*v1.Resource
+	if priorState != nil {
+		priorIndex = priorState.Resources.Index()
+	}
+	resources := make(v1.Resources, 0, len(sp.Resources))
+	for _, res := range sp.Resources {
+		if targeted[res.ID] {
+			resources = append(resources, res)
+		} else if prior, ok := priorIndex[res.ID]; ok {
+			resources = append(resources, *prior)
+		}
+	}
+	// Keep the resources removed from the spec but not targeted
+	if priorState != nil {
+		for _, prior := range priorState.Resources {
+			if _, ok := index[prior.ID]; !ok {
+				resources = append(resources, prior)
+			}
+		}
+	}
+
+	targetedSpec := *sp
+	targetedSpec.Resources = resources
+	return &targetedSpec, nil
+}
@@ -0,0 +1,52 @@
+package stack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+func TestTargetSpec(t *testing.T) {
+	newResource := func(id, value string, dependsOn ...string) v1.Resource {
+		return v1.Resource{
+			ID:         id,
+			Type:       v1.Kubernetes,
+			Attributes: map[string]interface{}{"data": map[string]interface{}{"key": value}},
+			DependsOn:  dependsOn,
+		}
+	}
+	sp := &v1.Spec{Resources: v1.Resources{
+		newResource("v1:Namespace:foo", "new"),
+		newResource("v1:ConfigMap:foo:bar", "new", "v1:Namespace:foo"),
+		newResource("v1:ConfigMap:foo:baz", "new", "v1:Namespace:foo"),
+		newResource("v1:ConfigMap:foo:qux", "new"),
+	}}
+	priorState := &v1.State{Resources: v1.Resources{
+		newResource("v1:Namespace:foo", "old"),
+		newResource("v1:ConfigMap:foo:baz", "old", "v1:Namespace:foo"),
+		newResource("v1:ConfigMap:foo:removed", "old"),
+	}}
+
+	t.Run("single target with dependencies", func(t *testing.T) {
+		targeted, err := targetSpec(sp, priorState, []string{"v1:ConfigMap:foo:bar"})
+		assert.NoError(t, err)
+		index := targeted.Resources.Index()
+		assert.Len(t, index, 4)
+		// The target and its dependency are applied
+		assert.Equal(t, "new", index["v1:ConfigMap:foo:bar"].Attributes["data"].(map[string]interface{})["key"])
+		assert.Equal(t, "new", index["v1:Namespace:foo"].Attributes["data"].(map[string]interface{})["key"])
+		// The untargeted resources are skipped as they are in the prior state
+		assert.Equal(t, "old", index["v1:ConfigMap:foo:baz"].Attributes["data"].(map[string]interface{})["key"])
+		assert.Contains(t, index, "v1:ConfigMap:foo:removed")
+		assert.NotContains(t, index, "v1:ConfigMap:foo:qux")
+		// The original spec is left untouched
+		assert.Len(t, sp.Resources, 4)
+	})
+
+	t.Run("target not found", func(t *testing.T) {
+		_, err := targetSpec(sp, priorState, []string{"v1:ConfigMap:foo:bar", "v1:ConfigMap:foo:missing"})
+		assert.ErrorIs(t, err, ErrApplyTargetNotFound)
+	})
+}
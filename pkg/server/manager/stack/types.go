@@ -2,10 +2,12 @@ package stack
 
 import (
 	"errors"
+	"sync"
 
 	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/repository"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 	cache "kusionstack.io/kusion/pkg/server/util/cache"
 )
 
@@ -30,6 +32,25 @@ var (
 	ErrWorkspaceEmpty                            = errors.New("workspace should not be empty in query")
 	ErrRunRequestBodyEmpty                       = errors.New("run request body should not be empty")
 	ErrRunCrashed                                = errors.New("run crashed")
+	ErrRunNotWaitingApproval                     = errors.New("the run is not waiting for approval")
+	ErrRunTimedOutWaitingApproval                = errors.New("the run has timed out or been interrupted while waiting for approval")
+	ErrRunStatusChanged                          = errors.New("the run status has been changed by others")
+	ErrPatcherDryRunSpecSource                   = errors.New("exactly one of runID and spec should be set to dry run the patcher")
+	ErrRunHasNoSpec                              = errors.New("the run is not a succeeded generate run with the spec as its result")
+	ErrRunHasNoPlan                              = errors.New("the run is not a succeeded preview run with the plan as its result")
+	ErrInvalidPlanID                             = errors.New("the plan ID should be the ID of a preview run")
+	ErrPlanStackMismatch                         = errors.New("the plan is previewed for another stack or workspace")
+	ErrPlanChecksumMismatch                      = errors.New("the checksum of the plan does not match its spec and changes")
+	ErrPlanStateChanged                          = errors.New("the state has changed since the plan was previewed, please preview again")
+	ErrPlanConsumed                              = errors.New("the plan has already been applied")
+	ErrRunEventsStreamingUnsupported             = errors.New("streaming the run events is not supported by the response writer")
+	ErrRunAlreadyCompleted                       = errors.New("the run has already completed")
+	ErrRunNotInFlight                            = errors.New("the run is not an in-flight async run of this server")
+	ErrInvalidRunTimeout                         = errors.New("timeout should be a positive number of seconds")
+	ErrApplyTargetNotFound                       = errors.New("the apply target is not a resource in the spec")
+	ErrApplyTargetsWithPlan                      = errors.New("the apply targets cannot be set when applying a plan")
+	ErrExpectedChecksumEmpty                     = errors.New("expectedChecksum should not be empty in query")
+	ErrPlanChecksumConflict                      = errors.New("the previewed changes conflict with the expected checksum")
 )
 
 type StackManager struct {
@@ -38,9 +59,20 @@ type StackManager struct {
 	workspaceRepo  repository.WorkspaceRepository
 	resourceRepo   repository.ResourceRepository
 	runRepo        repository.RunRepository
+	backendScope   *backendmanager.Scope
 	defaultBackend entity.Backend
 	maxConcurrent  int
 	repoCache      *cache.Cache[uint, *StackCache]
+	// pausedRuns maps the run ID to the *pausedRun waiting for approval
+	pausedRuns sync.Map
+	// pauseMu serializes the pauses and the continuations of the runs along with their status
+	pauseMu sync.Mutex
+	// runEvents publishes the progress events of the async runs
+	runEvents runEventHub
+	// inFlightRuns maps the run ID to the *inFlightRun of the async runs
+	inFlightRuns sync.Map
+	// applyProgresses maps the run ID to the *applyProgress of the apply runs
+	applyProgresses sync.Map
 }
 
 type StackCache struct {
@@ -53,6 +85,7 @@ type StackRequestParams struct {
 	Workspace     string
 	Format        string
 	Operator      string
+	RunID         uint
 	ExecuteParams StackExecuteParams
 }
 
@@ -66,6 +99,24 @@ type StackExecuteParams struct {
 	Unlock              bool
 	Watch               bool
 	WatchTimeoutSeconds int
+	WorkloadOnly        bool
+	WithSummary         bool
+	// StrictSecretStore fails the generation on the external secrets without a secret store.
+	StrictSecretStore bool
+	// NonStrict continues the generation past the failures of the built-in generators.
+	NonStrict bool
+	// Plan stores the plan artifact as the result of the preview run.
+	Plan bool
+	// PlanID is the ID of the preview run whose plan is applied exactly.
+	PlanID uint
+	// TimeoutSeconds overrides the default timeout of the async run if it is positive.
+	TimeoutSeconds int
+	// Targets restricts the apply to the resources with the IDs and their dependencies.
+	Targets []string
+	// ExpectedChecksum is the checksum of the plan the preview of a preview-then-apply run should match.
+	ExpectedChecksum string
+	// plan is the plan previewed in the same run to be applied exactly.
+	plan *Plan
 }
 
 type RunRequestParams struct {
@@ -77,6 +128,7 @@ func NewStackManager(stackRepo repository.StackRepository,
 	workspaceRepo repository.WorkspaceRepository,
 	resourceRepo repository.ResourceRepository,
 	runRepo repository.RunRepository,
+	backendScope *backendmanager.Scope,
 	defaultBackend entity.Backend,
 	maxConcurrent int,
 ) *StackManager {
@@ -86,6 +138,7 @@ func NewStackManager(stackRepo repository.StackRepository,
 		workspaceRepo:  workspaceRepo,
 		resourceRepo:   resourceRepo,
 		runRepo:        runRepo,
+		backendScope:   backendScope,
 		defaultBackend: defaultBackend,
 		maxConcurrent:  maxConcurrent,
 		repoCache:      cache.NewCache[uint, *StackCache](constant.RepoCacheTTL),
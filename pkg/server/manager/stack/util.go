@@ -24,6 +24,7 @@ import (
 	"kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/engine/release"
 	"kusionstack.io/kusion/pkg/engine/runtime/terraform/tfops"
+	"kusionstack.io/kusion/pkg/generators/appconfiguration"
 	workspacemanager "kusionstack.io/kusion/pkg/server/manager/workspace"
 	logutil "kusionstack.io/kusion/pkg/server/util/logging"
 	"kusionstack.io/kusion/pkg/util/diff"
@@ -42,6 +43,20 @@ func BuildOptions(dryrun bool, maxConcurrent int) *engineapi.APIOptions {
 	return executeOptions
 }
 
+// generateOptions returns the options of generating the spec, which reads the objects referenced by the module
+// configs from the backend of the workspace if it supports.
+func generateOptions(workloadOnly bool, params StackExecuteParams, bk backend.Backend) engineapi.GenerateOptions {
+	opts := engineapi.GenerateOptions{
+		WorkloadOnly:      workloadOnly,
+		StrictSecretStore: params.StrictSecretStore,
+		NonStrict:         params.NonStrict,
+	}
+	if objects, ok := bk.(appconfiguration.ObjectReader); ok {
+		opts.Objects = objects
+	}
+	return opts
+}
+
 // getWorkDirFromSource returns the workdir based on the source
 // if the source type is local, it will return the path as an absolute path on the local filesystem
 // if the source type is remote (git for example), it will pull the source and return the path to the pulled source
@@ -115,6 +130,11 @@ func ProcessChanges(ctx context.Context, w http.ResponseWriter, changes *models.
 		v.To = maskedTo
 	}
 
+	// The summary is returned even if there is no change, so that it can always be used to gate CI decisions
+	if format == engineapi.SummaryOutput {
+		return changes.ActionSummary(), nil
+	}
+
 	if changes.AllUnChange() {
 		logger.Info(NoDiffFound)
 		return changes, nil
@@ -152,6 +172,10 @@ func (m *StackManager) getBackendFromWorkspaceName(ctx context.Context, workspac
 		} else if err != nil {
 			return nil, err
 		}
+		// The backend of the workspace must be visible to the requester
+		if err = m.backendScope.CheckVisible(ctx, workspaceEntity.Backend); err != nil {
+			return nil, err
+		}
 		// Generate backend from entity
 		remoteBackend, err = workspacemanager.NewBackendFromEntity(*workspaceEntity.Backend)
 		if err != nil {
@@ -12,6 +12,9 @@ import (
 	"kusionstack.io/kusion/pkg/backend"
 	"kusionstack.io/kusion/pkg/domain/constant"
 	"kusionstack.io/kusion/pkg/domain/request"
+	"kusionstack.io/kusion/pkg/secrets"
+	_ "kusionstack.io/kusion/pkg/secrets/providers/register"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 	logutil "kusionstack.io/kusion/pkg/server/util/logging"
 
 	"github.com/elliotchance/orderedmap/v2"
@@ -100,6 +103,12 @@ func (m *WorkspaceManager) UpdateWorkspaceConfigs(ctx context.Context, id uint,
 	return &configs, nil
 }
 
+// ListSecretProviders returns the registered secret store providers along with the fields to configure
+// them in the secret store of the workspace.
+func (m *WorkspaceManager) ListSecretProviders(ctx context.Context) []secrets.ProviderInfo {
+	return secrets.ListProviders()
+}
+
 func (m *WorkspaceManager) CreateKCLModDependencies(ctx context.Context, id uint) (string, error) {
 	workspaceEntity, err := m.workspaceRepo.Get(ctx, id)
 	if err != nil {
@@ -191,6 +200,14 @@ func (m *WorkspaceManager) getBackendFromWorkspaceName(ctx context.Context, work
 			return nil, err
 		}
 
+		// The backend of the workspace must be visible to the requester.
+		if err = m.backendScope.CheckVisible(ctx, workspaceEntity.Backend); err != nil {
+			if errors.Is(err, backendmanager.ErrGettingNonExistingBackend) {
+				return nil, ErrBackendNotFound
+			}
+			return nil, err
+		}
+
 		// Generate backend from the workspace entity.
 		remoteBackend, err = NewBackendFromEntity(*workspaceEntity.Backend)
 		if err != nil {
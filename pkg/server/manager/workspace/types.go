@@ -5,6 +5,7 @@ import (
 
 	"kusionstack.io/kusion/pkg/domain/entity"
 	"kusionstack.io/kusion/pkg/domain/repository"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
 )
 
 var (
@@ -18,19 +19,19 @@ var (
 
 type WorkspaceManager struct {
 	workspaceRepo  repository.WorkspaceRepository
-	backendRepo    repository.BackendRepository
+	backendScope   *backendmanager.Scope
 	moduleRepo     repository.ModuleRepository
 	defaultBackend entity.Backend
 }
 
 func NewWorkspaceManager(workspaceRepo repository.WorkspaceRepository,
-	backendRepo repository.BackendRepository,
+	backendScope *backendmanager.Scope,
 	moduleRepo repository.ModuleRepository,
 	defaultBackend entity.Backend,
 ) *WorkspaceManager {
 	return &WorkspaceManager{
 		workspaceRepo:  workspaceRepo,
-		backendRepo:    backendRepo,
+		backendScope:   backendScope,
 		moduleRepo:     moduleRepo,
 		defaultBackend: defaultBackend,
 	}
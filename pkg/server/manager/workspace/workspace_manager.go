@@ -48,9 +48,9 @@ func (m *WorkspaceManager) DeleteWorkspaceByID(ctx context.Context, id uint) (er
 		return err
 	}
 
-	// Get backend by id
-	backendEntity, err := m.backendRepo.Get(ctx, existingEntity.Backend.ID)
-	if err != nil && err == gorm.ErrRecordNotFound {
+	// Get backend by id, which must be visible to the requester
+	backendEntity, err := m.backendScope.GetVisibleBackend(ctx, existingEntity.Backend.ID)
+	if errors.Is(err, backendmanager.ErrGettingNonExistingBackend) {
 		return ErrBackendNotFound
 	} else if err != nil {
 		return err
@@ -124,9 +124,9 @@ func (m *WorkspaceManager) UpdateWorkspaceByID(ctx context.Context, id uint, req
 	}()
 
 	if requestEntity.Name != "" && requestEntity.Name != beforeUpdatedEntity.Name {
-		// Get backend by id
-		backendEntity, err := m.backendRepo.Get(ctx, updatedEntity.Backend.ID)
-		if err != nil && err == gorm.ErrRecordNotFound {
+		// Get backend by id, which must be visible to the requester
+		backendEntity, err := m.backendScope.GetVisibleBackend(ctx, updatedEntity.Backend.ID)
+		if errors.Is(err, backendmanager.ErrGettingNonExistingBackend) {
 			return nil, ErrBackendNotFound
 		} else if err != nil {
 			return nil, err
@@ -160,9 +160,9 @@ func (m *WorkspaceManager) CreateWorkspace(ctx context.Context, requestPayload r
 		return nil, err
 	}
 
-	// Get backend by id
-	backendEntity, err := m.backendRepo.Get(ctx, requestPayload.BackendID)
-	if err != nil && err == gorm.ErrRecordNotFound {
+	// Get backend by id, which must be visible to the requester
+	backendEntity, err := m.backendScope.GetVisibleBackend(ctx, requestPayload.BackendID)
+	if errors.Is(err, backendmanager.ErrGettingNonExistingBackend) {
 		return nil, ErrBackendNotFound
 	} else if err != nil {
 		return nil, err
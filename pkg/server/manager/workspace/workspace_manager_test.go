@@ -12,7 +12,10 @@ import (
 	backend "kusionstack.io/kusion/pkg/backend"
 	"kusionstack.io/kusion/pkg/domain/constant"
 	entity "kusionstack.io/kusion/pkg/domain/entity"
+	"kusionstack.io/kusion/pkg/domain/repository"
 	"kusionstack.io/kusion/pkg/domain/request"
+	backendmanager "kusionstack.io/kusion/pkg/server/manager/backend"
+	appmiddleware "kusionstack.io/kusion/pkg/server/middleware"
 )
 
 type mockWorkspaceRepository struct {
@@ -111,6 +114,19 @@ func (m *mockBackendRepository) Get(ctx context.Context, id uint) (*entity.Backe
 	return args.Get(0).(*entity.Backend), args.Error(1)
 }
 
+type mockOrganizationRepository struct {
+	repository.OrganizationRepository
+	mock.Mock
+}
+
+func (m *mockOrganizationRepository) GetByName(ctx context.Context, name string) (*entity.Organization, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.Organization), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestInvalidNewBackendFromEntity(t *testing.T) {
 	// Test cases
 	testcases := []struct {
@@ -233,7 +249,7 @@ func TestWorkspaceManager_DeleteWorkspaceByID(t *testing.T) {
 	}, nil)
 	manager := &WorkspaceManager{
 		workspaceRepo: mockWorkspaceRepo,
-		backendRepo:   mockBackendRepo,
+		backendScope:  backendmanager.NewScope(mockBackendRepo, nil, false),
 	}
 	err := manager.DeleteWorkspaceByID(ctx, id)
 	if err != nil {
@@ -266,7 +282,7 @@ func TestWorkspaceManager_UpdateWorkspaceByID(t *testing.T) {
 	// Create a new WorkspaceManager instance with the mock repository
 	manager := &WorkspaceManager{
 		workspaceRepo: mockWorkspaceRepo,
-		backendRepo:   mockBackendRepo,
+		backendScope:  backendmanager.NewScope(mockBackendRepo, nil, false),
 	}
 	// Call the UpdateWorkspaceByID method
 	workspace, err := manager.UpdateWorkspaceByID(ctx, id, request.UpdateWorkspaceRequest{
@@ -283,3 +299,31 @@ func TestWorkspaceManager_UpdateWorkspaceByID(t *testing.T) {
 	// Assert that the Update method of the mock repository was called with the correct parameters
 	mockWorkspaceRepo.AssertCalled(t, "Update", ctx, updatedWorkspace)
 }
+
+func TestWorkspaceManager_CreateWorkspaceWithOtherOrganizationBackend(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), appmiddleware.OrgNameKey, "org-b")
+	mockWorkspaceRepo := &mockWorkspaceRepository{}
+	mockBackendRepo := &mockBackendRepository{}
+	mockOrgRepo := &mockOrganizationRepository{}
+
+	// the backend is scoped to the organization org-a
+	mockBackendRepo.On("Get", ctx, uint(1)).Return(&entity.Backend{
+		ID:             1,
+		OrganizationID: 1,
+		BackendConfig: v1.BackendConfig{
+			Type: v1.BackendTypeLocal,
+		},
+	}, nil)
+	mockOrgRepo.On("GetByName", ctx, "org-b").Return(&entity.Organization{ID: 2, Name: "org-b"}, nil)
+	manager := &WorkspaceManager{
+		workspaceRepo: mockWorkspaceRepo,
+		backendScope:  backendmanager.NewScope(mockBackendRepo, mockOrgRepo, true),
+	}
+
+	_, err := manager.CreateWorkspace(ctx, request.CreateWorkspaceRequest{
+		Name:      "dev",
+		BackendID: 1,
+	})
+	assert.ErrorIs(t, err, ErrBackendNotFound)
+	mockWorkspaceRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
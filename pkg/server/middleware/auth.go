@@ -11,6 +11,9 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 )
 
+// OrgNameKey is the key that holds the organization name of the authenticated requester in a request context.
+var OrgNameKey = &contextKey{"orgName"}
+
 // This is the middleware function that verifies the JWT against a JWKS KeyMap
 func TokenAuthMiddleware(keyMap map[string]any, whitelist []string, logFilePath string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -64,9 +67,13 @@ func TokenAuthMiddleware(keyMap map[string]any, whitelist []string, logFilePath
 				return
 			}
 
-			// Token is authenticated, pass it through
+			// Token is authenticated, pass it through along with the organization of the requester
 			logger.Info("request is authorized")
-			next.ServeHTTP(w, r)
+			ctx := r.Context()
+			if name, ok := orgName.(string); ok && name != "" {
+				ctx = context.WithValue(ctx, OrgNameKey, name)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -88,3 +95,15 @@ func GetMiddlewareLogger(ctx context.Context, logFile string) *httplog.Logger {
 	logger := InitLogger(logFile, "DefaultLogger")
 	return logger
 }
+
+// GetOrgName returns the organization name of the authenticated requester from the given context.
+// Returns the empty string if the request is not authenticated.
+func GetOrgName(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if orgName, ok := ctx.Value(OrgNameKey).(string); ok {
+		return orgName
+	}
+	return ""
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpswagger "github.com/swaggo/http-swagger"
 	docs "kusionstack.io/kusion/api/openapispec"
 	"kusionstack.io/kusion/pkg/infra/persistence"
@@ -87,6 +88,9 @@ func NewCoreRoute(config *server.Config) (*chi.Mux, error) {
 	// Endpoint to list all available endpoints in the router.
 	router.Get("/endpoints", endpoint.Endpoints(router))
 
+	// Endpoint to expose prometheus metrics, such as the persistence repository query latencies.
+	router.Get("/metrics", promhttp.Handler().ServeHTTP)
+
 	// Endpoint to list all available endpoints in the router.
 	router.Get("/server-configs", expvar.Handler().ServeHTTP)
 
@@ -173,14 +177,17 @@ func setupRestAPIV1(
 	moduleRepo := persistence.NewModuleRepository(config.DB)
 	runRepo := persistence.NewRunRepository(config.DB)
 
-	stackManager := stackmanager.NewStackManager(stackRepo, projectRepo, workspaceRepo, resourceRepo, runRepo, config.DefaultBackend, config.MaxConcurrent)
+	// backendScope is shared by all the managers looking up the backends
+	backendScope := backendmanager.NewScope(backendRepo, organizationRepo, config.BackendOrgScoped)
+
+	stackManager := stackmanager.NewStackManager(stackRepo, projectRepo, workspaceRepo, resourceRepo, runRepo, backendScope, config.DefaultBackend, config.MaxConcurrent)
 	sourceManager := sourcemanager.NewSourceManager(sourceRepo)
 	organizationManager := organizationmanager.NewOrganizationManager(organizationRepo)
-	backendManager := backendmanager.NewBackendManager(backendRepo)
-	workspaceManager := workspacemanager.NewWorkspaceManager(workspaceRepo, backendRepo, moduleRepo, config.DefaultBackend)
+	backendManager := backendmanager.NewBackendManager(backendRepo, organizationRepo, config.BackendOrgScoped)
+	workspaceManager := workspacemanager.NewWorkspaceManager(workspaceRepo, backendScope, moduleRepo, config.DefaultBackend)
 	projectManager := projectmanager.NewProjectManager(projectRepo, organizationRepo, sourceRepo, config.DefaultSource)
-	resourceManager := resourcemanager.NewResourceManager(resourceRepo)
-	moduleManager := modulemanager.NewModuleManager(moduleRepo, workspaceRepo, backendRepo)
+	resourceManager := resourcemanager.NewResourceManager(resourceRepo, workspaceManager)
+	moduleManager := modulemanager.NewModuleManager(moduleRepo, workspaceRepo, backendScope)
 
 	// Set up the handlers for the resources.
 	sourceHandler, err := source.NewHandler(sourceManager)
@@ -198,7 +205,7 @@ func setupRestAPIV1(
 		logger.Error(err.Error(), "Error creating project handler...", "error", err)
 		return
 	}
-	stackHandler, err := stack.NewHandler(stackManager, config.MaxAsyncConcurrent, config.MaxAsyncBuffer)
+	stackHandler, err := stack.NewHandler(stackManager, config.MaxAsyncConcurrent, config.MaxAsyncBuffer, config.MaxRunTimeout)
 	if err != nil {
 		logger.Error(err.Error(), "Error creating stack handler...", "error", err)
 		return
@@ -238,10 +245,16 @@ func setupRestAPIV1(
 		r.Route("/{runID}", func(r chi.Router) {
 			r.Get("/", stackHandler.GetRun())
 			r.Get("/result", stackHandler.GetRunResult())
+			r.Post("/continue", stackHandler.ContinueRun())
+			r.Post("/cancel", stackHandler.CancelRun())
+			r.Get("/events", stackHandler.StreamRunEvents())
 		})
 		// r.Post("/", backendHandler.CreateRun())
 		r.Get("/", stackHandler.ListRuns())
 	})
+	r.Route("/patchers", func(r chi.Router) {
+		r.Post("/dry-run", stackHandler.DryRunPatcher())
+	})
 	r.Route("/stacks", func(r chi.Router) {
 		r.Route("/{stackID}", func(r chi.Router) {
 			r.Post("/generate", stackHandler.GenerateStack())
@@ -250,6 +263,7 @@ func setupRestAPIV1(
 			r.Post("/preview/async", stackHandler.PreviewStackAsync())
 			r.Post("/apply", stackHandler.ApplyStack())
 			r.Post("/apply/async", stackHandler.ApplyStackAsync())
+			r.Post("/preview-apply/async", stackHandler.PreviewApplyStackAsync())
 			r.Post("/destroy", stackHandler.DestroyStack())
 			r.Post("/destroy/async", stackHandler.DestroyStackAsync())
 			// r.Route("/variable", func(r chi.Router) {
@@ -302,6 +316,7 @@ func setupRestAPIV1(
 				r.Post("/", workspaceHandler.ValidateWorkspaceConfigs())
 			})
 		})
+		r.Get("/secret-providers", workspaceHandler.ListSecretProviders())
 		r.Post("/", workspaceHandler.CreateWorkspace())
 		r.Get("/", workspaceHandler.ListWorkspaces())
 	})
@@ -320,6 +335,7 @@ func setupRestAPIV1(
 		})
 		r.Get("/", resourceHandler.ListResources())
 		r.Get("/graph", resourceHandler.GetResourceGraph())
+		r.Post("/scan", resourceHandler.ScanImportedResources())
 	})
 	r.Route("/modules", func(r chi.Router) {
 		r.Post("/", moduleHandler.CreateModule())
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 
 	"github.com/gonvenience/wrap"
 	"github.com/gonvenience/ytbx"
@@ -208,68 +209,36 @@ func MaskSensitiveData(oldData, newData interface{}) (interface{}, interface{})
 	// Replace the 'data' and 'stringData' attributes of the old secret resource.
 	if maskOld {
 		for k, v := range fromSecData {
-			var secStr string
-			secStr, ok := v.(string)
-			if !ok {
-				continue
-			}
-
-			if toV, ok := toSecData[k]; ok && secStr != toV.(string) {
-				maskedOldData.Attributes["data"].(map[string]interface{})[k] = maskStrBefore
-			} else {
-				maskedOldData.Attributes["data"].(map[string]interface{})[k] = maskStr
-			}
+			maskedOldData.Attributes["data"].(map[string]interface{})[k] = maskValue(v, toSecData, k, maskStrBefore)
 		}
-
 		for k, v := range fromSecStrData {
-			var secStr string
-			secStr, ok := v.(string)
-			if !ok {
-				continue
-			}
-
-			if toV, ok := toSecStrData[k]; ok && secStr != toV.(string) {
-				maskedOldData.Attributes["stringData"].(map[string]interface{})[k] = maskStrBefore
-			} else {
-				maskedOldData.Attributes["stringData"].(map[string]interface{})[k] = maskStr
-			}
+			maskedOldData.Attributes["stringData"].(map[string]interface{})[k] = maskValue(v, toSecStrData, k, maskStrBefore)
 		}
 	}
 
 	// Replace the 'data' and 'stringData' attributes of the new secret resource.
 	if maskNew {
 		for k, v := range toSecData {
-			var secStr string
-			secStr, ok := v.(string)
-			if !ok {
-				continue
-			}
-
-			if fromV, ok := fromSecData[k]; ok && secStr != fromV.(string) {
-				maskedNewData.Attributes["data"].(map[string]interface{})[k] = maskStrAfter
-			} else {
-				maskedNewData.Attributes["data"].(map[string]interface{})[k] = maskStr
-			}
+			maskedNewData.Attributes["data"].(map[string]interface{})[k] = maskValue(v, fromSecData, k, maskStrAfter)
 		}
-
 		for k, v := range toSecStrData {
-			var secStr string
-			secStr, ok := v.(string)
-			if !ok {
-				continue
-			}
-
-			if fromV, ok := fromSecStrData[k]; ok && secStr != fromV.(string) {
-				maskedNewData.Attributes["stringData"].(map[string]interface{})[k] = maskStrAfter
-			} else {
-				maskedNewData.Attributes["stringData"].(map[string]interface{})[k] = maskStr
-			}
+			maskedNewData.Attributes["stringData"].(map[string]interface{})[k] = maskValue(v, fromSecStrData, k, maskStrAfter)
 		}
 	}
 
 	return maskedOldData, maskedNewData
 }
 
+// maskValue returns the stable placeholder of the sensitive value, which is the changedMask if the value
+// differs from the one with the same key in the counterpart. Values of any type are masked, so that
+// neither the resolved secrets nor the secret refs are leaked.
+func maskValue(value interface{}, counterpart map[string]interface{}, key, changedMask string) string {
+	if other, ok := counterpart[key]; ok && !reflect.DeepEqual(value, other) {
+		return changedMask
+	}
+	return maskStr
+}
+
 // deepCopyResource deeply copies the old Resource into a new one.
 func deepCopyResource(from, to *v1.Resource) error {
 	to.ID = from.ID
@@ -250,6 +250,92 @@ func TestMaskSensitiveData(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "non-string data",
+			oldData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   []byte("value"),
+						"other": "dmFsdWUK",
+					},
+				},
+			},
+			newData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   []byte("value"),
+						"other": 1,
+					},
+				},
+			},
+			expectedMaskedOldData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "*******",
+						"other": "***before***",
+					},
+				},
+			},
+			expectedMaskedNewData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "*******",
+						"other": "***after****",
+					},
+				},
+			},
+		},
+		{
+			name: "masked data",
+			oldData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "***before***",
+						"other": "*******",
+					},
+				},
+			},
+			newData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "***after****",
+						"other": "*******",
+					},
+				},
+			},
+			expectedMaskedOldData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "***before***",
+						"other": "*******",
+					},
+				},
+			},
+			expectedMaskedNewData: &v1.Resource{
+				Type: v1.Kubernetes,
+				Attributes: map[string]interface{}{
+					"kind": "Secret",
+					"data": map[string]interface{}{
+						"key":   "***after****",
+						"other": "*******",
+					},
+				},
+			},
+		},
 		{
 			name: "secrets with string data",
 			oldData: &v1.Resource{
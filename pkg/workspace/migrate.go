@@ -0,0 +1,115 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+)
+
+// MigrationPlan describes what would be copied when migrating workspaces from the source
+// storage to the destination storage.
+type MigrationPlan struct {
+	// Create is the names of the workspaces to create in the destination storage.
+	Create []string
+
+	// Overwrite is the names of the workspaces existing in both storages, whose configs in
+	// the destination storage would be overwritten.
+	Overwrite []string
+
+	// Current is the name of the current workspace to set in the destination storage.
+	Current string
+}
+
+// PlanMigration returns the plan of migrating workspaces from src to dst without copying
+// anything, which can be used as the dry-run of Migrate.
+func PlanMigration(src, dst Storage) (*MigrationPlan, error) {
+	srcNames, err := src.GetNames()
+	if err != nil {
+		return nil, fmt.Errorf("get workspace names of source storage failed: %w", err)
+	}
+	dstNames, err := dst.GetNames()
+	if err != nil {
+		return nil, fmt.Errorf("get workspace names of destination storage failed: %w", err)
+	}
+	current, err := src.GetCurrent()
+	if err != nil {
+		return nil, fmt.Errorf("get current workspace of source storage failed: %w", err)
+	}
+
+	existing := make(map[string]struct{}, len(dstNames))
+	for _, name := range dstNames {
+		existing[name] = struct{}{}
+	}
+	plan := &MigrationPlan{Current: current}
+	for _, name := range srcNames {
+		if _, ok := existing[name]; ok {
+			plan.Overwrite = append(plan.Overwrite, name)
+		} else {
+			plan.Create = append(plan.Create, name)
+		}
+	}
+	return plan, nil
+}
+
+// Migrate copies all the workspace configs from src to dst, verifies the copied configs are
+// identical to the source ones, and preserves the current workspace. The workspaces only
+// existing in dst are left untouched.
+func Migrate(src, dst Storage) error {
+	plan, err := PlanMigration(src, dst)
+	if err != nil {
+		return err
+	}
+
+	copyWorkspace := func(name string, write func(ws *v1.Workspace) error) error {
+		ws, err := src.Get(name)
+		if err != nil {
+			return fmt.Errorf("get workspace %s from source storage failed: %w", name, err)
+		}
+		if err = write(ws); err != nil {
+			return fmt.Errorf("write workspace %s to destination storage failed: %w", name, err)
+		}
+		return verifyMigratedWorkspace(ws, dst)
+	}
+	for _, name := range plan.Create {
+		if err = copyWorkspace(name, dst.Create); err != nil {
+			return err
+		}
+	}
+	for _, name := range plan.Overwrite {
+		if err = copyWorkspace(name, dst.Update); err != nil {
+			return err
+		}
+	}
+
+	if plan.Current != "" {
+		if err = dst.SetCurrent(plan.Current); err != nil {
+			return fmt.Errorf("set current workspace %s of destination storage failed: %w", plan.Current, err)
+		}
+	}
+	return nil
+}
+
+// verifyMigratedWorkspace checks the workspace read from the destination storage is identical
+// to the source one.
+func verifyMigratedWorkspace(expected *v1.Workspace, dst Storage) error {
+	actual, err := dst.Get(expected.Name)
+	if err != nil {
+		return fmt.Errorf("get migrated workspace %s failed: %w", expected.Name, err)
+	}
+
+	expectedContent, err := yaml.Marshal(expected)
+	if err != nil {
+		return err
+	}
+	actualContent, err := yaml.Marshal(actual)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expectedContent, actualContent) {
+		return fmt.Errorf("migrated workspace %s is inconsistent with the source one", expected.Name)
+	}
+	return nil
+}
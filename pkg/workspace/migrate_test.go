@@ -0,0 +1,52 @@
+package workspace_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
+	"kusionstack.io/kusion/pkg/workspace"
+	"kusionstack.io/kusion/pkg/workspace/storages"
+)
+
+func TestMigrate(t *testing.T) {
+	src, err := storages.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	dst, err := storages.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	dev := &v1.Workspace{
+		Name: "dev",
+		Context: v1.GenericConfig{
+			"region": "us-east-1",
+		},
+	}
+	require.NoError(t, src.Create(dev))
+	require.NoError(t, src.SetCurrent("dev"))
+
+	plan, err := workspace.PlanMigration(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &workspace.MigrationPlan{
+		Create:    []string{"dev"},
+		Overwrite: []string{"default"},
+		Current:   "dev",
+	}, plan)
+
+	// dry-run copies nothing
+	names, err := dst.GetNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, names)
+
+	require.NoError(t, workspace.Migrate(src, dst))
+	names, err = dst.GetNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "dev"}, names)
+	current, err := dst.GetCurrent()
+	require.NoError(t, err)
+	assert.Equal(t, "dev", current)
+	migrated, err := dst.Get("dev")
+	require.NoError(t, err)
+	assert.Equal(t, dev, migrated)
+}
@@ -2,9 +2,13 @@ package storages
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 
+	"google.golang.org/api/googleapi"
 	"gopkg.in/yaml.v3"
 
 	googlestorage "cloud.google.com/go/storage"
@@ -19,7 +23,7 @@ type GoogleStorage struct {
 	// The prefix to store the workspaces files.
 	prefix string
 
-	meta *workspacesMetaData
+	metaCache
 }
 
 // NewGoogleStorage news google cloud workspace storage and init default workspace.
@@ -28,17 +32,15 @@ func NewGoogleStorage(bucket *googlestorage.BucketHandle, prefix string) (*Googl
 		bucket: *bucket,
 		prefix: prefix,
 	}
-	if err := s.readMeta(); err != nil {
-		return nil, err
-	}
-	return s, s.initDefaultWorkspaceIf()
+	return s, s.initDefaultWorkspaceIf(s)
 }
 
 func (s *GoogleStorage) Get(name string) (*v1.Workspace, error) {
+	meta := s.snapshot()
 	if name == "" {
-		name = s.meta.Current
+		name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, name) {
+	if !checkWorkspaceExistence(meta, name) {
 		return nil, ErrWorkspaceNotExist
 	}
 
@@ -62,23 +64,15 @@ func (s *GoogleStorage) Get(name string) (*v1.Workspace, error) {
 }
 
 func (s *GoogleStorage) Create(ws *v1.Workspace) error {
-	if checkWorkspaceExistence(s.meta, ws.Name) {
-		return ErrWorkspaceAlreadyExist
-	}
-
-	if err := s.writeWorkspace(ws); err != nil {
-		return err
-	}
-
-	addAvailableWorkspaces(s.meta, ws.Name)
-	return s.writeMeta()
+	return s.createWorkspace(s, ws)
 }
 
 func (s *GoogleStorage) Update(ws *v1.Workspace) error {
+	meta := s.snapshot()
 	if ws.Name == "" {
-		ws.Name = s.meta.Current
+		ws.Name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, ws.Name) {
+	if !checkWorkspaceExistence(meta, ws.Name) {
 		return ErrWorkspaceNotExist
 	}
 
@@ -86,127 +80,83 @@ func (s *GoogleStorage) Update(ws *v1.Workspace) error {
 }
 
 func (s *GoogleStorage) Delete(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return nil
-	}
-
-	obj := s.bucket.Object(s.prefix + "/" + name + yamlSuffix)
-	if err := obj.Delete(context.Background()); err != nil {
-		return fmt.Errorf("remove workspace in google storage failed: %w", err)
-	}
-
-	removeAvailableWorkspaces(s.meta, name)
-	return s.writeMeta()
+	return s.deleteWorkspace(s, name)
 }
 
 func (s *GoogleStorage) GetNames() ([]string, error) {
-	return s.meta.AvailableWorkspaces, nil
+	return s.snapshot().AvailableWorkspaces, nil
 }
 
 func (s *GoogleStorage) GetCurrent() (string, error) {
-	return s.meta.Current, nil
+	return s.snapshot().Current, nil
 }
 
 func (s *GoogleStorage) SetCurrent(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return ErrWorkspaceNotExist
-	}
-	s.meta.Current = name
-	return s.writeMeta()
+	return s.setCurrentWorkspace(s, name)
 }
 
-func (s *GoogleStorage) RenameWorkspace(oldName, newName string) (err error) {
-	if oldName == "" || newName == "" {
-		return fmt.Errorf("given name is empty")
-	}
-
-	// restore the old workspace name if the rename failed
-	defer func() {
-		if err != nil {
-			removeAvailableWorkspaces(s.meta, newName)
-			addAvailableWorkspaces(s.meta, oldName)
-			s.writeMeta()
-		}
-	}()
-
-	// update the meta file
-	removeAvailableWorkspaces(s.meta, oldName)
-	addAvailableWorkspaces(s.meta, newName)
-	if err = s.writeMeta(); err != nil {
-		return err
-	}
-
-	// rename the workspace file
-	oldObj := s.bucket.Object(s.prefix + "/" + oldName + yamlSuffix)
-	dstObj := s.bucket.Object(s.prefix + "/" + newName + yamlSuffix)
-	if _, err = dstObj.CopierFrom(oldObj).Run(context.Background()); err != nil {
-		return fmt.Errorf("rename workspace file failed: %w", err)
-	}
-	if err = oldObj.Delete(context.Background()); err != nil {
-		return fmt.Errorf("delete old workspace file failed: %w", err)
-	}
-
-	return nil
+func (s *GoogleStorage) RenameWorkspace(oldName, newName string) error {
+	return s.renameWorkspace(s, oldName, newName)
 }
 
-func (s *GoogleStorage) initDefaultWorkspaceIf() error {
-	if !checkWorkspaceExistence(s.meta, DefaultWorkspace) {
-		// if there is no default workspace, create one with empty workspace.
-		if err := s.writeWorkspace(&v1.Workspace{Name: DefaultWorkspace}); err != nil {
-			return err
-		}
-		addAvailableWorkspaces(s.meta, DefaultWorkspace)
-	}
-
-	if s.meta.Current == "" {
-		s.meta.Current = DefaultWorkspace
-	}
-	return s.writeMeta()
-}
-
-func (s *GoogleStorage) readMeta() error {
+// readMeta reads the workspaces metadata, whose version is the generation of the metadata object.
+func (s *GoogleStorage) readMeta() (*workspacesMetaData, string, error) {
 	ctx := context.Background()
 	obj := s.bucket.Object(s.prefix + "/" + metadataFile)
 	reader, err := obj.NewReader(ctx)
 	if err != nil {
 		if err == googlestorage.ErrObjectNotExist {
-			s.meta = &workspacesMetaData{}
-			return nil
+			return &workspacesMetaData{}, "", nil
 		}
-		return fmt.Errorf("get workspaces metadata from google failed: %w", err)
+		return nil, "", fmt.Errorf("get workspaces metadata from google failed: %w", err)
 	}
 	defer reader.Close()
 	content, err := io.ReadAll(reader)
 	if err != nil {
-		return fmt.Errorf("read workspaces meta data failed: %w", err)
+		return nil, "", fmt.Errorf("read workspaces meta data failed: %w", err)
 	}
+	generation := strconv.FormatInt(reader.Attrs.Generation, 10)
 	if len(content) == 0 {
-		s.meta = &workspacesMetaData{}
-		return nil
+		return &workspacesMetaData{}, generation, nil
 	}
 
 	meta := &workspacesMetaData{}
 	if err = yaml.Unmarshal(content, meta); err != nil {
-		return fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
+		return nil, "", fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
 	}
-	s.meta = meta
-	return nil
+	return meta, generation, nil
 }
 
-func (s *GoogleStorage) writeMeta() error {
+// writeMeta writes the workspaces metadata on condition that its generation is still the read one, or it still
+// does not exist.
+func (s *GoogleStorage) writeMeta(meta *workspacesMetaData, generation string) error {
+	conds := googlestorage.Conditions{DoesNotExist: true}
+	if generation != "" {
+		gen, err := strconv.ParseInt(generation, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse workspaces metadata generation failed: %w", err)
+		}
+		conds = googlestorage.Conditions{GenerationMatch: gen}
+	}
+
 	ctx := context.Background()
-	obj := s.bucket.Object(s.prefix + "/" + metadataFile)
-	content, err := yaml.Marshal(s.meta)
+	obj := s.bucket.Object(s.prefix + "/" + metadataFile).If(conds)
+	content, err := yaml.Marshal(meta)
 	if err != nil {
 		return fmt.Errorf("yaml marshal workspaces metadata failed: %w", err)
 	}
 
 	writer := obj.NewWriter(ctx)
 	if _, err = writer.Write(content); err != nil {
+		_ = writer.Close()
 		return fmt.Errorf("write workspaces metadata failed: %w", err)
 	}
 
 	if err = writer.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return errMetaConflict
+		}
 		return fmt.Errorf("close writer failed: %w", err)
 	}
 	return nil
@@ -229,3 +179,20 @@ func (s *GoogleStorage) writeWorkspace(ws *v1.Workspace) error {
 	}
 	return nil
 }
+
+func (s *GoogleStorage) removeWorkspace(name string) error {
+	obj := s.bucket.Object(s.prefix + "/" + name + yamlSuffix)
+	if err := obj.Delete(context.Background()); err != nil {
+		return fmt.Errorf("remove workspace in google storage failed: %w", err)
+	}
+	return nil
+}
+
+func (s *GoogleStorage) copyWorkspace(oldName, newName string) error {
+	src := s.bucket.Object(s.prefix + "/" + oldName + yamlSuffix)
+	dst := s.bucket.Object(s.prefix + "/" + newName + yamlSuffix)
+	if _, err := dst.CopierFrom(src).Run(context.Background()); err != nil {
+		return fmt.Errorf("copy workspace file failed: %w", err)
+	}
+	return nil
+}
@@ -43,12 +43,16 @@ func mockGoogleBucketHandle() *googlestorage.BucketHandle {
 
 func mockGoogleStorage() *GoogleStorage {
 	return &GoogleStorage{
-		bucket: *mockGoogleBucketHandle(),
-		prefix: "valid-prefix",
-		meta:   mockWorkspacesMetaData(),
+		bucket:    *mockGoogleBucketHandle(),
+		prefix:    "valid-prefix",
+		metaCache: metaCache{meta: mockWorkspacesMetaData()},
 	}
 }
 
+func mockGoogleStorageReadMeta() {
+	mockey.Mock((*GoogleStorage).readMeta).Return(mockWorkspacesMetaData(), "version", nil).Build()
+}
+
 func mockGoogleStorageWriteMeta() {
 	mockey.Mock((*GoogleStorage).writeMeta).Return(nil).Build()
 }
@@ -78,6 +82,7 @@ func TestGoogleStorage_Create(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock google storage operation", t, func() {
+				mockGoogleStorageReadMeta()
 				mockGoogleStorageWriteMeta()
 				mockGoogleStorageWriteWorkspace()
 				err := mockGoogleStorage().Create(tc.workspace)
@@ -133,6 +138,7 @@ func TestGoogleStorage_Delete(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock google storage operation", t, func() {
 				mockey.Mock((*googlestorage.ObjectHandle).Delete).Return(nil).Build()
+				mockGoogleStorageReadMeta()
 				mockGoogleStorageWriteMeta()
 				err := mockGoogleStorage().Delete(tc.wsName)
 				assert.Equal(t, tc.success, err == nil)
@@ -214,6 +220,7 @@ func TestGoogleStorage_SetCurrent(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock google storage operation", t, func() {
+				mockGoogleStorageReadMeta()
 				mockGoogleStorageWriteMeta()
 				err := mockGoogleStorage().SetCurrent(tc.current)
 				assert.Equal(t, tc.success, err == nil)
@@ -252,6 +259,7 @@ func TestGoogleStorage_RenameWorkspace(t *testing.T) {
 				mockey.Mock((*googlestorage.BucketHandle).Object).Return(&googlestorage.ObjectHandle{}).Build()
 				mockey.Mock((*googlestorage.ObjectHandle).NewWriter).Return(&mockWriter{}).Build()
 				mockey.Mock((*mockWriter).Close).Return(nil).Build()
+				mockGoogleStorageReadMeta()
 				mockGoogleStorageWriteMeta()
 				mockGoogleStorageWriteWorkspace()
 				storage := mockGoogleStorage()
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/gofrs/flock"
 	"gopkg.in/yaml.v3"
 
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
@@ -15,7 +16,7 @@ type LocalStorage struct {
 	// The directory path to store the workspace files.
 	path string
 
-	meta *workspacesMetaData
+	metaCache
 }
 
 // NewLocalStorage news local workspace storage and init default workspace.
@@ -26,19 +27,16 @@ func NewLocalStorage(path string) (*LocalStorage, error) {
 	if err := os.MkdirAll(s.path, os.ModePerm); err != nil {
 		return nil, fmt.Errorf("create workspace directory failed, %w", err)
 	}
-	// read workspaces metadata
-	if err := s.readMeta(); err != nil {
-		return nil, err
-	}
 
-	return s, s.initDefaultWorkspaceIf()
+	return s, s.initDefaultWorkspaceIf(s)
 }
 
 func (s *LocalStorage) Get(name string) (*v1.Workspace, error) {
+	meta := s.snapshot()
 	if name == "" {
-		name = s.meta.Current
+		name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, name) {
+	if !checkWorkspaceExistence(meta, name) {
 		return nil, ErrWorkspaceNotExist
 	}
 
@@ -56,23 +54,15 @@ func (s *LocalStorage) Get(name string) (*v1.Workspace, error) {
 }
 
 func (s *LocalStorage) Create(ws *v1.Workspace) error {
-	if checkWorkspaceExistence(s.meta, ws.Name) {
-		return ErrWorkspaceAlreadyExist
-	}
-
-	if err := s.writeWorkspace(ws); err != nil {
-		return err
-	}
-
-	addAvailableWorkspaces(s.meta, ws.Name)
-	return s.writeMeta()
+	return s.createWorkspace(s, ws)
 }
 
 func (s *LocalStorage) Update(ws *v1.Workspace) error {
+	meta := s.snapshot()
 	if ws.Name == "" {
-		ws.Name = s.meta.Current
+		ws.Name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, ws.Name) {
+	if !checkWorkspaceExistence(meta, ws.Name) {
 		return ErrWorkspaceNotExist
 	}
 
@@ -80,107 +70,66 @@ func (s *LocalStorage) Update(ws *v1.Workspace) error {
 }
 
 func (s *LocalStorage) Delete(name string) error {
-	if name == "" {
-		name = s.meta.Current
-	}
-	if !checkWorkspaceExistence(s.meta, name) {
-		return nil
-	}
-
-	if err := os.Remove(filepath.Join(s.path, name+yamlSuffix)); err != nil {
-		return fmt.Errorf("remove workspace file failed: %w", err)
-	}
-
-	removeAvailableWorkspaces(s.meta, name)
-	return s.writeMeta()
+	return s.deleteWorkspace(s, name)
 }
 
 func (s *LocalStorage) GetNames() ([]string, error) {
-	return s.meta.AvailableWorkspaces, nil
+	return s.snapshot().AvailableWorkspaces, nil
 }
 
 func (s *LocalStorage) GetCurrent() (string, error) {
-	return s.meta.Current, nil
+	return s.snapshot().Current, nil
 }
 
 func (s *LocalStorage) SetCurrent(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return ErrWorkspaceNotExist
-	}
-	s.meta.Current = name
-	return s.writeMeta()
+	return s.setCurrentWorkspace(s, name)
 }
 
-func (s *LocalStorage) RenameWorkspace(oldName, newName string) (err error) {
-	if oldName == "" || newName == "" {
-		return fmt.Errorf("given name is empty")
-	}
-
-	// restore the old workspace name if the rename failed
-	defer func() {
-		if err != nil {
-			removeAvailableWorkspaces(s.meta, newName)
-			addAvailableWorkspaces(s.meta, oldName)
-			s.writeMeta()
-		}
-	}()
-
-	// update the meta file
-	removeAvailableWorkspaces(s.meta, oldName)
-	addAvailableWorkspaces(s.meta, newName)
-	if err = s.writeMeta(); err != nil {
-		return err
-	}
-
-	// rename the workspace file
-	oldPath := filepath.Join(s.path, oldName+yamlSuffix)
-	newPath := filepath.Join(s.path, newName+yamlSuffix)
-	if err = os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("rename workspace file failed: %w", err)
-	}
-
-	return nil
+func (s *LocalStorage) RenameWorkspace(oldName, newName string) error {
+	return s.renameWorkspace(s, oldName, newName)
 }
 
-func (s *LocalStorage) initDefaultWorkspaceIf() error {
-	if !checkWorkspaceExistence(s.meta, DefaultWorkspace) {
-		// if there is no default workspace, create one with empty workspace.
-		if err := s.writeWorkspace(&v1.Workspace{Name: DefaultWorkspace}); err != nil {
-			return err
-		}
-		addAvailableWorkspaces(s.meta, DefaultWorkspace)
+// lockMeta locks the workspaces metadata with a file lock, which excludes the updates from the other storages
+// and processes on the same directory.
+func (s *LocalStorage) lockMeta() (func() error, error) {
+	lock := flock.New(filepath.Join(s.path, metadataLockFile))
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("lock workspaces metadata file failed: %w", err)
 	}
-
-	if s.meta.Current == "" {
-		s.meta.Current = DefaultWorkspace
-	}
-	return s.writeMeta()
+	return lock.Unlock, nil
 }
 
-func (s *LocalStorage) readMeta() error {
+// readMeta reads the workspaces metadata, whose version is always empty as the metadata is locked by lockMeta
+// across the read-modify-write.
+func (s *LocalStorage) readMeta() (*workspacesMetaData, string, error) {
 	content, err := os.ReadFile(filepath.Join(s.path, metadataFile))
 	if os.IsNotExist(err) {
-		s.meta = &workspacesMetaData{}
-		return nil
+		return &workspacesMetaData{}, "", nil
 	} else if err != nil {
-		return fmt.Errorf("read workspace metadata file failed: %w", err)
+		return nil, "", fmt.Errorf("read workspace metadata file failed: %w", err)
 	}
 
 	meta := &workspacesMetaData{}
 	if err = yaml.Unmarshal(content, meta); err != nil {
-		return fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
+		return nil, "", fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
 	}
-	s.meta = meta
-	return nil
+	return meta, "", nil
 }
 
-func (s *LocalStorage) writeMeta() error {
-	content, err := yaml.Marshal(s.meta)
+func (s *LocalStorage) writeMeta(meta *workspacesMetaData, _ string) error {
+	content, err := yaml.Marshal(meta)
 	if err != nil {
 		return fmt.Errorf("yaml marshal workspaces metadata failed: %w", err)
 	}
 
-	if err = os.WriteFile(filepath.Join(s.path, metadataFile), content, os.ModePerm); err != nil {
+	// write to a temporary file and rename it, so that the metadata file is never observed half-written.
+	metaPath := filepath.Join(s.path, metadataFile)
+	tmpPath := metaPath + ".tmp"
+	if err = os.WriteFile(tmpPath, content, os.ModePerm); err != nil {
+		return fmt.Errorf("write workspaces metadata file failed: %w", err)
+	}
+	if err = os.Rename(tmpPath, metaPath); err != nil {
+		_ = os.Remove(tmpPath)
 		return fmt.Errorf("write workspaces metadata file failed: %w", err)
 	}
 	return nil
@@ -197,3 +146,21 @@ func (s *LocalStorage) writeWorkspace(ws *v1.Workspace) error {
 	}
 	return nil
 }
+
+func (s *LocalStorage) removeWorkspace(name string) error {
+	if err := os.Remove(filepath.Join(s.path, name+yamlSuffix)); err != nil {
+		return fmt.Errorf("remove workspace file failed: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) copyWorkspace(oldName, newName string) error {
+	content, err := os.ReadFile(filepath.Join(s.path, oldName+yamlSuffix))
+	if err != nil {
+		return fmt.Errorf("read workspace file failed: %w", err)
+	}
+	if err = os.WriteFile(filepath.Join(s.path, newName+yamlSuffix), content, os.ModePerm); err != nil {
+		return fmt.Errorf("write workspace file failed: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package storages
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -360,6 +362,69 @@ func TestLocalStorage_SetCurrent(t *testing.T) {
 	}
 }
 
+func TestLocalStorage_SetCurrentConcurrently(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+	for _, name := range []string{"dev", "prod"} {
+		assert.NoError(t, s.Create(&v1.Workspace{Name: name}))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		name := "dev"
+		if i%2 == 0 {
+			name = "prod"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.SetCurrent(name))
+		}()
+	}
+	wg.Wait()
+
+	current, err := s.GetCurrent()
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"dev", "prod"}, current)
+
+	// the persisted metadata should be consistent with the in-memory one.
+	reloaded, err := NewLocalStorage(s.path)
+	assert.NoError(t, err)
+	persisted, err := reloaded.GetCurrent()
+	assert.NoError(t, err)
+	assert.Equal(t, current, persisted)
+}
+
+func TestLocalStorage_CreateConcurrently(t *testing.T) {
+	path := t.TempDir()
+	s, err := NewLocalStorage(path)
+	assert.NoError(t, err)
+	// another storage on the same directory, whose in-memory metadata gets stale.
+	other, err := NewLocalStorage(path)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var expected []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("ws-%d", i)
+		expected = append(expected, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Create(&v1.Workspace{Name: name}))
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, other.Create(&v1.Workspace{Name: "other"}))
+
+	// none of the workspaces should be lost in the persisted metadata.
+	reloaded, err := NewLocalStorage(path)
+	assert.NoError(t, err)
+	names, err := reloaded.GetNames()
+	assert.NoError(t, err)
+	assert.Subset(t, names, append(expected, DefaultWorkspace, "other"))
+}
+
 func TestLocalStorage_RenameWorkspace(t *testing.T) {
 	testcases := []struct {
 		name    string
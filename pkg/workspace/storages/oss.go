@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"gopkg.in/yaml.v3"
@@ -18,7 +19,7 @@ type OssStorage struct {
 	// The prefix to store the workspaces files.
 	prefix string
 
-	meta *workspacesMetaData
+	metaCache
 }
 
 // NewOssStorage news oss workspace storage and init default workspace.
@@ -27,17 +28,15 @@ func NewOssStorage(bucket *oss.Bucket, prefix string) (*OssStorage, error) {
 		bucket: bucket,
 		prefix: prefix,
 	}
-	if err := s.readMeta(); err != nil {
-		return nil, err
-	}
-	return s, s.initDefaultWorkspaceIf()
+	return s, s.initDefaultWorkspaceIf(s)
 }
 
 func (s *OssStorage) Get(name string) (*v1.Workspace, error) {
+	meta := s.snapshot()
 	if name == "" {
-		name = s.meta.Current
+		name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, name) {
+	if !checkWorkspaceExistence(meta, name) {
 		return nil, ErrWorkspaceNotExist
 	}
 
@@ -62,23 +61,15 @@ func (s *OssStorage) Get(name string) (*v1.Workspace, error) {
 }
 
 func (s *OssStorage) Create(ws *v1.Workspace) error {
-	if checkWorkspaceExistence(s.meta, ws.Name) {
-		return ErrWorkspaceAlreadyExist
-	}
-
-	if err := s.writeWorkspace(ws); err != nil {
-		return err
-	}
-
-	addAvailableWorkspaces(s.meta, ws.Name)
-	return s.writeMeta()
+	return s.createWorkspace(s, ws)
 }
 
 func (s *OssStorage) Update(ws *v1.Workspace) error {
+	meta := s.snapshot()
 	if ws.Name == "" {
-		ws.Name = s.meta.Current
+		ws.Name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, ws.Name) {
+	if !checkWorkspaceExistence(meta, ws.Name) {
 		return ErrWorkspaceNotExist
 	}
 
@@ -86,107 +77,36 @@ func (s *OssStorage) Update(ws *v1.Workspace) error {
 }
 
 func (s *OssStorage) Delete(name string) error {
-	if name == "" {
-		name = s.meta.Current
-	}
-	if !checkWorkspaceExistence(s.meta, name) {
-		return nil
-	}
-
-	if err := s.bucket.DeleteObject(s.prefix + "/" + name + yamlSuffix); err != nil {
-		return fmt.Errorf("remove workspace in oss failed: %w", err)
-	}
-
-	removeAvailableWorkspaces(s.meta, name)
-	return s.writeMeta()
+	return s.deleteWorkspace(s, name)
 }
 
 func (s *OssStorage) GetNames() ([]string, error) {
-	return s.meta.AvailableWorkspaces, nil
+	return s.snapshot().AvailableWorkspaces, nil
 }
 
 func (s *OssStorage) GetCurrent() (string, error) {
-	return s.meta.Current, nil
+	return s.snapshot().Current, nil
 }
 
 func (s *OssStorage) SetCurrent(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return ErrWorkspaceNotExist
-	}
-	s.meta.Current = name
-	return s.writeMeta()
-}
-
-func (s *OssStorage) RenameWorkspace(oldName, newName string) (err error) {
-	if oldName == "" || newName == "" {
-		return fmt.Errorf("given name is empty")
-	}
-
-	// restore the old workspace name if the rename failed
-	defer func() {
-		if err != nil {
-			removeAvailableWorkspaces(s.meta, newName)
-			addAvailableWorkspaces(s.meta, oldName)
-			s.writeMeta()
-		}
-	}()
-
-	// update the meta file
-	removeAvailableWorkspaces(s.meta, oldName)
-	addAvailableWorkspaces(s.meta, newName)
-	if err = s.writeMeta(); err != nil {
-		return err
-	}
-
-	// rename the workspace file by copying and deleting
-	oldPath := s.prefix + "/" + oldName + yamlSuffix
-	newPath := s.prefix + "/" + newName + yamlSuffix
-
-	// get old workspace content
-	body, err := s.bucket.GetObject(oldPath)
-	if err != nil {
-		return fmt.Errorf("get old workspace failed: %w", err)
-	}
-	defer body.Close()
-
-	// copy to new path
-	if err = s.bucket.PutObject(newPath, body); err != nil {
-		return fmt.Errorf("copy workspace failed: %w", err)
-	}
-
-	// delete old file
-	if err = s.bucket.DeleteObject(oldPath); err != nil {
-		return fmt.Errorf("delete old workspace failed: %w", err)
-	}
-
-	return nil
+	return s.setCurrentWorkspace(s, name)
 }
 
-func (s *OssStorage) initDefaultWorkspaceIf() error {
-	if !checkWorkspaceExistence(s.meta, DefaultWorkspace) {
-		// if there is no default workspace, create one with empty workspace.
-		if err := s.writeWorkspace(&v1.Workspace{Name: DefaultWorkspace}); err != nil {
-			return err
-		}
-		addAvailableWorkspaces(s.meta, DefaultWorkspace)
-	}
-
-	if s.meta.Current == "" {
-		s.meta.Current = DefaultWorkspace
-	}
-	return s.writeMeta()
+func (s *OssStorage) RenameWorkspace(oldName, newName string) error {
+	return s.renameWorkspace(s, oldName, newName)
 }
 
-func (s *OssStorage) readMeta() error {
-	body, err := s.bucket.GetObject(s.prefix + "/" + metadataFile)
+// readMeta reads the workspaces metadata, whose version is the ETag of the metadata object.
+func (s *OssStorage) readMeta() (*workspacesMetaData, string, error) {
+	var header http.Header
+	body, err := s.bucket.GetObject(s.prefix+"/"+metadataFile, oss.GetResponseHeader(&header))
 	if err != nil {
 		ossErr, ok := err.(oss.ServiceError)
 		// error code ref: github.com/aliyun/aliyun-oss-go-sdk@v2.1.8+incompatible/oss/bucket.go:553
 		if ok && ossErr.StatusCode == 404 {
-			s.meta = &workspacesMetaData{}
-			return nil
+			return &workspacesMetaData{}, "", nil
 		}
-		return fmt.Errorf("get workspaces metadata from oss failed: %w", err)
+		return nil, "", fmt.Errorf("get workspaces metadata from oss failed: %w", err)
 	}
 	defer func() {
 		_ = body.Close()
@@ -194,28 +114,37 @@ func (s *OssStorage) readMeta() error {
 
 	content, err := io.ReadAll(body)
 	if err != nil {
-		return fmt.Errorf("read workspaces metadata failed: %w", err)
+		return nil, "", fmt.Errorf("read workspaces metadata failed: %w", err)
 	}
+	etag := header.Get(oss.HTTPHeaderEtag)
 	if len(content) == 0 {
-		s.meta = &workspacesMetaData{}
-		return nil
+		return &workspacesMetaData{}, etag, nil
 	}
 
 	meta := &workspacesMetaData{}
 	if err = yaml.Unmarshal(content, meta); err != nil {
-		return fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
+		return nil, "", fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
 	}
-	s.meta = meta
-	return nil
+	return meta, etag, nil
 }
 
-func (s *OssStorage) writeMeta() error {
-	content, err := yaml.Marshal(s.meta)
+// writeMeta puts the workspaces metadata on condition that its ETag is still the read one, or it is not
+// overwritten if it did not exist.
+func (s *OssStorage) writeMeta(meta *workspacesMetaData, etag string) error {
+	content, err := yaml.Marshal(meta)
 	if err != nil {
 		return fmt.Errorf("yaml marshal workspaces metadata failed: %w", err)
 	}
 
-	if err = s.bucket.PutObject(s.prefix+"/"+metadataFile, bytes.NewReader(content)); err != nil {
+	precondition := oss.ForbidOverWrite(true)
+	if etag != "" {
+		precondition = oss.IfMatch(etag)
+	}
+	if err = s.bucket.PutObject(s.prefix+"/"+metadataFile, bytes.NewReader(content), precondition); err != nil {
+		ossErr, ok := err.(oss.ServiceError)
+		if ok && (ossErr.StatusCode == http.StatusPreconditionFailed || ossErr.StatusCode == http.StatusConflict) {
+			return errMetaConflict
+		}
 		return fmt.Errorf("put workspaces metadata to oss failed: %w", err)
 	}
 	return nil
@@ -232,3 +161,17 @@ func (s *OssStorage) writeWorkspace(ws *v1.Workspace) error {
 	}
 	return nil
 }
+
+func (s *OssStorage) removeWorkspace(name string) error {
+	if err := s.bucket.DeleteObject(s.prefix + "/" + name + yamlSuffix); err != nil {
+		return fmt.Errorf("remove workspace in oss failed: %w", err)
+	}
+	return nil
+}
+
+func (s *OssStorage) copyWorkspace(oldName, newName string) error {
+	if _, err := s.bucket.CopyObject(s.prefix+"/"+oldName+yamlSuffix, s.prefix+"/"+newName+yamlSuffix); err != nil {
+		return fmt.Errorf("copy workspace failed: %w", err)
+	}
+	return nil
+}
@@ -12,7 +12,11 @@ import (
 )
 
 func mockOssStorage(meta *workspacesMetaData) *OssStorage {
-	return &OssStorage{bucket: &oss.Bucket{}, meta: meta}
+	return &OssStorage{bucket: &oss.Bucket{}, metaCache: metaCache{meta: meta}}
+}
+
+func mockOssStorageReadMeta() {
+	mockey.Mock((*OssStorage).readMeta).Return(mockWorkspacesMetaData(), "version", nil).Build()
 }
 
 func mockOssStorageWriteMeta() {
@@ -74,6 +78,7 @@ func TestOssStorage_Create(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock oss operation", t, func() {
+				mockOssStorageReadMeta()
 				mockOssStorageWriteMeta()
 				mockOssStorageWriteWorkspace()
 				err := mockOssStorage(mockWorkspacesMetaData()).Create(tc.workspace)
@@ -129,6 +134,7 @@ func TestOssStorage_Delete(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock oss operation", t, func() {
 				mockey.Mock(oss.Bucket.DeleteObject).Return(nil).Build()
+				mockOssStorageReadMeta()
 				mockOssStorageWriteMeta()
 				err := mockOssStorage(mockWorkspacesMetaData()).Delete(tc.wsName)
 				assert.Equal(t, tc.success, err == nil)
@@ -210,6 +216,7 @@ func TestOssStorage_SetCurrent(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock oss operation", t, func() {
+				mockOssStorageReadMeta()
 				mockOssStorageWriteMeta()
 				err := mockOssStorage(mockWorkspacesMetaData()).SetCurrent(tc.current)
 				assert.Equal(t, tc.success, err == nil)
@@ -242,9 +249,10 @@ func TestOssStorage_RenameWorkspace(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock oss operation", t, func() {
-				mockey.Mock(oss.Bucket.GetObject).Return(io.NopCloser(bytes.NewReader([]byte(""))), nil).Build()
-				mockey.Mock(oss.Bucket.PutObject).Return(nil).Build()
+				mockey.Mock(oss.Bucket.CopyObject).Return(oss.CopyObjectResult{}, nil).Build()
 				mockey.Mock(oss.Bucket.DeleteObject).Return(nil).Build()
+				mockOssStorageReadMeta()
+				mockOssStorageWriteMeta()
 				err := mockOssStorage(mockWorkspacesMetaData()).RenameWorkspace(tc.oldName, tc.newName)
 				assert.Equal(t, tc.success, err == nil)
 			})
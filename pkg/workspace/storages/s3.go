@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -21,7 +22,7 @@ type S3Storage struct {
 	// The prefix to store the workspaces files.
 	prefix string
 
-	meta *workspacesMetaData
+	metaCache
 }
 
 // NewS3Storage news s3 workspace storage and init default workspace.
@@ -31,17 +32,15 @@ func NewS3Storage(s3 *s3.S3, bucket, prefix string) (*S3Storage, error) {
 		bucket: bucket,
 		prefix: prefix,
 	}
-	if err := s.readMeta(); err != nil {
-		return nil, err
-	}
-	return s, s.initDefaultWorkspaceIf()
+	return s, s.initDefaultWorkspaceIf(s)
 }
 
 func (s *S3Storage) Get(name string) (*v1.Workspace, error) {
+	meta := s.snapshot()
 	if name == "" {
-		name = s.meta.Current
+		name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, name) {
+	if !checkWorkspaceExistence(meta, name) {
 		return nil, ErrWorkspaceNotExist
 	}
 
@@ -71,23 +70,15 @@ func (s *S3Storage) Get(name string) (*v1.Workspace, error) {
 }
 
 func (s *S3Storage) Create(ws *v1.Workspace) error {
-	if checkWorkspaceExistence(s.meta, ws.Name) {
-		return ErrWorkspaceAlreadyExist
-	}
-
-	if err := s.writeWorkspace(ws); err != nil {
-		return err
-	}
-
-	addAvailableWorkspaces(s.meta, ws.Name)
-	return s.writeMeta()
+	return s.createWorkspace(s, ws)
 }
 
 func (s *S3Storage) Update(ws *v1.Workspace) error {
+	meta := s.snapshot()
 	if ws.Name == "" {
-		ws.Name = s.meta.Current
+		ws.Name = meta.Current
 	}
-	if !checkWorkspaceExistence(s.meta, ws.Name) {
+	if !checkWorkspaceExistence(meta, ws.Name) {
 		return ErrWorkspaceNotExist
 	}
 
@@ -95,101 +86,27 @@ func (s *S3Storage) Update(ws *v1.Workspace) error {
 }
 
 func (s *S3Storage) Delete(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return nil
-	}
-
-	input := &s3.DeleteObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(s.prefix + "/" + name + yamlSuffix),
-	}
-	if _, err := s.s3.DeleteObject(input); err != nil {
-		return fmt.Errorf("remove workspace in s3 failed: %w", err)
-	}
-
-	removeAvailableWorkspaces(s.meta, name)
-	return s.writeMeta()
+	return s.deleteWorkspace(s, name)
 }
 
 func (s *S3Storage) GetNames() ([]string, error) {
-	return s.meta.AvailableWorkspaces, nil
+	return s.snapshot().AvailableWorkspaces, nil
 }
 
 func (s *S3Storage) GetCurrent() (string, error) {
-	return s.meta.Current, nil
+	return s.snapshot().Current, nil
 }
 
 func (s *S3Storage) SetCurrent(name string) error {
-	if !checkWorkspaceExistence(s.meta, name) {
-		return ErrWorkspaceNotExist
-	}
-	s.meta.Current = name
-	return s.writeMeta()
-}
-
-func (s *S3Storage) RenameWorkspace(oldName, newName string) (err error) {
-	if oldName == "" || newName == "" {
-		return fmt.Errorf("given name is empty")
-	}
-
-	// restore the old workspace name if the rename failed
-	defer func() {
-		if err != nil {
-			removeAvailableWorkspaces(s.meta, newName)
-			addAvailableWorkspaces(s.meta, oldName)
-			s.writeMeta()
-		}
-	}()
-
-	// update the meta file
-	removeAvailableWorkspaces(s.meta, oldName)
-	addAvailableWorkspaces(s.meta, newName)
-	if err = s.writeMeta(); err != nil {
-		return err
-	}
-
-	// rename the workspace file by copying and deleting
-	oldPath := s.prefix + "/" + oldName + yamlSuffix
-	newPath := s.prefix + "/" + newName + yamlSuffix
-
-	// copy to new path
-	copyInput := &s3.CopyObjectInput{
-		Bucket:     aws.String(s.bucket),
-		CopySource: aws.String(s.bucket + "/" + oldPath),
-		Key:        aws.String(newPath),
-	}
-	if _, err = s.s3.CopyObject(copyInput); err != nil {
-		return fmt.Errorf("copy workspace failed: %w", err)
-	}
-
-	// delete old file
-	deleteInput := &s3.DeleteObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(oldPath),
-	}
-	if _, err = s.s3.DeleteObject(deleteInput); err != nil {
-		return fmt.Errorf("delete old workspace failed: %w", err)
-	}
-
-	return nil
+	return s.setCurrentWorkspace(s, name)
 }
 
-func (s *S3Storage) initDefaultWorkspaceIf() error {
-	if !checkWorkspaceExistence(s.meta, DefaultWorkspace) {
-		// if there is no default workspace, create one with empty workspace.
-		if err := s.writeWorkspace(&v1.Workspace{Name: DefaultWorkspace}); err != nil {
-			return err
-		}
-		addAvailableWorkspaces(s.meta, DefaultWorkspace)
-	}
-
-	if s.meta.Current == "" {
-		s.meta.Current = DefaultWorkspace
-	}
-	return s.writeMeta()
+func (s *S3Storage) RenameWorkspace(oldName, newName string) error {
+	return s.renameWorkspace(s, oldName, newName)
 }
 
-func (s *S3Storage) readMeta() error {
+// readMeta reads the workspaces metadata, whose version is the ETag of the metadata object.
+func (s *S3Storage) readMeta() (*workspacesMetaData, string, error) {
 	key := s.prefix + "/" + metadataFile
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -199,10 +116,9 @@ func (s *S3Storage) readMeta() error {
 	if err != nil {
 		awsErr, ok := err.(awserr.Error)
 		if ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
-			s.meta = &workspacesMetaData{}
-			return nil
+			return &workspacesMetaData{}, "", nil
 		}
-		return fmt.Errorf("get workspaces meta data from s3 failed: %w", err)
+		return nil, "", fmt.Errorf("get workspaces meta data from s3 failed: %w", err)
 	}
 	defer func() {
 		_ = output.Body.Close()
@@ -210,23 +126,24 @@ func (s *S3Storage) readMeta() error {
 
 	content, err := io.ReadAll(output.Body)
 	if err != nil {
-		return fmt.Errorf("read workspaces meta data failed: %w", err)
+		return nil, "", fmt.Errorf("read workspaces meta data failed: %w", err)
 	}
+	etag := aws.StringValue(output.ETag)
 	if len(content) == 0 {
-		s.meta = &workspacesMetaData{}
-		return nil
+		return &workspacesMetaData{}, etag, nil
 	}
 
 	meta := &workspacesMetaData{}
 	if err = yaml.Unmarshal(content, meta); err != nil {
-		return fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
+		return nil, "", fmt.Errorf("yaml unmarshal workspaces metadata failed: %w", err)
 	}
-	s.meta = meta
-	return nil
+	return meta, etag, nil
 }
 
-func (s *S3Storage) writeMeta() error {
-	content, err := yaml.Marshal(s.meta)
+// writeMeta puts the workspaces metadata with the If-Match precondition on the read ETag, or If-None-Match if
+// the metadata did not exist.
+func (s *S3Storage) writeMeta(meta *workspacesMetaData, etag string) error {
+	content, err := yaml.Marshal(meta)
 	if err != nil {
 		return fmt.Errorf("yaml marshal workspaces metadata failed: %w", err)
 	}
@@ -236,7 +153,18 @@ func (s *S3Storage) writeMeta() error {
 		Key:    aws.String(s.prefix + "/" + metadataFile),
 		Body:   bytes.NewReader(content),
 	}
-	if _, err = s.s3.PutObject(input); err != nil {
+	// the conditional headers are set on the request directly, as PutObjectInput of this sdk version lacks them.
+	req, _ := s.s3.PutObjectRequest(input)
+	if etag == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", etag)
+	}
+	if err = req.Send(); err != nil {
+		reqErr, ok := err.(awserr.RequestFailure)
+		if ok && (reqErr.StatusCode() == http.StatusPreconditionFailed || reqErr.StatusCode() == http.StatusConflict) {
+			return errMetaConflict
+		}
 		return fmt.Errorf("put workspaces metadata to s3 failed: %w", err)
 	}
 	return nil
@@ -258,3 +186,26 @@ func (s *S3Storage) writeWorkspace(ws *v1.Workspace) error {
 	}
 	return nil
 }
+
+func (s *S3Storage) removeWorkspace(name string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + "/" + name + yamlSuffix),
+	}
+	if _, err := s.s3.DeleteObject(input); err != nil {
+		return fmt.Errorf("remove workspace in s3 failed: %w", err)
+	}
+	return nil
+}
+
+func (s *S3Storage) copyWorkspace(oldName, newName string) error {
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		CopySource: aws.String(s.bucket + "/" + s.prefix + "/" + oldName + yamlSuffix),
+		Key:        aws.String(s.prefix + "/" + newName + yamlSuffix),
+	}
+	if _, err := s.s3.CopyObject(input); err != nil {
+		return fmt.Errorf("copy workspace failed: %w", err)
+	}
+	return nil
+}
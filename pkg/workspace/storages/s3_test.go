@@ -12,7 +12,11 @@ import (
 )
 
 func mockS3Storage(meta *workspacesMetaData) *S3Storage {
-	return &S3Storage{s3: &s3.S3{}, meta: meta}
+	return &S3Storage{s3: &s3.S3{}, metaCache: metaCache{meta: meta}}
+}
+
+func mockS3StorageReadMeta() {
+	mockey.Mock((*S3Storage).readMeta).Return(mockWorkspacesMetaData(), "version", nil).Build()
 }
 
 func mockS3StorageWriteMeta() {
@@ -76,6 +80,7 @@ func TestS3Storage_Create(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock s3 operation", t, func() {
+				mockS3StorageReadMeta()
 				mockS3StorageWriteMeta()
 				mockS3StorageWriteWorkspace()
 				err := mockS3Storage(mockWorkspacesMetaData()).Create(tc.workspace)
@@ -131,6 +136,7 @@ func TestS3Storage_Delete(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock s3 operation", t, func() {
 				mockey.Mock((*s3.S3).DeleteObject).Return(nil, nil).Build()
+				mockS3StorageReadMeta()
 				mockS3StorageWriteMeta()
 				err := mockS3Storage(mockWorkspacesMetaData()).Delete(tc.wsName)
 				assert.Equal(t, tc.success, err == nil)
@@ -212,6 +218,7 @@ func TestS3Storage_SetCurrent(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock s3 operation", t, func() {
+				mockS3StorageReadMeta()
 				mockS3StorageWriteMeta()
 				err := mockS3Storage(mockWorkspacesMetaData()).SetCurrent(tc.current)
 				assert.Equal(t, tc.success, err == nil)
@@ -245,8 +252,9 @@ func TestS3Storage_RenameWorkspace(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockey.PatchConvey("mock s3 operation", t, func() {
 				mockey.Mock((*s3.S3).CopyObject).Return(&s3.CopyObjectOutput{}, nil).Build()
-				mockey.Mock((*s3.S3).PutObject).Return(&s3.PutObjectOutput{}, nil).Build()
 				mockey.Mock((*s3.S3).DeleteObject).Return(&s3.DeleteObjectOutput{}, nil).Build()
+				mockS3StorageReadMeta()
+				mockS3StorageWriteMeta()
 				err := mockS3Storage(mockWorkspacesMetaData()).RenameWorkspace(tc.oldName, tc.newName)
 				assert.Equal(t, tc.success, err == nil)
 			})
@@ -4,7 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+
+	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 )
 
 const (
@@ -12,12 +17,19 @@ const (
 
 	workspacesPrefix = "workspaces"
 	metadataFile     = ".metadata.yml"
+	metadataLockFile = ".metadata.yml.lock"
 	yamlSuffix       = ".yaml"
+
+	// maxMetaUpdateAttempts is the max times to update the workspaces metadata when it is modified concurrently.
+	maxMetaUpdateAttempts = 5
 )
 
 var (
 	ErrWorkspaceNotExist     = errors.New("workspace does not exist")
 	ErrWorkspaceAlreadyExist = errors.New("workspace has already existed")
+
+	// errMetaConflict is returned by metaStore.writeMeta if the workspaces metadata has been modified since it was read.
+	errMetaConflict = errors.New("workspaces metadata has been modified concurrently")
 )
 
 // GenWorkspaceDirPath generates the workspace directory path, which is used for LocalStorage.
@@ -40,10 +52,231 @@ type workspacesMetaData struct {
 	// The name of Current workspace.
 	Current string `yaml:"current,omitempty" json:"current,omitempty"`
 
-	// AvailableWorkspaces is the name list of all the existing workspaces.
+	// AvailableWorkspaces is the name list of all the existing workspaces, which is kept sorted
+	// to make the serialization result stable.
 	AvailableWorkspaces []string `yaml:"availableWorkspaces,omitempty" json:"availableWorkspaces,omitempty"`
 }
 
+// deepCopy returns a copy of the workspaces metadata which shares nothing with the original one.
+func (m *workspacesMetaData) deepCopy() *workspacesMetaData {
+	return &workspacesMetaData{
+		Current:             m.Current,
+		AvailableWorkspaces: append([]string(nil), m.AvailableWorkspaces...),
+	}
+}
+
+// metaStore is the persistence of the workspaces metadata which supports the conditional write.
+type metaStore interface {
+	// readMeta reads the workspaces metadata along with its version, e.g. the ETag of the metadata object,
+	// and the version is empty if the metadata does not exist.
+	readMeta() (*workspacesMetaData, string, error)
+
+	// writeMeta writes the workspaces metadata only if its version is still the read one, or it still does not
+	// exist for the empty version, otherwise returns errMetaConflict.
+	writeMeta(meta *workspacesMetaData, version string) error
+}
+
+// metaLocker is implemented by the metaStore which cannot write conditionally, and locks the workspaces metadata
+// across the read-modify-write instead.
+type metaLocker interface {
+	lockMeta() (unlock func() error, err error)
+}
+
+// workspaceStore is the persistence of the workspaces metadata and files, which LocalStorage, OssStorage,
+// S3Storage and GoogleStorage implement to share the operations on the workspaces metadata.
+type workspaceStore interface {
+	metaStore
+
+	// writeWorkspace writes the workspace file.
+	writeWorkspace(ws *v1.Workspace) error
+
+	// removeWorkspace removes the workspace file.
+	removeWorkspace(name string) error
+
+	// copyWorkspace copies the workspace file of oldName to newName.
+	copyWorkspace(oldName, newName string) error
+}
+
+// metaCache caches the workspaces metadata of a storage, which gets refreshed by every update of the metadata.
+type metaCache struct {
+	mu   sync.Mutex
+	meta *workspacesMetaData
+}
+
+// snapshot returns a copy of the cached workspaces metadata.
+func (c *metaCache) snapshot() *workspacesMetaData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.meta.deepCopy()
+}
+
+// updateMeta reads the workspaces metadata from the store, applies the update and writes it back on condition that
+// the metadata has not been modified in the meantime, and retries on the conflict so that the concurrent updates
+// from other storages or processes are not lost. The update may be called several times, and should not have any
+// side effect other than modifying the given metadata. The cache gets refreshed once the update succeeds.
+func (c *metaCache) updateMeta(store metaStore, update func(meta *workspacesMetaData) error) error {
+	if locker, ok := store.(metaLocker); ok {
+		unlock, err := locker.lockMeta()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = unlock()
+		}()
+	}
+
+	for attempt := 1; ; attempt++ {
+		meta, version, err := store.readMeta()
+		if err != nil {
+			return err
+		}
+		original := meta.deepCopy()
+		if err = update(meta); err != nil {
+			return err
+		}
+
+		// skip the write if nothing changes, e.g. the default workspace has already been initialized.
+		if !reflect.DeepEqual(original, meta.deepCopy()) {
+			err = store.writeMeta(meta, version)
+		}
+		if err == nil {
+			c.mu.Lock()
+			c.meta = meta
+			c.mu.Unlock()
+			return nil
+		}
+		if !errors.Is(err, errMetaConflict) || attempt >= maxMetaUpdateAttempts {
+			return err
+		}
+	}
+}
+
+// createWorkspace adds the workspace to the metadata and writes the workspace file.
+func (c *metaCache) createWorkspace(store workspaceStore, ws *v1.Workspace) error {
+	// claim the name in the metadata before writing the workspace file, so that a concurrent creation of the
+	// same workspace fails rather than overwrites the file.
+	err := c.updateMeta(store, func(meta *workspacesMetaData) error {
+		if checkWorkspaceExistence(meta, ws.Name) {
+			return ErrWorkspaceAlreadyExist
+		}
+		addAvailableWorkspaces(meta, ws.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = store.writeWorkspace(ws); err != nil {
+		c.releaseWorkspace(store, ws.Name)
+		return err
+	}
+	return nil
+}
+
+// releaseWorkspace removes the claimed workspace name from the metadata on a best-effort basis, which is called
+// if the workspace file fails to be written.
+func (c *metaCache) releaseWorkspace(store workspaceStore, name string) {
+	_ = c.updateMeta(store, func(meta *workspacesMetaData) error {
+		removeAvailableWorkspaces(meta, name)
+		return nil
+	})
+}
+
+// deleteWorkspace removes the workspace from the metadata and removes the workspace file. If name is not
+// specified, deletes the current workspace.
+func (c *metaCache) deleteWorkspace(store workspaceStore, name string) error {
+	var deleted string
+	err := c.updateMeta(store, func(meta *workspacesMetaData) error {
+		deleted = name
+		if deleted == "" {
+			deleted = meta.Current
+		}
+		if !checkWorkspaceExistence(meta, deleted) {
+			deleted = ""
+			return nil
+		}
+		removeAvailableWorkspaces(meta, deleted)
+		return nil
+	})
+	if err != nil || deleted == "" {
+		return err
+	}
+
+	// remove the workspace file after the metadata no longer refers to it.
+	return store.removeWorkspace(deleted)
+}
+
+// setCurrentWorkspace sets the current workspace in the metadata.
+func (c *metaCache) setCurrentWorkspace(store workspaceStore, name string) error {
+	return c.updateMeta(store, func(meta *workspacesMetaData) error {
+		if !checkWorkspaceExistence(meta, name) {
+			return ErrWorkspaceNotExist
+		}
+		meta.Current = name
+		return nil
+	})
+}
+
+// renameWorkspace renames the workspace in the metadata, and moves the workspace file by copying and removing.
+func (c *metaCache) renameWorkspace(store workspaceStore, oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return fmt.Errorf("given name is empty")
+	}
+
+	rename := func(from, to string) func(meta *workspacesMetaData) error {
+		return func(meta *workspacesMetaData) error {
+			if !checkWorkspaceExistence(meta, from) {
+				return ErrWorkspaceNotExist
+			}
+			if checkWorkspaceExistence(meta, to) {
+				return ErrWorkspaceAlreadyExist
+			}
+			current := meta.Current
+			removeAvailableWorkspaces(meta, from)
+			addAvailableWorkspaces(meta, to)
+			if current == from {
+				meta.Current = to
+			}
+			return nil
+		}
+	}
+	if err := c.updateMeta(store, rename(oldName, newName)); err != nil {
+		return err
+	}
+
+	if err := store.copyWorkspace(oldName, newName); err != nil {
+		_ = c.updateMeta(store, rename(newName, oldName))
+		return err
+	}
+	// remove the old file after the metadata no longer refers to it.
+	return store.removeWorkspace(oldName)
+}
+
+// initDefaultWorkspaceIf creates the default workspace with empty configurations if it does not exist, and sets
+// it as the current workspace if there is no current one.
+func (c *metaCache) initDefaultWorkspaceIf(store workspaceStore) error {
+	var created bool
+	err := c.updateMeta(store, func(meta *workspacesMetaData) error {
+		created = !checkWorkspaceExistence(meta, DefaultWorkspace)
+		if created {
+			addAvailableWorkspaces(meta, DefaultWorkspace)
+		}
+		if meta.Current == "" {
+			meta.Current = DefaultWorkspace
+		}
+		return nil
+	})
+	if err != nil || !created {
+		return err
+	}
+
+	if err = store.writeWorkspace(&v1.Workspace{Name: DefaultWorkspace}); err != nil {
+		c.releaseWorkspace(store, DefaultWorkspace)
+		return err
+	}
+	return nil
+}
+
 // checkWorkspaceExistence returns the workspace exists or not.
 func checkWorkspaceExistence(meta *workspacesMetaData, name string) bool {
 	for _, ws := range meta.AvailableWorkspaces {
@@ -54,10 +287,12 @@ func checkWorkspaceExistence(meta *workspacesMetaData, name string) bool {
 	return false
 }
 
-// addAvailableWorkspaces adds the workspace name to the available list, should be called if checkWorkspaceExistence
-// returns false.
+// addAvailableWorkspaces adds the workspace name to the available list in alphabetical order, should be called if
+// checkWorkspaceExistence returns false.
 func addAvailableWorkspaces(meta *workspacesMetaData, name string) {
 	meta.AvailableWorkspaces = append(meta.AvailableWorkspaces, name)
+	// sort the whole list rather than inserting in place, so that the list of the legacy metadata gets sorted too
+	sort.Strings(meta.AvailableWorkspaces)
 }
 
 // removeAvailableWorkspaces deletes the workspace name from the available list.
@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -76,8 +77,8 @@ func TestAddAvailableWorkspaces(t *testing.T) {
 				AvailableWorkspaces: []string{
 					"default",
 					"dev",
-					"prod",
 					"pre",
+					"prod",
 				},
 			},
 		},
@@ -86,7 +87,7 @@ func TestAddAvailableWorkspaces(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			addAvailableWorkspaces(tc.meta, tc.wsName)
-			assert.Equal(t, tc.expectedMeta, tc.expectedMeta)
+			assert.Equal(t, tc.expectedMeta, tc.meta)
 		})
 	}
 }
@@ -140,7 +141,111 @@ func TestRemoveAvailableWorkspaces(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			removeAvailableWorkspaces(tc.meta, tc.wsName)
-			assert.Equal(t, tc.expectedMeta, tc.expectedMeta)
+			assert.Equal(t, tc.expectedMeta, tc.meta)
 		})
 	}
 }
+
+func TestAvailableWorkspacesOrderStability(t *testing.T) {
+	meta := &workspacesMetaData{}
+	for _, name := range []string{"prod", "default", "test", "dev"} {
+		addAvailableWorkspaces(meta, name)
+	}
+	assert.Equal(t, []string{"default", "dev", "prod", "test"}, meta.AvailableWorkspaces)
+
+	removeAvailableWorkspaces(meta, "prod")
+	addAvailableWorkspaces(meta, "pre")
+	assert.Equal(t, []string{"default", "dev", "pre", "test"}, meta.AvailableWorkspaces)
+
+	// the unsorted list of legacy metadata gets sorted after adding
+	legacy := &workspacesMetaData{AvailableWorkspaces: []string{"prod", "default"}}
+	addAvailableWorkspaces(legacy, "dev")
+	assert.Equal(t, []string{"default", "dev", "prod"}, legacy.AvailableWorkspaces)
+}
+
+// conflictingMetaStore is a metaStore whose metadata gets modified by another writer between the first reads
+// and writes of it.
+type conflictingMetaStore struct {
+	meta      *workspacesMetaData
+	version   int
+	conflicts int
+	writes    int
+}
+
+func (s *conflictingMetaStore) readMeta() (*workspacesMetaData, string, error) {
+	return s.meta.deepCopy(), strconv.Itoa(s.version), nil
+}
+
+func (s *conflictingMetaStore) writeMeta(meta *workspacesMetaData, version string) error {
+	s.writes++
+	if s.conflicts > 0 {
+		// another writer adds a workspace and wins
+		s.conflicts--
+		addAvailableWorkspaces(s.meta, "other-"+strconv.Itoa(s.conflicts))
+		s.version++
+	}
+	if version != strconv.Itoa(s.version) {
+		return errMetaConflict
+	}
+	s.meta = meta.deepCopy()
+	s.version++
+	return nil
+}
+
+func TestMetaCache_UpdateMeta(t *testing.T) {
+	testcases := []struct {
+		name          string
+		success       bool
+		conflicts     int
+		expectedNames []string
+	}{
+		{
+			name:          "update without conflict",
+			success:       true,
+			conflicts:     0,
+			expectedNames: []string{"default", "dev", "prod", "test"},
+		},
+		{
+			name:          "update retried on conflicts",
+			success:       true,
+			conflicts:     2,
+			expectedNames: []string{"default", "dev", "other-0", "other-1", "prod", "test"},
+		},
+		{
+			name:      "update failed too many conflicts",
+			success:   false,
+			conflicts: maxMetaUpdateAttempts,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &conflictingMetaStore{meta: mockWorkspacesMetaData(), conflicts: tc.conflicts}
+			cache := &metaCache{}
+			err := cache.updateMeta(store, func(meta *workspacesMetaData) error {
+				addAvailableWorkspaces(meta, "test")
+				return nil
+			})
+			assert.Equal(t, tc.success, err == nil)
+			if tc.success {
+				assert.Equal(t, tc.conflicts+1, store.writes)
+				assert.Equal(t, tc.expectedNames, store.meta.AvailableWorkspaces)
+				assert.Equal(t, store.meta, cache.meta)
+			} else {
+				assert.ErrorIs(t, err, errMetaConflict)
+			}
+		})
+	}
+}
+
+func TestMetaCache_UpdateMetaUnchanged(t *testing.T) {
+	store := &conflictingMetaStore{meta: mockWorkspacesMetaData()}
+	cache := &metaCache{}
+	err := cache.updateMeta(store, func(meta *workspacesMetaData) error {
+		meta.Current = "dev"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, store.writes)
+	assert.Equal(t, mockWorkspacesMetaData(), cache.meta)
+}
@@ -3,11 +3,16 @@ package workspace
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	v1 "kusionstack.io/kusion/pkg/apis/api.kusion.io/v1"
 )
 
-var ErrEmptyProjectName = errors.New("empty project name")
+var (
+	ErrEmptyProjectName         = errors.New("empty project name")
+	ErrCircularBaseModuleConfig = errors.New("circular base module configs")
+)
 
 // GetProjectModuleConfigs returns the module configs of a specified project, whose key is the module name, should be called after ValidateModuleConfigs.
 // If got empty module configs, return nil config and nil error.
@@ -32,10 +37,70 @@ func GetProjectModuleConfigs(configs v1.ModuleConfigs, projectName string) (map[
 			projectConfigs[name] = moduleConfig
 		}
 	}
+	if err := resolveBaseModuleConfigs(configs, projectConfigs); err != nil {
+		return nil, err
+	}
 
 	return projectConfigs, nil
 }
 
+// resolveBaseModuleConfigs layers the project module configs on their base module configs, which are resolved
+// recursively. The base module configs referencing each other in a cycle are reported along with the names of
+// the modules in the cycle.
+func resolveBaseModuleConfigs(configs v1.ModuleConfigs, projectConfigs map[string]v1.GenericConfig) error {
+	resolved := make(map[string]bool, len(projectConfigs))
+	var resolve func(name string, path []string) error
+	resolve = func(name string, path []string) error {
+		if resolved[name] {
+			return nil
+		}
+		for i, visited := range path {
+			if visited == name {
+				cycle := append(path[i:], name)
+				return fmt.Errorf("%w: %s", ErrCircularBaseModuleConfig, strings.Join(cycle, " -> "))
+			}
+		}
+		cfg := projectConfigs[name]
+		base, err := GetStringFromGenericConfig(cfg, v1.FieldBaseModuleConfig)
+		if err != nil {
+			return fmt.Errorf("%w, module name: %s", err, name)
+		}
+		if base != "" {
+			if _, ok := configs[base]; !ok {
+				return fmt.Errorf("base module config %s of module %s is not found in workspace", base, name)
+			}
+			if err = resolve(base, append(path[:len(path):len(path)], name)); err != nil {
+				return err
+			}
+			layered := make(v1.GenericConfig, len(projectConfigs[base])+len(cfg))
+			for k, v := range projectConfigs[base] {
+				layered[k] = v
+			}
+			for k, v := range cfg {
+				if k != v1.FieldBaseModuleConfig {
+					layered[k] = v
+				}
+			}
+			projectConfigs[name] = layered
+		}
+		resolved[name] = true
+		return nil
+	}
+
+	// resolve in the order of the module names, so that the same cycle is reported across the generations
+	names := make([]string, 0, len(projectConfigs))
+	for name := range projectConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetProjectModuleConfig returns the module config of a specified project, should be called after ValidateModuleConfig.
 // If got empty module config, return nil config and nil error.
 func GetProjectModuleConfig(config *v1.ModuleConfig, projectName string) (v1.GenericConfig, error) {
@@ -51,9 +116,11 @@ func GetProjectModuleConfig(config *v1.ModuleConfig, projectName string) (v1.Gen
 
 // getProjectModuleConfig gets the module config of a specified project without checking the correctness of project name.
 func getProjectModuleConfig(config *v1.ModuleConfig, projectName string) (v1.GenericConfig, error) {
-	projectCfg := config.Configs.Default
-	if len(projectCfg) == 0 {
-		projectCfg = make(v1.GenericConfig)
+	// copy the default block, as the patcher block is merged into the project config, which must not
+	// leak into the default block shared by the other projects.
+	projectCfg := make(v1.GenericConfig, len(config.Configs.Default))
+	for k, v := range config.Configs.Default {
+		projectCfg[k] = v
 	}
 
 	for name, cfg := range config.Configs.ModulePatcherConfigs {
@@ -142,3 +209,48 @@ func GetStringMapFromGenericConfig(config v1.GenericConfig, key string) (map[str
 	}
 	return stringMap, nil
 }
+
+// GetStringSliceFromGenericConfig returns the value of the key in config which should be a list of strings.
+// If exist but not a list of strings, return error; If not exist, return nil, nil.
+func GetStringSliceFromGenericConfig(config v1.GenericConfig, key string) ([]string, error) {
+	value, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	switch items := value.(type) {
+	case []string:
+		return items, nil
+	case []any:
+		stringSlice := make([]string, 0, len(items))
+		for i, item := range items {
+			stringValue, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("the value of %s[%d] is not string", key, i)
+			}
+			stringSlice = append(stringSlice, stringValue)
+		}
+		return stringSlice, nil
+	default:
+		return nil, fmt.Errorf("the value of %s is not list", key)
+	}
+}
+
+// GetPreviewIgnoreFields returns the fields ignored when computing the preview changes in the workspace, which
+// are the given fields, e.g. from the command line, merged with the ones configured in the workspace.
+func GetPreviewIgnoreFields(ws *v1.Workspace, fields ...string) []string {
+	var ignoreFields []string
+	if ws != nil {
+		ignoreFields = ws.PreviewIgnoreFields
+	}
+	merged := make([]string, 0, len(fields)+len(ignoreFields))
+	seen := make(map[string]bool, len(fields)+len(ignoreFields))
+	for _, list := range [][]string{fields, ignoreFields} {
+		for _, field := range list {
+			if !seen[field] {
+				seen[field] = true
+				merged = append(merged, field)
+			}
+		}
+	}
+	return merged
+}
@@ -29,6 +29,7 @@ func Test_GetProjectModuleConfigs(t *testing.T) {
 		projectName            string
 		moduleConfigs          v1.ModuleConfigs
 		success                bool
+		errContains            string
 		expectedProjectConfigs map[string]v1.GenericConfig
 	}{
 		{
@@ -47,11 +48,68 @@ func Test_GetProjectModuleConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "successfully layer project module configs on base module configs",
+			projectName: "foo",
+			moduleConfigs: v1.ModuleConfigs{
+				"mysql": {Configs: v1.Configs{Default: v1.GenericConfig{
+					"type":    "aws",
+					"version": "5.7",
+				}}},
+				"mysql-ha": {Configs: v1.Configs{Default: v1.GenericConfig{
+					v1.FieldBaseModuleConfig: "mysql",
+					"version":                "8.0",
+				}}},
+				"mysql-ha-large": {Configs: v1.Configs{Default: v1.GenericConfig{
+					v1.FieldBaseModuleConfig: "mysql-ha",
+					"instanceType":           "db.r5.large",
+				}}},
+			},
+			success: true,
+			expectedProjectConfigs: map[string]v1.GenericConfig{
+				"mysql": {
+					"type":    "aws",
+					"version": "5.7",
+				},
+				"mysql-ha": {
+					"type":    "aws",
+					"version": "8.0",
+				},
+				"mysql-ha-large": {
+					"type":         "aws",
+					"version":      "8.0",
+					"instanceType": "db.r5.large",
+				},
+			},
+		},
+		{
+			name:        "failed to get project module configs with circular base module configs",
+			projectName: "foo",
+			moduleConfigs: v1.ModuleConfigs{
+				"a": {Configs: v1.Configs{Default: v1.GenericConfig{v1.FieldBaseModuleConfig: "b"}}},
+				"b": {Configs: v1.Configs{Default: v1.GenericConfig{v1.FieldBaseModuleConfig: "c"}}},
+				"c": {Configs: v1.Configs{Default: v1.GenericConfig{v1.FieldBaseModuleConfig: "a"}}},
+			},
+			success:     false,
+			errContains: "circular base module configs: a -> b -> c -> a",
+		},
+		{
+			name:        "failed to get project module configs with missing base module config",
+			projectName: "foo",
+			moduleConfigs: v1.ModuleConfigs{
+				"a": {Configs: v1.Configs{Default: v1.GenericConfig{v1.FieldBaseModuleConfig: "b"}}},
+			},
+			success:     false,
+			errContains: "base module config b of module a is not found in workspace",
+		},
 	}
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			cfg, err := GetProjectModuleConfigs(tc.moduleConfigs, tc.projectName)
+			if tc.errContains != "" {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
 			assert.Equal(t, tc.success, err == nil)
 			assert.Equal(t, tc.expectedProjectConfigs, cfg)
 		})
@@ -106,6 +164,17 @@ func Test_GetProjectModuleConfig(t *testing.T) {
 	}
 }
 
+func Test_GetProjectModuleConfig_DefaultUnchanged(t *testing.T) {
+	moduleConfig := mockValidModuleConfigs()["mysql"]
+	_, err := GetProjectModuleConfig(moduleConfig, "foo")
+	assert.NoError(t, err)
+
+	cfg, err := GetProjectModuleConfig(moduleConfig, "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "db.t3.micro", cfg["instanceType"])
+	assert.Equal(t, "db.t3.micro", moduleConfig.Configs.Default["instanceType"])
+}
+
 func Test_GetIntFieldFromGenericConfig(t *testing.T) {
 	r2 := int32(2)
 
@@ -251,3 +320,33 @@ func Test_GetStringMapFieldFromGenericConfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetStringSliceFieldFromGenericConfig(t *testing.T) {
+	config := v1.GenericConfig{
+		"string_slice_field": []any{"region", "zone"},
+		"mixed_slice_field":  []any{"region", 1},
+		"string_field":       "region",
+	}
+	value, err := GetStringSliceFromGenericConfig(config, "string_slice_field")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"region", "zone"}, value)
+
+	value, err = GetStringSliceFromGenericConfig(config, "not_exist")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	_, err = GetStringSliceFromGenericConfig(config, "mixed_slice_field")
+	assert.EqualError(t, err, "the value of mixed_slice_field[1] is not string")
+
+	_, err = GetStringSliceFromGenericConfig(config, "string_field")
+	assert.EqualError(t, err, "the value of string_field is not list")
+}
+
+func Test_GetPreviewIgnoreFields(t *testing.T) {
+	assert.Empty(t, GetPreviewIgnoreFields(nil))
+	assert.Equal(t, []string{"spec.replicas"}, GetPreviewIgnoreFields(&v1.Workspace{Name: "dev"}, "spec.replicas"))
+	assert.Equal(t, []string{"spec.replicas", "metadata.annotations"}, GetPreviewIgnoreFields(&v1.Workspace{
+		Name:                "dev",
+		PreviewIgnoreFields: []string{"metadata.annotations", "spec.replicas"},
+	}, "spec.replicas"))
+}
@@ -33,7 +33,10 @@ var (
 	ErrEmptyTenantID                        = errors.New("azure tenant id must be provided when using Azure KeyVault")
 	ErrEmptyAlicloudRegion                  = errors.New("region must be provided when using Alicloud Secrets Manager")
 	ErrMissingProviderType                  = errors.New("must specify a provider type")
+	ErrUnknownSelectedSecretStore           = errors.New("secret store selector refers to a secret store not configured in secretStores")
 	ErrInvalidViettelCloudProjectID         = errors.New("invalid format project id for ViettelCloud Secrets Manager")
+	ErrEmptyOnPremisesName                  = errors.New("name must be provided when using the on-premises secret store")
+	ErrInvalidOwnerReference                = errors.New("apiVersion and kind of the owner reference must not be empty")
 )
 
 // ValidateWorkspace is used to validate the workspace get or set in the storage.
@@ -51,6 +54,22 @@ func ValidateWorkspace(ws *v1.Workspace) error {
 			return utilerrors.NewAggregate(allErrs)
 		}
 	}
+	for name, ss := range ws.SecretStores {
+		if ss == nil {
+			return fmt.Errorf("%w, secret store name: %s", ErrMissingProvider, name)
+		}
+		if allErrs := ValidateSecretStoreConfig(ss); allErrs != nil {
+			return fmt.Errorf("%w, secret store name: %s", utilerrors.NewAggregate(allErrs), name)
+		}
+	}
+	for pattern, name := range ws.SecretStoreSelectors {
+		if _, ok := ws.SecretStores[name]; !ok {
+			return fmt.Errorf("%w, pattern: %s, secret store name: %s", ErrUnknownSelectedSecretStore, pattern, name)
+		}
+	}
+	if ws.OwnerReference != nil && (ws.OwnerReference.APIVersion == "" || ws.OwnerReference.Kind == "") {
+		return ErrInvalidOwnerReference
+	}
 	return nil
 }
 
@@ -198,6 +217,25 @@ func ValidateSecretStoreConfig(spec *v1.SecretStore) []error {
 		}
 	}
 
+	if spec.Provider.Fake != nil {
+		if numProviders > 0 {
+			allErrs = append(allErrs, ErrMultiSecretStoreProviders)
+		} else {
+			numProviders++
+		}
+	}
+
+	if spec.Provider.OnPremises != nil {
+		if numProviders > 0 {
+			allErrs = append(allErrs, ErrMultiSecretStoreProviders)
+		} else {
+			numProviders++
+			if spec.Provider.OnPremises.Name == "" {
+				allErrs = append(allErrs, ErrEmptyOnPremisesName)
+			}
+		}
+	}
+
 	if numProviders == 0 {
 		allErrs = append(allErrs, ErrMissingProviderType)
 	}
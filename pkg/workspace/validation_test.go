@@ -205,6 +205,15 @@ func TestValidateWorkspace(t *testing.T) {
 			success:   false,
 			workspace: &v1.Workspace{},
 		},
+		{
+			name:    "invalid workspace empty owner reference kind",
+			success: false,
+			workspace: func() *v1.Workspace {
+				ws := mockValidWorkspace("dev")
+				ws.OwnerReference = &v1.OwnerReferenceConfig{APIVersion: "app.kusion.io/v1alpha1"}
+				return ws
+			}(),
+		},
 	}
 
 	for _, tc := range testcases {
@@ -488,6 +497,28 @@ func TestValidateSecretStoreConfig(t *testing.T) {
 			},
 			want: nil,
 		},
+		{
+			name: "valid fake secret store spec",
+			args: args{
+				spec: &v1.SecretStore{
+					Provider: &v1.ProviderSpec{
+						Fake: &v1.FakeProvider{},
+					},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "on-premises secret store without name",
+			args: args{
+				spec: &v1.SecretStore{
+					Provider: &v1.ProviderSpec{
+						OnPremises: &v1.OnPremisesProvider{},
+					},
+				},
+			},
+			want: []error{ErrEmptyOnPremisesName},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {